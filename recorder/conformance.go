@@ -0,0 +1,83 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+func newConformanceCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "conformance",
+		Usage:     "Checks a device's implementation of the device protocol (eg. for firmware developers)",
+		ArgsUsage: "<device address>",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "duration",
+				Value: 10 * time.Second,
+				Usage: "How long to stream signals from the device for",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single device address")
+			}
+
+			addrPort, err := netip.ParseAddrPort(c.Args().First())
+			if err != nil {
+				addr, err := netip.ParseAddr(c.Args().First())
+				if err != nil {
+					return fmt.Errorf("invalid device address %q", c.Args().First())
+				}
+				addrPort = netip.AddrPortFrom(addr, 80)
+			}
+
+			results := openpsg.CheckConformance(appContext(c.Context), addrPort, c.Duration("duration"))
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Check", "Result", "Detail"})
+			table.SetBorder(false)
+			table.SetAutoWrapText(false)
+
+			var failed int
+			for _, result := range results {
+				status := "PASS"
+				if !result.Passed {
+					status = "FAIL"
+					failed++
+				}
+				table.Append([]string{result.Check, status, result.Detail})
+			}
+
+			table.Render()
+
+			if failed > 0 {
+				return fmt.Errorf("device failed %d of %d conformance checks", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,120 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/urfave/cli/v2"
+)
+
+func newConvertCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "convert",
+		Usage:     "Converts an EDF recording to another file format",
+		ArgsUsage: "<recording.edf>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "Output format (wfdb, hdf5, nwb, dicom, csv, parquet)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Output directory (defaults to the directory of the recording)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording to convert")
+			}
+
+			inputPath := c.Args().First()
+
+			outputDir := c.String("output")
+			if outputDir == "" {
+				outputDir = filepath.Dir(inputPath)
+			}
+
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			f, err := os.Open(inputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			er, err := edfplus.Open(f)
+			if err != nil {
+				return fmt.Errorf("failed to read recording: %w", err)
+			}
+
+			name := recordName(inputPath)
+
+			slog.Info("Converting recording",
+				slog.String("input", inputPath),
+				slog.String("format", c.String("to")),
+				slog.String("outputDir", outputDir))
+
+			switch strings.ToLower(c.String("to")) {
+			case "wfdb":
+				err = export.WFDB(er, outputDir, name)
+			case "hdf5":
+				err = export.HDF5(er, outputDir, name)
+			case "nwb":
+				err = export.NWB(er, outputDir, name)
+			case "dicom":
+				err = export.DICOM(er, outputDir, name)
+			case "csv":
+				err = export.CSV(er, outputDir, name)
+			case "parquet":
+				err = export.Parquet(er, outputDir, name)
+			default:
+				return fmt.Errorf("unsupported output format: %s", c.String("to"))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to convert recording: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// recordName derives an output record name from the input file name, using
+// only the characters that are safe for all supported formats.
+func recordName(inputPath string) string {
+	name := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,166 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+func newDevicesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "devices",
+		Usage: "Manages the devices approved for recording (see --require-approval)",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "Lists the devices that have joined the network, and whether they are approved",
+				Action: func(c *cli.Context) error {
+					db, err := openLeaseDB(c)
+					if err != nil {
+						return err
+					}
+					defer db.Close()
+
+					leases, err := db.ListLeases()
+					if err != nil {
+						return fmt.Errorf("failed to list leases: %w", err)
+					}
+
+					table := tablewriter.NewWriter(os.Stdout)
+					table.SetHeader([]string{"MAC Address", "IP Address", "Hostname", "Approved"})
+					table.SetBorder(false)
+
+					for _, lease := range leases {
+						mac, err := net.ParseMAC(lease.MAC)
+						if err != nil {
+							return fmt.Errorf("invalid MAC address in lease: %w", err)
+						}
+
+						approved, err := db.IsApproved(mac)
+						if err != nil {
+							return fmt.Errorf("failed to check device approval: %w", err)
+						}
+
+						table.Append([]string{lease.MAC, lease.IPAddress, lease.Hostname, fmt.Sprint(approved)})
+					}
+
+					table.Render()
+
+					return nil
+				},
+			},
+			{
+				Name:      "approve",
+				Usage:     "Approves devices for recording",
+				ArgsUsage: "<MAC or IP address>...",
+				Action: func(c *cli.Context) error {
+					return setApproved(c, true)
+				},
+			},
+			{
+				Name:      "revoke",
+				Usage:     "Revokes the approval of devices",
+				ArgsUsage: "<MAC or IP address>...",
+				Action: func(c *cli.Context) error {
+					return setApproved(c, false)
+				},
+			},
+		},
+	}
+}
+
+// setApproved approves (or revokes the approval of) the devices given as
+// arguments, by MAC address or by the IP address of their lease.
+func setApproved(c *cli.Context, approved bool) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("expected the MAC or IP addresses of the devices")
+	}
+
+	db, err := openLeaseDB(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, arg := range c.Args().Slice() {
+		mac, err := deviceMAC(db, arg)
+		if err != nil {
+			return err
+		}
+
+		if err := db.SetApproved(mac, approved); err != nil {
+			return fmt.Errorf("failed to update device approval: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deviceMAC returns the MAC address of a device given by MAC address, or by
+// the IP address of its lease.
+func deviceMAC(db *leasedb.DB, arg string) (net.HardwareAddr, error) {
+	mac, err := net.ParseMAC(arg)
+	if err == nil {
+		return mac, nil
+	}
+
+	addr, err := netip.ParseAddr(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC or IP address %q", arg)
+	}
+
+	lease, err := db.GetLeaseByIP(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err = net.ParseMAC(lease.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC address in lease: %w", err)
+	}
+
+	return mac, nil
+}
+
+// openLeaseDB opens the DHCP lease database, for the network given by the
+// global flags.
+func openLeaseDB(c *cli.Context) (*leasedb.DB, error) {
+	prefix, err := netip.ParsePrefix(c.String("prefix"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse network prefix: %w", err)
+	}
+
+	gateway, err := netip.ParseAddr(c.String("gateway"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse network gateway address: %w", err)
+	}
+
+	db, err := leasedb.Open(c.String("db-path"), prefix, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dhcp lease database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,106 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"syscall"
+	"time"
+)
+
+// How often to check the free space on the output volume.
+const diskSpaceCheckInterval = 30 * time.Second
+
+// freeSpace returns the number of bytes available on the volume containing
+// path.
+func freeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem: %w", err)
+	}
+
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// errLowDiskSpace is returned when there is too little free space on the
+// output volume to start recording.
+var errLowDiskSpace = errors.New("disk is low on space")
+
+// checkDiskSpace returns errLowDiskSpace if the free space on the volume
+// containing path, as measured by free, is below minFree (zero to never).
+func checkDiskSpace(path string, minFree uint64, free func(path string) (uint64, error)) error {
+	if minFree == 0 {
+		return nil
+	}
+
+	n, err := free(path)
+	if err != nil {
+		slog.Warn("Failed to check free disk space", slog.Any("error", err))
+		return nil
+	}
+
+	if n < minFree {
+		return fmt.Errorf("%w: %d MiB free on %s", errLowDiskSpace, n>>20, path)
+	}
+
+	return nil
+}
+
+// watchDiskSpace checks the free space on the volume containing path every
+// interval, as measured by free, warning once it falls below warnFree. The
+// returned channel is closed once it falls below minFree (zero to never).
+func watchDiskSpace(ctx context.Context, path string, warnFree, minFree uint64,
+	interval time.Duration, free func(path string) (uint64, error)) <-chan struct{} {
+	low := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		warned := false
+		for {
+			n, err := free(path)
+			if err != nil {
+				slog.Warn("Failed to check free disk space", slog.Any("error", err))
+			} else if minFree > 0 && n < minFree {
+				slog.Warn("Disk space is critically low",
+					slog.String("path", path), slog.Uint64("free", n))
+				close(low)
+				return
+			} else if n < warnFree && !warned {
+				slog.Warn("Disk space is running low",
+					slog.String("path", path), slog.Uint64("free", n))
+				warned = true
+			} else if n >= warnFree {
+				warned = false
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return low
+}
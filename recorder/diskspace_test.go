@@ -0,0 +1,187 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mib = 1 << 20
+
+// fakeVolume reports a sequence of free space measurements, repeating the
+// last. A zero measurement fails.
+type fakeVolume struct {
+	mu     sync.Mutex
+	free   []uint64
+	checks int
+}
+
+func (v *fakeVolume) freeSpace(path string) (uint64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	free := v.free[min(v.checks, len(v.free)-1)]
+	v.checks++
+	if free == 0 {
+		return 0, errors.New("statfs failed")
+	}
+	return free, nil
+}
+
+func (v *fakeVolume) checked() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.checks
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		free    uint64
+		minFree uint64
+		wantErr bool
+	}{
+		{
+			name:    "Plenty of space",
+			free:    2048 * mib,
+			minFree: 100 * mib,
+		},
+		{
+			name:    "Low on space",
+			free:    50 * mib,
+			minFree: 100 * mib,
+			wantErr: true,
+		},
+		{
+			name:    "At the threshold",
+			free:    100 * mib,
+			minFree: 100 * mib,
+		},
+		{
+			name:    "Disabled",
+			free:    1,
+			minFree: 0,
+		},
+		{
+			// Recording isn't refused if the space can't be measured.
+			name:    "Measurement failed",
+			free:    0,
+			minFree: 100 * mib,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume := &fakeVolume{free: []uint64{tt.free}}
+
+			err := checkDiskSpace("/recordings", tt.minFree, volume.freeSpace)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errLowDiskSpace)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWatchDiskSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		free    []uint64
+		minFree uint64
+		// The number of checks after which the volume is low, zero if never.
+		wantLowAfter int
+	}{
+		{
+			name:    "Plenty of space",
+			free:    []uint64{2048 * mib},
+			minFree: 100 * mib,
+		},
+		{
+			name:    "Below the warning threshold",
+			free:    []uint64{2048 * mib, 500 * mib, 101 * mib},
+			minFree: 100 * mib,
+		},
+		{
+			name:         "Below the stop threshold",
+			free:         []uint64{2048 * mib, 500 * mib, 99 * mib},
+			minFree:      100 * mib,
+			wantLowAfter: 3,
+		},
+		{
+			name:         "Already low",
+			free:         []uint64{50 * mib},
+			minFree:      100 * mib,
+			wantLowAfter: 1,
+		},
+		{
+			name:    "Disabled",
+			free:    []uint64{1},
+			minFree: 0,
+		},
+		{
+			name:         "Measurement failed",
+			free:         []uint64{0, 0, 50 * mib},
+			minFree:      100 * mib,
+			wantLowAfter: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			volume := &fakeVolume{free: tt.free}
+			low := watchDiskSpace(ctx, "/recordings", 1024*mib, tt.minFree, time.Millisecond, volume.freeSpace)
+
+			if tt.wantLowAfter == 0 {
+				// Keep checking the last measurement for a while.
+				require.Eventually(t, func() bool {
+					return volume.checked() > len(tt.free)+5
+				}, time.Second, time.Millisecond)
+
+				select {
+				case <-low:
+					t.Fatal("the volume was reported as low on space")
+				default:
+				}
+				return
+			}
+
+			select {
+			case <-low:
+			case <-time.After(time.Second):
+				t.Fatal("the volume wasn't reported as low on space")
+			}
+
+			// Checking stops once the volume is low.
+			time.Sleep(10 * time.Millisecond)
+			assert.Equal(t, tt.wantLowAfter, volume.checked())
+		})
+	}
+}
@@ -0,0 +1,193 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/urfave/cli/v2"
+)
+
+func newExtractCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "extract",
+		Usage:     "Extracts a time window and/or some of the signals of an EDF recording into a new recording",
+		ArgsUsage: "<recording.edf>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "Path of the extracted recording",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "Start of the window, as an offset from the start of the recording (eg. 1h30m) or a local clock time (HH:MM or HH:MM:SS)",
+			},
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "End of the window, as an offset from the start of the recording (eg. 1h35m) or a local clock time (HH:MM or HH:MM:SS)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "signals",
+				Usage: "Extract only the selected signals, by name or glob pattern (eg. 'EEG*')",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording to extract from")
+			}
+
+			outputPath := c.String("output")
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("refusing to overwrite existing recording: %s", outputPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to stat recording: %w", err)
+			}
+
+			inputPath := c.Args().First()
+			f, err := os.Open(inputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			er, err := edfplus.Open(f)
+			if err != nil {
+				return fmt.Errorf("failed to read recording: %w", err)
+			}
+
+			startTime := er.Header().StartTime
+
+			from := time.Duration(0)
+			if c.IsSet("from") {
+				if from, err = parseOffset(c.String("from"), startTime); err != nil {
+					return fmt.Errorf("failed to parse --from: %w", err)
+				}
+			}
+
+			to := time.Duration(math.MaxInt64)
+			if c.IsSet("to") {
+				if to, err = parseOffset(c.String("to"), startTime); err != nil {
+					return fmt.Errorf("failed to parse --to: %w", err)
+				}
+			}
+
+			signals, err := selectSignals(er, c.StringSlice("signals"))
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Extracting recording",
+				slog.String("input", inputPath),
+				slog.String("output", outputPath),
+				slog.Int("signals", len(signals)))
+
+			partialPath := outputPath + partialSuffix
+			out, err := os.Create(partialPath)
+			if err != nil {
+				return fmt.Errorf("failed to create extracted recording: %w", err)
+			}
+			defer out.Close()
+
+			if err := edfplus.Extract(out, er, from, to, signals); err != nil {
+				_ = os.Remove(partialPath)
+				return fmt.Errorf("failed to extract recording: %w", err)
+			}
+
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("failed to close extracted recording: %w", err)
+			}
+
+			if err := os.Rename(partialPath, outputPath); err != nil {
+				return fmt.Errorf("failed to rename extracted recording: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// parseOffset parses either an offset from the start of a recording (eg.
+// 1h30m), or a local clock time (HH:MM or HH:MM:SS) which refers to its next
+// occurrence from the start of the recording.
+func parseOffset(s string, start time.Time) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return 0, fmt.Errorf("negative offset %q", s)
+		}
+		return d, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04:05", s, time.Local)
+	if err != nil {
+		if clock, err = time.ParseInLocation("15:04", s, time.Local); err != nil {
+			return 0, fmt.Errorf("invalid time %q, expected an offset (eg. 1h30m), HH:MM or HH:MM:SS", s)
+		}
+	}
+
+	t := time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.Local)
+	if t.Before(start) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t.Sub(start), nil
+}
+
+// selectSignals returns the indices of the ordinary signals of the recording
+// matching the selectors, by name or glob pattern (case insensitive). Without
+// any selectors, every signal is selected.
+func selectSignals(er *edfplus.Reader, selectors []string) ([]int, error) {
+	var indices []int
+	matched := make([]bool, len(selectors))
+
+	for i, signal := range er.Signals() {
+		selected := len(selectors) == 0
+		for j, selector := range selectors {
+			ok, err := path.Match(strings.ToLower(selector), strings.ToLower(signal.Label))
+			if err != nil {
+				return nil, fmt.Errorf("invalid signal selector %q: %w", selector, err)
+			}
+			if ok {
+				selected = true
+				matched[j] = true
+			}
+		}
+
+		if selected {
+			indices = append(indices, i)
+		}
+	}
+
+	for j, selector := range selectors {
+		if !matched[j] {
+			return nil, fmt.Errorf("no signals match %q", selector)
+		}
+	}
+
+	return indices, nil
+}
@@ -0,0 +1,141 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/fhir"
+)
+
+// How long to wait for the FHIR server to accept the bundle.
+const fhirPostTimeout = 30 * time.Second
+
+// publishFHIR generates the FHIR resources describing a completed recording,
+// and writes them to outputPath and/or posts them to endpoint.
+func publishFHIR(edfPath, patientID, recordingID, outputPath, endpoint string) error {
+	rec, err := summarizeRecording(edfPath)
+	if err != nil {
+		return err
+	}
+	// "X" marks an unknown EDF+ subfield.
+	if patientID != "X" {
+		rec.PatientID = patientID
+	}
+	rec.RecordingID = recordingID
+
+	bundle := fhir.NewRecordingBundle(*rec)
+
+	if outputPath != "" {
+		data, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal FHIR bundle: %w", err)
+		}
+
+		if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write FHIR bundle: %w", err)
+		}
+
+		slog.Info("Wrote FHIR bundle", slog.String("path", outputPath))
+	}
+
+	if endpoint != "" {
+		// The recording context has already been cancelled at this point.
+		ctx, cancel := context.WithTimeout(context.Background(), fhirPostTimeout)
+		defer cancel()
+
+		if err := fhir.Post(ctx, http.DefaultClient, endpoint, bundle); err != nil {
+			return err
+		}
+
+		slog.Info("Posted FHIR bundle", slog.String("endpoint", endpoint))
+	}
+
+	return nil
+}
+
+// summarizeRecording reads the timing, signals and checksum of an EDF file.
+func summarizeRecording(edfPath string) (*fhir.Recording, error) {
+	path, err := filepath.Abs(edfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of recording: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	er, err := edfplus.Open(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	hdr := er.Header()
+
+	var end time.Duration
+	for {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read data record: %w", err)
+		}
+		end = record.Onset + hdr.DataRecordDuration
+	}
+
+	rec := &fhir.Recording{
+		Path:  path,
+		Start: hdr.StartTime,
+		End:   hdr.StartTime.Add(end),
+	}
+
+	for _, signal := range er.Signals() {
+		rec.Signals = append(rec.Signals, fhir.Signal{
+			Label:      signal.Label,
+			Unit:       signal.PhysicalDimension,
+			SampleRate: float64(signal.SamplesPerRecord) / hdr.DataRecordDuration.Seconds(),
+		})
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek in recording: %w", err)
+	}
+
+	h := sha1.New()
+	if rec.Size, err = io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("failed to hash recording: %w", err)
+	}
+	rec.SHA1 = h.Sum(nil)
+
+	return rec, nil
+}
@@ -0,0 +1,114 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newFirmwareCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "firmware",
+		Usage: "Manages the firmware of devices",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "upload",
+				Usage:     "Updates the firmware of devices over the network",
+				ArgsUsage: "<image> <device address>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() < 2 {
+						return fmt.Errorf("expected a firmware image and the addresses of the devices to update")
+					}
+
+					image, err := os.ReadFile(c.Args().First())
+					if err != nil {
+						return fmt.Errorf("failed to read firmware image: %w", err)
+					}
+					if len(image) == 0 {
+						return fmt.Errorf("firmware image %q is empty", c.Args().First())
+					}
+
+					var deviceAddrs []netip.Addr
+					for _, arg := range c.Args().Tail() {
+						addr, err := netip.ParseAddr(arg)
+						if err != nil {
+							return fmt.Errorf("invalid device address %q", arg)
+						}
+						deviceAddrs = append(deviceAddrs, addr)
+					}
+
+					// Devices are updated one at a time, so a bad image is caught before it
+					// is sent to every device.
+					for _, addr := range deviceAddrs {
+						if err := updateFirmware(c, addr, image); err != nil {
+							return fmt.Errorf("failed to update device %s: %w", addr, err)
+						}
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// updateFirmware updates the firmware of the device at addr, logging the
+// firmware version before and after.
+func updateFirmware(c *cli.Context, addr netip.Addr, image []byte) error {
+	client, err := openpsg.Connect(c.Context, netip.AddrPortFrom(addr, 80))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	info, err := client.Info(c.Context)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Updating firmware", slog.Any("deviceAddr", addr), slog.String("firmware", info.FirmwareVersion))
+
+	// Log the progress every 10%.
+	var logged int
+	progress := func(sent, total int) {
+		if percent := sent * 100 / total; percent >= logged+10 || sent == total {
+			logged = percent
+			slog.Info("Sending firmware", slog.Any("deviceAddr", addr), slog.Int("percent", percent))
+		}
+	}
+
+	if err := client.UpdateFirmware(c.Context, image, progress); err != nil {
+		return err
+	}
+
+	info, err = client.Info(c.Context)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Updated firmware", slog.Any("deviceAddr", addr), slog.String("firmware", info.FirmwareVersion))
+
+	return nil
+}
@@ -0,0 +1,87 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hotkey annotates the recording with its text when its key is pressed on the
+// dashboard (eg. l for "Lights off"), replacing the paper log of events kept
+// by technicians.
+type hotkey struct {
+	key  string
+	text string
+}
+
+// The hotkeys available unless reassigned with --hotkey.
+var defaultHotkeys = []hotkey{
+	{key: "l", text: "Lights off"},
+	{key: "o", text: "Lights on"},
+	{key: "n", text: "Nurse in room"},
+}
+
+// The keys of the dashboard that can't be assigned to hotkeys.
+var reservedKeys = []string{"e", "q", "t"}
+
+// parseHotkeys parses hotkeys given as KEY=TEXT (eg. 'b=Bathroom break'),
+// adding them to (or replacing) the default hotkeys. Keys are single
+// characters, and are case insensitive.
+func parseHotkeys(specs []string) ([]hotkey, error) {
+	hotkeys := slices.Clone(defaultHotkeys)
+
+	for _, spec := range specs {
+		key, text, ok := strings.Cut(spec, "=")
+		text = strings.TrimSpace(text)
+		if !ok || text == "" {
+			return nil, fmt.Errorf("invalid hotkey %q, expected KEY=TEXT", spec)
+		}
+
+		key = strings.ToLower(key)
+		if r, size := utf8.DecodeRuneInString(key); size != len(key) || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return nil, fmt.Errorf("invalid hotkey %q, the key must be a single character", spec)
+		}
+		if slices.Contains(reservedKeys, key) {
+			return nil, fmt.Errorf("invalid hotkey %q, the key %q is used by the dashboard", spec, key)
+		}
+
+		i := slices.IndexFunc(hotkeys, func(h hotkey) bool { return h.key == key })
+		if i == -1 {
+			hotkeys = append(hotkeys, hotkey{key: key, text: text})
+		} else {
+			hotkeys[i].text = text
+		}
+	}
+
+	return hotkeys, nil
+}
+
+// lookupHotkey returns the hotkey of the key pressed (if any).
+func lookupHotkey(hotkeys []hotkey, key string) (hotkey, bool) {
+	key = strings.ToLower(key)
+	i := slices.IndexFunc(hotkeys, func(h hotkey) bool { return h.key == key })
+	if i == -1 {
+		return hotkey{}, false
+	}
+	return hotkeys[i], true
+}
@@ -0,0 +1,72 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newIdentifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "identify",
+		Usage:     "Blinks the LED of a device, to tell which device is which",
+		ArgsUsage: "<device address>",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "duration",
+				Value: 10 * time.Second,
+				Usage: "How long the LED blinks for",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single device address")
+			}
+
+			addrPort, err := netip.ParseAddrPort(c.Args().First())
+			if err != nil {
+				addr, err := netip.ParseAddr(c.Args().First())
+				if err != nil {
+					return fmt.Errorf("invalid device address %q", c.Args().First())
+				}
+				addrPort = netip.AddrPortFrom(addr, 80)
+			}
+
+			client, err := openpsg.Connect(c.Context, addrPort)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			if err := client.Identify(c.Context, c.Duration("duration")); err != nil {
+				return err
+			}
+
+			slog.Info("Device is blinking its LED", slog.Any("deviceAddr", addrPort.Addr()), slog.Duration("duration", c.Duration("duration")))
+
+			return nil
+		},
+	}
+}
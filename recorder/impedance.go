@@ -0,0 +1,170 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/urfave/cli/v2"
+)
+
+func newImpedanceCommand(logLevel *slog.Level) *cli.Command {
+	return &cli.Command{
+		Name:      "impedance",
+		Usage:     "Shows the electrode impedances of devices, refreshing them while the patient is hooked up",
+		ArgsUsage: "<device address>...",
+		Flags: []cli.Flag{
+			&cli.Float64Flag{
+				Name:  "max-impedance",
+				Value: openpsg.DefaultImpedanceThreshold,
+				Usage: "Highest acceptable electrode impedance in ohms",
+			},
+			noTUIFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return fmt.Errorf("expected at least one device address")
+			}
+
+			var deviceAddrs []netip.AddrPort
+			for _, arg := range c.Args().Slice() {
+				addrPort, err := netip.ParseAddrPort(arg)
+				if err != nil {
+					addr, err := netip.ParseAddr(arg)
+					if err != nil {
+						return fmt.Errorf("invalid device address %q", arg)
+					}
+					addrPort = netip.AddrPortFrom(addr, 80)
+				}
+				deviceAddrs = append(deviceAddrs, addrPort)
+			}
+
+			var view openpsg.ImpedanceView
+			if useTUI(c) {
+				impedanceView := newImpedanceView(c.Float64("max-impedance"), *logLevel)
+				defer impedanceView.Close()
+				view = impedanceView
+			}
+
+			return openpsg.MonitorImpedance(c.Context, deviceAddrs, c.Float64("max-impedance"), view)
+		},
+	}
+}
+
+// impedanceView shows the electrode impedances full screen, until Enter or q
+// is pressed.
+type impedanceView struct {
+	*screen
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newImpedanceView(threshold float64, level slog.Level) *impedanceView {
+	v := &impedanceView{done: make(chan struct{})}
+	v.screen = newScreen(&impedanceModel{impedanceView: v, threshold: threshold}, level, v.finish)
+	return v
+}
+
+// Show implements openpsg.ImpedanceView.
+func (v *impedanceView) Show(readings []openpsg.ImpedanceReading) {
+	v.program.Send(impedancesMsg(readings))
+}
+
+// Done implements openpsg.ImpedanceView.
+func (v *impedanceView) Done() <-chan struct{} {
+	return v.done
+}
+
+// finish stops checking impedances.
+func (v *impedanceView) finish() {
+	v.doneOnce.Do(func() { close(v.done) })
+}
+
+type impedancesMsg []openpsg.ImpedanceReading
+
+type impedanceModel struct {
+	*impedanceView
+	threshold float64
+
+	width    int
+	readings []openpsg.ImpedanceReading
+	measured bool
+}
+
+func (m *impedanceModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *impedanceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case impedancesMsg:
+		m.readings, m.measured = msg, true
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter", "q", "ctrl+c":
+			m.finish()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *impedanceModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("OpenPSG Recorder — Electrode Impedances"))
+	fmt.Fprintf(&b, "  Highest acceptable %s\n\n", openpsg.FormatImpedance(m.threshold))
+
+	rows := [][]string{{"Device", "Signal", "Impedance", "Status"}}
+	for _, reading := range m.readings {
+		status := "OK"
+		if reading.Impedance > m.threshold {
+			status = "High"
+		}
+		rows = append(rows, []string{reading.Device.String(), reading.Signal, openpsg.FormatImpedance(reading.Impedance), status})
+	}
+
+	writeColumns(&b, rows, m.width, func(row, col int, cell string) string {
+		if col != 3 {
+			return cell
+		}
+		if m.readings[row-1].Impedance > m.threshold {
+			return problemStyle.Render(cell)
+		}
+		return okStyle.Render(cell)
+	})
+	switch {
+	case !m.measured:
+		b.WriteString(dimStyle.Render("Measuring electrode impedances ...") + "\n")
+	case len(m.readings) == 0:
+		b.WriteString(dimStyle.Render("No devices measured electrode impedances") + "\n")
+	}
+
+	b.WriteString("\n" + dimStyle.Render("enter or q stop"))
+
+	return b.String()
+}
@@ -0,0 +1,132 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+func newInspectCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "inspect",
+		Usage:     "Prints the header of an EDF recording and any problems found in it",
+		ArgsUsage: "<recording.edf>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording to inspect")
+			}
+
+			f, err := os.Open(c.Args().First())
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			in, err := edfplus.Inspect(f)
+			if err != nil {
+				return fmt.Errorf("failed to inspect recording: %w", err)
+			}
+
+			printInspection(os.Stdout, in)
+
+			return nil
+		},
+	}
+}
+
+// printInspection prints the header fields, signals and anomalies of an EDF
+// file.
+func printInspection(w io.Writer, in *edfplus.Inspection) {
+	hdr := in.Header
+
+	format := hdr.Reserved
+	if format == "" {
+		format = "EDF"
+	}
+
+	declared := strconv.Itoa(hdr.DataRecords)
+	if hdr.DataRecords == -1 {
+		declared = "unknown"
+	}
+
+	fields := tablewriter.NewWriter(w)
+	fields.SetBorder(false)
+	fields.SetColumnSeparator(":")
+	fields.SetAlignment(tablewriter.ALIGN_LEFT)
+	fields.SetAutoWrapText(false)
+	fields.AppendBulk([][]string{
+		{"Format", format},
+		{"Patient", hdr.PatientID},
+		{"Recording", hdr.RecordingID},
+		{"Start Time", hdr.StartTime.Format(time.DateTime)},
+		{"Header Bytes", strconv.Itoa(hdr.HeaderBytes)},
+		{"Data Records", fmt.Sprintf("%s (%d in file)", declared, in.CompleteRecords)},
+		{"Record Duration", hdr.DataRecordDuration.String()},
+		{"Record Bytes", strconv.Itoa(in.RecordSize)},
+		{"Duration", (time.Duration(in.CompleteRecords) * hdr.DataRecordDuration).String()},
+		{"Signals", strconv.Itoa(hdr.SignalCount)},
+	})
+	fields.Render()
+
+	fmt.Fprintln(w)
+
+	signals := tablewriter.NewWriter(w)
+	signals.SetHeader([]string{"#", "Label", "Transducer", "Unit", "Physical Range", "Digital Range", "Samples", "Rate", "Prefiltering"})
+	signals.SetBorder(false)
+
+	for i, signal := range hdr.Signals {
+		rate := ""
+		if signal.Label != edfplus.AnnotationsLabel && hdr.DataRecordDuration > 0 {
+			rate = fmt.Sprintf("%.4g Hz", float64(signal.SamplesPerRecord)/hdr.DataRecordDuration.Seconds())
+		}
+
+		signals.Append([]string{
+			strconv.Itoa(i),
+			signal.Label,
+			signal.TransducerType,
+			signal.PhysicalDimension,
+			fmt.Sprintf("%g to %g", signal.PhysicalMin, signal.PhysicalMax),
+			fmt.Sprintf("%d to %d", signal.DigitalMin, signal.DigitalMax),
+			strconv.Itoa(signal.SamplesPerRecord),
+			rate,
+			signal.Prefiltering,
+		})
+	}
+	signals.Render()
+
+	fmt.Fprintln(w)
+
+	if len(in.Anomalies) == 0 {
+		fmt.Fprintln(w, "No anomalies found.")
+		return
+	}
+
+	fmt.Fprintln(w, "Anomalies:")
+	for _, anomaly := range in.Anomalies {
+		fmt.Fprintf(w, "  - %s\n", anomaly)
+	}
+}
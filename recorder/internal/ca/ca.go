@@ -0,0 +1,247 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package ca is a tiny certificate authority, issuing the certificates of
+// devices (and the recorder) for mutual TLS connections.
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caCertFile     = "ca.pem"
+	caKeyFile      = "ca-key.pem"
+	clientCertFile = "recorder.pem"
+	clientKeyFile  = "recorder-key.pem"
+
+	caValidity     = 20 * 365 * 24 * time.Hour
+	clientValidity = 5 * 365 * 24 * time.Hour
+	// Tolerate devices with slightly slow clocks.
+	clockSkew = time.Hour
+)
+
+// CA is a certificate authority, stored in a directory.
+type CA struct {
+	cert   *x509.Certificate
+	key    crypto.Signer
+	client tls.Certificate
+}
+
+// Open opens the certificate authority stored in dir, creating it (and the
+// client certificate of the recorder) if it doesn't exist yet.
+func Open(dir string) (*CA, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create CA directory: %w", err)
+	}
+
+	cert, key, err := loadKeyPair(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		cert, key, err = newKeyPair(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile), func(key crypto.Signer) (*x509.Certificate, error) {
+			return createCertificate(&x509.Certificate{
+				Subject:               pkix.Name{CommonName: "OpenPSG Recorder CA"},
+				NotAfter:              time.Now().Add(caValidity),
+				KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+				BasicConstraintsValid: true,
+				IsCA:                  true,
+				MaxPathLenZero:        true,
+			}, nil, key.Public(), key)
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CA: %w", err)
+	}
+
+	ca := &CA{cert: cert, key: key}
+
+	clientCert, clientKey, err := loadKeyPair(filepath.Join(dir, clientCertFile), filepath.Join(dir, clientKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		clientCert, clientKey, err = newKeyPair(filepath.Join(dir, clientCertFile), filepath.Join(dir, clientKeyFile), func(key crypto.Signer) (*x509.Certificate, error) {
+			return createCertificate(&x509.Certificate{
+				Subject:     pkix.Name{CommonName: "OpenPSG Recorder"},
+				NotAfter:    time.Now().Add(clientValidity),
+				KeyUsage:    x509.KeyUsageDigitalSignature,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}, ca.cert, key.Public(), ca.key)
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recorder certificate: %w", err)
+	}
+
+	ca.client = tls.Certificate{
+		Certificate: [][]byte{clientCert.Raw},
+		PrivateKey:  clientKey,
+		Leaf:        clientCert,
+	}
+
+	return ca, nil
+}
+
+// Certificate returns the certificate of the CA.
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// ClientCertificate returns the certificate the recorder presents to devices.
+func (ca *CA) ClientCertificate() tls.Certificate {
+	return ca.client
+}
+
+// TLSConfig returns the configuration for connecting to devices with
+// certificates issued by the CA, presenting the recorder's certificate.
+func (ca *CA) TLSConfig() *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{ca.client},
+	}
+}
+
+// Issue issues a certificate for a device, for the public key in its
+// certificate signing request, valid for its IP address and hostname (if any).
+func (ca *CA) Issue(csr *x509.CertificateRequest, ip net.IP, hostname string, validity time.Duration) (*x509.Certificate, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid certificate signing request: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: ip.String()},
+		NotAfter:    time.Now().Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{ip},
+	}
+	if hostname != "" {
+		template.Subject.CommonName = hostname
+		template.DNSNames = []string{hostname}
+	}
+
+	cert, err := createCertificate(template, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a certificate (hex encoded).
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// EncodeCertificate returns a certificate PEM encoded.
+func EncodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// ParseCertificateRequest parses a PEM encoded certificate signing request.
+func ParseCertificateRequest(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("no PEM encoded certificate signing request found")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+// createCertificate creates a certificate from a template, signed by parent
+// (or self-signed if parent is nil).
+func createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-clockSkew)
+
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// newKeyPair generates a key, creates its certificate and stores both.
+func newKeyPair(certPath, keyPath string, create func(key crypto.Signer) (*x509.Certificate, error)) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	cert, err := create(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, nil, fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.WriteFile(certPath, EncodeCertificate(cert), 0o644); err != nil {
+		return nil, nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// loadKeyPair loads a certificate and its key.
+func loadKeyPair(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("unsupported private key")
+	}
+
+	return cert, key, nil
+}
@@ -0,0 +1,89 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ca_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/ca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCA(t *testing.T) {
+	dir := t.TempDir()
+
+	authority, err := ca.Open(dir)
+	require.NoError(t, err)
+
+	t.Run("Reopen", func(t *testing.T) {
+		reopened, err := ca.Open(dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, ca.Fingerprint(authority.Certificate()), ca.Fingerprint(reopened.Certificate()))
+		assert.Equal(t, authority.ClientCertificate().Certificate, reopened.ClientCertificate().Certificate)
+	})
+
+	t.Run("Issue", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "device"},
+		}, key)
+		require.NoError(t, err)
+
+		csr, err := ca.ParseCertificateRequest(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+		require.NoError(t, err)
+
+		ip := net.ParseIP("10.0.0.12")
+		cert, err := authority.Issue(csr, ip, "psg-1", 24*time.Hour)
+		require.NoError(t, err)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(authority.Certificate())
+
+		_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "10.0.0.12"})
+		assert.NoError(t, err)
+
+		_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "psg-1"})
+		assert.NoError(t, err)
+
+		_, err = authority.ClientCertificate().Leaf.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		assert.NoError(t, err)
+
+		assert.Len(t, ca.Fingerprint(cert), 64)
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		_, err := ca.ParseCertificateRequest([]byte("not a request"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,80 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package dhcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/nclient4"
+)
+
+// Lease is an address leased from a DHCP server (eg. by a simulated device).
+type Lease struct {
+	// The leased address, and the prefix of the network.
+	Prefix netip.Prefix
+
+	client *nclient4.Client
+	lease  *nclient4.Lease
+}
+
+// RequestLease requests an address from the DHCP server on the network
+// interface, as a client with the hardware address and hostname.
+func RequestLease(ctx context.Context, ifname string, mac net.HardwareAddr, hostname string) (*Lease, error) {
+	client, err := nclient4.New(ifname, nclient4.WithHWAddr(mac))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DHCP client: %w", err)
+	}
+
+	lease, err := client.Request(ctx, dhcpv4.WithOption(dhcpv4.OptHostName(hostname)))
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to request lease: %w", err)
+	}
+
+	addr, ok := netip.AddrFromSlice(lease.ACK.YourIPAddr.To4())
+	if !ok {
+		_ = client.Close()
+		return nil, fmt.Errorf("invalid leased address: %s", lease.ACK.YourIPAddr)
+	}
+
+	bits, _ := lease.ACK.SubnetMask().Size()
+	if bits == 0 {
+		bits = addr.BitLen()
+	}
+
+	return &Lease{
+		Prefix: netip.PrefixFrom(addr, bits),
+		client: client,
+		lease:  lease,
+	}, nil
+}
+
+// Release gives the address back to the DHCP server.
+func (l *Lease) Release() error {
+	defer l.client.Close()
+
+	if err := l.client.Release(l.lease); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package edfplus implements the EDF+ extensions (annotations and the
+// structured identification fields) on top of the edf header types.
+package edfplus
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+const (
+	// Continuous is the reserved header field value for an EDF+ file with
+	// contiguous data records.
+	Continuous = "EDF+C"
+	// Discontinuous is the reserved header field value for an EDF+ file
+	// that may contain gaps between data records.
+	Discontinuous = "EDF+D"
+	// AnnotationsLabel is the label of the EDF+ annotations signal.
+	AnnotationsLabel = "EDF Annotations"
+	// InvalidSample is the digital value written in place of NaN samples. To
+	// distinguish it from real values, the digital minimum of signals that may
+	// contain invalid samples should be above it.
+	InvalidSample = math.MinInt16
+)
+
+// Annotation is a timestamped event stored in the EDF+ annotations signal.
+type Annotation struct {
+	// The onset of the annotation relative to the start of the file.
+	Onset time.Duration
+	// The duration of the annotation (zero if not applicable).
+	Duration time.Duration
+	// The annotation text.
+	Text string
+}
+
+// AnnotationSignal returns the signal header of an annotations signal able
+// to hold the specified number of bytes per data record.
+func AnnotationSignal(bytesPerRecord int) edf.SignalHeader {
+	return edf.SignalHeader{
+		Label:            AnnotationsLabel,
+		PhysicalMin:      -1,
+		PhysicalMax:      1,
+		DigitalMin:       math.MinInt16,
+		DigitalMax:       math.MaxInt16,
+		SamplesPerRecord: (bytesPerRecord + 1) / 2,
+	}
+}
+
+// PatientIdentification formats the EDF+ local patient identification field.
+// Unknown subfields should be left empty and will be replaced with "X".
+func PatientIdentification(code, sex string, birthdate time.Time, name string) string {
+	birthdateStr := ""
+	if !birthdate.IsZero() {
+		birthdateStr = strings.ToUpper(birthdate.Format("02-Jan-2006"))
+	}
+
+	return strings.Join([]string{subfield(code), subfield(sex), subfield(birthdateStr), subfield(name)}, " ")
+}
+
+// Patient holds the subfields of the EDF+ local patient identification field.
+// Unknown subfields are empty (or zero).
+type Patient struct {
+	Code      string
+	Sex       string
+	Birthdate time.Time
+	Name      string
+}
+
+// ParsePatientIdentification parses the EDF+ local patient identification
+// field. It returns false if the field does not follow the EDF+ structure.
+func ParsePatientIdentification(s string) (Patient, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return Patient{}, false
+	}
+
+	unknown := func(s string) string {
+		if s == "X" {
+			return ""
+		}
+		return strings.ReplaceAll(s, "_", " ")
+	}
+
+	patient := Patient{
+		Code: unknown(fields[0]),
+		Sex:  unknown(fields[1]),
+		Name: unknown(fields[3]),
+	}
+
+	if fields[2] != "X" {
+		birthdate, err := time.Parse("02-Jan-2006", fields[2])
+		if err != nil {
+			return Patient{}, false
+		}
+		patient.Birthdate = birthdate
+	}
+
+	return patient, true
+}
+
+// RecordingIdentification formats the EDF+ local recording identification field.
+// Unknown subfields should be left empty and will be replaced with "X".
+func RecordingIdentification(start time.Time, code, technician, equipment string) string {
+	return strings.Join([]string{"Startdate", strings.ToUpper(start.Format("02-Jan-2006")),
+		subfield(code), subfield(technician), subfield(equipment)}, " ")
+}
+
+// subfield makes a string safe for use as a space separated header subfield.
+func subfield(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "X"
+	}
+
+	return strings.ReplaceAll(s, " ", "_")
+}
+
+// appendTAL appends the time-stamped annotation list encoding of the
+// annotation to the buffer.
+func appendTAL(b []byte, a Annotation) []byte {
+	b = appendOnset(b, a.Onset)
+	if a.Duration > 0 {
+		b = append(b, 0x15)
+		b = strconv.AppendFloat(b, a.Duration.Seconds(), 'f', -1, 64)
+	}
+	b = append(b, 0x14)
+	b = append(b, sanitizeAnnotationText(a.Text)...)
+	b = append(b, 0x14, 0x00)
+	return b
+}
+
+// appendTimeKeepingTAL appends the time-keeping annotation that must start
+// the annotations signal of every data record.
+func appendTimeKeepingTAL(b []byte, onset time.Duration) []byte {
+	b = appendOnset(b, onset)
+	return append(b, 0x14, 0x14, 0x00)
+}
+
+func appendOnset(b []byte, onset time.Duration) []byte {
+	if onset >= 0 {
+		b = append(b, '+')
+	}
+	return strconv.AppendFloat(b, onset.Seconds(), 'f', -1, 64)
+}
+
+// sanitizeAnnotationText removes the bytes that have special meaning in a TAL.
+func sanitizeAnnotationText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 0x00, 0x14, 0x15:
+			return ' '
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,45 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatientIdentification(t *testing.T) {
+	birthdate := time.Date(1951, time.May, 2, 0, 0, 0, 0, time.UTC)
+
+	patient, ok := edfplus.ParsePatientIdentification(edfplus.PatientIdentification("MCH-0234567", "F", birthdate, "Haagse Harry"))
+	require.True(t, ok)
+
+	assert.Equal(t, edfplus.Patient{Code: "MCH-0234567", Sex: "F", Birthdate: birthdate, Name: "Haagse Harry"}, patient)
+
+	patient, ok = edfplus.ParsePatientIdentification(edfplus.PatientIdentification("", "", time.Time{}, ""))
+	require.True(t, ok)
+
+	assert.Equal(t, edfplus.Patient{}, patient)
+
+	_, ok = edfplus.ParsePatientIdentification("Plain EDF")
+	assert.False(t, ok)
+}
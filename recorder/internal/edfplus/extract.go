@@ -0,0 +1,146 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+// Extract writes the data records of er overlapping the window [from, to)
+// (relative to the start of the file) to w as a new EDF+ file, keeping only the
+// specified ordinary signals (indices into Signals). Data records are copied
+// whole, with the samples of the records at the edges that fall outside the
+// window marked invalid (see InvalidSample), and the new file starts at the
+// second of its first data record. Annotations within the extracted data
+// records are kept.
+func Extract(w io.WriteSeeker, er *Reader, from, to time.Duration, signals []int) error {
+	if to <= from {
+		return fmt.Errorf("empty window %s to %s", from, to)
+	}
+
+	ordinary := er.Signals()
+
+	hdr := edf.Header{
+		Version:            edf.Version0,
+		PatientID:          er.hdr.PatientID,
+		DataRecordDuration: er.hdr.DataRecordDuration,
+	}
+
+	// Unless the window covers the whole file, it may cut the data records at
+	// its edges.
+	trim := from > 0 || to < math.MaxInt64
+
+	for _, i := range signals {
+		if i < 0 || i >= len(ordinary) {
+			return fmt.Errorf("invalid signal index %d", i)
+		}
+
+		signal := ordinary[i]
+		// Keep the trimmed samples distinct from real values.
+		if trim && signal.DigitalMin <= InvalidSample {
+			signal.DigitalMin = InvalidSample + 1
+		}
+		hdr.Signals = append(hdr.Signals, signal)
+	}
+
+	annotationBytes := 64
+	if er.annotationsIndex != -1 {
+		annotationBytes = max(annotationBytes, 2*er.hdr.Signals[er.annotationsIndex].SamplesPerRecord)
+	}
+	hdr.Signals = append(hdr.Signals, AnnotationSignal(annotationBytes))
+
+	var ew *Writer
+	var shift time.Duration
+
+	er.Rewind()
+	for {
+		record, err := er.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("error reading data record: %w", err)
+		}
+
+		if record.Onset+er.hdr.DataRecordDuration <= from {
+			continue
+		}
+		if record.Onset >= to {
+			break
+		}
+
+		if ew == nil {
+			hdr.StartTime = er.hdr.StartTime.Add(record.Onset).Truncate(time.Second)
+			hdr.RecordingID = withStartdate(er.hdr.RecordingID, hdr.StartTime)
+			shift = hdr.StartTime.Sub(er.hdr.StartTime)
+
+			if ew, err = Create(w, hdr); err != nil {
+				return err
+			}
+		}
+
+		for _, a := range record.Annotations {
+			if a.Onset >= shift {
+				a.Onset -= shift
+				ew.Annotate(a)
+			}
+		}
+
+		samples := make([][]float64, len(signals))
+		for j, i := range signals {
+			samples[j] = make([]float64, len(record.Samples[i]))
+			n := len(record.Samples[i])
+			for k, digital := range record.Samples[i] {
+				t := record.Onset + time.Duration(k)*er.hdr.DataRecordDuration/time.Duration(n)
+				if t < from || t >= to {
+					samples[j][k] = math.NaN()
+					continue
+				}
+				samples[j][k] = physicalSample(ordinary[i], digital)
+			}
+		}
+
+		if err := ew.WriteRecord(record.Onset-shift, samples); err != nil {
+			return fmt.Errorf("error writing data record: %w", err)
+		}
+	}
+
+	if ew == nil {
+		return fmt.Errorf("no data records within the window")
+	}
+
+	return ew.Close()
+}
+
+// withStartdate replaces the start date in an EDF+ recording identification.
+func withStartdate(recordingID string, start time.Time) string {
+	fields := strings.Fields(recordingID)
+	if len(fields) < 2 || fields[0] != "Startdate" {
+		return recordingID
+	}
+
+	fields[1] = strings.ToUpper(start.Format("02-Jan-2006"))
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,135 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	startTime := time.Date(2025, time.February, 3, 23, 59, 59, 0, time.Local)
+
+	signal := func(label string) edf.SignalHeader {
+		return edf.SignalHeader{
+			Label:            label,
+			PhysicalMin:      -100,
+			PhysicalMax:      100,
+			DigitalMin:       math.MinInt16,
+			DigitalMax:       math.MaxInt16,
+			SamplesPerRecord: 4,
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		RecordingID:        edfplus.RecordingIdentification(startTime, "", "", ""),
+		StartTime:          startTime,
+		DataRecordDuration: time.Second,
+		Signals:            []edf.SignalHeader{signal("Nasal Pressure"), signal("SpO2"), edfplus.AnnotationSignal(64)},
+	})
+	require.NoError(t, err)
+
+	ew.Annotate(edfplus.Annotation{Onset: 500 * time.Millisecond, Text: "Lights off"})
+	for i := range 5 {
+		if i == 2 {
+			ew.Annotate(edfplus.Annotation{Onset: 2500 * time.Millisecond, Duration: 10 * time.Second, Text: "Obstructive apnea"})
+		}
+
+		v := float64(i)
+		require.NoError(t, ew.WriteRecord(time.Duration(i)*time.Second, [][]float64{{v, v, v, v}, {-v, -v, -v, -v}}))
+	}
+	require.NoError(t, ew.Close())
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	out, err := os.Create(filepath.Join(dir, "extract.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, out.Close())
+	})
+
+	require.NoError(t, edfplus.Extract(out, er, 1500*time.Millisecond, 3200*time.Millisecond, []int{1}))
+
+	_, err = out.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err = edfplus.Open(out)
+	require.NoError(t, err)
+
+	hdr := er.Header()
+	assert.True(t, startTime.Add(time.Second).Equal(hdr.StartTime))
+	assert.Equal(t, "Startdate 04-FEB-2025 X X X", hdr.RecordingID)
+	assert.Equal(t, 3, hdr.DataRecords)
+
+	signals := er.Signals()
+	require.Len(t, signals, 1)
+	assert.Equal(t, "SpO2", signals[0].Label)
+
+	var annotations []edfplus.Annotation
+	for i := range 3 {
+		record, err := er.ReadRecord()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(i)*time.Second, record.Onset)
+
+		for k, digital := range record.Samples[0] {
+			// Samples outside the window (0.5s to 2.2s into the new file) are trimmed.
+			offset := time.Duration(i)*time.Second + time.Duration(k)*250*time.Millisecond
+			if offset < 500*time.Millisecond || offset >= 2200*time.Millisecond {
+				assert.Equal(t, int16(edfplus.InvalidSample), digital)
+				continue
+			}
+			assert.InDelta(t, -float64(i+1), edfplus.DigitalToPhysical(signals[0], digital), 0.01)
+		}
+
+		annotations = append(annotations, record.Annotations...)
+	}
+
+	assert.Equal(t, []edfplus.Annotation{
+		{Onset: 1500 * time.Millisecond, Duration: 10 * time.Second, Text: "Obstructive apnea"},
+	}, annotations)
+
+	empty, err := os.Create(filepath.Join(dir, "empty.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, empty.Close())
+	})
+
+	assert.Error(t, edfplus.Extract(empty, er, 10*time.Second, 20*time.Second, []int{0}))
+}
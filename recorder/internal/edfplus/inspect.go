@@ -0,0 +1,122 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/OpenPSG/edf"
+)
+
+// Inspection describes the structure of an EDF file.
+type Inspection struct {
+	// The header of the file.
+	Header edf.Header
+	// The size of a data record in bytes.
+	RecordSize int
+	// The number of complete data records in the file.
+	CompleteRecords int
+	// The number of bytes of a truncated trailing data record.
+	TrailingBytes int
+	// Whether the file is EDF+ but has no annotations signal.
+	MissingAnnotations bool
+	// Problems found in the file.
+	Anomalies []string
+}
+
+// Inspect reads the header of an EDF file and checks it against the size of
+// the file, without reading the data records.
+func Inspect(f *os.File) (*Inspection, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking to header: %w", err)
+	}
+
+	hdr, err := ReadHeader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error getting file size: %w", err)
+	}
+
+	in := &Inspection{Header: *hdr}
+
+	annotationsIndex := -1
+	for i, signal := range hdr.Signals {
+		if signal.Label == AnnotationsLabel && annotationsIndex == -1 {
+			annotationsIndex = i
+		}
+		in.RecordSize += 2 * signal.SamplesPerRecord
+
+		if signal.SamplesPerRecord <= 0 {
+			in.anomalyf("signal %d (%s) has no samples per data record", i, signal.Label)
+		}
+		if signal.DigitalMin >= signal.DigitalMax {
+			in.anomalyf("signal %d (%s) has an invalid digital range [%d, %d]", i, signal.Label, signal.DigitalMin, signal.DigitalMax)
+		}
+		if signal.PhysicalMin == signal.PhysicalMax {
+			in.anomalyf("signal %d (%s) has an empty physical range", i, signal.Label)
+		}
+	}
+
+	if strings.HasPrefix(hdr.Reserved, "EDF+") && annotationsIndex == -1 {
+		in.MissingAnnotations = true
+		in.anomalyf("%s file has no annotations signal", hdr.Reserved)
+	}
+
+	if hdr.DataRecordDuration <= 0 {
+		in.anomalyf("data record duration is %s", hdr.DataRecordDuration)
+	}
+
+	if in.RecordSize <= 0 {
+		in.anomalyf("data records are empty")
+		return in, nil
+	}
+
+	dataBytes := fi.Size() - int64(hdr.HeaderBytes)
+	if dataBytes < 0 {
+		in.anomalyf("file is smaller than its header (%d of %d bytes)", fi.Size(), hdr.HeaderBytes)
+		return in, nil
+	}
+
+	in.CompleteRecords = int(dataBytes / int64(in.RecordSize))
+	in.TrailingBytes = int(dataBytes % int64(in.RecordSize))
+
+	switch {
+	case hdr.DataRecords == -1:
+		in.anomalyf("number of data records is unknown (-1), the file was not closed by its writer")
+	case hdr.DataRecords != in.CompleteRecords:
+		in.anomalyf("header declares %d data records but the file contains %d", hdr.DataRecords, in.CompleteRecords)
+	}
+
+	if in.TrailingBytes > 0 {
+		in.anomalyf("last data record is truncated (%d of %d bytes)", in.TrailingBytes, in.RecordSize)
+	}
+
+	return in, nil
+}
+
+func (in *Inspection) anomalyf(format string, args ...any) {
+	in.Anomalies = append(in.Anomalies, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,92 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now(),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 4,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{0, 0, 0, 0}}))
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{0, 0, 0, 0}}))
+	require.NoError(t, ew.Close())
+
+	in, err := edfplus.Inspect(f)
+	require.NoError(t, err)
+	assert.Equal(t, 2*(4+8), in.RecordSize)
+	assert.Equal(t, 2, in.CompleteRecords)
+	assert.Equal(t, 0, in.TrailingBytes)
+	assert.Empty(t, in.Anomalies)
+
+	// Simulate a crash part way through writing the third data record.
+	_, err = f.Seek(0, 2)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	in, err = edfplus.Inspect(f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, in.CompleteRecords)
+	assert.Equal(t, 3, in.TrailingBytes)
+	assert.Equal(t, []string{"last data record is truncated (3 of 24 bytes)"}, in.Anomalies)
+
+	// Declare more data records than the file contains.
+	_, err = f.WriteAt([]byte("5       "), 236)
+	require.NoError(t, err)
+
+	in, err = edfplus.Inspect(f)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"header declares 5 data records but the file contains 2",
+		"last data record is truncated (3 of 24 bytes)",
+	}, in.Anomalies)
+}
@@ -0,0 +1,104 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLabelLength is the width of the label field of an EDF signal header.
+const maxLabelLength = 16
+
+// signalTypes are the EDF+ standard texts for the type of a signal, the first
+// word of its label (eg. "EEG Fpz-Cz").
+var signalTypes = []string{
+	"EEG", "ECG", "EOG", "ERG", "EMG", "MEG", "MCG", "EP",
+	"Temp", "Resp", "SaO2", "Light", "Sound", "Event",
+}
+
+// electrodes are the electrode names of the 10-10 (and so 10-20) system, along
+// with the older 10-20 temporal names, mastoid/earlobe references and the AASM
+// EOG electrodes.
+var electrodes = []string{
+	"Nz", "Fp1", "Fpz", "Fp2",
+	"AF7", "AF3", "AFz", "AF4", "AF8",
+	"F9", "F7", "F5", "F3", "F1", "Fz", "F2", "F4", "F6", "F8", "F10",
+	"FT9", "FT7", "FC5", "FC3", "FC1", "FCz", "FC2", "FC4", "FC6", "FT8", "FT10",
+	"T9", "T7", "C5", "C3", "C1", "Cz", "C2", "C4", "C6", "T8", "T10",
+	"TP9", "TP7", "CP5", "CP3", "CP1", "CPz", "CP2", "CP4", "CP6", "TP8", "TP10",
+	"P9", "P7", "P5", "P3", "P1", "Pz", "P2", "P4", "P6", "P8", "P10",
+	"PO7", "PO3", "POz", "PO4", "PO8",
+	"O1", "Oz", "O2", "Iz",
+	"T3", "T4", "T5", "T6",
+	"A1", "A2", "M1", "M2",
+	"E1", "E2",
+}
+
+// NormalizeLabel checks a signal label against the EDF+ standard texts and,
+// for EEG and EOG signals, the 10-20 electrode names. It returns the label with
+// the case and spacing of the standard texts (eg. "eeg c4-m1" becomes
+// "EEG C4-M1"), and a description of any problems that normalizing couldn't
+// fix.
+func NormalizeLabel(label string) (string, []string) {
+	var problems []string
+
+	label = strings.Join(strings.Fields(label), " ")
+
+	signalType, specification, _ := strings.Cut(label, " ")
+	if canonical, ok := lookupFold(signalTypes, signalType); ok {
+		signalType = canonical
+	} else {
+		problems = append(problems, fmt.Sprintf("%q is not a standard signal type (eg. EEG, ECG, Resp)", signalType))
+	}
+
+	if (signalType == "EEG" || signalType == "EOG") && specification != "" {
+		parts := strings.Split(specification, "-")
+		for i, part := range parts {
+			if canonical, ok := lookupFold(electrodes, part); ok {
+				parts[i] = canonical
+			} else {
+				problems = append(problems, fmt.Sprintf("%q is not a 10-20 electrode name", part))
+			}
+		}
+		specification = strings.Join(parts, "-")
+	}
+
+	if specification != "" {
+		label = signalType + " " + specification
+	} else {
+		label = signalType
+	}
+
+	if len(label) > maxLabelLength {
+		problems = append(problems, fmt.Sprintf("label is longer than %d characters", maxLabelLength))
+	}
+
+	return label, problems
+}
+
+// lookupFold returns the name in names equal to s under case folding.
+func lookupFold(names []string, s string) (string, bool) {
+	for _, name := range names {
+		if strings.EqualFold(name, s) {
+			return name, true
+		}
+	}
+	return s, false
+}
@@ -0,0 +1,56 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLabel(t *testing.T) {
+	t.Run("Standard", func(t *testing.T) {
+		label, problems := edfplus.NormalizeLabel("EEG C4-M1")
+		assert.Equal(t, "EEG C4-M1", label)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("Normalized", func(t *testing.T) {
+		label, problems := edfplus.NormalizeLabel("  eeg  fp1-CZ ")
+		assert.Equal(t, "EEG Fp1-Cz", label)
+		assert.Empty(t, problems)
+
+		label, problems = edfplus.NormalizeLabel("resp nasal pressure")
+		assert.Equal(t, "Resp nasal pressure", label)
+		assert.NotEmpty(t, problems, "longer than 16 characters")
+	})
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		label, problems := edfplus.NormalizeLabel("Nasal Pressure")
+		assert.Equal(t, "Nasal Pressure", label)
+		assert.Len(t, problems, 1)
+	})
+
+	t.Run("Unknown Electrode", func(t *testing.T) {
+		label, problems := edfplus.NormalizeLabel("EEG C4-X9")
+		assert.Equal(t, "EEG C4-X9", label)
+		assert.Len(t, problems, 1)
+	})
+}
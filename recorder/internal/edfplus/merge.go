@@ -0,0 +1,288 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+// mergeInput is an EDF file being merged.
+type mergeInput struct {
+	er *Reader
+	// The offset of the start of the file from the start of the merged file.
+	offset time.Duration
+	// The onset of each data record relative to the start of the merged file.
+	onsets []time.Duration
+	// The ordinary signals of the file, and the output signal each is stored
+	// as.
+	signals     []edf.SignalHeader
+	outputIndex []int
+	// Data records read while filling the current data record of the merged
+	// file, by index.
+	cache map[int]*Record
+}
+
+// Merge combines the data records of several EDF files into a single
+// discontinuous EDF+ file, aligning them by their start times. This joins
+// recordings that were split by time, or by signal, or that were made by
+// different recorders during the same study.
+//
+// Signals with the same label are treated as the same signal, and must have
+// the same parameters. Where files overlap, samples are taken from the first
+// file that has them, and samples that no file has are written as invalid
+// (signals using the whole digital range are rescaled to leave the invalid
+// marker outside of it). Annotations are combined, with duplicates removed.
+func Merge(w io.WriteSeeker, inputs []*Reader) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no files to merge")
+	}
+
+	recordDuration := inputs[0].hdr.DataRecordDuration
+	if recordDuration <= 0 {
+		return fmt.Errorf("invalid data record duration %s", recordDuration)
+	}
+
+	// The merged file starts with the earliest file.
+	first := inputs[0].hdr
+	for _, er := range inputs[1:] {
+		if er.hdr.DataRecordDuration != recordDuration {
+			return fmt.Errorf("data record durations differ (%s and %s)", recordDuration, er.hdr.DataRecordDuration)
+		}
+		if er.hdr.PatientID != first.PatientID {
+			return fmt.Errorf("files are of different patients (%q and %q)", first.PatientID, er.hdr.PatientID)
+		}
+		if er.hdr.StartTime.Before(first.StartTime) {
+			first = er.hdr
+		}
+	}
+
+	hdr := edf.Header{
+		Version:            edf.Version0,
+		PatientID:          first.PatientID,
+		RecordingID:        first.RecordingID,
+		StartTime:          first.StartTime,
+		DataRecordDuration: recordDuration,
+		Reserved:           Discontinuous,
+	}
+
+	// The parameters of each output signal, as in the files.
+	var inputSignals []edf.SignalHeader
+	var annotationBytes int
+	var annotations []Annotation
+	mis := make([]*mergeInput, len(inputs))
+	for n, er := range inputs {
+		mi := &mergeInput{
+			er:     er,
+			offset: er.hdr.StartTime.Sub(hdr.StartTime),
+			cache:  make(map[int]*Record),
+		}
+
+		mi.signals = er.Signals()
+		for _, signal := range mi.signals {
+			i := slices.IndexFunc(inputSignals, func(s edf.SignalHeader) bool {
+				return s.Label == signal.Label
+			})
+			if i == -1 {
+				inputSignals = append(inputSignals, signal)
+				i = len(inputSignals) - 1
+			} else if !sameSignalParameters(inputSignals[i], signal) {
+				return fmt.Errorf("signal %q differs between files", signal.Label)
+			}
+			mi.outputIndex = append(mi.outputIndex, i)
+		}
+
+		if er.annotationsIndex != -1 {
+			annotationBytes += 2 * er.hdr.Signals[er.annotationsIndex].SamplesPerRecord
+		}
+
+		er.Rewind()
+		for {
+			record, err := er.ReadRecord()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("error reading data record %d of file %d: %w", len(mi.onsets), n+1, err)
+			}
+
+			if len(mi.onsets) > 0 && mi.offset+record.Onset < mi.onsets[len(mi.onsets)-1]+recordDuration {
+				return fmt.Errorf("data record %d of file %d overlaps the previous data record", len(mi.onsets), n+1)
+			}
+			mi.onsets = append(mi.onsets, mi.offset+record.Onset)
+
+			for _, a := range record.Annotations {
+				a.Onset += mi.offset
+				if !slices.Contains(annotations, a) {
+					annotations = append(annotations, a)
+				}
+			}
+		}
+
+		mis[n] = mi
+	}
+
+	// Keep the invalid marker written in place of missing samples outside of
+	// the digital range, so it isn't read back as the physical minimum.
+	for _, signal := range inputSignals {
+		if signal.DigitalMin <= InvalidSample {
+			signal.DigitalMin = InvalidSample + 1
+		}
+		hdr.Signals = append(hdr.Signals, signal)
+	}
+
+	// Enough room for the annotations of every file, plus the time-keeping
+	// annotation.
+	hdr.Signals = append(hdr.Signals, AnnotationSignal(max(annotationBytes, 64)))
+
+	slices.SortStableFunc(annotations, func(a, b Annotation) int {
+		return cmp.Compare(a.Onset, b.Onset)
+	})
+
+	// Data records of the merged file are aligned to the first data record of
+	// any of the files, and written wherever any file has data.
+	var base time.Duration
+	for i, mi := range mis {
+		if len(mi.onsets) > 0 && (i == 0 || mi.onsets[0] < base) {
+			base = mi.onsets[0]
+		}
+	}
+
+	var slots []int
+	for _, mi := range mis {
+		for _, onset := range mi.onsets {
+			firstSlot := int((onset - base) / recordDuration)
+			lastSlot := int((onset + recordDuration - 1 - base) / recordDuration)
+			for slot := firstSlot; slot <= lastSlot; slot++ {
+				slots = append(slots, slot)
+			}
+		}
+	}
+	slices.Sort(slots)
+	slots = slices.Compact(slots)
+
+	ew, err := Create(w, hdr)
+	if err != nil {
+		return err
+	}
+
+	signals := make([][]float64, len(hdr.Signals)-1)
+	for i := range signals {
+		signals[i] = make([]float64, hdr.Signals[i].SamplesPerRecord)
+	}
+
+	for _, slot := range slots {
+		onset := base + time.Duration(slot)*recordDuration
+
+		for i := range signals {
+			for j := range signals[i] {
+				signals[i][j] = math.NaN()
+			}
+		}
+
+		for n := range mis {
+			if err := mis[n].fill(signals, onset, recordDuration); err != nil {
+				return fmt.Errorf("error reading file %d: %w", n+1, err)
+			}
+		}
+
+		for len(annotations) > 0 && annotations[0].Onset < onset+recordDuration {
+			ew.Annotate(annotations[0])
+			annotations = annotations[1:]
+		}
+
+		if err := ew.WriteRecord(onset, signals); err != nil {
+			return fmt.Errorf("error writing data record: %w", err)
+		}
+	}
+
+	// Annotations after the last data record.
+	for _, a := range annotations {
+		ew.Annotate(a)
+	}
+
+	return ew.Close()
+}
+
+// fill copies the samples of the file falling within the data record of the
+// merged file starting at onset into signals, where they haven't already been
+// filled by an earlier file. Each sample of the merged file is taken from the
+// nearest sample of the file.
+func (mi *mergeInput) fill(signals [][]float64, onset, recordDuration time.Duration) error {
+	// Drop the data records that end before this one.
+	for index := range mi.cache {
+		if mi.onsets[index]+recordDuration <= onset {
+			delete(mi.cache, index)
+		}
+	}
+
+	for i, out := range mi.outputIndex {
+		signal := mi.signals[i]
+		samplesPerRecord := signal.SamplesPerRecord
+		halfSample := recordDuration / time.Duration(2*samplesPerRecord)
+
+		for j := range signals[out] {
+			if !math.IsNaN(signals[out][j]) {
+				continue
+			}
+
+			t := onset + time.Duration(int64(j)*int64(recordDuration)/int64(samplesPerRecord)) + halfSample
+
+			index := sort.Search(len(mi.onsets), func(k int) bool { return mi.onsets[k] > t }) - 1
+			if index < 0 {
+				continue
+			}
+
+			k := int(int64(t-mi.onsets[index]) * int64(samplesPerRecord) / int64(recordDuration))
+			if k >= samplesPerRecord {
+				continue // In a gap.
+			}
+
+			record, ok := mi.cache[index]
+			if !ok {
+				mi.er.Seek(index)
+				var err error
+				if record, err = mi.er.ReadRecord(); err != nil {
+					return fmt.Errorf("error reading data record %d: %w", index, err)
+				}
+				mi.cache[index] = record
+			}
+
+			signals[out][j] = physicalSample(signal, record.Samples[i][k])
+		}
+	}
+
+	return nil
+}
+
+// sameSignalParameters returns whether two signal headers describe the same
+// signal.
+func sameSignalParameters(a, b edf.SignalHeader) bool {
+	return a.PhysicalDimension == b.PhysicalDimension &&
+		a.PhysicalMin == b.PhysicalMin && a.PhysicalMax == b.PhysicalMax &&
+		a.DigitalMin == b.DigitalMin && a.DigitalMax == b.DigitalMax &&
+		a.SamplesPerRecord == b.SamplesPerRecord
+}
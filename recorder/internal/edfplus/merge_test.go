@@ -0,0 +1,244 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	startTime := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local)
+
+	signal := func(label string) edf.SignalHeader {
+		return edf.SignalHeader{
+			Label:            label,
+			PhysicalMin:      -100,
+			PhysicalMax:      100,
+			DigitalMin:       math.MinInt16 + 1,
+			DigitalMax:       math.MaxInt16,
+			SamplesPerRecord: 4,
+		}
+	}
+
+	type record struct {
+		onset   time.Duration
+		samples []float64
+	}
+
+	create := func(name string, startTime time.Time, label string, records []record, annotations ...edfplus.Annotation) *edfplus.Reader {
+		f, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, f.Close())
+		})
+
+		ew, err := edfplus.Create(f, edf.Header{
+			Version:            edf.Version0,
+			PatientID:          "X X X X",
+			StartTime:          startTime,
+			DataRecordDuration: time.Second,
+			Signals:            []edf.SignalHeader{signal(label), edfplus.AnnotationSignal(64)},
+		})
+		require.NoError(t, err)
+
+		for _, a := range annotations {
+			ew.Annotate(a)
+		}
+
+		for _, r := range records {
+			require.NoError(t, ew.WriteRecord(r.onset, [][]float64{r.samples}))
+		}
+		require.NoError(t, ew.Close())
+
+		_, err = f.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+
+		er, err := edfplus.Open(f)
+		require.NoError(t, err)
+
+		return er
+	}
+
+	lightsOff := edfplus.Annotation{Onset: 500 * time.Millisecond, Text: "Lights off"}
+
+	inputs := []*edfplus.Reader{
+		create("first.edf", startTime, "Nasal Pressure", []record{
+			{0, []float64{1, 1, 1, 1}},
+			{time.Second, []float64{2, 2, 2, 2}},
+		}, lightsOff),
+		// Overlaps the first file, and continues after a gap.
+		create("second.edf", startTime.Add(time.Second), "Nasal Pressure", []record{
+			{0, []float64{20, 20, 20, 20}},
+			{time.Second, []float64{30, 30, 30, 30}},
+			{3 * time.Second, []float64{50, 50, 50, 50}},
+		}),
+		// Recorded by another recorder at the same time, starting half way
+		// through a data record.
+		create("other.edf", startTime, "SpO2", []record{
+			{500 * time.Millisecond, []float64{1, 2, 3, 4}},
+			{1500 * time.Millisecond, []float64{5, 6, 7, 8}},
+		}, lightsOff),
+	}
+
+	f, err := os.Create(filepath.Join(dir, "merged.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	require.NoError(t, edfplus.Merge(f, inputs))
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	hdr := er.Header()
+	assert.Equal(t, edfplus.Discontinuous, hdr.Reserved)
+	assert.True(t, startTime.Equal(hdr.StartTime))
+	assert.Equal(t, 4, hdr.DataRecords)
+
+	signals := er.Signals()
+	require.Len(t, signals, 2)
+	assert.Equal(t, "Nasal Pressure", signals[0].Label)
+	assert.Equal(t, "SpO2", signals[1].Label)
+
+	nan := math.NaN()
+	expected := []struct {
+		onset    time.Duration
+		pressure []float64
+		spo2     []float64
+	}{
+		{0, []float64{1, 1, 1, 1}, []float64{nan, nan, 1, 2}},
+		{time.Second, []float64{2, 2, 2, 2}, []float64{3, 4, 5, 6}},
+		{2 * time.Second, []float64{30, 30, 30, 30}, []float64{7, 8, nan, nan}},
+		{4 * time.Second, []float64{50, 50, 50, 50}, []float64{nan, nan, nan, nan}},
+	}
+
+	var annotations []edfplus.Annotation
+	for _, e := range expected {
+		record, err := er.ReadRecord()
+		require.NoError(t, err)
+		assert.Equal(t, e.onset, record.Onset)
+
+		for i, want := range [][]float64{e.pressure, e.spo2} {
+			for j, digital := range record.Samples[i] {
+				if math.IsNaN(want[j]) {
+					assert.Equal(t, int16(edfplus.InvalidSample), digital)
+				} else {
+					assert.InDelta(t, want[j], edfplus.DigitalToPhysical(signals[i], digital), 0.01)
+				}
+			}
+		}
+
+		annotations = append(annotations, record.Annotations...)
+	}
+
+	_, err = er.ReadRecord()
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Equal(t, []edfplus.Annotation{lightsOff}, annotations)
+}
+
+func TestMergeFullDigitalRange(t *testing.T) {
+	dir := t.TempDir()
+	startTime := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local)
+
+	create := func(name, label string, onset time.Duration) *edfplus.Reader {
+		f, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, f.Close())
+		})
+
+		ew, err := edfplus.Create(f, edf.Header{
+			Version:            edf.Version0,
+			PatientID:          "X X X X",
+			StartTime:          startTime,
+			DataRecordDuration: time.Second,
+			Signals: []edf.SignalHeader{{
+				Label:            label,
+				PhysicalMin:      -5,
+				PhysicalMax:      5,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 2,
+			}, edfplus.AnnotationSignal(64)},
+		})
+		require.NoError(t, err)
+		require.NoError(t, ew.WriteRecord(onset, [][]float64{{-5, 5}}))
+		require.NoError(t, ew.Close())
+
+		_, err = f.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+
+		er, err := edfplus.Open(f)
+		require.NoError(t, err)
+		return er
+	}
+
+	inputs := []*edfplus.Reader{
+		create("ecg.edf", "ECG", 0),
+		create("spo2.edf", "SpO2", time.Second),
+	}
+
+	f, err := os.Create(filepath.Join(dir, "merged.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	require.NoError(t, edfplus.Merge(f, inputs))
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	// The signals are rescaled to leave the invalid marker outside of their
+	// digital range.
+	signals := er.Signals()
+	require.Len(t, signals, 2)
+	for _, signal := range signals {
+		assert.Equal(t, edfplus.InvalidSample+1, signal.DigitalMin)
+	}
+
+	first, err := er.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, []int16{edfplus.InvalidSample + 1, math.MaxInt16}, first.Samples[0])
+	assert.Equal(t, []int16{edfplus.InvalidSample, edfplus.InvalidSample}, first.Samples[1])
+
+	second, err := er.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, []int16{edfplus.InvalidSample, edfplus.InvalidSample}, second.Samples[0])
+	assert.Equal(t, []int16{edfplus.InvalidSample + 1, math.MaxInt16}, second.Samples[1])
+}
@@ -0,0 +1,381 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+// Record is a data record read from an EDF or EDF+ file.
+type Record struct {
+	// The onset of the data record relative to the start of the file.
+	Onset time.Duration
+	// The digital samples of each ordinary (non-annotation) signal.
+	Samples [][]int16
+	// The annotations stored in the data record.
+	Annotations []Annotation
+}
+
+// Reader reads EDF and EDF+ files.
+type Reader struct {
+	r                io.ReadSeeker
+	hdr              edf.Header
+	annotationsIndex int // Index of the annotations signal, -1 if none.
+	recordSize       int // Size of a data record in bytes.
+	record           int // Index of the next data record to read.
+	buf              []byte
+}
+
+// Open reads the header from r and returns a reader for the data records.
+func Open(r io.ReadSeeker) (*Reader, error) {
+	hdr, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	er := &Reader{
+		r:                r,
+		hdr:              *hdr,
+		annotationsIndex: -1,
+	}
+
+	for i, signal := range hdr.Signals {
+		if signal.Label == AnnotationsLabel && er.annotationsIndex == -1 {
+			er.annotationsIndex = i
+		}
+		er.recordSize += 2 * signal.SamplesPerRecord
+	}
+
+	if er.recordSize == 0 {
+		return nil, fmt.Errorf("data records are empty")
+	}
+
+	return er, nil
+}
+
+// Header returns the header of the file.
+func (er *Reader) Header() edf.Header {
+	return er.hdr
+}
+
+// Signals returns the headers of the ordinary (non-annotation) signals.
+func (er *Reader) Signals() []edf.SignalHeader {
+	var signals []edf.SignalHeader
+	for i, signal := range er.hdr.Signals {
+		if i != er.annotationsIndex {
+			signals = append(signals, signal)
+		}
+	}
+	return signals
+}
+
+// RecordSize returns the size of a data record in bytes.
+func (er *Reader) RecordSize() int {
+	return er.recordSize
+}
+
+// Rewind resets the reader to the first data record.
+func (er *Reader) Rewind() {
+	er.record = 0
+}
+
+// Seek sets the index of the next data record to read.
+func (er *Reader) Seek(record int) {
+	er.record = record
+}
+
+// ReadRecord reads the next data record. It returns io.EOF when there are no
+// more data records, and io.ErrUnexpectedEOF if the last data record is
+// truncated.
+func (er *Reader) ReadRecord() (*Record, error) {
+	if er.hdr.DataRecords >= 0 && er.record >= er.hdr.DataRecords {
+		return nil, io.EOF
+	}
+
+	offset := int64(er.hdr.HeaderBytes) + int64(er.record)*int64(er.recordSize)
+	if _, err := er.r.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking to data record: %w", err)
+	}
+
+	if cap(er.buf) < er.recordSize {
+		er.buf = make([]byte, er.recordSize)
+	}
+	buf := er.buf[:er.recordSize]
+
+	if _, err := io.ReadFull(er.r, buf); err != nil {
+		return nil, err
+	}
+
+	record := &Record{
+		Onset: time.Duration(er.record) * er.hdr.DataRecordDuration,
+	}
+
+	for i, signal := range er.hdr.Signals {
+		size := 2 * signal.SamplesPerRecord
+		data := buf[:size]
+		buf = buf[size:]
+
+		if i == er.annotationsIndex {
+			onset, annotations, err := parseTALs(data)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing annotations in data record %d: %w", er.record, err)
+			}
+
+			if onset != nil {
+				record.Onset = *onset
+			}
+			record.Annotations = annotations
+			continue
+		}
+
+		samples := make([]int16, signal.SamplesPerRecord)
+		for j := range samples {
+			samples[j] = int16(binary.LittleEndian.Uint16(data[2*j:]))
+		}
+		record.Samples = append(record.Samples, samples)
+	}
+
+	er.record++
+
+	return record, nil
+}
+
+// DigitalToPhysical converts a digital sample to its physical value using the
+// scaling of the signal.
+func DigitalToPhysical(signal edf.SignalHeader, digital int16) float64 {
+	if signal.DigitalMax == signal.DigitalMin {
+		return 0 // Avoid division by zero
+	}
+
+	return signal.PhysicalMin + (float64(digital)-float64(signal.DigitalMin))*
+		(signal.PhysicalMax-signal.PhysicalMin)/float64(signal.DigitalMax-signal.DigitalMin)
+}
+
+// physicalSample converts a digital sample to its physical value, or NaN if it
+// is invalid (see InvalidSample).
+func physicalSample(signal edf.SignalHeader, digital int16) float64 {
+	if digital == InvalidSample && signal.DigitalMin > InvalidSample {
+		return math.NaN()
+	}
+
+	return DigitalToPhysical(signal, digital)
+}
+
+// ReadHeader reads and parses an EDF or EDF+ header.
+func ReadHeader(r io.Reader) (*edf.Header, error) {
+	b := make([]byte, 256)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	hdr := &edf.Header{
+		Version:     edf.Version(field(b[0:8])),
+		PatientID:   field(b[8:88]),
+		RecordingID: field(b[88:168]),
+		Reserved:    field(b[192:236]),
+	}
+
+	startTime, err := parseStartTime(field(b[168:176]), field(b[176:184]), hdr.RecordingID)
+	if err != nil {
+		return nil, err
+	}
+	hdr.StartTime = startTime
+
+	if hdr.HeaderBytes, err = strconv.Atoi(field(b[184:192])); err != nil {
+		return nil, fmt.Errorf("error parsing header bytes: %w", err)
+	}
+
+	if hdr.DataRecords, err = strconv.Atoi(field(b[236:244])); err != nil {
+		return nil, fmt.Errorf("error parsing number of data records: %w", err)
+	}
+
+	duration, err := strconv.ParseFloat(field(b[244:252]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing data record duration: %w", err)
+	}
+	hdr.DataRecordDuration = time.Duration(math.Round(duration * float64(time.Second)))
+
+	if hdr.SignalCount, err = strconv.Atoi(field(b[252:256])); err != nil {
+		return nil, fmt.Errorf("error parsing signal count: %w", err)
+	}
+
+	if hdr.SignalCount < 0 || hdr.HeaderBytes != 256*(hdr.SignalCount+1) {
+		return nil, fmt.Errorf("invalid header size %d for %d signals", hdr.HeaderBytes, hdr.SignalCount)
+	}
+
+	b = make([]byte, 256*hdr.SignalCount)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("error reading signal headers: %w", err)
+	}
+
+	// Signal header fields are stored column-wise.
+	next := func(width int) []string {
+		values := make([]string, hdr.SignalCount)
+		for i := range values {
+			values[i] = field(b[i*width : (i+1)*width])
+		}
+		b = b[hdr.SignalCount*width:]
+		return values
+	}
+
+	labels := next(16)
+	transducerTypes := next(80)
+	physicalDimensions := next(8)
+	physicalMins := next(8)
+	physicalMaxs := next(8)
+	digitalMins := next(8)
+	digitalMaxs := next(8)
+	prefilterings := next(80)
+	samplesPerRecords := next(8)
+	reserveds := next(32)
+
+	hdr.Signals = make([]edf.SignalHeader, hdr.SignalCount)
+	for i := range hdr.Signals {
+		signal := &hdr.Signals[i]
+
+		signal.Label = labels[i]
+		signal.TransducerType = transducerTypes[i]
+		signal.PhysicalDimension = physicalDimensions[i]
+		signal.Prefiltering = prefilterings[i]
+		signal.Reserved = reserveds[i]
+
+		if signal.PhysicalMin, err = strconv.ParseFloat(physicalMins[i], 64); err != nil {
+			return nil, fmt.Errorf("error parsing physical minimum of signal %d: %w", i, err)
+		}
+		if signal.PhysicalMax, err = strconv.ParseFloat(physicalMaxs[i], 64); err != nil {
+			return nil, fmt.Errorf("error parsing physical maximum of signal %d: %w", i, err)
+		}
+		if signal.DigitalMin, err = strconv.Atoi(digitalMins[i]); err != nil {
+			return nil, fmt.Errorf("error parsing digital minimum of signal %d: %w", i, err)
+		}
+		if signal.DigitalMax, err = strconv.Atoi(digitalMaxs[i]); err != nil {
+			return nil, fmt.Errorf("error parsing digital maximum of signal %d: %w", i, err)
+		}
+		if signal.SamplesPerRecord, err = strconv.Atoi(samplesPerRecords[i]); err != nil {
+			return nil, fmt.Errorf("error parsing samples per record of signal %d: %w", i, err)
+		}
+		if signal.SamplesPerRecord < 0 {
+			return nil, fmt.Errorf("invalid samples per record %d of signal %d", signal.SamplesPerRecord, i)
+		}
+	}
+
+	return hdr, nil
+}
+
+// parseStartTime parses the start date and time of the recording. EDF+ files
+// store the full year in the recording identification field, otherwise years
+// are interpreted using the EDF 1985 - 2084 clipping window.
+func parseStartTime(dateStr, timeStr, recordingID string) (time.Time, error) {
+	startDate, err := time.Parse("02.01.06", dateStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing start date: %w", err)
+	}
+
+	startTime, err := time.Parse("15.04.05", timeStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing start time: %w", err)
+	}
+
+	year := 1900 + startDate.Year()%100
+	if year < 1985 {
+		year += 100
+	}
+
+	if fields := strings.Fields(recordingID); len(fields) > 1 && fields[0] == "Startdate" {
+		if d, err := time.Parse("02-Jan-2006", fields[1]); err == nil {
+			year = d.Year()
+		}
+	}
+
+	return time.Date(year, startDate.Month(), startDate.Day(),
+		startTime.Hour(), startTime.Minute(), startTime.Second(), 0, time.Local), nil
+}
+
+// parseTALs parses the time-stamped annotation lists of a data record,
+// returning the onset of the data record (if present) and the annotations.
+func parseTALs(data []byte) (*time.Duration, []Annotation, error) {
+	var recordOnset *time.Duration
+	var annotations []Annotation
+
+	for _, tal := range bytes.Split(data, []byte{0x00}) {
+		if len(tal) == 0 {
+			continue
+		}
+
+		parts := bytes.Split(tal, []byte{0x14})
+		if len(parts) < 2 {
+			return nil, nil, fmt.Errorf("invalid TAL %q", tal)
+		}
+
+		onsetStr, durationStr, _ := strings.Cut(string(parts[0]), "\x15")
+
+		onset, err := parseSeconds(onsetStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid onset %q: %w", onsetStr, err)
+		}
+
+		var duration time.Duration
+		if durationStr != "" {
+			if duration, err = parseSeconds(durationStr); err != nil {
+				return nil, nil, fmt.Errorf("invalid duration %q: %w", durationStr, err)
+			}
+		}
+
+		// The last part is always empty as every annotation is terminated.
+		texts := parts[1 : len(parts)-1]
+
+		// The first TAL of a data record is the time-keeping annotation.
+		if recordOnset == nil && len(annotations) == 0 && len(texts) > 0 && len(texts[0]) == 0 {
+			recordOnset = &onset
+			texts = texts[1:]
+		}
+
+		for _, text := range texts {
+			annotations = append(annotations, Annotation{
+				Onset:    onset,
+				Duration: duration,
+				Text:     string(text),
+			})
+		}
+	}
+
+	return recordOnset, annotations, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(math.Round(seconds * float64(time.Second))), nil
+}
+
+func field(b []byte) string {
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,117 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.edf")
+
+	startTime := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local)
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		RecordingID:        edfplus.RecordingIdentification(startTime, "", "", ""),
+		StartTime:          startTime,
+		DataRecordDuration: 500 * time.Millisecond,
+		Signals: []edf.SignalHeader{
+			{
+				Label:             "Nasal Pressure",
+				PhysicalDimension: "Pa",
+				PhysicalMin:       -100,
+				PhysicalMax:       100,
+				DigitalMin:        math.MinInt16,
+				DigitalMax:        math.MaxInt16,
+				SamplesPerRecord:  2,
+			},
+			edfplus.AnnotationSignal(64),
+		},
+	})
+	require.NoError(t, err)
+
+	ew.Annotate(edfplus.Annotation{Onset: 300 * time.Millisecond, Text: "Lights off"})
+	ew.Annotate(edfplus.Annotation{Onset: 400 * time.Millisecond, Duration: 10 * time.Second, Text: "Snoring"})
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{-100, 100}}))
+	require.NoError(t, ew.WriteRecord(2*time.Second, [][]float64{{0, 50}}))
+	require.NoError(t, ew.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	hdr := er.Header()
+	assert.Equal(t, startTime, hdr.StartTime)
+	assert.Equal(t, edfplus.Discontinuous, hdr.Reserved)
+	assert.Equal(t, 2, hdr.DataRecords)
+	assert.Equal(t, 500*time.Millisecond, hdr.DataRecordDuration)
+
+	signals := er.Signals()
+	require.Len(t, signals, 1)
+	assert.Equal(t, "Nasal Pressure", signals[0].Label)
+	assert.Equal(t, "Pa", signals[0].PhysicalDimension)
+
+	record, err := er.ReadRecord()
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Duration(0), record.Onset)
+	assert.Equal(t, [][]int16{{math.MinInt16, math.MaxInt16}}, record.Samples)
+	assert.Equal(t, []edfplus.Annotation{
+		{Onset: 300 * time.Millisecond, Text: "Lights off"},
+		{Onset: 400 * time.Millisecond, Duration: 10 * time.Second, Text: "Snoring"},
+	}, record.Annotations)
+
+	record, err = er.ReadRecord()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2*time.Second, record.Onset)
+	assert.InDelta(t, 50, edfplus.DigitalToPhysical(signals[0], record.Samples[0][1]), 0.01)
+	assert.Empty(t, record.Annotations)
+
+	_, err = er.ReadRecord()
+	assert.ErrorIs(t, err, io.EOF)
+
+	er.Seek(1)
+
+	record, err = er.ReadRecord()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2*time.Second, record.Onset)
+}
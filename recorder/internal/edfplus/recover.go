@@ -0,0 +1,77 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Offset of the number of data records field in the header.
+const dataRecordsOffset = 236
+
+// Recover finalizes an EDF file left behind by an interrupted writer. The
+// number of data records in the header is set from the size of the file, and
+// any truncated trailing data record is removed. It returns the number of
+// complete data records.
+func Recover(f *os.File) (int, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("error seeking to header: %w", err)
+	}
+
+	hdr, err := ReadHeader(f)
+	if err != nil {
+		return 0, err
+	}
+
+	var recordSize int64
+	for _, signal := range hdr.Signals {
+		recordSize += 2 * int64(signal.SamplesPerRecord)
+	}
+
+	if recordSize == 0 {
+		return 0, fmt.Errorf("data records are empty")
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("error getting file size: %w", err)
+	}
+
+	dataRecords := (fi.Size() - int64(hdr.HeaderBytes)) / recordSize
+	if dataRecords < 0 {
+		return 0, fmt.Errorf("file is smaller than its header")
+	}
+
+	if err := f.Truncate(int64(hdr.HeaderBytes) + dataRecords*recordSize); err != nil {
+		return 0, fmt.Errorf("error truncating trailing data record: %w", err)
+	}
+
+	var sb strings.Builder
+	writeField(&sb, strconv.FormatInt(dataRecords, 10), 8)
+
+	if _, err := f.WriteAt([]byte(sb.String()), dataRecordsOffset); err != nil {
+		return 0, fmt.Errorf("error writing number of data records: %w", err)
+	}
+
+	return int(dataRecords), nil
+}
@@ -0,0 +1,141 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf.partial"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now(),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 4,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{0, 0, 0, 0}}))
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{0, 0, 0, 0}}))
+
+	// Simulate a crash part way through writing the third data record.
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	dataRecords, err := edfplus.Recover(f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dataRecords)
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, er.Header().DataRecords)
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(256*3+2*2*(4+8)), fi.Size())
+}
+
+func TestAppend(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf.partial"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now(),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 4,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{0, 0, 0, 0}}))
+
+	// Simulate a crash part way through writing the second data record.
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	_, err = edfplus.Recover(f)
+	require.NoError(t, err)
+
+	ew, err = edfplus.Append(f)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ew.DataRecords())
+	assert.Equal(t, time.Second, ew.NextOnset())
+
+	// Overlapping the existing data records is not allowed.
+	require.Error(t, ew.WriteRecord(500*time.Millisecond, [][]float64{{0, 0, 0, 0}}))
+
+	require.NoError(t, ew.WriteRecord(10*time.Second, [][]float64{{0, 0, 0, 0}}))
+	require.NoError(t, ew.Close())
+
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+	assert.Equal(t, 2, er.Header().DataRecords)
+	assert.Equal(t, edfplus.Discontinuous, er.Header().Reserved)
+
+	_, err = er.ReadRecord()
+	require.NoError(t, err)
+
+	record, err := er.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, record.Onset)
+}
@@ -0,0 +1,131 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Repair writes a copy of the EDF file src to dst with its recoverable
+// problems fixed: the number of data records in the header is set from the
+// size of the file, any truncated trailing data record is removed, and an EDF+
+// file missing its annotations signal is rewritten with one (assuming its data
+// records are contiguous). It returns the anomalies (see Inspect) that were
+// repaired.
+func Repair(dst, src *os.File) ([]string, error) {
+	before, err := Inspect(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking to header: %w", err)
+	}
+
+	if err := copyFile(dst, src); err != nil {
+		return nil, err
+	}
+
+	dataRecords, err := Recover(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if before.MissingAnnotations {
+		if dataRecords == 0 {
+			return nil, fmt.Errorf("no complete data records")
+		}
+
+		if err := addAnnotationSignal(dst); err != nil {
+			return nil, fmt.Errorf("error adding annotations signal: %w", err)
+		}
+	}
+
+	after, err := Inspect(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	var repaired []string
+	for _, anomaly := range before.Anomalies {
+		if !slices.Contains(after.Anomalies, anomaly) {
+			repaired = append(repaired, anomaly)
+		}
+	}
+
+	return repaired, nil
+}
+
+// addAnnotationSignal rewrites the file with an annotations signal, keeping all
+// of its ordinary signals.
+func addAnnotationSignal(f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking to header: %w", err)
+	}
+
+	er, err := Open(f)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Name()), filepath.Base(f.Name())+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	signals := make([]int, len(er.Signals()))
+	for i := range signals {
+		signals[i] = i
+	}
+
+	if err := Extract(tmp, er, 0, math.MaxInt64, signals); err != nil {
+		return err
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating file: %w", err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking to start of temporary file: %w", err)
+	}
+
+	return copyFile(f, tmp)
+}
+
+// copyFile copies the contents of src, from its current offset, to the start
+// of dst.
+func copyFile(dst *os.File, src io.Reader) error {
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking to start of file: %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("error copying file: %w", err)
+	}
+
+	return nil
+}
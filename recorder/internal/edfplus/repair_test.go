@@ -0,0 +1,108 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	dir := t.TempDir()
+
+	src, err := os.Create(filepath.Join(dir, "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, src.Close())
+	})
+
+	// An EDF+ file without an annotations signal.
+	ew, err := edfplus.Create(src, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local),
+		DataRecordDuration: time.Second,
+		Reserved:           edfplus.Continuous,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 4,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{1, 1, 1, 1}}))
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{2, 2, 2, 2}}))
+
+	// Simulate a crash part way through writing the third data record.
+	_, err = src.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	dst, err := os.Create(filepath.Join(dir, "repaired.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, dst.Close())
+	})
+
+	repaired, err := edfplus.Repair(dst, src)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"EDF+C file has no annotations signal",
+		"number of data records is unknown (-1), the file was not closed by its writer",
+		"last data record is truncated (3 of 8 bytes)",
+	}, repaired)
+
+	in, err := edfplus.Inspect(dst)
+	require.NoError(t, err)
+	assert.Empty(t, in.Anomalies)
+
+	_, err = dst.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(dst)
+	require.NoError(t, err)
+
+	hdr := er.Header()
+	assert.Equal(t, 2, hdr.DataRecords)
+	require.Len(t, hdr.Signals, 2)
+	assert.Equal(t, edfplus.AnnotationsLabel, hdr.Signals[1].Label)
+
+	for i := range 2 {
+		record, err := er.ReadRecord()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(i)*time.Second, record.Onset)
+
+		for _, digital := range record.Samples[0] {
+			assert.InDelta(t, float64(i+1), edfplus.DigitalToPhysical(hdr.Signals[0], digital), 0.01)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+// Writer writes EDF and EDF+ files.
+type Writer struct {
+	w                io.WriteSeeker
+	hdr              edf.Header
+	annotationsIndex int // Index of the annotations signal, -1 if none.
+	pending          []Annotation
+	dataRecords      int // Number of data records written so far.
+	nextOnset        time.Duration
+	buf              []byte
+}
+
+// Create writes the header to w and returns a writer for the data records.
+// If the header contains an annotations signal, the file is written as EDF+.
+func Create(w io.WriteSeeker, hdr edf.Header) (*Writer, error) {
+	hdr.DataRecords = -1 // Unknown number of data records (at this time).
+	hdr.SignalCount = len(hdr.Signals)
+	hdr.HeaderBytes = 256 * (hdr.SignalCount + 1)
+	hdr.Signals = append([]edf.SignalHeader(nil), hdr.Signals...)
+
+	ew := &Writer{
+		w:                w,
+		hdr:              hdr,
+		annotationsIndex: -1,
+	}
+
+	for i := range ew.hdr.Signals {
+		signal := &ew.hdr.Signals[i]
+
+		if signal.Label == AnnotationsLabel {
+			if ew.annotationsIndex != -1 {
+				return nil, fmt.Errorf("multiple annotation signals are not supported")
+			}
+			ew.annotationsIndex = i
+		}
+
+		if signal.PhysicalMin == signal.PhysicalMax || signal.DigitalMin >= signal.DigitalMax {
+			return nil, fmt.Errorf("invalid range for signal %q", signal.Label)
+		}
+
+		// Use the physical range as it will be represented in the header, so
+		// that readers reconstruct exactly the values we wrote.
+		signal.PhysicalMin = parseNumber(formatNumber(signal.PhysicalMin, 8))
+		signal.PhysicalMax = parseNumber(formatNumber(signal.PhysicalMax, 8))
+	}
+
+	if ew.annotationsIndex != -1 && ew.hdr.Reserved == "" {
+		ew.hdr.Reserved = Continuous
+	}
+
+	if err := ew.writeHeader(); err != nil {
+		return nil, fmt.Errorf("error writing header: %w", err)
+	}
+
+	return ew, nil
+}
+
+// Append returns a writer to append data records to an EDF+ file left behind
+// by an interrupted writer, once its trailing data record has been removed (see
+// Recover). Appended data records must start after the last data record in the
+// file, leaving a gap if they don't follow on from it.
+func Append(rw io.ReadWriteSeeker) (*Writer, error) {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking to header: %w", err)
+	}
+
+	er, err := Open(rw)
+	if err != nil {
+		return nil, err
+	}
+
+	if er.hdr.DataRecords < 0 {
+		return nil, fmt.Errorf("unknown number of data records")
+	}
+
+	ew := &Writer{
+		w:                rw,
+		hdr:              er.hdr,
+		annotationsIndex: er.annotationsIndex,
+		dataRecords:      er.hdr.DataRecords,
+	}
+
+	if ew.annotationsIndex == -1 {
+		return nil, fmt.Errorf("appending requires an annotations signal")
+	}
+
+	if ew.dataRecords > 0 {
+		er.record = ew.dataRecords - 1
+		last, err := er.ReadRecord()
+		if err != nil {
+			return nil, fmt.Errorf("error reading last data record: %w", err)
+		}
+		ew.nextOnset = last.Onset + ew.hdr.DataRecordDuration
+	}
+
+	if _, err := rw.Seek(int64(ew.hdr.HeaderBytes)+int64(ew.dataRecords)*int64(er.recordSize), io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error seeking to end of data records: %w", err)
+	}
+
+	return ew, nil
+}
+
+// Header returns the header of the file being written.
+func (ew *Writer) Header() edf.Header {
+	return ew.hdr
+}
+
+// DataRecords returns the number of data records written so far.
+func (ew *Writer) DataRecords() int {
+	return ew.dataRecords
+}
+
+// NextOnset returns the onset of the data record following on from the last
+// one written.
+func (ew *Writer) NextOnset() time.Duration {
+	return ew.nextOnset
+}
+
+// Annotate queues an annotation to be written with the next data record.
+func (ew *Writer) Annotate(a Annotation) {
+	ew.pending = append(ew.pending, a)
+}
+
+// WriteRecord writes a data record starting at the given onset (relative to
+// the start of the file). The signals exclude the annotations signal, which is
+// populated from the queued annotations. If the onset leaves a gap after the
+// previous data record, the file is marked as discontinuous (EDF+D).
+func (ew *Writer) WriteRecord(onset time.Duration, signals [][]float64) error {
+	expectedSignals := ew.hdr.SignalCount
+	if ew.annotationsIndex != -1 {
+		expectedSignals--
+	}
+
+	if len(signals) != expectedSignals {
+		return fmt.Errorf("expected %d signals, got %d", expectedSignals, len(signals))
+	}
+
+	if ew.dataRecords > 0 && onset != ew.nextOnset {
+		if ew.annotationsIndex == -1 {
+			return fmt.Errorf("discontinuous data records require an annotations signal")
+		}
+
+		if onset < ew.nextOnset {
+			return fmt.Errorf("data record onset %s overlaps the previous data record", onset)
+		}
+
+		if ew.hdr.Reserved != Discontinuous {
+			ew.hdr.Reserved = Discontinuous
+			if err := ew.writeHeader(); err != nil {
+				return fmt.Errorf("error writing header: %w", err)
+			}
+		}
+	}
+
+	ew.buf = ew.buf[:0]
+
+	j := 0
+	for i, signal := range ew.hdr.Signals {
+		if i == ew.annotationsIndex {
+			ew.buf = ew.appendAnnotations(ew.buf, onset, 2*signal.SamplesPerRecord)
+			continue
+		}
+
+		samples := signals[j]
+		j++
+
+		if len(samples) != signal.SamplesPerRecord {
+			return fmt.Errorf("expected %d samples for signal %q, got %d",
+				signal.SamplesPerRecord, signal.Label, len(samples))
+		}
+
+		for _, sample := range samples {
+			ew.buf = binary.LittleEndian.AppendUint16(ew.buf, uint16(convertPhysicalToDigital(sample,
+				signal.PhysicalMin, signal.PhysicalMax, signal.DigitalMin, signal.DigitalMax)))
+		}
+	}
+
+	if _, err := ew.w.Write(ew.buf); err != nil {
+		return err
+	}
+
+	ew.dataRecords++
+	ew.nextOnset = onset + ew.hdr.DataRecordDuration
+	return nil
+}
+
+// Checkpoint updates the header with the number of data records written so far
+// and, if the underlying writer supports it (eg. *os.File), flushes the file to
+// stable storage. This bounds how much of the file is lost if writing is
+// interrupted (eg. by a power loss).
+func (ew *Writer) Checkpoint() error {
+	ew.hdr.DataRecords = ew.dataRecords
+	if err := ew.writeHeader(); err != nil {
+		return fmt.Errorf("error writing header: %w", err)
+	}
+
+	if s, ok := ew.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return fmt.Errorf("error syncing file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close updates the header with the final number of data records.
+func (ew *Writer) Close() error {
+	ew.hdr.DataRecords = ew.dataRecords
+	if err := ew.writeHeader(); err != nil {
+		return fmt.Errorf("error writing header: %w", err)
+	}
+
+	if len(ew.pending) > 0 {
+		return fmt.Errorf("%d annotations were not written", len(ew.pending))
+	}
+
+	return nil
+}
+
+// appendAnnotations appends the annotations signal for a data record, using at
+// most size bytes.
+func (ew *Writer) appendAnnotations(b []byte, onset time.Duration, size int) []byte {
+	start := len(b)
+	b = appendTimeKeepingTAL(b, onset)
+
+	written := 0
+	for _, a := range ew.pending {
+		avail := size - (len(b) - start)
+
+		tal := appendTAL(nil, a)
+		if len(tal) > avail {
+			if written > 0 {
+				break
+			}
+
+			// It will never fit, so truncate the text rather than stalling
+			// the queue forever.
+			a.Text = truncateText(a.Text, len(a.Text)-(len(tal)-avail))
+			if tal = appendTAL(nil, a); len(tal) > avail {
+				tal = nil
+			}
+		}
+
+		b = append(b, tal...)
+		written++
+	}
+	ew.pending = ew.pending[written:]
+
+	// Pad the remainder of the signal with zeros.
+	for len(b)-start < size {
+		b = append(b, 0x00)
+	}
+
+	return b
+}
+
+func (ew *Writer) writeHeader() error {
+	if _, err := ew.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	writeField(&sb, string(ew.hdr.Version), 8)
+	writeField(&sb, ew.hdr.PatientID, 80)
+	writeField(&sb, ew.hdr.RecordingID, 80)
+	writeField(&sb, ew.hdr.StartTime.Format("02.01.06"), 8)
+	writeField(&sb, ew.hdr.StartTime.Format("15.04.05"), 8)
+	writeField(&sb, strconv.Itoa(ew.hdr.HeaderBytes), 8)
+	writeField(&sb, ew.hdr.Reserved, 44)
+	writeField(&sb, strconv.Itoa(ew.hdr.DataRecords), 8)
+	writeField(&sb, formatNumber(ew.hdr.DataRecordDuration.Seconds(), 8), 8)
+	writeField(&sb, strconv.Itoa(ew.hdr.SignalCount), 4)
+
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, signal.Label, 16)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, signal.TransducerType, 80)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, signal.PhysicalDimension, 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, formatNumber(signal.PhysicalMin, 8), 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, formatNumber(signal.PhysicalMax, 8), 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, strconv.Itoa(signal.DigitalMin), 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, strconv.Itoa(signal.DigitalMax), 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, signal.Prefiltering, 80)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, strconv.Itoa(signal.SamplesPerRecord), 8)
+	}
+	for _, signal := range ew.hdr.Signals {
+		writeField(&sb, signal.Reserved, 32)
+	}
+
+	if _, err := io.WriteString(ew.w, sb.String()); err != nil {
+		return err
+	}
+
+	_, err := ew.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// writeField writes a space padded, printable ASCII header field, truncating
+// it if necessary.
+func writeField(sb *strings.Builder, value string, width int) {
+	n := 0
+	for _, r := range value {
+		if n == width {
+			break
+		}
+
+		if r < 0x20 || r > 0x7e {
+			r = '_'
+		}
+
+		sb.WriteRune(r)
+		n++
+	}
+
+	for ; n < width; n++ {
+		sb.WriteByte(' ')
+	}
+}
+
+// formatNumber formats a number using as much precision as fits in width.
+func formatNumber(v float64, width int) string {
+	if s := strconv.FormatFloat(v, 'f', -1, 64); len(s) <= width {
+		return s
+	}
+
+	for prec := width; prec > 0; prec-- {
+		if s := strconv.FormatFloat(v, 'f', prec, 64); len(s) <= width {
+			return s
+		}
+	}
+
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+// truncateText truncates text to at most n bytes, without splitting runes.
+func truncateText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+
+	end := 0
+	for i := range text {
+		if i > n {
+			break
+		}
+		end = i
+	}
+
+	return text[:end]
+}
+
+func parseNumber(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// convertPhysicalToDigital converts a physical value to a digital value,
+// rounding to the nearest digital value and clamping to the digital range.
+// NaN values are converted to InvalidSample.
+func convertPhysicalToDigital(physical, pmin, pmax float64, dmin, dmax int) int16 {
+	if math.IsNaN(physical) {
+		return InvalidSample
+	}
+
+	digital := math.Round((physical-pmin)*float64(dmax-dmin)/(pmax-pmin)) + float64(dmin)
+
+	return int16(max(float64(dmin), min(float64(dmax), digital)))
+}
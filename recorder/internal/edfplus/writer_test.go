@@ -0,0 +1,278 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package edfplus_test
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	startTime := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.UTC)
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		PatientID:          edfplus.PatientIdentification("MCH-0234567", "F", time.Date(1951, time.May, 2, 0, 0, 0, 0, time.UTC), "Haagse Harry"),
+		RecordingID:        edfplus.RecordingIdentification(startTime, "PSG-1", "", "OpenPSG"),
+		StartTime:          startTime,
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:             "Nasal Pressure",
+				PhysicalDimension: "Pa",
+				PhysicalMin:       -100,
+				PhysicalMax:       100,
+				DigitalMin:        math.MinInt16,
+				DigitalMax:        math.MaxInt16,
+				SamplesPerRecord:  4,
+			},
+			edfplus.AnnotationSignal(32),
+		},
+	})
+	require.NoError(t, err)
+
+	ew.Annotate(edfplus.Annotation{Onset: 1500 * time.Millisecond, Duration: time.Second, Text: "Lights off"})
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{-100, 0, 100, 1000}}))
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{0, 0, 0, 0}}))
+	require.NoError(t, ew.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	headerBytes := 256 * 3
+	require.Len(t, data, headerBytes+2*(4+16)*2)
+
+	t.Run("Header", func(t *testing.T) {
+		assert.Equal(t, "MCH-0234567 F 02-MAY-1951 Haagse_Harry", trimField(data[8:88]))
+		assert.Equal(t, "Startdate 03-FEB-2025 PSG-1 X OpenPSG", trimField(data[88:168]))
+		assert.Equal(t, "03.02.25", trimField(data[168:176]))
+		assert.Equal(t, "22.30.00", trimField(data[176:184]))
+		assert.Equal(t, "768", trimField(data[184:192]))
+		assert.Equal(t, edfplus.Continuous, trimField(data[192:236]))
+		assert.Equal(t, "2", trimField(data[236:244]))
+		assert.Equal(t, "1", trimField(data[244:252]))
+		assert.Equal(t, "2", trimField(data[252:256]))
+		assert.Equal(t, edfplus.AnnotationsLabel, trimField(data[256+16:256+32]))
+	})
+
+	t.Run("Samples", func(t *testing.T) {
+		record := data[headerBytes:]
+
+		var samples []int16
+		for i := 0; i < 4; i++ {
+			samples = append(samples, int16(binary.LittleEndian.Uint16(record[2*i:])))
+		}
+
+		// Out of range values are clamped.
+		assert.Equal(t, []int16{math.MinInt16, 0, math.MaxInt16, math.MaxInt16}, samples)
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		recordSize := 2 * (4 + 16)
+
+		first := data[headerBytes+8 : headerBytes+recordSize]
+		assert.Equal(t, "+0\x14\x14\x00+1.5\x151\x14Lights off\x14\x00"+strings.Repeat("\x00", 8), string(first))
+
+		second := data[headerBytes+recordSize+8:]
+		assert.Equal(t, "+1\x14\x14\x00", string(second[:5]))
+	})
+}
+
+func trimField(b []byte) string {
+	end := len(b)
+	for end > 0 && b[end-1] == ' ' {
+		end--
+	}
+	return string(b[:end])
+}
+
+func TestWriterDiscontinuous(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now(),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 1,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{0}}))
+	assert.Equal(t, edfplus.Continuous, ew.Header().Reserved)
+
+	require.NoError(t, ew.WriteRecord(5*time.Second, [][]float64{{0}}))
+	assert.Equal(t, edfplus.Discontinuous, ew.Header().Reserved)
+
+	// Overlapping data records are not allowed.
+	require.Error(t, ew.WriteRecord(5500*time.Millisecond, [][]float64{{0}}))
+
+	require.NoError(t, ew.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, edfplus.Discontinuous, trimField(data[192:236]))
+	assert.Equal(t, "+5\x14\x14\x00", string(data[256*3+18+2:][:5]))
+}
+
+func TestWriterSubSecondStart(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now().Truncate(time.Second),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 1,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	// The first data record may start at any offset from the start time.
+	require.NoError(t, ew.WriteRecord(250*time.Millisecond, [][]float64{{0}}))
+	require.NoError(t, ew.WriteRecord(1250*time.Millisecond, [][]float64{{0}}))
+	assert.Equal(t, edfplus.Continuous, ew.Header().Reserved)
+
+	require.NoError(t, ew.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, "+0.25\x14\x14\x00", string(data[256*3+2:][:8]))
+}
+
+func TestWriterInvalidSamples(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now().Truncate(time.Second),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       edfplus.InvalidSample + 1,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 2,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{-1000, math.NaN()}}))
+	require.NoError(t, ew.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+
+	record := data[256*3:]
+	// NaN values are written as the invalid marker, below the digital minimum.
+	assert.Equal(t, int16(edfplus.InvalidSample+1), int16(binary.LittleEndian.Uint16(record)))
+	assert.Equal(t, int16(edfplus.InvalidSample), int16(binary.LittleEndian.Uint16(record[2:])))
+}
+
+func TestWriterCheckpoint(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		StartTime:          time.Now().Truncate(time.Second),
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:            "Nasal Pressure",
+				PhysicalMin:      -100,
+				PhysicalMax:      100,
+				DigitalMin:       math.MinInt16,
+				DigitalMax:       math.MaxInt16,
+				SamplesPerRecord: 1,
+			},
+			edfplus.AnnotationSignal(16),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{0}}))
+	require.NoError(t, ew.Checkpoint())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "1", trimField(data[236:244]))
+
+	// Data records are still appended after the checkpoint.
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{0}}))
+
+	data, err = os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Len(t, data, 256*3+2*(2+16))
+}
@@ -0,0 +1,205 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/parquet"
+)
+
+// CSV writes each signal of the recording to a CSV file (<name>_<label>.csv)
+// in dir. Each row holds the time of the sample in seconds since the start of
+// the recording and its physical value, gaps in discontinuous recordings are
+// left out.
+func CSV(er *edfplus.Reader, dir, name string) error {
+	signals := er.Signals()
+
+	fileName := signalFileNamer(name)
+
+	files := make([]*os.File, len(signals))
+	writers := make([]*bufio.Writer, len(signals))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}()
+
+	for i, signal := range signals {
+		f, err := os.Create(filepath.Join(dir, fileName(signal.Label)+".csv"))
+		if err != nil {
+			return fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		files[i] = f
+		writers[i] = bufio.NewWriter(f)
+
+		column := strings.ReplaceAll(signal.Label, ",", " ")
+		if signal.PhysicalDimension != "" {
+			column += " (" + signal.PhysicalDimension + ")"
+		}
+
+		if _, err := fmt.Fprintf(writers[i], "time (s),%s\n", column); err != nil {
+			return fmt.Errorf("failed to write CSV file: %w", err)
+		}
+	}
+
+	var line []byte
+	err := forEachSample(er, func(i int, t time.Duration, value float64) error {
+		line = strconv.AppendFloat(line[:0], t.Seconds(), 'f', -1, 64)
+		line = append(line, ',')
+		line = strconv.AppendFloat(line, value, 'g', -1, 64)
+		line = append(line, '\n')
+		_, err := writers[i].Write(line)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, f := range files {
+		if err := writers[i].Flush(); err != nil {
+			return fmt.Errorf("failed to write CSV file: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close CSV file: %w", err)
+		}
+		files[i] = nil
+	}
+
+	return nil
+}
+
+// Parquet writes each signal of the recording to a Parquet file
+// (<name>_<label>.parquet) in dir, with a time column (seconds since the start
+// of the recording) and a value column (physical value). The signal label,
+// units and sample rate are stored in the file metadata. Gaps in
+// discontinuous recordings are left out.
+func Parquet(er *edfplus.Reader, dir, name string) error {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	fileName := signalFileNamer(name)
+
+	files := make([]*os.File, len(signals))
+	buffers := make([]*bufio.Writer, len(signals))
+	writers := make([]*parquet.Writer, len(signals))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}()
+
+	for i, signal := range signals {
+		f, err := os.Create(filepath.Join(dir, fileName(signal.Label)+".parquet"))
+		if err != nil {
+			return fmt.Errorf("failed to create Parquet file: %w", err)
+		}
+		files[i] = f
+		buffers[i] = bufio.NewWriter(f)
+
+		writers[i] = parquet.NewWriter(buffers[i], []string{"time", "value"}, []parquet.KeyValue{
+			{Key: "label", Value: signal.Label},
+			{Key: "unit", Value: signal.PhysicalDimension},
+			{Key: "sample_rate", Value: strconv.FormatFloat(sampleRate(hdr.DataRecordDuration, signal.SamplesPerRecord), 'f', -1, 64)},
+			{Key: "start_time", Value: hdr.StartTime.Format(time.RFC3339)},
+			{Key: "patient_id", Value: hdr.PatientID},
+			{Key: "recording_id", Value: hdr.RecordingID},
+		})
+	}
+
+	err := forEachSample(er, func(i int, t time.Duration, value float64) error {
+		return writers[i].WriteRow(t.Seconds(), value)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, f := range files {
+		if err := writers[i].Close(); err != nil {
+			return fmt.Errorf("failed to write Parquet file: %w", err)
+		}
+
+		if err := buffers[i].Flush(); err != nil {
+			return fmt.Errorf("failed to write Parquet file: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close Parquet file: %w", err)
+		}
+		files[i] = nil
+	}
+
+	return nil
+}
+
+// forEachSample calls fn with the time and physical value of every sample of
+// each signal in the recording.
+func forEachSample(er *edfplus.Reader, fn func(signal int, t time.Duration, value float64) error) error {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	for {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		for i, signal := range signals {
+			for k, sample := range record.Samples[i] {
+				t := record.Onset + time.Duration(int64(k)*int64(hdr.DataRecordDuration)/int64(signal.SamplesPerRecord))
+				if err := fn(i, t, edfplus.DigitalToPhysical(signal, sample)); err != nil {
+					return fmt.Errorf("failed to write samples: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// signalFileNamer returns a function that maps signal labels to unique file
+// names, prefixed with the record name.
+func signalFileNamer(name string) func(label string) string {
+	uniqueName := uniqueNamer()
+	return func(label string) string {
+		label = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+				return r
+			}
+			return '_'
+		}, strings.TrimSpace(label))
+
+		return uniqueName(name + "_" + label)
+	}
+}
@@ -0,0 +1,50 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.CSV(createRecording(t), dir, "test"))
+
+	eeg, err := os.ReadFile(filepath.Join(dir, "test_EEG_C4-M1.csv"))
+	require.NoError(t, err)
+
+	// Gaps are left out.
+	assert.Equal(t, "time (s),EEG C4-M1 (uV)\n"+
+		"0,1\n0.25,2\n0.5,3\n0.75,4\n"+
+		"2,5\n2.25,5\n2.5,5\n2.75,5\n", string(eeg))
+
+	spo2, err := os.ReadFile(filepath.Join(dir, "test_SpO2.csv"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "time (s),SpO2 (%)\n"+
+		"0,5\n0.5,6\n"+
+		"2,7\n2.5,7\n", string(spo2))
+}
@@ -0,0 +1,412 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+)
+
+const (
+	// Sleep Electroencephalogram Waveform Storage.
+	dicomSleepEEGSOPClassUID = "1.2.840.10008.5.1.4.1.1.9.7.4"
+	// Explicit VR Little Endian.
+	dicomTransferSyntaxUID      = "1.2.840.10008.1.2.1"
+	dicomImplementationClassUID = "2.25.288149830424553548212012340559752554707"
+	// Signals without a standard channel source code are described using
+	// a private coding scheme.
+	dicomCodingScheme = "99OPENPSG"
+)
+
+// Padding value used to mark gaps in the waveform data.
+var dicomPaddingValue int16 = math.MinInt16
+
+// DICOM writes the recording as a DICOM Sleep EEG Waveform object
+// (<name>.dcm) in dir, with the patient demographics taken from the EDF+
+// patient identification. Signals are grouped into multiplex groups by sample
+// rate, gaps are filled with the waveform padding value, and EDF+ annotations
+// are stored as waveform annotations.
+func DICOM(er *edfplus.Reader, dir, name string) error {
+	hdr := er.Header()
+	signals := er.Signals()
+	if len(signals) == 0 {
+		return fmt.Errorf("recording has no signals")
+	}
+
+	lengths, annotations, err := scanRecording(er)
+	if err != nil {
+		return err
+	}
+
+	// Each multiplex group holds signals sampled at the same rate.
+	type multiplexGroup struct {
+		samplesPerRecord int
+		signals          []int
+	}
+	var groups []*multiplexGroup
+	for i, signal := range signals {
+		var group *multiplexGroup
+		for _, g := range groups {
+			if g.samplesPerRecord == signal.SamplesPerRecord {
+				group = g
+			}
+		}
+		if group == nil {
+			group = &multiplexGroup{samplesPerRecord: signal.SamplesPerRecord}
+			groups = append(groups, group)
+		}
+		group.signals = append(group.signals, i)
+	}
+
+	sopInstanceUID := newDICOMUID()
+	startTime := hdr.StartTime
+
+	out, err := os.Create(filepath.Join(dir, name+".dcm"))
+	if err != nil {
+		return fmt.Errorf("failed to create DICOM file: %w", err)
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+
+	// File meta information.
+	var meta []byte
+	meta = appendDICOMElement(meta, 0x0002, 0x0001, "OB", []byte{0, 1})
+	meta = appendDICOMString(meta, 0x0002, 0x0002, "UI", dicomSleepEEGSOPClassUID)
+	meta = appendDICOMString(meta, 0x0002, 0x0003, "UI", sopInstanceUID)
+	meta = appendDICOMString(meta, 0x0002, 0x0010, "UI", dicomTransferSyntaxUID)
+	meta = appendDICOMString(meta, 0x0002, 0x0012, "UI", dicomImplementationClassUID)
+	meta = appendDICOMString(meta, 0x0002, 0x0013, "SH", "OPENPSG")
+
+	b := make([]byte, 128)
+	b = append(b, "DICM"...)
+	b = appendDICOMElement(b, 0x0002, 0x0000, "UL", binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	b = append(b, meta...)
+
+	patient, _ := edfplus.ParsePatientIdentification(hdr.PatientID)
+	if patient.Code == "" && patient.Name == "" {
+		patient.Code = hdr.PatientID
+	}
+
+	var birthdate string
+	if !patient.Birthdate.IsZero() {
+		birthdate = patient.Birthdate.Format("20060102")
+	}
+
+	var sex string
+	switch patient.Sex {
+	case "F", "M":
+		sex = patient.Sex
+	case "":
+	default:
+		sex = "O"
+	}
+
+	b = appendDICOMString(b, 0x0008, 0x0005, "CS", "ISO_IR 192")
+	b = appendDICOMString(b, 0x0008, 0x0016, "UI", dicomSleepEEGSOPClassUID)
+	b = appendDICOMString(b, 0x0008, 0x0018, "UI", sopInstanceUID)
+	b = appendDICOMString(b, 0x0008, 0x0020, "DA", startTime.Format("20060102"))
+	b = appendDICOMString(b, 0x0008, 0x0023, "DA", startTime.Format("20060102"))
+	b = appendDICOMString(b, 0x0008, 0x002A, "DT", startTime.Format("20060102150405-0700"))
+	b = appendDICOMString(b, 0x0008, 0x0030, "TM", startTime.Format("150405"))
+	b = appendDICOMString(b, 0x0008, 0x0033, "TM", startTime.Format("150405"))
+	b = appendDICOMString(b, 0x0008, 0x0050, "SH", "")
+	b = appendDICOMString(b, 0x0008, 0x0060, "CS", "EEG")
+	b = appendDICOMString(b, 0x0008, 0x0070, "LO", "OpenPSG")
+	b = appendDICOMString(b, 0x0008, 0x0090, "PN", "")
+	b = appendDICOMString(b, 0x0010, 0x0010, "PN", patient.Name)
+	b = appendDICOMString(b, 0x0010, 0x0020, "LO", patient.Code)
+	b = appendDICOMString(b, 0x0010, 0x0030, "DA", birthdate)
+	b = appendDICOMString(b, 0x0010, 0x0040, "CS", sex)
+	b = appendDICOMString(b, 0x0020, 0x000D, "UI", newDICOMUID())
+	b = appendDICOMString(b, 0x0020, 0x000E, "UI", newDICOMUID())
+	b = appendDICOMString(b, 0x0020, 0x0010, "SH", "")
+	b = appendDICOMString(b, 0x0020, 0x0011, "IS", "1")
+	b = appendDICOMString(b, 0x0020, 0x0013, "IS", "1")
+	b = appendDICOMElement(b, 0x0040, 0x0555, "SQ", nil)
+
+	if len(annotations) > 0 {
+		b = appendDICOMSequenceStart(b, 0x0040, 0xB020)
+		for _, a := range annotations {
+			b = appendDICOMItemStart(b)
+			// Annotations apply to all channels of the first multiplex group.
+			b = appendDICOMElement(b, 0x0040, 0xA0B0, "US", []byte{1, 0, 0, 0})
+			if a.Duration > 0 {
+				b = appendDICOMString(b, 0x0040, 0xA130, "CS", "SEGMENT")
+				b = appendDICOMString(b, 0x0040, 0xA138, "DS",
+					formatDS(a.Onset.Seconds())+`\`+formatDS((a.Onset+a.Duration).Seconds()))
+			} else {
+				b = appendDICOMString(b, 0x0040, 0xA130, "CS", "POINT")
+				b = appendDICOMString(b, 0x0040, 0xA138, "DS", formatDS(a.Onset.Seconds()))
+			}
+			b = appendDICOMString(b, 0x0040, 0xA160, "UT", a.Text)
+			b = appendDICOMElement(b, 0x0040, 0xA180, "US", []byte{1, 0})
+			b = appendDICOMItemEnd(b)
+		}
+		b = appendDICOMSequenceEnd(b)
+	}
+
+	b = appendDICOMSequenceStart(b, 0x5400, 0x0100)
+
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("failed to write DICOM header: %w", err)
+	}
+
+	for _, group := range groups {
+		rate := sampleRate(hdr.DataRecordDuration, group.samplesPerRecord)
+		samples := lengths[group.signals[0]]
+
+		b = appendDICOMItemStart(nil)
+		b = appendDICOMString(b, 0x003A, 0x0004, "CS", "ORIGINAL")
+		b = appendDICOMElement(b, 0x003A, 0x0005, "US", binary.LittleEndian.AppendUint16(nil, uint16(len(group.signals))))
+		b = appendDICOMElement(b, 0x003A, 0x0010, "UL", binary.LittleEndian.AppendUint32(nil, uint32(samples)))
+		b = appendDICOMString(b, 0x003A, 0x001A, "DS", formatDS(rate))
+		b = appendDICOMString(b, 0x003A, 0x0020, "SH", formatDS(rate)+" Hz")
+
+		b = appendDICOMSequenceStart(b, 0x003A, 0x0200)
+		for channel, i := range group.signals {
+			b = appendDICOMChannelDefinition(b, channel+1, signals[i])
+		}
+		b = appendDICOMSequenceEnd(b)
+
+		b = appendDICOMElement(b, 0x5400, 0x1004, "US", binary.LittleEndian.AppendUint16(nil, 16))
+		b = appendDICOMString(b, 0x5400, 0x1006, "CS", "SS")
+		b = appendDICOMElement(b, 0x5400, 0x100A, "OW", binary.LittleEndian.AppendUint16(nil, uint16(dicomPaddingValue)))
+
+		dataSize := 2 * samples * int64(len(group.signals))
+		if dataSize > math.MaxUint32-1 {
+			return fmt.Errorf("waveform data is too large for a DICOM object")
+		}
+
+		b = binary.LittleEndian.AppendUint16(b, 0x5400)
+		b = binary.LittleEndian.AppendUint16(b, 0x1010)
+		b = append(b, "OW\x00\x00"...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(dataSize))
+
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("failed to write DICOM header: %w", err)
+		}
+
+		if err := writeDICOMWaveformData(er, w, signals, group.signals, samples); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(appendDICOMItemEnd(nil)); err != nil {
+			return fmt.Errorf("failed to write DICOM header: %w", err)
+		}
+	}
+
+	if _, err := w.Write(appendDICOMSequenceEnd(nil)); err != nil {
+		return fmt.Errorf("failed to write DICOM header: %w", err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write DICOM file: %w", err)
+	}
+
+	return out.Close()
+}
+
+// writeDICOMWaveformData writes the interleaved samples of a multiplex group.
+func writeDICOMWaveformData(er *edfplus.Reader, w io.Writer, signals []edf.SignalHeader, channels []int, samples int64) error {
+	hdr := er.Header()
+	samplesPerRecord := signals[channels[0]].SamplesPerRecord
+	rate := sampleRate(hdr.DataRecordDuration, samplesPerRecord)
+
+	padding := make([]byte, 2*len(channels))
+	for i := range channels {
+		binary.LittleEndian.PutUint16(padding[2*i:], uint16(dicomPaddingValue))
+	}
+
+	er.Rewind()
+
+	var next int64
+	frame := make([]byte, 2*len(channels))
+	for next < samples {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		start := int64(math.Round(record.Onset.Seconds() * rate))
+		for ; next < start; next++ {
+			if _, err := w.Write(padding); err != nil {
+				return fmt.Errorf("failed to write waveform data: %w", err)
+			}
+		}
+
+		for k := 0; k < samplesPerRecord; k++ {
+			for j, i := range channels {
+				sample := record.Samples[i][k]
+				// Don't let valid samples be mistaken for padding.
+				if sample == dicomPaddingValue {
+					sample++
+				}
+				binary.LittleEndian.PutUint16(frame[2*j:], uint16(sample))
+			}
+
+			if _, err := w.Write(frame); err != nil {
+				return fmt.Errorf("failed to write waveform data: %w", err)
+			}
+		}
+		next = start + int64(samplesPerRecord)
+	}
+
+	for ; next < samples; next++ {
+		if _, err := w.Write(padding); err != nil {
+			return fmt.Errorf("failed to write waveform data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// appendDICOMChannelDefinition appends a channel definition sequence item.
+func appendDICOMChannelDefinition(b []byte, channel int, signal edf.SignalHeader) []byte {
+	sensitivity := (signal.PhysicalMax - signal.PhysicalMin) / float64(signal.DigitalMax-signal.DigitalMin)
+	baseline := signal.PhysicalMin - float64(signal.DigitalMin)*sensitivity
+
+	units := signal.PhysicalDimension
+	if units == "" {
+		units = "1"
+	}
+
+	source := strings.ToUpper(strings.Join(strings.Fields(signal.Label), "_"))
+
+	b = appendDICOMItemStart(b)
+	b = appendDICOMString(b, 0x003A, 0x0202, "IS", strconv.Itoa(channel))
+	b = appendDICOMString(b, 0x003A, 0x0203, "SH", signal.Label)
+	b = appendDICOMSequenceStart(b, 0x003A, 0x0208)
+	b = appendDICOMCode(b, source, dicomCodingScheme, signal.Label)
+	b = appendDICOMSequenceEnd(b)
+	b = appendDICOMString(b, 0x003A, 0x0210, "DS", formatDS(sensitivity))
+	b = appendDICOMSequenceStart(b, 0x003A, 0x0211)
+	b = appendDICOMCode(b, units, "UCUM", units)
+	b = appendDICOMSequenceEnd(b)
+	b = appendDICOMString(b, 0x003A, 0x0212, "DS", "1")
+	b = appendDICOMString(b, 0x003A, 0x0213, "DS", formatDS(baseline))
+	b = appendDICOMString(b, 0x003A, 0x0214, "DS", "0")
+	b = appendDICOMElement(b, 0x003A, 0x021A, "US", binary.LittleEndian.AppendUint16(nil, 16))
+	return appendDICOMItemEnd(b)
+}
+
+// appendDICOMCode appends a code sequence item.
+func appendDICOMCode(b []byte, value, scheme, meaning string) []byte {
+	if len(value) > 16 {
+		value = value[:16]
+	}
+
+	b = appendDICOMItemStart(b)
+	b = appendDICOMString(b, 0x0008, 0x0100, "SH", value)
+	b = appendDICOMString(b, 0x0008, 0x0102, "SH", scheme)
+	b = appendDICOMString(b, 0x0008, 0x0104, "LO", meaning)
+	return appendDICOMItemEnd(b)
+}
+
+// appendDICOMString appends a string valued element, padded to an even length.
+func appendDICOMString(b []byte, group, element uint16, vr, value string) []byte {
+	data := []byte(value)
+	if len(data)%2 != 0 {
+		if vr == "UI" {
+			data = append(data, 0)
+		} else {
+			data = append(data, ' ')
+		}
+	}
+	return appendDICOMElement(b, group, element, vr, data)
+}
+
+// appendDICOMElement appends an explicit VR little endian data element.
+func appendDICOMElement(b []byte, group, element uint16, vr string, data []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, group)
+	b = binary.LittleEndian.AppendUint16(b, element)
+	b = append(b, vr...)
+
+	switch vr {
+	case "OB", "OW", "SQ", "UN", "UT":
+		b = append(b, 0, 0)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	default:
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(data)))
+	}
+
+	return append(b, data...)
+}
+
+// appendDICOMSequenceStart appends the start of an undefined length sequence.
+func appendDICOMSequenceStart(b []byte, group, element uint16) []byte {
+	b = binary.LittleEndian.AppendUint16(b, group)
+	b = binary.LittleEndian.AppendUint16(b, element)
+	b = append(b, "SQ\x00\x00"...)
+	return binary.LittleEndian.AppendUint32(b, math.MaxUint32)
+}
+
+func appendDICOMSequenceEnd(b []byte) []byte {
+	return append(b, 0xFE, 0xFF, 0xDD, 0xE0, 0, 0, 0, 0)
+}
+
+// appendDICOMItemStart appends the start of an undefined length item.
+func appendDICOMItemStart(b []byte) []byte {
+	return append(b, 0xFE, 0xFF, 0x00, 0xE0, 0xFF, 0xFF, 0xFF, 0xFF)
+}
+
+func appendDICOMItemEnd(b []byte) []byte {
+	return append(b, 0xFE, 0xFF, 0x0D, 0xE0, 0, 0, 0, 0)
+}
+
+// formatDS formats a decimal string, which is limited to 16 characters.
+func formatDS(v float64) string {
+	for precision := -1; ; precision-- {
+		var s string
+		if precision == -1 {
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		} else {
+			s = strconv.FormatFloat(v, 'g', 16+precision, 64)
+		}
+
+		if len(s) <= 16 || precision < -16 {
+			return s
+		}
+	}
+}
+
+// newDICOMUID returns a unique identifier derived from a random UUID.
+func newDICOMUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return "2.25." + new(big.Int).SetBytes(b[:]).String()
+}
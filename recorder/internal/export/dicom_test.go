@@ -0,0 +1,125 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suyashkumar/dicom"
+	"github.com/suyashkumar/dicom/pkg/tag"
+)
+
+func TestDICOM(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.DICOM(createRecording(t), dir, "test"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.dcm"))
+	require.NoError(t, err)
+
+	// Parsed with an established DICOM library, rather than our own encoder's
+	// assumptions.
+	dataset, err := dicom.Parse(bytes.NewReader(data), int64(len(data)), nil)
+	require.NoError(t, err)
+
+	var elements dicomElements
+	for it := dataset.FlatStatefulIterator(); it.HasNext(); {
+		elements = append(elements, it.Next())
+	}
+
+	assert.Equal(t, []string{"1.2.840.10008.1.2.1"}, elements.strings(tag.TransferSyntaxUID))
+	assert.Equal(t, []string{"1.2.840.10008.5.1.4.1.1.9.7.4"}, elements.strings(tag.SOPClassUID))
+
+	assert.Equal(t, []string{"Haagse Harry"}, elements.strings(tag.PatientName))
+	assert.Equal(t, []string{"MCH-0234567"}, elements.strings(tag.PatientID))
+	assert.Equal(t, []string{"F"}, elements.strings(tag.PatientSex))
+	assert.Equal(t, []string{"223000"}, elements.strings(tag.StudyTime))
+
+	// A multiplex group per sample rate.
+	assert.Equal(t, []int{1, 1}, elements.ints(tag.NumberOfWaveformChannels))
+	assert.Equal(t, []int{12, 6}, elements.ints(tag.NumberOfWaveformSamples))
+	assert.Equal(t, []string{"4", "2"}, elements.strings(tag.SamplingFrequency))
+	assert.Equal(t, []string{"EEG C4-M1", "SpO2"}, elements.strings(tag.ChannelLabel))
+	assert.Equal(t, []string{"0.1", "0.1"}, elements.strings(tag.ChannelSensitivity))
+	assert.Equal(t, []string{"0", "0"}, elements.strings(tag.ChannelBaseline))
+
+	// Gaps are filled with the padding value.
+	const padding = math.MinInt16
+	waveform := func(samples ...int16) []byte {
+		var b []byte
+		for _, sample := range samples {
+			b = binary.LittleEndian.AppendUint16(b, uint16(sample))
+		}
+		return b
+	}
+	assert.Equal(t, [][]byte{waveform(padding), waveform(padding)}, elements.bytes(tag.WaveformPaddingValue))
+	assert.Equal(t, [][]byte{
+		waveform(10, 20, 30, 40, padding, padding, padding, padding, 50, 50, 50, 50),
+		waveform(50, 60, padding, padding, 70, 70),
+	}, elements.bytes(tag.WaveformData))
+
+	// Annotations with a duration are segments, the rest points.
+	assert.Equal(t, []string{"POINT", "SEGMENT", "POINT"}, elements.strings(tag.TemporalRangeType))
+	assert.Equal(t, []string{"0.5", "2.25", "12.25", "1000"}, elements.strings(tag.ReferencedTimeOffsets))
+	assert.Equal(t, []string{"Lights off", "Obstructive apnea", "Lights on"}, elements.strings(tag.TextValue))
+}
+
+type dicomElements []*dicom.Element
+
+func (elements dicomElements) values(t tag.Tag) []dicom.Value {
+	var values []dicom.Value
+	for _, e := range elements {
+		if e.Tag == t {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+func (elements dicomElements) strings(t tag.Tag) []string {
+	var values []string
+	for _, value := range elements.values(t) {
+		values = append(values, dicom.MustGetStrings(value)...)
+	}
+	return values
+}
+
+func (elements dicomElements) ints(t tag.Tag) []int {
+	var values []int
+	for _, value := range elements.values(t) {
+		values = append(values, dicom.MustGetInts(value)...)
+	}
+	return values
+}
+
+func (elements dicomElements) bytes(t tag.Tag) [][]byte {
+	var values [][]byte
+	for _, value := range elements.values(t) {
+		values = append(values, dicom.MustGetBytes(value))
+	}
+	return values
+}
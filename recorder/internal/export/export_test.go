@@ -0,0 +1,87 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/require"
+)
+
+var recordingStartTime = time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local)
+
+// createRecording writes a discontinuous recording with an EEG signal at 4 Hz
+// and an SpO2 signal at 2 Hz. The data records start at 0s and 2s, leaving a
+// one second gap, and the digital values are ten times the physical values.
+func createRecording(t *testing.T) *edfplus.Reader {
+	signal := func(label, unit string, samplesPerRecord int) edf.SignalHeader {
+		return edf.SignalHeader{
+			Label:             label,
+			PhysicalDimension: unit,
+			PhysicalMin:       -100,
+			PhysicalMax:       100,
+			DigitalMin:        -1000,
+			DigitalMax:        1000,
+			SamplesPerRecord:  samplesPerRecord,
+		}
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		PatientID:          edfplus.PatientIdentification("MCH-0234567", "F", time.Date(1951, time.May, 2, 0, 0, 0, 0, time.UTC), "Haagse Harry"),
+		RecordingID:        edfplus.RecordingIdentification(recordingStartTime, "", "", ""),
+		StartTime:          recordingStartTime,
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			signal("EEG C4-M1", "uV", 4),
+			signal("SpO2", "%", 2),
+			edfplus.AnnotationSignal(64),
+		},
+	})
+	require.NoError(t, err)
+
+	ew.Annotate(edfplus.Annotation{Onset: 500 * time.Millisecond, Text: "Lights off"})
+	ew.Annotate(edfplus.Annotation{Onset: 1000 * time.Second, Text: "Lights on"})
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{1, 2, 3, 4}, {5, 6}}))
+
+	ew.Annotate(edfplus.Annotation{Onset: 2250 * time.Millisecond, Duration: 10 * time.Second, Text: "Obstructive apnea"})
+	require.NoError(t, ew.WriteRecord(2*time.Second, [][]float64{{5, 5, 5, 5}, {7, 7}}))
+
+	require.NoError(t, ew.Close())
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	return er
+}
@@ -0,0 +1,205 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/hdf5"
+)
+
+// HDF5 writes the recording as an HDF5 file (<name>.h5) in dir. Each signal
+// is stored as a dataset of physical values (float32, NaN for gaps) with its
+// units, prefiltering, transducer type and sample rate as attributes. EDF+
+// annotations are stored in the annotation_onsets, annotation_durations and
+// annotation_texts datasets.
+func HDF5(er *edfplus.Reader, dir, name string) error {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	lengths, annotations, err := scanRecording(er)
+	if err != nil {
+		return err
+	}
+
+	f := hdf5.NewFile()
+	root := f.Root()
+
+	hdf5.SetStringAttribute(root, "start_time", hdr.StartTime.Format(time.RFC3339))
+	hdf5.SetStringAttribute(root, "patient_id", hdr.PatientID)
+	hdf5.SetStringAttribute(root, "recording_id", hdr.RecordingID)
+
+	uniqueName := uniqueNamer()
+
+	datasets := make([]*hdf5.Dataset, len(signals))
+	for i, signal := range signals {
+		datasets[i] = root.CreateDataset(uniqueName(signal.Label), hdf5.Float32, lengths[i])
+		hdf5.SetStringAttribute(datasets[i], "units", signal.PhysicalDimension)
+		hdf5.SetStringAttribute(datasets[i], "prefiltering", signal.Prefiltering)
+		hdf5.SetStringAttribute(datasets[i], "transducer", signal.TransducerType)
+		hdf5.SetFloat64Attribute(datasets[i], "sample_rate", sampleRate(hdr.DataRecordDuration, signal.SamplesPerRecord))
+	}
+
+	if len(annotations) > 0 {
+		var onsets, durations []byte
+		texts := make([]string, len(annotations))
+		for i, a := range annotations {
+			onsets = binary.LittleEndian.AppendUint64(onsets, math.Float64bits(a.Onset.Seconds()))
+			durations = binary.LittleEndian.AppendUint64(durations, math.Float64bits(a.Duration.Seconds()))
+			texts[i] = a.Text
+		}
+
+		root.CreateDataset(uniqueName("annotation_onsets"), hdf5.Float64, int64(len(annotations))).SetData(onsets)
+		root.CreateDataset(uniqueName("annotation_durations"), hdf5.Float64, int64(len(annotations))).SetData(durations)
+		root.NewStringsDataset(uniqueName("annotation_texts"), texts)
+	}
+
+	out, err := os.Create(filepath.Join(dir, name+".h5"))
+	if err != nil {
+		return fmt.Errorf("failed to create HDF5 file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := f.WriteMetadata(out); err != nil {
+		return fmt.Errorf("failed to write HDF5 metadata: %w", err)
+	}
+
+	if err := writeSignalValues(er, out, datasets); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// scanRecording reads through the recording to determine the length of each
+// signal (including any gaps) and collect the annotations, sorted by onset.
+func scanRecording(er *edfplus.Reader) ([]int64, []edfplus.Annotation, error) {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	lengths := make([]int64, len(signals))
+	var annotations []edfplus.Annotation
+	for {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		for i, signal := range signals {
+			start := int64(math.Round(record.Onset.Seconds() * sampleRate(hdr.DataRecordDuration, signal.SamplesPerRecord)))
+			lengths[i] = max(lengths[i], start+int64(signal.SamplesPerRecord))
+		}
+
+		annotations = append(annotations, record.Annotations...)
+	}
+
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].Onset < annotations[j].Onset
+	})
+
+	return lengths, annotations, nil
+}
+
+// writeSignalValues writes the physical values of each signal into its
+// float32 dataset, filling any gaps with NaN.
+func writeSignalValues(er *edfplus.Reader, out *os.File, datasets []*hdf5.Dataset) error {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	er.Rewind()
+
+	w := bufio.NewWriter(out)
+	nan := binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(math.NaN())))
+	next := make([]int64, len(signals))
+	for {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		for i, signal := range signals {
+			start := int64(math.Round(record.Onset.Seconds() * sampleRate(hdr.DataRecordDuration, signal.SamplesPerRecord)))
+
+			if _, err := out.Seek(datasets[i].DataOffset()+4*min(next[i], start), io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek in HDF5 file: %w", err)
+			}
+
+			for ; next[i] < start; next[i]++ {
+				if _, err := w.Write(nan); err != nil {
+					return fmt.Errorf("failed to write signal values: %w", err)
+				}
+			}
+
+			var b [4]byte
+			for _, sample := range record.Samples[i] {
+				binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(edfplus.DigitalToPhysical(signal, sample))))
+				if _, err := w.Write(b[:]); err != nil {
+					return fmt.Errorf("failed to write signal values: %w", err)
+				}
+			}
+			next[i] = start + int64(len(record.Samples[i]))
+
+			if err := w.Flush(); err != nil {
+				return fmt.Errorf("failed to write signal values: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// uniqueNamer returns a function that maps signal labels to unique HDF5 link
+// names.
+func uniqueNamer() func(name string) string {
+	names := make(map[string]bool)
+	return func(name string) string {
+		name = strings.ReplaceAll(name, "/", "_")
+		if name == "" || name == "." {
+			name = "signal"
+		}
+
+		unique := name
+		for i := 2; names[unique]; i++ {
+			unique = fmt.Sprintf("%s_%d", name, i)
+		}
+		names[unique] = true
+		return unique
+	}
+}
+
+func sampleRate(dataRecordDuration time.Duration, samplesPerRecord int) float64 {
+	return float64(samplesPerRecord) / dataRecordDuration.Seconds()
+}
@@ -0,0 +1,288 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/hdf5"
+)
+
+// The version of the NWB schema the files conform to.
+const nwbVersion = "2.7.0"
+
+// NWB writes the recording as a Neurodata Without Borders (NWB 2) file
+// (<name>.nwb) in dir. Signals measured in volts (EEG, EOG, EMG, ECG etc.) are
+// stored as ElectricalSeries, with an entry in the electrodes table, all other
+// signals are stored as TimeSeries. Signals are stored as physical values
+// (float32, NaN for gaps). EDF+ annotations are stored in the
+// "annotations" TimeIntervals table.
+func NWB(er *edfplus.Reader, dir, name string) error {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	lengths, annotations, err := scanRecording(er)
+	if err != nil {
+		return err
+	}
+
+	f := hdf5.NewFile()
+	root := f.Root()
+
+	setNeurodataType(root, "core", "NWBFile")
+	hdf5.SetStringAttribute(root, "nwb_version", nwbVersion)
+
+	sessionStartTime := hdr.StartTime.Format(time.RFC3339)
+	sessionDescription := hdr.RecordingID
+	if sessionDescription == "" {
+		sessionDescription = "OpenPSG recording"
+	}
+
+	root.NewStringsDataset("file_create_date", []string{time.Now().Format(time.RFC3339)})
+	root.NewStringDataset("identifier", newObjectID())
+	root.NewStringDataset("session_description", sessionDescription)
+	root.NewStringDataset("session_start_time", sessionStartTime)
+	root.NewStringDataset("timestamps_reference_time", sessionStartTime)
+
+	acquisition := root.CreateGroup("acquisition")
+	root.CreateGroup("analysis")
+	root.CreateGroup("processing")
+	stimulus := root.CreateGroup("stimulus")
+	stimulus.CreateGroup("presentation")
+	stimulus.CreateGroup("templates")
+
+	general := root.CreateGroup("general")
+
+	if patient, ok := edfplus.ParsePatientIdentification(hdr.PatientID); ok && patient.Code != "" {
+		subject := general.CreateGroup("subject")
+		setNeurodataType(subject, "core", "Subject")
+		subject.NewStringDataset("subject_id", patient.Code)
+
+		switch patient.Sex {
+		case "F", "M":
+			subject.NewStringDataset("sex", patient.Sex)
+		default:
+			subject.NewStringDataset("sex", "U")
+		}
+
+		if !patient.Birthdate.IsZero() {
+			subject.NewStringDataset("date_of_birth", patient.Birthdate.Format(time.RFC3339))
+		}
+	}
+
+	device := general.CreateGroup("devices").CreateGroup("OpenPSG")
+	setNeurodataType(device, "core", "Device")
+	hdf5.SetStringAttribute(device, "description", "OpenPSG polysomnography recorder")
+	hdf5.SetStringAttribute(device, "manufacturer", "OpenPSG")
+
+	// Signals measured in volts are recorded by (surface) electrodes.
+	var electrodeLabels []string
+	for _, signal := range signals {
+		if _, ok := voltsConversion(signal.PhysicalDimension); ok {
+			electrodeLabels = append(electrodeLabels, signal.Label)
+		}
+	}
+
+	var electrodes *hdf5.Group
+	if len(electrodeLabels) > 0 {
+		ephys := general.CreateGroup("extracellular_ephys")
+
+		electrodeGroup := ephys.CreateGroup("OpenPSG")
+		setNeurodataType(electrodeGroup, "core", "ElectrodeGroup")
+		hdf5.SetStringAttribute(electrodeGroup, "description", "OpenPSG surface electrodes")
+		hdf5.SetStringAttribute(electrodeGroup, "location", "unknown")
+		electrodeGroup.Link("device", device)
+
+		ids := make([]byte, 0, 8*len(electrodeLabels))
+		groups := make([]hdf5.Object, len(electrodeLabels))
+		groupNames := make([]string, len(electrodeLabels))
+		for i := range electrodeLabels {
+			ids = binary.LittleEndian.AppendUint64(ids, uint64(i))
+			groups[i] = electrodeGroup
+			groupNames[i] = "OpenPSG"
+		}
+
+		electrodes = ephys.CreateGroup("electrodes")
+		setNeurodataType(electrodes, "hdmf-common", "DynamicTable")
+		hdf5.SetStringAttribute(electrodes, "description", "metadata about extracellular electrodes")
+		hdf5.SetStringsAttribute(electrodes, "colnames", []string{"location", "group", "group_name"})
+
+		id := electrodes.CreateDataset("id", hdf5.Int64, int64(len(electrodeLabels)))
+		id.SetData(ids)
+		setNeurodataType(id, "hdmf-common", "ElementIdentifiers")
+
+		location := electrodes.NewStringsDataset("location", electrodeLabels)
+		setVectorData(location, "the location of channel within the subject e.g. brain region")
+
+		group := electrodes.CreateDataset("group", hdf5.Reference, int64(len(electrodeLabels)))
+		group.SetReferences(groups)
+		setVectorData(group, "a reference to the ElectrodeGroup this electrode is a part of")
+
+		groupName := electrodes.NewStringsDataset("group_name", groupNames)
+		setVectorData(groupName, "the name of the ElectrodeGroup this electrode is a part of")
+	}
+
+	uniqueName := uniqueNamer()
+
+	datasets := make([]*hdf5.Dataset, len(signals))
+	var electrodeIndex int64
+	for i, signal := range signals {
+		series := acquisition.CreateGroup(uniqueName(signal.Label))
+
+		description := signal.TransducerType
+		if description == "" {
+			description = signal.Label
+		}
+		hdf5.SetStringAttribute(series, "description", description)
+		hdf5.SetStringAttribute(series, "comments", "no comments")
+
+		data := series.CreateDataset("data", hdf5.Float32, lengths[i])
+		datasets[i] = data
+
+		// The resolution is the size of a single digital step.
+		resolution := (signal.PhysicalMax - signal.PhysicalMin) / float64(signal.DigitalMax-signal.DigitalMin)
+
+		if conversion, ok := voltsConversion(signal.PhysicalDimension); ok {
+			setNeurodataType(series, "core", "ElectricalSeries")
+			if signal.Prefiltering != "" {
+				hdf5.SetStringAttribute(series, "filtering", signal.Prefiltering)
+			}
+
+			hdf5.SetStringAttribute(data, "unit", "volts")
+			hdf5.SetFloat32Attribute(data, "conversion", float32(conversion))
+			hdf5.SetFloat32Attribute(data, "resolution", float32(resolution*conversion))
+
+			region := series.CreateDataset("electrodes", hdf5.Int64, 1)
+			region.SetData(binary.LittleEndian.AppendUint64(nil, uint64(electrodeIndex)))
+			setNeurodataType(region, "hdmf-common", "DynamicTableRegion")
+			hdf5.SetStringAttribute(region, "description", "the electrode that recorded this signal")
+			hdf5.SetReferenceAttribute(region, "table", electrodes)
+			electrodeIndex++
+		} else {
+			setNeurodataType(series, "core", "TimeSeries")
+
+			unit := signal.PhysicalDimension
+			if unit == "" {
+				unit = "n/a"
+			}
+
+			hdf5.SetStringAttribute(data, "unit", unit)
+			hdf5.SetFloat32Attribute(data, "conversion", 1)
+			hdf5.SetFloat32Attribute(data, "resolution", float32(resolution))
+		}
+		hdf5.SetFloat32Attribute(data, "offset", 0)
+
+		startingTime := series.CreateDataset("starting_time", hdf5.Float64)
+		startingTime.SetData(binary.LittleEndian.AppendUint64(nil, 0))
+		hdf5.SetFloat32Attribute(startingTime, "rate", float32(sampleRate(hdr.DataRecordDuration, signal.SamplesPerRecord)))
+		hdf5.SetStringAttribute(startingTime, "unit", "seconds")
+	}
+
+	if len(annotations) > 0 {
+		var ids, startTimes, stopTimes []byte
+		labels := make([]string, len(annotations))
+		for i, a := range annotations {
+			ids = binary.LittleEndian.AppendUint64(ids, uint64(i))
+			startTimes = binary.LittleEndian.AppendUint64(startTimes, math.Float64bits(a.Onset.Seconds()))
+			stopTimes = binary.LittleEndian.AppendUint64(stopTimes, math.Float64bits((a.Onset + a.Duration).Seconds()))
+			labels[i] = a.Text
+		}
+
+		intervals := root.CreateGroup("intervals").CreateGroup("annotations")
+		setNeurodataType(intervals, "core", "TimeIntervals")
+		hdf5.SetStringAttribute(intervals, "description", "EDF+ annotations")
+		hdf5.SetStringsAttribute(intervals, "colnames", []string{"start_time", "stop_time", "label"})
+
+		id := intervals.CreateDataset("id", hdf5.Int64, int64(len(annotations)))
+		id.SetData(ids)
+		setNeurodataType(id, "hdmf-common", "ElementIdentifiers")
+
+		startTime := intervals.CreateDataset("start_time", hdf5.Float64, int64(len(annotations)))
+		startTime.SetData(startTimes)
+		setVectorData(startTime, "Start time of epoch, in seconds")
+
+		stopTime := intervals.CreateDataset("stop_time", hdf5.Float64, int64(len(annotations)))
+		stopTime.SetData(stopTimes)
+		setVectorData(stopTime, "Stop time of epoch, in seconds")
+
+		label := intervals.NewStringsDataset("label", labels)
+		setVectorData(label, "Annotation text")
+	}
+
+	out, err := os.Create(filepath.Join(dir, name+".nwb"))
+	if err != nil {
+		return fmt.Errorf("failed to create NWB file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := f.WriteMetadata(out); err != nil {
+		return fmt.Errorf("failed to write NWB metadata: %w", err)
+	}
+
+	if err := writeSignalValues(er, out, datasets); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// setNeurodataType sets the attributes that identify an NWB (or HDMF) object.
+func setNeurodataType(obj hdf5.Object, namespace, neurodataType string) {
+	hdf5.SetStringAttribute(obj, "namespace", namespace)
+	hdf5.SetStringAttribute(obj, "neurodata_type", neurodataType)
+	hdf5.SetStringAttribute(obj, "object_id", newObjectID())
+}
+
+// setVectorData marks a dataset as a column of a DynamicTable.
+func setVectorData(dataset *hdf5.Dataset, description string) {
+	setNeurodataType(dataset, "hdmf-common", "VectorData")
+	hdf5.SetStringAttribute(dataset, "description", description)
+}
+
+// voltsConversion returns the factor that converts values in the given unit to
+// volts, or false if the unit is not a measure of voltage.
+func voltsConversion(unit string) (float64, bool) {
+	switch unit {
+	case "V":
+		return 1, true
+	case "mV":
+		return 1e-3, true
+	case "uV", "µV":
+		return 1e-6, true
+	case "nV":
+		return 1e-9, true
+	}
+	return 0, false
+}
+
+// newObjectID returns a random (version 4) UUID.
+func newObjectID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
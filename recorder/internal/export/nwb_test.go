@@ -0,0 +1,232 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNWB(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.NWB(createRecording(t), dir, "test"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.nwb"))
+	require.NoError(t, err)
+	require.Equal(t, "\x89HDF\r\n\x1a\n", string(data[:8]))
+
+	f := &h5File{t: t, data: data}
+
+	root := f.lookup("/")
+	assert.Equal(t, "NWBFile", f.stringAttribute(root, "neurodata_type"))
+	assert.Equal(t, "2.7.0", f.stringAttribute(root, "nwb_version"))
+	assert.Equal(t, []string{recordingStartTime.Format(time.RFC3339)}, f.strings(f.lookup("/session_start_time")))
+
+	subject := f.lookup("/general/subject")
+	assert.Equal(t, []string{"MCH-0234567"}, f.strings(f.lookup("/general/subject/subject_id")))
+	assert.Equal(t, []string{"F"}, f.strings(f.lookup("/general/subject/sex")))
+	assert.Equal(t, "Subject", f.stringAttribute(subject, "neurodata_type"))
+
+	assert.Equal(t, []string{"EEG C4-M1"}, f.strings(f.lookup("/general/extracellular_ephys/electrodes/location")))
+
+	nan := float32(math.NaN())
+
+	// Voltage signals are electrical series, stored with a conversion to volts.
+	eeg := f.lookup("/acquisition/EEG C4-M1")
+	assert.Equal(t, "ElectricalSeries", f.stringAttribute(eeg, "neurodata_type"))
+	eegData := f.lookup("/acquisition/EEG C4-M1/data")
+	assert.Equal(t, "volts", f.stringAttribute(eegData, "unit"))
+	assert.Equal(t, float32(1e-6), f.float32Attribute(eegData, "conversion"))
+	assert.Equal(t, float32(4), f.float32Attribute(f.lookup("/acquisition/EEG C4-M1/starting_time"), "rate"))
+	assertFloat32s(t, []float32{1, 2, 3, 4, nan, nan, nan, nan, 5, 5, 5, 5}, f.float32s(eegData))
+
+	spo2 := f.lookup("/acquisition/SpO2")
+	assert.Equal(t, "TimeSeries", f.stringAttribute(spo2, "neurodata_type"))
+	spo2Data := f.lookup("/acquisition/SpO2/data")
+	assert.Equal(t, "%", f.stringAttribute(spo2Data, "unit"))
+	assert.Equal(t, float32(2), f.float32Attribute(f.lookup("/acquisition/SpO2/starting_time"), "rate"))
+	assertFloat32s(t, []float32{5, 6, nan, nan, 7, 7}, f.float32s(spo2Data))
+
+	// Annotations are sorted by onset.
+	assert.Equal(t, []string{"Lights off", "Obstructive apnea", "Lights on"}, f.strings(f.lookup("/intervals/annotations/label")))
+	assert.Equal(t, []float64{0.5, 2.25, 1000}, f.float64s(f.lookup("/intervals/annotations/start_time")))
+	assert.Equal(t, []float64{0.5, 12.25, 1000}, f.float64s(f.lookup("/intervals/annotations/stop_time")))
+}
+
+func assertFloat32s(t *testing.T, expected, actual []float32) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		if math.IsNaN(float64(expected[i])) {
+			assert.True(t, math.IsNaN(float64(actual[i])), "value %d is %v, expected NaN", i, actual[i])
+		} else {
+			assert.Equal(t, expected[i], actual[i], "value %d", i)
+		}
+	}
+}
+
+const (
+	h5MessageDatatype    = 0x0003
+	h5MessageLayout      = 0x0008
+	h5MessageAttribute   = 0x000C
+	h5MessageSymbolTable = 0x0011
+)
+
+// h5File reads the (version 0 superblock) HDF5 files written by the hdf5
+// package.
+type h5File struct {
+	t    *testing.T
+	data []byte
+}
+
+// lookup returns the object header address of the object at path.
+func (f *h5File) lookup(path string) uint64 {
+	addr := binary.LittleEndian.Uint64(f.data[64:])
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+
+		symbolTable := f.message(addr, h5MessageSymbolTable, "")
+		require.NotNil(f.t, symbolTable, "%s is not a group", path)
+
+		links := f.links(binary.LittleEndian.Uint64(symbolTable), binary.LittleEndian.Uint64(symbolTable[8:]))
+		var ok bool
+		addr, ok = links[name]
+		require.True(f.t, ok, "%s not found", path)
+	}
+	return addr
+}
+
+// links reads the links of a group from its B-tree and local heap.
+func (f *h5File) links(btreeAddr, heapAddr uint64) map[string]uint64 {
+	require.Equal(f.t, "TREE", string(f.data[btreeAddr:btreeAddr+4]))
+	require.Equal(f.t, "HEAP", string(f.data[heapAddr:heapAddr+4]))
+
+	heap := f.data[binary.LittleEndian.Uint64(f.data[heapAddr+24:]):]
+
+	links := make(map[string]uint64)
+	if binary.LittleEndian.Uint16(f.data[btreeAddr+6:]) == 0 {
+		return links
+	}
+
+	snodAddr := binary.LittleEndian.Uint64(f.data[btreeAddr+32:])
+	require.Equal(f.t, "SNOD", string(f.data[snodAddr:snodAddr+4]))
+
+	entries := binary.LittleEndian.Uint16(f.data[snodAddr+6:])
+	for i := uint64(0); i < uint64(entries); i++ {
+		entry := f.data[snodAddr+8+40*i:]
+		name := heap[binary.LittleEndian.Uint64(entry):]
+		links[string(name[:bytes.IndexByte(name, 0)])] = binary.LittleEndian.Uint64(entry[8:])
+	}
+	return links
+}
+
+// message returns the first message of the given type in an object header, or
+// for attributes the attribute with the given name.
+func (f *h5File) message(addr uint64, messageType uint16, name string) []byte {
+	messages := binary.LittleEndian.Uint16(f.data[addr+2:])
+	offset := addr + 16
+	for range messages {
+		size := uint64(binary.LittleEndian.Uint16(f.data[offset+2:]))
+		message := f.data[offset+8 : offset+8+size]
+		if binary.LittleEndian.Uint16(f.data[offset:]) == messageType {
+			if messageType != h5MessageAttribute {
+				return message
+			}
+
+			nameSize := binary.LittleEndian.Uint16(message[2:])
+			if string(message[8:8+nameSize-1]) == name {
+				return message
+			}
+		}
+		offset += 8 + size
+	}
+	return nil
+}
+
+// attribute returns the datatype message and value of an attribute.
+func (f *h5File) attribute(addr uint64, name string) ([]byte, []byte) {
+	message := f.message(addr, h5MessageAttribute, name)
+	require.NotNil(f.t, message, "attribute %s not found", name)
+
+	pad8 := func(n uint16) int { return (int(n) + 7) &^ 7 }
+	nameSize := binary.LittleEndian.Uint16(message[2:])
+	datatypeSize := binary.LittleEndian.Uint16(message[4:])
+	dataspaceSize := binary.LittleEndian.Uint16(message[6:])
+
+	datatype := message[8+pad8(nameSize):]
+	value := datatype[pad8(datatypeSize)+pad8(dataspaceSize):]
+	return datatype[:datatypeSize], value
+}
+
+func (f *h5File) stringAttribute(addr uint64, name string) string {
+	datatype, value := f.attribute(addr, name)
+	return string(bytes.TrimRight(value[:binary.LittleEndian.Uint32(datatype[4:])], "\x00"))
+}
+
+func (f *h5File) float32Attribute(addr uint64, name string) float32 {
+	_, value := f.attribute(addr, name)
+	return math.Float32frombits(binary.LittleEndian.Uint32(value))
+}
+
+// values returns the contiguous values of a dataset.
+func (f *h5File) values(addr uint64) []byte {
+	layout := f.message(addr, h5MessageLayout, "")
+	require.NotNil(f.t, layout, "object is not a dataset")
+
+	dataAddr := binary.LittleEndian.Uint64(layout[2:])
+	return f.data[dataAddr : dataAddr+binary.LittleEndian.Uint64(layout[10:])]
+}
+
+func (f *h5File) strings(addr uint64) []string {
+	size := int(binary.LittleEndian.Uint32(f.message(addr, h5MessageDatatype, "")[4:]))
+
+	var values []string
+	for b := f.values(addr); len(b) > 0; b = b[size:] {
+		values = append(values, string(bytes.TrimRight(b[:size], "\x00")))
+	}
+	return values
+}
+
+func (f *h5File) float32s(addr uint64) []float32 {
+	var values []float32
+	for b := f.values(addr); len(b) > 0; b = b[4:] {
+		values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	return values
+}
+
+func (f *h5File) float64s(addr uint64) []float64 {
+	var values []float64
+	for b := f.values(addr); len(b) > 0; b = b[8:] {
+		values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	}
+	return values
+}
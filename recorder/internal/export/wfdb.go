@@ -0,0 +1,258 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package export converts EDF recordings into other file formats.
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+const (
+	// WFDB format 16 reserves the most negative value to mark invalid samples.
+	wfdbInvalidSample = math.MinInt16
+	// MIT annotation codes.
+	wfdbNote = 22
+	wfdbSkip = 59
+	wfdbAux  = 63
+)
+
+// WFDB writes the recording as a WFDB (PhysioNet) record with the given name
+// in dir. The record consists of a header file (.hea), a signal file (.dat) in
+// format 16, and an annotation file (.atr) holding any EDF+ annotations. Gaps
+// in discontinuous recordings are filled with invalid samples.
+func WFDB(er *edfplus.Reader, dir, name string) error {
+	signals := er.Signals()
+	if len(signals) == 0 {
+		return fmt.Errorf("recording has no signals")
+	}
+
+	hdr := er.Header()
+
+	// WFDB records have a single frame rate, with each signal storing a whole
+	// number of samples per frame.
+	framesPerRecord := signals[0].SamplesPerRecord
+	for _, signal := range signals[1:] {
+		framesPerRecord = gcd(framesPerRecord, signal.SamplesPerRecord)
+	}
+	if framesPerRecord == 0 {
+		return fmt.Errorf("recording has signals without samples")
+	}
+
+	frameRate := float64(framesPerRecord) / hdr.DataRecordDuration.Seconds()
+
+	samplesPerFrame := make([]int, len(signals))
+	for i, signal := range signals {
+		samplesPerFrame[i] = signal.SamplesPerRecord / framesPerRecord
+	}
+
+	datFile, err := os.Create(filepath.Join(dir, name+".dat"))
+	if err != nil {
+		return fmt.Errorf("failed to create signal file: %w", err)
+	}
+	defer datFile.Close()
+
+	w := bufio.NewWriter(datFile)
+
+	checksums := make([]int16, len(signals))
+	initialValues := make([]int16, len(signals))
+
+	frameSize := 0
+	for _, n := range samplesPerFrame {
+		frameSize += 2 * n
+	}
+	frame := make([]byte, frameSize)
+
+	// writeFrame writes the next frame, with the kth sample of the ith signal
+	// taken from sample(i, k).
+	var frames int64
+	writeFrame := func(sample func(i, k int) int16) error {
+		offset := 0
+		for i := range signals {
+			for k := 0; k < samplesPerFrame[i]; k++ {
+				value := sample(i, k)
+				if frames == 0 && k == 0 {
+					initialValues[i] = value
+				}
+				checksums[i] += value
+
+				binary.LittleEndian.PutUint16(frame[offset:], uint16(value))
+				offset += 2
+			}
+		}
+		frames++
+
+		_, err := w.Write(frame)
+		return err
+	}
+
+	type wfdbAnnotation struct {
+		frame int64
+		text  string
+	}
+	var annotations []wfdbAnnotation
+
+	for {
+		record, err := er.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		// Fill any gap before the data record with invalid samples.
+		startFrame := int64(math.Round(record.Onset.Seconds() * frameRate))
+		for frames < startFrame {
+			if err := writeFrame(func(i, k int) int16 { return wfdbInvalidSample }); err != nil {
+				return fmt.Errorf("failed to write samples: %w", err)
+			}
+		}
+
+		for f := 0; f < framesPerRecord; f++ {
+			err := writeFrame(func(i, k int) int16 {
+				sample := record.Samples[i][f*samplesPerFrame[i]+k]
+				// Don't let valid samples be mistaken for invalid ones.
+				if sample == wfdbInvalidSample {
+					sample++
+				}
+				return sample
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write samples: %w", err)
+			}
+		}
+
+		for _, a := range record.Annotations {
+			annotations = append(annotations, wfdbAnnotation{
+				frame: int64(math.Round(a.Onset.Seconds() * frameRate)),
+				text:  a.Text,
+			})
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write samples: %w", err)
+	}
+
+	if err := datFile.Close(); err != nil {
+		return fmt.Errorf("failed to close signal file: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %d %s %d %s %s\n", name, len(signals), strconv.FormatFloat(frameRate, 'f', -1, 64),
+		frames, hdr.StartTime.Format("15:04:05"), hdr.StartTime.Format("02/01/2006"))
+
+	for i, signal := range signals {
+		format := "16"
+		if samplesPerFrame[i] > 1 {
+			format += "x" + strconv.Itoa(samplesPerFrame[i])
+		}
+
+		gain := float64(signal.DigitalMax-signal.DigitalMin) / (signal.PhysicalMax - signal.PhysicalMin)
+		baseline := int(math.Round(float64(signal.DigitalMin) - signal.PhysicalMin*gain))
+
+		units := strings.ReplaceAll(signal.PhysicalDimension, " ", "_")
+		if units == "" {
+			units = "NU"
+		}
+
+		fmt.Fprintf(&sb, "%s.dat %s %s(%d)/%s 16 0 %d %d 0 %s\n", name, format,
+			strconv.FormatFloat(gain, 'g', -1, 64), baseline, units, initialValues[i], checksums[i], signal.Label)
+	}
+
+	for _, info := range []string{hdr.PatientID, hdr.RecordingID} {
+		if info != "" {
+			fmt.Fprintf(&sb, "# %s\n", info)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name+".hea"), []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write header file: %w", err)
+	}
+
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].frame < annotations[j].frame
+	})
+
+	// Annotations are stored in the MIT format as NOTE annotations with the
+	// text as the auxiliary information.
+	var atr []byte
+	appendWord := func(code, value int) {
+		atr = binary.LittleEndian.AppendUint16(atr, uint16(code<<10|value))
+	}
+
+	var lastFrame int64
+	for _, a := range annotations {
+		interval := max(a.frame-lastFrame, 0)
+		lastFrame += interval
+
+		if interval > 1023 {
+			appendWord(wfdbSkip, 0)
+			// Skip intervals are stored as PDP-11 longs (high word first).
+			atr = binary.LittleEndian.AppendUint16(atr, uint16(interval>>16))
+			atr = binary.LittleEndian.AppendUint16(atr, uint16(interval))
+			interval = 0
+		}
+
+		appendWord(wfdbNote, int(interval))
+
+		text := a.text
+		if len(text) > 255 {
+			text = text[:255]
+		}
+
+		if text != "" {
+			appendWord(wfdbAux, len(text))
+			atr = append(atr, text...)
+			if len(text)%2 != 0 {
+				atr = append(atr, 0)
+			}
+		}
+	}
+	appendWord(0, 0)
+
+	if err := os.WriteFile(filepath.Join(dir, name+".atr"), atr, 0o644); err != nil {
+		return fmt.Errorf("failed to write annotation file: %w", err)
+	}
+
+	return nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,94 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package export_test
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWFDB(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.WFDB(createRecording(t), dir, "test"))
+
+	hea, err := os.ReadFile(filepath.Join(dir, "test.hea"))
+	require.NoError(t, err)
+
+	// 2 frames per second, with 2 EEG samples and 1 SpO2 sample per frame.
+	assert.Equal(t, "test 2 2 6 22:30:00 03/02/2025\n"+
+		"test.dat 16x2 10(0)/uV 16 0 10 300 0 EEG C4-M1\n"+
+		"test.dat 16 10(0)/% 16 0 50 250 0 SpO2\n"+
+		"# MCH-0234567 F 02-MAY-1951 Haagse_Harry\n"+
+		"# Startdate 03-FEB-2025 X X X\n", string(hea))
+
+	// The gap is filled with invalid samples.
+	const invalid = math.MinInt16
+	var expected []byte
+	for _, sample := range []int16{
+		10, 20, 50,
+		30, 40, 60,
+		invalid, invalid, invalid,
+		invalid, invalid, invalid,
+		50, 50, 70,
+		50, 50, 70,
+	} {
+		expected = binary.LittleEndian.AppendUint16(expected, uint16(sample))
+	}
+
+	dat, err := os.ReadFile(filepath.Join(dir, "test.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, dat)
+
+	word := func(code, value int) []byte {
+		return binary.LittleEndian.AppendUint16(nil, uint16(code<<10|value))
+	}
+
+	const (
+		note = 22
+		skip = 59
+		aux  = 63
+	)
+
+	expected = nil
+	expected = append(expected, word(note, 1)...)
+	expected = append(expected, word(aux, 10)...)
+	expected = append(expected, "Lights off"...)
+	expected = append(expected, word(note, 4)...)
+	expected = append(expected, word(aux, 17)...)
+	expected = append(expected, "Obstructive apnea\x00"...)
+	// Intervals too long for an annotation are stored in a SKIP (PDP-11 long).
+	expected = append(expected, word(skip, 0)...)
+	expected = append(expected, 0, 0, 0xcb, 0x07)
+	expected = append(expected, word(note, 0)...)
+	expected = append(expected, word(aux, 9)...)
+	expected = append(expected, "Lights on\x00"...)
+	expected = append(expected, word(0, 0)...)
+
+	atr, err := os.ReadFile(filepath.Join(dir, "test.atr"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, atr)
+}
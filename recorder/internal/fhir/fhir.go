@@ -0,0 +1,304 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package fhir generates HL7 FHIR (R4) resources describing a recording, for
+// integration with electronic health record systems.
+package fhir
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// Recording summarizes a completed recording.
+type Recording struct {
+	// Absolute path of the EDF file.
+	Path string
+	// Size of the EDF file in bytes.
+	Size int64
+	// SHA-1 hash of the EDF file.
+	SHA1 []byte
+	// Patient identifier (EDF+ patient code).
+	PatientID string
+	// Recording identifier (EDF+ recording code).
+	RecordingID string
+	// Start and end time of the recording.
+	Start, End time.Time
+	// The recorded signals.
+	Signals []Signal
+}
+
+// Signal describes a recorded signal.
+type Signal struct {
+	Label      string
+	Unit       string
+	SampleRate float64
+}
+
+// Bundle is a FHIR Bundle resource.
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+// BundleEntry is an entry in a Bundle.
+type BundleEntry struct {
+	FullURL  string         `json:"fullUrl"`
+	Resource any            `json:"resource"`
+	Request  *BundleRequest `json:"request,omitempty"`
+}
+
+// BundleRequest describes how a transaction entry is processed.
+type BundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// Device is a FHIR Device resource.
+type Device struct {
+	ResourceType string       `json:"resourceType"`
+	Status       string       `json:"status"`
+	Manufacturer string       `json:"manufacturer,omitempty"`
+	DeviceName   []DeviceName `json:"deviceName,omitempty"`
+}
+
+// DeviceName is a name of a Device.
+type DeviceName struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Observation is a FHIR Observation resource.
+type Observation struct {
+	ResourceType    string                 `json:"resourceType"`
+	Status          string                 `json:"status"`
+	Code            CodeableConcept        `json:"code"`
+	Subject         *Reference             `json:"subject,omitempty"`
+	EffectivePeriod *Period                `json:"effectivePeriod,omitempty"`
+	Device          *Reference             `json:"device,omitempty"`
+	Component       []ObservationComponent `json:"component,omitempty"`
+}
+
+// ObservationComponent is a component result of an Observation.
+type ObservationComponent struct {
+	Code          CodeableConcept `json:"code"`
+	ValueQuantity *Quantity       `json:"valueQuantity,omitempty"`
+}
+
+// DocumentReference is a FHIR DocumentReference resource.
+type DocumentReference struct {
+	ResourceType string                     `json:"resourceType"`
+	Status       string                     `json:"status"`
+	Type         *CodeableConcept           `json:"type,omitempty"`
+	Subject      *Reference                 `json:"subject,omitempty"`
+	Date         string                     `json:"date,omitempty"`
+	Description  string                     `json:"description,omitempty"`
+	Content      []DocumentReferenceContent `json:"content"`
+	Context      *DocumentReferenceContext  `json:"context,omitempty"`
+}
+
+// DocumentReferenceContent is the document referenced.
+type DocumentReferenceContent struct {
+	Attachment Attachment `json:"attachment"`
+}
+
+// DocumentReferenceContext is the clinical context of a document.
+type DocumentReferenceContext struct {
+	Period  *Period     `json:"period,omitempty"`
+	Related []Reference `json:"related,omitempty"`
+}
+
+// Attachment refers to content stored elsewhere.
+type Attachment struct {
+	ContentType string `json:"contentType,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Hash        string `json:"hash,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Creation    string `json:"creation,omitempty"`
+}
+
+// Reference is a reference to another resource.
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
+}
+
+// Identifier is a business identifier.
+type Identifier struct {
+	Value string `json:"value"`
+}
+
+// CodeableConcept is a concept, described by text.
+type CodeableConcept struct {
+	Text string `json:"text"`
+}
+
+// Period is a time range.
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Quantity is a measured amount, using UCUM units.
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+const ucumSystem = "http://unitsofmeasure.org"
+
+// NewRecordingBundle returns a transaction Bundle holding a Device (the
+// recorder), an Observation summarizing the recording, and a
+// DocumentReference pointing at the EDF file.
+func NewRecordingBundle(rec Recording) *Bundle {
+	deviceURL := newUUIDURL()
+	observationURL := newUUIDURL()
+	documentURL := newUUIDURL()
+
+	var subject *Reference
+	if rec.PatientID != "" {
+		subject = &Reference{Identifier: &Identifier{Value: rec.PatientID}}
+	}
+
+	period := &Period{
+		Start: rec.Start.Format(time.RFC3339),
+		End:   rec.End.Format(time.RFC3339),
+	}
+
+	device := &Device{
+		ResourceType: "Device",
+		Status:       "active",
+		Manufacturer: "OpenPSG",
+		DeviceName:   []DeviceName{{Name: "OpenPSG Recorder", Type: "model-name"}},
+	}
+
+	observation := &Observation{
+		ResourceType:    "Observation",
+		Status:          "final",
+		Code:            CodeableConcept{Text: "Polysomnography recording"},
+		Subject:         subject,
+		EffectivePeriod: period,
+		Device:          &Reference{Reference: deviceURL},
+		Component: []ObservationComponent{{
+			Code:          CodeableConcept{Text: "Recording duration"},
+			ValueQuantity: &Quantity{Value: rec.End.Sub(rec.Start).Seconds(), Unit: "s", System: ucumSystem, Code: "s"},
+		}},
+	}
+
+	for _, signal := range rec.Signals {
+		observation.Component = append(observation.Component, ObservationComponent{
+			Code:          CodeableConcept{Text: signal.Label + " sample rate"},
+			ValueQuantity: &Quantity{Value: signal.SampleRate, Unit: "Hz", System: ucumSystem, Code: "Hz"},
+		})
+	}
+
+	description := "Polysomnography recording"
+	if rec.RecordingID != "" {
+		description += " " + rec.RecordingID
+	}
+
+	fileURL := url.URL{Scheme: "file", Path: filepath.ToSlash(rec.Path)}
+
+	document := &DocumentReference{
+		ResourceType: "DocumentReference",
+		Status:       "current",
+		Type:         &CodeableConcept{Text: "European Data Format (EDF+) recording"},
+		Subject:      subject,
+		Date:         time.Now().Format(time.RFC3339),
+		Description:  description,
+		Content: []DocumentReferenceContent{{
+			Attachment: Attachment{
+				ContentType: "application/octet-stream",
+				URL:         fileURL.String(),
+				Size:        rec.Size,
+				Hash:        base64.StdEncoding.EncodeToString(rec.SHA1),
+				Title:       filepath.Base(rec.Path),
+				Creation:    rec.Start.Format(time.RFC3339),
+			},
+		}},
+		Context: &DocumentReferenceContext{
+			Period:  period,
+			Related: []Reference{{Reference: observationURL}},
+		},
+	}
+
+	return &Bundle{
+		ResourceType: "Bundle",
+		Type:         "transaction",
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Entry: []BundleEntry{
+			{FullURL: deviceURL, Resource: device, Request: &BundleRequest{Method: http.MethodPost, URL: "Device"}},
+			{FullURL: observationURL, Resource: observation, Request: &BundleRequest{Method: http.MethodPost, URL: "Observation"}},
+			{FullURL: documentURL, Resource: document, Request: &BundleRequest{Method: http.MethodPost, URL: "DocumentReference"}},
+		},
+	}
+}
+
+// Post submits the bundle as a transaction to the FHIR server at endpoint
+// (the service base URL).
+func Post(ctx context.Context, client *http.Client, endpoint string, bundle *Bundle) error {
+	body, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/fhir+json")
+	req.Header.Set("Accept", "application/fhir+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post bundle: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("FHIR server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// newUUIDURL returns a random (version 4) UUID URN, used to reference
+// resources within a bundle.
+func newUUIDURL() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
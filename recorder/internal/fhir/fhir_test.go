@@ -0,0 +1,95 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fhir_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/fhir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRecordingBundle(t *testing.T) {
+	start := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.UTC)
+
+	bundle := fhir.NewRecordingBundle(fhir.Recording{
+		Path:      "/data/openpsg.edf",
+		Size:      1024,
+		SHA1:      []byte{0xde, 0xad, 0xbe, 0xef},
+		PatientID: "MCH-0234567",
+		Start:     start,
+		End:       start.Add(8 * time.Hour),
+		Signals:   []fhir.Signal{{Label: "EEG C3", Unit: "uV", SampleRate: 256}},
+	})
+
+	assert.Equal(t, "transaction", bundle.Type)
+	require.Len(t, bundle.Entry, 3)
+
+	observation, ok := bundle.Entry[1].Resource.(*fhir.Observation)
+	require.True(t, ok)
+
+	assert.Equal(t, bundle.Entry[0].FullURL, observation.Device.Reference)
+	assert.Equal(t, "MCH-0234567", observation.Subject.Identifier.Value)
+	require.Len(t, observation.Component, 2)
+	assert.Equal(t, float64(8*60*60), observation.Component[0].ValueQuantity.Value)
+	assert.Equal(t, float64(256), observation.Component[1].ValueQuantity.Value)
+
+	document, ok := bundle.Entry[2].Resource.(*fhir.DocumentReference)
+	require.True(t, ok)
+
+	attachment := document.Content[0].Attachment
+	assert.Equal(t, "file:///data/openpsg.edf", attachment.URL)
+	assert.Equal(t, "3q2+7w==", attachment.Hash)
+	assert.Equal(t, bundle.Entry[1].FullURL, document.Context.Related[0].Reference)
+}
+
+func TestPost(t *testing.T) {
+	var received map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/fhir+json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &received))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	bundle := fhir.NewRecordingBundle(fhir.Recording{Path: "/openpsg.edf", Start: time.Now(), End: time.Now()})
+
+	require.NoError(t, fhir.Post(context.Background(), srv.Client(), srv.URL, bundle))
+	assert.Equal(t, "Bundle", received["resourceType"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid bundle", http.StatusBadRequest)
+	}))
+	t.Cleanup(failing.Close)
+
+	err := fhir.Post(context.Background(), failing.Client(), failing.URL, bundle)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid bundle")
+}
@@ -0,0 +1,566 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package hdf5 is a minimal HDF5 file writer. It supports nested groups (and
+// hard links), contiguous datasets, and attributes. Only the original
+// (version 0 superblock) file format structures are used, so files can be
+// read by any HDF5 library release.
+//
+// Large datasets are not buffered in memory, instead the metadata is written
+// first and the caller writes the dataset values at the offsets assigned
+// during layout.
+package hdf5
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Datatype is an HDF5 datatype.
+type Datatype struct {
+	message []byte
+	size    int
+}
+
+// Size returns the size of a single element of the datatype in bytes.
+func (dt Datatype) Size() int {
+	return dt.size
+}
+
+var (
+	// Int16 is a little-endian, signed 16-bit integer.
+	Int16 = Datatype{message: []byte{0x10, 0x08, 0x00, 0x00, 2, 0, 0, 0, 0, 0, 16, 0}, size: 2}
+	// Int32 is a little-endian, signed 32-bit integer.
+	Int32 = Datatype{message: []byte{0x10, 0x08, 0x00, 0x00, 4, 0, 0, 0, 0, 0, 32, 0}, size: 4}
+	// Int64 is a little-endian, signed 64-bit integer.
+	Int64 = Datatype{message: []byte{0x10, 0x08, 0x00, 0x00, 8, 0, 0, 0, 0, 0, 64, 0}, size: 8}
+	// Float32 is a little-endian, IEEE 754 single precision float.
+	Float32 = Datatype{message: []byte{0x11, 0x20, 0x1f, 0x00, 4, 0, 0, 0, 0, 0, 32, 0, 23, 8, 0, 23, 127, 0, 0, 0}, size: 4}
+	// Float64 is a little-endian, IEEE 754 double precision float.
+	Float64 = Datatype{message: []byte{0x11, 0x20, 0x3f, 0x00, 8, 0, 0, 0, 0, 0, 64, 0, 52, 11, 0, 52, 0xff, 0x03, 0, 0}, size: 8}
+	// Reference is a reference to an object (group or dataset).
+	Reference = Datatype{message: []byte{0x17, 0x00, 0x00, 0x00, 8, 0, 0, 0}, size: 8}
+)
+
+// String returns a fixed length, null padded, UTF-8 string datatype.
+func String(size int) Datatype {
+	return Datatype{message: binary.LittleEndian.AppendUint32([]byte{0x13, 0x11, 0x00, 0x00}, uint32(size)), size: size}
+}
+
+// Object is a group or dataset.
+type Object interface {
+	header() *objectHeader
+}
+
+// File is an HDF5 file under construction.
+type File struct {
+	root *Group
+}
+
+// NewFile creates a new, empty, HDF5 file.
+func NewFile() *File {
+	return &File{root: &Group{}}
+}
+
+// Root returns the root group of the file.
+func (f *File) Root() *Group {
+	return f.root
+}
+
+type objectHeader struct {
+	attributes []attribute
+	addr       int64
+	refCount   int
+}
+
+func (oh *objectHeader) header() *objectHeader {
+	return oh
+}
+
+type attribute struct {
+	name     string
+	datatype Datatype
+	dims     []int64
+	data     []byte
+	refs     []Object
+}
+
+func (oh *objectHeader) setAttribute(a attribute) {
+	for i := range oh.attributes {
+		if oh.attributes[i].name == a.name {
+			oh.attributes[i] = a
+			return
+		}
+	}
+	oh.attributes = append(oh.attributes, a)
+}
+
+// SetStringAttribute sets a string attribute on the object.
+func SetStringAttribute(obj Object, name, value string) {
+	dt, data := encodeStrings([]string{value})
+	obj.header().setAttribute(attribute{name: name, datatype: dt, data: data})
+}
+
+// SetStringsAttribute sets a one dimensional array of strings attribute on the object.
+func SetStringsAttribute(obj Object, name string, values []string) {
+	dt, data := encodeStrings(values)
+	obj.header().setAttribute(attribute{name: name, datatype: dt, dims: []int64{int64(len(values))}, data: data})
+}
+
+// SetFloat32Attribute sets a float32 attribute on the object.
+func SetFloat32Attribute(obj Object, name string, value float32) {
+	obj.header().setAttribute(attribute{name: name, datatype: Float32,
+		data: binary.LittleEndian.AppendUint32(nil, math.Float32bits(value))})
+}
+
+// SetFloat64Attribute sets a float64 attribute on the object.
+func SetFloat64Attribute(obj Object, name string, value float64) {
+	obj.header().setAttribute(attribute{name: name, datatype: Float64,
+		data: binary.LittleEndian.AppendUint64(nil, math.Float64bits(value))})
+}
+
+// SetInt64Attribute sets an int64 attribute on the object.
+func SetInt64Attribute(obj Object, name string, value int64) {
+	obj.header().setAttribute(attribute{name: name, datatype: Int64,
+		data: binary.LittleEndian.AppendUint64(nil, uint64(value))})
+}
+
+// SetReferenceAttribute sets an object reference attribute on the object.
+func SetReferenceAttribute(obj Object, name string, target Object) {
+	obj.header().setAttribute(attribute{name: name, datatype: Reference, refs: []Object{target}})
+}
+
+// Group is a group of links to other objects.
+type Group struct {
+	objectHeader
+	links []link
+
+	btreeAddr    int64
+	snodAddr     int64
+	heapAddr     int64
+	heap         []byte
+	sortedLinks  []link
+	nameOffsets  []uint64
+	freeBlockOff uint64
+}
+
+type link struct {
+	name   string
+	target Object
+}
+
+// CreateGroup creates a new group within the group.
+func (g *Group) CreateGroup(name string) *Group {
+	group := &Group{}
+	g.Link(name, group)
+	return group
+}
+
+// CreateDataset creates a new dataset within the group. If no dimensions are
+// specified the dataset is a scalar.
+func (g *Group) CreateDataset(name string, datatype Datatype, dims ...int64) *Dataset {
+	dataset := &Dataset{datatype: datatype, dims: dims}
+	g.Link(name, dataset)
+	return dataset
+}
+
+// Link adds a hard link to an existing object to the group.
+func (g *Group) Link(name string, target Object) {
+	g.links = append(g.links, link{name: name, target: target})
+}
+
+// Dataset is a contiguous array of values.
+type Dataset struct {
+	objectHeader
+	datatype Datatype
+	dims     []int64
+	data     []byte
+	refs     []Object
+
+	dataAddr int64
+}
+
+// SetData sets the (small) contents of the dataset, which will be written
+// along with the metadata.
+func (d *Dataset) SetData(data []byte) {
+	d.data = data
+}
+
+// SetReferences sets the contents of a Reference dataset.
+func (d *Dataset) SetReferences(targets []Object) {
+	d.refs = targets
+}
+
+// DataOffset returns the file offset of the dataset values. Only valid after
+// the metadata has been written.
+func (d *Dataset) DataOffset() int64 {
+	return d.dataAddr
+}
+
+// DataSize returns the size of the dataset values in bytes.
+func (d *Dataset) DataSize() int64 {
+	size := int64(d.datatype.size)
+	for _, dim := range d.dims {
+		size *= dim
+	}
+	return size
+}
+
+// NewStringsDataset creates a one dimensional dataset of strings.
+func (g *Group) NewStringsDataset(name string, values []string) *Dataset {
+	dt, data := encodeStrings(values)
+	dataset := g.CreateDataset(name, dt, int64(len(values)))
+	dataset.SetData(data)
+	return dataset
+}
+
+// NewStringDataset creates a scalar string dataset.
+func (g *Group) NewStringDataset(name, value string) *Dataset {
+	dt, data := encodeStrings([]string{value})
+	dataset := g.CreateDataset(name, dt)
+	dataset.SetData(data)
+	return dataset
+}
+
+func encodeStrings(values []string) (Datatype, []byte) {
+	size := 1
+	for _, value := range values {
+		size = max(size, len(value))
+	}
+
+	data := make([]byte, size*len(values))
+	for i, value := range values {
+		copy(data[i*size:], value)
+	}
+
+	return String(size), data
+}
+
+const (
+	undefinedAddress = math.MaxUint64
+	// Terminates the free list of a local heap.
+	freeListEnd = 1
+
+	messageDataspace   = 0x0001
+	messageDatatype    = 0x0003
+	messageFillValue   = 0x0005
+	messageLayout      = 0x0008
+	messageAttribute   = 0x000C
+	messageSymbolTable = 0x0011
+
+	superblockSize = 96
+	btreeK         = 16
+	btreeSize      = 24 + 2*btreeK*16 + 8
+	localHeapSize  = 32
+)
+
+// WriteMetadata lays out the file and writes its metadata (and any dataset
+// values set with SetData). It returns the total size of the file, the caller
+// must write the values of all other datasets before the file is complete.
+func (f *File) WriteMetadata(w io.WriterAt) (int64, error) {
+	// Find all of the objects in the file.
+	var objects []Object
+	visited := make(map[Object]bool)
+	var visit func(obj Object)
+	visit = func(obj Object) {
+		if visited[obj] {
+			obj.header().refCount++
+			return
+		}
+		visited[obj] = true
+		obj.header().refCount = 1
+		objects = append(objects, obj)
+
+		if g, ok := obj.(*Group); ok {
+			for _, l := range g.links {
+				visit(l.target)
+			}
+		}
+	}
+	visit(f.root)
+
+	// Symbol table nodes hold up to 2K entries, use a single node per group.
+	leafK := 4
+	for _, obj := range objects {
+		if g, ok := obj.(*Group); ok {
+			leafK = max(leafK, (len(g.links)+1)/2)
+		}
+	}
+	snodSize := int64(8 + 2*leafK*40)
+
+	// Assign the addresses of all of the metadata.
+	addr := int64(superblockSize)
+	for _, obj := range objects {
+		oh := obj.header()
+		oh.addr = addr
+
+		switch obj := obj.(type) {
+		case *Group:
+			obj.layoutHeap()
+
+			addr += int64(len(encodeObjectHeader(obj.refCount, obj.messages())))
+			obj.btreeAddr = addr
+			addr += btreeSize
+			obj.snodAddr = addr
+			if len(obj.links) > 0 {
+				addr += snodSize
+			}
+			obj.heapAddr = addr
+			addr += localHeapSize + int64(len(obj.heap))
+		case *Dataset:
+			addr += int64(len(encodeObjectHeader(obj.refCount, obj.messages())))
+		}
+	}
+
+	// Followed by the dataset values.
+	for _, obj := range objects {
+		if d, ok := obj.(*Dataset); ok {
+			d.dataAddr = addr
+			addr += d.DataSize()
+		}
+	}
+	eofAddr := addr
+
+	// Superblock (version 0).
+	b := []byte("\x89HDF\r\n\x1a\n")
+	b = append(b, 0, 0, 0, 0, 0, 8, 8, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(leafK))
+	b = binary.LittleEndian.AppendUint16(b, btreeK)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, undefinedAddress)
+	b = binary.LittleEndian.AppendUint64(b, uint64(eofAddr))
+	b = binary.LittleEndian.AppendUint64(b, undefinedAddress)
+	// Root group symbol table entry, caching the symbol table addresses.
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(f.root.addr))
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(f.root.btreeAddr))
+	b = binary.LittleEndian.AppendUint64(b, uint64(f.root.heapAddr))
+
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *Group:
+			b = append(b, encodeObjectHeader(obj.refCount, obj.messages())...)
+			b = obj.appendSymbolTable(b, leafK)
+		case *Dataset:
+			b = append(b, encodeObjectHeader(obj.refCount, obj.messages())...)
+		}
+	}
+
+	if _, err := w.WriteAt(b, 0); err != nil {
+		return 0, err
+	}
+
+	for _, obj := range objects {
+		d, ok := obj.(*Dataset)
+		if !ok {
+			continue
+		}
+
+		data := d.data
+		if d.refs != nil {
+			data = nil
+			for _, target := range d.refs {
+				data = binary.LittleEndian.AppendUint64(data, uint64(target.header().addr))
+			}
+		}
+
+		if len(data) > 0 {
+			if int64(len(data)) != d.DataSize() {
+				return 0, fmt.Errorf("dataset has %d bytes of data, expected %d", len(data), d.DataSize())
+			}
+
+			if _, err := w.WriteAt(data, d.dataAddr); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return eofAddr, nil
+}
+
+// layoutHeap lays out the local heap that holds the link names of the group.
+func (g *Group) layoutHeap() {
+	g.sortedLinks = append([]link(nil), g.links...)
+	sort.Slice(g.sortedLinks, func(i, j int) bool {
+		return g.sortedLinks[i].name < g.sortedLinks[j].name
+	})
+
+	// Offset zero must be the empty string.
+	g.heap = make([]byte, 8)
+	g.nameOffsets = make([]uint64, len(g.sortedLinks))
+	for i, l := range g.sortedLinks {
+		g.nameOffsets[i] = uint64(len(g.heap))
+		g.heap = append(g.heap, l.name...)
+		g.heap = pad8(append(g.heap, 0))
+	}
+
+	g.freeBlockOff = uint64(len(g.heap))
+	g.heap = binary.LittleEndian.AppendUint64(g.heap, freeListEnd)
+	g.heap = binary.LittleEndian.AppendUint64(g.heap, 16)
+}
+
+// appendSymbolTable appends the B-tree, symbol table node, and local heap of
+// the group.
+func (g *Group) appendSymbolTable(b []byte, leafK int) []byte {
+	// Group B-tree (version 1) with at most a single symbol table node.
+	start := len(b)
+	b = append(b, "TREE"...)
+	b = append(b, 0, 0)
+	if len(g.sortedLinks) > 0 {
+		b = binary.LittleEndian.AppendUint16(b, 1)
+	} else {
+		b = binary.LittleEndian.AppendUint16(b, 0)
+	}
+	b = binary.LittleEndian.AppendUint64(b, undefinedAddress)
+	b = binary.LittleEndian.AppendUint64(b, undefinedAddress)
+	if len(g.sortedLinks) > 0 {
+		b = binary.LittleEndian.AppendUint64(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, uint64(g.snodAddr))
+		b = binary.LittleEndian.AppendUint64(b, g.nameOffsets[len(g.nameOffsets)-1])
+	}
+	b = append(b, make([]byte, start+btreeSize-len(b))...)
+
+	// Symbol table node.
+	if len(g.sortedLinks) > 0 {
+		start = len(b)
+		b = append(b, "SNOD"...)
+		b = append(b, 1, 0)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(g.sortedLinks)))
+		for i, l := range g.sortedLinks {
+			b = binary.LittleEndian.AppendUint64(b, g.nameOffsets[i])
+			b = binary.LittleEndian.AppendUint64(b, uint64(l.target.header().addr))
+			b = append(b, make([]byte, 24)...)
+		}
+		b = append(b, make([]byte, start+8+2*leafK*40-len(b))...)
+	}
+
+	// Local heap.
+	b = append(b, "HEAP"...)
+	b = append(b, 0, 0, 0, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(g.heap)))
+	b = binary.LittleEndian.AppendUint64(b, g.freeBlockOff)
+	b = binary.LittleEndian.AppendUint64(b, uint64(g.heapAddr+localHeapSize))
+	return append(b, g.heap...)
+}
+
+func (g *Group) messages() [][]byte {
+	symbolTable := binary.LittleEndian.AppendUint64(nil, uint64(g.btreeAddr))
+	symbolTable = binary.LittleEndian.AppendUint64(symbolTable, uint64(g.heapAddr))
+
+	messages := [][]byte{encodeMessage(messageSymbolTable, 0, symbolTable)}
+	for _, attr := range g.attributes {
+		messages = append(messages, attr.message())
+	}
+	return messages
+}
+
+func (d *Dataset) messages() [][]byte {
+	// Fill value (version 2), allocated early and never written.
+	fillValue := []byte{2, 1, 1, 0}
+
+	// Contiguous data layout (version 3).
+	layout := []byte{3, 1}
+	layout = binary.LittleEndian.AppendUint64(layout, uint64(d.dataAddr))
+	layout = binary.LittleEndian.AppendUint64(layout, uint64(d.DataSize()))
+
+	messages := [][]byte{
+		encodeMessage(messageDataspace, 0, encodeDataspace(d.dims)),
+		encodeMessage(messageDatatype, 1, d.datatype.message),
+		encodeMessage(messageFillValue, 1, fillValue),
+		encodeMessage(messageLayout, 0, layout),
+	}
+	for _, attr := range d.attributes {
+		messages = append(messages, attr.message())
+	}
+	return messages
+}
+
+// message encodes an attribute message (version 1).
+func (a attribute) message() []byte {
+	dataspace := encodeDataspace(a.dims)
+
+	data := a.data
+	if a.refs != nil {
+		data = nil
+		for _, target := range a.refs {
+			data = binary.LittleEndian.AppendUint64(data, uint64(target.header().addr))
+		}
+	}
+
+	b := []byte{1, 0}
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(a.name)+1))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(a.datatype.message)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(dataspace)))
+	b = append(b, a.name...)
+	b = pad8(append(b, 0))
+	b = pad8(append(b, a.datatype.message...))
+	b = pad8(append(b, dataspace...))
+	b = append(b, data...)
+	return encodeMessage(messageAttribute, 0, b)
+}
+
+// encodeDataspace encodes a simple dataspace message (version 1), a dataspace
+// without dimensions is a scalar.
+func encodeDataspace(dims []int64) []byte {
+	b := []byte{1, byte(len(dims)), 0, 0, 0, 0, 0, 0}
+	for _, dim := range dims {
+		b = binary.LittleEndian.AppendUint64(b, uint64(dim))
+	}
+	return b
+}
+
+// encodeMessage encodes an object header message (version 1).
+func encodeMessage(messageType uint16, flags byte, data []byte) []byte {
+	data = pad8(append([]byte(nil), data...))
+
+	b := binary.LittleEndian.AppendUint16(nil, messageType)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(data)))
+	b = append(b, flags, 0, 0, 0)
+	return append(b, data...)
+}
+
+// encodeObjectHeader encodes an object header (version 1).
+func encodeObjectHeader(refCount int, messages [][]byte) []byte {
+	var size int
+	for _, message := range messages {
+		size += len(message)
+	}
+
+	b := []byte{1, 0}
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(messages)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(refCount))
+	b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	b = append(b, 0, 0, 0, 0)
+	for _, message := range messages {
+		b = append(b, message...)
+	}
+	return b
+}
+
+func pad8(b []byte) []byte {
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
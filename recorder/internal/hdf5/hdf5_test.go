@@ -0,0 +1,123 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package hdf5_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/hdf5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetadata(t *testing.T) {
+	f := hdf5.NewFile()
+	root := f.Root()
+
+	hdf5.SetStringAttribute(root, "description", "test")
+
+	group := root.CreateGroup("group")
+	values := group.CreateDataset("values", hdf5.Int16, 3)
+	root.Link("alias", values)
+	group.NewStringDataset("name", "hello")
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "test.h5"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, out.Close())
+	})
+
+	size, err := f.WriteMetadata(out)
+	require.NoError(t, err)
+
+	_, err = out.WriteAt([]byte{1, 0, 2, 0, 3, 0}, values.DataOffset())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+
+	require.Equal(t, "\x89HDF\r\n\x1a\n", string(data[:8]))
+	assert.Equal(t, int64(len(data)), size)
+	assert.Equal(t, uint64(size), binary.LittleEndian.Uint64(data[40:]))
+
+	rootLinks := readLinks(t, data, binary.LittleEndian.Uint64(data[80:]), binary.LittleEndian.Uint64(data[88:]))
+	assert.Len(t, rootLinks, 2)
+
+	// Both the alias and the link within the group point at the same dataset.
+	groupAddr, ok := rootLinks["group"]
+	require.True(t, ok)
+
+	groupLinks := readGroupLinks(t, data, groupAddr)
+	assert.Len(t, groupLinks, 2)
+	assert.Equal(t, rootLinks["alias"], groupLinks["values"])
+
+	// The dataset is referenced by two hard links.
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data[groupLinks["values"]+4:]))
+
+	assert.Equal(t, byte(1), data[groupLinks["name"]], "object header version")
+	assert.True(t, bytes.Contains(data, []byte("hello")))
+}
+
+// readGroupLinks finds the symbol table message of a group and returns its links.
+func readGroupLinks(t *testing.T, data []byte, addr uint64) map[string]uint64 {
+	messages := binary.LittleEndian.Uint16(data[addr+2:])
+	offset := addr + 16
+	for i := 0; i < int(messages); i++ {
+		messageType := binary.LittleEndian.Uint16(data[offset:])
+		messageSize := binary.LittleEndian.Uint16(data[offset+2:])
+		if messageType == 0x0011 {
+			return readLinks(t, data, binary.LittleEndian.Uint64(data[offset+8:]), binary.LittleEndian.Uint64(data[offset+16:]))
+		}
+		offset += 8 + uint64(messageSize)
+	}
+
+	t.Fatalf("object at %d is not a group", addr)
+	return nil
+}
+
+// readLinks reads the links of a group from its B-tree and local heap.
+func readLinks(t *testing.T, data []byte, btreeAddr, heapAddr uint64) map[string]uint64 {
+	require.Equal(t, "TREE", string(data[btreeAddr:btreeAddr+4]))
+	require.Equal(t, "HEAP", string(data[heapAddr:heapAddr+4]))
+
+	heapData := data[binary.LittleEndian.Uint64(data[heapAddr+24:]):]
+
+	links := make(map[string]uint64)
+	if binary.LittleEndian.Uint16(data[btreeAddr+6:]) == 0 {
+		return links
+	}
+
+	snodAddr := binary.LittleEndian.Uint64(data[btreeAddr+32:])
+	require.Equal(t, "SNOD", string(data[snodAddr:snodAddr+4]))
+
+	entries := binary.LittleEndian.Uint16(data[snodAddr+6:])
+	for i := uint64(0); i < uint64(entries); i++ {
+		entry := data[snodAddr+8+40*i:]
+		nameOffset := binary.LittleEndian.Uint64(entry)
+		name := heapData[nameOffset:]
+		name = name[:bytes.IndexByte(name, 0)]
+		links[string(name)] = binary.LittleEndian.Uint64(entry[8:])
+	}
+
+	return links
+}
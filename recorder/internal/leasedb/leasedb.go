@@ -19,17 +19,23 @@
 package leasedb
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/netip"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/OpenPSG/OpenPSG/recorder/internal/netutil"
 	"github.com/miekg/dns"
 	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
 )
 
 const (
@@ -37,24 +43,62 @@ const (
 	leasesBucketName           = "leases"
 	leasesByIPBucketName       = "leases_by_ip"
 	leasesByHostnameBucketName = "leases_by_hostname"
+	// Certificate fingerprints of provisioned devices, by MAC address (kept
+	// when their leases expire).
+	fingerprintsBucketName = "fingerprints"
+	// Devices approved for recording, by MAC address (kept when their leases
+	// expire).
+	approvedBucketName = "approved"
+	// Addresses reserved for devices, by MAC address.
+	reservationsBucketName = "reservations"
 )
 
+// The number of events buffered for a watcher before they are dropped.
+const watchBufferSize = 64
+
+// ErrInUse is returned when opening a database held open by another process
+// (eg. a running recorder).
+var ErrInUse = errors.New("lease database is in use by another process")
+
 // DB represents a database of DHCP leases.
 type DB struct {
 	db          *bolt.DB
 	gateway     netip.Addr
 	prefix      netip.Prefix
 	reaperTimer *time.Ticker
+
+	watchersMu sync.Mutex
+	watchers   map[chan Event]struct{}
+}
+
+// EventType is the kind of change made to a lease.
+type EventType int
+
+const (
+	// A lease was offered to a device.
+	LeaseOffered EventType = iota
+	// A lease was accepted (or renewed) by its device.
+	LeaseUpdated
+	// A lease was released, declined or expired.
+	LeaseRemoved
+)
+
+// Event is a change made to a lease.
+type Event struct {
+	Type  EventType
+	Lease Lease
 }
 
 func Open(dbPath string, prefix netip.Prefix, gateway netip.Addr) (*DB, error) {
 	db, err := bolt.Open(dbPath, 0o600, &bolt.Options{Timeout: 1 * time.Second})
-	if err != nil {
+	if errors.Is(err, bolterrors.ErrTimeout) {
+		return nil, ErrInUse
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to open lease database: %w", err)
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		for _, bucketName := range []string{configBucketName, leasesBucketName, leasesByIPBucketName, leasesByHostnameBucketName} {
+		for _, bucketName := range []string{configBucketName, leasesBucketName, leasesByIPBucketName, leasesByHostnameBucketName, fingerprintsBucketName, approvedBucketName, reservationsBucketName} {
 			_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
 			if err != nil {
 				return err
@@ -89,6 +133,7 @@ func Open(dbPath string, prefix netip.Prefix, gateway netip.Addr) (*DB, error) {
 		gateway:     gateway,
 		prefix:      prefix,
 		reaperTimer: time.NewTicker(5 * time.Minute),
+		watchers:    make(map[chan Event]struct{}),
 	}
 
 	// Reap any expired leases on startup.
@@ -114,6 +159,45 @@ func (db *DB) Close() error {
 	return db.db.Close()
 }
 
+// Watch returns a channel receiving the changes made to the leases, until the
+// context is cancelled (when the channel is closed). Events are dropped if they
+// aren't received promptly.
+func (db *DB) Watch(ctx context.Context) <-chan Event {
+	ch := make(chan Event, watchBufferSize)
+
+	db.watchersMu.Lock()
+	db.watchers[ch] = struct{}{}
+	db.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		db.watchersMu.Lock()
+		defer db.watchersMu.Unlock()
+
+		delete(db.watchers, ch)
+		close(ch)
+	}()
+
+	return ch
+}
+
+// notify sends the events to the watchers.
+func (db *DB) notify(events ...Event) {
+	db.watchersMu.Lock()
+	defer db.watchersMu.Unlock()
+
+	for ch := range db.watchers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				slog.Warn("Dropped lease event", slog.String("mac", event.Lease.MAC))
+			}
+		}
+	}
+}
+
 type Lease struct {
 	MAC       string    `json:"mac"`
 	IPAddress string    `json:"ip_address"`
@@ -134,11 +218,17 @@ func (db *DB) NewLease(mac net.HardwareAddr, hostname string, expiresAt time.Tim
 			return fmt.Errorf("lease already exists for MAC: %s", mac)
 		}
 
-		// Find the next free IP address
-		addr, err := db.nextFreeAddress()
+		// Use the address reserved for the device, or the next free address.
+		addr, err := db.reservedAddress(tx, mac)
 		if err != nil {
 			return err
 		}
+		if !addr.IsValid() {
+			addr, err = db.nextFreeAddress(tx)
+			if err != nil {
+				return err
+			}
+		}
 
 		// Create the lease
 		lease = &Lease{
@@ -170,6 +260,9 @@ func (db *DB) NewLease(mac net.HardwareAddr, hostname string, expiresAt time.Tim
 
 		return nil
 	})
+	if err == nil {
+		db.notify(Event{Type: LeaseOffered, Lease: *lease})
+	}
 	return lease, err
 }
 
@@ -195,7 +288,7 @@ func (db *DB) GetLease(mac net.HardwareAddr) (*Lease, error) {
 
 // UpdateLease updates the lease associated with a MAC address.
 func (db *DB) UpdateLease(lease *Lease) error {
-	return db.db.Update(func(tx *bolt.Tx) error {
+	err := db.db.Update(func(tx *bolt.Tx) error {
 		leasesBucket := tx.Bucket([]byte(leasesBucketName))
 		leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
 		leasesByHostnameBucket := tx.Bucket([]byte(leasesByHostnameBucketName))
@@ -226,11 +319,16 @@ func (db *DB) UpdateLease(lease *Lease) error {
 
 		return nil
 	})
+	if err == nil {
+		db.notify(Event{Type: LeaseUpdated, Lease: *lease})
+	}
+	return err
 }
 
 // RemoveLease removes a lease associated with a MAC address.
 func (db *DB) RemoveLease(mac net.HardwareAddr) error {
-	return db.db.Update(func(tx *bolt.Tx) error {
+	var lease Lease
+	err := db.db.Update(func(tx *bolt.Tx) error {
 		leasesBucket := tx.Bucket([]byte(leasesBucketName))
 		leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
 		leasesByHostnameBucket := tx.Bucket([]byte(leasesByHostnameBucketName))
@@ -240,7 +338,6 @@ func (db *DB) RemoveLease(mac net.HardwareAddr) error {
 			return fmt.Errorf("lease not found for MAC: %s", mac)
 		}
 
-		var lease Lease
 		if err := json.Unmarshal(data, &lease); err != nil {
 			return err
 		}
@@ -261,6 +358,10 @@ func (db *DB) RemoveLease(mac net.HardwareAddr) error {
 
 		return nil
 	})
+	if err == nil {
+		db.notify(Event{Type: LeaseRemoved, Lease: lease})
+	}
+	return err
 }
 
 // ListLeases returns all leases in the database.
@@ -281,9 +382,72 @@ func (db *DB) ListLeases() ([]*Lease, error) {
 	return leases, err
 }
 
+// GetLeaseByIP returns the lease of an IP address.
+func (db *DB) GetLeaseByIP(addr netip.Addr) (*Lease, error) {
+	var mac net.HardwareAddr
+	err := db.db.View(func(tx *bolt.Tx) error {
+		leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
+		if v := leasesByIPBucket.Get(addr.Unmap().AsSlice()); v != nil {
+			mac = append(mac, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mac == nil {
+		return nil, fmt.Errorf("lease not found for IP address: %s", addr)
+	}
+
+	return db.GetLease(mac)
+}
+
+// SetFingerprint stores the certificate fingerprint of a provisioned device.
+func (db *DB) SetFingerprint(mac net.HardwareAddr, fingerprint string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		fingerprintsBucket := tx.Bucket([]byte(fingerprintsBucketName))
+		return fingerprintsBucket.Put(mac, []byte(fingerprint))
+	})
+}
+
+// GetFingerprint returns the certificate fingerprint of a provisioned device
+// (empty if it hasn't been provisioned).
+func (db *DB) GetFingerprint(mac net.HardwareAddr) (string, error) {
+	var fingerprint string
+	err := db.db.View(func(tx *bolt.Tx) error {
+		fingerprintsBucket := tx.Bucket([]byte(fingerprintsBucketName))
+		fingerprint = string(fingerprintsBucket.Get(mac))
+		return nil
+	})
+	return fingerprint, err
+}
+
+// SetApproved approves (or revokes the approval of) a device for recording.
+func (db *DB) SetApproved(mac net.HardwareAddr, approved bool) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		approvedBucket := tx.Bucket([]byte(approvedBucketName))
+		if !approved {
+			return approvedBucket.Delete(mac)
+		}
+		return approvedBucket.Put(mac, []byte(time.Now().UTC().Format(time.RFC3339)))
+	})
+}
+
+// IsApproved returns whether a device has been approved for recording.
+func (db *DB) IsApproved(mac net.HardwareAddr) (bool, error) {
+	var approved bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		approvedBucket := tx.Bucket([]byte(approvedBucketName))
+		approved = approvedBucket.Get(mac) != nil
+		return nil
+	})
+	return approved, err
+}
+
 // ReapExpiredLeases removes all leases that have expired (visible for testing).
 func (db *DB) ReapExpiredLeases() error {
-	return db.db.Update(func(tx *bolt.Tx) error {
+	var reaped []Event
+	err := db.db.Update(func(tx *bolt.Tx) error {
 		leasesBucket := tx.Bucket([]byte(leasesBucketName))
 		leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
 		leasesByHostnameBucket := tx.Bucket([]byte(leasesByHostnameBucketName))
@@ -309,37 +473,177 @@ func (db *DB) ReapExpiredLeases() error {
 						return err
 					}
 				}
+
+				reaped = append(reaped, Event{Type: LeaseRemoved, Lease: lease})
 			}
 		}
 
 		return nil
 	})
+	if err == nil {
+		db.notify(reaped...)
+	}
+	return err
 }
 
-func (db *DB) nextFreeAddress() (netip.Addr, error) {
-	var addr netip.Addr
-	err := db.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(leasesByIPBucketName))
+// nextFreeAddress returns the first address of the prefix that isn't leased
+// or reserved.
+func (db *DB) nextFreeAddress(tx *bolt.Tx) (netip.Addr, error) {
+	leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
+
+	reserved := make(map[netip.Addr]bool)
+	err := tx.Bucket([]byte(reservationsBucketName)).ForEach(func(_, v []byte) error {
+		if addr, ok := netip.AddrFromSlice(v); ok {
+			reserved[addr] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	// Start from the first valid address in the prefix
+	addr := db.prefix.Addr()
+	if addr.Is4() && addr.As4()[3] == 0 {
+		addr = addr.Next()
+	}
+
+	broadcastAddr := netutil.BroadcastAddress(db.prefix)
+
+	for ; db.prefix.Contains(addr); addr = addr.Next() {
+		if addr == db.gateway || addr == broadcastAddr || reserved[addr] {
+			continue
+		}
+
+		if leasesByIPBucket.Get(addr.AsSlice()) == nil {
+			return addr, nil
+		}
+	}
+
+	return netip.Addr{}, fmt.Errorf("no free IP addresses")
+}
+
+// reservedAddress returns the address reserved for a device (if any), checking
+// it isn't leased to another device.
+func (db *DB) reservedAddress(tx *bolt.Tx, mac net.HardwareAddr) (netip.Addr, error) {
+	v := tx.Bucket([]byte(reservationsBucketName)).Get(mac)
+	if v == nil {
+		return netip.Addr{}, nil
+	}
+
+	addr, ok := netip.AddrFromSlice(v)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("invalid reserved address for MAC: %s", mac)
+	}
+
+	if holder := tx.Bucket([]byte(leasesByIPBucketName)).Get(addr.AsSlice()); holder != nil && !bytes.Equal(holder, mac) {
+		return netip.Addr{}, fmt.Errorf("reserved address %s is leased to %s", addr, net.HardwareAddr(holder))
+	}
+
+	return addr, nil
+}
+
+// Reservation is an address reserved for a device.
+type Reservation struct {
+	MAC       string `json:"mac"`
+	IPAddress string `json:"ip_address"`
+}
+
+// Reserve reserves an address for a device, so it's always offered the same
+// address. If the device holds a lease for another address, the lease is
+// removed (so it's offered the reserved address when it renews its lease).
+func (db *DB) Reserve(mac net.HardwareAddr, addr netip.Addr) error {
+	addr = addr.Unmap()
+	if !db.prefix.Contains(addr) || addr == db.prefix.Masked().Addr() ||
+		addr == db.gateway || addr == netutil.BroadcastAddress(db.prefix) {
+		return fmt.Errorf("address %s can't be leased in %s", addr, db.prefix)
+	}
+
+	var removed *Lease
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		leasesBucket := tx.Bucket([]byte(leasesBucketName))
+		leasesByIPBucket := tx.Bucket([]byte(leasesByIPBucketName))
+		leasesByHostnameBucket := tx.Bucket([]byte(leasesByHostnameBucketName))
+		reservationsBucket := tx.Bucket([]byte(reservationsBucketName))
 
-		// Start from the first valid address in the prefix
-		addr = db.prefix.Addr()
-		if addr.Is4() && addr.As4()[3] == 0 {
-			addr = addr.Next()
+		err := reservationsBucket.ForEach(func(k, v []byte) error {
+			if !bytes.Equal(k, mac) && bytes.Equal(v, addr.AsSlice()) {
+				return fmt.Errorf("address %s is reserved for %s", addr, net.HardwareAddr(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
-		broadcastAddr := netutil.BroadcastAddress(db.prefix)
+		if holder := leasesByIPBucket.Get(addr.AsSlice()); holder != nil && !bytes.Equal(holder, mac) {
+			return fmt.Errorf("address %s is leased to %s (remove its lease first)", addr, net.HardwareAddr(holder))
+		}
 
-		for ; db.prefix.Contains(addr); addr = addr.Next() {
-			if addr == db.gateway || addr == broadcastAddr {
-				continue
+		if data := leasesBucket.Get(mac); data != nil {
+			var lease Lease
+			if err := json.Unmarshal(data, &lease); err != nil {
+				return err
 			}
 
-			if b.Get([]byte(addr.String())) == nil {
-				return nil
+			if lease.IPAddress != addr.String() {
+				if err := leasesBucket.Delete(mac); err != nil {
+					return err
+				}
+
+				if err := leasesByIPBucket.Delete(netip.MustParseAddr(lease.IPAddress).AsSlice()); err != nil {
+					return err
+				}
+
+				if lease.Hostname != "" {
+					if err := leasesByHostnameBucket.Delete([]byte(lease.Hostname)); err != nil {
+						return err
+					}
+				}
+
+				removed = &lease
 			}
 		}
 
-		return fmt.Errorf("no free IP addresses")
+		return reservationsBucket.Put(mac, addr.AsSlice())
+	})
+	if err == nil && removed != nil {
+		db.notify(Event{Type: LeaseRemoved, Lease: *removed})
+	}
+	return err
+}
+
+// Unreserve removes the address reserved for a device.
+func (db *DB) Unreserve(mac net.HardwareAddr) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		reservationsBucket := tx.Bucket([]byte(reservationsBucketName))
+		if reservationsBucket.Get(mac) == nil {
+			return fmt.Errorf("no address reserved for MAC: %s", mac)
+		}
+		return reservationsBucket.Delete(mac)
+	})
+}
+
+// ListReservations returns the addresses reserved for devices, ordered by
+// address.
+func (db *DB) ListReservations() ([]Reservation, error) {
+	var reservations []Reservation
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(reservationsBucketName)).ForEach(func(k, v []byte) error {
+			addr, ok := netip.AddrFromSlice(v)
+			if !ok {
+				return fmt.Errorf("invalid reserved address for MAC: %s", net.HardwareAddr(k))
+			}
+
+			reservations = append(reservations, Reservation{
+				MAC:       net.HardwareAddr(k).String(),
+				IPAddress: addr.String(),
+			})
+			return nil
+		})
+	})
+	sort.Slice(reservations, func(i, j int) bool {
+		return netip.MustParseAddr(reservations[i].IPAddress).Less(netip.MustParseAddr(reservations[j].IPAddress))
 	})
-	return addr, err
+	return reservations, err
 }
@@ -19,6 +19,7 @@
 package leasedb_test
 
 import (
+	"context"
 	"net"
 	"path/filepath"
 	"testing"
@@ -109,6 +110,56 @@ func TestLeaseDB(t *testing.T) {
 		_, err = db.GetLease(mac)
 		assert.Error(t, err, "expected error when retrieving a removed lease")
 	})
+
+	t.Run("TestGetLeaseByIP", func(t *testing.T) {
+		mac := net.HardwareAddr{0x00, 0x1F, 0x2A, 0x3B, 0x4C, 0x5D}
+		hostname := "test-host-5"
+
+		lease, err := db.NewLease(mac, hostname, time.Now().Add(24*time.Hour))
+		require.NoError(t, err)
+
+		found, err := db.GetLeaseByIP(netip.MustParseAddr(lease.IPAddress))
+		require.NoError(t, err)
+
+		assert.Equal(t, "00:1f:2a:3b:4c:5d", found.MAC)
+
+		_, err = db.GetLeaseByIP(netip.MustParseAddr("10.0.0.1"))
+		assert.Error(t, err, "expected error when retrieving the lease of an unknown address")
+	})
+
+	t.Run("TestFingerprint", func(t *testing.T) {
+		mac := net.HardwareAddr{0x00, 0x2A, 0x3B, 0x4C, 0x5D, 0x6E}
+
+		fingerprint, err := db.GetFingerprint(mac)
+		require.NoError(t, err)
+		assert.Empty(t, fingerprint)
+
+		err = db.SetFingerprint(mac, "3f2a")
+		require.NoError(t, err)
+
+		fingerprint, err = db.GetFingerprint(mac)
+		require.NoError(t, err)
+		assert.Equal(t, "3f2a", fingerprint)
+	})
+	t.Run("TestApproval", func(t *testing.T) {
+		mac := net.HardwareAddr{0x00, 0x3B, 0x4C, 0x5D, 0x6E, 0x7F}
+
+		approved, err := db.IsApproved(mac)
+		require.NoError(t, err)
+		assert.False(t, approved)
+
+		require.NoError(t, db.SetApproved(mac, true))
+
+		approved, err = db.IsApproved(mac)
+		require.NoError(t, err)
+		assert.True(t, approved)
+
+		require.NoError(t, db.SetApproved(mac, false))
+
+		approved, err = db.IsApproved(mac)
+		require.NoError(t, err)
+		assert.False(t, approved)
+	})
 }
 
 func TestLeaseDB_ReapExpiredLeases(t *testing.T) {
@@ -139,3 +190,126 @@ func TestLeaseDB_ReapExpiredLeases(t *testing.T) {
 	_, err = db.GetLease(mac)
 	assert.Error(t, err, "expected error when retrieving an expired lease")
 }
+
+func TestLeaseDB_Watch(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.168.1.0/24")
+	gateway := netip.MustParseAddr("192.168.1.1")
+
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "leases.db")
+
+	db, err := leasedb.Open(dbPath, prefix, gateway)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := db.Watch(ctx)
+
+	mac := net.HardwareAddr{0x00, 0x4C, 0x5D, 0x6E, 0x7F, 0x80}
+	lease, err := db.NewLease(mac, "test-host", time.Now().Add(5*time.Minute))
+	require.NoError(t, err)
+
+	event := <-events
+	assert.Equal(t, leasedb.LeaseOffered, event.Type)
+	assert.Equal(t, *lease, event.Lease)
+
+	lease.ExpiresAt = time.Now().Add(24 * time.Hour)
+	require.NoError(t, db.UpdateLease(lease))
+
+	event = <-events
+	assert.Equal(t, leasedb.LeaseUpdated, event.Type)
+	assert.Equal(t, lease.IPAddress, event.Lease.IPAddress)
+
+	require.NoError(t, db.RemoveLease(mac))
+
+	event = <-events
+	assert.Equal(t, leasedb.LeaseRemoved, event.Type)
+	assert.Equal(t, lease.IPAddress, event.Lease.IPAddress)
+
+	expiredMAC := net.HardwareAddr{0x00, 0x5D, 0x6E, 0x7F, 0x80, 0x91}
+	expired, err := db.NewLease(expiredMAC, "", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	<-events
+
+	require.NoError(t, db.ReapExpiredLeases())
+
+	event = <-events
+	assert.Equal(t, leasedb.LeaseRemoved, event.Type)
+	assert.Equal(t, expired.MAC, event.Lease.MAC)
+
+	cancel()
+
+	_, ok := <-events
+	assert.False(t, ok, "expected the channel to be closed once the context is cancelled")
+}
+
+func TestLeaseDB_Reservations(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.168.1.0/24")
+	gateway := netip.MustParseAddr("192.168.1.1")
+
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "leases.db")
+
+	db, err := leasedb.Open(dbPath, prefix, gateway)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	mac1 := net.HardwareAddr{0x00, 0x6E, 0x7F, 0x80, 0x91, 0x01}
+	mac2 := net.HardwareAddr{0x00, 0x6E, 0x7F, 0x80, 0x91, 0x02}
+	mac3 := net.HardwareAddr{0x00, 0x6E, 0x7F, 0x80, 0x91, 0x03}
+	mac4 := net.HardwareAddr{0x00, 0x6E, 0x7F, 0x80, 0x91, 0x04}
+
+	lease1, err := db.NewLease(mac1, "", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.2", lease1.IPAddress)
+
+	lease2, err := db.NewLease(mac2, "", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.3", lease2.IPAddress)
+
+	assert.ErrorContains(t, db.Reserve(mac3, netip.MustParseAddr("192.168.1.2")), "is leased to 00:6e:7f:80:91:01")
+	assert.Error(t, db.Reserve(mac3, gateway))
+	assert.Error(t, db.Reserve(mac3, netip.MustParseAddr("10.0.0.2")))
+
+	require.NoError(t, db.Reserve(mac3, netip.MustParseAddr("192.168.1.10")))
+	assert.ErrorContains(t, db.Reserve(mac4, netip.MustParseAddr("192.168.1.10")), "is reserved for 00:6e:7f:80:91:03")
+
+	lease3, err := db.NewLease(mac3, "", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.10", lease3.IPAddress)
+
+	// Reserving another address removes the device's lease.
+	events := db.Watch(context.Background())
+	require.NoError(t, db.Reserve(mac1, netip.MustParseAddr("192.168.1.4")))
+
+	event := <-events
+	assert.Equal(t, leasedb.LeaseRemoved, event.Type)
+	assert.Equal(t, lease1.MAC, event.Lease.MAC)
+
+	_, err = db.GetLease(mac1)
+	assert.Error(t, err)
+
+	// Reserved addresses aren't leased to other devices.
+	lease4, err := db.NewLease(mac4, "", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.2", lease4.IPAddress)
+
+	reservations, err := db.ListReservations()
+	require.NoError(t, err)
+	assert.Equal(t, []leasedb.Reservation{
+		{MAC: mac1.String(), IPAddress: "192.168.1.4"},
+		{MAC: mac3.String(), IPAddress: "192.168.1.10"},
+	}, reservations)
+
+	require.NoError(t, db.Unreserve(mac3))
+	assert.Error(t, db.Unreserve(mac3))
+
+	_, err = leasedb.Open(dbPath, prefix, gateway)
+	assert.ErrorIs(t, err, leasedb.ErrInUse)
+}
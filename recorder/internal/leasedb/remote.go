@@ -0,0 +1,329 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package leasedb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// How long a request to a served lease database may take (other than
+// watching it).
+const clientTimeout = 5 * time.Second
+
+// Store is the read only view of the leases used to discover and record
+// devices. It's satisfied by DB, and by Client (for a database served by
+// another process).
+type Store interface {
+	// Watch returns a channel receiving the changes made to the leases, until
+	// the context is cancelled.
+	Watch(ctx context.Context) <-chan Event
+	ListLeases() ([]*Lease, error)
+	GetLeaseByIP(addr netip.Addr) (*Lease, error)
+	GetFingerprint(mac net.HardwareAddr) (string, error)
+	IsApproved(mac net.HardwareAddr) (bool, error)
+}
+
+// Network is the network the leases are served on.
+type Network struct {
+	Interface string       `json:"interface"`
+	Prefix    netip.Prefix `json:"prefix"`
+	Gateway   netip.Addr   `json:"gateway"`
+}
+
+// NewHandler returns an HTTP handler serving a read only view of the database
+// (and the network it serves), for clients attaching with Dial.
+func NewHandler(db *DB, network Network) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /network", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, network, nil)
+	})
+
+	mux.HandleFunc("GET /leases", func(w http.ResponseWriter, r *http.Request) {
+		leases, err := db.ListLeases()
+		writeJSON(w, leases, err)
+	})
+
+	mux.HandleFunc("GET /leases/{addr}", func(w http.ResponseWriter, r *http.Request) {
+		addr, err := netip.ParseAddr(r.PathValue("addr"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		lease, err := db.GetLeaseByIP(addr)
+		writeJSON(w, lease, err)
+	})
+
+	mux.HandleFunc("GET /reservations", func(w http.ResponseWriter, r *http.Request) {
+		reservations, err := db.ListReservations()
+		writeJSON(w, reservations, err)
+	})
+
+	mux.HandleFunc("GET /fingerprints/{mac}", func(w http.ResponseWriter, r *http.Request) {
+		mac, err := net.ParseMAC(r.PathValue("mac"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fingerprint, err := db.GetFingerprint(mac)
+		writeJSON(w, fingerprint, err)
+	})
+
+	mux.HandleFunc("GET /approved/{mac}", func(w http.ResponseWriter, r *http.Request) {
+		mac, err := net.ParseMAC(r.PathValue("mac"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		approved, err := db.IsApproved(mac)
+		writeJSON(w, approved, err)
+	})
+
+	// The events are streamed as newline delimited JSON.
+	mux.HandleFunc("GET /watch", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events := db.Watch(r.Context())
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		enc := json.NewEncoder(w)
+		for event := range events {
+			if err := enc.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write response", slog.Any("error", err))
+	}
+}
+
+// Client is a read only view of a database served by another process (see
+// NewHandler) on a Unix socket.
+type Client struct {
+	client  *http.Client
+	network Network
+}
+
+// Dial attaches to the database served on the Unix socket.
+func Dial(ctx context.Context, socketPath string) (*Client, error) {
+	c := &Client{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clientTimeout)
+	defer cancel()
+
+	if err := c.get(ctx, "/network", &c.network); err != nil {
+		return nil, fmt.Errorf("failed to attach to lease database: %w", err)
+	}
+
+	return c, nil
+}
+
+// Close closes the connections to the database.
+func (c *Client) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// Network returns the network the database serves.
+func (c *Client) Network() Network {
+	return c.network
+}
+
+// Watch returns a channel receiving the changes made to the leases, until the
+// context is cancelled (when the channel is closed). If the connection to the
+// database is lost, it's reconnected (and the changes made in the meantime are
+// lost).
+func (c *Client) Watch(ctx context.Context) <-chan Event {
+	ch := make(chan Event, watchBufferSize)
+
+	// Connected before returning, so no changes made afterwards are missed.
+	resp, err := c.do(ctx, "/watch")
+
+	go func() {
+		defer close(ch)
+
+		for {
+			if err == nil {
+				err = receiveEvents(resp, ch)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("Lost connection to lease database, reconnecting", slog.Any("error", err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+
+			resp, err = c.do(ctx, "/watch")
+		}
+	}()
+
+	return ch
+}
+
+// receiveEvents receives the events streamed in the response, until it ends.
+func receiveEvents(resp *http.Response, ch chan<- Event) error {
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := dec.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("Dropped lease event", slog.String("mac", event.Lease.MAC))
+		}
+	}
+}
+
+// ListLeases returns all the leases.
+func (c *Client) ListLeases() ([]*Lease, error) {
+	var leases []*Lease
+	err := c.getWithTimeout("/leases", &leases)
+	return leases, err
+}
+
+// GetLeaseByIP returns the lease of an IP address.
+func (c *Client) GetLeaseByIP(addr netip.Addr) (*Lease, error) {
+	var lease *Lease
+	if err := c.getWithTimeout("/leases/"+addr.String(), &lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// ListReservations returns the addresses reserved for devices, ordered by
+// address.
+func (c *Client) ListReservations() ([]Reservation, error) {
+	var reservations []Reservation
+	err := c.getWithTimeout("/reservations", &reservations)
+	return reservations, err
+}
+
+// GetFingerprint returns the certificate fingerprint of a provisioned device
+// (empty if it hasn't been provisioned).
+func (c *Client) GetFingerprint(mac net.HardwareAddr) (string, error) {
+	var fingerprint string
+	err := c.getWithTimeout("/fingerprints/"+mac.String(), &fingerprint)
+	return fingerprint, err
+}
+
+// IsApproved returns whether a device has been approved for recording.
+func (c *Client) IsApproved(mac net.HardwareAddr) (bool, error) {
+	var approved bool
+	err := c.getWithTimeout("/approved/"+mac.String(), &approved)
+	return approved, err
+}
+
+func (c *Client) getWithTimeout(path string, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
+	defer cancel()
+
+	return c.get(ctx, path, v)
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	resp, err := c.do(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// do sends a request, returning an error (with the message of the server) if
+// it wasn't successful.
+func (c *Client) do(ctx context.Context, path string) (*http.Response, error) {
+	// The host is ignored, as the client dials the socket.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://leasedb"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.New(strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,125 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package leasedb_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	network := leasedb.Network{
+		Interface: "eth1",
+		Prefix:    netip.MustParsePrefix("192.168.1.0/24"),
+		Gateway:   netip.MustParseAddr("192.168.1.1"),
+	}
+
+	db, err := leasedb.Open(filepath.Join(t.TempDir(), "leases.db"), network.Prefix, network.Gateway)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	// Unix socket paths are limited in length.
+	socketDir, err := os.MkdirTemp("", "leasedb")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(socketDir))
+	})
+
+	ln, err := net.Listen("unix", filepath.Join(socketDir, "leasedb.sock"))
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: leasedb.NewHandler(db, network)}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, srv.Close())
+	})
+
+	client, err := leasedb.Dial(context.Background(), filepath.Join(socketDir, "leasedb.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	assert.Equal(t, network, client.Network())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := client.Watch(ctx)
+
+	mac := net.HardwareAddr{0x00, 0x1B, 0x2C, 0x3D, 0x4E, 0x5F}
+	lease, err := db.NewLease(mac, "test-host", time.Now().Add(24*time.Hour))
+	require.NoError(t, err)
+
+	event := <-events
+	assert.Equal(t, leasedb.LeaseOffered, event.Type)
+	assert.Equal(t, lease.MAC, event.Lease.MAC)
+
+	leases, err := client.ListLeases()
+	require.NoError(t, err)
+	require.Len(t, leases, 1)
+	assert.Equal(t, lease.MAC, leases[0].MAC)
+	assert.Equal(t, lease.IPAddress, leases[0].IPAddress)
+
+	got, err := client.GetLeaseByIP(netip.MustParseAddr(lease.IPAddress))
+	require.NoError(t, err)
+	assert.Equal(t, lease.MAC, got.MAC)
+
+	_, err = client.GetLeaseByIP(netip.MustParseAddr("192.168.1.200"))
+	assert.ErrorContains(t, err, "lease not found for IP address: 192.168.1.200")
+
+	approved, err := client.IsApproved(mac)
+	require.NoError(t, err)
+	assert.False(t, approved)
+
+	require.NoError(t, db.SetApproved(mac, true))
+	require.NoError(t, db.SetFingerprint(mac, "abcd"))
+
+	approved, err = client.IsApproved(mac)
+	require.NoError(t, err)
+	assert.True(t, approved)
+
+	fingerprint, err := client.GetFingerprint(mac)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", fingerprint)
+
+	require.NoError(t, db.Reserve(mac, netip.MustParseAddr(lease.IPAddress)))
+
+	reservations, err := client.ListReservations()
+	require.NoError(t, err)
+	assert.Equal(t, []leasedb.Reservation{{MAC: lease.MAC, IPAddress: lease.IPAddress}}, reservations)
+
+	cancel()
+
+	for range events {
+		// Drained until closed.
+	}
+}
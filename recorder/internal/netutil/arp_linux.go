@@ -0,0 +1,110 @@
+//go:build linux
+
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Addresses are resolved in batches, waiting for each batch to respond, so the
+// neighbor table of the kernel doesn't overflow on large prefixes.
+const (
+	arpSweepBatchSize = 256
+	arpSweepWait      = 500 * time.Millisecond
+)
+
+// Neighbor is a host on the network.
+type Neighbor struct {
+	Addr netip.Addr
+	MAC  net.HardwareAddr
+}
+
+// ARPSweep resolves every address of the prefix with ARP on the network
+// interface with the given name, returning the hosts that responded (or were
+// recently seen). Each address is sent an empty datagram (to the discard port)
+// so the kernel resolves it.
+func ARPSweep(ctx context.Context, ifname string, prefix netip.Prefix) ([]Neighbor, error) {
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("ARP sweeps require an IPv4 prefix")
+	}
+
+	link, err := netlink.LinkByName(ifname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface with name %s: %w", ifname, err)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer conn.Close()
+
+	prefix = prefix.Masked()
+	broadcast := BroadcastAddress(prefix)
+
+	found := make(map[netip.Addr]net.HardwareAddr)
+	addr := prefix.Addr().Next()
+	for prefix.Contains(addr) && addr != broadcast {
+		for i := 0; i < arpSweepBatchSize && prefix.Contains(addr) && addr != broadcast; i++ {
+			// Sends fail for addresses that recently failed to resolve.
+			_, _ = conn.WriteToUDPAddrPort(nil, netip.AddrPortFrom(addr, 9))
+			addr = addr.Next()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(arpSweepWait):
+		}
+
+		neighbors, err := netlink.NeighList(link.Attrs().Index, netlink.FAMILY_V4)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list neighbors: %w", err)
+		}
+
+		for _, neighbor := range neighbors {
+			resolved := netlink.NUD_REACHABLE | netlink.NUD_STALE | netlink.NUD_DELAY | netlink.NUD_PROBE | netlink.NUD_PERMANENT
+			if neighbor.State&resolved == 0 || len(neighbor.HardwareAddr) == 0 {
+				continue
+			}
+
+			neighborAddr, ok := netip.AddrFromSlice(neighbor.IP)
+			if !ok || !prefix.Contains(neighborAddr.Unmap()) {
+				continue
+			}
+			found[neighborAddr.Unmap()] = neighbor.HardwareAddr
+		}
+	}
+
+	var hosts []Neighbor
+	for _, addr := range slices.SortedFunc(maps.Keys(found), netip.Addr.Compare) {
+		hosts = append(hosts, Neighbor{Addr: addr, MAC: found[addr]})
+	}
+	return hosts, nil
+}
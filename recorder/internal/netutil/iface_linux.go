@@ -50,3 +50,43 @@ func ConfigureNetworkInterface(ifname string, gateway netip.Addr, prefix netip.P
 
 	return nil
 }
+
+// AddAddress assigns an additional IP address and network prefix to the network
+// interface with the given name.
+func AddAddress(ifname string, prefix netip.Prefix) error {
+	link, err := netlink.LinkByName(ifname)
+	if err != nil {
+		return fmt.Errorf("failed to find interace with name %s: %w", ifname, err)
+	}
+
+	addr, err := netlink.ParseAddr(prefix.String())
+	if err != nil {
+		return fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	if err := netlink.AddrAdd(link, addr); err != nil && err.Error() != "file exists" {
+		return fmt.Errorf("failed to add address to interface: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAddress removes an IP address assigned with AddAddress from the network
+// interface with the given name.
+func RemoveAddress(ifname string, prefix netip.Prefix) error {
+	link, err := netlink.LinkByName(ifname)
+	if err != nil {
+		return fmt.Errorf("failed to find interace with name %s: %w", ifname, err)
+	}
+
+	addr, err := netlink.ParseAddr(prefix.String())
+	if err != nil {
+		return fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	if err := netlink.AddrDel(link, addr); err != nil {
+		return fmt.Errorf("failed to remove address from interface: %w", err)
+	}
+
+	return nil
+}
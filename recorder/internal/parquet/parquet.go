@@ -0,0 +1,234 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package parquet is a minimal Apache Parquet file writer. It supports flat
+// schemas of required double precision columns, stored uncompressed using the
+// plain encoding.
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The number of rows buffered in memory before a row group is written.
+const rowGroupSize = 1 << 20
+
+const (
+	magic = "PAR1"
+
+	typeDouble         = 5
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageTypeData       = 0
+)
+
+// KeyValue is a file metadata entry.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Writer writes rows to a Parquet file.
+type Writer struct {
+	w         *countingWriter
+	columns   []string
+	metadata  []KeyValue
+	buffers   [][]byte
+	rows      int64 // Rows in the current row group.
+	totalRows int64
+	rowGroups [][]byte // Encoded RowGroup structs.
+}
+
+// NewWriter returns a writer for a file with the named (double) columns.
+func NewWriter(w io.Writer, columns []string, metadata []KeyValue) *Writer {
+	return &Writer{
+		w:        &countingWriter{w: w},
+		columns:  columns,
+		metadata: metadata,
+		buffers:  make([][]byte, len(columns)),
+	}
+}
+
+// WriteRow appends a row, with a value for each column.
+func (pw *Writer) WriteRow(values ...float64) error {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.columns), len(values))
+	}
+
+	for i, v := range values {
+		pw.buffers[i] = binary.LittleEndian.AppendUint64(pw.buffers[i], math.Float64bits(v))
+	}
+	pw.rows++
+
+	if pw.rows == rowGroupSize {
+		return pw.flush()
+	}
+
+	return nil
+}
+
+// Close writes any buffered rows and the file footer. It does not close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	if pw.w.n == 0 {
+		if _, err := pw.w.Write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+
+	if pw.rows > 0 {
+		if err := pw.flush(); err != nil {
+			return err
+		}
+	}
+
+	// FileMetaData
+	c := &compactWriter{}
+	c.structure(func() {
+		c.i32(1, 1)
+		c.list(2, compactStruct, len(pw.columns)+1, func(i int) {
+			c.structure(func() {
+				if i == 0 {
+					c.str(4, "schema")
+					c.i32(5, int32(len(pw.columns)))
+					return
+				}
+
+				c.i32(1, typeDouble)
+				c.i32(3, repetitionRequired)
+				c.str(4, pw.columns[i-1])
+			})
+		})
+		c.i64(3, pw.totalRows)
+		c.list(4, compactStruct, len(pw.rowGroups), func(i int) {
+			c.b = append(c.b, pw.rowGroups[i]...)
+		})
+		if len(pw.metadata) > 0 {
+			c.list(5, compactStruct, len(pw.metadata), func(i int) {
+				c.structure(func() {
+					c.str(1, pw.metadata[i].Key)
+					c.str(2, pw.metadata[i].Value)
+				})
+			})
+		}
+		c.str(6, "OpenPSG")
+	})
+
+	footer := binary.LittleEndian.AppendUint32(c.b, uint32(len(c.b)))
+	footer = append(footer, magic...)
+
+	_, err := pw.w.Write(footer)
+	return err
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *Writer) flush() error {
+	if pw.w.n == 0 {
+		if _, err := pw.w.Write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+
+	offsets := make([]int64, len(pw.columns))
+	sizes := make([]int64, len(pw.columns))
+
+	for i := range pw.columns {
+		data := pw.buffers[i]
+
+		// PageHeader
+		c := &compactWriter{}
+		c.structure(func() {
+			c.i32(1, pageTypeData)
+			c.i32(2, int32(len(data)))
+			c.i32(3, int32(len(data)))
+			// DataPageHeader, required columns have no levels.
+			c.field(5, compactStruct)
+			c.structure(func() {
+				c.i32(1, int32(pw.rows))
+				c.i32(2, encodingPlain)
+				c.i32(3, encodingRLE)
+				c.i32(4, encodingRLE)
+			})
+		})
+
+		offsets[i] = pw.w.n
+		sizes[i] = int64(len(c.b) + len(data))
+
+		if _, err := pw.w.Write(c.b); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(data); err != nil {
+			return err
+		}
+
+		pw.buffers[i] = data[:0]
+	}
+
+	// RowGroup
+	var totalSize int64
+	c := &compactWriter{}
+	c.structure(func() {
+		c.list(1, compactStruct, len(pw.columns), func(i int) {
+			// ColumnChunk
+			c.structure(func() {
+				c.i64(2, offsets[i])
+				// ColumnMetaData
+				c.field(3, compactStruct)
+				c.structure(func() {
+					c.i32(1, typeDouble)
+					c.list(2, compactI32, 2, func(j int) {
+						c.varint(zigzag([]int64{encodingPlain, encodingRLE}[j]))
+					})
+					c.list(3, compactBinary, 1, func(int) {
+						c.binary(pw.columns[i])
+					})
+					c.i32(4, codecUncompressed)
+					c.i64(5, pw.rows)
+					c.i64(6, sizes[i])
+					c.i64(7, sizes[i])
+					c.i64(9, offsets[i])
+				})
+			})
+			totalSize += sizes[i]
+		})
+		c.i64(2, totalSize)
+		c.i64(3, pw.rows)
+	})
+
+	pw.rowGroups = append(pw.rowGroups, c.b)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,61 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package parquet_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	pw := parquet.NewWriter(&buf, []string{"time", "value"}, []parquet.KeyValue{{Key: "unit", Value: "uV"}})
+
+	require.NoError(t, pw.WriteRow(0, 1.5))
+	require.NoError(t, pw.WriteRow(0.5, -2.5))
+	require.Error(t, pw.WriteRow(1))
+	require.NoError(t, pw.Close())
+
+	// Read back with an established Parquet implementation.
+	pf, err := buffer.NewBufferFile(buf.Bytes())
+	require.NoError(t, err)
+
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	require.NoError(t, err)
+	t.Cleanup(pr.ReadStop)
+
+	assert.Equal(t, int64(2), pr.GetNumRows())
+
+	require.Len(t, pr.Footer.KeyValueMetadata, 1)
+	assert.Equal(t, "unit", pr.Footer.KeyValueMetadata[0].Key)
+	assert.Equal(t, "uV", pr.Footer.KeyValueMetadata[0].GetValue())
+
+	for i, column := range [][]any{{0.0, 0.5}, {1.5, -2.5}} {
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), 2)
+		require.NoError(t, err)
+		assert.Equal(t, column, values)
+	}
+}
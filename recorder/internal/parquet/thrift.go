@@ -0,0 +1,101 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol types.
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes Thrift structs using the compact protocol, which is
+// used for all Parquet metadata.
+type compactWriter struct {
+	b []byte
+	// The last field id of each struct being encoded.
+	lastFieldIDs []int16
+}
+
+// structure encodes a struct, whose fields are written by fn.
+func (c *compactWriter) structure(fn func()) {
+	c.lastFieldIDs = append(c.lastFieldIDs, 0)
+	fn()
+	c.b = append(c.b, 0)
+	c.lastFieldIDs = c.lastFieldIDs[:len(c.lastFieldIDs)-1]
+}
+
+// field encodes a field header.
+func (c *compactWriter) field(id int16, typ byte) {
+	last := &c.lastFieldIDs[len(c.lastFieldIDs)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.b = append(c.b, byte(delta)<<4|typ)
+	} else {
+		c.b = append(c.b, typ)
+		c.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compactWriter) str(id int16, s string) {
+	c.field(id, compactBinary)
+	c.binary(s)
+}
+
+// list encodes a list field of n elements, each written by fn.
+func (c *compactWriter) list(id int16, elemType byte, n int, fn func(i int)) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.b = append(c.b, byte(n)<<4|elemType)
+	} else {
+		c.b = append(c.b, 0xf0|elemType)
+		c.varint(uint64(n))
+	}
+
+	for i := 0; i < n; i++ {
+		fn(i)
+	}
+}
+
+func (c *compactWriter) binary(s string) {
+	c.varint(uint64(len(s)))
+	c.b = append(c.b, s...)
+}
+
+func (c *compactWriter) varint(v uint64) {
+	c.b = binary.AppendUvarint(c.b, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
@@ -0,0 +1,163 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package pseudonym generates patient pseudonyms and maintains an encrypted
+// key file mapping them back to the original identifiers.
+package pseudonym
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// Identifies (and versions) the key file format.
+	keyFileMagic = "OPSGKEY1"
+	saltSize     = 16
+	// PBKDF2-HMAC-SHA256 iterations, per the OWASP recommendation.
+	kdfIterations = 600000
+	keySize       = 32
+)
+
+// ErrWrongPassphrase is returned when a key file cannot be decrypted.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupt key file")
+
+// Mapping links a pseudonym to the identifiers it replaced.
+type Mapping struct {
+	Pseudonym   string    `json:"pseudonym"`
+	PatientID   string    `json:"patient_id"`
+	RecordingID string    `json:"recording_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// New returns a new random pseudonym (eg. "PSN-K3M9QX7T2B").
+func New() string {
+	var b [10]byte
+	_, _ = rand.Read(b[:])
+	return "PSN-" + base32.StdEncoding.EncodeToString(b[:])[:10]
+}
+
+// Append adds a mapping to the key file at path (creating it if needed), the
+// key file is encrypted with a key derived from passphrase.
+func Append(path, passphrase string, m Mapping) error {
+	var mappings []Mapping
+	if _, err := os.Stat(path); err == nil {
+		if mappings, err = Read(path, passphrase); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat key file: %w", err)
+	}
+
+	mappings = append(mappings, m)
+
+	plaintext, err := json.Marshal(mappings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mappings: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := append([]byte(keyFileMagic), salt...)
+	data := append(header, nonce...)
+	data = aead.Seal(data, nonce, plaintext, header)
+
+	// Atomically replace the key file, so a failure never loses mappings.
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace key file: %w", err)
+	}
+
+	return nil
+}
+
+// Read decrypts the key file at path and returns its mappings.
+func Read(path, passphrase string) ([]Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	headerSize := len(keyFileMagic) + saltSize
+	if len(data) < headerSize || !bytes.Equal(data[:len(keyFileMagic)], []byte(keyFileMagic)) {
+		return nil, fmt.Errorf("not a key file")
+	}
+
+	header := data[:headerSize]
+	salt := header[len(keyFileMagic):]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < headerSize+aead.NonceSize() {
+		return nil, fmt.Errorf("key file is truncated")
+	}
+
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var mappings []Mapping
+	if err := json.Unmarshal(plaintext, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mappings: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// newAEAD returns an AES-256-GCM cipher using a key derived from passphrase.
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, kdfIterations, keySize, sha256.New))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,58 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pseudonym_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/pseudonym"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.bin")
+
+	first := pseudonym.Mapping{
+		Pseudonym:   pseudonym.New(),
+		PatientID:   "MCH-0234567",
+		RecordingID: "PSG-1",
+		CreatedAt:   time.Date(2025, time.February, 3, 22, 30, 0, 0, time.UTC),
+	}
+	second := pseudonym.Mapping{
+		Pseudonym: pseudonym.New(),
+		PatientID: "MCH-0234568",
+		CreatedAt: time.Date(2025, time.February, 4, 22, 30, 0, 0, time.UTC),
+	}
+	assert.NotEqual(t, first.Pseudonym, second.Pseudonym)
+
+	require.NoError(t, pseudonym.Append(path, "correct horse", first))
+	require.NoError(t, pseudonym.Append(path, "correct horse", second))
+
+	mappings, err := pseudonym.Read(path, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, []pseudonym.Mapping{first, second}, mappings)
+
+	_, err = pseudonym.Read(path, "battery staple")
+	assert.ErrorIs(t, err, pseudonym.ErrWrongPassphrase)
+
+	assert.Error(t, pseudonym.Append(path, "battery staple", first))
+}
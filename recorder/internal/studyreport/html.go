@@ -0,0 +1,254 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package studyreport
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// The width of the timelines in SVG units, and the height of each of their
+// rows.
+const (
+	timelineWidth = 1000
+	rowHeight     = 18
+	labelWidth    = 160
+)
+
+// The colour of each coverage in the timeline, and its description.
+var coverageStyles = []struct {
+	Coverage    Coverage
+	Colour      string
+	Description string
+}{
+	{CoverageOK, "#2e7d32", "Recorded"},
+	{CoveragePartial, "#f9a825", "Partly missing"},
+	{CoverageFlat, "#8e24aa", "Flat"},
+	{CoverageMissing, "#c62828", "Missing"},
+	{CoverageAbsent, "#e0e0e0", "Not recorded"},
+}
+
+// The sleep stages of the hypnogram, from the top.
+var sleepStages = []string{"W", "R", "N1", "N2", "N3"}
+
+type (
+	htmlReport struct {
+		*Study
+		Width, Height int
+		LabelWidth    int
+		RowHeight     int
+		Ticks         []tick
+		Rows          []timelineRow
+		Legend        []legendEntry
+		Stages        []stageRow
+		StagesHeight  int
+	}
+	stageRow struct {
+		Label string
+		Y     int
+	}
+	tick struct {
+		X     float64
+		Label string
+	}
+	timelineRow struct {
+		Label    string
+		Y        int
+		Received string
+		Spans    []span
+	}
+	span struct {
+		X, Width float64
+		Colour   string
+	}
+	legendEntry struct {
+		Colour      string
+		Description string
+	}
+)
+
+// WriteHTML writes the overview of the study as a self-contained HTML document
+// (with the timelines drawn as inline SVG, so it can be shared or printed).
+func (s *Study) WriteHTML(w io.Writer) error {
+	report := htmlReport{
+		Study:        s,
+		Width:        labelWidth + timelineWidth,
+		Height:       len(s.Signals) * rowHeight,
+		LabelWidth:   labelWidth,
+		RowHeight:    rowHeight,
+		Ticks:        s.ticks(),
+		Stages:       make([]stageRow, len(sleepStages)),
+		StagesHeight: len(sleepStages) * rowHeight,
+	}
+
+	for i, stage := range sleepStages {
+		report.Stages[i] = stageRow{Label: stage, Y: i*rowHeight + rowHeight/2}
+	}
+
+	colours := make(map[Coverage]string)
+	for _, style := range coverageStyles {
+		colours[style.Coverage] = style.Colour
+		report.Legend = append(report.Legend, legendEntry{Colour: style.Colour, Description: style.Description})
+	}
+
+	for i, signal := range s.Signals {
+		row := timelineRow{
+			Label:    signal.Label,
+			Y:        i * rowHeight,
+			Received: fmt.Sprintf("%.1f%%", 100*signal.Received),
+		}
+
+		// Consecutive epochs of the same coverage are drawn as one span.
+		epochWidth := float64(timelineWidth) / float64(max(len(signal.Epochs), 1))
+		for start := 0; start < len(signal.Epochs); {
+			end := start + 1
+			for end < len(signal.Epochs) && signal.Epochs[end] == signal.Epochs[start] {
+				end++
+			}
+			row.Spans = append(row.Spans, span{
+				X:      labelWidth + float64(start)*epochWidth,
+				Width:  float64(end-start) * epochWidth,
+				Colour: colours[signal.Epochs[start]],
+			})
+			start = end
+		}
+
+		report.Rows = append(report.Rows, row)
+	}
+
+	if err := reportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// ticks returns the hours of the recording, to label the timelines with.
+func (s *Study) ticks() []tick {
+	if s.Duration <= 0 {
+		return nil
+	}
+
+	interval := time.Hour
+	if s.Duration < 2*time.Hour {
+		interval = 15 * time.Minute
+	}
+
+	var ticks []tick
+	for t := s.StartTime.Truncate(interval); !t.After(s.StartTime.Add(s.Duration)); t = t.Add(interval) {
+		if t.Before(s.StartTime) {
+			continue
+		}
+		ticks = append(ticks, tick{
+			X:     labelWidth + float64(t.Sub(s.StartTime))/float64(s.Duration)*timelineWidth,
+			Label: t.Format("15:04"),
+		})
+	}
+	return ticks
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #212121; }
+h1 { margin-bottom: 0.2em; }
+h2 { margin-top: 1.5em; border-bottom: 1px solid #bdbdbd; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.2em 0.8em 0.2em 0; }
+th { border-bottom: 1px solid #bdbdbd; }
+svg { width: 100%; height: auto; }
+svg text { font-size: 11px; fill: #424242; }
+.legend span { display: inline-block; width: 1em; height: 1em; margin: 0 0.3em 0 1em; vertical-align: middle; }
+.note { color: #757575; }
+@media print { body { margin: 0; } h2 { break-after: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>Patient</th><td>{{.PatientID}}</td></tr>
+<tr><th>Recording</th><td>{{.RecordingID}}</td></tr>
+<tr><th>Start</th><td>{{.StartTime.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>Duration</th><td>{{.Duration}}</td></tr>
+</table>
+
+<h2>Hypnogram</h2>
+<svg viewBox="0 0 {{.Width}} {{.StagesHeight}}" preserveAspectRatio="xMinYMin meet" role="img">
+{{- range .Stages}}
+<text x="0" y="{{.Y}}" dy="4">{{.Label}}</text>
+<line x1="{{$.LabelWidth}}" x2="{{$.Width}}" y1="{{.Y}}" y2="{{.Y}}" stroke="#e0e0e0"/>
+{{- end}}
+</svg>
+<p class="note">Not scored.</p>
+
+<h2>Signal Coverage</h2>
+<p class="legend">{{range .Legend}}<span style="background: {{.Colour}}"></span>{{.Description}}{{end}}</p>
+<svg viewBox="0 0 {{.Width}} {{.Height}}" preserveAspectRatio="xMinYMin meet" role="img">
+{{- range .Rows}}
+<text x="0" y="{{.Y}}" dy="13">{{.Label}}</text>
+{{- $y := .Y}}
+{{- range .Spans}}
+<rect x="{{printf "%.2f" .X}}" y="{{$y}}" width="{{printf "%.2f" .Width}}" height="{{$.RowHeight}}" fill="{{.Colour}}" stroke="white" stroke-width="0.5"/>
+{{- end}}
+{{- end}}
+</svg>
+<svg viewBox="0 0 {{.Width}} 16" preserveAspectRatio="xMinYMin meet">
+{{- range .Ticks}}
+<text x="{{printf "%.2f" .X}}" y="12" text-anchor="middle">{{.Label}}</text>
+{{- end}}
+</svg>
+<table>
+<tr><th>Signal</th><th>Unit</th><th>Sample Rate</th><th>Received</th></tr>
+{{- range $i, $row := .Rows}}
+{{- with index $.Signals $i}}
+<tr><td>{{.Label}}</td><td>{{.Unit}}</td><td>{{printf "%g" .SampleRate}} Hz</td><td>{{$row.Received}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+
+<h2>Events</h2>
+{{- if .Events}}
+<table>
+<tr><th>Event</th><th>Count</th></tr>
+{{- range .Events}}
+<tr><td>{{.Event}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="note">No events were annotated.</p>
+{{- end}}
+
+<h2>Devices</h2>
+{{- if .Devices}}
+<table>
+<tr><th>Address</th><th>Name</th><th>MAC Address</th><th>Hostname</th><th>Model</th><th>Serial Number</th><th>Firmware</th><th>Signals</th></tr>
+{{- range .Devices}}
+<tr><td>{{.Address}}</td><td>{{.Name}}</td><td>{{.MAC}}</td><td>{{.Hostname}}</td><td>{{.Model}}</td><td>{{.SerialNumber}}</td><td>{{.Firmware}}</td><td>{{range $i, $s := .Signals}}{{if $i}}, {{end}}{{$s}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="note">No sidecar was found describing the devices.</p>
+{{- end}}
+</body>
+</html>
+`))
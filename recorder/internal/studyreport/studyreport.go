@@ -0,0 +1,193 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package studyreport renders an overview of a recording (its signal coverage,
+// events and devices) as a shareable HTML document.
+package studyreport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+// Coverage describes how well a signal was recorded during an epoch.
+type Coverage int
+
+const (
+	// CoverageAbsent means no data record was written for the epoch (eg. the
+	// recording was paused, or no device was connected).
+	CoverageAbsent Coverage = iota
+	// CoverageMissing means at most half of the values were received.
+	CoverageMissing
+	// CoveragePartial means more than half (but not all) of the values were
+	// received.
+	CoveragePartial
+	// CoverageFlat means the values didn't change (eg. a detached electrode,
+	// or missing values filled with zero).
+	CoverageFlat
+	// CoverageOK means every value was received, and the signal changed.
+	CoverageOK
+)
+
+// Study is an overview of a recording.
+type Study struct {
+	Title       string
+	PatientID   string
+	RecordingID string
+	StartTime   time.Time
+	Duration    time.Duration
+	// The duration of each epoch (the data records of the recording).
+	EpochDuration time.Duration
+	Signals       []Signal
+	Events        []EventCount
+	Devices       []Device
+}
+
+// Signal is the coverage of a signal over the recording.
+type Signal struct {
+	Label      string
+	Unit       string
+	SampleRate float64
+	// The coverage of each epoch.
+	Epochs []Coverage
+	// The fraction of the values of the recording received.
+	Received float64
+}
+
+// EventCount is the number of annotations of a kind (the text of the
+// annotation up to the first colon, eg. "Device disconnected").
+type EventCount struct {
+	Event string
+	Count int
+}
+
+// Device is a device recorded from (see the sidecar of the recording).
+type Device struct {
+	Address      string
+	Name         string
+	MAC          string
+	Hostname     string
+	Model        string
+	SerialNumber string
+	Firmware     string
+	Signals      []string
+}
+
+// Analyze reads the recording, measuring the coverage of each signal by epoch
+// and counting its events.
+func Analyze(er *edfplus.Reader, title string) (*Study, error) {
+	hdr := er.Header()
+	signals := er.Signals()
+
+	study := &Study{
+		Title:         title,
+		PatientID:     strings.TrimSpace(hdr.PatientID),
+		RecordingID:   strings.TrimSpace(hdr.RecordingID),
+		StartTime:     hdr.StartTime,
+		EpochDuration: hdr.DataRecordDuration,
+		Signals:       make([]Signal, len(signals)),
+	}
+	if study.EpochDuration <= 0 {
+		return nil, fmt.Errorf("invalid data record duration: %s", hdr.DataRecordDuration)
+	}
+
+	received := make([]int, len(signals))
+	for i, signal := range signals {
+		study.Signals[i] = Signal{
+			Label:      strings.TrimSpace(signal.Label),
+			Unit:       strings.TrimSpace(signal.PhysicalDimension),
+			SampleRate: float64(signal.SamplesPerRecord) / study.EpochDuration.Seconds(),
+		}
+	}
+
+	events := make(map[string]int)
+
+	er.Rewind()
+	for {
+		record, err := er.ReadRecord()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data record: %w", err)
+		}
+
+		for _, annotation := range record.Annotations {
+			event, _, _ := strings.Cut(annotation.Text, ":")
+			events[strings.TrimSpace(event)]++
+		}
+
+		// Data records of discontinuous recordings may be missing.
+		epoch := int(record.Onset / study.EpochDuration)
+		study.Duration = max(study.Duration, record.Onset+study.EpochDuration)
+
+		for i, signal := range signals {
+			if len(record.Samples[i]) == 0 {
+				continue
+			}
+
+			// Invalid values are written in place of missing values, if they
+			// are outside the digital range of the signal.
+			var valid int
+			for _, sample := range record.Samples[i] {
+				if sample != edfplus.InvalidSample || signal.DigitalMin <= edfplus.InvalidSample {
+					valid++
+				}
+			}
+			received[i] += valid
+
+			coverage := CoverageOK
+			switch {
+			case 2*valid <= len(record.Samples[i]):
+				coverage = CoverageMissing
+			case valid < len(record.Samples[i]):
+				coverage = CoveragePartial
+			case slices.Min(record.Samples[i]) == slices.Max(record.Samples[i]):
+				coverage = CoverageFlat
+			}
+
+			for len(study.Signals[i].Epochs) <= epoch {
+				study.Signals[i].Epochs = append(study.Signals[i].Epochs, CoverageAbsent)
+			}
+			study.Signals[i].Epochs[epoch] = coverage
+		}
+	}
+
+	epochs := int(study.Duration / study.EpochDuration)
+	for i, signal := range signals {
+		for len(study.Signals[i].Epochs) < epochs {
+			study.Signals[i].Epochs = append(study.Signals[i].Epochs, CoverageAbsent)
+		}
+		if expected := epochs * signal.SamplesPerRecord; expected > 0 {
+			study.Signals[i].Received = float64(received[i]) / float64(expected)
+		}
+	}
+
+	for _, event := range slices.Sorted(maps.Keys(events)) {
+		study.Events = append(study.Events, EventCount{Event: event, Count: events[event]})
+	}
+
+	return study, nil
+}
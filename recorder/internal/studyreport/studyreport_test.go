@@ -0,0 +1,121 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package studyreport_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/studyreport"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStudyReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.edf")
+
+	startTime := time.Date(2025, time.February, 3, 22, 30, 0, 0, time.Local)
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		PatientID:          "MCH-0234567 F 02-MAY-1951 Haagse_Harry",
+		RecordingID:        edfplus.RecordingIdentification(startTime, "", "", ""),
+		StartTime:          startTime,
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			{
+				Label:             "EEG C3-M2",
+				PhysicalDimension: "uV",
+				PhysicalMin:       -100,
+				PhysicalMax:       100,
+				DigitalMin:        edfplus.InvalidSample + 1,
+				DigitalMax:        math.MaxInt16,
+				SamplesPerRecord:  4,
+			},
+			edfplus.AnnotationSignal(128),
+		},
+	})
+	require.NoError(t, err)
+
+	nan := math.NaN()
+
+	ew.Annotate(edfplus.Annotation{Onset: 0, Text: "Lights off"})
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{1, 2, 3, 4}}))
+	ew.Annotate(edfplus.Annotation{Onset: time.Second, Text: "Device disconnected: 127.0.2.1"})
+	require.NoError(t, ew.WriteRecord(time.Second, [][]float64{{nan, nan, nan, 1}}))
+	require.NoError(t, ew.WriteRecord(2*time.Second, [][]float64{{5, 5, 5, 5}}))
+	ew.Annotate(edfplus.Annotation{Onset: 4 * time.Second, Text: "Device disconnected: 127.0.2.2"})
+	require.NoError(t, ew.WriteRecord(4*time.Second, [][]float64{{1, nan, 3, 4}}))
+	require.NoError(t, ew.Close())
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	study, err := studyreport.Analyze(er, "Test Study")
+	require.NoError(t, err)
+
+	assert.Equal(t, "MCH-0234567 F 02-MAY-1951 Haagse_Harry", study.PatientID)
+	assert.Equal(t, 5*time.Second, study.Duration)
+
+	require.Len(t, study.Signals, 1)
+	assert.Equal(t, "EEG C3-M2", study.Signals[0].Label)
+	assert.Equal(t, 4.0, study.Signals[0].SampleRate)
+	assert.Equal(t, []studyreport.Coverage{
+		studyreport.CoverageOK,
+		studyreport.CoverageMissing,
+		studyreport.CoverageFlat,
+		studyreport.CoverageAbsent,
+		studyreport.CoveragePartial,
+	}, study.Signals[0].Epochs)
+	assert.InDelta(t, 0.6, study.Signals[0].Received, 1e-9)
+
+	assert.Equal(t, []studyreport.EventCount{
+		{Event: "Device disconnected", Count: 2},
+		{Event: "Lights off", Count: 1},
+	}, study.Events)
+
+	study.Devices = []studyreport.Device{{Address: "127.0.2.1", Name: "Head <Box>", Signals: []string{"EEG C3-M2"}}}
+
+	var sb strings.Builder
+	require.NoError(t, study.WriteHTML(&sb))
+
+	html := sb.String()
+	assert.Contains(t, html, "<title>Test Study</title>")
+	assert.Contains(t, html, "EEG C3-M2")
+	assert.Contains(t, html, "60.0%")
+	assert.Contains(t, html, "<td>Device disconnected</td><td>2</td>")
+	assert.Contains(t, html, "Head &lt;Box&gt;")
+	assert.Contains(t, html, "Not scored.")
+}
@@ -18,20 +18,24 @@
 
 package termutil
 
-import "fmt"
+import (
+	"os"
 
-// ClearLines clears n lines from the terminal.
-func ClearLines(n int) {
-	// Move the cursor up n rows
-	fmt.Printf("\033[%dA", n)
-	// Clear each of these rows
-	for i := 0; i < n; i++ {
-		fmt.Print("\033[2K\r")
-		// Move the cursor down to the next line after the first
-		if i < n-1 {
-			fmt.Print("\033[1B")
-		}
+	"golang.org/x/term"
+)
+
+// IsTerminal reports whether stdout is a terminal. Otherwise (eg. when run by
+// systemd, or redirected to a file) output should be appended, rather than
+// redrawn in place.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Width returns the width of the terminal stdout is attached to, if it is.
+func Width() (int, bool) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 0, false
 	}
-	// Position the cursor back at the beginning of the first cleared line
-	fmt.Printf("\033[%dA", n-1)
+	return width, true
 }
@@ -0,0 +1,117 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package wav is a minimal writer for 16-bit PCM WAV files, whose length is
+// not known in advance. The sizes in the header are filled in when the writer
+// is closed, so a file that was not closed still holds its samples.
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// headerSize is the size of the RIFF, format and data chunk headers.
+const headerSize = 44
+
+// Writer writes interleaved 16-bit little-endian PCM samples to a WAV file.
+type Writer struct {
+	w          io.WriteSeeker
+	sampleRate uint32
+	channels   uint16
+	dataSize   int64
+}
+
+// NewWriter writes the header of a WAV file to w, which must be positioned at
+// the start of the file.
+func NewWriter(w io.WriteSeeker, sampleRate uint32, channels uint16) (*Writer, error) {
+	if sampleRate == 0 || channels == 0 {
+		return nil, fmt.Errorf("invalid sample rate or number of channels")
+	}
+
+	ww := &Writer{w: w, sampleRate: sampleRate, channels: channels}
+	if _, err := w.Write(ww.header()); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return ww, nil
+}
+
+// Write appends raw sample data (interleaved 16-bit little-endian samples).
+func (ww *Writer) Write(p []byte) (int, error) {
+	n, err := ww.w.Write(p)
+	ww.dataSize += int64(n)
+	return n, err
+}
+
+// Samples returns the number of samples written per channel.
+func (ww *Writer) Samples() int64 {
+	return ww.dataSize / (2 * int64(ww.channels))
+}
+
+// Close fills in the sizes in the header. It does not close the underlying
+// file.
+func (ww *Writer) Close() error {
+	// An odd sized data chunk is padded to an even size.
+	if ww.dataSize%2 != 0 {
+		if _, err := ww.w.Write([]byte{0}); err != nil {
+			return fmt.Errorf("failed to write padding: %w", err)
+		}
+	}
+
+	if _, err := ww.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to header: %w", err)
+	}
+
+	if _, err := ww.w.Write(ww.header()); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	if _, err := ww.w.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek to end: %w", err)
+	}
+
+	return nil
+}
+
+func (ww *Writer) header() []byte {
+	// Sizes are limited to 32 bits, longer recordings have truncated sizes.
+	dataSize := uint32(min(ww.dataSize, math.MaxUint32-headerSize))
+	blockAlign := 2 * ww.channels
+
+	hdr := make([]byte, 0, headerSize)
+	hdr = append(hdr, "RIFF"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, headerSize-8+dataSize+dataSize%2)
+	hdr = append(hdr, "WAVE"...)
+
+	hdr = append(hdr, "fmt "...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, 16)
+	hdr = binary.LittleEndian.AppendUint16(hdr, 1) // PCM
+	hdr = binary.LittleEndian.AppendUint16(hdr, ww.channels)
+	hdr = binary.LittleEndian.AppendUint32(hdr, ww.sampleRate)
+	hdr = binary.LittleEndian.AppendUint32(hdr, ww.sampleRate*uint32(blockAlign))
+	hdr = binary.LittleEndian.AppendUint16(hdr, blockAlign)
+	hdr = binary.LittleEndian.AppendUint16(hdr, 16)
+
+	hdr = append(hdr, "data"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, dataSize)
+
+	return hdr
+}
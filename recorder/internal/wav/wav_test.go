@@ -0,0 +1,75 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package wav_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	w, err := wav.NewWriter(f, 8000, 1)
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte{1, 0, 2, 0})
+	require.NoError(t, err)
+	_, err = w.Write([]byte{3, 0})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), w.Samples())
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	require.Len(t, data, 44+6)
+
+	assert.Equal(t, "RIFF", string(data[0:4]))
+	assert.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:8]))
+	assert.Equal(t, "WAVE", string(data[8:12]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(data[20:22]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(data[22:24]))
+	assert.Equal(t, uint32(8000), binary.LittleEndian.Uint32(data[24:28]))
+	assert.Equal(t, uint32(16000), binary.LittleEndian.Uint32(data[28:32]))
+	assert.Equal(t, "data", string(data[36:40]))
+	assert.Equal(t, uint32(6), binary.LittleEndian.Uint32(data[40:44]))
+	assert.Equal(t, []byte{1, 0, 2, 0, 3, 0}, data[44:])
+}
+
+func TestWriterInvalid(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.wav"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	_, err = wav.NewWriter(f, 0, 1)
+	assert.Error(t, err)
+}
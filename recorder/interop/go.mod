@@ -0,0 +1,17 @@
+// Checks the files written by the recorder with independent implementations of
+// their formats. It is a separate module as the HDF5 reader requires a newer
+// Go release than the recorder.
+module github.com/OpenPSG/OpenPSG/recorder/interop
+
+go 1.25
+
+require (
+	github.com/OpenPSG/OpenPSG/recorder v0.0.0
+	github.com/OpenPSG/edf v0.2.1
+	github.com/scigolib/hdf5 v0.14.1
+	github.com/stretchr/testify v1.12.1
+)
+
+require go.yaml.in/yaml/v3 v3.0.5 // indirect
+
+replace github.com/OpenPSG/OpenPSG/recorder => ../
@@ -0,0 +1,74 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interop_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHDF5(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.HDF5(createRecording(t), dir, "test"))
+
+	objects := h5Objects(t, filepath.Join(dir, "test.h5"))
+
+	assert.Equal(t, recordingStartTime.Format(time.RFC3339), h5Attribute(t, objects["/"], "start_time"))
+
+	eeg := h5Dataset(t, objects, "/EEG C4-M1")
+	assert.Equal(t, "uV", h5Attribute(t, eeg, "units"))
+	assert.Equal(t, 4.0, h5Attribute(t, eeg, "sample_rate"))
+	values, err := eeg.Read()
+	require.NoError(t, err)
+	assertValues(t, []float64{1, 2, 3, 4, nan, nan, nan, nan, 5, 5, 5, 5}, values)
+
+	spo2 := h5Dataset(t, objects, "/SpO2")
+	assert.Equal(t, "%", h5Attribute(t, spo2, "units"))
+	values, err = spo2.Read()
+	require.NoError(t, err)
+	assertValues(t, []float64{5, 6, nan, nan, 7, 7}, values)
+
+	texts, err := h5Dataset(t, objects, "/annotation_texts").ReadStrings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Lights off", "Obstructive apnea"}, texts)
+
+	onsets, err := h5Dataset(t, objects, "/annotation_onsets").Read()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 2.25}, onsets)
+}
+
+var nan = math.NaN()
+
+func assertValues(t *testing.T, expected, actual []float64) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		if math.IsNaN(expected[i]) {
+			assert.True(t, math.IsNaN(actual[i]), "value %d is %v, expected NaN", i, actual[i])
+		} else {
+			assert.InDelta(t, expected[i], actual[i], 1e-6, "value %d", i)
+		}
+	}
+}
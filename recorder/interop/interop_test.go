@@ -0,0 +1,132 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interop_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+	"github.com/scigolib/hdf5"
+	"github.com/stretchr/testify/require"
+)
+
+var recordingStartTime = time.Date(2025, time.February, 3, 22, 30, 0, 0, time.UTC)
+
+// createRecording writes a discontinuous recording with an EEG signal at 4 Hz
+// and an SpO2 signal at 2 Hz. The data records start at 0s and 2s, leaving a
+// one second gap.
+func createRecording(t *testing.T) *edfplus.Reader {
+	signal := func(label, unit string, samplesPerRecord int) edf.SignalHeader {
+		return edf.SignalHeader{
+			Label:             label,
+			PhysicalDimension: unit,
+			PhysicalMin:       -100,
+			PhysicalMax:       100,
+			DigitalMin:        -1000,
+			DigitalMax:        1000,
+			SamplesPerRecord:  samplesPerRecord,
+		}
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		PatientID:          edfplus.PatientIdentification("MCH-0234567", "F", time.Date(1951, time.May, 2, 0, 0, 0, 0, time.UTC), "Haagse Harry"),
+		RecordingID:        edfplus.RecordingIdentification(recordingStartTime, "", "", ""),
+		StartTime:          recordingStartTime,
+		DataRecordDuration: time.Second,
+		Signals: []edf.SignalHeader{
+			signal("EEG C4-M1", "uV", 4),
+			signal("SpO2", "%", 2),
+			edfplus.AnnotationSignal(64),
+		},
+	})
+	require.NoError(t, err)
+
+	ew.Annotate(edfplus.Annotation{Onset: 500 * time.Millisecond, Text: "Lights off"})
+	require.NoError(t, ew.WriteRecord(0, [][]float64{{1, 2, 3, 4}, {5, 6}}))
+
+	ew.Annotate(edfplus.Annotation{Onset: 2250 * time.Millisecond, Duration: 10 * time.Second, Text: "Obstructive apnea"})
+	require.NoError(t, ew.WriteRecord(2*time.Second, [][]float64{{5, 5, 5, 5}, {7, 7}}))
+
+	require.NoError(t, ew.Close())
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+
+	return er
+}
+
+// h5Objects opens an HDF5 file, returning its groups and datasets by path.
+func h5Objects(t *testing.T, path string) map[string]hdf5.Object {
+	f, err := hdf5.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.Close())
+	})
+
+	objects := make(map[string]hdf5.Object)
+	f.Walk(func(path string, obj hdf5.Object) {
+		objects[path] = obj
+	})
+	return objects
+}
+
+// h5Attribute reads the named attribute of a group or dataset.
+func h5Attribute(t *testing.T, obj hdf5.Object, name string) any {
+	switch obj := obj.(type) {
+	case *hdf5.Dataset:
+		value, err := obj.ReadAttribute(name)
+		require.NoError(t, err)
+		return value
+	case *hdf5.Group:
+		attributes, err := obj.Attributes()
+		require.NoError(t, err)
+		for _, a := range attributes {
+			if a.Name == name {
+				value, err := a.ReadValue()
+				require.NoError(t, err)
+				return value
+			}
+		}
+	}
+
+	require.Failf(t, "missing attribute", "%s has no attribute %q", obj.Name(), name)
+	return nil
+}
+
+// h5Dataset returns the dataset at path.
+func h5Dataset(t *testing.T, objects map[string]hdf5.Object, path string) *hdf5.Dataset {
+	d, ok := objects[path].(*hdf5.Dataset)
+	require.True(t, ok, "%s is not a dataset", path)
+	return d
+}
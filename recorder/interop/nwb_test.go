@@ -0,0 +1,73 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package interop_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNWB(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, export.NWB(createRecording(t), dir, "test"))
+
+	objects := h5Objects(t, filepath.Join(dir, "test.nwb"))
+
+	assert.Equal(t, "NWBFile", h5Attribute(t, objects["/"], "neurodata_type"))
+	assert.Equal(t, "2.7.0", h5Attribute(t, objects["/"], "nwb_version"))
+
+	startTime, err := h5Dataset(t, objects, "/session_start_time").ReadStrings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{recordingStartTime.Format(time.RFC3339)}, startTime)
+
+	assert.Equal(t, "Subject", h5Attribute(t, objects["/general/subject/"], "neurodata_type"))
+	subjectID, err := h5Dataset(t, objects, "/general/subject/subject_id").ReadStrings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MCH-0234567"}, subjectID)
+
+	// Voltage signals are electrical series, stored with a conversion to volts.
+	assert.Equal(t, "ElectricalSeries", h5Attribute(t, objects["/acquisition/EEG C4-M1/"], "neurodata_type"))
+	eeg := h5Dataset(t, objects, "/acquisition/EEG C4-M1/data")
+	assert.Equal(t, "volts", h5Attribute(t, eeg, "unit"))
+	assert.Equal(t, float32(1e-6), h5Attribute(t, eeg, "conversion"))
+	values, err := eeg.Read()
+	require.NoError(t, err)
+	assertValues(t, []float64{1, 2, 3, 4, nan, nan, nan, nan, 5, 5, 5, 5}, values)
+
+	assert.Equal(t, "TimeSeries", h5Attribute(t, objects["/acquisition/SpO2/"], "neurodata_type"))
+	spo2 := h5Dataset(t, objects, "/acquisition/SpO2/data")
+	assert.Equal(t, "%", h5Attribute(t, spo2, "unit"))
+	values, err = spo2.Read()
+	require.NoError(t, err)
+	assertValues(t, []float64{5, 6, nan, nan, 7, 7}, values)
+
+	labels, err := h5Dataset(t, objects, "/intervals/annotations/label").ReadStrings()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Lights off", "Obstructive apnea"}, labels)
+
+	stopTimes, err := h5Dataset(t, objects, "/intervals/annotations/stop_time").Read()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 12.25}, stopTimes)
+}
@@ -0,0 +1,205 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+func newLeasesCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "leases",
+		Usage: "Manages the DHCP leases of devices, and the addresses reserved for them",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "Lists the leases and reserved addresses (read only while the network services are running)",
+				Action: listLeases,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Removes the leases of devices, so they're offered a new address",
+				ArgsUsage: "<MAC or IP address>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() == 0 {
+						return fmt.Errorf("expected the MAC or IP addresses of the devices")
+					}
+
+					return updateLeases(c, func(db *leasedb.DB) error {
+						for _, arg := range c.Args().Slice() {
+							mac, err := deviceMAC(db, arg)
+							if err != nil {
+								return err
+							}
+
+							if err := db.RemoveLease(mac); err != nil {
+								return fmt.Errorf("failed to remove lease: %w", err)
+							}
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "reserve",
+				Usage:     "Reserves an address for a device, so it's always offered the same address",
+				ArgsUsage: "<MAC or IP address> <reserved IP address>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("expected the MAC or IP address of the device, and the address to reserve")
+					}
+
+					addr, err := netip.ParseAddr(c.Args().Get(1))
+					if err != nil {
+						return fmt.Errorf("invalid IP address %q", c.Args().Get(1))
+					}
+
+					return updateLeases(c, func(db *leasedb.DB) error {
+						mac, err := deviceMAC(db, c.Args().First())
+						if err != nil {
+							return err
+						}
+
+						if err := db.Reserve(mac, addr); err != nil {
+							return fmt.Errorf("failed to reserve address: %w", err)
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "unreserve",
+				Usage:     "Removes the addresses reserved for devices",
+				ArgsUsage: "<MAC or IP address>...",
+				Action: func(c *cli.Context) error {
+					if c.NArg() == 0 {
+						return fmt.Errorf("expected the MAC or IP addresses of the devices")
+					}
+
+					return updateLeases(c, func(db *leasedb.DB) error {
+						for _, arg := range c.Args().Slice() {
+							mac, err := deviceMAC(db, arg)
+							if err != nil {
+								return err
+							}
+
+							if err := db.Unreserve(mac); err != nil {
+								return fmt.Errorf("failed to remove reservation: %w", err)
+							}
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// listLeases prints the leases and reserved addresses. While the network
+// services are running (holding the database open), they're read from the
+// socket the database is served on.
+func listLeases(c *cli.Context) error {
+	var store interface {
+		ListLeases() ([]*leasedb.Lease, error)
+		ListReservations() ([]leasedb.Reservation, error)
+	}
+
+	db, err := openLeaseDB(c)
+	switch {
+	case errors.Is(err, leasedb.ErrInUse):
+		client, err := leasedb.Dial(c.Context, c.String("socket"))
+		if err != nil {
+			return fmt.Errorf("lease database is in use, and isn't served on %s: %w", c.String("socket"), err)
+		}
+		defer client.Close()
+
+		slog.Info("Listing the leases of the running network services")
+		store = client
+	case err != nil:
+		return err
+	default:
+		defer db.Close()
+		store = db
+	}
+
+	leases, err := store.ListLeases()
+	if err != nil {
+		return fmt.Errorf("failed to list leases: %w", err)
+	}
+
+	reservations, err := store.ListReservations()
+	if err != nil {
+		return fmt.Errorf("failed to list reservations: %w", err)
+	}
+
+	slices.SortFunc(leases, func(a, b *leasedb.Lease) int {
+		return netip.MustParseAddr(a.IPAddress).Compare(netip.MustParseAddr(b.IPAddress))
+	})
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"MAC Address", "IP Address", "Hostname", "Expires", "Reserved"})
+	table.SetBorder(false)
+
+	for _, lease := range leases {
+		expires := lease.ExpiresAt.Local().Format(time.DateTime)
+		if lease.ExpiresAt.Before(time.Now()) {
+			expires = "Expired"
+		}
+
+		reserved := slices.Contains(reservations, leasedb.Reservation{MAC: lease.MAC, IPAddress: lease.IPAddress})
+		table.Append([]string{lease.MAC, lease.IPAddress, lease.Hostname, expires, fmt.Sprint(reserved)})
+	}
+
+	// Reserved addresses not currently leased.
+	for _, reservation := range reservations {
+		if !slices.ContainsFunc(leases, func(lease *leasedb.Lease) bool {
+			return lease.MAC == reservation.MAC && lease.IPAddress == reservation.IPAddress
+		}) {
+			table.Append([]string{reservation.MAC, reservation.IPAddress, "", "Not leased", "true"})
+		}
+	}
+
+	table.Render()
+
+	return nil
+}
+
+// updateLeases opens the lease database to update it, which can't be done
+// while the network services are running.
+func updateLeases(c *cli.Context, update func(db *leasedb.DB) error) error {
+	db, err := openLeaseDB(c)
+	if errors.Is(err, leasedb.ErrInUse) {
+		return fmt.Errorf("%w (leases can only be listed while the network services are running, stop them to change leases)", leasedb.ErrInUse)
+	} else if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return update(db)
+}
@@ -0,0 +1,380 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// The number of clock ticks per second in /proc/<pid>/stat (USER_HZ, which is
+// 100 on every architecture Linux supports).
+const clockTicksPerSecond = 100
+
+func newLoadTestCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "loadtest",
+		Usage: "Simulates many high-rate devices, to measure how many channels a recorder can keep up with",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "devices",
+				Value: 100,
+				Usage: "Number of devices to simulate",
+			},
+			&cli.IntFlag{
+				Name:  "channels",
+				Value: 16,
+				Usage: "Number of signals of each device",
+			},
+			&cli.UintFlag{
+				Name:  "sample-rate",
+				Value: 512,
+				Usage: "Sample rate of the signals in Hertz",
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "127.0.2.1:8080",
+				Usage: "Address the first device listens on (the others listen on the following addresses)",
+			},
+			&cli.StringFlag{
+				Name:  "sources-file",
+				Usage: "Write the sources to record (one per line, as DRIVER:ARG) to this file",
+			},
+			&cli.StringFlag{
+				Name:  "recorder-process",
+				Usage: "Name of the recorder's process (eg. recorder), to measure its CPU and memory usage once it's running",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Value: 5 * time.Second,
+				Usage: "How often to report the throughput",
+			},
+			&cli.DurationFlag{
+				Name:  "duration",
+				Usage: "How long to run the load test for (until interrupted if zero)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			n := c.Int("devices")
+			channels := c.Int("channels")
+			sampleRate := uint32(c.Uint("sample-rate"))
+			if n < 1 || channels < 1 || sampleRate == 0 {
+				return fmt.Errorf("expected at least one device, channel and sample per second")
+			}
+
+			listenAddr, err := netip.ParseAddrPort(c.String("listen"))
+			if err != nil {
+				return fmt.Errorf("invalid listen address: %w", err)
+			}
+
+			ctx, cancel := context.WithCancel(appContext(c.Context))
+			defer cancel()
+			if duration := c.Duration("duration"); duration > 0 {
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+
+			g, ctx := errgroup.WithContext(ctx)
+
+			var devices []*openpsg.SimulatedDevice
+			var sources []string
+			addr := listenAddr
+			for i := range n {
+				if i > 0 {
+					addr = netip.AddrPortFrom(addr.Addr().Next(), addr.Port())
+				}
+
+				device := &openpsg.SimulatedDevice{
+					Info: openpsg.DeviceInfo{
+						SerialNumber: fmt.Sprintf("LOAD%03d", i+1),
+						Model:        "OpenPSG-Simulator",
+					},
+					Generator: loadTestSignals(channels, sampleRate),
+				}
+
+				l, err := net.Listen("tcp", addr.String())
+				if err != nil {
+					return fmt.Errorf("failed to listen: %w", err)
+				}
+
+				g.Go(func() error {
+					return device.Serve(ctx, l)
+				})
+
+				devices = append(devices, device)
+				sources = append(sources, "openpsg:"+addr.String())
+			}
+
+			if path := c.String("sources-file"); path != "" {
+				if err := os.WriteFile(path, []byte(strings.Join(sources, "\n")+"\n"), 0o644); err != nil {
+					return fmt.Errorf("failed to write sources: %w", err)
+				}
+			}
+
+			slog.Info("Simulating devices",
+				slog.Int("devices", n),
+				slog.Int("channels", n*channels),
+				slog.Uint64("sampleRate", uint64(sampleRate)),
+				slog.Any("firstAddress", listenAddr),
+				slog.Any("lastAddress", addr))
+
+			report := &loadTestReport{
+				devices:    devices,
+				channels:   channels,
+				sampleRate: sampleRate,
+				process:    c.String("recorder-process"),
+			}
+
+			g.Go(func() error {
+				ticker := time.NewTicker(c.Duration("interval"))
+				defer ticker.Stop()
+
+				report.sample()
+				for {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-ticker.C:
+						report.sample()
+					}
+				}
+			})
+
+			if err := g.Wait(); err != nil {
+				return err
+			}
+
+			report.render()
+			return nil
+		},
+	}
+}
+
+// loadTestSignals returns the signals of a load testing device: EEG channels
+// of sine waves of different frequencies.
+func loadTestSignals(channels int, sampleRate uint32) openpsg.Waveforms {
+	signals := make(openpsg.Waveforms, channels)
+	for i := range signals {
+		signals[i] = openpsg.SimulatedSignal{
+			Signal: openpsg.Signal{
+				ID:         uint32(i + 1),
+				Name:       fmt.Sprintf("EEG %d", i+1),
+				Unit:       openpsg.Microvolts,
+				Min:        -250,
+				Max:        250,
+				SampleRate: sampleRate,
+			},
+			Waveform:  openpsg.WaveformSine,
+			Frequency: float64(1 + i%30),
+			Amplitude: 50,
+		}
+	}
+	return signals
+}
+
+// loadTestReport measures the throughput of the simulated devices, and the
+// resources used by the recorder, while load testing.
+type loadTestReport struct {
+	devices    []*openpsg.SimulatedDevice
+	channels   int
+	sampleRate uint32
+	process    string
+
+	pid        int
+	start      time.Time
+	last       time.Time
+	lastValues uint64
+	lastCPU    time.Duration
+
+	maxConnected int
+	maxLag       time.Duration
+	peakCPU      float64
+	peakRSS      uint64
+}
+
+// sample measures the throughput since the last sample, logging it.
+func (r *loadTestReport) sample() {
+	now := time.Now()
+
+	var connected int
+	var values uint64
+	var lag time.Duration
+	for _, device := range r.devices {
+		stats := device.Stats()
+		if stats.Connections > 0 {
+			connected++
+		}
+		values += stats.ValuesSent
+		lag = max(lag, stats.Lag)
+	}
+
+	var cpuTime time.Duration
+	var rss uint64
+	var measured bool
+	if r.process != "" {
+		if pid, ok := findProcess(r.process); ok {
+			var err error
+			cpuTime, rss, err = processUsage(pid)
+			if err != nil {
+				slog.Warn("Failed to measure recorder usage", slog.Int("pid", pid), slog.Any("error", err))
+			}
+
+			// The CPU used is measured from the first sample of the process.
+			measured = err == nil && pid == r.pid
+			r.pid = pid
+		}
+	}
+
+	if !r.last.IsZero() {
+		elapsed := now.Sub(r.last).Seconds()
+		rate := float64(values-r.lastValues) / elapsed
+		expected := float64(connected*r.channels) * float64(r.sampleRate)
+
+		attrs := []any{
+			slog.Int("connected", connected),
+			slog.String("valuesPerSecond", fmt.Sprintf("%.0f", rate)),
+			slog.Duration("lag", lag),
+		}
+		if expected > 0 {
+			attrs = append(attrs, slog.String("throughput", fmt.Sprintf("%.1f%%", 100*rate/expected)))
+		}
+		if measured {
+			cpu := 100 * (cpuTime - r.lastCPU).Seconds() / elapsed
+			r.peakCPU = max(r.peakCPU, cpu)
+			attrs = append(attrs,
+				slog.String("recorderCPU", fmt.Sprintf("%.1f%%", cpu)),
+				slog.String("recorderRSS", fmt.Sprintf("%d MiB", rss>>20)))
+		}
+		slog.Info("Load test throughput", attrs...)
+	} else {
+		r.start = now
+	}
+
+	r.last, r.lastValues, r.lastCPU = now, values, cpuTime
+	r.maxConnected = max(r.maxConnected, connected)
+	r.maxLag = max(r.maxLag, lag)
+	r.peakRSS = max(r.peakRSS, rss)
+}
+
+// render prints a summary of the load test.
+func (r *loadTestReport) render() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Measure", "Value"})
+	table.SetBorder(false)
+
+	elapsed := r.last.Sub(r.start).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(r.lastValues) / elapsed
+	}
+
+	table.Append([]string{"Devices connected", fmt.Sprintf("%d of %d", r.maxConnected, len(r.devices))})
+	table.Append([]string{"Channels", fmt.Sprintf("%d at %d Hz", r.maxConnected*r.channels, r.sampleRate)})
+	table.Append([]string{"Mean values per second", fmt.Sprintf("%.0f (expected %d)", rate, r.maxConnected*r.channels*int(r.sampleRate))})
+	table.Append([]string{"Max lag", r.maxLag.String()})
+	if r.process != "" {
+		table.Append([]string{"Peak recorder CPU", fmt.Sprintf("%.1f%%", r.peakCPU)})
+		table.Append([]string{"Peak recorder RSS", fmt.Sprintf("%d MiB", r.peakRSS>>20)})
+	}
+
+	table.Render()
+}
+
+// findProcess returns the ID of the newest process (other than this one) with
+// the name.
+func findProcess(name string) (int, bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, false
+	}
+
+	var found int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+
+		comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+		if err == nil && strings.TrimSpace(string(comm)) == name {
+			found = max(found, pid)
+		}
+	}
+	return found, found != 0
+}
+
+// processUsage returns the CPU time used by a process, and its resident set
+// size in bytes.
+func processUsage(pid int) (time.Duration, uint64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read process stats: %w", err)
+	}
+
+	// The command name may contain spaces, so fields are counted from the
+	// closing parenthesis (utime and stime are the 14th and 15th fields).
+	i := strings.LastIndexByte(string(stat), ')')
+	fields := strings.Fields(string(stat[i+1:]))
+	if i < 0 || len(fields) < 13 {
+		return 0, 0, fmt.Errorf("invalid process stats")
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid process stats: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid process stats: %w", err)
+	}
+	cpuTime := time.Duration(utime+stime) * time.Second / clockTicksPerSecond
+
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read process status: %w", err)
+	}
+	defer f.Close()
+
+	var rss uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:"); ok {
+			kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid resident set size: %w", err)
+			}
+			rss = kb << 10
+		}
+	}
+
+	return cpuTime, rss, scanner.Err()
+}
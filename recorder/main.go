@@ -20,24 +20,17 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net"
-	"net/netip"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
 
 	"log/slog"
 
-	"github.com/OpenPSG/OpenPSG/recorder/internal/dhcp"
-	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
-	"github.com/OpenPSG/OpenPSG/recorder/internal/netutil"
-	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
-	"github.com/OpenPSG/sntp"
 	"github.com/adrg/xdg"
 	"github.com/urfave/cli/v2"
-	"golang.org/x/sync/errgroup"
 )
 
 func main() {
@@ -48,6 +41,39 @@ func main() {
 		dbPath = "dhcp_leases.db"
 	}
 
+	// Keep the pseudonym key file away from the recordings.
+	keyFilePath, err := xdg.DataFile("openpsg-recorder/pseudonyms.key")
+	if err != nil {
+		slog.Warn("Failed to get default key file path", slog.Any("error", err))
+		keyFilePath = "pseudonyms.key"
+	}
+
+	// The certificate authority provisioning devices (see provision).
+	caDir := "ca"
+	if caCertPath, err := xdg.DataFile("openpsg-recorder/ca/ca.pem"); err != nil {
+		slog.Warn("Failed to get default CA directory", slog.Any("error", err))
+	} else {
+		caDir = filepath.Dir(caCertPath)
+	}
+
+	// Study profiles (see profile).
+	profilesDir := "profiles"
+	if profilePath, err := xdg.ConfigFile("openpsg-recorder/profiles/" + profileFileName); err != nil {
+		slog.Warn("Failed to get default profiles directory", slog.Any("error", err))
+	} else {
+		profilesDir = filepath.Dir(profilePath)
+	}
+
+	// The socket serve listens on, for record to attach to.
+	socketPath, err := xdg.RuntimeFile("openpsg-recorder/serve.sock")
+	if err != nil {
+		slog.Warn("Failed to get default socket path", slog.Any("error", err))
+		socketPath = "serve.sock"
+	}
+
+	// Configured by the log-level flag.
+	var logLevel slog.Level
+
 	sharedFlags := []cli.Flag{
 		&cli.StringFlag{
 			Name:  "log-level",
@@ -64,129 +90,46 @@ func main() {
 	app := &cli.App{
 		Name:  "openpsg-recorder",
 		Usage: "Records PSG data from one or more Ethernet sensors",
-		Flags: append([]cli.Flag{
-			&cli.StringFlag{
-				Name:     "interface",
-				Aliases:  []string{"i"},
-				Usage:    "Network interface name",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:  "prefix",
-				Value: "10.24.0.0/24",
-				Usage: "CIDR prefix for the network",
-			},
-			&cli.StringFlag{
-				Name:  "gateway",
-				Value: "10.24.0.1",
-				Usage: "Gateway IP address",
-			},
-			&cli.StringFlag{
-				Name:    "output",
-				Aliases: []string{"o"},
-				Value:   "openpsg.edf",
-				Usage:   "Output file for the recording",
-			},
-			&cli.StringFlag{
-				Name:    "patient-id",
-				Aliases: []string{"p"},
-				Value:   "X",
-				Usage:   "Patient ID for the recording",
-			},
-			&cli.StringFlag{
-				Name:    "recording-id",
-				Aliases: []string{"r"},
-				Value:   "1",
-				Usage:   "Recording ID for the recording",
-			},
-		}, sharedFlags...),
-		Action: func(c *cli.Context) error {
+		Flags: slices.Concat(networkFlags(), recordFlags(keyFilePath), []cli.Flag{socketFlag(socketPath)}, sharedFlags),
+		Before: func(c *cli.Context) error {
 			// Configure the logger.
-			var logLevel slog.Level
 			if err := logLevel.UnmarshalText([]byte(c.String("log-level"))); err != nil {
 				return fmt.Errorf("failed to parse log level: %w", err)
 			}
 			slog.SetLogLoggerLevel(logLevel)
 
-			ifname := c.String("interface")
-
-			prefix, err := netip.ParsePrefix(c.String("prefix"))
-			if err != nil {
-				return fmt.Errorf("failed to parse network prefix: %w", err)
-			}
-
-			gateway, err := netip.ParseAddr(c.String("gateway"))
-			if err != nil {
-				return fmt.Errorf("failed to parse network gateway address: %w", err)
-			}
-
-			// Configure the network interface.
-			if err := netutil.ConfigureNetworkInterface(ifname, gateway, prefix); err != nil {
-				return fmt.Errorf("failed to setup interface: %w", err)
-			}
-
-			// Open the DHCP lease database.
-			db, err := leasedb.Open(c.String("db-path"), prefix, gateway)
-			if err != nil {
-				return fmt.Errorf("failed to open dhcp lease database: %w", err)
-			}
-			defer db.Close()
-
-			g, ctx := errgroup.WithContext(appContext(c.Context))
-
-			// Set up the DHCP server.
-			dhcpServer := dhcp.NewServer(db, ifname, prefix, gateway)
-			g.Go(func() error {
-				slog.Debug("Starting DHCP server",
-					slog.String("interface", ifname),
-					slog.Any("prefix", prefix),
-					slog.Any("gateway", gateway))
-
-				err := dhcpServer.ListenAndServe(ctx)
-				if err != nil && !errors.Is(err, net.ErrClosed) {
-					return fmt.Errorf("failed to run DHCP server: %w", err)
-				}
-
-				return nil
-			})
-
-			// Set up the NTP server
-			ntpServer := sntp.NewServer()
-			g.Go(func() error {
-				slog.Debug("Starting NTP server")
-
-				err := ntpServer.ListenAndServe(ctx, net.JoinHostPort(gateway.String(), "123"))
-				if err != nil && !errors.Is(err, net.ErrClosed) {
-					return fmt.Errorf("failed to run NTP server: %w", err)
-				}
-
-				return nil
-			})
-
-			g.Go(func() error {
-				slog.Info("Discovering devices ...")
-
-				deviceAddrs, err := openpsg.Discover(ctx, db)
-				if err != nil {
-					return fmt.Errorf("failed to discover devices: %w", err)
-				}
-
-				slog.Info("Recording from devices", slog.Any("deviceAddrs", deviceAddrs))
-
-				f, err := os.Create(c.String("output"))
-				if err != nil {
-					return fmt.Errorf("failed to create file: %w", err)
-				}
-				defer f.Close()
-
-				if err := openpsg.Record(ctx, f, c.String("patient-id"), c.String("recording-id"), deviceAddrs); err != nil {
-					return fmt.Errorf("failed to record from devices: %w", err)
-				}
-
-				return nil
-			})
-
-			return g.Wait()
+			return applyProfileFlag(c, profilesDir)
+		},
+		Commands: []*cli.Command{
+			newConformanceCommand(),
+			newConvertCommand(),
+			newDevicesCommand(),
+			newExtractCommand(),
+			newFirmwareCommand(),
+			newIdentifyCommand(),
+			newImpedanceCommand(&logLevel),
+			newInspectCommand(),
+			newLeasesCommand(),
+			newLoadTestCommand(),
+			newMergeCommand(),
+			newPreviewCommand(&logLevel),
+			newProbeCommand(),
+			newProfileCommand(profilesDir),
+			newProvisionCommand(caDir),
+			newPseudonymsCommand(keyFilePath),
+			newRecordCommand(keyFilePath, socketPath, profilesDir, &logLevel),
+			newRecoverCommand(),
+			newRepairCommand(),
+			newReplayCommand(),
+			newReplayCaptureCommand(),
+			newReportCommand(),
+			newServeCommand(socketPath),
+			newSimulateCommand(),
+		},
+		// Without a subcommand, the recorder runs the network services itself
+		// for the duration of the recording.
+		Action: func(c *cli.Context) error {
+			return runRecorder(c, logLevel, false)
 		},
 	}
 
@@ -210,3 +153,29 @@ func appContext(ctx context.Context) context.Context {
 
 	return ctx
 }
+
+// pauseSignals pauses the recording on SIGUSR1 and resumes it on SIGUSR2.
+func pauseSignals(ctx context.Context) <-chan bool {
+	pause := make(chan bool)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(sigs)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case s := <-sigs:
+				select {
+				case pause <- s == syscall.SIGUSR1:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return pause
+}
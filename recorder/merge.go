@@ -0,0 +1,98 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/urfave/cli/v2"
+)
+
+func newMergeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "merge",
+		Usage:     "Combines EDF recordings into a single discontinuous EDF+ recording",
+		ArgsUsage: "<recording.edf> <recording.edf>...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "Path of the merged recording",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 2 {
+				return fmt.Errorf("expected at least two recordings to merge")
+			}
+
+			outputPath := c.String("output")
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("refusing to overwrite existing recording: %s", outputPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to stat recording: %w", err)
+			}
+
+			var inputs []*edfplus.Reader
+			for _, inputPath := range c.Args().Slice() {
+				f, err := os.Open(inputPath)
+				if err != nil {
+					return fmt.Errorf("failed to open recording: %w", err)
+				}
+				defer f.Close()
+
+				er, err := edfplus.Open(f)
+				if err != nil {
+					return fmt.Errorf("failed to read recording %s: %w", inputPath, err)
+				}
+				inputs = append(inputs, er)
+			}
+
+			slog.Info("Merging recordings",
+				slog.Any("inputs", c.Args().Slice()),
+				slog.String("output", outputPath))
+
+			partialPath := outputPath + partialSuffix
+			f, err := os.Create(partialPath)
+			if err != nil {
+				return fmt.Errorf("failed to create merged recording: %w", err)
+			}
+			defer f.Close()
+
+			if err := edfplus.Merge(f, inputs); err != nil {
+				_ = os.Remove(partialPath)
+				return fmt.Errorf("failed to merge recordings: %w", err)
+			}
+
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to close merged recording: %w", err)
+			}
+
+			if err := os.Rename(partialPath, outputPath); err != nil {
+				return fmt.Errorf("failed to rename merged recording: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,474 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/serial"
+)
+
+// The baud rate of ANT USB receivers (eg. the ANT USB-m stick, with the
+// usbserial driver).
+const antBaud = 115200
+
+// How long to wait for the receiver to respond to a command.
+const antResponseTimeout = time.Second
+
+// How long the receiver takes to reset.
+const antResetDelay = 500 * time.Millisecond
+
+// The heart rate is unknown when no new heart beat has been received from the
+// strap for this long.
+const antHeartRateTimeout = 5 * time.Second
+
+// The ANT+ network key.
+var antPlusNetworkKey = []byte{0xb9, 0xa5, 0x21, 0xfb, 0xbd, 0x72, 0xc3, 0x45}
+
+// ANT message IDs.
+const (
+	antSync               byte = 0xa4
+	antChannelEvent       byte = 0x40
+	antAssignChannel      byte = 0x42
+	antChannelPeriod      byte = 0x43
+	antSearchTimeout      byte = 0x44
+	antChannelRFFrequency byte = 0x45
+	antSetNetworkKey      byte = 0x46
+	antResetSystem        byte = 0x4a
+	antOpenChannel        byte = 0x4b
+	antBroadcastData      byte = 0x4e
+	antChannelID          byte = 0x51
+)
+
+// ANT channel event codes.
+const (
+	antResponseNoError       byte = 0x00
+	antEventRXFailGoToSearch byte = 0x08
+)
+
+// The channel settings of the ANT+ heart rate monitor profile.
+const (
+	antHRMDeviceType  byte   = 120
+	antHRMRFFrequency byte   = 57 // 2457 MHz
+	antHRMPeriod      uint16 = 8070
+)
+
+// The ID of the pulse signal of an ANT+ heart rate monitor.
+const antPulse uint32 = 1
+
+// antMessage is a message to or from an ANT receiver.
+type antMessage struct {
+	id   byte
+	data []byte
+}
+
+// encode returns the message framed for the serial interface: sync, length,
+// ID, data and an XOR checksum.
+func (m antMessage) encode() []byte {
+	b := append([]byte{antSync, byte(len(m.data)), m.id}, m.data...)
+	var checksum byte
+	for _, c := range b {
+		checksum ^= c
+	}
+	return append(b, checksum)
+}
+
+// antDecoder decodes the messages from an ANT receiver.
+type antDecoder struct {
+	buf []byte
+}
+
+// decode adds a byte, returning a message once one is complete.
+func (d *antDecoder) decode(b byte) (antMessage, bool) {
+	if len(d.buf) == 0 && b != antSync {
+		return antMessage{}, false
+	}
+
+	d.buf = append(d.buf, b)
+	if len(d.buf) < 2 || len(d.buf) < int(d.buf[1])+4 {
+		return antMessage{}, false
+	}
+
+	var checksum byte
+	for _, c := range d.buf {
+		checksum ^= c
+	}
+	if checksum != 0 {
+		// Resynchronize on the next sync byte after the bad one.
+		buf := d.buf[1:]
+		d.buf = nil
+		for _, c := range buf {
+			if msg, ok := d.decode(c); ok {
+				return msg, true
+			}
+		}
+		return antMessage{}, false
+	}
+
+	msg := antMessage{id: d.buf[2], data: append([]byte(nil), d.buf[3:len(d.buf)-1]...)}
+	d.buf = d.buf[:0]
+	return msg, true
+}
+
+// antHeartRate tracks the heart rate from the data pages of an ANT+ heart rate
+// monitor. Every page ends with the time of the last heart beat, a count of
+// heart beats (rolling over at 256) and the computed heart rate (zero if
+// invalid). The first byte is the page number, with its high bit toggled every
+// four pages, except on legacy monitors which never toggle it.
+type antHeartRate struct {
+	received  bool
+	heartRate float64
+	beatCount byte
+	lastBeat  time.Time
+
+	toggle bool
+	// Whether the monitor toggles the page bit, so the page numbers are valid.
+	paged bool
+
+	identified   bool
+	manufacturer byte
+	serial       uint16
+}
+
+// update adds a data page (the 8 bytes of a broadcast data message after the
+// channel number) received at now.
+func (h *antHeartRate) update(page []byte, now time.Time) {
+	toggle := page[0]&0x80 != 0
+	if h.received && toggle != h.toggle {
+		h.paged = true
+	}
+	h.toggle = toggle
+
+	// The manufacturer information page, with the upper 16 bits of the serial
+	// number.
+	if h.paged && page[0]&0x7f == 2 && !h.identified {
+		h.identified = true
+		h.manufacturer, h.serial = page[1], binary.LittleEndian.Uint16(page[2:4])
+		slog.Info("Paired with ANT+ heart rate monitor",
+			slog.Int("manufacturer", int(h.manufacturer)), slog.Int("serial", int(h.serial)))
+	}
+
+	// Some straps keep transmitting their last heart rate when they're taken
+	// off, so the heart rate is only fresh while the beat count changes.
+	if !h.received || page[6] != h.beatCount {
+		h.lastBeat = now
+	}
+	h.beatCount = page[6]
+	h.heartRate = float64(page[7])
+	h.received = true
+}
+
+// value returns the heart rate at now (zero while unknown).
+func (h *antHeartRate) value(now time.Time) float64 {
+	if !h.received || now.Sub(h.lastBeat) > antHeartRateTimeout {
+		return 0
+	}
+	return h.heartRate
+}
+
+// ANTSource streams the heart rate of an ANT+ heart rate monitor (eg. a chest
+// strap), received by an ANT USB receiver.
+type ANTSource struct {
+	port    *os.File
+	signals []Signal
+
+	messages     chan antMessage
+	signalValues chan SignalValues
+	leadOff      chan LeadOffStatus
+	disconnected chan struct{}
+
+	mu      sync.Mutex
+	enabled map[uint32]bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func init() {
+	RegisterDriver("ant", func(port string, _ ...ConnectOption) (netip.Addr, SourceOpener, error) {
+		return netip.Addr{}, OpenANT(port), nil
+	})
+}
+
+// OpenANT returns an opener for the ANT+ heart rate monitor received by the
+// ANT receiver at the serial port path (eg. "/dev/ttyUSB0"). The receiver pairs
+// with the first heart rate monitor it finds.
+func OpenANT(path string) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		port, err := serial.Open(path, antBaud)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ANT receiver: %w", err)
+		}
+
+		readCtx, cancel := context.WithCancel(context.Background())
+		s := &ANTSource{
+			port: port,
+			signals: []Signal{
+				{ID: antPulse, Name: "Pulse", Unit: BeatsPerMinute, Min: 0, Max: 255,
+					SampleFormat: SampleFormatFloat32, SampleRate: 1, LeadOffDetection: true},
+			},
+			messages:     make(chan antMessage),
+			signalValues: make(chan SignalValues),
+			leadOff:      make(chan LeadOffStatus, leadOffBufferSize),
+			disconnected: make(chan struct{}),
+			enabled:      make(map[uint32]bool),
+			cancel:       cancel,
+			done:         make(chan struct{}),
+		}
+		go s.read(readCtx)
+
+		if err := s.configure(ctx); err != nil {
+			cancel()
+			_ = port.Close()
+			return nil, fmt.Errorf("failed to configure ANT receiver: %w", err)
+		}
+
+		go s.stream(readCtx)
+
+		return s, nil
+	}
+}
+
+// configure resets the receiver and opens a channel to receive from any ANT+
+// heart rate monitor.
+func (s *ANTSource) configure(ctx context.Context) error {
+	if _, err := s.port.Write(antMessage{id: antResetSystem, data: []byte{0}}.encode()); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(antResetDelay):
+	}
+
+	// Discard the startup message.
+	for drained := false; !drained; {
+		select {
+		case <-s.messages:
+		default:
+			drained = true
+		}
+	}
+
+	const channel, network = 0, 0
+	for _, msg := range []antMessage{
+		{id: antSetNetworkKey, data: append([]byte{network}, antPlusNetworkKey...)},
+		// A bidirectional receive (slave) channel.
+		{id: antAssignChannel, data: []byte{channel, 0x00, network}},
+		// Any device number and transmission type.
+		{id: antChannelID, data: []byte{channel, 0, 0, antHRMDeviceType, 0}},
+		{id: antChannelRFFrequency, data: []byte{channel, antHRMRFFrequency}},
+		{id: antChannelPeriod, data: []byte{channel, byte(antHRMPeriod & 0xff), byte(antHRMPeriod >> 8)}},
+		// Search indefinitely.
+		{id: antSearchTimeout, data: []byte{channel, 0xff}},
+		{id: antOpenChannel, data: []byte{channel}},
+	} {
+		if err := s.command(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// command sends a command to the receiver and waits for its response.
+func (s *ANTSource) command(ctx context.Context, msg antMessage) error {
+	if _, err := s.port.Write(msg.encode()); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, antResponseTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no response to message 0x%02x: %w", msg.id, ctx.Err())
+		case resp, ok := <-s.messages:
+			if !ok {
+				return fmt.Errorf("receiver detached")
+			}
+
+			if resp.id != antChannelEvent || len(resp.data) < 3 || resp.data[1] != msg.id {
+				continue
+			}
+			if resp.data[2] != antResponseNoError {
+				return fmt.Errorf("message 0x%02x failed with code 0x%02x", msg.id, resp.data[2])
+			}
+			return nil
+		}
+	}
+}
+
+// Signals returns the signals of the heart rate monitor.
+func (s *ANTSource) Signals(ctx context.Context) ([]Signal, error) {
+	return s.signals, nil
+}
+
+// Start streaming the specified signals.
+func (s *ANTSource) Start(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		s.enabled[id] = true
+	}
+	return nil
+}
+
+// Stop streaming the specified signals.
+func (s *ANTSource) Stop(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		delete(s.enabled, id)
+	}
+	return nil
+}
+
+// Disconnected returns a channel that is closed when the receiver is detached
+// (or closed).
+func (s *ANTSource) Disconnected() <-chan struct{} {
+	return s.disconnected
+}
+
+// SignalValues returns a channel that will receive the values of the signals.
+func (s *ANTSource) SignalValues() <-chan SignalValues {
+	return s.signalValues
+}
+
+// LeadOff returns a channel that will receive changes in whether the heart rate
+// monitor is in range (and worn). Changes are dropped if they aren't received
+// promptly.
+func (s *ANTSource) LeadOff() <-chan LeadOffStatus {
+	return s.leadOff
+}
+
+func (s *ANTSource) Close() error {
+	s.cancel()
+	// Closing the port interrupts any pending read.
+	err := s.port.Close()
+	<-s.done
+
+	close(s.signalValues)
+	close(s.leadOff)
+
+	return err
+}
+
+// read decodes the messages from the receiver, until it is detached or closed.
+func (s *ANTSource) read(ctx context.Context) {
+	defer close(s.messages)
+
+	var decoder antDecoder
+	r := bufio.NewReader(s.port)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Failed to read from ANT receiver", slog.Any("error", err))
+			}
+			return
+		}
+
+		msg, ok := decoder.decode(b)
+		if !ok {
+			continue
+		}
+
+		select {
+		case s.messages <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stream sends the heart rate once a second (zero while unknown).
+func (s *ANTSource) stream(ctx context.Context) {
+	defer close(s.done)
+	defer close(s.disconnected)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock := sampleClock{rate: 1}
+
+	// The strap is assumed to be in range until the receiver reports losing it.
+	var heartRate antHeartRate
+	var lost bool
+	setLost := func(l bool) {
+		if l == lost {
+			return
+		}
+		lost = l
+		select {
+		case s.leadOff <- LeadOffStatus{ID: antPulse, Timestamp: time.Now(), LeadOff: lost}:
+		default:
+			slog.Warn("Dropped ANT+ heart rate monitor status")
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-s.messages:
+			if !ok {
+				return
+			}
+
+			switch {
+			case msg.id == antBroadcastData && len(msg.data) == 9:
+				heartRate.update(msg.data[1:], time.Now())
+				setLost(false)
+			case msg.id == antChannelEvent && len(msg.data) >= 3 && msg.data[1] == 1 &&
+				msg.data[2] == antEventRXFailGoToSearch:
+				setLost(true)
+			}
+		case <-ticker.C:
+			if !s.isEnabled(antPulse) {
+				continue
+			}
+
+			select {
+			case s.signalValues <- SignalValues{ID: antPulse, Timestamp: clock.timestamp(1), Values: []float64{heartRate.value(time.Now())}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *ANTSource) isEnabled(id uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enabled[id]
+}
@@ -0,0 +1,155 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+// hrmPage encodes a data page of a heart rate monitor, with the page specific
+// bytes.
+func hrmPage(number byte, toggle bool, specific [3]byte, beatCount, heartRate byte) []byte {
+	if toggle {
+		number |= 0x80
+	}
+	return []byte{number, specific[0], specific[1], specific[2], 0x00, 0x04, beatCount, heartRate}
+}
+
+func TestANTHeartRate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	// The manufacturer information page, from manufacturer 1 with serial
+	// 0x1234.
+	manufacturer := [3]byte{1, 0x34, 0x12}
+
+	type page struct {
+		// The seconds since start the page is received.
+		at   int
+		data []byte
+	}
+
+	tests := []struct {
+		name  string
+		pages []page
+		// The seconds since start the heart rate is read.
+		at               int
+		wantValue        float64
+		wantIdentified   bool
+		wantManufacturer byte
+		wantSerial       uint16
+	}{
+		{
+			name:      "No pages",
+			wantValue: 0,
+		},
+		{
+			name: "Heart rate",
+			pages: []page{
+				{0, hrmPage(4, false, [3]byte{}, 1, 60)},
+				{1, hrmPage(4, false, [3]byte{}, 2, 62)},
+			},
+			at:        2,
+			wantValue: 62,
+		},
+		{
+			name: "Invalid heart rate",
+			pages: []page{
+				{0, hrmPage(4, false, [3]byte{}, 1, 0)},
+			},
+			at:        1,
+			wantValue: 0,
+		},
+		{
+			name: "Beat count rollover",
+			pages: []page{
+				{0, hrmPage(4, false, [3]byte{}, 254, 60)},
+				{2, hrmPage(4, false, [3]byte{}, 255, 60)},
+				{4, hrmPage(4, false, [3]byte{}, 0, 60)},
+				{6, hrmPage(4, false, [3]byte{}, 1, 60)},
+			},
+			at:        10,
+			wantValue: 60,
+		},
+		{
+			name: "Beat count stopped",
+			pages: []page{
+				{0, hrmPage(4, false, [3]byte{}, 7, 60)},
+				{2, hrmPage(4, false, [3]byte{}, 7, 60)},
+				{4, hrmPage(4, false, [3]byte{}, 7, 60)},
+				{6, hrmPage(4, false, [3]byte{}, 7, 60)},
+			},
+			at:        6,
+			wantValue: 0,
+		},
+		{
+			name: "Page toggle",
+			pages: []page{
+				{0, hrmPage(4, false, [3]byte{}, 1, 60)},
+				{1, hrmPage(2, true, manufacturer, 2, 60)},
+			},
+			at:               1,
+			wantValue:        60,
+			wantIdentified:   true,
+			wantManufacturer: 1,
+			wantSerial:       0x1234,
+		},
+		{
+			name: "Page before toggle",
+			// The page number isn't known to be valid until the bit toggles.
+			pages: []page{
+				{0, hrmPage(2, false, manufacturer, 1, 60)},
+				{1, hrmPage(4, true, [3]byte{}, 2, 60)},
+			},
+			at:        1,
+			wantValue: 60,
+		},
+		{
+			name: "Legacy monitor",
+			// Legacy monitors never toggle the bit, so the first byte isn't a
+			// page number.
+			pages: []page{
+				{0, hrmPage(2, false, manufacturer, 1, 60)},
+				{1, hrmPage(2, false, manufacturer, 2, 60)},
+				{2, hrmPage(2, false, manufacturer, 3, 60)},
+			},
+			at:        2,
+			wantValue: 60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h openpsg.ANTHeartRate
+			for _, p := range tt.pages {
+				h.Update(p.data, start.Add(time.Duration(p.at)*time.Second))
+			}
+
+			assert.Equal(t, tt.wantValue, h.Value(start.Add(time.Duration(tt.at)*time.Second)))
+
+			manufacturer, serial, identified := h.Manufacturer()
+			assert.Equal(t, tt.wantIdentified, identified)
+			assert.Equal(t, tt.wantManufacturer, manufacturer)
+			assert.Equal(t, tt.wantSerial, serial)
+		})
+	}
+}
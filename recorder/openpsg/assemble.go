@@ -0,0 +1,681 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/edf"
+)
+
+// write creates the EDF files of the recording (or appends to the file of a
+// resumed recording), and assembles the buffered signal values into data
+// records until the recording is stopped.
+func (r *recording) write(ctx context.Context, edfFile io.WriteSeeker, patientID, recordingID string) (err error) {
+	startTime := r.start.Truncate(time.Second)
+
+	hdr := edf.Header{
+		Version:            edf.Version0,
+		PatientID:          edfplus.PatientIdentification(patientID, "", time.Time{}, ""),
+		RecordingID:        edfplus.RecordingIdentification(startTime, recordingID, "", equipment(r.sidecar.Devices)),
+		StartTime:          startTime,
+		Reserved:           edfplus.Continuous,
+		DataRecordDuration: r.options.recordDuration,
+	}
+
+	signalHeaders := r.signalHeaders()
+
+	maxSignalsPerFile := r.options.maxSignalsPerFile
+	if maxSignalsPerFile == 0 {
+		maxSignalsPerFile = defaultMaxSignalsPerFile
+	}
+
+	parts, err := splitSignals(len(signalHeaders), maxSignalsPerFile)
+	if err != nil {
+		return err
+	}
+
+	if len(parts) > 1 && r.options.splitFiles == nil {
+		return fmt.Errorf("too many signals for a single EDF file (%d > %d)", len(signalHeaders)+1, maxSignalsPerFile)
+	}
+
+	if len(parts) > 1 && r.options.resume != nil {
+		return fmt.Errorf("recordings split across multiple EDF files can't be resumed")
+	}
+
+	if err := r.assignFiles(signalHeaders, parts); err != nil {
+		return err
+	}
+
+	writers := make([]*edfplus.Writer, 0, len(parts))
+	// The recording is only complete if every header is finalized.
+	defer func() {
+		for _, ew := range writers {
+			if closeErr := ew.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to close EDF writer: %w", closeErr)
+			}
+		}
+	}()
+
+	if r.options.resume != nil {
+		rws, ok := edfFile.(io.ReadWriteSeeker)
+		if !ok {
+			return fmt.Errorf("resuming requires a readable EDF file")
+		}
+
+		ew, err := edfplus.Append(rws)
+		if err != nil {
+			return fmt.Errorf("failed to open EDF file for appending: %w", err)
+		}
+		writers = append(writers, ew)
+
+		if err := r.options.resume.Validate(ew.Header(), ew.DataRecords()); err != nil {
+			return fmt.Errorf("failed to resume recording: %w", err)
+		}
+
+		slog.Info("Resuming recording", slog.Int("dataRecords", ew.DataRecords()))
+	} else {
+		slog.Info("Writing EDF file header")
+
+		for n, part := range parts {
+			w := edfFile
+			if n > 0 {
+				w, err = r.options.splitFiles.Create(n)
+				if err != nil {
+					return fmt.Errorf("failed to create EDF file: %w", err)
+				}
+			}
+
+			partHdr := hdr
+			partHdr.Signals = nil
+			for _, signalIndex := range part {
+				partHdr.Signals = append(partHdr.Signals, signalHeaders[signalIndex])
+			}
+			partHdr.Signals = append(partHdr.Signals, edfplus.AnnotationSignal(annotationBytesPerRecord))
+
+			ew, err := edfplus.Create(w, partHdr)
+			if err != nil {
+				return fmt.Errorf("failed to create EDF writer: %w", err)
+			}
+			writers = append(writers, ew)
+		}
+	}
+
+	samplesPerRecord := make([]int, len(signalHeaders))
+	for i, signalHeader := range signalHeaders {
+		samplesPerRecord[i] = signalHeader.SamplesPerRecord
+	}
+
+	// The journal describes the data records that have been checkpointed.
+	// Recordings split across multiple files can't be resumed.
+	var journal *Journal
+	if r.options.journalPath != "" && len(parts) == 1 {
+		journal = r.journal(writers[0].Header(), signalHeaders)
+		if err := journal.writeFile(r.options.journalPath); err != nil {
+			return err
+		}
+	}
+
+	checkpointed := func() {
+		if journal == nil {
+			return
+		}
+
+		journal.DataRecords = writers[0].DataRecords()
+		journal.EndOffset = int64(journal.HeaderBytes) + int64(journal.DataRecords)*int64(journal.RecordSize)
+		if err := journal.writeFile(r.options.journalPath); err != nil {
+			slog.Warn("Failed to write journal", slog.Any("error", err))
+		}
+	}
+
+	rw := newRecordWriter(writers, parts, samplesPerRecord, r.options.syncInterval, checkpointed)
+	// Before the EDF writers are closed.
+	defer func() {
+		if closeErr := rw.close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	a := newAssembler(r, rw, signalHeaders, startTime)
+
+	r.sidecar.StartTime = r.start
+	r.writeSidecar()
+
+	if r.options.resume != nil {
+		a.annotate(Annotation{Time: r.sessionStart, Text: "Recording resumed after interruption"})
+
+		// The data records already in the file are kept.
+		r.sidecar.DataRecords = writers[0].DataRecords()
+		if gap := a.onset - writers[0].NextOnset(); gap > 0 {
+			r.sidecar.addGap(writers[0].NextOnset(), gap)
+		}
+	} else {
+		a.annotate(Annotation{Time: r.start, Text: "Recording started"})
+
+		if r.options.startOffsetAnnotation {
+			a.annotate(Annotation{Time: r.start, Text: fmt.Sprintf("Start offset %.6f s", a.onset.Seconds())})
+		}
+	}
+
+	// Each data record is written half a record after it ends, giving late
+	// (or out of order) signal values time to arrive.
+	timer := time.NewTimer(time.Until(r.recordStart) + hdr.DataRecordDuration/2)
+	defer timer.Stop()
+
+	var ticker *time.Ticker
+	var ticks <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	annotations := r.options.annotations
+	pause := r.options.pause
+	for {
+		select {
+		case <-ctx.Done():
+			return a.stop()
+		case <-rw.failed:
+			return rw.close()
+		case annotation, ok := <-annotations:
+			if !ok {
+				annotations = nil
+				continue
+			}
+			a.annotate(annotation)
+		case annotation := <-r.deviceEvents:
+			a.annotate(annotation)
+		case t := <-r.audioStarted:
+			slog.Info("Started audio capture", slog.String("file", r.sidecar.Audio.File))
+			a.annotate(Annotation{Time: t, Text: "Audio started: " + r.sidecar.Audio.File})
+
+			r.sidecar.Audio.StartTime = &t
+			r.writeSidecar()
+		case p, ok := <-pause:
+			if !ok {
+				pause = nil
+				continue
+			}
+			a.pause(p, time.Now())
+		case <-timer.C:
+			ticker = time.NewTicker(hdr.DataRecordDuration)
+			ticks = ticker.C
+		case <-ticks:
+			if err := a.writeRecord(false); err != nil {
+				return err
+			}
+
+			// Catch up on any data records that are overdue (eg. as writing
+			// stalled), as ticks are dropped while the writer is busy.
+			for time.Since(a.end()) >= hdr.DataRecordDuration {
+				if err := a.writeRecord(false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// signalHeaders returns the headers of the signals stored in the EDF files:
+// the recorded signals, followed by the derived signals and the lead-off
+// status signals.
+func (r *recording) signalHeaders() []edf.SignalHeader {
+	recordDuration := r.options.recordDuration
+
+	signalHeaders := make([]edf.SignalHeader, len(r.signals))
+	for i, signal := range r.signals {
+		dmin, dmax := signal.edfDigitalRange()
+		signalHeaders[i] = edf.SignalHeader{
+			Label:             signal.Name,
+			TransducerType:    transducer(r.names[r.signalDevices[i]], signal.TransducerType),
+			PhysicalDimension: string(signal.Unit),
+			PhysicalMin:       r.calibrations[i].apply(float64(signal.Min)),
+			PhysicalMax:       r.calibrations[i].apply(float64(signal.Max)),
+			DigitalMin:        dmin,
+			DigitalMax:        dmax,
+			Prefiltering:      signal.Prefiltering.String(),
+			SamplesPerRecord:  int(float64(signal.SampleRate) * recordDuration.Seconds()),
+		}
+
+		if r.calibrations[i] != nil {
+			signalHeaders[i].Prefiltering = strings.TrimSpace(signalHeaders[i].Prefiltering + " " + r.calibrations[i].String())
+		}
+
+		// Keep the invalid marker outside of the digital range.
+		if r.options.gapFill == GapFillInvalid && dmin <= edfplus.InvalidSample {
+			signalHeaders[i].DigitalMin = edfplus.InvalidSample + 1
+		}
+	}
+
+	for _, d := range r.derived {
+		signalHeader := edf.SignalHeader{
+			Label:             d.channel.Label,
+			TransducerType:    string(d.channel.TransducerType),
+			PhysicalDimension: string(d.unit),
+			PhysicalMin:       d.min,
+			PhysicalMax:       d.max,
+			DigitalMin:        math.MinInt16,
+			DigitalMax:        math.MaxInt16,
+			Prefiltering:      signalHeaders[d.inputs[0]].Prefiltering,
+			SamplesPerRecord:  int(float64(d.sampleRate) * recordDuration.Seconds()),
+		}
+
+		if r.options.gapFill == GapFillInvalid {
+			signalHeader.DigitalMin = edfplus.InvalidSample + 1
+		}
+
+		signalHeaders = append(signalHeaders, signalHeader)
+	}
+
+	for _, i := range r.leadOffSignals {
+		signalHeaders = append(signalHeaders, edf.SignalHeader{
+			Label:            "LO " + r.signals[i].Name,
+			TransducerType:   "Lead-off detection",
+			PhysicalMin:      0,
+			PhysicalMax:      1,
+			DigitalMin:       0,
+			DigitalMax:       1,
+			SamplesPerRecord: int(leadOffSampleRate * recordDuration.Seconds()),
+		})
+	}
+
+	return signalHeaders
+}
+
+// assignFiles records which EDF file (of a recording split across multiple
+// files) each signal is stored in, in the sidecar and the manifest.
+func (r *recording) assignFiles(signalHeaders []edf.SignalHeader, parts [][]int) error {
+	signalFiles := make([]string, len(signalHeaders))
+	signalFileIndices := make([]int, len(signalHeaders))
+	for n, part := range parts {
+		for i, signalIndex := range part {
+			if len(parts) > 1 {
+				signalFiles[signalIndex] = r.options.splitFiles.Name(n)
+			}
+			signalFileIndices[signalIndex] = i
+		}
+	}
+
+	for i := range r.sidecar.Devices {
+		for j := range r.sidecar.Devices[i].Signals {
+			signal := &r.sidecar.Devices[i].Signals[j]
+			signal.File = signalFiles[signal.Index]
+			signal.Index = signalFileIndices[signal.Index]
+		}
+	}
+
+	for i := range r.sidecar.Derived {
+		signal := &r.sidecar.Derived[i]
+		signal.File = signalFiles[signal.Index]
+		signal.Index = signalFileIndices[signal.Index]
+	}
+
+	if len(parts) == 1 {
+		return nil
+	}
+
+	slog.Info("Splitting recording across multiple EDF files", slog.Int("files", len(parts)))
+
+	manifest := Manifest{StartTime: r.start}
+	for n, part := range parts {
+		file := ManifestFile{Name: r.options.splitFiles.Name(n), Signals: []string{}}
+		for _, signalIndex := range part {
+			file.Signals = append(file.Signals, signalHeaders[signalIndex].Label)
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if r.options.manifestPath != "" {
+		return manifest.writeFile(r.options.manifestPath)
+	}
+	return nil
+}
+
+// journal returns the journal of the recording (that of the interrupted
+// recording, when resuming), before any data records are written to the EDF
+// file with header hdr.
+func (r *recording) journal(hdr edf.Header, signalHeaders []edf.SignalHeader) *Journal {
+	if r.options.resume != nil {
+		return r.options.resume
+	}
+
+	journal := &Journal{StartTime: r.start, HeaderBytes: hdr.HeaderBytes, Pseudonym: r.options.pseudonym}
+	for _, signalHeader := range hdr.Signals {
+		journal.RecordSize += 2 * signalHeader.SamplesPerRecord
+	}
+	for i, signal := range r.signals {
+		journal.Signals = append(journal.Signals, JournalSignal{
+			Device:           r.signalDevices[i].String(),
+			ID:               signal.ID,
+			Label:            signalHeaders[i].Label,
+			SamplesPerRecord: signalHeaders[i].SamplesPerRecord,
+		})
+	}
+	for _, signalHeader := range signalHeaders[len(r.signals):] {
+		journal.Signals = append(journal.Signals, JournalSignal{
+			Label:            signalHeader.Label,
+			SamplesPerRecord: signalHeader.SamplesPerRecord,
+			Derived:          true,
+		})
+	}
+	return journal
+}
+
+// writeSidecar writes the sidecar (if enabled), which is updated as the
+// recording progresses.
+func (r *recording) writeSidecar() {
+	if r.options.sidecarPath == "" {
+		return
+	}
+
+	if err := r.sidecar.writeFile(r.options.sidecarPath); err != nil {
+		slog.Warn("Failed to write sidecar", slog.Any("error", err))
+	}
+}
+
+// assembler assembles the buffered signal values (and the annotations) of the
+// recording into data records, passing them to the record writer.
+type assembler struct {
+	r             *recording
+	rw            *recordWriter
+	signalHeaders []edf.SignalHeader
+	// The start time in the EDF header, and the onset of the next data record
+	// relative to it.
+	startTime time.Time
+	onset     time.Duration
+	// Annotations are written with the next data record, and are duplicated
+	// in every file, so each can be read on its own.
+	pendingAnnotations []edfplus.Annotation
+	// The last received value of each signal, for GapFillHoldLast.
+	lastValues []float64
+	// The dropouts to annotate, for GapFillInvalid.
+	dropouts dropouts
+	paused   pauses
+}
+
+func newAssembler(r *recording, rw *recordWriter, signalHeaders []edf.SignalHeader, startTime time.Time) *assembler {
+	return &assembler{
+		r:             r,
+		rw:            rw,
+		signalHeaders: signalHeaders,
+		startTime:     startTime,
+		onset:         r.recordStart.Sub(startTime),
+		lastValues:    make([]float64, len(r.signals)),
+		dropouts:      dropouts{minGap: r.options.recordDuration / 8},
+	}
+}
+
+// end returns the end of the next data record.
+func (a *assembler) end() time.Time {
+	return a.startTime.Add(a.onset + a.r.options.recordDuration)
+}
+
+// annotate adds an annotation to the next data record.
+func (a *assembler) annotate(annotation Annotation) {
+	slog.Debug("Recording annotation", slog.Time("time", annotation.Time), slog.String("text", annotation.Text))
+
+	a.pendingAnnotations = append(a.pendingAnnotations, edfplus.Annotation{
+		Onset:    annotation.Time.Sub(a.startTime),
+		Duration: annotation.Duration,
+		Text:     annotation.Text,
+	})
+}
+
+// annotateDropouts annotates each dropout of the signals.
+func (a *assembler) annotateDropouts(dropouts []dropout) {
+	for _, d := range dropouts {
+		a.annotate(Annotation{
+			Time:     a.startTime.Add(d.start),
+			Duration: d.end - d.start,
+			Text:     d.text(),
+		})
+	}
+}
+
+// pause pauses (or resumes) the recording at t, leaving the data records it
+// covers as gaps.
+func (a *assembler) pause(p bool, t time.Time) {
+	if !a.paused.set(p, t) {
+		return
+	}
+
+	if p {
+		slog.Info("Pausing recording")
+		a.annotate(Annotation{Time: t, Text: "Recording paused"})
+	} else {
+		slog.Info("Resuming recording")
+		a.annotate(Annotation{Time: t, Text: "Recording resumed"})
+	}
+}
+
+// writeRecord assembles the next data record from the signal buffers, and
+// queues it to be written. The final data record of the recording is written
+// even if no values have arrived for it, so its annotations are kept.
+func (a *assembler) writeRecord(final bool) error {
+	r := a.r
+	recordDuration := r.options.recordDuration
+	defer func() {
+		a.onset += recordDuration
+	}()
+
+	if a.paused.covers(a.startTime.Add(a.onset), a.end()) {
+		slog.Info("Recording paused, leaving a gap in the recording",
+			slog.Duration("onset", a.onset))
+		r.sidecar.addPause(a.onset, recordDuration)
+		r.pausedTime += recordDuration
+		a.annotateDropouts(a.dropouts.flush())
+
+		for i, buf := range r.signalBuffers {
+			buf.discard(a.signalHeaders[i].SamplesPerRecord)
+		}
+		return nil
+	}
+
+	// If no signal values have arrived (eg. every device has dropped out),
+	// leave a gap in the recording rather than writing an empty record.
+	if !final && !anySignalValues(r.signalBuffers, a.signalHeaders) {
+		slog.Warn("No signal values received, leaving a gap in the recording",
+			slog.Duration("onset", a.onset))
+		r.sidecar.addGap(a.onset, recordDuration)
+		a.annotateDropouts(a.dropouts.flush())
+
+		for i, buf := range r.signalBuffers {
+			buf.discard(a.signalHeaders[i].SamplesPerRecord)
+			r.stats[i].skipped(a.signalHeaders[i].SamplesPerRecord)
+		}
+		return nil
+	}
+
+	// Prepare a record to write to the EDF file. The buffers are reused, to
+	// avoid allocating on long recordings with many signals.
+	buffers, err := a.rw.buffers()
+	if err != nil {
+		return err
+	}
+	record, received := buffers.values, buffers.received
+
+	for i, buf := range r.signalBuffers {
+		buf.take(a.signalHeaders[i].SamplesPerRecord, record[i], received[i])
+
+		// The final data record is padded beyond the end of the recording.
+		counted := received[i]
+		if final {
+			n := math.Ceil(r.endTime.Sub(a.startTime.Add(a.onset)).Seconds() * float64(r.signals[i].SampleRate))
+			counted = counted[:min(len(counted), max(0, int(n)))]
+		}
+		r.stats[i].written(counted)
+
+		if missing := countMissing(received[i]); missing > 0 {
+			slog.Warn("Missing signal values",
+				slog.String("signal", r.signals[i].Name),
+				slog.Int("missing", missing))
+
+			if r.options.gapFill == GapFillInvalid {
+				// The final data record is padded beyond the end of the
+				// recording, which isn't a dropout.
+				end := a.onset + recordDuration
+				if final {
+					end = min(end, r.endTime.Sub(a.startTime))
+				}
+
+				n := time.Duration(len(received[i]))
+				for _, run := range missingRuns(received[i]) {
+					runStart := a.onset + recordDuration*time.Duration(run[0])/n
+					if runStart < end {
+						a.dropouts.add(r.signals[i].Name, runStart, min(end, a.onset+recordDuration*time.Duration(run[1])/n))
+					}
+				}
+			}
+		}
+
+		r.options.gapFill.fill(record[i], received[i], a.signalHeaders[i].PhysicalMin, &a.lastValues[i])
+	}
+
+	if r.options.gapFill == GapFillInvalid {
+		a.annotateDropouts(a.dropouts.finish(a.onset+recordDuration, final))
+	}
+
+	for j := range r.derived {
+		i := len(r.signals) + j
+		r.derived[j].compute(record[i], received[i], record, received)
+	}
+
+	for k, signalIndex := range r.leadOffSignals {
+		i := len(r.signals) + len(r.derived) + k
+		r.leadOffTimelines[signalIndex].sample(record[i], a.startTime.Add(a.onset), leadOffSampleRate)
+		for j := range received[i] {
+			received[i][j] = true
+		}
+	}
+
+	slog.Info("Writing record to EDF file",
+		slog.Int("signals", len(record)),
+		slog.Duration("duration", recordDuration))
+
+	a.rw.enqueue(a.onset, a.pendingAnnotations, buffers)
+	a.pendingAnnotations = nil
+	r.sidecar.DataRecords++
+
+	return nil
+}
+
+// stop flushes any remaining signal values, and marks the end of the
+// recording.
+func (a *assembler) stop() error {
+	r := a.r
+	r.endTime = time.Now()
+	a.annotate(Annotation{Time: r.endTime, Text: "Recording stopped"})
+
+	// The oxygen desaturation index of each oximetry signal is reported at the
+	// end of the study.
+	for _, detector := range r.desaturationDetectors {
+		if detector == nil {
+			continue
+		}
+
+		summary := detector.summary()
+		slog.Info("Oxygen desaturation index",
+			slog.String("signal", summary.Signal),
+			slog.Duration("duration", summary.Duration),
+			slog.Int("desaturations3", summary.Desaturations3),
+			slog.Int("desaturations4", summary.Desaturations4),
+			slog.Float64("odi3", summary.ODI3()),
+			slog.Float64("odi4", summary.ODI4()))
+		a.annotate(Annotation{Time: r.endTime, Text: fmt.Sprintf("ODI3 %.1f/h, ODI4 %.1f/h: %s",
+			summary.ODI3(), summary.ODI4(), summary.Signal)})
+
+		r.sidecar.Oximetry = append(r.sidecar.Oximetry, SidecarOximetry{
+			Label:          summary.Signal,
+			Duration:       summary.Duration.Seconds(),
+			Desaturations3: summary.Desaturations3,
+			Desaturations4: summary.Desaturations4,
+			ODI3:           summary.ODI3(),
+			ODI4:           summary.ODI4(),
+		})
+	}
+
+	// Up to two data records are pending, as each is written half a record
+	// after it ends, so records are written up to the one the recording
+	// stopped in.
+	var err error
+	for {
+		final := !a.end().Before(r.endTime)
+		if err = a.writeRecord(final); err != nil || final {
+			break
+		}
+	}
+	if closeErr := a.rw.close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	r.sidecar.EndTime = &r.endTime
+	r.writeSidecar()
+
+	return err
+}
+
+// anySignalValues returns true if any values have been received for the next
+// data record.
+func anySignalValues(signalBuffers []*signalBuffer, signalHeaders []edf.SignalHeader) bool {
+	for i, buf := range signalBuffers {
+		if buf.receivedCount(signalHeaders[i].SamplesPerRecord) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// countMissing returns the number of values that were not received.
+func countMissing(received []bool) int {
+	var missing int
+	for _, ok := range received {
+		if !ok {
+			missing++
+		}
+	}
+	return missing
+}
+
+// equipment returns the equipment subfield of the recording identification,
+// listing the serial number and firmware version of each identified device
+// (eg. "OpenPSG,0A1B2C/0.1.0"). It is truncated to fit the header, the sidecar
+// has the full details.
+func equipment(devices []SidecarDevice) string {
+	parts := []string{"OpenPSG"}
+	for _, device := range devices {
+		if device.SerialNumber == "" {
+			continue
+		}
+
+		part := device.SerialNumber
+		if device.Firmware != "" {
+			part += "/" + device.Firmware
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
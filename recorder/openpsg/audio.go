@@ -0,0 +1,142 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/wav"
+)
+
+// DefaultAudioSampleRate is the default sample rate of audio recordings,
+// enough for snoring.
+const DefaultAudioSampleRate = 16000
+
+// AudioCapture configures the recording of audio (eg. from a snore
+// microphone) to a WAV file alongside the EDF file.
+type AudioCapture struct {
+	// The ALSA capture device (eg. "default" or "hw:1,0").
+	Device string
+	// The sample rate in Hertz (DefaultAudioSampleRate if zero).
+	SampleRate uint32
+	// The path of the WAV file.
+	Path string
+}
+
+// WithAudio records audio from an ALSA capture device to a (mono, 16-bit) WAV
+// file alongside the recording, using arecord. The time of the first audio
+// sample is annotated in the EDF file (and recorded in the sidecar) so the
+// audio can be aligned with the signals.
+func WithAudio(a AudioCapture) RecordOption {
+	return func(o *recordOptions) {
+		if a.SampleRate == 0 {
+			a.SampleRate = DefaultAudioSampleRate
+		}
+		o.audio = &a
+	}
+}
+
+// captureAudio records audio until the context is cancelled, calling started
+// with the (estimated) time of the first sample.
+func captureAudio(ctx context.Context, a AudioCapture, started func(time.Time)) error {
+	f, err := os.Create(a.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create audio file: %w", err)
+	}
+	defer f.Close()
+
+	w, err := wav.NewWriter(f, a.SampleRate, 1)
+	if err != nil {
+		return fmt.Errorf("failed to create audio file: %w", err)
+	}
+
+	device := a.Device
+	if device == "" {
+		device = "default"
+	}
+
+	cmd := exec.CommandContext(ctx, "arecord", "-q", "-D", device,
+		"-f", "S16_LE", "-c", "1", "-r", strconv.FormatUint(uint64(a.SampleRate), 10), "-t", "raw")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to capture audio: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to capture audio: %w", err)
+	}
+
+	// Audio is read in 100ms chunks.
+	buf := make([]byte, 2*a.SampleRate/10)
+	var startTime time.Time
+	var readErr error
+	for {
+		n, err := stdout.Read(buf)
+		if n > 0 {
+			if startTime.IsZero() {
+				// The first chunk was captured before it was read.
+				startTime = time.Now().Add(-time.Duration(float64(n/2) / float64(a.SampleRate) * float64(time.Second)))
+				started(startTime)
+			}
+
+			if _, err := w.Write(buf[:n]); err != nil {
+				readErr = fmt.Errorf("failed to write audio: %w", err)
+				break
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	waitErr := cmd.Wait()
+
+	if !startTime.IsZero() {
+		elapsed := time.Since(startTime)
+		slog.Info("Stopped audio capture",
+			slog.String("path", a.Path),
+			slog.Int64("samples", w.Samples()),
+			slog.Float64("effectiveRate", float64(w.Samples())/elapsed.Seconds()))
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize audio file: %w", err)
+	}
+
+	if readErr != nil {
+		return fmt.Errorf("failed to capture audio: %w", readErr)
+	}
+
+	// arecord is killed when the recording is stopped.
+	if waitErr != nil && ctx.Err() == nil {
+		return fmt.Errorf("audio capture stopped: %w", waitErr)
+	}
+
+	return nil
+}
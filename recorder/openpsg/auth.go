@@ -0,0 +1,84 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The length of the random challenges sent to devices.
+const challengeSize = 32
+
+// ErrUnauthorizedDevice is returned when connecting to a device that fails to
+// prove it holds the pre-shared device key (see WithDeviceKey).
+var ErrUnauthorizedDevice = errors.New("unauthorized device")
+
+// WithDeviceKey only connects to devices holding the pre-shared key, so rogue
+// devices on the network can't be recorded from. On connecting, the device is
+// sent a random challenge, and has to respond with its HMAC-SHA256 under the
+// key.
+func WithDeviceKey(key []byte) ConnectOption {
+	return func(o *connectOptions) {
+		o.deviceKey = key
+	}
+}
+
+// challengeResponse is the response to an openpsg.authenticate request.
+type challengeResponse struct {
+	// The hex encoded HMAC-SHA256 of the challenge under the device key.
+	Response string `json:"response"`
+}
+
+// authenticate challenges the device to prove it holds the device key.
+func authenticate(ctx context.Context, rpcConn *jsonrpc2.Conn, key []byte) error {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	var resp challengeResponse
+	params := map[string]string{"challenge": hex.EncodeToString(challenge)}
+	if err := rpcConn.Call(ctx, "openpsg.authenticate", params, &resp); err != nil {
+		if isMethodNotFound(err) {
+			return fmt.Errorf("%w: the device doesn't support authentication", ErrUnauthorizedDevice)
+		}
+		return fmt.Errorf("failed to authenticate device: %w", err)
+	}
+
+	response, err := hex.DecodeString(resp.Response)
+	if err != nil {
+		return fmt.Errorf("%w: invalid response: %w", ErrUnauthorizedDevice, err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(challenge)
+	if !hmac.Equal(response, mac.Sum(nil)) {
+		return fmt.Errorf("%w: incorrect response to challenge", ErrUnauthorizedDevice)
+	}
+
+	return nil
+}
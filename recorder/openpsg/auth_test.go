@@ -0,0 +1,225 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDevice is a device on the network, answering the handshake of the
+// recorder.
+type fakeDevice struct {
+	// Answers an authentication challenge, nil if authentication isn't
+	// supported.
+	respond func(challenge []byte) string
+
+	mu sync.Mutex
+	// The methods called by the recorder.
+	methods []string
+	// Closed once the recorder disconnects.
+	disconnected chan struct{}
+}
+
+// listen accepts a connection from the recorder, returning the address of the
+// device.
+func (d *fakeDevice) listen(t *testing.T) netip.AddrPort {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	d.disconnected = make(chan struct{})
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		rpcConn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}),
+			jsonrpc2.HandlerWithError(d.handle))
+		<-rpcConn.DisconnectNotify()
+		close(d.disconnected)
+	}()
+
+	return netip.MustParseAddrPort(l.Addr().String())
+}
+
+func (d *fakeDevice) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, error) {
+	d.mu.Lock()
+	d.methods = append(d.methods, req.Method)
+	d.mu.Unlock()
+
+	switch {
+	case req.Method == "openpsg.version":
+		return openpsg.DeviceVersion{Version: openpsg.ProtocolVersion}, nil
+	case req.Method == "openpsg.authenticate" && d.respond != nil:
+		var params struct {
+			Challenge string `json:"challenge"`
+		}
+		if err := json.Unmarshal(*req.Params, &params); err != nil {
+			return nil, err
+		}
+		challenge, err := hex.DecodeString(params.Challenge)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"response": d.respond(challenge)}, nil
+	default:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: "method not found"}
+	}
+}
+
+// calls returns the methods called by the recorder.
+func (d *fakeDevice) calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.methods...)
+}
+
+// hmacResponse returns the response to a challenge under key.
+func hmacResponse(key, challenge []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(challenge)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	key := []byte("device key")
+
+	// A response captured from an earlier connection.
+	captured := hmacResponse(key, make([]byte, 32))
+
+	tests := []struct {
+		name             string
+		respond          func(challenge []byte) string
+		wantUnauthorized bool
+	}{
+		{
+			name: "Correct key",
+			respond: func(challenge []byte) string {
+				return hmacResponse(key, challenge)
+			},
+		},
+		{
+			name: "Wrong key",
+			respond: func(challenge []byte) string {
+				return hmacResponse([]byte("another key"), challenge)
+			},
+			wantUnauthorized: true,
+		},
+		{
+			name: "Missing key",
+			respond: func(challenge []byte) string {
+				return ""
+			},
+			wantUnauthorized: true,
+		},
+		{
+			name: "Replayed response",
+			respond: func(challenge []byte) string {
+				return captured
+			},
+			wantUnauthorized: true,
+		},
+		{
+			name: "Invalid response",
+			respond: func(challenge []byte) string {
+				return "not hex"
+			},
+			wantUnauthorized: true,
+		},
+		{
+			name:             "Authentication unsupported",
+			wantUnauthorized: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := &fakeDevice{respond: tt.respond}
+			addr := device.listen(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			client, err := openpsg.Connect(ctx, addr, openpsg.WithDeviceKey(key))
+			if !tt.wantUnauthorized {
+				require.NoError(t, err)
+				require.NoError(t, client.Close())
+				return
+			}
+			require.ErrorIs(t, err, openpsg.ErrUnauthorizedDevice)
+			assert.Nil(t, client)
+
+			// The connection is closed without any further requests.
+			select {
+			case <-device.disconnected:
+			case <-ctx.Done():
+				t.Fatal("the connection to the device wasn't closed")
+			}
+			assert.Equal(t, []string{"openpsg.version", "openpsg.authenticate"}, device.calls())
+		})
+	}
+}
+
+func TestAuthenticateFreshChallenges(t *testing.T) {
+	key := []byte("device key")
+
+	// Each connection is sent a new challenge, so responses can't be replayed.
+	var mu sync.Mutex
+	var challenges []string
+	for range 2 {
+		device := &fakeDevice{respond: func(challenge []byte) string {
+			mu.Lock()
+			defer mu.Unlock()
+
+			challenges = append(challenges, hex.EncodeToString(challenge))
+			return hmacResponse(key, challenge)
+		}}
+		addr := device.listen(t)
+
+		client, err := openpsg.Connect(context.Background(), addr, openpsg.WithDeviceKey(key))
+		require.NoError(t, err)
+		require.NoError(t, client.Close())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, challenges, 2)
+	assert.NotEqual(t, challenges[0], challenges[1])
+}
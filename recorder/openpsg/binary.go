@@ -0,0 +1,162 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// decodeBinaryValues decodes a frame of signal values encoded in CBOR: an array
+// of the signal ID, the sequence number, the timestamp (in microseconds since
+// the Unix epoch) and a byte string of the values, packed little-endian in the
+// sample format of the signal (looked up with format).
+func decodeBinaryValues(frame []byte, format func(id uint32) (SampleFormat, bool)) (SignalValues, error) {
+	major, n, frame, err := cborHead(frame)
+	if err != nil {
+		return SignalValues{}, err
+	}
+	if major != cborArray || n != 4 {
+		return SignalValues{}, errors.New("expected an array of 4 items")
+	}
+
+	var fields [3]uint64
+	for i := range fields {
+		major, fields[i], frame, err = cborHead(frame)
+		if err != nil {
+			return SignalValues{}, err
+		}
+		if major != cborUnsigned {
+			return SignalValues{}, fmt.Errorf("expected an unsigned integer (item %d)", i)
+		}
+	}
+
+	major, n, frame, err = cborHead(frame)
+	if err != nil {
+		return SignalValues{}, err
+	}
+	if major != cborBytes || uint64(len(frame)) != n {
+		return SignalValues{}, errors.New("expected a byte string of values")
+	}
+
+	if fields[0] > math.MaxUint32 {
+		return SignalValues{}, fmt.Errorf("invalid signal id: %d", fields[0])
+	}
+	id := uint32(fields[0])
+
+	sampleFormat, ok := format(id)
+	if !ok {
+		return SignalValues{}, fmt.Errorf("unknown signal: %d", id)
+	}
+
+	values, err := unpackValues(frame, sampleFormat)
+	if err != nil {
+		return SignalValues{}, err
+	}
+
+	seq := fields[1]
+	return SignalValues{
+		ID:        id,
+		Seq:       &seq,
+		Timestamp: time.UnixMicro(int64(fields[2])).UTC(),
+		Values:    values,
+	}, nil
+}
+
+// unpackValues unpacks values packed little-endian in a sample format.
+func unpackValues(b []byte, format SampleFormat) ([]float64, error) {
+	var size int
+	switch format {
+	case SampleFormatInt16:
+		size = 2
+	case SampleFormatInt24:
+		size = 3
+	case SampleFormatInt32, SampleFormatFloat32:
+		size = 4
+	default:
+		return nil, fmt.Errorf("unsupported sample format: %s", format)
+	}
+	if len(b)%size != 0 {
+		return nil, fmt.Errorf("%d bytes is not a whole number of %s values", len(b), format)
+	}
+
+	values := make([]float64, 0, len(b)/size)
+	for ; len(b) > 0; b = b[size:] {
+		var value float64
+		switch format {
+		case SampleFormatInt16:
+			value = float64(int16(binary.LittleEndian.Uint16(b)))
+		case SampleFormatInt24:
+			// Sign extend from 24 bits.
+			value = float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8)
+		case SampleFormatInt32:
+			value = float64(int32(binary.LittleEndian.Uint32(b)))
+		case SampleFormatFloat32:
+			value = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// CBOR major types.
+const (
+	cborUnsigned byte = 0
+	cborBytes    byte = 2
+	cborArray    byte = 4
+)
+
+// cborHead decodes the head of a CBOR data item: its major type and argument
+// (the value of an integer, or the length of a string or array).
+func cborHead(b []byte) (major byte, arg uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, io.ErrUnexpectedEOF
+	}
+
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported CBOR additional information: %d", info)
+	}
+
+	if len(b) < size {
+		return 0, 0, nil, io.ErrUnexpectedEOF
+	}
+	for _, c := range b[:size] {
+		arg = arg<<8 | uint64(c)
+	}
+	return major, arg, b[size:], nil
+}
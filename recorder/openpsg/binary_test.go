@@ -0,0 +1,204 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  openpsg.SampleFormat
+		data    []byte
+		want    []float64
+		wantErr bool
+	}{
+		{
+			name:   "Int16",
+			format: openpsg.SampleFormatInt16,
+			data:   []byte{0x01, 0x00, 0xff, 0xff, 0x00, 0x80, 0xff, 0x7f},
+			want:   []float64{1, -1, math.MinInt16, math.MaxInt16},
+		},
+		{
+			name:   "Int24",
+			format: openpsg.SampleFormatInt24,
+			data:   []byte{0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0x00, 0x00, 0x80, 0xff, 0xff, 0x7f, 0x34, 0x12, 0xfe},
+			want:   []float64{1, -1, -1 << 23, 1<<23 - 1, -0x01edcc},
+		},
+		{
+			name:   "Int32",
+			format: openpsg.SampleFormatInt32,
+			data:   []byte{0x01, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x80},
+			want:   []float64{1, -1, math.MinInt32},
+		},
+		{
+			name:   "Float32",
+			format: openpsg.SampleFormatFloat32,
+			data:   []byte{0x00, 0x00, 0xc0, 0x3f, 0x00, 0x00, 0x20, 0xc1},
+			want:   []float64{1.5, -10},
+		},
+		{
+			name:   "Empty",
+			format: openpsg.SampleFormatInt16,
+			want:   []float64{},
+		},
+		{
+			name:    "Partial value",
+			format:  openpsg.SampleFormatInt24,
+			data:    []byte{0x01, 0x00, 0x00, 0x01},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported format",
+			format:  "int8",
+			data:    []byte{0x01},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := openpsg.UnpackValues(tt.data, tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
+
+func TestDecodeBinaryValues(t *testing.T) {
+	format := func(id uint32) (openpsg.SampleFormat, bool) {
+		switch id {
+		case 1:
+			return openpsg.SampleFormatInt16, true
+		case 2:
+			return openpsg.SampleFormatInt24, true
+		default:
+			return "", false
+		}
+	}
+
+	// 2025-01-01T22:30:00.000001Z in microseconds since the Unix epoch.
+	timestamp := []byte{0x1b, 0x00, 0x06, 0x2a, 0xac, 0x96, 0x06, 0x4a, 0x01}
+
+	tests := []struct {
+		name    string
+		frame   []byte
+		want    openpsg.SignalValues
+		wantErr error
+	}{
+		{
+			name:  "Int16",
+			frame: concat([]byte{0x84, 0x01, 0x19, 0x01, 0x2c}, timestamp, []byte{0x44, 0x01, 0x00, 0xfe, 0xff}),
+			want: openpsg.SignalValues{
+				ID:        1,
+				Seq:       ptr(uint64(300)),
+				Timestamp: time.Date(2025, 1, 1, 22, 30, 0, 1000, time.UTC),
+				Values:    []float64{1, -2},
+			},
+		},
+		{
+			name:  "Int24",
+			frame: concat([]byte{0x84, 0x02, 0x1a, 0xff, 0xff, 0xff, 0xff}, timestamp, []byte{0x46, 0xff, 0xff, 0xff, 0x00, 0x00, 0x80}),
+			want: openpsg.SignalValues{
+				ID:        2,
+				Seq:       ptr(uint64(math.MaxUint32)),
+				Timestamp: time.Date(2025, 1, 1, 22, 30, 0, 1000, time.UTC),
+				Values:    []float64{-1, -1 << 23},
+			},
+		},
+		{
+			name:    "Not an array",
+			frame:   []byte{0x01},
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Too few items",
+			frame:   concat([]byte{0x83, 0x01, 0x00}, timestamp),
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Negative sequence number",
+			frame:   concat([]byte{0x84, 0x01, 0x20}, timestamp, []byte{0x40}),
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Unknown signal",
+			frame:   concat([]byte{0x84, 0x03, 0x00}, timestamp, []byte{0x40}),
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Truncated values",
+			frame:   concat([]byte{0x84, 0x01, 0x00}, timestamp, []byte{0x44, 0x01, 0x00}),
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Partial value",
+			frame:   concat([]byte{0x84, 0x01, 0x00}, timestamp, []byte{0x43, 0x01, 0x00, 0x02}),
+			wantErr: assert.AnError,
+		},
+		{
+			name:    "Truncated",
+			frame:   []byte{0x84, 0x01, 0x19, 0x01},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := openpsg.DecodeBinaryValues(tt.frame, format)
+			switch tt.wantErr {
+			case nil:
+				require.NoError(t, err)
+			case assert.AnError:
+				assert.Error(t, err)
+				return
+			default:
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
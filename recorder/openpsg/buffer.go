@@ -0,0 +1,226 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// signalBuffer places the values of a signal at the sample offsets given by
+// their timestamps, so values that arrive late or out of order (within the
+// capacity of the buffer) still end up in the right place in the recording.
+type signalBuffer struct {
+	mu         sync.Mutex
+	start      time.Time
+	sampleRate float64
+	// The sample index (relative to start) of the next slot to be taken.
+	next     int64
+	values   []float64
+	received []bool
+	// Values beyond the capacity of the buffer (nil if not spilling).
+	spill *spillFile
+}
+
+// newSignalBuffer creates a buffer holding capacity values of a signal,
+// starting at the specified time.
+func newSignalBuffer(start time.Time, sampleRate float64, capacity int) *signalBuffer {
+	return &signalBuffer{
+		start:      start,
+		sampleRate: sampleRate,
+		values:     make([]float64, capacity),
+		received:   make([]bool, capacity),
+	}
+}
+
+// put stores values starting at the specified timestamp. It returns the number
+// of values that were dropped as they were either too late (their slots have
+// already been taken) or too early (beyond the capacity of the buffer, and
+// unable to be spilled).
+func (b *signalBuffer) put(timestamp time.Time, values []float64) (dropped int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	index := int64(math.Round(timestamp.Sub(b.start).Seconds() * b.sampleRate))
+	capacity := int64(len(b.values))
+
+	// Values whose slots have already been taken.
+	late := int(min(max(b.next-index, 0), int64(len(values))))
+	dropped += late
+	values = values[late:]
+	index += int64(late)
+
+	// Values that fit in the buffer are copied in at most two contiguous runs,
+	// even while earlier values are spilled, as the spill file only holds
+	// values beyond the capacity of the buffer.
+	fit := int(min(max(b.next+capacity-index, 0), int64(len(values))))
+
+	for copied := 0; copied < fit; {
+		slot := int((index + int64(copied)) % capacity)
+		n := copy(b.values[slot:], values[copied:fit])
+		for i := slot; i < slot+n; i++ {
+			b.received[i] = true
+		}
+		copied += n
+	}
+
+	for i, value := range values[fit:] {
+		if !b.spillValue(index+int64(fit+i), value) {
+			dropped++
+		}
+	}
+
+	return dropped
+}
+
+// take removes the next n slots from the buffer, copying their values and
+// whether each value was received (missing values are zero) into the slices,
+// which may be nil to discard them.
+func (b *signalBuffer) take(n int, values []float64, received []bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for taken := 0; taken < n; {
+		// Slots are taken in (at most) two contiguous runs.
+		slot := int(b.next % int64(len(b.values)))
+		run := min(n-taken, len(b.values)-slot)
+
+		if values != nil {
+			copy(values[taken:], b.values[slot:slot+run])
+		}
+		if received != nil {
+			copy(received[taken:], b.received[slot:slot+run])
+		}
+		clear(b.values[slot : slot+run])
+		clear(b.received[slot : slot+run])
+
+		b.next += int64(run)
+		taken += run
+	}
+
+	b.unspill()
+}
+
+// discard removes the next n slots from the buffer.
+func (b *signalBuffer) discard(n int) {
+	b.take(n, nil, nil)
+}
+
+// spillValue writes a value to the spill file, returning false if it could not
+// be spilled.
+func (b *signalBuffer) spillValue(index int64, value float64) bool {
+	if b.spill == nil {
+		return false
+	}
+
+	ok, err := b.spill.push(index, value)
+	if err != nil {
+		b.spillFailed(err)
+		return false
+	}
+
+	return ok
+}
+
+// unspill moves spilled values back into the buffer, as far as there is room.
+func (b *signalBuffer) unspill() {
+	for b.spill != nil && b.spill.len() > 0 {
+		index, value, err := b.spill.peek()
+		if err != nil {
+			b.spillFailed(err)
+			return
+		}
+
+		if index >= b.next+int64(len(b.values)) {
+			return
+		}
+
+		// Values that are now too late are discarded.
+		if index >= b.next {
+			slot := index % int64(len(b.values))
+			b.values[slot] = value
+			b.received[slot] = true
+		}
+
+		if err := b.spill.pop(); err != nil {
+			b.spillFailed(err)
+			return
+		}
+	}
+}
+
+// spillFailed stops spilling after an error, discarding any spilled values.
+func (b *signalBuffer) spillFailed(err error) {
+	slog.Warn("Failed to spill signal values, discarding overflow", slog.Any("error", err))
+
+	_ = b.spill.Close()
+	b.spill = nil
+}
+
+// Close removes the spill file (if any).
+func (b *signalBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.spill == nil {
+		return nil
+	}
+
+	err := b.spill.Close()
+	b.spill = nil
+	return err
+}
+
+// fill returns the fraction of the buffer holding received values, and the
+// number of values spilled.
+func (b *signalBuffer) fill() (float64, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var received int
+	for _, ok := range b.received {
+		if ok {
+			received++
+		}
+	}
+
+	var spilled int
+	if b.spill != nil {
+		spilled = b.spill.len()
+	}
+
+	return float64(received) / float64(len(b.received)), spilled
+}
+
+// receivedCount returns the number of values received for the next n slots.
+func (b *signalBuffer) receivedCount(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var count int
+	for i := int64(0); i < int64(n); i++ {
+		if b.received[(b.next+i)%int64(len(b.values))] {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,198 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalBuffer(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+	// At 10 Hz, each sample is 100 ms.
+	at := func(index int) time.Time {
+		return start.Add(time.Duration(index) * 100 * time.Millisecond)
+	}
+
+	type put struct {
+		index  int
+		values []float64
+	}
+
+	tests := []struct {
+		name string
+		// The slots taken before the values are put.
+		taken        int
+		puts         []put
+		dropped      int
+		wantValues   []float64
+		wantReceived []bool
+	}{
+		{
+			name:         "In order",
+			puts:         []put{{0, []float64{1, 2, 3}}},
+			wantValues:   []float64{1, 2, 3, 0},
+			wantReceived: []bool{true, true, true, false},
+		},
+		{
+			name:         "Out of order",
+			puts:         []put{{3, []float64{4, 5}}, {0, []float64{1, 2, 3}}},
+			wantValues:   []float64{1, 2, 3, 4, 5},
+			wantReceived: []bool{true, true, true, true, true},
+		},
+		{
+			name:         "Late",
+			taken:        2,
+			puts:         []put{{0, []float64{1, 2, 3, 4}}},
+			dropped:      2,
+			wantValues:   []float64{3, 4, 0},
+			wantReceived: []bool{true, true, false},
+		},
+		{
+			name:         "Entirely late",
+			taken:        4,
+			puts:         []put{{0, []float64{1, 2}}},
+			dropped:      2,
+			wantValues:   []float64{0},
+			wantReceived: []bool{false},
+		},
+		{
+			name:         "Beyond capacity",
+			puts:         []put{{6, []float64{1, 2, 3, 4}}},
+			dropped:      2,
+			wantValues:   []float64{0, 0, 0, 0, 0, 0, 1, 2},
+			wantReceived: []bool{false, false, false, false, false, false, true, true},
+		},
+		{
+			name:         "Wraparound",
+			taken:        6,
+			puts:         []put{{6, []float64{1, 2, 3, 4}}},
+			wantValues:   []float64{1, 2, 3, 4},
+			wantReceived: []bool{true, true, true, true},
+		},
+		{
+			name:         "Gap",
+			puts:         []put{{0, []float64{1}}, {2, []float64{3}}},
+			wantValues:   []float64{1, 0, 3},
+			wantReceived: []bool{true, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := openpsg.NewSignalBuffer(start, 10, 8)
+			buf.Take(tt.taken)
+
+			var dropped int
+			for _, p := range tt.puts {
+				dropped += buf.Put(at(p.index), p.values)
+			}
+			assert.Equal(t, tt.dropped, dropped)
+
+			values, received := buf.Take(len(tt.wantValues))
+			assert.Equal(t, tt.wantValues, values)
+			assert.Equal(t, tt.wantReceived, received)
+		})
+	}
+}
+
+func TestSignalBufferSpill(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+
+	buf := openpsg.NewSignalBuffer(start, 10, 4)
+	t.Cleanup(func() {
+		require.NoError(t, buf.Close())
+	})
+	require.NoError(t, buf.Spill(dir))
+
+	// Values beyond the capacity of the buffer are spilled rather than dropped.
+	assert.Zero(t, buf.Put(start, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+
+	fill, spilled := buf.Fill()
+	assert.Equal(t, 1.0, fill)
+	assert.Equal(t, 6, spilled)
+
+	// Later values beyond the capacity of the buffer are spilled after them.
+	assert.Zero(t, buf.Put(start.Add(time.Second), []float64{11}))
+	_, spilled = buf.Fill()
+	assert.Equal(t, 7, spilled)
+
+	// Taking values moves spilled values back into the buffer.
+	values, _ := buf.Take(4)
+	assert.Equal(t, []float64{1, 2, 3, 4}, values)
+
+	_, spilled = buf.Fill()
+	assert.Equal(t, 3, spilled)
+
+	values, _ = buf.Take(4)
+	assert.Equal(t, []float64{5, 6, 7, 8}, values)
+
+	values, received := buf.Take(4)
+	assert.Equal(t, []float64{9, 10, 11, 0}, values)
+	assert.Equal(t, []bool{true, true, true, false}, received)
+
+	_, spilled = buf.Fill()
+	assert.Zero(t, spilled)
+}
+
+func TestSignalBufferSpillOutOfOrder(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+	// At 10 Hz, each sample is 100 ms.
+	at := func(index int) time.Time {
+		return start.Add(time.Duration(index) * 100 * time.Millisecond)
+	}
+
+	dir := t.TempDir()
+
+	buf := openpsg.NewSignalBuffer(start, 10, 4)
+	t.Cleanup(func() {
+		require.NoError(t, buf.Close())
+	})
+	require.NoError(t, buf.Spill(dir))
+
+	// Values beyond the capacity of the buffer arrive first and are spilled.
+	assert.Zero(t, buf.Put(at(2), []float64{3, 4, 5, 6, 7}))
+
+	_, spilled := buf.Fill()
+	assert.Equal(t, 3, spilled)
+
+	// A late value whose slot is still in the buffer is stored rather than
+	// being queued behind the spilled values.
+	assert.Zero(t, buf.Put(at(0), []float64{1, 2}))
+
+	_, spilled = buf.Fill()
+	assert.Equal(t, 3, spilled)
+
+	values, received := buf.Take(4)
+	assert.Equal(t, []float64{1, 2, 3, 4}, values)
+	assert.Equal(t, []bool{true, true, true, true}, received)
+
+	values, received = buf.Take(4)
+	assert.Equal(t, []float64{5, 6, 7, 0}, values)
+	assert.Equal(t, []bool{true, true, true, false}, received)
+
+	_, spilled = buf.Fill()
+	assert.Zero(t, spilled)
+}
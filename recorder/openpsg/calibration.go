@@ -0,0 +1,149 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Calibration maps device signals to the calibration applied to their values,
+// correcting the gain and offset of individual channels.
+type Calibration struct {
+	Channels []ChannelCalibration `json:"channels" yaml:"channels"`
+}
+
+// ChannelCalibration is the calibration of a single device signal, given either
+// as a gain and offset, or as two calibration points.
+type ChannelCalibration struct {
+	Device netip.Addr `json:"device" yaml:"device"`
+	// The identifier of the signal on the device.
+	Signal uint32 `json:"signal" yaml:"signal"`
+	// The calibrated value is gain * value + offset.
+	Gain   float64 `json:"gain,omitempty" yaml:"gain,omitempty"`
+	Offset float64 `json:"offset,omitempty" yaml:"offset,omitempty"`
+	// Two (measured, actual) pairs of physical values, from which the gain and
+	// offset are derived.
+	Points []CalibrationPoint `json:"points,omitempty" yaml:"points,omitempty"`
+}
+
+// CalibrationPoint is a physical value measured by a device, and the value it
+// should have been.
+type CalibrationPoint struct {
+	Measured float64 `json:"measured" yaml:"measured"`
+	Actual   float64 `json:"actual" yaml:"actual"`
+}
+
+// LoadCalibration reads a calibration from the file at path. Files with a
+// .yaml or .yml extension are parsed as YAML, others as JSON.
+func LoadCalibration(path string) (*Calibration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calibration: %w", err)
+	}
+
+	var c Calibration
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &c)
+	default:
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calibration: %w", err)
+	}
+
+	for _, channel := range c.Channels {
+		if !channel.Device.IsValid() {
+			return nil, fmt.Errorf("calibration channel is missing a device address")
+		}
+
+		if len(channel.Points) > 0 && (len(channel.Points) != 2 || channel.Points[0].Measured == channel.Points[1].Measured) {
+			return nil, fmt.Errorf("calibration of signal %d on device %s needs two points with different measured values",
+				channel.Signal, channel.Device)
+		}
+
+		if gain, _ := channel.coefficients(); gain <= 0 {
+			return nil, fmt.Errorf("calibration of signal %d on device %s has a non-positive gain",
+				channel.Signal, channel.Device)
+		}
+	}
+
+	return &c, nil
+}
+
+// WithCalibration applies the calibration of each channel to its values before
+// they are stored. The applied calibration is recorded in the sidecar and the
+// prefiltering field of the EDF signal header.
+func WithCalibration(c *Calibration) RecordOption {
+	return func(o *recordOptions) {
+		o.calibration = c
+	}
+}
+
+// lookup returns the calibration of the signal with the specified id on the
+// device at addr, or nil if it isn't calibrated.
+func (c *Calibration) lookup(addr netip.Addr, id uint32) *ChannelCalibration {
+	if c == nil {
+		return nil
+	}
+
+	for i, channel := range c.Channels {
+		if channel.Device == addr && channel.Signal == id {
+			return &c.Channels[i]
+		}
+	}
+
+	return nil
+}
+
+// coefficients returns the gain and offset of the calibration.
+func (c *ChannelCalibration) coefficients() (gain, offset float64) {
+	if len(c.Points) == 2 {
+		p0, p1 := c.Points[0], c.Points[1]
+		gain = (p1.Actual - p0.Actual) / (p1.Measured - p0.Measured)
+		return gain, p0.Actual - gain*p0.Measured
+	}
+
+	return c.Gain, c.Offset
+}
+
+// apply returns the calibrated value (the value itself if c is nil).
+func (c *ChannelCalibration) apply(value float64) float64 {
+	if c == nil {
+		return value
+	}
+
+	gain, offset := c.coefficients()
+	return gain*value + offset
+}
+
+// String describes the calibration for the EDF prefiltering field.
+func (c *ChannelCalibration) String() string {
+	gain, offset := c.coefficients()
+	return "CAL:gain=" + strconv.FormatFloat(gain, 'g', 6, 64) +
+		",offset=" + strconv.FormatFloat(offset, 'g', 6, 64)
+}
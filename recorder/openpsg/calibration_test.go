@@ -0,0 +1,166 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCalibration(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "JSON",
+			file: "calibration.json",
+			data: `{"channels": [{"device": "10.0.0.12", "signal": 1, "gain": 2, "offset": 1}]}`,
+		},
+		{
+			name: "YAML",
+			file: "calibration.yaml",
+			data: "channels:\n  - device: 10.0.0.12\n    signal: 1\n    points:\n      - {measured: 0, actual: 1}\n      - {measured: 1, actual: 3}\n",
+		},
+		{
+			name:    "Missing device",
+			file:    "calibration.json",
+			data:    `{"channels": [{"signal": 1, "gain": 2}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing gain",
+			file:    "calibration.json",
+			data:    `{"channels": [{"device": "10.0.0.12", "signal": 1, "offset": 1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Negative gain",
+			file:    "calibration.json",
+			data:    `{"channels": [{"device": "10.0.0.12", "signal": 1, "gain": -2}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "One point",
+			file:    "calibration.json",
+			data:    `{"channels": [{"device": "10.0.0.12", "signal": 1, "points": [{"measured": 0, "actual": 1}]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Same measured values",
+			file:    "calibration.json",
+			data:    `{"channels": [{"device": "10.0.0.12", "signal": 1, "points": [{"measured": 1, "actual": 1}, {"measured": 1, "actual": 3}]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "YAML with a JSON extension",
+			file:    "calibration.json",
+			data:    "channels:\n  - device: 10.0.0.12\n    signal: 1\n    gain: 2\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o644))
+
+			c, err := openpsg.LoadCalibration(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, c.Channels, 1)
+		})
+	}
+}
+
+func TestRecordCalibration(t *testing.T) {
+	tests := []struct {
+		name        string
+		calibration openpsg.ChannelCalibration
+		// The physical range of the ECG signal (-5 to 5 mV on the device).
+		wantMin, wantMax float64
+		wantPrefiltering string
+	}{
+		{
+			name:             "Gain and offset",
+			calibration:      openpsg.ChannelCalibration{Gain: 2, Offset: 1},
+			wantMin:          -9,
+			wantMax:          11,
+			wantPrefiltering: "CAL:gain=2,offset=1",
+		},
+		{
+			name: "Two points",
+			calibration: openpsg.ChannelCalibration{Points: []openpsg.CalibrationPoint{
+				{Measured: 1, Actual: 0.5},
+				{Measured: 3, Actual: 1.5},
+			}},
+			wantMin:          -2.5,
+			wantMax:          2.5,
+			wantPrefiltering: "CAL:gain=0.5,offset=0",
+		},
+		{
+			name:             "Offset only",
+			calibration:      openpsg.ChannelCalibration{Gain: 1, Offset: -0.25},
+			wantMin:          -5.25,
+			wantMax:          4.75,
+			wantPrefiltering: "CAL:gain=1,offset=-0.25",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calibration := tt.calibration
+			calibration.Device = openpsg.LocalSourceAddr(1)
+			calibration.Signal = 1
+
+			er := record(t, newFakeSource().open, 500*time.Millisecond,
+				openpsg.WithCalibration(&openpsg.Calibration{Channels: []openpsg.ChannelCalibration{calibration}}))
+
+			require.Len(t, er.Signals(), 1)
+			signal := er.Signals()[0]
+			assert.Equal(t, tt.wantMin, signal.PhysicalMin)
+			assert.Equal(t, tt.wantMax, signal.PhysicalMax)
+			assert.Equal(t, tt.wantPrefiltering, signal.Prefiltering)
+		})
+	}
+
+	t.Run("Another device", func(t *testing.T) {
+		// The calibration of a signal of another device leaves the range as is.
+		er := record(t, newFakeSource().open, 500*time.Millisecond,
+			openpsg.WithCalibration(&openpsg.Calibration{Channels: []openpsg.ChannelCalibration{
+				{Device: openpsg.LocalSourceAddr(2), Signal: 1, Gain: 2, Offset: 1},
+			}}))
+
+		require.Len(t, er.Signals(), 1)
+		assert.Equal(t, -5.0, er.Signals()[0].PhysicalMin)
+		assert.Equal(t, 5.0, er.Signals()[0].PhysicalMax)
+		assert.Empty(t, er.Signals()[0].Prefiltering)
+	})
+}
@@ -0,0 +1,320 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// CapturedFrame is a frame sent to or received from a device, captured with
+// WithCapture.
+type CapturedFrame struct {
+	Time time.Time `json:"time"`
+	// The frame was sent to the device (rather than received from it).
+	Sent bool `json:"sent"`
+	// The content type of binary frames (eg. application/cbor).
+	ContentType string `json:"contentType,omitempty"`
+	// The frame, if it's a JSON-RPC object.
+	JSON json.RawMessage `json:"json,omitempty"`
+	// The frame, if it's binary (after decompression).
+	Data []byte `json:"data,omitempty"`
+}
+
+// WithCapture captures the frames sent to and received from devices, with
+// timestamps, to a file per connection in the directory (named after the
+// device and when it was connected to), eg. to reproduce intermittent device
+// misbehavior offline with a CaptureReplay.
+func WithCapture(dir string) ConnectOption {
+	return func(o *connectOptions) {
+		o.captureDir = dir
+	}
+}
+
+// capture writes the frames of a connection to a capture file, as JSON lines.
+type capture struct {
+	mu     sync.Mutex
+	f      *os.File
+	enc    *json.Encoder
+	failed bool
+	closed bool
+}
+
+// openCapture creates a capture file for a connection to the named device.
+func openCapture(dir, name string) (*capture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl", name, time.Now().UTC().Format("20060102T150405.000")))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture: %w", err)
+	}
+
+	slog.Debug("Capturing frames", slog.String("device", name), slog.String("path", path))
+
+	return &capture{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// frame captures a frame sent to or received from the device.
+func (c *capture) frame(sent bool, contentType string, frame []byte) {
+	captured := CapturedFrame{Time: time.Now(), Sent: sent}
+	if contentType == "" && json.Valid(frame) {
+		captured.JSON = frame
+	} else {
+		captured.ContentType = contentType
+		captured.Data = frame
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	if err := c.enc.Encode(captured); err != nil && !c.failed {
+		c.failed = true
+		slog.Warn("Failed to capture frame", slog.String("path", c.f.Name()), slog.Any("error", err))
+	}
+}
+
+func (c *capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return c.f.Close()
+}
+
+// LoadCapture reads the frames of a capture file.
+func LoadCapture(path string) ([]CapturedFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+	defer f.Close()
+
+	var frames []CapturedFrame
+	dec := json.NewDecoder(f)
+	for {
+		var frame CapturedFrame
+		if err := dec.Decode(&frame); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read capture: %w", err)
+		}
+		frames = append(frames, frame)
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("capture is empty")
+	}
+	return frames, nil
+}
+
+// CaptureReplay replays a captured connection to the recorder, as the device.
+// The frames the device sent unprompted (eg. signal values) are sent with the
+// timing they were captured with, and requests are responded to with the
+// captured responses to the same method (in order), so the recorder sees the
+// device behave as it did. The connection is closed when the capture ends.
+type CaptureReplay struct {
+	Frames []CapturedFrame
+}
+
+// capturedMessage is the part of a JSON-RPC object needed to replay it.
+type capturedMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+}
+
+// Serve replays the capture to each connection accepted on the listener, until
+// the context is cancelled.
+func (r *CaptureReplay) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		slog.Info("Replaying capture", slog.Any("remoteAddr", conn.RemoteAddr()))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			if err := r.replay(ctx, conn); err != nil {
+				slog.Warn("Failed to replay capture", slog.Any("error", err))
+			}
+		}()
+	}
+}
+
+// replay replays the capture to a connection.
+func (r *CaptureReplay) replay(ctx context.Context, conn net.Conn) error {
+	// The captured responses to each method, in order, and the frames the
+	// device sent unprompted.
+	requests := make(map[string]string)
+	responses := make(map[string][]json.RawMessage)
+	var unprompted []CapturedFrame
+	for _, frame := range r.Frames {
+		var msg capturedMessage
+		if frame.JSON != nil {
+			if err := json.Unmarshal(frame.JSON, &msg); err != nil {
+				return fmt.Errorf("invalid captured frame: %w", err)
+			}
+		}
+
+		switch {
+		case frame.Sent:
+			if msg.ID != nil && msg.Method != "" {
+				requests[string(*msg.ID)] = msg.Method
+			}
+		case msg.ID != nil && msg.Method == "":
+			method, ok := requests[string(*msg.ID)]
+			if ok {
+				responses[method] = append(responses[method], frame.JSON)
+			}
+		default:
+			unprompted = append(unprompted, frame)
+		}
+	}
+
+	var mu sync.Mutex
+	write := func(contentType string, frame []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		header := fmt.Sprintf("Content-Length: %d\r\n", len(frame))
+		if contentType != "" {
+			header += fmt.Sprintf("Content-Type: %s\r\n", contentType)
+		}
+		_, err := io.WriteString(conn, header+"\r\n")
+		if err == nil {
+			_, err = conn.Write(frame)
+		}
+		return err
+	}
+
+	// Respond to requests with the captured responses.
+	go func() {
+		stream := bufio.NewReader(conn)
+		for {
+			header, err := readHeader(stream)
+			if err != nil {
+				return
+			}
+
+			frame := make([]byte, header.contentLength)
+			if _, err := io.ReadFull(stream, frame); err != nil {
+				return
+			}
+
+			var msg capturedMessage
+			if err := json.Unmarshal(frame, &msg); err != nil || msg.ID == nil {
+				continue
+			}
+
+			var response []byte
+			if captured := responses[msg.Method]; len(captured) > 0 {
+				responses[msg.Method] = captured[1:]
+				response, err = withID(captured[0], *msg.ID)
+			} else {
+				response, err = json.Marshal(map[string]any{
+					"jsonrpc": "2.0",
+					"id":      msg.ID,
+					"error":   &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: "no captured response to " + msg.Method},
+				})
+			}
+			if err != nil {
+				slog.Warn("Failed to replay response", slog.String("method", msg.Method), slog.Any("error", err))
+				continue
+			}
+
+			if err := write("", response); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Send the frames the device sent unprompted, with their captured timing.
+	start := time.Now()
+	first := r.Frames[0].Time
+	for _, frame := range append(unprompted, CapturedFrame{Time: r.Frames[len(r.Frames)-1].Time}) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(start.Add(frame.Time.Sub(first)))):
+		}
+
+		var err error
+		if frame.JSON != nil {
+			err = write("", frame.JSON)
+		} else if frame.Data != nil {
+			err = write(frame.ContentType, frame.Data)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to send frame: %w", err)
+		}
+	}
+
+	slog.Info("Capture ended, closing the connection", slog.Any("remoteAddr", conn.RemoteAddr()))
+	return nil
+}
+
+// withID replaces the ID of a JSON-RPC object.
+func withID(obj json.RawMessage, id json.RawMessage) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &fields); err != nil {
+		return nil, err
+	}
+	fields["id"] = id
+	return json.Marshal(fields)
+}
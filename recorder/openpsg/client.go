@@ -20,78 +20,788 @@ package openpsg
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"net/netip"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/OpenPSG/OpenPSG/recorder/internal/serial"
 	"github.com/sourcegraph/jsonrpc2"
 )
 
+// The default timeout of dialing devices and of requests to them (see
+// WithDialTimeout and WithCallTimeout).
 const timeout = 5 * time.Second
 
+// The number of lead-off status changes buffered before they are dropped.
+const leadOffBufferSize = 16
+
+// Devices are pinged at this interval, and treated as failed once they miss
+// maxMissedPings pings in a row (eg. a wedged device that no longer sends
+// values, while its connection is still up).
+const (
+	pingInterval   = 2 * time.Second
+	pingTimeout    = 2 * time.Second
+	maxMissedPings = 2
+)
+
+// Client is a connection to an OpenPSG device. If the connection is lost (eg.
+// the TCP connection is reset, or the device stops responding to pings), the
+// client reconnects transparently, restarting the signals it had started, and
+// reports the outage (see Outages).
 type Client struct {
-	rpcConn      *jsonrpc2.Conn
-	signalValues chan SignalValues
+	// The address (or serial port) of the device, for logging.
+	name string
+	// The IP address of the device, if connected over the network.
+	host    netip.Addr
+	options connectOptions
+	dial    func(ctx context.Context) (io.ReadWriteCloser, error)
+
+	// The subscription of SignalValues.
+	values  *Subscription
+	leadOff chan LeadOffStatus
+	outages chan Outage
+	events  chan DeviceEvent
+
+	subscriptionsMu     sync.Mutex
+	subscriptions       []*Subscription
+	subscriptionsClosed bool
+
+	// Held for reading while sending notifications, so the channels aren't
+	// closed meanwhile.
+	closeMu sync.RWMutex
+	closed  bool
+
+	// Cancelled when the client is closed.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	rpcConn  *jsonrpc2.Conn
+	version  DeviceVersion
+	identity string
+	// The signals started on the device, restarted when reconnecting.
+	started map[uint32]bool
+	// When the connection was lost, while reconnecting.
+	lostAt time.Time
+	status DeviceStatus
+	// The enabled status channels.
+	enabled map[uint32]bool
+	// The sequence numbers of the values of each signal.
+	sequences map[uint32]*sequence
+	clock     clockFilter
+	// The configuration of the signals, reapplied when reconnecting.
+	configuration []SignalConfiguration
+	// The sample formats of the signals, to decode binary values.
+	formats map[uint32]SampleFormat
+	// Values streamed over UDP (see StreamUDP), reordered by signal.
+	udpConn *net.UDPConn
+	udpDone chan struct{}
+	reorder map[uint32]*reorderBuffer
+}
+
+// sequence checks the sequence numbers of the values of a signal.
+type sequence struct {
+	// The next expected sequence number, if any values have been received since
+	// connecting.
+	next    uint32
+	started bool
+	errors  SequenceErrors
+}
+
+// Outage is a period during which the connection to a device was lost.
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the duration of the outage.
+func (o Outage) Duration() time.Duration {
+	return o.End.Sub(o.Start)
 }
 
 // Connect to the device at the specified address and port.
-func Connect(ctx context.Context, deviceAddrPort netip.AddrPort) (*Client, error) {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+func Connect(ctx context.Context, deviceAddrPort netip.AddrPort, opts ...ConnectOption) (*Client, error) {
+	options := newConnectOptions(opts...)
+
+	return newClient(ctx, deviceAddrPort.String(), deviceAddrPort.Addr().Unmap(), options, dialDevice(deviceAddrPort, options))
+}
+
+// dialDevice returns a function dialing the device at deviceAddrPort, over TLS
+// if configured.
+func dialDevice(deviceAddrPort netip.AddrPort, options connectOptions) func(ctx context.Context) (io.ReadWriteCloser, error) {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		ctx, cancel := context.WithTimeout(ctx, options.dialTimeout)
+		defer cancel()
+
+		var conn net.Conn
+		var err error
+		if options.tlsConfig != nil {
+			config := options.tlsConfig
+			if options.pinnedCertificates != nil {
+				config = verifyPinnedCertificate(config, deviceAddrPort.Addr().Unmap(), options.pinnedCertificates)
+			}
+
+			d := tls.Dialer{Config: config}
+			conn, err = d.DialContext(ctx, "tcp", deviceAddrPort.String())
+		} else {
+			var d net.Dialer
+			conn, err = d.DialContext(ctx, "tcp", deviceAddrPort.String())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to device: %w", err)
+		}
+		return conn, nil
+	}
+}
+
+// ConnectSerial connects to the device attached to the serial port at path
+// (eg. "/dev/ttyACM0" for a USB CDC device), speaking the same protocol as
+// over the network.
+func ConnectSerial(ctx context.Context, path string, baud int) (*Client, error) {
+	return newClient(ctx, path, netip.Addr{}, newConnectOptions(), func(ctx context.Context) (io.ReadWriteCloser, error) {
+		port, err := serial.Open(path, baud)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to device: %w", err)
+		}
+		return port, nil
+	})
+}
+
+func newClient(ctx context.Context, name string, host netip.Addr, options connectOptions, dial func(ctx context.Context) (io.ReadWriteCloser, error)) (*Client, error) {
+	clientCtx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		name:      name,
+		host:      host,
+		options:   options,
+		dial:      dial,
+		leadOff:   make(chan LeadOffStatus, leadOffBufferSize),
+		outages:   make(chan Outage, leadOffBufferSize),
+		events:    make(chan DeviceEvent, leadOffBufferSize),
+		ctx:       clientCtx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		started:   make(map[uint32]bool),
+		enabled:   make(map[uint32]bool),
+		sequences: make(map[uint32]*sequence),
+		formats:   make(map[uint32]SampleFormat),
+	}
+	// Subscribed before connecting, so no values are missed.
+	c.values = c.Subscribe()
+
+	if err := options.retry.retry(ctx, name, c.connect); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go c.maintain()
+
+	return c, nil
+}
+
+// connect connects to the device, negotiating the protocol version and
+// restarting the started signals.
+func (c *Client) connect(ctx context.Context) error {
+	stream, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
 	defer cancel()
+	codec := frameCodec{values: c.handleBinaryValues}
+	if c.options.captureDir != "" {
+		codec.capture, err = openCapture(c.options.captureDir, c.name)
+		if err != nil {
+			_ = stream.Close()
+			return err
+		}
+	}
+	rpcConn := jsonrpc2.NewConn(c.ctx, jsonrpc2.NewBufferedStream(stream, codec), c)
+	if codec.capture != nil {
+		go func() {
+			<-rpcConn.DisconnectNotify()
+			_ = codec.capture.Close()
+		}()
+	}
 
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "tcp", deviceAddrPort.String())
+	version, err := negotiate(ctx, rpcConn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to device: %w", err)
+		_ = rpcConn.Close()
+		return err
+	}
+
+	if c.options.deviceKey != nil {
+		if err := authenticate(ctx, rpcConn, c.options.deviceKey); err != nil {
+			_ = rpcConn.Close()
+			return err
+		}
+	}
+
+	var identity string
+	if version.has(CapabilityIdentity) {
+		identity, err = identify(ctx, rpcConn)
+		if err != nil {
+			_ = rpcConn.Close()
+			return err
+		}
+	}
+
+	if version.has(CapabilityCompression) {
+		var compression struct {
+			Algorithm string `json:"algorithm"`
+		}
+		params := map[string][]string{"algorithms": compressionAlgorithms}
+		if err := rpcConn.Call(ctx, "openpsg.compression", params, &compression); err != nil {
+			slog.Debug("Failed to enable compression", slog.String("device", c.name), slog.Any("error", err))
+		} else if compression.Algorithm != "" {
+			slog.Debug("Enabled compression", slog.String("device", c.name), slog.String("algorithm", compression.Algorithm))
+		}
 	}
 
-	c := Client{
-		signalValues: make(chan SignalValues),
+	if version.has(CapabilityBinaryStreaming) {
+		params := map[string]string{"values": "cbor"}
+		if err := rpcConn.Call(ctx, "openpsg.encoding", params, nil); err != nil {
+			slog.Debug("Failed to enable binary streaming, streaming JSON values",
+				slog.String("device", c.name), slog.Any("error", err))
+		}
+	}
+
+	// Restore the streaming and configuration of the signals (without holding
+	// the lock while waiting for the responses, as values are handled
+	// meanwhile).
+	c.mu.Lock()
+	udpConn, configuration := c.udpConn, c.configuration
+	c.mu.Unlock()
+
+	if udpConn != nil {
+		if err := requestUDP(ctx, rpcConn, udpConn); err != nil {
+			_ = rpcConn.Close()
+			return err
+		}
+	}
+
+	if configuration != nil {
+		if err := configure(ctx, rpcConn, configuration); err != nil {
+			_ = rpcConn.Close()
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx.Err() != nil {
+		_ = rpcConn.Close()
+		return c.ctx.Err()
+	}
+
+	// Another device may have taken over the address.
+	if c.identity != "" && identity != c.identity {
+		_ = rpcConn.Close()
+		return fmt.Errorf("%w: the identity of the device changed (expected %s)", ErrUnauthorizedDevice, c.identity)
+	}
+
+	if c.udpConn != nil {
+		c.reorder = make(map[uint32]*reorderBuffer)
+	}
+
+	if len(c.started) > 0 {
+		if err := rpcConn.Notify(ctx, "openpsg.start", slices.Sorted(maps.Keys(c.started))); err != nil {
+			_ = rpcConn.Close()
+			return fmt.Errorf("failed to restart signals: %w", err)
+		}
+	}
+
+	c.rpcConn = rpcConn
+	c.version = version
+	c.identity = identity
+	// The device may have restarted its sequence numbers (and reset its clock).
+	for _, seq := range c.sequences {
+		seq.started = false
+	}
+	c.clock = clockFilter{}
+	go c.keepalive(rpcConn)
+
+	return nil
+}
+
+// maintain reconnects to the device (with exponential backoff) whenever the
+// connection is lost, until the client is closed.
+func (c *Client) maintain() {
+	defer close(c.done)
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.conn().DisconnectNotify():
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		outage := Outage{Start: time.Now()}
+		slog.Warn("Lost connection to device, reconnecting", slog.String("device", c.name))
+
+		c.mu.Lock()
+		c.lostAt = outage.Start
+		c.mu.Unlock()
+
+		delay := c.options.retry.MinDelay
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			delay = min(2*delay, c.options.retry.MaxDelay)
+
+			if err := c.connect(c.ctx); err != nil {
+				slog.Debug("Failed to reconnect to device", slog.String("device", c.name), slog.Any("error", err))
+				continue
+			}
+			break
+		}
+
+		outage.End = time.Now()
+		c.mu.Lock()
+		c.lostAt = time.Time{}
+		c.mu.Unlock()
+
+		slog.Info("Reconnected to device", slog.String("device", c.name), slog.Duration("outage", outage.Duration()))
+
+		select {
+		case c.outages <- outage:
+		default:
+			slog.Warn("Dropped device outage", slog.String("device", c.name))
+		}
 	}
-	c.rpcConn = jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}), &c)
-	return &c, nil
 }
 
+// conn returns the current connection to the device.
+func (c *Client) conn() *jsonrpc2.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rpcConn
+}
+
+// keepalive pings the device until the connection is closed, closing it when
+// the device stops responding so it is treated as disconnected. Devices with
+// the time capability are pinged with openpsg.time, measuring their clock
+// offset.
+func (c *Client) keepalive(rpcConn *jsonrpc2.Conn) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	var missed int
+	for {
+		select {
+		case <-rpcConn.DisconnectNotify():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err := c.ping(ctx, rpcConn)
+		cancel()
+
+		// Any response will do (firmware without openpsg.ping responds with an
+		// error).
+		var rpcErr *jsonrpc2.Error
+		if err == nil || errors.As(err, &rpcErr) {
+			missed = 0
+			continue
+		}
+		if errors.Is(err, jsonrpc2.ErrClosed) {
+			return
+		}
+
+		missed++
+		if missed < maxMissedPings {
+			slog.Debug("Device missed a ping", slog.String("device", c.name), slog.Any("error", err))
+			continue
+		}
+
+		slog.Warn("Device stopped responding, closing the connection",
+			slog.String("device", c.name), slog.Int("missedPings", missed))
+		_ = rpcConn.Close()
+		return
+	}
+}
+
+// ping pings the device, measuring its clock offset if it can.
+func (c *Client) ping(ctx context.Context, rpcConn *jsonrpc2.Conn) error {
+	if !c.HasCapability(CapabilityTime) {
+		return rpcConn.Call(ctx, "openpsg.ping", nil, nil)
+	}
+
+	var t deviceTime
+	t1 := time.Now()
+	if err := rpcConn.Call(ctx, "openpsg.time", nil, &t); err != nil {
+		return err
+	}
+	t4 := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock.add(clockOffset(t1, t.Received, t.Transmitted, t4))
+	return nil
+}
+
+// ClockOffset returns the estimated offset of the device's clock from the
+// recorder's, if it has been measured (see CapabilityTime).
+func (c *Client) ClockOffset() (ClockOffset, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.clock.estimate()
+}
+
+// negotiate retrieves the protocol version and capabilities of the device,
+// failing if the device speaks a newer version than the recorder.
+func negotiate(ctx context.Context, rpcConn *jsonrpc2.Conn) (DeviceVersion, error) {
+	var version DeviceVersion
+	if err := rpcConn.Call(ctx, "openpsg.version", nil, &version); err != nil {
+		if !isMethodNotFound(err) {
+			return DeviceVersion{}, fmt.Errorf("failed to get protocol version: %w", err)
+		}
+
+		// Firmware predating the handshake.
+		version = DeviceVersion{}
+	}
+
+	if version.Version > ProtocolVersion {
+		return DeviceVersion{}, fmt.Errorf("%w: the device speaks protocol version %d, but the recorder only supports up to version %d (update the recorder)",
+			ErrIncompatibleDevice, version.Version, ProtocolVersion)
+	}
+
+	slog.Debug("Negotiated protocol version",
+		slog.Int("version", version.Version),
+		slog.Any("capabilities", version.Capabilities))
+
+	return version, nil
+}
+
+// isMethodNotFound reports whether the device doesn't implement a method (eg.
+// older firmware).
+func isMethodNotFound(err error) bool {
+	var rpcErr *jsonrpc2.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc2.CodeMethodNotFound
+}
+
+// Version returns the protocol version and capabilities of the device.
+func (c *Client) Version() DeviceVersion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// HasCapability reports whether the device supports an optional feature of the
+// protocol.
+func (c *Client) HasCapability(capability Capability) bool {
+	return c.Version().has(capability)
+}
+
+// Close closes the connection to the device, and the channels of the client
+// and its subscriptions. It is safe to call while values are being received.
 func (c *Client) Close() error {
-	err := c.rpcConn.Close()
-	close(c.signalValues)
+	// Values being sent are interrupted once the client is cancelled.
+	c.cancel()
+	err := c.conn().Close()
+	<-c.done
+
+	c.mu.Lock()
+	udpConn, udpDone := c.udpConn, c.udpDone
+	c.mu.Unlock()
+	if udpConn != nil {
+		_ = udpConn.Close()
+		<-udpDone
+	}
+
+	c.closeSubscriptions()
+
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.leadOff)
+		close(c.outages)
+		close(c.events)
+	}
 	return err
 }
 
 // Retrieve the list of signals available on the device.
 func (c *Client) Signals(ctx context.Context) ([]Signal, error) {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
 	defer cancel()
 
 	var signals []Signal
-	if err := c.rpcConn.Call(ctx, "openpsg.signals", nil, &signals); err != nil {
+	if err := c.conn().Call(ctx, "openpsg.signals", nil, &signals); err != nil {
 		return nil, fmt.Errorf("failed to get signals: %w", err)
 	}
-	return signals, nil
+
+	c.mu.Lock()
+	for _, signal := range signals {
+		c.formats[signal.ID] = signal.sampleFormat()
+	}
+	c.mu.Unlock()
+
+	return append(signals, statusSignals(c.Version())...), nil
 }
 
-// Start collecting data for the specified signals.
+// Info retrieves the serial number, hardware model and firmware version of the
+// device (empty if the firmware doesn't report them).
+func (c *Client) Info(ctx context.Context) (DeviceInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	var info DeviceInfo
+	if err := c.conn().Call(ctx, "openpsg.info", nil, &info); err != nil {
+		if isMethodNotFound(err) {
+			return DeviceInfo{}, nil
+		}
+		return DeviceInfo{}, fmt.Errorf("failed to get device info: %w", err)
+	}
+	return info, nil
+}
+
+// Link retrieves the type, speed and signal strength of the device's link to
+// the network (empty if the device doesn't have the link capability).
+func (c *Client) Link(ctx context.Context) (LinkInfo, error) {
+	if !c.HasCapability(CapabilityLink) {
+		return LinkInfo{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	var link LinkInfo
+	if err := c.conn().Call(ctx, "openpsg.link", nil, &link); err != nil {
+		return LinkInfo{}, fmt.Errorf("failed to get device link: %w", err)
+	}
+	return link, nil
+}
+
+// MeasureClockOffset measures the offset of the device's clock from the
+// recorder's straight away, rather than waiting for it to be measured while
+// pinging the device (see CapabilityTime). The estimated offset is returned.
+func (c *Client) MeasureClockOffset(ctx context.Context) (ClockOffset, bool, error) {
+	if !c.HasCapability(CapabilityTime) {
+		return ClockOffset{}, false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	if err := c.ping(ctx, c.conn()); err != nil {
+		return ClockOffset{}, false, fmt.Errorf("failed to measure clock offset: %w", err)
+	}
+
+	offset, ok := c.ClockOffset()
+	return offset, ok, nil
+}
+
+// Identify makes the device blink its LED for the duration, so it can be told
+// apart from other devices (eg. while hooking up a patient).
+func (c *Client) Identify(ctx context.Context, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	params := map[string]float64{"duration": duration.Seconds()}
+	if err := c.conn().Call(ctx, "openpsg.identify", params, nil); err != nil {
+		if isMethodNotFound(err) {
+			return fmt.Errorf("the device can't identify itself")
+		}
+		return fmt.Errorf("failed to identify device: %w", err)
+	}
+	return nil
+}
+
+// Start collecting data for the specified signals. They are restarted if the
+// client reconnects.
 func (c *Client) Start(ctx context.Context, signalIDs []uint32) error {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	signalIDs = c.setStarted(signalIDs, true)
+	if len(signalIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
 	defer cancel()
 
-	return c.rpcConn.Notify(ctx, "openpsg.start", signalIDs)
+	return c.conn().Notify(ctx, "openpsg.start", signalIDs)
 }
 
 // Stop collecting data for the specified signals.
 func (c *Client) Stop(ctx context.Context, signalIDs []uint32) error {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	signalIDs = c.setStarted(signalIDs, false)
+	if len(signalIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
 	defer cancel()
 
-	return c.rpcConn.Notify(ctx, "openpsg.stop", signalIDs)
+	return c.conn().Notify(ctx, "openpsg.stop", signalIDs)
+}
+
+// setStarted records the signals as started (or stopped), enabling the status
+// channels among them, and returns the remaining signals streamed by the
+// device.
+func (c *Client) setStarted(signalIDs []uint32, started bool) []uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deviceSignalIDs []uint32
+	for _, id := range signalIDs {
+		enabled := c.started
+		if isStatusSignal(id) {
+			enabled = c.enabled
+		} else {
+			deviceSignalIDs = append(deviceSignalIDs, id)
+		}
+
+		if started {
+			enabled[id] = true
+		} else {
+			delete(enabled, id)
+		}
+	}
+	return deviceSignalIDs
+}
+
+// checkSequence checks the sequence number of a batch of values of a signal,
+// reporting lost batches, and returning false if the batch is a duplicate (to
+// be dropped).
+func (c *Client) checkSequence(id uint32, n uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq, ok := c.sequences[id]
+	if !ok {
+		seq = &sequence{}
+		c.sequences[id] = seq
+	}
+
+	if seq.started {
+		delta := seqDelta(uint32(n), seq.next)
+		if delta < 0 {
+			seq.errors.Duplicated++
+			slog.Warn("Dropped duplicated signal values",
+				slog.String("device", c.name), slog.Any("id", id), slog.Uint64("seq", n))
+			return false
+		}
+
+		if delta > 0 {
+			seq.errors.Lost += uint64(delta)
+			slog.Warn("Lost signal values",
+				slog.String("device", c.name), slog.Any("id", id), slog.Int64("batches", delta))
+		}
+	}
+
+	seq.next, seq.started = uint32(n)+1, true
+	return true
+}
+
+// seqDelta returns how far sequence number a is ahead of b (negative if it is
+// behind). Devices send 32-bit sequence numbers that wrap around, so they are
+// compared using serial number arithmetic (RFC 1982).
+func seqDelta(a, b uint32) int64 {
+	return int64(int32(a - b))
+}
+
+// SequenceErrors returns the batches of values of each signal lost or
+// duplicated in transit (for devices sending sequence numbers).
+func (c *Client) SequenceErrors() map[uint32]SequenceErrors {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := make(map[uint32]SequenceErrors, len(c.sequences))
+	for id, seq := range c.sequences {
+		errs[id] = seq.errors
+	}
+	return errs
 }
 
-// SignalValues returns a channel that will receive the values of the signals.
+// handleBinaryValues handles a frame of signal values encoded in CBOR.
+func (c *Client) handleBinaryValues(frame []byte) {
+	values, err := decodeBinaryValues(frame, c.sampleFormat)
+	if err != nil {
+		slog.Error("Failed to decode binary values", slog.String("device", c.name), slog.Any("error", err))
+		return
+	}
+
+	if !c.checkSequence(values.ID, *values.Seq) {
+		return
+	}
+
+	c.publish(values)
+}
+
+// sampleFormat returns the sample format of a signal, if it is known.
+func (c *Client) sampleFormat(id uint32) (SampleFormat, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	format, ok := c.formats[id]
+	return format, ok
+}
+
+// Disconnected returns a channel that is closed when the client is closed. A
+// lost connection is instead reconnected, and reported as an outage.
+func (c *Client) Disconnected() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+// Outages returns a channel that will receive the outages of the connection
+// to the device, once it has been reconnected. Outages are dropped if they
+// aren't received promptly.
+func (c *Client) Outages() <-chan Outage {
+	return c.outages
+}
+
+// CurrentOutage returns the outage in progress (without an end), if the
+// connection to the device is lost and the client is reconnecting.
+func (c *Client) CurrentOutage() (Outage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Outage{Start: c.lostAt}, !c.lostAt.IsZero()
+}
+
+// Events returns a channel that will receive the events reported by the device
+// (eg. the patient pressing the event button). Events are dropped if they
+// aren't received promptly.
+func (c *Client) Events() <-chan DeviceEvent {
+	return c.events
+}
+
+// SignalValues returns a channel that will receive the values of the signals
+// (received since connecting), closed once the client is closed. Other
+// consumers of the values (eg. a live viewer) should Subscribe instead.
 func (c *Client) SignalValues() <-chan SignalValues {
-	return c.signalValues
+	return c.values.Values()
+}
+
+// LeadOff returns a channel that will receive changes in the lead-off status of
+// the signals. Changes are dropped if they aren't received promptly.
+func (c *Client) LeadOff() <-chan LeadOffStatus {
+	return c.leadOff
 }
 
 // Handle a notification from the server.
@@ -104,8 +814,58 @@ func (c *Client) Handle(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Re
 			return
 		}
 
-		c.signalValues <- values
+		if values.Seq != nil && !c.checkSequence(values.ID, *values.Seq) {
+			return
+		}
+
+		c.publish(values)
+	case "openpsg.leadoff":
+		var status LeadOffStatus
+		if err := json.Unmarshal(*r.Params, &status); err != nil {
+			slog.Error("Failed to unmarshal lead-off status", slog.Any("error", err))
+			return
+		}
+
+		if !notify(c, c.leadOff, status) && c.ctx.Err() == nil {
+			slog.Warn("Dropped lead-off status", slog.Any("id", status.ID))
+		}
+	case "openpsg.status":
+		var status DeviceStatus
+		if err := json.Unmarshal(*r.Params, &status); err != nil {
+			slog.Error("Failed to unmarshal status", slog.Any("error", err))
+			return
+		}
+
+		c.handleStatus(status)
+	case "openpsg.event":
+		var event DeviceEvent
+		if err := json.Unmarshal(*r.Params, &event); err != nil {
+			slog.Error("Failed to unmarshal event", slog.Any("error", err))
+			return
+		}
+
+		if !notify(c, c.events, event) && c.ctx.Err() == nil {
+			slog.Warn("Dropped device event", slog.String("device", c.name), slog.Any("type", event.Type))
+		}
 	default:
 		slog.Warn("Unknown notification received", slog.String("method", r.Method))
 	}
 }
+
+// notify sends a notification (eg. an event) to ch unless it is full, or the
+// client has been closed, returning whether it was sent.
+func notify[T any](c *Client, ch chan T, v T) bool {
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
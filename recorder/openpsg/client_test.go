@@ -0,0 +1,94 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSequence(t *testing.T) {
+	tests := []struct {
+		name         string
+		seqs         []uint64
+		wantAccepted []bool
+		wantErrors   openpsg.SequenceErrors
+	}{
+		{
+			name:         "In order",
+			seqs:         []uint64{0, 1, 2},
+			wantAccepted: []bool{true, true, true},
+		},
+		{
+			name:         "Starting anywhere",
+			seqs:         []uint64{1000, 1001},
+			wantAccepted: []bool{true, true},
+		},
+		{
+			name:         "Lost",
+			seqs:         []uint64{0, 3, 4},
+			wantAccepted: []bool{true, true, true},
+			wantErrors:   openpsg.SequenceErrors{Lost: 2},
+		},
+		{
+			name:         "Duplicated",
+			seqs:         []uint64{0, 1, 1, 0, 2},
+			wantAccepted: []bool{true, true, false, false, true},
+			wantErrors:   openpsg.SequenceErrors{Duplicated: 2},
+		},
+		{
+			name:         "Reordered",
+			seqs:         []uint64{5, 7, 6, 8},
+			wantAccepted: []bool{true, true, false, true},
+			wantErrors:   openpsg.SequenceErrors{Lost: 1, Duplicated: 1},
+		},
+		{
+			name:         "Wraparound",
+			seqs:         []uint64{math.MaxUint32 - 1, math.MaxUint32, 0, 1},
+			wantAccepted: []bool{true, true, true, true},
+		},
+		{
+			name:         "Lost across wraparound",
+			seqs:         []uint64{math.MaxUint32, 2},
+			wantAccepted: []bool{true, true},
+			wantErrors:   openpsg.SequenceErrors{Lost: 2},
+		},
+		{
+			name:         "Duplicated across wraparound",
+			seqs:         []uint64{math.MaxUint32, 0, math.MaxUint32, 1},
+			wantAccepted: []bool{true, true, false, true},
+			wantErrors:   openpsg.SequenceErrors{Duplicated: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := openpsg.NewTestClient()
+
+			for i, seq := range tt.seqs {
+				assert.Equal(t, tt.wantAccepted[i], c.CheckSequence(1, seq), "sequence number %d", seq)
+			}
+
+			assert.Equal(t, map[uint32]openpsg.SequenceErrors{1: tt.wantErrors}, c.SequenceErrors())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// The number of recent clock measurements the offset is estimated from.
+const clockFilterSize = 8
+
+// WithClockOffsetCorrection corrects the timestamps of devices by their clock
+// offset from the recorder, measured with openpsg.time exchanges, rather than
+// trusting the devices to be synchronized (eg. between NTP updates).
+func WithClockOffsetCorrection() RecordOption {
+	return func(o *recordOptions) {
+		o.clockOffsetCorrection = true
+	}
+}
+
+// ClockOffset is a measurement of the offset of a device's clock from the
+// recorder's clock.
+type ClockOffset struct {
+	// How far the device's clock is ahead of the recorder's.
+	Offset time.Duration
+	// The round-trip delay of the measurement.
+	Delay time.Duration
+}
+
+// deviceTime is the response to an openpsg.time request: when the device
+// received the request, and when it sent the response, by its clock.
+type deviceTime struct {
+	Received    time.Time `json:"received"`
+	Transmitted time.Time `json:"transmitted"`
+}
+
+// clockOffset returns the offset and delay of an NTP-style exchange, sent at
+// t1 and received at t4 by the recorder, and received at t2 and responded to at
+// t3 by the device.
+func clockOffset(t1, t2, t3, t4 time.Time) ClockOffset {
+	return ClockOffset{
+		Offset: (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		Delay:  t4.Sub(t1) - t3.Sub(t2),
+	}
+}
+
+// clockFilter estimates the clock offset of a device from its recent
+// measurements, using the one with the least delay (as it is the least
+// affected by asymmetric delays).
+type clockFilter struct {
+	measurements []ClockOffset
+}
+
+func (f *clockFilter) add(m ClockOffset) {
+	if len(f.measurements) == clockFilterSize {
+		f.measurements = slices.Delete(f.measurements, 0, 1)
+	}
+	f.measurements = append(f.measurements, m)
+}
+
+// estimate returns the best recent measurement, if any.
+func (f *clockFilter) estimate() (ClockOffset, bool) {
+	if len(f.measurements) == 0 {
+		return ClockOffset{}, false
+	}
+
+	return slices.MinFunc(f.measurements, func(a, b ClockOffset) int {
+		return cmp.Compare(a.Delay, b.Delay)
+	}), true
+}
@@ -0,0 +1,167 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The content type of signal values encoded in CBOR.
+const cborContentType = "application/cbor"
+
+// The largest frame accepted (after decompression).
+const maxFrameSize = 1 << 20
+
+// The compression algorithms the recorder supports, in order of preference.
+var compressionAlgorithms = []string{"deflate"}
+
+// frameCodec reads and writes JSON-RPC objects framed with Content-Length
+// headers, as jsonrpc2.VSCodeObjectCodec. Devices streaming binary values (see
+// CapabilityBinaryStreaming) interleave frames of signal values encoded in CBOR,
+// with the Content-Type application/cbor, which are passed to values rather
+// than decoded as JSON-RPC objects. Frames with a Content-Encoding (see
+// CapabilityCompression) are decompressed.
+type frameCodec struct {
+	values func(frame []byte)
+	// Captures the frames, if enabled (see WithCapture).
+	capture *capture
+}
+
+func (c frameCodec) WriteObject(stream io.Writer, obj interface{}) error {
+	if c.capture != nil {
+		if frame, err := json.Marshal(obj); err == nil {
+			c.capture.frame(true, "", frame)
+		}
+	}
+	return jsonrpc2.VSCodeObjectCodec{}.WriteObject(stream, obj)
+}
+
+func (c frameCodec) ReadObject(stream *bufio.Reader, v interface{}) error {
+	for {
+		header, err := readHeader(stream)
+		if err != nil {
+			return err
+		}
+
+		if header.contentLength > maxFrameSize {
+			return fmt.Errorf("frame too large: %d bytes", header.contentLength)
+		}
+
+		frame := make([]byte, header.contentLength)
+		if _, err := io.ReadFull(stream, frame); err != nil {
+			return err
+		}
+
+		frame, err = decompress(frame, header.contentEncoding)
+		if err != nil {
+			return err
+		}
+
+		if c.capture != nil {
+			contentType := header.contentType
+			if contentType == "application/json" {
+				contentType = ""
+			}
+			c.capture.frame(false, contentType, frame)
+		}
+
+		if header.contentType != cborContentType {
+			return json.Unmarshal(frame, v)
+		}
+		c.values(frame)
+	}
+}
+
+// frameHeader is the header of a frame.
+type frameHeader struct {
+	contentLength   uint64
+	contentType     string
+	contentEncoding string
+}
+
+// readHeader reads the header of a frame.
+func readHeader(stream *bufio.Reader) (frameHeader, error) {
+	var header frameHeader
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			return frameHeader{}, err
+		}
+		if !strings.HasSuffix(line, "\r\n") {
+			return frameHeader{}, errors.New(`line endings must be \r\n`)
+		}
+
+		line = strings.TrimSuffix(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(name) {
+		case "content-length":
+			header.contentLength, err = strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return frameHeader{}, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		case "content-type":
+			header.contentType, _, _ = strings.Cut(value, ";")
+		case "content-encoding":
+			header.contentEncoding = strings.ToLower(value)
+		}
+	}
+
+	if header.contentLength == 0 {
+		return frameHeader{}, errors.New("no Content-Length header found")
+	}
+	return header, nil
+}
+
+// decompress decompresses a frame with a content encoding.
+func decompress(frame []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return frame, nil
+	case "deflate":
+		r := flate.NewReader(bytes.NewReader(frame))
+		defer r.Close()
+
+		// Guard against frames that decompress to far more than they should.
+		decompressed, err := io.ReadAll(io.LimitReader(r, maxFrameSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress frame: %w", err)
+		}
+		if len(decompressed) > maxFrameSize {
+			return nil, fmt.Errorf("decompressed frame too large: more than %d bytes", maxFrameSize)
+		}
+		return decompressed, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %q", encoding)
+	}
+}
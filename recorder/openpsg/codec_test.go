@@ -0,0 +1,143 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frame encodes a frame with the headers.
+func frame(body []byte, headers ...string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	for _, header := range headers {
+		b.WriteString(header + "\r\n")
+	}
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.Bytes()
+}
+
+// deflate compresses data.
+func deflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	w, err := flate.NewWriter(&b, flate.BestCompression)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+// jsonOfSize returns a JSON object of exactly n bytes.
+func jsonOfSize(n int) []byte {
+	const prefix, suffix = `{"padding":"`, `"}`
+	return []byte(prefix + strings.Repeat("x", n-len(prefix)-len(suffix)) + suffix)
+}
+
+func TestFrameCodec(t *testing.T) {
+	tests := []struct {
+		name       string
+		stream     []byte
+		want       map[string]string
+		wantValues []string
+		wantErr    string
+	}{
+		{
+			name:   "JSON",
+			stream: frame([]byte(`{"method":"ping"}`), "Content-Type: application/json"),
+			want:   map[string]string{"method": "ping"},
+		},
+		{
+			name:   "Identity",
+			stream: frame([]byte(`{"method":"ping"}`), "Content-Encoding: identity"),
+			want:   map[string]string{"method": "ping"},
+		},
+		{
+			name:   "Deflate",
+			stream: frame(deflate(t, []byte(`{"method":"ping"}`)), "Content-Encoding: Deflate"),
+			want:   map[string]string{"method": "ping"},
+		},
+		{
+			name:   "Deflate at the limit",
+			stream: frame(deflate(t, jsonOfSize(openpsg.MaxFrameSize)), "Content-Encoding: deflate"),
+			want:   map[string]string{"padding": strings.Repeat("x", openpsg.MaxFrameSize-len(`{"padding":""}`))},
+		},
+		{
+			name: "Deflate beyond the limit",
+			// A small frame decompressing to more than the limit.
+			stream:  frame(deflate(t, jsonOfSize(openpsg.MaxFrameSize+1)), "Content-Encoding: deflate"),
+			wantErr: "decompressed frame too large",
+		},
+		{
+			name:    "Corrupt deflate",
+			stream:  frame([]byte("not deflated"), "Content-Encoding: deflate"),
+			wantErr: "failed to decompress frame",
+		},
+		{
+			name:    "Unknown encoding",
+			stream:  frame([]byte(`{"method":"ping"}`), "Content-Encoding: br"),
+			wantErr: `unsupported content encoding: "br"`,
+		},
+		{
+			name:    "Frame too large",
+			stream:  []byte(fmt.Sprintf("Content-Length: %d\r\n\r\n", openpsg.MaxFrameSize+1)),
+			wantErr: "frame too large",
+		},
+		{
+			name: "Values",
+			// Frames of values are passed on, rather than decoded.
+			stream: append(frame([]byte{0xa0}, "Content-Type: application/cbor"),
+				frame([]byte(`{"method":"ping"}`))...),
+			want:       map[string]string{"method": "ping"},
+			wantValues: []string{"\xa0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var values []string
+			codec := openpsg.NewFrameCodec(func(frame []byte) {
+				values = append(values, string(frame))
+			})
+
+			var obj map[string]string
+			err := codec.ReadObject(bufio.NewReader(bytes.NewReader(tt.stream)), &obj)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, obj)
+			assert.Equal(t, tt.wantValues, values)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// SignalConfiguration configures a signal on a device, with an
+// openpsg.configure request. Unset settings are left as they are.
+type SignalConfiguration struct {
+	// The unique identifier of the signal.
+	ID uint32 `json:"id"`
+	// The sample rate in Hz.
+	SampleRate float64 `json:"sampleRate,omitempty"`
+	// The gain of the signal's amplifier.
+	Gain float64 `json:"gain,omitempty"`
+	// Whether the signal is enabled (disabled signals aren't reported by the
+	// device).
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// MontageConfiguration configures a signal of a montage device before
+// recording, rather than accepting the device's defaults.
+type MontageConfiguration struct {
+	// The signal on the device, by ID or name.
+	Signal     SignalRef `json:"signal" yaml:"signal"`
+	SampleRate float64   `json:"sampleRate,omitempty" yaml:"sampleRate,omitempty"`
+	Gain       float64   `json:"gain,omitempty" yaml:"gain,omitempty"`
+	Enabled    *bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+}
+
+// configurableSource is a source whose signals can be configured (see
+// Client.Configure).
+type configurableSource interface {
+	Configure(ctx context.Context, configs []SignalConfiguration) error
+}
+
+// Configure configures the signals of the device. The configuration is
+// reapplied if the client reconnects. The signals the device reports change to
+// match (see Signals).
+func (c *Client) Configure(ctx context.Context, configs []SignalConfiguration) error {
+	if !c.HasCapability(CapabilityConfigure) {
+		return fmt.Errorf("device doesn't support configuring signals")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	if err := configure(ctx, c.conn(), configs); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.configuration = configs
+	return nil
+}
+
+// configure sends the configuration of the signals to the device.
+func configure(ctx context.Context, rpcConn *jsonrpc2.Conn, configs []SignalConfiguration) error {
+	if err := rpcConn.Call(ctx, "openpsg.configure", configs, nil); err != nil {
+		return fmt.Errorf("failed to configure signals: %w", err)
+	}
+	return nil
+}
+
+// configure returns an opener configuring the signals of the device at addr as
+// given in the montage (if any) whenever it is opened.
+func (m *Montage) configure(addr netip.Addr, open SourceOpener) SourceOpener {
+	if m == nil {
+		return open
+	}
+
+	var configuration []MontageConfiguration
+	for _, device := range m.Devices {
+		if device.Address == addr {
+			configuration = device.Configuration
+		}
+	}
+	if len(configuration) == 0 {
+		return open
+	}
+
+	return func(ctx context.Context) (SignalSource, error) {
+		source, err := open(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := source.(configurableSource)
+		if !ok {
+			_ = source.Close()
+			return nil, fmt.Errorf("source %s can't be configured", addr)
+		}
+
+		signals, err := source.Signals(ctx)
+		if err != nil {
+			_ = source.Close()
+			return nil, fmt.Errorf("failed to get signals: %w", err)
+		}
+
+		configs, err := resolveConfiguration(configuration, signals)
+		if err != nil {
+			_ = source.Close()
+			return nil, err
+		}
+
+		if err := s.Configure(ctx, configs); err != nil {
+			_ = source.Close()
+			return nil, err
+		}
+
+		slog.Debug("Configured device signals", slog.Any("deviceAddr", addr), slog.Any("configuration", configs))
+
+		return source, nil
+	}
+}
+
+// resolveConfiguration resolves the signals of a montage configuration.
+func resolveConfiguration(configuration []MontageConfiguration, signals []Signal) ([]SignalConfiguration, error) {
+	var configs []SignalConfiguration
+	for _, config := range configuration {
+		found := false
+		for _, signal := range signals {
+			if config.Signal.matches(signal) {
+				configs = append(configs, SignalConfiguration{
+					ID:         signal.ID,
+					SampleRate: config.SampleRate,
+					Gain:       config.Gain,
+					Enabled:    config.Enabled,
+				})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("device has no signal %q to configure", config.Signal)
+		}
+	}
+	return configs, nil
+}
@@ -0,0 +1,500 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ConformanceResult is the result of a check of a device's implementation of
+// the protocol.
+type ConformanceResult struct {
+	Check  string
+	Passed bool
+	// Why the check failed (or notes on how it passed).
+	Detail string
+}
+
+// conformanceRun collects the results of the conformance checks.
+type conformanceRun struct {
+	results []ConformanceResult
+}
+
+func (r *conformanceRun) pass(check, detail string) {
+	r.results = append(r.results, ConformanceResult{Check: check, Passed: true, Detail: detail})
+}
+
+func (r *conformanceRun) fail(check, detail string) {
+	r.results = append(r.results, ConformanceResult{Check: check, Detail: detail})
+}
+
+// check records a check as passed if there were no problems, or failed with
+// the problems found.
+func (r *conformanceRun) check(check string, problems []string, detail string) {
+	if len(problems) > 0 {
+		r.fail(check, strings.Join(problems, "; "))
+	} else {
+		r.pass(check, detail)
+	}
+}
+
+// streamedSignal is what was received of a signal while it was streamed.
+type streamedSignal struct {
+	batches []SignalValues
+	values  int
+}
+
+// CheckConformance exercises the implementation of the device protocol of the
+// device at addrPort (eg. new firmware), checking signal enumeration, starting
+// and stopping signals, the timestamps, batch sizes and ranges of streamed
+// values (streaming them for the duration), and how malformed input is
+// handled. The checks after a failure to connect are skipped.
+func CheckConformance(ctx context.Context, addrPort netip.AddrPort, duration time.Duration, opts ...ConnectOption) []ConformanceResult {
+	dial := dialDevice(addrPort, newConnectOptions(opts...))
+
+	var r conformanceRun
+
+	client, err := Connect(ctx, addrPort, opts...)
+	if err != nil {
+		r.fail("Connection", err.Error())
+		return r.results
+	}
+
+	version := client.Version()
+	r.pass("Connection", fmt.Sprintf("protocol version %d, capabilities: %s", version.Version, formatCapabilities(version.Capabilities)))
+
+	signals := checkSignals(ctx, &r, client)
+	if len(signals) > 0 {
+		checkStreaming(ctx, &r, client, signals, duration)
+	}
+
+	_ = client.Close()
+
+	checkMalformedInput(ctx, &r, dial)
+
+	return r.results
+}
+
+// formatCapabilities lists the capabilities of a device (eg. "time, status").
+func formatCapabilities(capabilities []Capability) string {
+	if len(capabilities) == 0 {
+		return "none"
+	}
+
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = string(capability)
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkSignals checks the signals of the device are valid, returning them.
+func checkSignals(ctx context.Context, r *conformanceRun, client *Client) []Signal {
+	deviceSignals, err := client.Signals(ctx)
+	if err != nil {
+		r.fail("Signal enumeration", err.Error())
+		return nil
+	}
+
+	var signals []Signal
+	for _, signal := range deviceSignals {
+		if !isStatusSignal(signal.ID) {
+			signals = append(signals, signal)
+		}
+	}
+
+	var problems []string
+	if len(signals) == 0 {
+		problems = append(problems, "no signals")
+	}
+
+	ids := make(map[uint32]bool)
+	for _, signal := range signals {
+		if ids[signal.ID] {
+			problems = append(problems, fmt.Sprintf("duplicate signal ID %d", signal.ID))
+		}
+		ids[signal.ID] = true
+
+		if isStatusSignal(signal.ID) {
+			problems = append(problems, fmt.Sprintf("signal ID %d is reserved for status signals", signal.ID))
+		}
+		if strings.TrimSpace(signal.Name) == "" {
+			problems = append(problems, fmt.Sprintf("signal %d has no name", signal.ID))
+		}
+		if signal.SampleRate == 0 {
+			problems = append(problems, fmt.Sprintf("signal %q has no sample rate", signal.Name))
+		}
+		if signal.Min >= signal.Max {
+			problems = append(problems, fmt.Sprintf("signal %q has an invalid physical range [%g, %g]", signal.Name, signal.Min, signal.Max))
+		}
+		if err := signal.validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	r.check("Signal enumeration", problems, fmt.Sprintf("%d signals", len(signals)))
+	if len(problems) > 0 {
+		return nil
+	}
+	return signals
+}
+
+// checkStreaming starts the signals, checking the values streamed for the
+// duration, then stops them (checking no more values are sent) and restarts
+// them.
+func checkStreaming(ctx context.Context, r *conformanceRun, client *Client, signals []Signal, duration time.Duration) {
+	signalIDs := make([]uint32, len(signals))
+	for i, signal := range signals {
+		signalIDs[i] = signal.ID
+	}
+
+	if err := client.Start(ctx, signalIDs); err != nil {
+		r.fail("Start", err.Error())
+		return
+	}
+	defer func() {
+		_ = client.Stop(ctx, signalIDs)
+	}()
+
+	// Wait for the first values of each signal.
+	streamed := make(map[uint32]*streamedSignal)
+	receive := func(deadline <-chan time.Time, done func() bool) error {
+		for !done() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline:
+				return nil
+			case values := <-client.SignalValues():
+				s, ok := streamed[values.ID]
+				if !ok {
+					s = &streamedSignal{}
+					streamed[values.ID] = s
+				}
+				s.batches = append(s.batches, values)
+				s.values += len(values.Values)
+			}
+		}
+		return nil
+	}
+
+	allStreamed := func() bool {
+		return len(streamed) >= len(signals)
+	}
+	if err := receive(time.After(timeout), allStreamed); err != nil {
+		return
+	}
+	r.check("Start", missingSignals(signals, streamed), "values received for every signal")
+	if !allStreamed() {
+		return
+	}
+
+	// Stream the signals for the duration.
+	streamed = make(map[uint32]*streamedSignal)
+	start := time.Now()
+	if err := receive(time.After(duration), func() bool { return false }); err != nil {
+		return
+	}
+	elapsed := time.Since(start)
+
+	checkTimestamps(r, signals, streamed)
+	checkSampleRates(r, signals, streamed, elapsed)
+	checkBatches(r, signals, streamed)
+	checkSequences(r, signals, streamed)
+
+	// Values sent before the stop request was processed are drained, then no
+	// more should be sent.
+	if err := client.Stop(ctx, signalIDs); err != nil {
+		r.fail("Stop", err.Error())
+		return
+	}
+	drainValues(ctx, client, time.Second)
+	streamed = make(map[uint32]*streamedSignal)
+	if err := receive(time.After(2*time.Second), func() bool { return false }); err != nil {
+		return
+	}
+
+	var problems []string
+	for _, signal := range signals {
+		if s, ok := streamed[signal.ID]; ok {
+			problems = append(problems, fmt.Sprintf("%d values of %q sent after stopping", s.values, signal.Name))
+		}
+	}
+	r.check("Stop", problems, "no values sent after stopping")
+
+	// The signals must be able to be started again (eg. after a paused
+	// recording).
+	if err := client.Start(ctx, signalIDs); err != nil {
+		r.fail("Restart", err.Error())
+		return
+	}
+	if err := receive(time.After(timeout), allStreamed); err != nil {
+		return
+	}
+	r.check("Restart", missingSignals(signals, streamed), "values received for every signal")
+}
+
+// drainValues discards the values received from the client for a duration.
+func drainValues(ctx context.Context, client *Client, d time.Duration) {
+	deadline := time.After(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			return
+		case <-client.SignalValues():
+		}
+	}
+}
+
+// missingSignals returns problems for the signals no values were received of.
+func missingSignals(signals []Signal, streamed map[uint32]*streamedSignal) []string {
+	var problems []string
+	for _, signal := range signals {
+		if _, ok := streamed[signal.ID]; !ok {
+			problems = append(problems, fmt.Sprintf("no values received of %q", signal.Name))
+		}
+	}
+	return problems
+}
+
+// checkTimestamps checks the timestamps of each signal increase, with each
+// batch following on from the last (within two sample periods).
+func checkTimestamps(r *conformanceRun, signals []Signal, streamed map[uint32]*streamedSignal) {
+	var problems []string
+	for _, signal := range signals {
+		s, ok := streamed[signal.ID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("no values received of %q", signal.Name))
+			continue
+		}
+
+		period := time.Duration(float64(time.Second) / float64(signal.SampleRate))
+		tolerance := max(2*period, 10*time.Millisecond)
+		for i := 1; i < len(s.batches); i++ {
+			prev, batch := s.batches[i-1], s.batches[i]
+			if !batch.Timestamp.After(prev.Timestamp) {
+				problems = append(problems, fmt.Sprintf("timestamp of %q went backwards (%s after %s)",
+					signal.Name, batch.Timestamp.Format(time.RFC3339Nano), prev.Timestamp.Format(time.RFC3339Nano)))
+				break
+			}
+
+			expected := prev.Timestamp.Add(time.Duration(len(prev.Values)) * period)
+			if gap := batch.Timestamp.Sub(expected); gap > tolerance || gap < -tolerance {
+				problems = append(problems, fmt.Sprintf("timestamp of %q is %s off the sample rate", signal.Name, gap))
+				break
+			}
+		}
+	}
+	r.check("Timestamp monotonicity", problems, "timestamps increase with the sample rate")
+}
+
+// checkSampleRates checks the values of each signal were sent at its sample
+// rate (within 10%).
+func checkSampleRates(r *conformanceRun, signals []Signal, streamed map[uint32]*streamedSignal, elapsed time.Duration) {
+	var problems []string
+	for _, signal := range signals {
+		var values int
+		if s, ok := streamed[signal.ID]; ok {
+			values = s.values
+		}
+
+		rate := float64(values) / elapsed.Seconds()
+		if math.Abs(rate-float64(signal.SampleRate)) > 0.1*float64(signal.SampleRate) {
+			problems = append(problems, fmt.Sprintf("%q sent at %.1f Hz rather than %d Hz", signal.Name, rate, signal.SampleRate))
+		}
+	}
+	r.check("Sample rate", problems, "values sent at the sample rate of each signal")
+}
+
+// checkBatches checks the batches of values are neither empty nor longer than
+// a second (delaying them), and the values are within the digital range of
+// their signal.
+func checkBatches(r *conformanceRun, signals []Signal, streamed map[uint32]*streamedSignal) {
+	var sizeProblems, rangeProblems []string
+	var largest int
+	for _, signal := range signals {
+		s, ok := streamed[signal.ID]
+		if !ok {
+			continue
+		}
+
+		dmin, dmax := signal.digitalRange()
+		var outOfRange int
+		for _, batch := range s.batches {
+			largest = max(largest, len(batch.Values))
+			if len(batch.Values) == 0 {
+				sizeProblems = append(sizeProblems, fmt.Sprintf("empty batch of %q", signal.Name))
+			} else if len(batch.Values) > int(signal.SampleRate) {
+				sizeProblems = append(sizeProblems, fmt.Sprintf("batch of %d values of %q is longer than a second", len(batch.Values), signal.Name))
+			}
+
+			if signal.sampleFormat() == SampleFormatFloat32 {
+				continue
+			}
+			for _, value := range batch.Values {
+				if value < float64(dmin) || value > float64(dmax) || value != math.Trunc(value) {
+					outOfRange++
+				}
+			}
+		}
+
+		if outOfRange > 0 {
+			rangeProblems = append(rangeProblems, fmt.Sprintf("%d values of %q outside of the digital range [%d, %d]", outOfRange, signal.Name, dmin, dmax))
+		}
+	}
+
+	r.check("Batch sizes", slices.Compact(sizeProblems), fmt.Sprintf("at most %d values per batch", largest))
+	r.check("Value range", rangeProblems, "values within the digital range of each signal")
+}
+
+// checkSequences checks the sequence numbers of each signal, if sent, increase
+// by one with each batch.
+func checkSequences(r *conformanceRun, signals []Signal, streamed map[uint32]*streamedSignal) {
+	var problems []string
+	var sent bool
+	for _, signal := range signals {
+		s, ok := streamed[signal.ID]
+		if !ok {
+			continue
+		}
+
+		for i, batch := range s.batches {
+			if batch.Seq == nil {
+				if sent {
+					problems = append(problems, fmt.Sprintf("batch of %q without a sequence number", signal.Name))
+				}
+				continue
+			}
+			sent = true
+
+			if i > 0 && s.batches[i-1].Seq != nil && uint32(*batch.Seq) != uint32(*s.batches[i-1].Seq)+1 {
+				problems = append(problems, fmt.Sprintf("sequence number of %q jumped from %d to %d", signal.Name, *s.batches[i-1].Seq, *batch.Seq))
+				break
+			}
+		}
+	}
+
+	detail := "sequence numbers increase by one"
+	if !sent {
+		detail = "no sequence numbers sent"
+	}
+	r.check("Sequence numbers", slices.Compact(problems), detail)
+}
+
+// checkMalformedInput sends malformed requests to the device over a fresh
+// connection, checking it responds with errors (or ignores them) and keeps
+// responding.
+func checkMalformedInput(ctx context.Context, r *conformanceRun, dial func(ctx context.Context) (io.ReadWriteCloser, error)) {
+	stream, rpcConn, err := dialRPC(ctx, dial)
+	if err != nil {
+		r.fail("Malformed JSON", err.Error())
+		return
+	}
+	defer func() {
+		_ = rpcConn.Close()
+	}()
+
+	// A truncated JSON object.
+	body := `{"jsonrpc": "2.0", "id": 1, "method": `
+	if _, err := fmt.Fprintf(stream, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		r.fail("Malformed JSON", fmt.Sprintf("failed to send: %v", err))
+		return
+	}
+
+	if err := callRPC(ctx, rpcConn, "openpsg.ping", nil); err != nil {
+		// Closing the connection is acceptable, as long as the device accepts
+		// a new one.
+		_ = rpcConn.Close()
+
+		_, rpcConn, err = dialRPC(ctx, dial)
+		if err == nil {
+			err = callRPC(ctx, rpcConn, "openpsg.ping", nil)
+		}
+		if err != nil {
+			r.fail("Malformed JSON", fmt.Sprintf("device stopped responding: %v", err))
+			return
+		}
+
+		r.pass("Malformed JSON", "connection closed, device still responding")
+	} else {
+		r.pass("Malformed JSON", "device still responding")
+	}
+
+	var rpcErr *jsonrpc2.Error
+	err = callRPC(ctx, rpcConn, "openpsg.conformance", nil)
+	switch {
+	case errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc2.CodeMethodNotFound:
+		r.pass("Unknown method", "method not found error")
+	case err != nil:
+		r.fail("Unknown method", fmt.Sprintf("expected a method not found error, got: %v", err))
+	default:
+		r.fail("Unknown method", "expected a method not found error, got a result")
+	}
+
+	err = callRPC(ctx, rpcConn, "openpsg.start", "not a list of signal IDs")
+	switch {
+	case errors.As(err, &rpcErr):
+		r.pass("Invalid parameters", fmt.Sprintf("error %d: %s", rpcErr.Code, rpcErr.Message))
+	case err != nil:
+		r.fail("Invalid parameters", fmt.Sprintf("expected an error, got: %v", err))
+	default:
+		r.fail("Invalid parameters", "expected an error, got a result")
+	}
+
+	if err := callRPC(ctx, rpcConn, "openpsg.ping", nil); err != nil {
+		r.fail("Responsive after malformed input", err.Error())
+	} else {
+		r.pass("Responsive after malformed input", "device still responding")
+	}
+}
+
+// dialRPC connects to the device over a new connection, for sending requests
+// without a client. Requests from the device are ignored.
+func dialRPC(ctx context.Context, dial func(ctx context.Context) (io.ReadWriteCloser, error)) (io.ReadWriteCloser, *jsonrpc2.Conn, error) {
+	stream, err := dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rpcConn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+			return nil, nil
+		}))
+	return stream, rpcConn, nil
+}
+
+// callRPC calls a method of the device, with a timeout.
+func callRPC(ctx context.Context, rpcConn *jsonrpc2.Conn, method string, params any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return rpcConn.Call(ctx, method, params, nil)
+}
@@ -0,0 +1,370 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+// How often the CPAP logs are checked for new data.
+const cpapPollInterval = 10 * time.Second
+
+// cpapSignal is a signal of a CPAP machine, stored in its log files.
+type cpapSignal struct {
+	Signal
+	// The suffix of the log files holding the signal.
+	suffix string
+	// The label of the signal in the log files.
+	label string
+}
+
+// cpapSignals are the signals of ResMed (AirSense 10/11 and compatible) CPAP
+// machines, from the high rate breathing (BRP) and low rate therapy (PLD) logs.
+var cpapSignals = []cpapSignal{
+	{
+		Signal: Signal{ID: 1, Name: "Resp CPAP flow", Unit: LitresPerSecond, Min: -3, Max: 3,
+			SampleFormat: SampleFormatFloat32, SampleRate: 25},
+		suffix: "_BRP.edf", label: "Flow.40ms",
+	},
+	{
+		Signal: Signal{ID: 2, Name: "CPAP pressure", Unit: CentimetresOfWater, Min: 0, Max: 30,
+			SampleFormat: SampleFormatFloat32, SampleRate: 25},
+		suffix: "_BRP.edf", label: "Press.40ms",
+	},
+	{
+		// Logged every 2 seconds, each value is repeated to store it at 1 Hz.
+		Signal: Signal{ID: 3, Name: "CPAP leak", Unit: LitresPerSecond, Min: 0, Max: 2,
+			SampleFormat: SampleFormatFloat32, SampleRate: 1},
+		suffix: "_PLD.edf", label: "Leak.2s",
+	},
+	{
+		Signal: Signal{ID: 4, Name: "CPAP mask press", Unit: CentimetresOfWater, Min: 0, Max: 30,
+			SampleFormat: SampleFormatFloat32, SampleRate: 1},
+		suffix: "_PLD.edf", label: "MaskPress.2s",
+	},
+}
+
+// CPAPSource streams the flow, pressure and leak of a CPAP machine from the
+// logs it writes to its SD card (eg. a Wi-Fi SD card, or a card reader), for
+// titration studies. The logs are EDF files, which are read as they grow.
+type CPAPSource struct {
+	dir          string
+	signalValues chan SignalValues
+	leadOff      chan LeadOffStatus
+	disconnected chan struct{}
+
+	mu      sync.Mutex
+	enabled map[uint32]bool
+	// Stops polling the logs, nil if not polling.
+	cancel context.CancelFunc
+	done   chan struct{}
+	files  map[string]*cpapLogFile
+}
+
+// cpapLogFile is a log file being read.
+type cpapLogFile struct {
+	f      *os.File
+	reader *edfplus.Reader
+	start  time.Time
+	// The index of each CPAP signal in the file, -1 if not present.
+	indices []int
+}
+
+func init() {
+	RegisterDriver("cpap", func(dir string, _ ...ConnectOption) (netip.Addr, SourceOpener, error) {
+		return netip.Addr{}, OpenCPAP(dir), nil
+	})
+}
+
+// OpenCPAP returns an opener for the CPAP logs in dir (eg. the DATALOG
+// directory of the SD card of the machine).
+func OpenCPAP(dir string) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to open CPAP logs: %w", err)
+		}
+
+		return &CPAPSource{
+			dir:          dir,
+			signalValues: make(chan SignalValues),
+			leadOff:      make(chan LeadOffStatus),
+			disconnected: make(chan struct{}),
+			enabled:      make(map[uint32]bool),
+			files:        make(map[string]*cpapLogFile),
+		}, nil
+	}
+}
+
+// Signals returns the signals of the CPAP machine.
+func (s *CPAPSource) Signals(ctx context.Context) ([]Signal, error) {
+	signals := make([]Signal, len(cpapSignals))
+	for i, signal := range cpapSignals {
+		signals[i] = signal.Signal
+	}
+	return signals, nil
+}
+
+// Start streaming the specified signals, from the data logged after now.
+func (s *CPAPSource) Start(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		s.enabled[id] = true
+	}
+
+	if s.cancel == nil {
+		pollCtx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		s.done = make(chan struct{})
+		go s.poll(pollCtx, time.Now())
+	}
+
+	return nil
+}
+
+// Stop streaming the specified signals.
+func (s *CPAPSource) Stop(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	for _, id := range signalIDs {
+		delete(s.enabled, id)
+	}
+	stop := len(s.enabled) == 0
+	s.mu.Unlock()
+
+	if stop {
+		s.stopPolling()
+	}
+
+	return nil
+}
+
+// Disconnected returns a channel that is closed when the source is closed.
+func (s *CPAPSource) Disconnected() <-chan struct{} {
+	return s.disconnected
+}
+
+// SignalValues returns a channel that will receive the values of the signals.
+func (s *CPAPSource) SignalValues() <-chan SignalValues {
+	return s.signalValues
+}
+
+// LeadOff returns a channel that will never receive lead-off status changes.
+func (s *CPAPSource) LeadOff() <-chan LeadOffStatus {
+	return s.leadOff
+}
+
+func (s *CPAPSource) Close() error {
+	s.stopPolling()
+
+	var err error
+	for path, lf := range s.files {
+		err = errors.Join(err, lf.f.Close())
+		delete(s.files, path)
+	}
+
+	close(s.disconnected)
+	close(s.signalValues)
+	close(s.leadOff)
+
+	return err
+}
+
+func (s *CPAPSource) stopPolling() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+func (s *CPAPSource) poll(ctx context.Context, since time.Time) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(cpapPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.readLogs(ctx, since); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to read CPAP logs", slog.String("dir", s.dir), slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readLogs sends the data records added to log files modified since the
+// specified time.
+func (s *CPAPSource) readLogs(ctx context.Context, since time.Time) error {
+	return filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !(strings.HasSuffix(path, "_BRP.edf") || strings.HasSuffix(path, "_PLD.edf")) {
+			return nil
+		}
+
+		lf, ok := s.files[path]
+		if !ok {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			// Logs from earlier sessions are ignored.
+			if info.ModTime().Before(since) {
+				return nil
+			}
+
+			lf, err = openCPAPLog(path)
+			if err != nil {
+				// The header may not have been written yet.
+				slog.Debug("Failed to open CPAP log", slog.String("path", path), slog.Any("error", err))
+				return nil
+			}
+			s.files[path] = lf
+		}
+
+		return s.readRecords(ctx, lf)
+	})
+}
+
+func openCPAPLog(path string) (*cpapLogFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+
+	reader, err := edfplus.Open(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read log header: %w", err)
+	}
+
+	if d := reader.Header().DataRecordDuration; d <= 0 {
+		_ = f.Close()
+		return nil, fmt.Errorf("invalid data record duration %s", d)
+	}
+
+	lf := &cpapLogFile{
+		f:       f,
+		reader:  reader,
+		start:   reader.Header().StartTime,
+		indices: make([]int, len(cpapSignals)),
+	}
+
+	signals := reader.Signals()
+	for i, signal := range cpapSignals {
+		lf.indices[i] = -1
+		if !strings.HasSuffix(path, signal.suffix) {
+			continue
+		}
+
+		for j, header := range signals {
+			if strings.TrimSpace(header.Label) != signal.label {
+				continue
+			}
+
+			// The rate of the signal is needed to repeat its values.
+			if header.SamplesPerRecord == 0 {
+				_ = f.Close()
+				return nil, fmt.Errorf("signal %q has no samples", signal.label)
+			}
+			lf.indices[i] = j
+		}
+	}
+
+	return lf, nil
+}
+
+// readRecords sends the values of the complete data records added to a log
+// file since it was last read.
+func (s *CPAPSource) readRecords(ctx context.Context, lf *cpapLogFile) error {
+	signals := lf.reader.Signals()
+	for {
+		// A truncated data record is read again once it is complete.
+		record, err := lf.reader.ReadRecord()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read log: %w", err)
+		}
+
+		for i, signal := range cpapSignals {
+			index := lf.indices[i]
+			if index == -1 || !s.isEnabled(signal.ID) {
+				continue
+			}
+
+			// Values logged at less than the sample rate of the signal are
+			// repeated.
+			header := signals[index]
+			rate := float64(header.SamplesPerRecord) / lf.reader.Header().DataRecordDuration.Seconds()
+			repeat := max(int(math.Round(float64(signal.SampleRate)/rate)), 1)
+
+			values := make([]float64, 0, repeat*len(record.Samples[index]))
+			for _, digital := range record.Samples[index] {
+				value := edfplus.DigitalToPhysical(header, digital)
+				for range repeat {
+					values = append(values, value)
+				}
+			}
+
+			select {
+			case s.signalValues <- SignalValues{
+				ID:        signal.ID,
+				Timestamp: lf.start.Add(record.Onset),
+				Values:    values,
+			}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (s *CPAPSource) isEnabled(id uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enabled[id]
+}
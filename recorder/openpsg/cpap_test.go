@@ -0,0 +1,266 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cpapLog is a fixture of a CPAP log file, where each of the labelled signals
+// has the same number of samples per data record.
+type cpapLog struct {
+	labels           []string
+	samplesPerRecord int
+	duration         time.Duration
+	// The values of each signal in each data record.
+	records [][][]float64
+	// The number of bytes of a further, truncated data record.
+	truncated int
+	// Fields of the header to overwrite once it is written, by offset.
+	patch map[int]string
+}
+
+// write writes the log to path, starting at start.
+func (l cpapLog) write(t *testing.T, path string, start time.Time) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	var signals []edf.SignalHeader
+	for _, label := range l.labels {
+		// Digital values are stored as is.
+		signals = append(signals, edf.SignalHeader{
+			Label:            label,
+			PhysicalMin:      math.MinInt16,
+			PhysicalMax:      math.MaxInt16,
+			DigitalMin:       math.MinInt16,
+			DigitalMax:       math.MaxInt16,
+			SamplesPerRecord: l.samplesPerRecord,
+		})
+	}
+	signals = append(signals, edfplus.AnnotationSignal(30))
+
+	ew, err := edfplus.Create(f, edf.Header{
+		Version:            edf.Version0,
+		RecordingID:        edfplus.RecordingIdentification(start, "", "", ""),
+		StartTime:          start,
+		DataRecordDuration: l.duration,
+		Signals:            signals,
+	})
+	require.NoError(t, err)
+
+	for i, record := range l.records {
+		require.NoError(t, ew.WriteRecord(time.Duration(i)*l.duration, record))
+	}
+	require.NoError(t, ew.Close())
+
+	_, err = f.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, l.truncated))
+	require.NoError(t, err)
+
+	for offset, value := range l.patch {
+		field := []byte(value)
+		for len(field) < 8 {
+			field = append(field, ' ')
+		}
+		_, err = f.WriteAt(field, int64(offset))
+		require.NoError(t, err)
+	}
+}
+
+func TestCPAPLog(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.Local)
+
+	// The offsets of the data record duration and of the samples per data
+	// record of the first signal, in a header with two signals (one of them
+	// the annotations).
+	const durationOffset, samplesOffset = 244, 256 + 2*216
+
+	tests := []struct {
+		name    string
+		suffix  string
+		log     cpapLog
+		want    []openpsg.SignalValues
+		wantErr bool
+	}{
+		{
+			name:   "Breathing",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms", "Press.40ms"},
+				samplesPerRecord: 2,
+				duration:         80 * time.Millisecond,
+				records: [][][]float64{
+					{{1, -1}, {10, 11}},
+					{{2, -2}, {12, 13}},
+				},
+			},
+			want: []openpsg.SignalValues{
+				{ID: 1, Timestamp: start, Values: []float64{1, -1}},
+				{ID: 2, Timestamp: start, Values: []float64{10, 11}},
+				{ID: 1, Timestamp: start.Add(80 * time.Millisecond), Values: []float64{2, -2}},
+				{ID: 2, Timestamp: start.Add(80 * time.Millisecond), Values: []float64{12, 13}},
+			},
+		},
+		{
+			name:   "Therapy",
+			suffix: "_PLD.edf",
+			// Values logged every 2 seconds are repeated.
+			log: cpapLog{
+				labels:           []string{"Leak.2s", "MaskPress.2s"},
+				samplesPerRecord: 2,
+				duration:         4 * time.Second,
+				records: [][][]float64{
+					{{1, 2}, {8, 9}},
+				},
+			},
+			want: []openpsg.SignalValues{
+				{ID: 3, Timestamp: start, Values: []float64{1, 1, 2, 2}},
+				{ID: 4, Timestamp: start, Values: []float64{8, 8, 9, 9}},
+			},
+		},
+		{
+			name:   "Other signals",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms", "TrigCycEvt.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				records:          [][][]float64{{{1}, {0}}},
+			},
+			want: []openpsg.SignalValues{
+				{ID: 1, Timestamp: start, Values: []float64{1}},
+			},
+		},
+		{
+			name:   "Signals of another log",
+			suffix: "_PLD.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				records:          [][][]float64{{{1}}},
+			},
+		},
+		{
+			name:   "Truncated record",
+			suffix: "_BRP.edf",
+			// The truncated data record is read once it is complete.
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				records:          [][][]float64{{{1}}},
+				truncated:        10,
+				patch:            map[int]string{236: "-1"},
+			},
+			want: []openpsg.SignalValues{
+				{ID: 1, Timestamp: start, Values: []float64{1}},
+			},
+		},
+		{
+			name:   "No data records",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+			},
+		},
+		{
+			name:   "No samples",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				patch:            map[int]string{samplesOffset: "0"},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Negative samples",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				records:          [][][]float64{{{1}}},
+				patch:            map[int]string{samplesOffset: "-1"},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "No data record duration",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				records:          [][][]float64{{{1}}},
+				patch:            map[int]string{durationOffset: "0"},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Truncated header",
+			suffix: "_BRP.edf",
+			log: cpapLog{
+				labels:           []string{"Flow.40ms"},
+				samplesPerRecord: 1,
+				duration:         40 * time.Millisecond,
+				// The header claims more signals than it holds.
+				patch: map[int]string{184: "1024", 252: "3"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "20250101_223000"+tt.suffix)
+			tt.log.write(t, path, start)
+
+			values, err := openpsg.ReadCPAPLog(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
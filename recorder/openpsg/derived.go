@@ -0,0 +1,272 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DerivedChannel is a signal computed in real time from recorded signals (eg. a
+// RIP sum or a bipolar EEG derivation), and stored as an additional EDF signal.
+type DerivedChannel struct {
+	// The label of the derived signal in the EDF file.
+	Label string `json:"label" yaml:"label"`
+	// The labels of the signals it is computed from.
+	Inputs []string `json:"inputs" yaml:"inputs"`
+	// The weight of each input in the sum (1 by default), eg. [1, -1] for a
+	// bipolar derivation.
+	Weights []float64 `json:"weights,omitempty" yaml:"weights,omitempty"`
+	// Applied to the weighted sum of the inputs.
+	Transform DerivedTransform `json:"transform,omitempty" yaml:"transform,omitempty"`
+	// Multiplies the (transformed) value, 1 if not set.
+	Scale float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	// The unit of the derived signal (the unit of the first input by default).
+	Unit           Unit           `json:"unit,omitempty" yaml:"unit,omitempty"`
+	TransducerType TransducerType `json:"transducerType,omitempty" yaml:"transducerType,omitempty"`
+	// The physical range of the derived signal, derived from the range of the
+	// inputs if not set.
+	Min float32 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max float32 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// DerivedTransform is a transform applied to the value of a derived signal.
+type DerivedTransform string
+
+const (
+	// DerivedTransformNone leaves the value as is.
+	DerivedTransformNone DerivedTransform = ""
+	// DerivedTransformSqrt takes the signed square root of the value, which
+	// linearizes airflow measured by a nasal pressure transducer.
+	DerivedTransformSqrt DerivedTransform = "sqrt"
+	// DerivedTransformPosition classifies the body position (see BodyPosition)
+	// from three accelerometer inputs (in any unit), in the order of the
+	// superior (towards the head), left and anterior (out of the chest) axes of
+	// the sensor. The weights can flip or scale axes, the scale is ignored.
+	DerivedTransformPosition DerivedTransform = "position"
+)
+
+// BodyPosition is the code of a body position, as stored in a position signal.
+type BodyPosition int
+
+const (
+	BodyPositionUnknown BodyPosition = iota
+	BodyPositionSupine
+	BodyPositionProne
+	BodyPositionLeft
+	BodyPositionRight
+	BodyPositionUpright
+)
+
+// bodyPosition classifies the body position from the acceleration (which
+// points away from the ground at rest) along the superior, left and anterior
+// axes of a sensor worn on the chest.
+func bodyPosition(superior, left, anterior float64) BodyPosition {
+	switch {
+	case math.Abs(superior) >= math.Abs(left) && math.Abs(superior) >= math.Abs(anterior):
+		if superior > 0 {
+			return BodyPositionUpright
+		}
+		return BodyPositionUnknown
+	case math.Abs(anterior) >= math.Abs(left):
+		if anterior > 0 {
+			return BodyPositionSupine
+		}
+		return BodyPositionProne
+	case left > 0:
+		return BodyPositionRight
+	default:
+		return BodyPositionLeft
+	}
+}
+
+// accelerometerAxis matches the name of an accelerometer signal, capturing the
+// name of the accelerometer and the axis (eg. "Accel X").
+var accelerometerAxis = regexp.MustCompile(`(?i)^(.*acc.*?)[ _-]?([xyz])$`)
+
+// accelerometerPosition returns a position channel derived from the first
+// accelerometer with signals for all three axes, unless one of the channels
+// is already a position channel.
+func accelerometerPosition(signals []Signal, channels []DerivedChannel) []DerivedChannel {
+	for _, channel := range channels {
+		if channel.Transform == DerivedTransformPosition {
+			return nil
+		}
+	}
+
+	var accelerometers []string
+	axes := make(map[string][3]string)
+	for _, signal := range signals {
+		matches := accelerometerAxis.FindStringSubmatch(signal.Name)
+		if matches == nil {
+			continue
+		}
+
+		name := strings.ToLower(matches[1])
+		if _, ok := axes[name]; !ok {
+			accelerometers = append(accelerometers, name)
+		}
+
+		inputs := axes[name]
+		inputs[strings.IndexByte("xyz", strings.ToLower(matches[2])[0])] = signal.Name
+		axes[name] = inputs
+	}
+
+	for _, name := range accelerometers {
+		inputs := axes[name]
+		if slices.Contains(inputs[:], "") {
+			continue
+		}
+
+		return []DerivedChannel{{
+			Label:     "Position",
+			Inputs:    inputs[:],
+			Transform: DerivedTransformPosition,
+		}}
+	}
+
+	return nil
+}
+
+// derivedSignal is a derived channel with its inputs resolved.
+type derivedSignal struct {
+	channel  DerivedChannel
+	inputs   []int
+	weights  []float64
+	min, max float64
+	unit     Unit
+	// The sample rate shared by the inputs.
+	sampleRate uint32
+}
+
+// resolveDerived resolves the inputs of each derived channel to the indices of
+// recorded signals, with the physical range of each signal in ranges.
+func resolveDerived(channels []DerivedChannel, signals []Signal, ranges [][2]float64) ([]derivedSignal, error) {
+	derived := make([]derivedSignal, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Label == "" || len(channel.Inputs) == 0 {
+			return nil, fmt.Errorf("derived channel %q needs a label and inputs", channel.Label)
+		}
+
+		if len(channel.Weights) > 0 && len(channel.Weights) != len(channel.Inputs) {
+			return nil, fmt.Errorf("derived channel %q needs a weight for each input", channel.Label)
+		}
+
+		switch channel.Transform {
+		case DerivedTransformNone, DerivedTransformSqrt:
+		case DerivedTransformPosition:
+			if len(channel.Inputs) != 3 {
+				return nil, fmt.Errorf("position channel %q needs three accelerometer inputs", channel.Label)
+			}
+		default:
+			return nil, fmt.Errorf("unknown transform %q for derived channel %q", channel.Transform, channel.Label)
+		}
+
+		if channel.Scale < 0 {
+			return nil, fmt.Errorf("derived channel %q has a negative scale", channel.Label)
+		}
+
+		d := derivedSignal{channel: channel, unit: channel.Unit}
+		for i, input := range channel.Inputs {
+			index := slices.IndexFunc(signals, func(signal Signal) bool {
+				return signal.Name == input
+			})
+			if index == -1 {
+				return nil, fmt.Errorf("input %q of derived channel %q is not being recorded", input, channel.Label)
+			}
+
+			if i == 0 {
+				d.sampleRate = signals[index].SampleRate
+				if d.unit == "" {
+					d.unit = signals[index].Unit
+				}
+			} else if signals[index].SampleRate != d.sampleRate {
+				return nil, fmt.Errorf("inputs of derived channel %q have different sample rates", channel.Label)
+			}
+
+			weight := 1.0
+			if len(channel.Weights) > 0 {
+				weight = channel.Weights[i]
+			}
+
+			d.inputs = append(d.inputs, index)
+			d.weights = append(d.weights, weight)
+			d.min += min(weight*ranges[index][0], weight*ranges[index][1])
+			d.max += max(weight*ranges[index][0], weight*ranges[index][1])
+		}
+
+		if channel.Transform == DerivedTransformPosition {
+			d.min, d.max = float64(BodyPositionUnknown), float64(BodyPositionUpright)
+			d.unit = channel.Unit
+		} else {
+			// The transform and scale are monotonically increasing.
+			d.min, d.max = d.transform(d.min), d.transform(d.max)
+		}
+		if channel.Min != 0 || channel.Max != 0 {
+			d.min, d.max = float64(channel.Min), float64(channel.Max)
+		}
+
+		if d.min >= d.max {
+			return nil, fmt.Errorf("derived channel %q has an empty physical range", channel.Label)
+		}
+
+		derived = append(derived, d)
+	}
+
+	return derived, nil
+}
+
+// transform applies the transform and scale of the channel to a value.
+func (d *derivedSignal) transform(value float64) float64 {
+	if d.channel.Transform == DerivedTransformSqrt {
+		value = math.Copysign(math.Sqrt(math.Abs(value)), value)
+	}
+
+	if d.channel.Scale != 0 {
+		value *= d.channel.Scale
+	}
+
+	return value
+}
+
+// compute calculates the values of the derived signal from the values of the
+// recorded signals in a data record. A value is only received if all of its
+// inputs were.
+func (d *derivedSignal) compute(values []float64, received []bool, record [][]float64, recordReceived [][]bool) {
+	for j := range values {
+		var sum float64
+		ok := true
+		for i, input := range d.inputs {
+			sum += d.weights[i] * record[input][j]
+			ok = ok && recordReceived[input][j]
+		}
+		received[j] = ok
+
+		if d.channel.Transform == DerivedTransformPosition {
+			values[j] = float64(bodyPosition(d.weights[0]*record[d.inputs[0]][j],
+				d.weights[1]*record[d.inputs[1]][j], d.weights[2]*record[d.inputs[2]][j]))
+			continue
+		}
+
+		values[j] = d.transform(sum)
+	}
+}
@@ -0,0 +1,62 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyPosition(t *testing.T) {
+	// The components of a vector tilted 30 degrees from an axis.
+	tilt := math.Sin(math.Pi / 6)
+	level := math.Cos(math.Pi / 6)
+
+	tests := []struct {
+		name string
+		// The acceleration along the superior, left and anterior axes.
+		superior, left, anterior float64
+		want                     openpsg.BodyPosition
+	}{
+		{name: "Supine", anterior: 1, want: openpsg.BodyPositionSupine},
+		{name: "Prone", anterior: -1, want: openpsg.BodyPositionProne},
+		// Lying on the left side, the right of the chest faces up.
+		{name: "Left", left: -1, want: openpsg.BodyPositionLeft},
+		{name: "Right", left: 1, want: openpsg.BodyPositionRight},
+		{name: "Upright", superior: 1, want: openpsg.BodyPositionUpright},
+		{name: "Head down", superior: -1, want: openpsg.BodyPositionUnknown},
+		{name: "Supine rolled to the left", left: -tilt, anterior: level, want: openpsg.BodyPositionSupine},
+		{name: "Supine rolled to the right", left: tilt, anterior: level, want: openpsg.BodyPositionSupine},
+		{name: "Left rolled to prone", left: -level, anterior: -tilt, want: openpsg.BodyPositionLeft},
+		{name: "Propped up", superior: tilt, anterior: level, want: openpsg.BodyPositionSupine},
+		{name: "Sitting up", superior: level, anterior: tilt, want: openpsg.BodyPositionUpright},
+		{name: "Reclined halfway", superior: 1, anterior: 1, want: openpsg.BodyPositionUpright},
+		{name: "Between supine and left", left: -1, anterior: 1, want: openpsg.BodyPositionSupine},
+		{name: "Scaled", left: 9.81, anterior: 0.5, want: openpsg.BodyPositionRight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, openpsg.ClassifyPosition(tt.superior, tt.left, tt.anterior))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DeviceFilter selects the devices to record from by the signals they
+// advertise (eg. only devices with pressure transducers), so unrelated devices
+// on the network are left out of a study.
+type DeviceFilter struct {
+	selectors []deviceSelector
+}
+
+type deviceSelector struct {
+	// The field of the signal matched ("signal", "transducer" or "unit").
+	field   string
+	pattern string
+}
+
+// ParseDeviceFilter parses a list of device selectors. Each selector is a glob
+// pattern matching the name of a signal (eg. "EEG*"), or the transducer type or
+// unit of a signal when prefixed by "transducer=" or "unit=" (eg.
+// "transducer=*Pressure*"). Devices advertising a signal matching any of the
+// selectors are selected.
+func ParseDeviceFilter(selectors []string) (*DeviceFilter, error) {
+	var f DeviceFilter
+	for _, selector := range selectors {
+		sel := deviceSelector{field: "signal", pattern: selector}
+		if field, pattern, ok := strings.Cut(selector, "="); ok {
+			sel.field, sel.pattern = strings.ToLower(strings.TrimSpace(field)), pattern
+		}
+
+		switch sel.field {
+		case "signal", "transducer", "unit":
+		default:
+			return nil, fmt.Errorf("invalid device selector %q (expected signal, transducer or unit)", selector)
+		}
+
+		if sel.pattern == "" {
+			return nil, fmt.Errorf("empty device selector %q", selector)
+		}
+		if _, err := path.Match(sel.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid device selector %q: %w", selector, err)
+		}
+
+		f.selectors = append(f.selectors, sel)
+	}
+
+	return &f, nil
+}
+
+// WithDeviceFilter records only the devices selected by the filter. Sources
+// given with WithSource, and devices in the montage, are always recorded.
+func WithDeviceFilter(f *DeviceFilter) RecordOption {
+	return func(o *recordOptions) {
+		o.deviceFilter = f
+	}
+}
+
+// matches returns true if a device advertising the signals is selected.
+func (f *DeviceFilter) matches(signals []Signal) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, signal := range signals {
+		for _, sel := range f.selectors {
+			if sel.matches(signal) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (sel deviceSelector) matches(signal Signal) bool {
+	var value string
+	switch sel.field {
+	case "signal":
+		value = signal.Name
+	case "transducer":
+		value = string(signal.TransducerType)
+	case "unit":
+		value = string(signal.Unit)
+	}
+
+	matched, _ := path.Match(strings.ToLower(sel.pattern), strings.ToLower(value))
+	return matched
+}
@@ -0,0 +1,131 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceFilter(t *testing.T) {
+	headBox := []openpsg.Signal{
+		{Name: "EEG C4-M1", TransducerType: "AgAgCl electrode", Unit: openpsg.Microvolts},
+		{Name: "ECG", TransducerType: "AgAgCl electrode", Unit: openpsg.Millivolts},
+	}
+	airflow := []openpsg.Signal{
+		{Name: "Nasal Pressure", TransducerType: "Pressure transducer", Unit: openpsg.CentimetresOfWater},
+	}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		signals   []openpsg.Signal
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "Signal name",
+			selectors: []string{"ECG"},
+			signals:   headBox,
+			want:      true,
+		},
+		{
+			name:      "Signal pattern",
+			selectors: []string{"eeg*"},
+			signals:   headBox,
+			want:      true,
+		},
+		{
+			name:      "Signal not advertised",
+			selectors: []string{"EEG*"},
+			signals:   airflow,
+			want:      false,
+		},
+		{
+			name:      "Explicit signal field",
+			selectors: []string{"signal=Nasal*"},
+			signals:   airflow,
+			want:      true,
+		},
+		{
+			name:      "Transducer",
+			selectors: []string{"transducer=*pressure*"},
+			signals:   airflow,
+			want:      true,
+		},
+		{
+			name:      "Transducer not advertised",
+			selectors: []string{"Transducer = *pressure*"},
+			signals:   headBox,
+			want:      false,
+		},
+		{
+			name:      "Unit",
+			selectors: []string{"unit=uV"},
+			signals:   headBox,
+			want:      true,
+		},
+		{
+			name:      "Any selector",
+			selectors: []string{"unit=cmH2O", "EEG*"},
+			signals:   headBox,
+			want:      true,
+		},
+		{
+			name:      "No signals",
+			selectors: []string{"*"},
+			want:      false,
+		},
+		{
+			name:      "Unknown field",
+			selectors: []string{"type=EEG"},
+			wantErr:   true,
+		},
+		{
+			name:      "Empty pattern",
+			selectors: []string{"unit="},
+			wantErr:   true,
+		},
+		{
+			name:      "Invalid pattern",
+			selectors: []string{"EEG[C"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openpsg.ParseDeviceFilter(tt.selectors)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.Matches(tt.signals))
+		})
+	}
+
+	t.Run("No filter", func(t *testing.T) {
+		var f *openpsg.DeviceFilter
+		assert.True(t, f.Matches(airflow))
+	})
+}
@@ -20,100 +20,478 @@ package openpsg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
 	"net/netip"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
-	"github.com/OpenPSG/OpenPSG/recorder/internal/termutil"
-	"github.com/olekukonko/tablewriter"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/netutil"
 	"golang.org/x/term"
 )
 
+// ARPSweep finds devices that don't hold a lease from the recorder (eg.
+// statically configured devices, or devices still using an address leased
+// before) by resolving every address of the network prefix with ARP.
+type ARPSweep struct {
+	// The name of the network interface the devices are attached to.
+	Interface string
+	Prefix    netip.Prefix
+}
+
+// probe sweeps the network, probing the devices found that don't hold a lease
+// and aren't already known, and forgetting those no longer found.
+func (s *ARPSweep) probe(ctx context.Context, db leasedb.Store, devices map[string]*discoveredDevice, requireApproval bool, filter *DeviceFilter, opts ...ConnectOption) error {
+	neighbors, err := netutil.ARPSweep(ctx, s.Interface, s.Prefix)
+	if err != nil {
+		if ctx.Err() != nil {
+			return context.Canceled
+		}
+		slog.Warn("Failed to sweep the network for devices", slog.Any("error", err))
+		return nil
+	}
+
+	found := make(map[string]bool)
+	for _, neighbor := range neighbors {
+		addr := neighbor.Addr.String()
+		found[addr] = true
+
+		if _, ok := devices[addr]; ok {
+			continue
+		}
+
+		lease := &leasedb.Lease{MAC: neighbor.MAC.String(), IPAddress: addr}
+		d, err := probeDevice(ctx, db, lease, requireApproval, filter, opts...)
+		if err != nil {
+			return err
+		}
+		d.swept = true
+		devices[addr] = d
+	}
+
+	for addr, d := range devices {
+		if d.swept && !found[addr] {
+			delete(devices, addr)
+		}
+	}
+
+	return nil
+}
+
+// discoveredDevice is a device shown while discovering devices.
+type discoveredDevice struct {
+	lease *leasedb.Lease
+	// Found by an ARP sweep, rather than holding a lease.
+	swept       bool
+	device      string
+	firmware    string
+	signals     []string
+	sampleRates string
+	lineNoise   string
+	battery     string
+	clockOffset string
+	link        string
+	status      string
+	temperature string
+}
+
+func (d *discoveredDevice) online() bool {
+	return d.status == "Online"
+}
+
+// DiscoveredDevice is a device found while discovering devices, as shown to
+// the operator.
+type DiscoveredDevice struct {
+	MAC         string
+	Addr        netip.Addr
+	Hostname    string
+	Name        string
+	Device      string
+	Firmware    string
+	Signals     []string
+	SampleRates string
+	LineNoise   string
+	Battery     string
+	ClockOffset string
+	Link        string
+	Status      string
+	// Whether the device will be recorded from.
+	Online bool
+}
+
+// DiscoveryView shows the devices found while discovering devices, and
+// decides when to stop.
+type DiscoveryView interface {
+	// Show is called with the devices found whenever they change.
+	Show(devices []DiscoveredDevice)
+	// Done is closed once the operator has finished discovering devices.
+	Done() <-chan struct{}
+}
+
 // Discover scans the network for sensor devices and returns a list of their IP addresses.
-func Discover(ctx context.Context, db *leasedb.DB) ([]netip.Addr, error) {
-	discoverComplete := make(chan struct{})
+// Devices are probed as their leases are accepted (or renewed), and removed
+// once their leases are released or expire. Devices that couldn't be
+// connected to are probed again every 5 seconds.
+// If requireApproval is set, devices that haven't been approved (see
+// leasedb.DB.SetApproved) are shown, but not connected to or returned. With an
+// ARP sweep, devices that don't hold a lease are also found. Devices are shown
+// with their names, if named. Devices not selected by the filter (if any) are
+// shown as excluded, and not returned.
+// The devices are shown by the view, or logged as they change (until Enter is
+// pressed) if the view is nil.
+func Discover(ctx context.Context, db leasedb.Store, requireApproval bool, sweep *ARPSweep, names DeviceNames, filter *DeviceFilter, view DiscoveryView, opts ...ConnectOption) ([]netip.Addr, error) {
+	if view == nil {
+		view = newLogView()
+	}
 
-	// Start a goroutine to listen for key presses.
-	go func() {
-		defer close(discoverComplete)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Watched before listing the leases, so no changes are missed.
+	events := db.Watch(watchCtx)
+
+	leases, err := db.ListLeases()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
 
-		_, err := term.ReadPassword(int(os.Stdin.Fd()))
+	// The devices by IP address.
+	devices := make(map[string]*discoveredDevice)
+	for _, lease := range leases {
+		devices[lease.IPAddress], err = probeDevice(ctx, db, lease, requireApproval, filter, opts...)
 		if err != nil {
-			slog.Warn("Failed to read from stdin", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	show := func() []netip.Addr {
+		var shown []DiscoveredDevice
+		var deviceAddrs []netip.Addr
+		for _, d := range sortedDevices(devices) {
+			addr := netip.MustParseAddr(d.lease.IPAddress)
+			shown = append(shown, DiscoveredDevice{
+				MAC:         d.lease.MAC,
+				Addr:        addr,
+				Hostname:    d.lease.Hostname,
+				Name:        names.lookup(addr, d.lease),
+				Device:      d.device,
+				Firmware:    d.firmware,
+				Signals:     d.signals,
+				SampleRates: d.sampleRates,
+				LineNoise:   d.lineNoise,
+				Battery:     d.battery,
+				ClockOffset: d.clockOffset,
+				Link:        d.link,
+				Status:      d.status + d.temperature,
+				Online:      d.online(),
+			})
+
+			if d.online() {
+				deviceAddrs = append(deviceAddrs, addr)
+			}
 		}
-	}()
 
-	// Create a new ASCII table for the current leases
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"MAC Address", "IP Address", "Hostname", "Signals", "Status"})
-	table.SetBorder(false)
+		view.Show(shown)
+
+		return deviceAddrs
+	}
+
+	deviceAddrs := show()
 
-	firstScan := true
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
-	var deviceAddrs []netip.Addr
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, context.Canceled
-		case <-discoverComplete:
+		case <-view.Done():
 			return deviceAddrs, nil
+		case event, ok := <-events:
+			if !ok {
+				return nil, context.Canceled
+			}
+
+			if event.Type == leasedb.LeaseRemoved {
+				if d, ok := devices[event.Lease.IPAddress]; ok && d.lease.MAC == event.Lease.MAC {
+					delete(devices, event.Lease.IPAddress)
+				}
+			} else {
+				devices[event.Lease.IPAddress], err = probeDevice(ctx, db, &event.Lease, requireApproval, filter, opts...)
+				if err != nil {
+					return nil, err
+				}
+			}
 		case <-ticker.C:
+			// Leases are only reaped every few minutes.
+			for addr, d := range devices {
+				if !d.swept && d.lease.ExpiresAt.Before(time.Now()) {
+					delete(devices, addr)
+				}
+			}
+
+			for addr, d := range devices {
+				if d.online() || d.status == "Excluded" {
+					continue
+				}
+
+				devices[addr], err = probeDevice(ctx, db, d.lease, requireApproval, filter, opts...)
+				if err != nil {
+					return nil, err
+				}
+				devices[addr].swept = d.swept
+			}
+
+			if sweep != nil {
+				if err := sweep.probe(ctx, db, devices, requireApproval, filter, opts...); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		deviceAddrs = show()
+	}
+}
+
+// logView logs the devices as they change, until Enter is pressed (for when
+// the devices aren't shown on a dashboard, eg. when stdout isn't a terminal).
+type logView struct {
+	// The rows logged, by IP address.
+	logged map[string][]string
+	done   <-chan struct{}
+}
+
+// The keys the devices are logged with.
+var discoveryKeys = []string{"mac", "ip", "hostname", "name", "device", "firmware", "signals", "sampleRates", "lineNoise", "battery", "clockOffset", "link", "status"}
+
+func newLogView() *logView {
+	slog.Info("Discovering devices, press Enter to stop")
+
+	return &logView{
+		logged: make(map[string][]string),
+		done:   enterPressed(),
+	}
+}
+
+func (v *logView) Show(devices []DiscoveredDevice) {
+	found := make(map[string]bool)
+	for _, d := range devices {
+		row := []string{
+			d.MAC,
+			d.Addr.String(),
+			d.Hostname,
+			d.Name,
+			d.Device,
+			d.Firmware,
+			strings.Join(d.Signals, ", "),
+			d.SampleRates,
+			d.LineNoise,
+			d.Battery,
+			d.ClockOffset,
+			d.Link,
+			d.Status,
+		}
+		found[row[1]] = true
+
+		if !slices.Equal(v.logged[row[1]], row) {
+			attrs := make([]any, len(row))
+			for i, value := range row {
+				attrs[i] = slog.String(discoveryKeys[i], value)
+			}
+			slog.Info("Discovered device", attrs...)
+			v.logged[row[1]] = row
+		}
+	}
+
+	for addr := range v.logged {
+		if !found[addr] {
+			slog.Info("Device left", slog.String("ip", addr))
+			delete(v.logged, addr)
+		}
+	}
+}
+
+func (v *logView) Done() <-chan struct{} {
+	return v.done
+}
+
+// enterPressed returns a channel that is closed once Enter is pressed (or
+// stdin can't be read).
+func enterPressed() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		if _, err := term.ReadPassword(int(os.Stdin.Fd())); err != nil {
+			slog.Warn("Failed to read from stdin", slog.Any("error", err))
 		}
+	}()
+	return done
+}
+
+// sortedDevices returns the devices in order of their IP addresses.
+func sortedDevices(devices map[string]*discoveredDevice) []*discoveredDevice {
+	sorted := slices.Collect(maps.Values(devices))
+	slices.SortFunc(sorted, func(a, b *discoveredDevice) int {
+		return netip.MustParseAddr(a.lease.IPAddress).Compare(netip.MustParseAddr(b.lease.IPAddress))
+	})
+	return sorted
+}
 
-		leases, err := db.ListLeases()
+// probeDevice connects to the device holding the lease, retrieving its
+// signals, status, clock offset and link, and checking it for mains
+// interference. An error is only returned if the approval of the device
+// couldn't be checked.
+func probeDevice(ctx context.Context, db leasedb.Store, lease *leasedb.Lease, requireApproval bool, filter *DeviceFilter, opts ...ConnectOption) (*discoveredDevice, error) {
+	d := &discoveredDevice{
+		lease:       lease,
+		device:      "-",
+		firmware:    "-",
+		sampleRates: "-",
+		lineNoise:   "-",
+		battery:     "-",
+		clockOffset: "-",
+		link:        "-",
+		status:      "Offline",
+	}
+
+	if requireApproval {
+		approved, err := isApproved(db, lease)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list leases: %w", err)
+			return nil, err
+		}
+		if !approved {
+			d.status = "Unapproved"
+			return d, nil
 		}
+	}
 
-		if !firstScan {
-			table.ClearRows()
+	deviceAddr := netip.MustParseAddr(lease.IPAddress)
+	client, err := Connect(ctx, netip.AddrPortFrom(deviceAddr, 80), opts...)
+	if err != nil {
+		if errors.Is(err, ErrIncompatibleDevice) {
+			d.status = "Incompatible"
+		} else if errors.Is(err, ErrUnauthorizedDevice) {
+			d.status = "Unauthorized"
 		}
+		return d, nil
+	}
+	defer client.Close()
 
-		deviceAddrs = deviceAddrs[:0]
+	signals, err := client.Signals(ctx)
+	if err != nil {
+		return d, nil
+	}
+	for _, signal := range signals {
+		d.signals = append(d.signals, signal.Name)
+	}
+	if len(signals) > 0 {
+		d.sampleRates = sampleRates(signals)
+	}
 
-		for _, lease := range leases {
-			deviceAddr := netip.MustParseAddr(lease.IPAddress)
+	if !filter.matches(signals) {
+		d.status = "Excluded"
+		return d, nil
+	}
+	d.status = "Online"
 
-			var signalNames []string
-			status := "Offline"
+	info, err := client.Info(ctx)
+	if err != nil {
+		slog.Debug("Failed to identify device", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+	} else {
+		if device := (DeviceInfo{Model: info.Model, SerialNumber: info.SerialNumber}).String(); device != "" {
+			d.device = device
+		}
+		if info.FirmwareVersion != "" {
+			d.firmware = info.FirmwareVersion
+		}
+	}
 
-			client, err := Connect(ctx, netip.AddrPortFrom(deviceAddr, 80))
-			if err == nil {
-				signals, err := client.Signals(ctx)
-				_ = client.Close()
-				if err == nil {
-					for _, signal := range signals {
-						signalNames = append(signalNames, signal.Name)
-					}
-					status = "Online"
-				}
-			}
+	offset, ok, err := client.MeasureClockOffset(ctx)
+	if err != nil {
+		slog.Debug("Failed to measure clock offset", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+	} else if ok {
+		d.clockOffset = formatClockOffset(offset.Offset)
+	}
 
-			table.Append([]string{
-				lease.MAC,
-				lease.IPAddress,
-				lease.Hostname,
-				strings.Join(signalNames, ", "),
-				status,
-			})
+	link, err := client.Link(ctx)
+	if err != nil {
+		slog.Debug("Failed to get device link", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+	} else if link != (LinkInfo{}) {
+		d.link = link.String()
+	}
 
-			if status == "Online" {
-				deviceAddrs = append(deviceAddrs, deviceAddr)
-			}
+	// Check for mains interference, so grounding can be fixed before
+	// recording starts.
+	noisy, err := measureLineNoise(ctx, client, signals, DefaultLineNoiseThreshold)
+	switch {
+	case err != nil:
+		slog.Debug("Failed to measure line noise", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+	case len(noisy) > 0:
+		d.lineNoise = strings.Join(noisy, ", ")
+	default:
+		d.lineNoise = "OK"
+	}
+
+	if deviceStatus, ok := client.Status(); ok {
+		if deviceStatus.Battery != nil {
+			d.battery = fmt.Sprintf("%.0f%%", *deviceStatus.Battery)
 		}
+		if deviceStatus.Temperature != nil {
+			d.temperature = fmt.Sprintf(" (%.1f°C)", *deviceStatus.Temperature)
+		}
+	}
 
-		if !firstScan {
-			termutil.ClearLines(table.NumLines() + 3)
+	return d, nil
+}
+
+// sampleRates summarizes the sample rates of the signals, from fastest to
+// slowest (eg. "256, 100, 25 Hz").
+func sampleRates(signals []Signal) string {
+	var rates []uint32
+	for _, signal := range signals {
+		if !slices.Contains(rates, signal.SampleRate) {
+			rates = append(rates, signal.SampleRate)
 		}
+	}
+	slices.Sort(rates)
+	slices.Reverse(rates)
+
+	parts := make([]string, len(rates))
+	for i, rate := range rates {
+		parts[i] = strconv.FormatUint(uint64(rate), 10)
+	}
+	return strings.Join(parts, ", ") + " Hz"
+}
+
+// formatClockOffset formats the offset of a device's clock to the nearest
+// millisecond, signed (eg. "+12ms", or "-3ms" if the device's clock is
+// behind).
+func formatClockOffset(offset time.Duration) string {
+	offset = offset.Round(time.Millisecond)
+	if offset >= 0 {
+		return "+" + offset.String()
+	}
+	return offset.String()
+}
+
+// isApproved returns whether the device holding the lease has been approved.
+func isApproved(db leasedb.Store, lease *leasedb.Lease) (bool, error) {
+	mac, err := net.ParseMAC(lease.MAC)
+	if err != nil {
+		return false, fmt.Errorf("invalid MAC address in lease: %w", err)
+	}
 
-		table.Render()
-		fmt.Println("Press Enter to stop scanning for devices ...")
-		firstScan = false
+	approved, err := db.IsApproved(mac)
+	if err != nil {
+		return false, fmt.Errorf("failed to check device approval: %w", err)
 	}
+	return approved, nil
 }
@@ -0,0 +1,114 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// Measured drift beyond this is assumed to be caused by missing (or
+	// delayed) batches rather than the clock, and is ignored. Crystal
+	// oscillators are typically within 100 ppm.
+	maxClockDrift = 0.005
+	// The weight given to each new measurement of the drift.
+	driftSmoothing = 0.01
+)
+
+// WithDriftCompensation resamples signal values to their nominal sample rate,
+// correcting for the drift of each device's sample clock relative to its
+// timestamps. Otherwise the samples of a drifting device overlap (or leave
+// holes between) consecutive batches.
+func WithDriftCompensation() RecordOption {
+	return func(o *recordOptions) {
+		o.driftCompensation = true
+	}
+}
+
+// driftEstimator estimates the actual sample rate of a signal from the number
+// of values received between the timestamps of consecutive batches.
+type driftEstimator struct {
+	sampleRate float64
+	// The timestamp and number of values of the latest batch.
+	last      time.Time
+	lastCount int
+	// The ratio of the actual to the nominal sample rate (zero until measured).
+	ratio float64
+}
+
+func newDriftEstimator(sampleRate float64) *driftEstimator {
+	return &driftEstimator{sampleRate: sampleRate}
+}
+
+// observe updates the estimate with a batch of n values starting at timestamp.
+// Batches that arrive out of order are ignored.
+func (d *driftEstimator) observe(timestamp time.Time, n int) {
+	if !timestamp.After(d.last) {
+		return
+	}
+
+	if !d.last.IsZero() {
+		ratio := float64(d.lastCount) / timestamp.Sub(d.last).Seconds() / d.sampleRate
+		if math.Abs(ratio-1) <= maxClockDrift {
+			if d.ratio == 0 {
+				d.ratio = ratio
+			} else {
+				d.ratio += driftSmoothing * (ratio - d.ratio)
+			}
+		}
+	}
+
+	d.last, d.lastCount = timestamp, n
+}
+
+// ppm returns the estimated drift in parts per million.
+func (d *driftEstimator) ppm() float64 {
+	if d.ratio == 0 {
+		return 0
+	}
+	return (d.ratio - 1) * 1e6
+}
+
+// resample linearly interpolates values sampled at the actual sample rate onto
+// the nominal sample rate, appending them to dst.
+func (d *driftEstimator) resample(dst, values []float64) []float64 {
+	if d.ratio == 0 || len(values) < 2 {
+		return append(dst, values...)
+	}
+
+	n := int(math.Round(float64(len(values)) / d.ratio))
+	dst = slices.Grow(dst, n)
+	resampled := dst[len(dst) : len(dst)+n]
+	for i := range resampled {
+		// The position of the nominal sample in the actual samples.
+		pos := float64(i) * d.ratio
+		j := int(pos)
+		if j >= len(values)-1 {
+			resampled[i] = values[len(values)-1]
+			continue
+		}
+
+		frac := pos - float64(j)
+		resampled[i] = values[j] + frac*(values[j+1]-values[j])
+	}
+
+	return dst[:len(dst)+n]
+}
@@ -0,0 +1,123 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriftEstimator(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// The time between the timestamps of consecutive batches of 100 values.
+		intervals []time.Duration
+		wantPPM   float64
+	}{
+		{
+			name:      "No drift",
+			intervals: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:      "Fast clock",
+			intervals: []time.Duration{999900 * time.Microsecond, 999900 * time.Microsecond},
+			wantPPM:   100,
+		},
+		{
+			name:      "Slow clock",
+			intervals: []time.Duration{1000050 * time.Microsecond, 1000050 * time.Microsecond},
+			wantPPM:   -50,
+		},
+		{
+			// A missing batch would otherwise look like a clock at half speed.
+			name:      "Missing batch",
+			intervals: []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+		{
+			name:      "Out of order",
+			intervals: []time.Duration{-time.Second, -time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := openpsg.NewDriftEstimator(100)
+
+			timestamp := start
+			d.Observe(timestamp, 100)
+			for _, interval := range tt.intervals {
+				timestamp = timestamp.Add(interval)
+				d.Observe(timestamp, 100)
+			}
+
+			assert.InDelta(t, tt.wantPPM, d.PPM(), 0.1)
+		})
+	}
+}
+
+func TestDriftEstimatorSmoothing(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	d := openpsg.NewDriftEstimator(100)
+	d.Observe(start, 100)
+	d.Observe(start.Add(999900*time.Microsecond), 100)
+	require.InDelta(t, 100, d.PPM(), 0.1)
+
+	// A batch arriving 2ms late only moves the estimate by a hundredth of
+	// its apparent drift.
+	d.Observe(start.Add(1999900*time.Microsecond+2*time.Millisecond), 100)
+	late := (1/1.002 - 1) * 1e6
+	assert.InDelta(t, 100+0.01*(late-100), d.PPM(), 0.1)
+
+	// A batch arriving 10ms late is assumed to be delayed and is ignored.
+	ppm := d.PPM()
+	d.Observe(start.Add(3001900*time.Microsecond+10*time.Millisecond), 100)
+	assert.Equal(t, ppm, d.PPM())
+}
+
+func TestDriftEstimatorResample(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	ramp := make([]float64, 1000)
+	for i := range ramp {
+		ramp[i] = float64(i)
+	}
+
+	// Until the drift is measured, values are passed through.
+	d := openpsg.NewDriftEstimator(1000)
+	assert.Equal(t, []float64{-1, 0, 1, 2}, d.Resample([]float64{-1}, ramp[:3]))
+
+	// A clock running 0.1% fast produces 1001 values in what should have
+	// been a second, resampled to 1000.
+	d.Observe(start, 1001)
+	d.Observe(start.Add(time.Second), 1000)
+	require.InDelta(t, 1000, d.PPM(), 0.1)
+
+	resampled := d.Resample(nil, ramp)
+	require.Len(t, resampled, 999)
+	for i, value := range resampled {
+		assert.InDelta(t, float64(i)*1.001, value, 1e-6, "value %d", i)
+	}
+}
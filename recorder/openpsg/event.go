@@ -0,0 +1,83 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"time"
+)
+
+// DeviceEventType is the kind of an event reported by a device.
+type DeviceEventType string
+
+const (
+	// DeviceEventButton is the patient pressing the event button.
+	DeviceEventButton DeviceEventType = "button"
+	// DeviceEventFault is a fault of a sensor (eg. an oximeter probe fault).
+	DeviceEventFault DeviceEventType = "fault"
+)
+
+// DeviceEvent is an event reported by a device, with an openpsg.event
+// notification.
+type DeviceEvent struct {
+	// When the event occurred, by the device's clock.
+	Timestamp time.Time       `json:"timestamp"`
+	Type      DeviceEventType `json:"type"`
+	// An optional description of the event.
+	Text string `json:"text,omitempty"`
+	// The duration of the event in seconds (zero if not applicable).
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// annotation returns the annotation of the event.
+func (e DeviceEvent) annotation() Annotation {
+	var text string
+	switch e.Type {
+	case DeviceEventButton:
+		text = "Patient event"
+	case DeviceEventFault:
+		text = "Sensor fault"
+	default:
+		text = "Device event"
+		if e.Type != "" {
+			text += " (" + string(e.Type) + ")"
+		}
+	}
+	if e.Text != "" {
+		text += ": " + e.Text
+	}
+
+	return Annotation{
+		Time:     e.Timestamp,
+		Duration: time.Duration(e.Duration * float64(time.Second)),
+		Text:     text,
+	}
+}
+
+// eventSource is a source reporting events (see Client.Events).
+type eventSource interface {
+	Events() <-chan DeviceEvent
+}
+
+// events returns the events reported by the source, if it reports them.
+func events(source SignalSource) <-chan DeviceEvent {
+	if s, ok := source.(eventSource); ok {
+		return s.Events()
+	}
+	return nil
+}
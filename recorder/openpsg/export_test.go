@@ -0,0 +1,375 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// The internals of the package, exported for its tests.
+
+const (
+	ReorderTimeout  = reorderTimeout
+	ReorderWindow   = reorderWindow
+	MinCompactBytes = minCompactBytes
+	SpillEntrySize  = spillEntrySize
+)
+
+var (
+	UnpackValues       = unpackValues
+	DecodeBinaryValues = decodeBinaryValues
+	MissingRuns        = missingRuns
+	NewResampler       = newResampler
+	NewDriftEstimator  = newDriftEstimator
+	NewBiquad          = newBiquad
+	ClassifyPosition   = bodyPosition
+	LineNoiseRatio     = lineNoiseRatio
+)
+
+type (
+	SignalBuffer   = signalBuffer
+	SpillFile      = spillFile
+	ReorderBuffer  = reorderBuffer
+	Resampler      = resampler
+	DriftEstimator = driftEstimator
+	Biquad         = biquad
+)
+
+func NewSignalBuffer(start time.Time, sampleRate float64, capacity int) *SignalBuffer {
+	return newSignalBuffer(start, sampleRate, capacity)
+}
+
+func (b *SignalBuffer) Put(timestamp time.Time, values []float64) int {
+	return b.put(timestamp, values)
+}
+
+// Take takes the next n slots of the buffer.
+func (b *SignalBuffer) Take(n int) ([]float64, []bool) {
+	values, received := make([]float64, n), make([]bool, n)
+	b.take(n, values, received)
+	return values, received
+}
+
+// Spill spills the values beyond the capacity of the buffer to a file in dir.
+func (b *SignalBuffer) Spill(dir string) error {
+	spill, err := newSpillFile(dir)
+	if err != nil {
+		return err
+	}
+	b.spill = spill
+	return nil
+}
+
+func (b *SignalBuffer) Fill() (float64, int) {
+	return b.fill()
+}
+
+func NewSpillFile(dir string) (*SpillFile, error) {
+	return newSpillFile(dir)
+}
+
+func (s *SpillFile) Len() int {
+	return s.len()
+}
+
+func (s *SpillFile) Push(index int64, value float64) (bool, error) {
+	return s.push(index, value)
+}
+
+func (s *SpillFile) Peek() (int64, float64, error) {
+	return s.peek()
+}
+
+func (s *SpillFile) Pop() error {
+	return s.pop()
+}
+
+// Size returns the size of the file.
+func (s *SpillFile) Size() (int64, error) {
+	info, err := s.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+var VerifyPinnedCertificate = verifyPinnedCertificate
+
+const MaxFrameSize = maxFrameSize
+
+type FrameCodec = frameCodec
+
+func NewFrameCodec(values func(frame []byte)) FrameCodec {
+	return frameCodec{values: values}
+}
+
+// OximeterSample is a sample decoded from the byte stream of an oximeter.
+type OximeterSample struct {
+	Pleth     float64
+	SpO2      float64
+	Pulse     float64
+	SensorOff bool
+}
+
+// DecodeOximeter decodes the byte stream of an oximeter.
+func DecodeOximeter(protocol OximeterProtocol, data []byte) []OximeterSample {
+	decoder := protocol.decoder()
+
+	var samples []OximeterSample
+	for _, b := range data {
+		if s, ok := decoder.decode(b); ok {
+			samples = append(samples, OximeterSample{Pleth: s.pleth, SpO2: s.spo2, Pulse: s.pulse, SensorOff: s.sensorOff})
+		}
+	}
+	return samples
+}
+
+type ANTHeartRate = antHeartRate
+
+func (h *ANTHeartRate) Update(page []byte, now time.Time) {
+	h.update(page, now)
+}
+
+func (h *ANTHeartRate) Value(now time.Time) float64 {
+	return h.value(now)
+}
+
+// Manufacturer returns the manufacturer ID and serial number of the monitor,
+// once they're known.
+func (h *ANTHeartRate) Manufacturer() (byte, uint16, bool) {
+	return h.manufacturer, h.serial, h.identified
+}
+
+// ReadCPAPLog reads the values of the CPAP signals in the complete data
+// records of a log file.
+func ReadCPAPLog(path string) ([]SignalValues, error) {
+	lf, err := openCPAPLog(path)
+	if err != nil {
+		return nil, err
+	}
+	defer lf.f.Close()
+
+	s := &CPAPSource{
+		signalValues: make(chan SignalValues, 1024),
+		enabled:      make(map[uint32]bool),
+	}
+	for _, signal := range cpapSignals {
+		s.enabled[signal.ID] = true
+	}
+
+	err = s.readRecords(context.Background(), lf)
+	close(s.signalValues)
+
+	var values []SignalValues
+	for sv := range s.signalValues {
+		values = append(values, sv)
+	}
+	return values, err
+}
+
+type DesaturationDetector = desaturationDetector
+
+func NewDesaturationDetector(name string, sampleRate uint32) *DesaturationDetector {
+	return newDesaturationDetector(name, sampleRate)
+}
+
+func (d *DesaturationDetector) Observe(timestamp time.Time, values []float64) []Annotation {
+	return d.observe(timestamp, values)
+}
+
+func (d *DesaturationDetector) Summary() OximetrySummary {
+	return d.summary()
+}
+
+type RespiratoryEventDetector = respiratoryEventDetector
+
+func NewRespiratoryEventDetector(name string, sampleRate uint32) *RespiratoryEventDetector {
+	return newRespiratoryEventDetector(name, sampleRate)
+}
+
+func (d *RespiratoryEventDetector) Observe(timestamp time.Time, values []float64) []Annotation {
+	return d.observe(timestamp, values)
+}
+
+func (f *DeviceFilter) Matches(signals []Signal) bool {
+	return f.matches(signals)
+}
+
+type QualityMonitor = qualityMonitor
+
+func NewQualityMonitor(name string, sampleRate uint32, physicalMin, physicalMax float64) *QualityMonitor {
+	return newQualityMonitor(name, sampleRate, physicalMin, physicalMax)
+}
+
+func (m *QualityMonitor) Observe(values []float64) (SignalQuality, bool) {
+	return m.observe(values)
+}
+
+func (m *QualityMonitor) Current() SignalQuality {
+	return m.current()
+}
+
+type LineNoiseMonitor = lineNoiseMonitor
+
+func NewLineNoiseMonitor(signal Signal, frequency, threshold float64) *LineNoiseMonitor {
+	return newLineNoiseMonitor(signal, frequency, threshold)
+}
+
+func (m *LineNoiseMonitor) Observe(values []float64) (float64, bool) {
+	return m.observe(values)
+}
+
+func (m *LineNoiseMonitor) Noisy() bool {
+	return m.noisy
+}
+
+func NewReorderBuffer() *ReorderBuffer {
+	return &reorderBuffer{pending: make(map[uint32]pendingValues)}
+}
+
+func (b *ReorderBuffer) Add(values SignalValues, now time.Time) bool {
+	return b.add(values, now)
+}
+
+func (b *ReorderBuffer) Release(now time.Time) []SignalValues {
+	return b.release(now)
+}
+
+// NewTestClient creates a client that isn't connected to a device.
+func NewTestClient() *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		name:      "test",
+		ctx:       ctx,
+		cancel:    cancel,
+		sequences: make(map[uint32]*sequence),
+	}
+}
+
+func (c *Client) CheckSequence(id uint32, n uint64) bool {
+	return c.checkSequence(id, n)
+}
+
+func (c *Client) Publish(values SignalValues) bool {
+	return c.publish(values)
+}
+
+// CloseSubscriptions closes the client and its subscriptions.
+func (c *Client) CloseSubscriptions() {
+	c.cancel()
+	c.closeSubscriptions()
+}
+
+func (p GapFill) Fill(values []float64, received []bool, physicalMin float64, last *float64) {
+	p.fill(values, received, physicalMin, last)
+}
+
+func (r *Resampler) Resample(dst []float64, timestamp time.Time, values []float64) (time.Time, []float64) {
+	return r.resample(dst, timestamp, values)
+}
+
+func (r *Resampler) Delay() time.Duration {
+	return r.delay
+}
+
+func (d *DriftEstimator) Observe(timestamp time.Time, n int) {
+	d.observe(timestamp, n)
+}
+
+func (d *DriftEstimator) PPM() float64 {
+	return d.ppm()
+}
+
+func (d *DriftEstimator) Resample(dst, values []float64) []float64 {
+	return d.resample(dst, values)
+}
+
+func (f *Biquad) Process(x float64) float64 {
+	return f.process(x)
+}
+
+// WithDataRecordDuration shortens the data records, so recordings can be
+// tested in seconds.
+func WithDataRecordDuration(d time.Duration) RecordOption {
+	return func(o *recordOptions) {
+		o.recordDuration = d
+	}
+}
+
+type Recording = recording
+
+// NewTestRecording connects to the sources and prepares their signals, as
+// Record does, without starting to record.
+func NewTestRecording(ctx context.Context, opts ...RecordOption) (*Recording, error) {
+	options := recordOptions{recordDuration: dataRecordDuration}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.bufferDuration == 0 {
+		options.bufferDuration = 2 * options.recordDuration
+	}
+
+	r := &recording{options: options}
+	if err := r.connect(ctx, nil); err != nil {
+		r.close()
+		return nil, err
+	}
+	if err := r.prepare(); err != nil {
+		r.close()
+		return nil, err
+	}
+	r.deviceEvents = make(chan Annotation, len(r.devices))
+	return r, nil
+}
+
+func (r *Recording) Close() {
+	r.close()
+}
+
+// Ingest ingests the values of the first device until ctx is cancelled.
+func (r *Recording) Ingest(ctx context.Context) error {
+	return r.ingest(ctx, r.devices[0])
+}
+
+// Write writes the recording to w until ctx is cancelled.
+func (r *Recording) Write(ctx context.Context, w io.WriteSeeker) error {
+	return r.write(ctx, w, "X", "Test")
+}
+
+func (r *Recording) RecordStart() time.Time {
+	return r.recordStart
+}
+
+// Put buffers the values of signal i starting at timestamp.
+func (r *Recording) Put(i int, timestamp time.Time, values []float64) int {
+	return r.signalBuffers[i].put(timestamp, values)
+}
+
+// Take takes the next n slots of the buffer of signal i.
+func (r *Recording) Take(i, n int) ([]float64, []bool) {
+	values, received := make([]float64, n), make([]bool, n)
+	r.signalBuffers[i].take(n, values, received)
+	return values, received
+}
+
+func (r *Recording) Report() RecordingReport {
+	return r.report()
+}
@@ -0,0 +1,168 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strings"
+)
+
+// The quality factors of the filters. High and low-pass filters are second
+// order Butterworth filters, notch filters have a bandwidth of 1/30th of their
+// frequency.
+const (
+	butterworthQ = 1 / math.Sqrt2
+	notchQ       = 30
+)
+
+// ChannelFilters are IIR filters applied to the values of the selected signals
+// before they are stored.
+type ChannelFilters struct {
+	selector signalSelector
+	filters  FilterList
+}
+
+// ParseChannelFilters parses filters for the signals matched by a selector (see
+// ParseSignalSelection), in the form "<selector>=<filters>", where the filters
+// are in the format of the prefiltering field (eg. "EEG*=HP:0.3Hz LP:35Hz N:50Hz").
+func ParseChannelFilters(s string) (*ChannelFilters, error) {
+	selector, filters, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid channel filters %q, expected <selector>=<filters>", s)
+	}
+
+	sel, err := parseSignalSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	cf := &ChannelFilters{selector: sel}
+	if err := cf.filters.parse(strings.TrimSpace(filters)); err != nil {
+		return nil, fmt.Errorf("invalid channel filters %q: %w", s, err)
+	}
+
+	for _, filter := range cf.filters.Filters {
+		switch filter.Kind {
+		case HighPass, LowPass, Notch:
+		default:
+			return nil, fmt.Errorf("unknown filter kind %q", filter.Kind)
+		}
+	}
+
+	return cf, nil
+}
+
+// WithFilters applies the filters to the values of the matching signals. The
+// applied filters are appended to the prefiltering field of each signal.
+func WithFilters(filters ...*ChannelFilters) RecordOption {
+	return func(o *recordOptions) {
+		o.filters = append(o.filters, filters...)
+	}
+}
+
+// filterChain is the filters applied to a signal, in order.
+type filterChain []*biquad
+
+// newFilterChain creates the filters matching the signal of the device at addr,
+// along with the list of filters for its prefiltering field.
+func newFilterChain(channelFilters []*ChannelFilters, addr netip.Addr, signal Signal) (filterChain, []Filter, error) {
+	var chain filterChain
+	var applied []Filter
+	for _, cf := range channelFilters {
+		if !cf.selector.matches(addr, signal) {
+			continue
+		}
+
+		for _, filter := range cf.filters.Filters {
+			f, err := newBiquad(filter, float64(signal.SampleRate))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create filter for signal %q: %w", signal.Name, err)
+			}
+
+			chain = append(chain, f)
+			applied = append(applied, filter)
+		}
+	}
+
+	return chain, applied, nil
+}
+
+// apply filters the values in place.
+func (c filterChain) apply(values []float64) {
+	for _, f := range c {
+		for i, value := range values {
+			values[i] = f.process(value)
+		}
+	}
+}
+
+// biquad is a second order IIR filter (direct form I), with coefficients from
+// the Audio EQ Cookbook.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func newBiquad(filter Filter, sampleRate float64) (*biquad, error) {
+	frequency := float64(filter.Frequency)
+	if filter.Unit == Kilohertz {
+		frequency *= 1000
+	}
+
+	if frequency <= 0 || frequency >= sampleRate/2 {
+		return nil, fmt.Errorf("%s filter frequency %g Hz must be between 0 and the Nyquist frequency (%g Hz)",
+			filter.Kind, frequency, sampleRate/2)
+	}
+
+	w0 := 2 * math.Pi * frequency / sampleRate
+	cosw0 := math.Cos(w0)
+
+	var b0, b1, b2, alpha float64
+	switch filter.Kind {
+	case HighPass:
+		alpha = math.Sin(w0) / (2 * butterworthQ)
+		b0, b1, b2 = (1+cosw0)/2, -(1 + cosw0), (1+cosw0)/2
+	case LowPass:
+		alpha = math.Sin(w0) / (2 * butterworthQ)
+		b0, b1, b2 = (1-cosw0)/2, 1-cosw0, (1-cosw0)/2
+	case Notch:
+		alpha = math.Sin(w0) / (2 * notchQ)
+		b0, b1, b2 = 1, -2*cosw0, 1
+	default:
+		return nil, fmt.Errorf("unknown filter kind %q", filter.Kind)
+	}
+
+	a0 := 1 + alpha
+	return &biquad{
+		b0: b0 / a0,
+		b1: b1 / a0,
+		b2: b2 / a0,
+		a1: -2 * cosw0 / a0,
+		a2: (1 - alpha) / a0,
+	}, nil
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x1, f.x2 = x, f.x1
+	f.y1, f.y2 = y, f.y1
+	return y
+}
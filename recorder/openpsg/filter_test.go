@@ -0,0 +1,94 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gain returns the gain of the filter for a sinusoid of the frequency (or a
+// constant if zero), measured over the last 10s once the filter has settled.
+func gain(f *openpsg.Biquad, frequency, sampleRate float64) float64 {
+	n := int(60 * sampleRate)
+	settled := n - int(10*sampleRate)
+
+	var in, out float64
+	for i := range n {
+		x := math.Cos(2 * math.Pi * frequency * float64(i) / sampleRate)
+		y := f.Process(x)
+		if i >= settled {
+			in += x * x
+			out += y * y
+		}
+	}
+	return math.Sqrt(out / in)
+}
+
+func TestBiquad(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    openpsg.Filter
+		frequency float64
+		wantGain  float64
+		delta     float64
+	}{
+		{"Low-pass passband", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Hertz, Frequency: 35}, 0, 1, 1e-6},
+		{"Low-pass cutoff", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Hertz, Frequency: 35}, 35, 1 / math.Sqrt2, 0.01},
+		{"Low-pass stopband", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Hertz, Frequency: 35}, 100, 0, 0.1},
+		{"High-pass passband", openpsg.Filter{Kind: openpsg.HighPass, Unit: openpsg.Hertz, Frequency: 0.3}, 10, 1, 0.01},
+		{"High-pass cutoff", openpsg.Filter{Kind: openpsg.HighPass, Unit: openpsg.Hertz, Frequency: 0.3}, 0.3, 1 / math.Sqrt2, 0.01},
+		{"High-pass stopband", openpsg.Filter{Kind: openpsg.HighPass, Unit: openpsg.Hertz, Frequency: 0.3}, 0, 0, 1e-3},
+		{"Notch", openpsg.Filter{Kind: openpsg.Notch, Unit: openpsg.Hertz, Frequency: 50}, 50, 0, 0.01},
+		{"Notch passband", openpsg.Filter{Kind: openpsg.Notch, Unit: openpsg.Hertz, Frequency: 50}, 10, 1, 0.01},
+		{"Kilohertz", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Kilohertz, Frequency: 0.035}, 35, 1 / math.Sqrt2, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := openpsg.NewBiquad(tt.filter, 256)
+			require.NoError(t, err)
+
+			assert.InDelta(t, tt.wantGain, gain(f, tt.frequency, 256), tt.delta)
+		})
+	}
+}
+
+func TestBiquadInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter openpsg.Filter
+	}{
+		{"Zero frequency", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Hertz}},
+		{"Nyquist frequency", openpsg.Filter{Kind: openpsg.LowPass, Unit: openpsg.Hertz, Frequency: 128}},
+		{"Beyond the Nyquist frequency", openpsg.Filter{Kind: openpsg.HighPass, Unit: openpsg.Kilohertz, Frequency: 1}},
+		{"Unknown kind", openpsg.Filter{Kind: "BP", Unit: openpsg.Hertz, Frequency: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openpsg.NewBiquad(tt.filter, 256)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Firmware images are sent in chunks of this size, small enough for the
+// request buffers of devices.
+const firmwareChunkSize = 512
+
+// The time allowed for a device to verify its new firmware, and to reboot into
+// it.
+const (
+	firmwareVerifyTimeout = 30 * time.Second
+	firmwareRebootTimeout = 2 * time.Minute
+)
+
+// firmwareUpdate is the parameters of an openpsg.update.begin request.
+type firmwareUpdate struct {
+	// The size of the image in bytes.
+	Size int `json:"size"`
+	// The hex encoded SHA-256 digest of the image, verified by the device.
+	SHA256 string `json:"sha256"`
+}
+
+// firmwareChunk is the parameters of an openpsg.update.write request.
+type firmwareChunk struct {
+	Offset int `json:"offset"`
+	// The base64 encoded chunk of the image.
+	Data string `json:"data"`
+}
+
+// UpdateFirmware sends a firmware image to the device in chunks, reporting the
+// progress (in bytes sent). Once the whole image has been sent, the device
+// verifies its digest and reboots into it, and UpdateFirmware waits for the
+// client to reconnect.
+func (c *Client) UpdateFirmware(ctx context.Context, image []byte, progress func(sent, total int)) error {
+	if !c.HasCapability(CapabilityFirmwareUpdate) {
+		return fmt.Errorf("device doesn't support firmware updates")
+	}
+
+	digest := sha256.Sum256(image)
+	update := firmwareUpdate{Size: len(image), SHA256: hex.EncodeToString(digest[:])}
+	if err := c.call(ctx, c.options.callTimeout, "openpsg.update.begin", update); err != nil {
+		return fmt.Errorf("failed to begin firmware update: %w", err)
+	}
+
+	for offset := 0; offset < len(image); offset += firmwareChunkSize {
+		chunk := image[offset:min(offset+firmwareChunkSize, len(image))]
+		params := firmwareChunk{Offset: offset, Data: base64.StdEncoding.EncodeToString(chunk)}
+		if err := c.call(ctx, c.options.callTimeout, "openpsg.update.write", params); err != nil {
+			return fmt.Errorf("failed to send firmware (at offset %d): %w", offset, err)
+		}
+
+		if progress != nil {
+			progress(offset+len(chunk), len(image))
+		}
+	}
+
+	// Drain outages from before the update, so the reboot can be waited for.
+drain:
+	for {
+		select {
+		case <-c.outages:
+		default:
+			break drain
+		}
+	}
+
+	// The device verifies the image, and reboots into it once it has responded.
+	if err := c.call(ctx, firmwareVerifyTimeout, "openpsg.update.finish", nil); err != nil {
+		return fmt.Errorf("failed to verify firmware: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(firmwareRebootTimeout):
+		return fmt.Errorf("device didn't reconnect after rebooting into the new firmware")
+	case <-c.outages:
+		return nil
+	}
+}
+
+// call calls a method of the device, with a timeout.
+func (c *Client) call(ctx context.Context, timeout time.Duration, method string, params any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return c.conn().Call(ctx, method, params, nil)
+}
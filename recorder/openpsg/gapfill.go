@@ -0,0 +1,190 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GapFill is the policy used to fill signal values that were not received.
+type GapFill string
+
+const (
+	// GapFillZero fills missing values with zero.
+	GapFillZero GapFill = "zero"
+	// GapFillPhysicalMin fills missing values with the physical minimum of the
+	// signal.
+	GapFillPhysicalMin GapFill = "physical-min"
+	// GapFillHoldLast repeats the last received value.
+	GapFillHoldLast GapFill = "hold-last"
+	// GapFillInvalid fills missing values with a digital value outside of the
+	// signal's digital range, and annotates each dropout (merged across
+	// signals and data records).
+	GapFillInvalid GapFill = "invalid"
+)
+
+// ParseGapFill parses a gap-fill policy.
+func ParseGapFill(s string) (GapFill, error) {
+	switch policy := GapFill(strings.ToLower(s)); policy {
+	case GapFillZero, GapFillPhysicalMin, GapFillHoldLast, GapFillInvalid:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown gap-fill policy: %s", s)
+	}
+}
+
+// WithGapFill sets the policy used to fill signal values that were not
+// received (the default is GapFillZero).
+func WithGapFill(policy GapFill) RecordOption {
+	return func(o *recordOptions) {
+		o.gapFill = policy
+	}
+}
+
+// fill replaces the missing values according to the policy. last holds the
+// last received value of the signal and is updated.
+func (p GapFill) fill(values []float64, received []bool, physicalMin float64, last *float64) {
+	for i := range values {
+		if received[i] {
+			*last = values[i]
+			continue
+		}
+
+		switch p {
+		case GapFillPhysicalMin:
+			values[i] = physicalMin
+		case GapFillHoldLast:
+			values[i] = *last
+		case GapFillInvalid:
+			// Written as edfplus.InvalidSample.
+			values[i] = math.NaN()
+		default:
+			values[i] = 0
+		}
+	}
+}
+
+// missingRuns returns the [start, end) indices of each run of missing values.
+func missingRuns(received []bool) [][2]int {
+	var runs [][2]int
+	for i := 0; i < len(received); i++ {
+		if received[i] {
+			continue
+		}
+
+		start := i
+		for i < len(received) && !received[i] {
+			i++
+		}
+		runs = append(runs, [2]int{start, i})
+	}
+
+	return runs
+}
+
+// A dropout is a period in which the values of some signals were missing,
+// relative to the start time of the recording.
+type dropout struct {
+	start, end time.Duration
+	signals    []string
+}
+
+// text describes the dropout, naming the signals if they fit.
+func (d dropout) text() string {
+	if names := strings.Join(d.signals, ", "); len(d.signals) == 1 || len(names) <= 64 {
+		return "Missing signal values: " + names
+	}
+	return fmt.Sprintf("Missing signal values: %d signals", len(d.signals))
+}
+
+// merge extends the dropout to cover other.
+func (d *dropout) merge(other dropout) {
+	d.start = min(d.start, other.start)
+	d.end = max(d.end, other.end)
+	for _, signal := range other.signals {
+		if !slices.Contains(d.signals, signal) {
+			d.signals = append(d.signals, signal)
+		}
+	}
+}
+
+// dropouts merges the runs of missing values of every signal into dropouts,
+// across data records, so a device dropping out is annotated once rather than
+// for every signal in every data record (which would overflow the annotation
+// signal).
+type dropouts struct {
+	// Dropouts closer together than this are merged, bounding the number
+	// annotated in each data record.
+	minGap time.Duration
+	// The runs of the data record being assembled.
+	runs []dropout
+	// The dropout that may continue into the next data record.
+	open *dropout
+}
+
+// add adds a run of missing values of a signal.
+func (d *dropouts) add(signal string, start, end time.Duration) {
+	d.runs = append(d.runs, dropout{start: start, end: end, signals: []string{signal}})
+}
+
+// finish merges the runs of the data record ending at end, returning the
+// dropouts that have ended. The last dropout is kept open if it may continue
+// into the next data record, unless this is the final data record.
+func (d *dropouts) finish(end time.Duration, final bool) []dropout {
+	sort.SliceStable(d.runs, func(i, j int) bool {
+		return d.runs[i].start < d.runs[j].start
+	})
+
+	var ended []dropout
+	for _, run := range d.runs {
+		if d.open != nil && run.start <= d.open.end+d.minGap {
+			d.open.merge(run)
+			continue
+		}
+
+		if d.open != nil {
+			ended = append(ended, *d.open)
+		}
+		d.open = &run
+	}
+	d.runs = d.runs[:0]
+
+	if d.open != nil && (final || d.open.end+d.minGap <= end) {
+		ended = append(ended, *d.open)
+		d.open = nil
+	}
+
+	return ended
+}
+
+// flush returns the open dropout (if any), eg. before a gap in the recording.
+func (d *dropouts) flush() []dropout {
+	if d.open == nil {
+		return nil
+	}
+
+	ended := []dropout{*d.open}
+	d.open = nil
+	return ended
+}
@@ -0,0 +1,134 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGapFill(t *testing.T) {
+	policy, err := openpsg.ParseGapFill("Hold-Last")
+	require.NoError(t, err)
+	assert.Equal(t, openpsg.GapFillHoldLast, policy)
+
+	_, err = openpsg.ParseGapFill("interpolate")
+	assert.Error(t, err)
+}
+
+func TestGapFill(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		name     string
+		policy   openpsg.GapFill
+		values   []float64
+		received []bool
+		want     []float64
+		wantLast float64
+	}{
+		{
+			name:     "Zero",
+			policy:   openpsg.GapFillZero,
+			values:   []float64{1, 9, 3, 9, 9},
+			received: []bool{true, false, true, false, false},
+			want:     []float64{1, 0, 3, 0, 0},
+			wantLast: 3,
+		},
+		{
+			name:     "Physical minimum",
+			policy:   openpsg.GapFillPhysicalMin,
+			values:   []float64{1, 9, 3, 9, 9},
+			received: []bool{true, false, true, false, false},
+			want:     []float64{1, -5, 3, -5, -5},
+			wantLast: 3,
+		},
+		{
+			name:     "Hold last",
+			policy:   openpsg.GapFillHoldLast,
+			values:   []float64{1, 9, 3, 9, 9},
+			received: []bool{true, false, true, false, false},
+			want:     []float64{1, 1, 3, 3, 3},
+			wantLast: 3,
+		},
+		{
+			name:     "Hold last from the previous record",
+			policy:   openpsg.GapFillHoldLast,
+			values:   []float64{9, 9, 2},
+			received: []bool{false, false, true},
+			want:     []float64{7, 7, 2},
+			wantLast: 2,
+		},
+		{
+			name:     "Invalid",
+			policy:   openpsg.GapFillInvalid,
+			values:   []float64{1, 9, 3},
+			received: []bool{true, false, true},
+			want:     []float64{1, nan, 3},
+			wantLast: 3,
+		},
+		{
+			name:     "Nothing received",
+			policy:   openpsg.GapFillHoldLast,
+			values:   []float64{9, 9},
+			received: []bool{false, false},
+			want:     []float64{7, 7},
+			wantLast: 7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := 7.0
+			tt.policy.Fill(tt.values, tt.received, -5, &last)
+
+			require.Len(t, tt.values, len(tt.want))
+			for i, want := range tt.want {
+				if math.IsNaN(want) {
+					assert.True(t, math.IsNaN(tt.values[i]), "value %d", i)
+				} else {
+					assert.Equal(t, want, tt.values[i], "value %d", i)
+				}
+			}
+			assert.Equal(t, tt.wantLast, last)
+		})
+	}
+}
+
+func TestMissingRuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		received []bool
+		want     [][2]int
+	}{
+		{"None missing", []bool{true, true}, nil},
+		{"All missing", []bool{false, false, false}, [][2]int{{0, 3}}},
+		{"Runs", []bool{false, true, false, false, true, false}, [][2]int{{0, 1}, {2, 4}, {5, 6}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, openpsg.MissingRuns(tt.received))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// deviceIdentity is the response to an openpsg.attest request.
+type deviceIdentity struct {
+	// The base64 encoded (PKIX, DER) public key of the device.
+	PublicKey string `json:"publicKey"`
+	// The base64 encoded signature of the challenge by the device's key.
+	Signature string `json:"signature"`
+}
+
+// identify challenges the device to prove its identity, by signing a random
+// challenge with its private key, returning the fingerprint of its public key.
+func identify(ctx context.Context, rpcConn *jsonrpc2.Conn) (string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	var identity deviceIdentity
+	params := map[string]string{"challenge": hex.EncodeToString(challenge)}
+	if err := rpcConn.Call(ctx, "openpsg.attest", params, &identity); err != nil {
+		return "", fmt.Errorf("failed to identify device: %w", err)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(identity.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid public key: %w", ErrUnauthorizedDevice, err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(identity.Signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid signature: %w", ErrUnauthorizedDevice, err)
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid public key: %w", ErrUnauthorizedDevice, err)
+	}
+
+	var valid bool
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, challenge, signature)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(challenge)
+		valid = ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	default:
+		return "", fmt.Errorf("%w: unsupported public key type %T", ErrUnauthorizedDevice, publicKey)
+	}
+	if !valid {
+		return "", fmt.Errorf("%w: invalid signature of challenge", ErrUnauthorizedDevice)
+	}
+
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Identity returns the fingerprint (the hex encoded SHA-256 of the public key)
+// of the identity the device proved when connecting, or an empty string if the
+// device can't prove its identity (see CapabilityIdentity). A device proving a
+// different identity when reconnecting is refused.
+func (c *Client) Identity() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.identity
+}
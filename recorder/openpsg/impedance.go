@@ -0,0 +1,311 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// DefaultImpedanceThreshold is the highest acceptable electrode impedance in
+// ohms (AASM recommends below 5 kOhm for EEG, but accepts up to 10 kOhm).
+const DefaultImpedanceThreshold = 10000
+
+// ErrHighImpedance is returned when recording is blocked by electrode
+// impedances exceeding the threshold (see WithImpedanceCheck).
+var ErrHighImpedance = errors.New("electrode impedance too high")
+
+// ElectrodeImpedance is the impedance of the electrodes of a signal, measured
+// by a device with an openpsg.impedance request.
+type ElectrodeImpedance struct {
+	// The unique identifier of the signal.
+	ID uint32 `json:"id"`
+	// The impedance in ohms.
+	Impedance float64 `json:"impedance"`
+}
+
+// impedanceSource is a source that can measure electrode impedances (see
+// Client.Impedance).
+type impedanceSource interface {
+	Impedance(ctx context.Context, signalIDs []uint32) ([]ElectrodeImpedance, error)
+}
+
+// Impedance measures the electrode impedance of the signals (those without
+// electrodes are left out).
+func (c *Client) Impedance(ctx context.Context, signalIDs []uint32) ([]ElectrodeImpedance, error) {
+	if !c.HasCapability(CapabilityImpedance) {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	var impedances []ElectrodeImpedance
+	if err := c.conn().Call(ctx, "openpsg.impedance", signalIDs, &impedances); err != nil {
+		return nil, fmt.Errorf("failed to measure impedance: %w", err)
+	}
+	return impedances, nil
+}
+
+// ImpedanceCheck checks the electrode impedances before recording starts.
+type ImpedanceCheck struct {
+	// The highest acceptable impedance in ohms.
+	Threshold float64
+	// Refuse to start recording if any impedance exceeds the threshold, rather
+	// than warning.
+	Block bool
+	// Where the readings are shown (stdout if nil).
+	Output io.Writer
+}
+
+// WithImpedanceCheck measures the electrode impedances of the devices that can
+// before recording starts, showing them and warning about (or refusing to
+// record with) impedances exceeding the threshold.
+func WithImpedanceCheck(check ImpedanceCheck) RecordOption {
+	return func(o *recordOptions) {
+		o.impedanceCheck = &check
+	}
+}
+
+// ImpedanceReading is the electrode impedance of a signal of a device.
+type ImpedanceReading struct {
+	Device netip.Addr
+	Signal string
+	// The impedance in ohms.
+	Impedance float64
+}
+
+// ImpedanceView shows the electrode impedances while they are monitored (see
+// MonitorImpedance), and decides when to stop.
+type ImpedanceView interface {
+	// Show is called with the readings each time they are measured.
+	Show(readings []ImpedanceReading)
+	// Done is closed once the operator has finished checking impedances.
+	Done() <-chan struct{}
+}
+
+// measureImpedances measures the electrode impedances of the signals of a
+// source, if it can.
+func measureImpedances(ctx context.Context, addr netip.Addr, source SignalSource, signals []Signal) ([]ImpedanceReading, error) {
+	s, ok := source.(impedanceSource)
+	if !ok {
+		return nil, nil
+	}
+
+	names := make(map[uint32]string, len(signals))
+	ids := make([]uint32, 0, len(signals))
+	for _, signal := range signals {
+		if !isStatusSignal(signal.ID) {
+			names[signal.ID] = signal.Name
+			ids = append(ids, signal.ID)
+		}
+	}
+
+	impedances, err := s.Impedance(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []ImpedanceReading
+	for _, impedance := range impedances {
+		if name, ok := names[impedance.ID]; ok {
+			readings = append(readings, ImpedanceReading{Device: addr, Signal: name, Impedance: impedance.Impedance})
+		}
+	}
+	return readings, nil
+}
+
+// check shows the impedance readings, returning ErrHighImpedance if any exceed
+// the threshold and recording is blocked.
+func (check *ImpedanceCheck) check(readings []ImpedanceReading) error {
+	if len(readings) == 0 {
+		slog.Info("No devices measured electrode impedances")
+		return nil
+	}
+
+	output := check.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	renderImpedances(output, readings, check.Threshold)
+
+	var high int
+	for _, reading := range readings {
+		if reading.Impedance > check.Threshold {
+			high++
+			slog.Warn("Electrode impedance too high",
+				slog.Any("deviceAddr", reading.Device),
+				slog.String("signal", reading.Signal),
+				slog.Float64("impedance", reading.Impedance))
+		}
+	}
+
+	if high > 0 && check.Block {
+		return fmt.Errorf("%w: %d signals exceed %.1f kOhm", ErrHighImpedance, high, check.Threshold/1000)
+	}
+	return nil
+}
+
+// renderImpedances renders a table of impedance readings.
+func renderImpedances(w io.Writer, readings []ImpedanceReading, threshold float64) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Device", "Signal", "Impedance", "Status"})
+	table.SetBorder(false)
+
+	for _, reading := range readings {
+		table.Append([]string{
+			reading.Device.String(),
+			reading.Signal,
+			FormatImpedance(reading.Impedance),
+			impedanceStatus(reading.Impedance, threshold),
+		})
+	}
+
+	table.Render()
+}
+
+// FormatImpedance formats an impedance in ohms as kOhm.
+func FormatImpedance(impedance float64) string {
+	return fmt.Sprintf("%.1f kOhm", impedance/1000)
+}
+
+// impedanceStatus returns whether the impedance exceeds the threshold, as
+// shown to the operator.
+func impedanceStatus(impedance, threshold float64) string {
+	if impedance > threshold {
+		return "High"
+	}
+	return "OK"
+}
+
+// MonitorImpedance shows the electrode impedances of the devices, refreshing
+// them every few seconds (eg. while hooking up a patient) until the operator
+// is done. The readings are shown by the view, or logged when their status
+// changes (until Enter is pressed) if the view is nil.
+func MonitorImpedance(ctx context.Context, deviceAddrs []netip.AddrPort, threshold float64, view ImpedanceView, opts ...ConnectOption) error {
+	type device struct {
+		addr    netip.Addr
+		client  *Client
+		signals []Signal
+	}
+
+	var devices []device
+	defer func() {
+		for _, device := range devices {
+			_ = device.client.Close()
+		}
+	}()
+
+	for _, addrPort := range deviceAddrs {
+		addr := addrPort.Addr()
+		client, err := Connect(ctx, addrPort, opts...)
+		if err != nil {
+			return err
+		}
+
+		signals, err := client.Signals(ctx)
+		if err != nil {
+			_ = client.Close()
+			return err
+		}
+
+		if !client.HasCapability(CapabilityImpedance) {
+			slog.Warn("Device can't measure electrode impedance", slog.Any("deviceAddr", addr))
+		}
+
+		devices = append(devices, device{addr: addr, client: client, signals: signals})
+	}
+
+	if view == nil {
+		view = newImpedanceLogView(threshold)
+	}
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		var readings []ImpedanceReading
+		for _, device := range devices {
+			deviceReadings, err := measureImpedances(ctx, device.addr, device.client, device.signals)
+			if err != nil {
+				slog.Debug("Failed to measure impedance", slog.Any("deviceAddr", device.addr), slog.Any("error", err))
+				continue
+			}
+			readings = append(readings, deviceReadings...)
+		}
+
+		view.Show(readings)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-view.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// impedanceLogView logs the electrode impedances when their status changes,
+// until Enter is pressed (for when they aren't shown on a dashboard, eg. when
+// stdout isn't a terminal).
+type impedanceLogView struct {
+	threshold float64
+	// The status logged, by device and signal.
+	logged map[string]string
+	done   <-chan struct{}
+}
+
+func newImpedanceLogView(threshold float64) *impedanceLogView {
+	slog.Info("Checking impedances, press Enter to stop")
+
+	return &impedanceLogView{
+		threshold: threshold,
+		logged:    make(map[string]string),
+		done:      enterPressed(),
+	}
+}
+
+func (v *impedanceLogView) Show(readings []ImpedanceReading) {
+	for _, reading := range readings {
+		status := impedanceStatus(reading.Impedance, v.threshold)
+
+		key := reading.Device.String() + "/" + reading.Signal
+		if v.logged[key] == status {
+			continue
+		}
+		v.logged[key] = status
+
+		slog.Info("Electrode impedance",
+			slog.Any("deviceAddr", reading.Device), slog.String("signal", reading.Signal),
+			slog.String("impedance", FormatImpedance(reading.Impedance)), slog.String("status", status))
+	}
+}
+
+func (v *impedanceLogView) Done() <-chan struct{} {
+	return v.done
+}
@@ -0,0 +1,500 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/netip"
+	"sync/atomic"
+	"time"
+)
+
+// connectedDevice is a device being recorded from.
+type connectedDevice struct {
+	addr      netip.Addr
+	open      SourceOpener
+	client    SignalSource
+	signalIDs []uint32
+	// The identity of the device, verified when reconnecting.
+	identity  string
+	connected atomic.Bool
+	// Counts of the signal values received and dropped, and the batches lost
+	// in transit (see DeviceProgress).
+	received, dropped, lost atomic.Uint64
+	// Counted for the report (see DeviceReport).
+	reconnects, outages int
+	disconnected        time.Duration
+	clockOffset         *ClockOffset
+
+	// The batches lost by earlier connections to the device, and when the
+	// batches lost were last counted.
+	lostBefore  uint64
+	lostChecked time.Time
+	// Scratch slices reused for each batch of values, as the signal buffers
+	// copy the values.
+	physical, resampled, stored []float64
+}
+
+// ingest receives the signal values, lead-off status, events and outages of a
+// device until the recording is stopped, reconnecting to the device if the
+// connection is lost. A device that couldn't be connected to when recording
+// started first waits for it to join the recording.
+func (r *recording) ingest(ctx context.Context, device *connectedDevice) error {
+	if device.client == nil {
+		slog.Info("Waiting for device to join the recording", slog.Any("deviceAddr", device.addr))
+
+		client, err := reconnect(ctx, device.addr, device.open, device.signalIDs, "")
+		if err != nil {
+			// The recording has been stopped.
+			return nil
+		}
+
+		if s, ok := client.(identitySource); ok {
+			device.identity = s.Identity()
+		}
+
+		slog.Info("Device joined the recording", slog.Any("deviceAddr", device.addr))
+		r.deviceEvent(ctx, "Device connected: "+device.addr.String())
+
+		device.client = client
+		device.connected.Store(true)
+	} else {
+		slog.Debug("Starting recording",
+			slog.Any("deviceAddr", device.addr),
+			slog.Any("signals", device.signalIDs))
+
+		if err := device.client.Start(ctx, device.signalIDs); err != nil {
+			return fmt.Errorf("failed to start recording: %w", err)
+		}
+		device.connected.Store(true)
+	}
+
+	deviceSignalValues := device.client.SignalValues()
+	deviceLeadOff := device.client.LeadOff()
+	deviceReportedEvents := events(device.client)
+	deviceOutages := outages(device.client)
+	disconnected := disconnections(device.client)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.stopDevice(device, deviceOutages)
+			return nil
+		case <-disconnected:
+			// The signals of the device are left missing until it reconnects.
+			slog.Warn("Lost connection to device", slog.Any("deviceAddr", device.addr))
+			r.deviceEvent(ctx, "Device disconnected: "+device.addr.String())
+			device.connected.Store(false)
+			device.outages++
+
+			device.lostBefore += lostBatches(device.client)
+			r.countLost(device)
+			_ = device.client.Close()
+
+			disconnectedAt := time.Now()
+			client, err := reconnect(ctx, device.addr, device.open, device.signalIDs, device.identity)
+			device.disconnected += time.Since(disconnectedAt)
+			if err != nil {
+				// The recording has been stopped.
+				device.client = nil
+				return nil
+			}
+			device.reconnects++
+
+			slog.Info("Reconnected to device", slog.Any("deviceAddr", device.addr))
+			r.deviceEvent(ctx, "Device reconnected: "+device.addr.String())
+
+			device.client = client
+			device.connected.Store(true)
+			deviceSignalValues = client.SignalValues()
+			deviceLeadOff = client.LeadOff()
+			deviceReportedEvents = events(client)
+			deviceOutages = outages(client)
+			disconnected = disconnections(client)
+		case outage, ok := <-deviceOutages:
+			if !ok {
+				deviceOutages = nil
+				continue
+			}
+
+			device.reconnects++
+			device.outages++
+			device.disconnected += outage.Duration()
+
+			// The signals of the device are missing during the outage.
+			slog.Warn("Device was disconnected",
+				slog.Any("deviceAddr", device.addr), slog.Duration("outage", outage.Duration()))
+
+			r.annotate(ctx, Annotation{Time: outage.Start, Duration: outage.Duration(), Text: "Device disconnected: " + device.addr.String()})
+		case event, ok := <-deviceReportedEvents:
+			if !ok {
+				deviceReportedEvents = nil
+				continue
+			}
+
+			annotation := event.annotation()
+			annotation.Time = r.correctClock(device.client, annotation.Time)
+
+			slog.Info("Device reported an event",
+				slog.Any("deviceAddr", device.addr), slog.String("event", annotation.Text))
+
+			r.annotate(ctx, annotation)
+		case status := <-deviceLeadOff:
+			r.ingestLeadOff(ctx, device, status)
+		case sv := <-deviceSignalValues:
+			r.ingestValues(ctx, device, sv)
+		}
+	}
+}
+
+// stopDevice stops the streaming of a device once the recording has stopped,
+// counting the outages and lost batches not yet counted for the report.
+func (r *recording) stopDevice(device *connectedDevice, deviceOutages <-chan Outage) {
+	slog.Debug("Stopping recording", slog.Any("deviceAddr", device.addr))
+
+	if r.driftEstimators != nil {
+		for _, signalID := range device.signalIDs {
+			id := r.signalIndices[device.addr][signalID]
+			slog.Info("Estimated clock drift",
+				slog.Any("deviceAddr", device.addr),
+				slog.String("signal", r.signals[id].Name),
+				slog.Float64("ppm", r.driftEstimators[id].ppm()))
+		}
+	}
+
+	r.countLost(device)
+
+	// Outages reported but not yet counted, and any still in progress, are
+	// counted up to the end of the recording.
+drain:
+	for {
+		select {
+		case outage, ok := <-deviceOutages:
+			if !ok {
+				break drain
+			}
+			device.reconnects++
+			device.outages++
+			device.disconnected += outage.Duration()
+		default:
+			break drain
+		}
+	}
+	if s, ok := device.client.(outageSource); ok {
+		if outage, ok := s.CurrentOutage(); ok {
+			device.outages++
+			device.disconnected += time.Since(outage.Start)
+		}
+	}
+
+	if s, ok := device.client.(clockSource); ok {
+		if offset, ok := s.ClockOffset(); ok {
+			device.clockOffset = &offset
+			slog.Info("Measured clock offset",
+				slog.Any("deviceAddr", device.addr),
+				slog.Duration("offset", offset.Offset),
+				slog.Duration("delay", offset.Delay))
+		}
+	}
+
+	if s, ok := device.client.(sequenceSource); ok {
+		for signalID, errs := range s.SequenceErrors() {
+			id, ok := r.signalIndices[device.addr][signalID]
+			if !ok || (errs.Lost == 0 && errs.Duplicated == 0) {
+				continue
+			}
+
+			slog.Warn("Signal values lost or duplicated in transit",
+				slog.Any("deviceAddr", device.addr),
+				slog.String("signal", r.signals[id].Name),
+				slog.Uint64("lost", errs.Lost),
+				slog.Uint64("duplicated", errs.Duplicated))
+		}
+	}
+
+	// A device that is still disconnected (eg. reconnecting by itself) can't be
+	// stopped, which doesn't affect the recording.
+	if err := device.client.Stop(context.Background(), device.signalIDs); err != nil {
+		slog.Warn("Failed to stop device streaming",
+			slog.Any("deviceAddr", device.addr), slog.Any("error", err))
+	}
+}
+
+// countLost adds the batches lost by the current connection to a device to the
+// statistics of its signals.
+func (r *recording) countLost(device *connectedDevice) {
+	if s, ok := device.client.(sequenceSource); ok {
+		for signalID, errs := range s.SequenceErrors() {
+			if id, ok := r.signalIndices[device.addr][signalID]; ok {
+				r.stats[id].lost += errs.Lost
+			}
+		}
+	}
+}
+
+// ingestLeadOff records a change in whether the sensor of a signal is
+// attached.
+func (r *recording) ingestLeadOff(ctx context.Context, device *connectedDevice, status LeadOffStatus) {
+	status.Timestamp = r.correctClock(device.client, status.Timestamp)
+
+	id, ok := r.signalIndices[device.addr][status.ID]
+	if !ok || r.leadOffTimelines[id] == nil {
+		slog.Warn("Received lead-off status for unknown signal",
+			slog.Any("deviceAddr", device.addr), slog.Any("id", status.ID))
+		return
+	}
+
+	r.leadOffTimelines[id].set(status.Timestamp, status.LeadOff)
+
+	if status.LeadOff {
+		slog.Warn("Sensor detached", slog.String("signal", r.signals[id].Name))
+	} else {
+		slog.Info("Sensor attached",
+			slog.String("signal", r.signals[id].Name),
+			slog.Float64("impedance", status.Impedance))
+	}
+
+	r.annotate(ctx, Annotation{Time: status.Timestamp, Text: leadOffAnnotation(r.signals[id].Name, status)})
+}
+
+// ingestValues converts a batch of signal values to physical values, monitors
+// and filters them, and resamples them to the storage rate of the signal
+// before buffering them for the next data records.
+func (r *recording) ingestValues(ctx context.Context, device *connectedDevice, sv SignalValues) {
+	sv.Timestamp = r.correctClock(device.client, sv.Timestamp)
+
+	// Rewrite the signal id to it's global form.
+	id, ok := r.signalIndices[device.addr][sv.ID]
+	if !ok {
+		slog.Warn("Received values for unknown signal",
+			slog.Any("deviceAddr", device.addr), slog.Any("id", sv.ID))
+		return
+	}
+	signal := r.signals[id]
+
+	device.received.Add(uint64(len(sv.Values)))
+	if time.Since(device.lostChecked) >= time.Second {
+		device.lost.Store(device.lostBefore + lostBatches(device.client))
+		device.lostChecked = time.Now()
+	}
+
+	physical := device.physical[:0]
+	for _, value := range sv.Values {
+		physical = append(physical, r.calibrations[id].apply(signal.physicalValue(value)))
+	}
+	device.physical = physical
+
+	r.stats[id].observe(sv.Timestamp, physical)
+
+	if ratio, changed := r.lineNoiseMonitors[id].observe(physical); changed {
+		if r.lineNoiseMonitors[id].noisy {
+			slog.Warn("Mains interference detected, check the grounding of the electrodes",
+				slog.String("signal", signal.Name),
+				slog.Float64("ratio", ratio))
+			r.deviceEvent(ctx, "Line noise detected: "+signal.Name)
+		} else {
+			slog.Info("Mains interference resolved", slog.String("signal", signal.Name))
+			r.deviceEvent(ctx, "Line noise resolved: "+signal.Name)
+		}
+	}
+
+	if quality, changed := r.qualityMonitors[id].observe(physical); changed {
+		if problem := quality.Problem(); problem != "" {
+			slog.Warn("Poor signal quality, check the sensor",
+				slog.String("signal", signal.Name),
+				slog.String("problem", problem),
+				slog.Float64("clipping", quality.Clipping),
+				slog.Float64("rms", quality.RMS))
+			r.deviceEvent(ctx, "Signal "+problem+": "+signal.Name)
+		} else {
+			slog.Info("Signal quality restored", slog.String("signal", signal.Name))
+			r.deviceEvent(ctx, "Signal quality restored: "+signal.Name)
+		}
+	}
+
+	r.filterChains[id].apply(physical)
+
+	if detector := r.respiratoryDetectors[id]; detector != nil {
+		for _, event := range detector.observe(sv.Timestamp, physical) {
+			slog.Info("Detected candidate respiratory event",
+				slog.String("event", event.Text),
+				slog.Duration("duration", event.Duration))
+			r.annotate(ctx, event)
+		}
+	}
+
+	if detector := r.desaturationDetectors[id]; detector != nil {
+		for _, event := range detector.observe(sv.Timestamp, physical) {
+			slog.Info("Detected oxygen desaturation",
+				slog.String("event", event.Text),
+				slog.Duration("duration", event.Duration))
+			r.annotate(ctx, event)
+		}
+	}
+
+	values := physical
+	if r.driftEstimators != nil {
+		r.driftEstimators[id].observe(sv.Timestamp, len(values))
+		device.resampled = r.driftEstimators[id].resample(device.resampled[:0], values)
+		values = device.resampled
+	}
+
+	timestamp := sv.Timestamp
+	if r.resamplers[id] != nil {
+		timestamp, device.stored = r.resamplers[id].resample(device.stored[:0], timestamp, values)
+		values = device.stored
+	}
+
+	timestamp, values = trimBefore(r.recordStart, timestamp, float64(signal.SampleRate), values)
+	if len(values) == 0 {
+		return
+	}
+
+	if dropped := r.signalBuffers[id].put(timestamp, values); dropped > 0 {
+		device.dropped.Add(uint64(dropped))
+		r.stats[id].dropped += uint64(dropped)
+		slog.Warn("Dropped signal values outside of the buffered window",
+			slog.Any("deviceAddr", device.addr),
+			slog.String("signal", signal.Name),
+			slog.Time("timestamp", timestamp),
+			slog.Int("dropped", dropped))
+	}
+}
+
+// correctClock converts a timestamp of a device to the recorder's clock, if
+// clock offset correction is enabled and the offset has been measured.
+func (r *recording) correctClock(source SignalSource, t time.Time) time.Time {
+	if !r.options.clockOffsetCorrection {
+		return t
+	}
+	if s, ok := source.(clockSource); ok {
+		if offset, ok := s.ClockOffset(); ok {
+			return t.Add(-offset.Offset)
+		}
+	}
+	return t
+}
+
+// annotate passes an annotation to the writer, unless the recording has been
+// stopped.
+func (r *recording) annotate(ctx context.Context, a Annotation) {
+	select {
+	case r.deviceEvents <- a:
+	case <-ctx.Done():
+	}
+}
+
+// deviceEvent annotates an event of a device (eg. a connection) at the current
+// time.
+func (r *recording) deviceEvent(ctx context.Context, text string) {
+	r.annotate(ctx, Annotation{Time: time.Now(), Text: text})
+}
+
+// trimBefore removes the values starting at timestamp that precede t (eg. those
+// received before the first data record), returning the timestamp of the first
+// remaining value.
+func trimBefore(t, timestamp time.Time, sampleRate float64, values []float64) (time.Time, []float64) {
+	if !timestamp.Before(t) {
+		return timestamp, values
+	}
+
+	n := min(int(math.Ceil(t.Sub(timestamp).Seconds()*sampleRate)), len(values))
+	return timestamp.Add(time.Duration(float64(n) / sampleRate * float64(time.Second))), values[n:]
+}
+
+// outages returns the outages reported by a source that reconnects by itself
+// (nil if it doesn't).
+func outages(source SignalSource) <-chan Outage {
+	if s, ok := source.(outageSource); ok {
+		return s.Outages()
+	}
+	return nil
+}
+
+// disconnections returns a channel that is closed when the connection to a
+// source is lost, or nil for a source that reconnects by itself (reporting its
+// outages instead).
+func disconnections(source SignalSource) <-chan struct{} {
+	if _, ok := source.(outageSource); ok {
+		return nil
+	}
+	return source.Disconnected()
+}
+
+// reconnect repeatedly attempts to reconnect to a device (with exponential
+// backoff) and restart the recording of its signals, until it succeeds or the
+// context is cancelled. If the device proved its identity, only a device
+// proving the same identity is accepted.
+func reconnect(ctx context.Context, deviceAddr netip.Addr, open SourceOpener, signalIDs []uint32, identity string) (SignalSource, error) {
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, maxReconnectDelay)
+
+		client, err := open(ctx)
+		if err != nil {
+			slog.Debug("Failed to reconnect to device",
+				slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+			continue
+		}
+
+		if s, ok := client.(identitySource); ok && identity != "" && s.Identity() != identity {
+			_ = client.Close()
+			slog.Warn("Reconnected device has a different identity, refusing it",
+				slog.Any("deviceAddr", deviceAddr), slog.String("identity", s.Identity()))
+			continue
+		}
+
+		// Signal ids are only meaningful if the device still has the same signals.
+		deviceSignals, err := client.Signals(ctx)
+		if err != nil {
+			_ = client.Close()
+			slog.Debug("Failed to get signals", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+			continue
+		}
+
+		available := make(map[uint32]bool)
+		for _, signal := range deviceSignals {
+			available[signal.ID] = true
+		}
+
+		for _, id := range signalIDs {
+			if !available[id] {
+				slog.Warn("Reconnected device is missing a recorded signal",
+					slog.Any("deviceAddr", deviceAddr), slog.Any("id", id))
+			}
+		}
+
+		if err := client.Start(ctx, signalIDs); err != nil {
+			_ = client.Close()
+			slog.Debug("Failed to restart recording", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+			continue
+		}
+
+		return client, nil
+	}
+}
@@ -0,0 +1,161 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/OpenPSG/edf"
+)
+
+// Journal records the progress of a recording, so that it can be resumed
+// after the recorder is interrupted (eg. by a crash). It is only updated once
+// the data records it describes have been flushed to stable storage.
+type Journal struct {
+	// The precise start time of the recording.
+	StartTime time.Time `json:"start_time"`
+	// The size of the EDF header and of each data record in bytes.
+	HeaderBytes int `json:"header_bytes"`
+	RecordSize  int `json:"record_size"`
+	// The number of data records flushed to stable storage.
+	DataRecords int `json:"data_records"`
+	// The offset of the end of the last flushed data record.
+	EndOffset int64 `json:"end_offset"`
+	// The signals of the recording, in the order they are stored.
+	Signals []JournalSignal `json:"signals"`
+	// The pseudonym that replaced the patient identification (if anonymized),
+	// kept when the recording is resumed.
+	Pseudonym string `json:"pseudonym,omitempty"`
+}
+
+// JournalSignal maps a device signal to a signal in the EDF file.
+type JournalSignal struct {
+	Device           string `json:"device"`
+	ID               uint32 `json:"id"`
+	Label            string `json:"label"`
+	SamplesPerRecord int    `json:"samples_per_record"`
+	// Derived (and lead-off status) signals aren't recorded from a device
+	// signal.
+	Derived bool `json:"derived,omitempty"`
+}
+
+// WithJournal maintains a journal of the recording in the file at path, which
+// is updated whenever the EDF file is checkpointed (see WithSyncInterval).
+func WithJournal(path string) RecordOption {
+	return func(o *recordOptions) {
+		o.journalPath = path
+	}
+}
+
+// WithPseudonym records in the journal that the patient identification was
+// replaced with the pseudonym.
+func WithPseudonym(pseudonym string) RecordOption {
+	return func(o *recordOptions) {
+		o.pseudonym = pseudonym
+	}
+}
+
+// WithResume resumes the interrupted recording described by the journal. The
+// EDF file passed to Record must be the partial file of the recording (opened
+// for reading and writing) with any truncated data record removed. The
+// devices must provide the same signals as before, and the new data records
+// are appended after a gap (EDF+D).
+func WithResume(j *Journal) RecordOption {
+	return func(o *recordOptions) {
+		o.resume = j
+	}
+}
+
+// LoadJournal reads the journal from the file at path.
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+
+	return &j, nil
+}
+
+// deviceSignals returns the signals of the journal recorded from devices.
+func (j *Journal) deviceSignals() []JournalSignal {
+	var signals []JournalSignal
+	for _, signal := range j.Signals {
+		if !signal.Derived {
+			signals = append(signals, signal)
+		}
+	}
+	return signals
+}
+
+// Validate checks that the header of a partial EDF file (with dataRecords
+// complete data records) matches the journal.
+func (j *Journal) Validate(hdr edf.Header, dataRecords int) error {
+	// The annotations signal isn't in the journal.
+	if hdr.HeaderBytes != j.HeaderBytes || len(hdr.Signals) != len(j.Signals)+1 {
+		return fmt.Errorf("header does not match the journal")
+	}
+
+	var recordSize int
+	for i, signal := range hdr.Signals {
+		recordSize += 2 * signal.SamplesPerRecord
+
+		if i < len(j.Signals) && (signal.Label != j.Signals[i].Label || signal.SamplesPerRecord != j.Signals[i].SamplesPerRecord) {
+			return fmt.Errorf("signal %q does not match the journal", signal.Label)
+		}
+	}
+
+	if recordSize != j.RecordSize {
+		return fmt.Errorf("data record size does not match the journal")
+	}
+
+	// Data records beyond the journal may not have been flushed, but they are
+	// complete.
+	if dataRecords < j.DataRecords {
+		return fmt.Errorf("file has %d data records, but %d were flushed", dataRecords, j.DataRecords)
+	}
+
+	return nil
+}
+
+// writeFile atomically replaces the journal file at path.
+func (j *Journal) writeFile(path string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+
+	return nil
+}
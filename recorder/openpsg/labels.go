@@ -0,0 +1,78 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+// LabelCheck is how signal labels are checked against the EDF+ standard texts
+// and 10-20 electrode names before the header is written.
+type LabelCheck string
+
+const (
+	// LabelCheckOff doesn't check labels.
+	LabelCheckOff LabelCheck = "off"
+	// LabelCheckWarn logs a warning for non-standard labels.
+	LabelCheckWarn LabelCheck = "warn"
+	// LabelCheckNormalize also corrects the case and spacing of labels (eg.
+	// "eeg c4-m1" becomes "EEG C4-M1").
+	LabelCheckNormalize LabelCheck = "normalize"
+)
+
+// ParseLabelCheck parses a label check mode.
+func ParseLabelCheck(s string) (LabelCheck, error) {
+	switch mode := LabelCheck(strings.ToLower(s)); mode {
+	case LabelCheckOff, LabelCheckWarn, LabelCheckNormalize:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown label check mode: %s", s)
+	}
+}
+
+// WithLabelCheck sets how signal labels are checked (the default is
+// LabelCheckOff).
+func WithLabelCheck(mode LabelCheck) RecordOption {
+	return func(o *recordOptions) {
+		o.labelCheck = mode
+	}
+}
+
+// check checks the labels of the signals, normalizing them if requested.
+func (mode LabelCheck) check(signals []Signal) {
+	if mode == "" || mode == LabelCheckOff {
+		return
+	}
+
+	for i := range signals {
+		label, problems := edfplus.NormalizeLabel(signals[i].Name)
+		for _, problem := range problems {
+			slog.Warn("Non-standard signal label",
+				slog.String("label", signals[i].Name), slog.String("problem", problem))
+		}
+
+		if mode == LabelCheckNormalize {
+			signals[i].Name = label
+		}
+	}
+}
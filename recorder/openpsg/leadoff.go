@@ -0,0 +1,82 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The sample rate of lead-off status signals.
+const leadOffSampleRate = 1
+
+// leadOffTimeline records the lead-off status of a signal over time, so it can
+// be sampled when data records are written.
+type leadOffTimeline struct {
+	mu sync.Mutex
+	// The status before the first change.
+	off     bool
+	changes []leadOffChange
+}
+
+type leadOffChange struct {
+	at  time.Time
+	off bool
+}
+
+// set records a change in the status at the specified time.
+func (t *leadOffTimeline) set(at time.Time, off bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.changes = append(t.changes, leadOffChange{at: at, off: off})
+}
+
+// sample fills values with the status (1 if the sensor is detached) at each
+// sample time from start, forgetting changes before the last sample.
+func (t *leadOffTimeline) sample(values []float64, start time.Time, sampleRate float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range values {
+		at := start.Add(time.Duration(float64(i) / sampleRate * float64(time.Second)))
+		for len(t.changes) > 0 && !t.changes[0].at.After(at) {
+			t.off = t.changes[0].off
+			t.changes = t.changes[1:]
+		}
+
+		values[i] = 0
+		if t.off {
+			values[i] = 1
+		}
+	}
+}
+
+// leadOffAnnotation describes a change in the lead-off status of a signal.
+func leadOffAnnotation(name string, status LeadOffStatus) string {
+	if status.LeadOff {
+		return "Lead off: " + name
+	}
+
+	if status.Impedance > 0 {
+		return fmt.Sprintf("Lead on: %s (%.1f kOhm)", name, status.Impedance/1000)
+	}
+	return "Lead on: " + name
+}
@@ -0,0 +1,224 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// The default fraction of a signal's power at the mains frequency above
+	// which line noise is reported.
+	DefaultLineNoiseThreshold = 0.5
+	// Line noise is reported as resolved once it falls below this fraction of
+	// the threshold, so a marginal signal doesn't flap between states.
+	lineNoiseHysteresis = 0.5
+	// How long to stream signals for when measuring line noise during
+	// discovery.
+	lineNoiseMeasurementDuration = 3 * time.Second
+)
+
+// WithLineNoiseDetection continuously estimates the power of mains
+// interference in voltage signals (eg. EEG and ECG), logging and annotating
+// when its fraction of the signal's power exceeds the threshold. The mains
+// frequency is either 50 or 60 Hz, or zero to check both.
+func WithLineNoiseDetection(frequency, threshold float64) RecordOption {
+	return func(o *recordOptions) {
+		o.lineFrequency = frequency
+		o.lineNoiseThreshold = threshold
+	}
+}
+
+// lineNoiseMonitor estimates the fraction of a signal's power at the mains
+// frequency over one second windows.
+type lineNoiseMonitor struct {
+	sampleRate  float64
+	frequencies []float64
+	threshold   float64
+	window      []float64
+	// Whether line noise is currently being reported.
+	noisy bool
+}
+
+// newLineNoiseMonitor creates a monitor for the signal, returning nil if it
+// isn't a voltage signal or its sample rate is too low to measure the mains
+// frequency.
+func newLineNoiseMonitor(signal Signal, frequency, threshold float64) *lineNoiseMonitor {
+	switch signal.Unit {
+	case Microvolts, Millivolts, Volts:
+	default:
+		return nil
+	}
+
+	frequencies := []float64{50, 60}
+	if frequency != 0 {
+		frequencies = []float64{frequency}
+	}
+
+	sampleRate := float64(signal.SampleRate)
+	frequencies = slices.DeleteFunc(frequencies, func(f float64) bool { return f >= sampleRate/2 })
+	if len(frequencies) == 0 || threshold <= 0 {
+		return nil
+	}
+
+	return &lineNoiseMonitor{
+		sampleRate:  sampleRate,
+		frequencies: frequencies,
+		threshold:   threshold,
+		window:      make([]float64, 0, int(sampleRate)),
+	}
+}
+
+// observe adds values to the current window. When a window completes and the
+// line noise crosses the threshold (in either direction), it returns the
+// fraction of power at the mains frequency and true.
+func (m *lineNoiseMonitor) observe(values []float64) (ratio float64, changed bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	for _, value := range values {
+		m.window = append(m.window, value)
+		if len(m.window) < cap(m.window) {
+			continue
+		}
+
+		r := lineNoiseRatio(m.window, m.sampleRate, m.frequencies)
+		m.window = m.window[:0]
+
+		if (!m.noisy && r > m.threshold) || (m.noisy && r < m.threshold*lineNoiseHysteresis) {
+			m.noisy = !m.noisy
+			ratio, changed = r, true
+		}
+	}
+
+	return ratio, changed
+}
+
+// lineNoiseRatio returns the largest fraction of the power of the values (less
+// their mean) at one of the frequencies, using the Goertzel algorithm.
+func lineNoiseRatio(values []float64, sampleRate float64, frequencies []float64) float64 {
+	var mean float64
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	variance /= float64(len(values))
+
+	if variance == 0 {
+		return 0
+	}
+
+	var ratio float64
+	for _, frequency := range frequencies {
+		coeff := 2 * math.Cos(2*math.Pi*frequency/sampleRate)
+
+		var s1, s2 float64
+		for _, value := range values {
+			s0 := value - mean + coeff*s1 - s2
+			s1, s2 = s0, s1
+		}
+
+		// The power of a sinusoid of amplitude A is A^2/2, with |X| = A*N/2.
+		magnitude := s1*s1 + s2*s2 - coeff*s1*s2
+		n := float64(len(values))
+		power := 2 * magnitude / (n * n)
+
+		ratio = max(ratio, power/variance)
+	}
+
+	return min(ratio, 1)
+}
+
+// measureLineNoise briefly streams the voltage signals of a device, returning
+// the names of those with line noise above the threshold.
+func measureLineNoise(ctx context.Context, client *Client, signals []Signal, threshold float64) ([]string, error) {
+	monitors := make(map[uint32]*lineNoiseMonitor)
+	var signalIDs []uint32
+	for _, signal := range signals {
+		if m := newLineNoiseMonitor(signal, 0, threshold); m != nil {
+			monitors[signal.ID] = m
+			signalIDs = append(signalIDs, signal.ID)
+		}
+	}
+
+	if len(signalIDs) == 0 {
+		return nil, nil
+	}
+
+	if err := client.Start(ctx, signalIDs); err != nil {
+		return nil, fmt.Errorf("failed to start signals: %w", err)
+	}
+	defer func() {
+		_ = client.Stop(ctx, signalIDs)
+		// Values may still arrive until the device has stopped.
+		go func() {
+			for range client.SignalValues() {
+			}
+		}()
+	}()
+
+	signalsByID := make(map[uint32]Signal)
+	for _, signal := range signals {
+		signalsByID[signal.ID] = signal
+	}
+
+	timer := time.NewTimer(lineNoiseMeasurementDuration)
+	defer timer.Stop()
+
+	var physical []float64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			var noisy []string
+			for _, signal := range signals {
+				if m := monitors[signal.ID]; m != nil && m.noisy {
+					noisy = append(noisy, signal.Name)
+				}
+			}
+			return noisy, nil
+		case sv, ok := <-client.SignalValues():
+			if !ok {
+				return nil, fmt.Errorf("device disconnected")
+			}
+
+			m := monitors[sv.ID]
+			if m == nil {
+				continue
+			}
+
+			physical = physical[:0]
+			for _, value := range sv.Values {
+				physical = append(physical, signalsByID[sv.ID].physicalValue(value))
+			}
+			m.observe(physical)
+		}
+	}
+}
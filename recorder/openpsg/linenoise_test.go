@@ -0,0 +1,171 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eegRate is the sample rate of the synthetic EEG.
+const eegRate = 256
+
+// eeg returns a second of a 10 Hz rhythm of the amplitude (around an offset),
+// with interference of the amplitude at the mains frequency.
+func eeg(amplitude, mainsFrequency, mainsAmplitude float64) []float64 {
+	values := make([]float64, eegRate)
+	for i := range values {
+		t := float64(i) / eegRate
+		values[i] = 20 + amplitude*math.Sin(2*math.Pi*10*t) + mainsAmplitude*math.Sin(2*math.Pi*mainsFrequency*t+0.3)
+	}
+	return values
+}
+
+func TestLineNoiseRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []float64
+		frequencies []float64
+		want        float64
+	}{
+		{
+			name:        "Clean",
+			values:      eeg(50, 50, 0),
+			frequencies: []float64{50, 60},
+			want:        0,
+		},
+		{
+			// The power of the interference is 4 times that of the rhythm.
+			name:        "50 Hz",
+			values:      eeg(50, 50, 100),
+			frequencies: []float64{50, 60},
+			want:        0.8,
+		},
+		{
+			name:        "60 Hz",
+			values:      eeg(50, 60, 100),
+			frequencies: []float64{50, 60},
+			want:        0.8,
+		},
+		{
+			name:        "60 Hz measuring 50 Hz",
+			values:      eeg(50, 60, 100),
+			frequencies: []float64{50},
+			want:        0,
+		},
+		{
+			name:        "Interference only",
+			values:      eeg(0, 50, 100),
+			frequencies: []float64{50, 60},
+			want:        1,
+		},
+		{
+			name:        "Flat",
+			values:      make([]float64, eegRate),
+			frequencies: []float64{50, 60},
+			want:        0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, openpsg.LineNoiseRatio(tt.values, eegRate, tt.frequencies), 0.01)
+		})
+	}
+}
+
+func TestLineNoiseMonitor(t *testing.T) {
+	signal := openpsg.Signal{Name: "EEG C3-A2", Unit: openpsg.Microvolts, SampleRate: eegRate}
+
+	tests := []struct {
+		name      string
+		frequency float64
+		// The mains frequency and amplitude of the interference in each second.
+		mainsFrequency float64
+		amplitudes     []float64
+		wantChanged    []bool
+	}{
+		{
+			name:           "Clean",
+			mainsFrequency: 50,
+			amplitudes:     []float64{0, 0, 0},
+			wantChanged:    []bool{false, false, false},
+		},
+		{
+			// The interference is reported until it falls below half the
+			// threshold.
+			name:           "50 Hz",
+			mainsFrequency: 50,
+			amplitudes:     []float64{0, 100, 100, 40, 0, 0},
+			wantChanged:    []bool{false, true, false, false, true, false},
+		},
+		{
+			name:           "60 Hz",
+			mainsFrequency: 60,
+			amplitudes:     []float64{0, 100, 0},
+			wantChanged:    []bool{false, true, true},
+		},
+		{
+			name:           "60 Hz measuring 50 Hz",
+			frequency:      50,
+			mainsFrequency: 60,
+			amplitudes:     []float64{0, 100, 0},
+			wantChanged:    []bool{false, false, false},
+		},
+		{
+			// Just below the threshold.
+			name:           "Marginal",
+			mainsFrequency: 50,
+			amplitudes:     []float64{45, 45},
+			wantChanged:    []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openpsg.NewLineNoiseMonitor(signal, tt.frequency, openpsg.DefaultLineNoiseThreshold)
+			require.NotNil(t, m)
+
+			var changed []bool
+			for _, amplitude := range tt.amplitudes {
+				// Each second arrives in two batches.
+				values := eeg(50, tt.mainsFrequency, amplitude)
+				_, first := m.Observe(values[:eegRate/2])
+				assert.False(t, first)
+				_, ok := m.Observe(values[eegRate/2:])
+				changed = append(changed, ok)
+			}
+			assert.Equal(t, tt.wantChanged, changed)
+		})
+	}
+
+	t.Run("Not a voltage", func(t *testing.T) {
+		signal := openpsg.Signal{Name: "Flow", Unit: openpsg.LitresPerSecond, SampleRate: eegRate}
+		assert.Nil(t, openpsg.NewLineNoiseMonitor(signal, 0, openpsg.DefaultLineNoiseThreshold))
+	})
+
+	t.Run("Sample rate too low", func(t *testing.T) {
+		signal := openpsg.Signal{Name: "ECG", Unit: openpsg.Millivolts, SampleRate: 100}
+		assert.Nil(t, openpsg.NewLineNoiseMonitor(signal, 0, openpsg.DefaultLineNoiseThreshold))
+	})
+}
@@ -0,0 +1,295 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"gopkg.in/yaml.v3"
+)
+
+// How often required devices that aren't ready are checked again.
+const requiredDevicesInterval = 5 * time.Second
+
+// DeviceManifest lists the devices required for a recording (eg. the airflow
+// sensor of a sleep study), so a recording doesn't start without them.
+type DeviceManifest struct {
+	Devices []RequiredDevice `json:"devices" yaml:"devices"`
+}
+
+// RequiredDevice is a device required for a recording, identified by its
+// hostname, MAC address or address (all of those given must match).
+type RequiredDevice struct {
+	// The name of the device in messages (eg. "Airflow sensor").
+	Name     string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Hostname string     `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	MAC      string     `json:"mac,omitempty" yaml:"mac,omitempty"`
+	Address  netip.Addr `json:"address,omitempty" yaml:"address,omitempty"`
+	// The signals the device must advertise, by name.
+	Signals []string `json:"signals,omitempty" yaml:"signals,omitempty"`
+}
+
+// String returns the name of the device, or how it is identified.
+func (d RequiredDevice) String() string {
+	switch {
+	case d.Name != "":
+		return d.Name
+	case d.Hostname != "":
+		return d.Hostname
+	case d.MAC != "":
+		return d.MAC
+	default:
+		return d.Address.String()
+	}
+}
+
+// matches returns true if the candidate is the required device.
+func (d RequiredDevice) matches(candidate Candidate) bool {
+	if d.Hostname != "" && !strings.EqualFold(d.Hostname, candidate.Hostname) {
+		return false
+	}
+	if d.MAC != "" && !strings.EqualFold(d.MAC, candidate.MAC) {
+		return false
+	}
+	if d.Address.IsValid() && d.Address != candidate.Addr {
+		return false
+	}
+	return true
+}
+
+// LoadDeviceManifest reads a manifest of required devices from the file at
+// path. Files with a .yaml or .yml extension are parsed as YAML, others as
+// JSON.
+func LoadDeviceManifest(path string) (*DeviceManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device manifest: %w", err)
+	}
+
+	var m DeviceManifest
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse device manifest: %w", err)
+	}
+
+	for i, device := range m.Devices {
+		if device.Hostname == "" && device.MAC == "" && !device.Address.IsValid() {
+			return nil, fmt.Errorf("required device %q needs a hostname, MAC address or address", device.Name)
+		}
+
+		if device.MAC != "" {
+			mac, err := net.ParseMAC(device.MAC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MAC address of required device %q: %w", device, err)
+			}
+			m.Devices[i].MAC = mac.String()
+		}
+	}
+
+	return &m, nil
+}
+
+// Candidate is a device that may be one of those required by a manifest.
+type Candidate struct {
+	Addr     netip.Addr
+	MAC      string
+	Hostname string
+	// Opens the device (by default connecting to the OpenPSG device at Addr).
+	Open SourceOpener
+}
+
+// LeaseCandidates returns the devices holding leases as candidates. If
+// requireApproval is set, devices that haven't been approved are left out.
+func LeaseCandidates(db leasedb.Store, requireApproval bool) ([]Candidate, error) {
+	leases, err := db.ListLeases()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+
+	var candidates []Candidate
+	for _, lease := range leases {
+		if lease.ExpiresAt.Before(time.Now()) {
+			continue
+		}
+
+		if requireApproval {
+			approved, err := isApproved(db, lease)
+			if err != nil {
+				return nil, err
+			}
+			if !approved {
+				continue
+			}
+		}
+
+		addr, err := netip.ParseAddr(lease.IPAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address in lease: %w", err)
+		}
+
+		candidates = append(candidates, Candidate{
+			Addr:     addr,
+			MAC:      lease.MAC,
+			Hostname: lease.Hostname,
+		})
+	}
+
+	return candidates, nil
+}
+
+// SourceCandidates returns the sources as candidates. OpenPSG devices given by
+// hostname (eg. "openpsg:sensor-3.local") are identified by it.
+func SourceCandidates(sources []Source) []Candidate {
+	candidates := make([]Candidate, 0, len(sources))
+	for _, source := range sources {
+		candidate := Candidate{Addr: source.Addr, Open: source.Open}
+
+		if name, arg, _ := strings.Cut(source.Spec, ":"); name == "openpsg" {
+			host := arg
+			if h, _, err := net.SplitHostPort(arg); err == nil {
+				host = h
+			}
+			if _, err := netip.ParseAddr(host); err != nil {
+				candidate.Hostname = host
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
+
+// WaitForDevices waits until every device in the manifest is online and
+// advertising its signals, giving up after timeout (with a timeout of zero
+// the devices are only checked once). The devices are looked for among the
+// candidates, which are listed again each time they're checked. The addresses
+// of the required devices are returned.
+func (m *DeviceManifest) WaitForDevices(ctx context.Context, timeout time.Duration, candidates func() ([]Candidate, error), opts ...ConnectOption) ([]netip.Addr, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		available, err := candidates()
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, notReady := m.check(ctx, available, opts...)
+		if len(notReady) == 0 {
+			return addrs, nil
+		}
+
+		if !time.Now().Add(requiredDevicesInterval).Before(deadline) {
+			return nil, fmt.Errorf("required devices not ready: %s", strings.Join(notReady, "; "))
+		}
+
+		slog.Info("Waiting for required devices", slog.Any("notReady", notReady))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(requiredDevicesInterval):
+		}
+	}
+}
+
+// check returns the addresses of the required devices that are ready, and
+// why the others aren't.
+func (m *DeviceManifest) check(ctx context.Context, candidates []Candidate, opts ...ConnectOption) ([]netip.Addr, []string) {
+	var addrs []netip.Addr
+	var notReady []string
+
+	for _, device := range m.Devices {
+		reason := "not found"
+		for _, candidate := range candidates {
+			if !device.matches(candidate) {
+				continue
+			}
+
+			missing, err := candidate.missingSignals(ctx, device.Signals, opts...)
+			if err != nil {
+				reason = fmt.Sprintf("offline at %s (%v)", candidate.Addr, err)
+				continue
+			}
+			if len(missing) > 0 {
+				reason = fmt.Sprintf("missing signals %s at %s", strings.Join(missing, ", "), candidate.Addr)
+				continue
+			}
+
+			reason = ""
+			if !slices.Contains(addrs, candidate.Addr) {
+				addrs = append(addrs, candidate.Addr)
+			}
+			break
+		}
+
+		if reason != "" {
+			notReady = append(notReady, fmt.Sprintf("%s: %s", device, reason))
+		}
+	}
+
+	return addrs, notReady
+}
+
+// missingSignals opens the candidate, returning those of the signals it
+// doesn't advertise.
+func (c Candidate) missingSignals(ctx context.Context, required []string, opts ...ConnectOption) ([]string, error) {
+	open := c.Open
+	if open == nil {
+		open = openDevice(netip.AddrPortFrom(c.Addr, 80), opts...)
+	}
+
+	source, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	signals, err := source.Signals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signals: %w", err)
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !slices.ContainsFunc(signals, func(signal Signal) bool {
+			return strings.EqualFold(signal.Name, name)
+		}) {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,184 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDeviceManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []openpsg.RequiredDevice
+		wantErr bool
+	}{
+		{
+			name: "YAML",
+			file: "devices.yaml",
+			data: "devices:\n" +
+				"  - name: Airflow sensor\n" +
+				"    hostname: openpsg-airflow\n" +
+				"    signals: [Nasal Pressure, Thermistor]\n" +
+				"  - mac: AA-BB-CC-DD-EE-FF\n" +
+				"  - address: 10.0.0.12\n",
+			want: []openpsg.RequiredDevice{
+				{Name: "Airflow sensor", Hostname: "openpsg-airflow", Signals: []string{"Nasal Pressure", "Thermistor"}},
+				{MAC: "aa:bb:cc:dd:ee:ff"},
+				{Address: netip.MustParseAddr("10.0.0.12")},
+			},
+		},
+		{
+			name: "JSON",
+			file: "devices.json",
+			data: `{"devices": [{"name": "Airflow sensor", "mac": "AA:BB:CC:DD:EE:FF", "signals": ["Nasal Pressure"]}]}`,
+			want: []openpsg.RequiredDevice{
+				{Name: "Airflow sensor", MAC: "aa:bb:cc:dd:ee:ff", Signals: []string{"Nasal Pressure"}},
+			},
+		},
+		{
+			name:    "Unidentified device",
+			file:    "devices.yaml",
+			data:    "devices:\n  - name: Airflow sensor\n    signals: [Nasal Pressure]\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid MAC address",
+			file:    "devices.yaml",
+			data:    "devices:\n  - mac: AA-BB-CC\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid address",
+			file:    "devices.yaml",
+			data:    "devices:\n  - address: 10.0.0\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o644))
+
+			m, err := openpsg.LoadDeviceManifest(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Devices)
+		})
+	}
+}
+
+func TestWaitForDevices(t *testing.T) {
+	addr := netip.MustParseAddr("10.0.0.12")
+
+	// A candidate device advertising a signal with the name.
+	candidate := func(signal string) openpsg.Candidate {
+		source := newFakeSource()
+		source.signal.Name = signal
+		return openpsg.Candidate{Addr: addr, MAC: "aa:bb:cc:dd:ee:ff", Hostname: "openpsg-airflow", Open: source.open}
+	}
+
+	offline := openpsg.Candidate{Addr: addr, Hostname: "openpsg-airflow", Open: func(ctx context.Context) (openpsg.SignalSource, error) {
+		return nil, errors.New("connection refused")
+	}}
+
+	tests := []struct {
+		name       string
+		devices    []openpsg.RequiredDevice
+		candidates []openpsg.Candidate
+		want       []netip.Addr
+		wantErr    string
+	}{
+		{
+			name:       "By hostname",
+			devices:    []openpsg.RequiredDevice{{Hostname: "OpenPSG-Airflow", Signals: []string{"nasal pressure"}}},
+			candidates: []openpsg.Candidate{candidate("Nasal Pressure")},
+			want:       []netip.Addr{addr},
+		},
+		{
+			name:       "By MAC address and address",
+			devices:    []openpsg.RequiredDevice{{MAC: "aa:bb:cc:dd:ee:ff", Address: addr}},
+			candidates: []openpsg.Candidate{candidate("Nasal Pressure")},
+			want:       []netip.Addr{addr},
+		},
+		{
+			name:       "Another address",
+			devices:    []openpsg.RequiredDevice{{Name: "Airflow sensor", MAC: "aa:bb:cc:dd:ee:ff", Address: netip.MustParseAddr("10.0.0.13")}},
+			candidates: []openpsg.Candidate{candidate("Nasal Pressure")},
+			wantErr:    "Airflow sensor: not found",
+		},
+		{
+			name:    "Not found",
+			devices: []openpsg.RequiredDevice{{Hostname: "openpsg-airflow"}},
+			wantErr: "openpsg-airflow: not found",
+		},
+		{
+			name:       "Missing signals",
+			devices:    []openpsg.RequiredDevice{{Name: "Airflow sensor", Hostname: "openpsg-airflow", Signals: []string{"Nasal Pressure", "Thermistor"}}},
+			candidates: []openpsg.Candidate{candidate("Nasal Pressure")},
+			wantErr:    "Airflow sensor: missing signals Thermistor at 10.0.0.12",
+		},
+		{
+			name:       "Offline",
+			devices:    []openpsg.RequiredDevice{{Hostname: "openpsg-airflow"}},
+			candidates: []openpsg.Candidate{offline},
+			wantErr:    "openpsg-airflow: offline at 10.0.0.12 (connection refused)",
+		},
+		{
+			name: "One of several not ready",
+			devices: []openpsg.RequiredDevice{
+				{Hostname: "openpsg-airflow", Signals: []string{"Nasal Pressure"}},
+				{Name: "Oximeter", Hostname: "openpsg-oximeter"},
+			},
+			candidates: []openpsg.Candidate{candidate("Nasal Pressure")},
+			wantErr:    "Oximeter: not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &openpsg.DeviceManifest{Devices: tt.devices}
+
+			// Without a timeout the devices are only checked once.
+			addrs, err := m.WaitForDevices(context.Background(), 0, func() ([]openpsg.Candidate, error) {
+				return tt.candidates, nil
+			})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, addrs)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Montage lists the devices (and their signals) expected in a recording. As
+// the signals of an EDF file are fixed when it is created, this allows devices
+// that power on after the recording has started to join it. It can also give
+// the order and labels of the channels in the EDF file, and signals derived
+// from them.
+type Montage struct {
+	Devices  []MontageDevice  `json:"devices" yaml:"devices"`
+	Channels []MontageChannel `json:"channels" yaml:"channels"`
+	Derived  []DerivedChannel `json:"derived" yaml:"derived"`
+}
+
+// MontageDevice is a device expected in a recording.
+type MontageDevice struct {
+	Address netip.Addr `json:"address" yaml:"address"`
+	// The signals to record from the device, described as the device would.
+	Signals []Signal `json:"signals" yaml:"signals"`
+	// The configuration of the signals of the device, applied before recording
+	// (see CapabilityConfigure).
+	Configuration []MontageConfiguration `json:"configuration,omitempty" yaml:"configuration,omitempty"`
+}
+
+// MontageChannel places a device signal in the EDF file. Channels are stored in
+// the order they are listed, followed by any signals not in the montage.
+type MontageChannel struct {
+	// The label of the channel in the EDF file (eg. "EEG C4-M1").
+	Label  string     `json:"label" yaml:"label"`
+	Device netip.Addr `json:"device" yaml:"device"`
+	// The signal on the device, by ID or name.
+	Signal SignalRef `json:"signal" yaml:"signal"`
+	// The reference the signal is measured against (eg. "M1"), if any.
+	Reference string `json:"reference,omitempty" yaml:"reference,omitempty"`
+}
+
+// SignalRef refers to a device signal by its ID or name.
+type SignalRef string
+
+// UnmarshalJSON accepts either a signal ID (number) or name (string).
+func (r *SignalRef) UnmarshalJSON(data []byte) error {
+	var id uint32
+	if err := json.Unmarshal(data, &id); err == nil {
+		*r = SignalRef(strconv.FormatUint(uint64(id), 10))
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("signal must be an ID or name: %w", err)
+	}
+
+	*r = SignalRef(name)
+	return nil
+}
+
+// matches returns true if the reference refers to signal.
+func (r SignalRef) matches(signal Signal) bool {
+	if id, err := strconv.ParseUint(string(r), 10, 32); err == nil {
+		return uint32(id) == signal.ID
+	}
+	return strings.EqualFold(string(r), signal.Name)
+}
+
+// LoadMontage reads a montage from the file at path. Files with a .yaml or .yml
+// extension are parsed as YAML, others as JSON.
+func LoadMontage(path string) (*Montage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read montage: %w", err)
+	}
+
+	var m Montage
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse montage: %w", err)
+	}
+
+	for _, device := range m.Devices {
+		if !device.Address.IsValid() {
+			return nil, fmt.Errorf("montage device is missing an address")
+		}
+
+		for _, signal := range device.Signals {
+			if signal.SampleRate == 0 || signal.Min >= signal.Max {
+				return nil, fmt.Errorf("invalid montage signal %q for device %s", signal.Name, device.Address)
+			}
+		}
+
+		for _, config := range device.Configuration {
+			if config.Signal == "" || config.SampleRate < 0 || config.Gain < 0 {
+				return nil, fmt.Errorf("invalid montage configuration of signal %q for device %s", config.Signal, device.Address)
+			}
+		}
+	}
+
+	labels := make(map[string]bool)
+	for _, channel := range m.Channels {
+		if channel.Label == "" || !channel.Device.IsValid() || channel.Signal == "" {
+			return nil, fmt.Errorf("montage channel %q needs a label, device and signal", channel.Label)
+		}
+
+		if labels[channel.Label] {
+			return nil, fmt.Errorf("duplicate montage channel %q", channel.Label)
+		}
+		labels[channel.Label] = true
+	}
+
+	return &m, nil
+}
+
+// WithMontage reserves the signals of the devices in the montage, so they can
+// join the recording whenever they become available. Signals are stored as
+// described in the montage, rather than as reported by the device.
+func WithMontage(m *Montage) RecordOption {
+	return func(o *recordOptions) {
+		o.montage = m
+	}
+}
+
+// addresses returns the device addresses along with those in the montage.
+func (m *Montage) addresses(deviceAddrs []netip.Addr) []netip.Addr {
+	if m == nil {
+		return deviceAddrs
+	}
+
+	addrs := slices.Clone(deviceAddrs)
+	for _, device := range m.Devices {
+		if !slices.Contains(addrs, device.Address) {
+			addrs = append(addrs, device.Address)
+		}
+	}
+
+	return addrs
+}
+
+// signals returns the montage signals of the device at addr, if any (devices
+// listed only to be configured record the signals they report).
+func (m *Montage) signals(addr netip.Addr) ([]Signal, bool) {
+	if m == nil {
+		return nil, false
+	}
+
+	for _, device := range m.Devices {
+		if device.Address == addr && len(device.Signals) > 0 {
+			return device.Signals, true
+		}
+	}
+
+	return nil, false
+}
+
+// order returns the order to store the signals in (as indices into signals),
+// along with the montage channel of each (nil if not in the montage).
+func (m *Montage) order(signalDevices []netip.Addr, signals []Signal) ([]int, []*MontageChannel) {
+	order := make([]int, 0, len(signals))
+	channels := make([]*MontageChannel, 0, len(signals))
+	placed := make([]bool, len(signals))
+
+	if m != nil {
+		for i, channel := range m.Channels {
+			index := -1
+			for j, signal := range signals {
+				if !placed[j] && signalDevices[j] == channel.Device && channel.Signal.matches(signal) {
+					index = j
+					break
+				}
+			}
+
+			if index == -1 {
+				slog.Warn("Montage channel not found, leaving it out of the recording",
+					slog.String("label", channel.Label), slog.Any("device", channel.Device))
+				continue
+			}
+
+			placed[index] = true
+			order = append(order, index)
+			channels = append(channels, &m.Channels[i])
+		}
+	}
+
+	for i := range signals {
+		if !placed[i] {
+			order = append(order, i)
+			channels = append(channels, nil)
+		}
+	}
+
+	return order, channels
+}
@@ -0,0 +1,157 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/OpenPSG/edf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMontage(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []openpsg.MontageChannel
+		wantErr bool
+	}{
+		{
+			name: "YAML",
+			file: "montage.yaml",
+			data: "channels:\n" +
+				"  - {label: EEG C4-M1, device: 10.0.0.12, signal: 3, reference: M1}\n" +
+				"  - {label: Nasal Pressure, device: 10.0.0.13, signal: Pressure}\n",
+			want: []openpsg.MontageChannel{
+				{Label: "EEG C4-M1", Device: netip.MustParseAddr("10.0.0.12"), Signal: "3", Reference: "M1"},
+				{Label: "Nasal Pressure", Device: netip.MustParseAddr("10.0.0.13"), Signal: "Pressure"},
+			},
+		},
+		{
+			name: "JSON",
+			file: "montage.json",
+			data: `{"channels": [{"label": "EEG C4-M1", "device": "10.0.0.12", "signal": 3, "reference": "M1"},` +
+				`{"label": "Nasal Pressure", "device": "10.0.0.13", "signal": "Pressure"}]}`,
+			want: []openpsg.MontageChannel{
+				{Label: "EEG C4-M1", Device: netip.MustParseAddr("10.0.0.12"), Signal: "3", Reference: "M1"},
+				{Label: "Nasal Pressure", Device: netip.MustParseAddr("10.0.0.13"), Signal: "Pressure"},
+			},
+		},
+		{
+			name:    "Duplicate label",
+			file:    "montage.yaml",
+			data:    "channels:\n  - {label: EEG C4-M1, device: 10.0.0.12, signal: 3}\n  - {label: EEG C4-M1, device: 10.0.0.12, signal: 4}\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing device",
+			file:    "montage.yaml",
+			data:    "channels:\n  - {label: EEG C4-M1, signal: 3}\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing signal",
+			file:    "montage.yaml",
+			data:    "channels:\n  - {label: EEG C4-M1, device: 10.0.0.12}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o644))
+
+			m, err := openpsg.LoadMontage(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Channels)
+		})
+	}
+}
+
+// physicalValue converts a digital sample of a signal to its physical value.
+func physicalValue(signal edf.SignalHeader, sample int16) float64 {
+	return signal.PhysicalMin + float64(int(sample)-signal.DigitalMin)*
+		(signal.PhysicalMax-signal.PhysicalMin)/float64(signal.DigitalMax-signal.DigitalMin)
+}
+
+func TestRecordMontage(t *testing.T) {
+	// Each signal of the device has a constant value of ten times its ID.
+	source := newMultiSource(3, 0, 0)
+	for i := range source.signals {
+		source.signals[i].SampleFormat = openpsg.SampleFormatFloat32
+		source.signals[i].Unit = openpsg.Microvolts
+		source.signals[i].Min, source.signals[i].Max = -100, 100
+	}
+	source.value = func(id uint32, i int) float64 {
+		return float64(id) * 10
+	}
+
+	// The channels are stored in the order of the montage rather than that of
+	// the device, and referenced by their montage labels.
+	path := filepath.Join(t.TempDir(), "montage.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+channels:
+  - {label: EEG C4, device: 127.0.1.1, signal: 3}
+  - {label: M1, device: 127.0.1.1, signal: EEG channel 01}
+  - {label: EEG C3, device: 127.0.1.1, signal: 2}
+derived:
+  - {label: EEG C4-M1, inputs: [EEG C4, M1], weights: [1, -1]}
+  - {label: EEG M1-C3, inputs: [M1, EEG C3], weights: [1, -1]}
+`), 0o644))
+
+	montage, err := openpsg.LoadMontage(path)
+	require.NoError(t, err)
+
+	er := record(t, source.open, 2500*time.Millisecond, openpsg.WithMontage(montage))
+
+	var labels []string
+	for _, signal := range er.Signals() {
+		labels = append(labels, signal.Label)
+	}
+	require.Equal(t, []string{"EEG C4", "M1", "EEG C3", "EEG C4-M1", "EEG M1-C3"}, labels)
+
+	// The derived ranges are those of the difference of the inputs.
+	assert.Equal(t, -200.0, er.Signals()[3].PhysicalMin)
+	assert.Equal(t, 200.0, er.Signals()[3].PhysicalMax)
+
+	// The second data record is complete.
+	_, err = er.ReadRecord()
+	require.NoError(t, err)
+	record, err := er.ReadRecord()
+	require.NoError(t, err)
+
+	want := []float64{30, 10, 20, 30 - 10, 10 - 20}
+	for i, signal := range er.Signals() {
+		for _, sample := range record.Samples[i] {
+			require.InDelta(t, want[i], physicalValue(signal, sample), 0.01, signal.Label)
+		}
+	}
+}
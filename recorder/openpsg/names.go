@@ -0,0 +1,106 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"gopkg.in/yaml.v3"
+)
+
+// DeviceNames maps devices to friendly names (eg. "Bed 3 head-box"), by their
+// MAC address, hostname or address.
+type DeviceNames map[string]string
+
+// LoadDeviceNames reads the names of devices from the file at path. Files with
+// a .yaml or .yml extension are parsed as YAML, others as JSON.
+func LoadDeviceNames(path string) (DeviceNames, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device names: %w", err)
+	}
+
+	var names map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &names)
+	default:
+		err = json.Unmarshal(data, &names)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse device names: %w", err)
+	}
+
+	// Look up MAC addresses and hostnames however they were written.
+	normalized := make(DeviceNames, len(names))
+	for device, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("device %q has an empty name", device)
+		}
+
+		if mac, err := net.ParseMAC(device); err == nil {
+			device = mac.String()
+		}
+		normalized[strings.ToLower(device)] = name
+	}
+
+	return normalized, nil
+}
+
+// WithDeviceNames names the devices in the sidecar, and in the transducer
+// field of their signals.
+func WithDeviceNames(names DeviceNames) RecordOption {
+	return func(o *recordOptions) {
+		o.deviceNames = names
+	}
+}
+
+// lookup returns the name of the device at addr holding the lease (if any),
+// or an empty string if it isn't named.
+func (n DeviceNames) lookup(addr netip.Addr, lease *leasedb.Lease) string {
+	if lease != nil {
+		if name, ok := n[strings.ToLower(lease.MAC)]; ok {
+			return name
+		}
+		if name, ok := n[strings.ToLower(lease.Hostname)]; ok && lease.Hostname != "" {
+			return name
+		}
+	}
+	return n[addr.String()]
+}
+
+// transducer prefixes the transducer type of a signal with the name of its
+// device, if named.
+func transducer(name string, transducerType TransducerType) string {
+	switch {
+	case name == "":
+		return string(transducerType)
+	case transducerType == "":
+		return name
+	default:
+		return name + ": " + string(transducerType)
+	}
+}
@@ -0,0 +1,124 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDeviceNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    openpsg.DeviceNames
+		wantErr bool
+	}{
+		{
+			// MAC addresses and hostnames are looked up however they were
+			// written.
+			name: "YAML",
+			file: "names.yaml",
+			data: "AA-BB-CC-DD-EE-FF: Bed 3 head-box\nOpenPSG-Airflow: Bed 3 airflow\n10.0.0.12: Bed 4 oximeter\n",
+			want: openpsg.DeviceNames{
+				"aa:bb:cc:dd:ee:ff": "Bed 3 head-box",
+				"openpsg-airflow":   "Bed 3 airflow",
+				"10.0.0.12":         "Bed 4 oximeter",
+			},
+		},
+		{
+			name: "JSON",
+			file: "names.json",
+			data: `{"AA:BB:CC:DD:EE:FF": "Bed 3 head-box"}`,
+			want: openpsg.DeviceNames{"aa:bb:cc:dd:ee:ff": "Bed 3 head-box"},
+		},
+		{
+			name:    "Empty name",
+			file:    "names.yaml",
+			data:    "openpsg-airflow: \"\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "Not a mapping",
+			file:    "names.yaml",
+			data:    "- openpsg-airflow\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0o644))
+
+			names, err := openpsg.LoadDeviceNames(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestRecordDeviceNames(t *testing.T) {
+	tests := []struct {
+		name           string
+		names          openpsg.DeviceNames
+		transducerType openpsg.TransducerType
+		want           string
+	}{
+		{
+			name:           "Named",
+			names:          openpsg.DeviceNames{"127.0.1.1": "Bed 3 head-box"},
+			transducerType: "AgAgCl electrode",
+			want:           "Bed 3 head-box: AgAgCl electrode",
+		},
+		{
+			name:  "Named without a transducer type",
+			names: openpsg.DeviceNames{"127.0.1.1": "Bed 3 head-box"},
+			want:  "Bed 3 head-box",
+		},
+		{
+			name:           "Another device named",
+			names:          openpsg.DeviceNames{"127.0.1.2": "Bed 4 head-box"},
+			transducerType: "AgAgCl electrode",
+			want:           "AgAgCl electrode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newFakeSource()
+			source.signal.TransducerType = tt.transducerType
+
+			er := record(t, source.open, 500*time.Millisecond, openpsg.WithDeviceNames(tt.names))
+			require.Len(t, er.Signals(), 1)
+			assert.Equal(t, tt.want, er.Signals()[0].TransducerType)
+		})
+	}
+}
@@ -20,11 +20,16 @@ package openpsg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // TransducerType defines types of transducers
@@ -44,6 +49,22 @@ const (
 	Hertz      Unit = "Hz"
 	Kilohertz  Unit = "kHz"
 	Pascal     Unit = "Pa"
+
+	CentimetresOfWater Unit = "cmH2O"
+	LitresPerSecond    Unit = "L/s"
+	Percent            Unit = "%"
+	BeatsPerMinute     Unit = "bpm"
+	DegreesCelsius     Unit = "degC"
+)
+
+// SampleFormat defines the formats signal values are sent in
+type SampleFormat string
+
+const (
+	SampleFormatInt16   SampleFormat = "int16"
+	SampleFormatInt24   SampleFormat = "int24"
+	SampleFormatInt32   SampleFormat = "int32"
+	SampleFormatFloat32 SampleFormat = "float32"
 )
 
 // FilterKind defines types of filters
@@ -74,7 +95,25 @@ func (fl *FilterList) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	parts := strings.Split(filtersStr, " ")
+	return fl.parse(filtersStr)
+}
+
+// UnmarshalYAML custom unmarshaller for FilterList (eg. in a montage)
+func (fl *FilterList) UnmarshalYAML(value *yaml.Node) error {
+	var filtersStr string
+	if err := value.Decode(&filtersStr); err != nil {
+		return err
+	}
+
+	return fl.parse(filtersStr)
+}
+
+func (fl *FilterList) parse(filtersStr string) error {
+	if strings.TrimSpace(filtersStr) == "" {
+		return nil
+	}
+
+	parts := strings.Fields(filtersStr)
 	for _, part := range parts {
 		details := strings.Split(part, ":")
 		if len(details) != 2 {
@@ -112,6 +151,20 @@ func (fl *FilterList) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// String formats the filters for the prefiltering field of an EDF signal
+// header (eg. "HP:0.1Hz N:50Hz").
+func (fl FilterList) String() string {
+	var builder strings.Builder
+	for i, filter := range fl.Filters {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(fmt.Sprintf("%s:%s%s", filter.Kind,
+			strconv.FormatFloat(float64(filter.Frequency), 'g', -1, 32), filter.Unit))
+	}
+	return builder.String()
+}
+
 // MarshalJSON custom marshaller for FilterList
 func (fl *FilterList) MarshalJSON() ([]byte, error) {
 	var builder strings.Builder
@@ -127,28 +180,258 @@ func (fl *FilterList) MarshalJSON() ([]byte, error) {
 // Signal represents a signal configuration
 type Signal struct {
 	// The unique identifier of the signal.
-	ID uint32 `json:"id"`
+	ID uint32 `json:"id" yaml:"id"`
 	// The human-readable name of the signal.
-	Name string `json:"name"`
+	Name string `json:"name" yaml:"name"`
 	// The type of transducer used to measure the signal.
-	TransducerType TransducerType `json:"transducerType"`
+	TransducerType TransducerType `json:"transducerType" yaml:"transducerType"`
 	// The unit of the signal (eg. microvolts).
-	Unit Unit `json:"unit"`
+	Unit Unit `json:"unit" yaml:"unit"`
 	// The minimum value of the signal (in the unit of the signal).
-	Min float32 `json:"min"`
+	Min float32 `json:"min" yaml:"min"`
 	// The maximum value of the signal (in the unit of the signal).
-	Max float32 `json:"max"`
+	Max float32 `json:"max" yaml:"max"`
+	// The digital value corresponding to the minimum value of the signal
+	// (defaults to the range of the sample format if neither digitalMin or
+	// digitalMax are set).
+	DigitalMin int32 `json:"digitalMin,omitempty" yaml:"digitalMin,omitempty"`
+	// The digital value corresponding to the maximum value of the signal.
+	DigitalMax int32 `json:"digitalMax,omitempty" yaml:"digitalMax,omitempty"`
+	// The format of the signal values (defaults to int16). Float32 values are
+	// physical values, rather than digital values.
+	SampleFormat SampleFormat `json:"sampleFormat,omitempty" yaml:"sampleFormat,omitempty"`
 	// The list of filters applied to the signal.
-	Prefiltering FilterList `json:"prefiltering"`
+	Prefiltering FilterList `json:"prefiltering" yaml:"prefiltering"`
 	// The sample rate of the signal (in Hertz).
-	SampleRate uint32 `json:"sampleRate"`
+	SampleRate uint32 `json:"sampleRate" yaml:"sampleRate"`
+	// The device reports when the sensor of the signal is detached (see
+	// LeadOffStatus).
+	LeadOffDetection bool `json:"leadOffDetection,omitempty" yaml:"leadOffDetection,omitempty"`
+}
+
+// sampleFormat returns the format of the signal values.
+func (s Signal) sampleFormat() SampleFormat {
+	if s.SampleFormat == "" {
+		return SampleFormatInt16
+	}
+	return s.SampleFormat
+}
+
+// digitalRange returns the range of digital values reported for the signal.
+func (s Signal) digitalRange() (dmin, dmax int) {
+	if s.DigitalMin != 0 || s.DigitalMax != 0 {
+		return int(s.DigitalMin), int(s.DigitalMax)
+	}
+
+	switch s.sampleFormat() {
+	case SampleFormatInt24:
+		return -1 << 23, 1<<23 - 1
+	case SampleFormatInt32:
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt16, math.MaxInt16
+	}
+}
+
+// edfDigitalRange returns the range of digital values used to store the signal
+// in an EDF file. Integer values are stored as is (validate rejects ranges
+// wider than the 16-bit samples of the file), and float values are rescaled to
+// the full 16-bit range.
+func (s Signal) edfDigitalRange() (dmin, dmax int) {
+	if s.sampleFormat() == SampleFormatFloat32 {
+		return math.MinInt16, math.MaxInt16
+	}
+	return s.digitalRange()
+}
+
+// validate checks the sample format and digital range of the signal are
+// supported.
+func (s Signal) validate() error {
+	var formatMin, formatMax int
+	switch s.sampleFormat() {
+	case SampleFormatInt16:
+		formatMin, formatMax = math.MinInt16, math.MaxInt16
+	case SampleFormatInt24:
+		formatMin, formatMax = -1<<23, 1<<23-1
+	case SampleFormatInt32:
+		formatMin, formatMax = math.MinInt32, math.MaxInt32
+	case SampleFormatFloat32:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sample format %q for signal %q", s.SampleFormat, s.Name)
+	}
+
+	dmin, dmax := s.digitalRange()
+	if dmin >= dmax || dmin < formatMin || dmax > formatMax {
+		return fmt.Errorf("invalid digital range [%d, %d] for signal %q", dmin, dmax, s.Name)
+	}
+	// Rescaling wider values would lose their resolution.
+	if dmin < math.MinInt16 || dmax > math.MaxInt16 {
+		return fmt.Errorf("digital range [%d, %d] of signal %q is wider than the 16-bit samples of an EDF file", dmin, dmax, s.Name)
+	}
+	return nil
+}
+
+// physicalValue converts a value reported by a device to a physical value.
+func (s Signal) physicalValue(value float64) float64 {
+	if s.sampleFormat() == SampleFormatFloat32 {
+		return value
+	}
+
+	dmin, dmax := s.digitalRange()
+	pmin, pmax := float64(s.Min), float64(s.Max)
+	return pmin + (value-float64(dmin))*(pmax-pmin)/float64(dmax-dmin)
+}
+
+// ProtocolVersion is the newest version of the device protocol supported by the
+// recorder. Devices speaking an older version are still supported, with fewer
+// capabilities (firmware predating the handshake is version 0).
+const ProtocolVersion = 1
+
+// Capability is an optional feature of the device protocol.
+type Capability string
+
+const (
+	// CapabilityLeadOff is sending openpsg.leadoff notifications.
+	CapabilityLeadOff Capability = "leadoff"
+	// CapabilityImpedance is measuring the electrode impedance of signals.
+	CapabilityImpedance Capability = "impedance"
+	// CapabilityBinaryStreaming is streaming values in a binary encoding.
+	CapabilityBinaryStreaming Capability = "binary"
+	// CapabilityTime is responding to openpsg.time requests, to measure the
+	// clock offset of the device.
+	CapabilityTime Capability = "time"
+	// CapabilityStatus is sending openpsg.status notifications.
+	CapabilityStatus Capability = "status"
+	// CapabilityBattery is reporting the battery charge in status notifications.
+	CapabilityBattery Capability = "battery"
+	// CapabilityTemperature is reporting the temperature of the device in
+	// status notifications.
+	CapabilityTemperature Capability = "temperature"
+	// CapabilityCompression is compressing the frames it sends, with an
+	// algorithm negotiated by an openpsg.compression request.
+	CapabilityCompression Capability = "compression"
+	// CapabilityConfigure is configuring the sample rate, gain and enabled
+	// state of signals (see Client.Configure).
+	CapabilityConfigure Capability = "configure"
+	// CapabilityEvents is sending openpsg.event notifications (see
+	// DeviceEvent).
+	CapabilityEvents Capability = "events"
+	// CapabilityFirmwareUpdate is updating the firmware of the device (see
+	// Client.UpdateFirmware).
+	CapabilityFirmwareUpdate Capability = "update"
+	// CapabilityIdentify is blinking an LED on request, to tell which device is
+	// which (see Client.Identify).
+	CapabilityIdentify Capability = "identify"
+	// CapabilityIdentity is proving the identity of the device, by signing a
+	// challenge with its private key (see Client.Identity).
+	CapabilityIdentity Capability = "identity"
+	// CapabilityProvisioning is being provisioned with a certificate (see
+	// Client.CertificateRequest).
+	CapabilityProvisioning Capability = "provision"
+	// CapabilityUDP is streaming values over UDP (see WithUDPStreaming).
+	CapabilityUDP Capability = "udp"
+	// CapabilityLink is reporting its link to the network (see Client.Link).
+	CapabilityLink Capability = "link"
+)
+
+// DeviceVersion is the version of the protocol spoken by a device, and its
+// optional capabilities.
+type DeviceVersion struct {
+	Version      int
+	Capabilities []Capability
+}
+
+// DeviceInfo identifies a device, for traceability.
+type DeviceInfo struct {
+	SerialNumber    string `json:"serialNumber"`
+	Model           string `json:"model"`
+	FirmwareVersion string `json:"firmwareVersion"`
+}
+
+// String returns a description of the device (eg. "OpenPSG-NCPT 0A1B2C (0.1.0)").
+func (i DeviceInfo) String() string {
+	var parts []string
+	for _, part := range []string{i.Model, i.SerialNumber} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if i.FirmwareVersion != "" {
+		parts = append(parts, "("+i.FirmwareVersion+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// has reports whether the device supports an optional feature of the protocol.
+func (v DeviceVersion) has(capability Capability) bool {
+	return slices.Contains(v.Capabilities, capability)
+}
+
+// ErrIncompatibleDevice is returned when connecting to a device speaking a
+// newer version of the protocol than the recorder.
+var ErrIncompatibleDevice = errors.New("incompatible device")
+
+// LeadOffStatus is sent by a device when the sensor of a signal is detached
+// or reattached (or the electrode impedance changes significantly).
+type LeadOffStatus struct {
+	// The unique identifier of the signal.
+	ID uint32
+	// When the status changed.
+	Timestamp time.Time
+	// The sensor is detached (or the electrode impedance too high to record).
+	LeadOff bool `json:"leadOff"`
+	// The electrode impedance in ohms (zero if not measured).
+	Impedance float64
+}
+
+// SequenceErrors counts the batches of values of a signal lost or duplicated
+// in transit, detected from their sequence numbers.
+type SequenceErrors struct {
+	Lost       uint64
+	Duplicated uint64
+}
+
+// LinkInfo describes the link of a device to the network.
+type LinkInfo struct {
+	// The type of link (eg. "wifi" or "ethernet").
+	Type string `json:"type"`
+	// The speed of the link in Mbit/s.
+	Speed float64 `json:"speed"`
+	// The signal strength of a wireless link in dBm (nil if not wireless).
+	RSSI *float64 `json:"rssi,omitempty"`
+}
+
+// String returns a description of the link (eg. "wifi 72 Mbit/s, -58 dBm").
+func (l LinkInfo) String() string {
+	s := fmt.Sprintf("%s %g Mbit/s", l.Type, l.Speed)
+	if l.RSSI != nil {
+		s += fmt.Sprintf(", %.0f dBm", *l.RSSI)
+	}
+	return strings.TrimSpace(s)
+}
+
+// DeviceStatus is sent by a device once a second, reporting its health.
+type DeviceStatus struct {
+	// When the status was reported.
+	Timestamp time.Time
+	// The charge of the battery in percent (nil if not reported).
+	Battery *float64
+	// The temperature of the device in degrees Celsius (nil if not reported).
+	Temperature *float64
+	// The time since the device started, in seconds.
+	Uptime float64
 }
 
 type SignalValues struct {
 	// The unique identifier of the signal these values belong to.
 	ID uint32
+	// The sequence number of the values, incremented with each batch of values
+	// of the signal and wrapping around at 32 bits (nil if not sent by the
+	// device).
+	Seq *uint64 `json:"seq,omitempty"`
 	// The start timestamp of the values.
 	Timestamp time.Time
-	// The list of values.
-	Values []int16
+	// The list of values, in the sample format of the signal.
+	Values []float64
 }
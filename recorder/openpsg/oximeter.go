@@ -0,0 +1,389 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/serial"
+)
+
+// OximeterProtocol is the serial protocol of a pulse oximeter.
+type OximeterProtocol string
+
+const (
+	// OximeterNonin is Nonin data format 2 (eg. the WristOx2 3150 and Xpod),
+	// at 9600 baud.
+	OximeterNonin OximeterProtocol = "nonin"
+	// OximeterContec is the live data stream of Contec CMS50 oximeters (eg.
+	// the CMS50D+), at 19200 baud.
+	OximeterContec OximeterProtocol = "contec"
+)
+
+// The IDs of the signals of a pulse oximeter.
+const (
+	oximeterSpO2 uint32 = iota + 1
+	oximeterPulse
+	oximeterPleth
+)
+
+// ParseOximeterProtocol parses the name of an oximeter protocol.
+func ParseOximeterProtocol(s string) (OximeterProtocol, error) {
+	switch p := OximeterProtocol(strings.ToLower(s)); p {
+	case OximeterNonin, OximeterContec:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown oximeter protocol %q (expected nonin or contec)", s)
+	}
+}
+
+// baud returns the baud rate of the protocol.
+func (p OximeterProtocol) baud() int {
+	if p == OximeterContec {
+		return 19200
+	}
+	return 9600
+}
+
+// plethRate returns the sample rate of the plethysmogram.
+func (p OximeterProtocol) plethRate() uint32 {
+	if p == OximeterContec {
+		return 60
+	}
+	return 75
+}
+
+// decoder returns a decoder for the protocol.
+func (p OximeterProtocol) decoder() oximeterDecoder {
+	if p == OximeterContec {
+		return &contecDecoder{}
+	}
+	return &noninDecoder{frame: -1}
+}
+
+// oximeterSample is a sample of the plethysmogram, along with the saturation
+// and pulse rate if they were updated (-1 if not, 0 if unknown). While the
+// oximeter is out of track (unable to find a pulse) both are unknown.
+type oximeterSample struct {
+	pleth     float64
+	spo2      float64
+	pulse     float64
+	sensorOff bool
+}
+
+// oximeterDecoder decodes the byte stream of an oximeter.
+type oximeterDecoder interface {
+	// decode adds a byte, returning a sample once one is complete.
+	decode(b byte) (oximeterSample, bool)
+}
+
+// noninDecoder decodes Nonin data format 2: 5 byte frames (start, status,
+// pleth, a value depending on the frame, checksum), 25 frames per packet. The
+// status has the sensor disconnected (0x40), out of track (0x20) and packet
+// sync (0x01) flags.
+type noninDecoder struct {
+	buf []byte
+	// The index of the frame in the packet (from one), -1 if not synchronized.
+	frame   int
+	pulseHi byte
+}
+
+func (d *noninDecoder) decode(b byte) (oximeterSample, bool) {
+	d.buf = append(d.buf, b)
+	if len(d.buf) < 5 {
+		return oximeterSample{}, false
+	}
+
+	frame := d.buf[:5]
+	if frame[0] != 0x01 || frame[1]&0x80 == 0 || frame[0]+frame[1]+frame[2]+frame[3] != frame[4] {
+		// Resynchronize on the next byte.
+		d.buf = d.buf[:copy(d.buf, d.buf[1:])]
+		d.frame = -1
+		return oximeterSample{}, false
+	}
+	d.buf = d.buf[:0]
+
+	status, value := frame[1], frame[3]
+	if status&0x01 != 0 {
+		d.frame = 1
+	} else if d.frame > 0 {
+		d.frame++
+	}
+
+	sample := oximeterSample{pleth: float64(frame[2]), spo2: -1, pulse: -1, sensorOff: status&0x40 != 0}
+	switch d.frame {
+	case 1:
+		d.pulseHi = value
+	case 2:
+		if pulse := int(d.pulseHi&0x03)<<7 | int(value&0x7f); pulse != 511 {
+			sample.pulse = float64(pulse)
+		} else {
+			sample.pulse = 0
+		}
+	case 3:
+		if spo2 := value & 0x7f; spo2 != 127 {
+			sample.spo2 = float64(spo2)
+		} else {
+			sample.spo2 = 0
+		}
+	}
+	if status&0x20 != 0 {
+		sample.spo2, sample.pulse = 0, 0
+	}
+
+	return sample, true
+}
+
+// contecDecoder decodes the 5 byte packets of Contec CMS50 oximeters, where
+// only the first byte has its high bit set. The third byte has the probe error
+// (0x10) and pulse searching (0x20) flags.
+type contecDecoder struct {
+	buf []byte
+}
+
+func (d *contecDecoder) decode(b byte) (oximeterSample, bool) {
+	if b&0x80 != 0 {
+		d.buf = d.buf[:0]
+	} else if len(d.buf) == 0 {
+		// Not synchronized.
+		return oximeterSample{}, false
+	}
+
+	d.buf = append(d.buf, b)
+	if len(d.buf) < 5 {
+		return oximeterSample{}, false
+	}
+
+	packet := d.buf
+	d.buf = d.buf[:0]
+
+	sample := oximeterSample{pleth: float64(packet[1] & 0x7f), sensorOff: packet[2]&0x10 != 0}
+	if pulse := int(packet[2]&0x40)<<1 | int(packet[3]&0x7f); pulse != 0xff {
+		sample.pulse = float64(pulse)
+	}
+	if spo2 := packet[4] & 0x7f; spo2 != 0x7f {
+		sample.spo2 = float64(spo2)
+	}
+	if packet[2]&0x20 != 0 {
+		sample.spo2, sample.pulse = 0, 0
+	}
+
+	return sample, true
+}
+
+// OximeterSource streams the saturation, pulse rate and plethysmogram of a
+// pulse oximeter attached over serial (eg. USB).
+type OximeterSource struct {
+	port     *os.File
+	protocol OximeterProtocol
+	signals  []Signal
+
+	signalValues chan SignalValues
+	leadOff      chan LeadOffStatus
+	disconnected chan struct{}
+
+	mu      sync.Mutex
+	enabled map[uint32]bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func init() {
+	for _, protocol := range []OximeterProtocol{OximeterNonin, OximeterContec} {
+		RegisterDriver(string(protocol), func(port string, _ ...ConnectOption) (netip.Addr, SourceOpener, error) {
+			return netip.Addr{}, OpenOximeter(port, protocol), nil
+		})
+	}
+}
+
+// OpenOximeter returns an opener for the pulse oximeter at the serial port
+// path (eg. "/dev/ttyUSB0").
+func OpenOximeter(path string, protocol OximeterProtocol) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		port, err := serial.Open(path, protocol.baud())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open oximeter: %w", err)
+		}
+
+		readCtx, cancel := context.WithCancel(context.Background())
+		s := &OximeterSource{
+			port:     port,
+			protocol: protocol,
+			signals: []Signal{
+				{ID: oximeterSpO2, Name: "SpO2", Unit: Percent, Min: 0, Max: 100,
+					SampleFormat: SampleFormatFloat32, SampleRate: 1, LeadOffDetection: true},
+				{ID: oximeterPulse, Name: "Pulse", Unit: BeatsPerMinute, Min: 0, Max: 300,
+					SampleFormat: SampleFormatFloat32, SampleRate: 1},
+				{ID: oximeterPleth, Name: "Pleth", Min: 0, Max: 255,
+					SampleFormat: SampleFormatFloat32, SampleRate: protocol.plethRate()},
+			},
+			signalValues: make(chan SignalValues),
+			leadOff:      make(chan LeadOffStatus, leadOffBufferSize),
+			disconnected: make(chan struct{}),
+			enabled:      make(map[uint32]bool),
+			cancel:       cancel,
+			done:         make(chan struct{}),
+		}
+		go s.read(readCtx)
+
+		return s, nil
+	}
+}
+
+// Signals returns the signals of the oximeter.
+func (s *OximeterSource) Signals(ctx context.Context) ([]Signal, error) {
+	return s.signals, nil
+}
+
+// Start streaming the specified signals.
+func (s *OximeterSource) Start(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		s.enabled[id] = true
+	}
+	return nil
+}
+
+// Stop streaming the specified signals.
+func (s *OximeterSource) Stop(ctx context.Context, signalIDs []uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		delete(s.enabled, id)
+	}
+	return nil
+}
+
+// Disconnected returns a channel that is closed when the oximeter is detached
+// (or closed).
+func (s *OximeterSource) Disconnected() <-chan struct{} {
+	return s.disconnected
+}
+
+// SignalValues returns a channel that will receive the values of the signals.
+func (s *OximeterSource) SignalValues() <-chan SignalValues {
+	return s.signalValues
+}
+
+// LeadOff returns a channel that will receive changes in whether the sensor is
+// attached. Changes are dropped if they aren't received promptly.
+func (s *OximeterSource) LeadOff() <-chan LeadOffStatus {
+	return s.leadOff
+}
+
+func (s *OximeterSource) Close() error {
+	s.cancel()
+	// Closing the port interrupts any pending read.
+	err := s.port.Close()
+	<-s.done
+
+	close(s.signalValues)
+	close(s.leadOff)
+
+	return err
+}
+
+// read decodes the data from the oximeter, sending the values of the enabled
+// signals once a second.
+func (s *OximeterSource) read(ctx context.Context) {
+	defer close(s.done)
+	defer close(s.disconnected)
+
+	decoder := s.protocol.decoder()
+	clock := sampleClock{rate: float64(s.protocol.plethRate())}
+
+	// The sensor is assumed to be attached until the oximeter reports otherwise.
+	var spo2, pulse float64
+	var sensorOff bool
+	pleth := make([]float64, 0, s.protocol.plethRate())
+
+	r := bufio.NewReader(s.port)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Failed to read from oximeter", slog.Any("error", err))
+			}
+			return
+		}
+
+		sample, ok := decoder.decode(b)
+		if !ok {
+			continue
+		}
+
+		if sample.spo2 >= 0 {
+			spo2 = sample.spo2
+		}
+		if sample.pulse >= 0 {
+			pulse = sample.pulse
+		}
+
+		if sample.sensorOff != sensorOff {
+			sensorOff = sample.sensorOff
+			select {
+			case s.leadOff <- LeadOffStatus{ID: oximeterSpO2, Timestamp: time.Now(), LeadOff: sensorOff}:
+			default:
+				slog.Warn("Dropped oximeter sensor status")
+			}
+		}
+
+		pleth = append(pleth, sample.pleth)
+		if len(pleth) < cap(pleth) {
+			continue
+		}
+
+		timestamp := clock.timestamp(len(pleth))
+		for _, sv := range []SignalValues{
+			{ID: oximeterSpO2, Timestamp: timestamp, Values: []float64{spo2}},
+			{ID: oximeterPulse, Timestamp: timestamp, Values: []float64{pulse}},
+			{ID: oximeterPleth, Timestamp: timestamp, Values: append([]float64(nil), pleth...)},
+		} {
+			if !s.isEnabled(sv.ID) {
+				continue
+			}
+
+			select {
+			case s.signalValues <- sv:
+			case <-ctx.Done():
+				return
+			}
+		}
+		pleth = pleth[:0]
+	}
+}
+
+func (s *OximeterSource) isEnabled(id uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enabled[id]
+}
@@ -0,0 +1,180 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+// noninFrame encodes a Nonin data format 2 frame.
+func noninFrame(status, pleth, value byte) []byte {
+	status |= 0x80
+	return []byte{0x01, status, pleth, value, 0x01 + status + pleth + value}
+}
+
+// noninPacket encodes the first three frames of a packet, with the pulse rate
+// and saturation.
+func noninPacket(status byte, pulse int, spo2 byte) []byte {
+	return slices.Concat(
+		noninFrame(status|0x01, 10, byte(pulse>>7)),
+		noninFrame(status, 20, byte(pulse&0x7f)),
+		noninFrame(status, 30, spo2),
+	)
+}
+
+func TestNoninDecoder(t *testing.T) {
+	// A packet with a sync frame, and the pulse rate and saturation.
+	packet := func(pulse, spo2 float64, sensorOff bool) []openpsg.OximeterSample {
+		return []openpsg.OximeterSample{
+			{Pleth: 10, SpO2: -1, Pulse: -1, SensorOff: sensorOff},
+			{Pleth: 20, SpO2: -1, Pulse: pulse, SensorOff: sensorOff},
+			{Pleth: 30, SpO2: spo2, Pulse: -1, SensorOff: sensorOff},
+		}
+	}
+
+	corrupt := noninFrame(0, 20, 72)
+	corrupt[4]++
+
+	tests := []struct {
+		name string
+		data []byte
+		want []openpsg.OximeterSample
+	}{
+		{
+			name: "Packet",
+			data: noninPacket(0, 72, 97),
+			want: packet(72, 97, false),
+		},
+		{
+			name: "High pulse rate",
+			data: noninPacket(0, 300, 97),
+			want: packet(300, 97, false),
+		},
+		{
+			name: "Garbage",
+			data: slices.Concat([]byte{0x55, 0x01, 0x02, 0xff}, noninPacket(0, 72, 97)),
+			want: packet(72, 97, false),
+		},
+		{
+			name: "Bad checksum",
+			// The frame is dropped, and the frames after it aren't placed in
+			// the packet until the next sync frame.
+			data: slices.Concat(noninFrame(0x01, 10, 0), corrupt, noninFrame(0, 30, 97), noninPacket(0, 72, 96)),
+			want: append([]openpsg.OximeterSample{
+				{Pleth: 10, SpO2: -1, Pulse: -1},
+				{Pleth: 30, SpO2: -1, Pulse: -1},
+			}, packet(72, 96, false)...),
+		},
+		{
+			name: "Missing status bit",
+			data: slices.Concat([]byte{0x01, 0x01, 20, 72, 0x01 + 0x01 + 20 + 72}, noninPacket(0, 72, 97)),
+			want: packet(72, 97, false),
+		},
+		{
+			name: "Sensor off",
+			data: noninPacket(0x40, 511, 127),
+			want: packet(0, 0, true),
+		},
+		{
+			name: "Out of track",
+			data: noninPacket(0x20, 72, 97),
+			want: []openpsg.OximeterSample{
+				{Pleth: 10},
+				{Pleth: 20},
+				{Pleth: 30},
+			},
+		},
+		{
+			name: "Missing pulse rate",
+			data: noninPacket(0, 511, 97),
+			want: packet(0, 97, false),
+		},
+		{
+			name: "Missing saturation",
+			data: noninPacket(0, 72, 127),
+			want: packet(72, 0, false),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, openpsg.DecodeOximeter(openpsg.OximeterNonin, tt.data))
+		})
+	}
+}
+
+func TestContecDecoder(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []openpsg.OximeterSample
+	}{
+		{
+			name: "Packet",
+			data: []byte{0x80, 50, 0x00, 72, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50, SpO2: 97, Pulse: 72}},
+		},
+		{
+			name: "High pulse rate",
+			data: []byte{0x80, 50, 0x40, 44, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50, SpO2: 97, Pulse: 172}},
+		},
+		{
+			name: "Garbage",
+			data: []byte{0x10, 0x20, 0x30, 0x80, 50, 0x00, 72, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50, SpO2: 97, Pulse: 72}},
+		},
+		{
+			name: "Truncated",
+			// A sync byte restarts the packet.
+			data: []byte{0x80, 40, 0x00, 0x80, 50, 0x00, 72, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50, SpO2: 97, Pulse: 72}},
+		},
+		{
+			name: "Sensor off",
+			data: []byte{0x80, 0, 0x10 | 0x40, 0x7f, 0x7f},
+			want: []openpsg.OximeterSample{{SensorOff: true}},
+		},
+		{
+			name: "Out of track",
+			data: []byte{0x80, 50, 0x20, 72, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50}},
+		},
+		{
+			name: "Missing pulse rate",
+			data: []byte{0x80, 50, 0x40, 0x7f, 97},
+			want: []openpsg.OximeterSample{{Pleth: 50, SpO2: 97}},
+		},
+		{
+			name: "Missing saturation",
+			data: []byte{0x80, 50, 0x00, 72, 0x7f},
+			want: []openpsg.OximeterSample{{Pleth: 50, Pulse: 72}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, openpsg.DecodeOximeter(openpsg.OximeterContec, tt.data))
+		})
+	}
+}
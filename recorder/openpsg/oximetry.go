@@ -0,0 +1,207 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Saturation is compared to its average over this period before each
+	// desaturation.
+	desaturationBaselineDuration = 2 * time.Minute
+	// Desaturations aren't detected until there is at least this much baseline.
+	minDesaturationBaseline = 30 * time.Second
+	// A desaturation is a drop in saturation of at least 3% (counted separately
+	// for the 3% and 4% oxygen desaturation indices), lasting at least 10
+	// seconds.
+	minDesaturation         = 3.0
+	minDesaturationDuration = 10 * time.Second
+)
+
+// OximetrySummary summarizes the desaturations detected in an oximetry signal.
+type OximetrySummary struct {
+	Signal string
+	// The time with valid saturation values.
+	Duration time.Duration
+	// The number of desaturations of at least 3% and 4%.
+	Desaturations3 int
+	Desaturations4 int
+}
+
+// ODI3 returns the number of desaturations of at least 3% per hour.
+func (s OximetrySummary) ODI3() float64 {
+	return s.perHour(s.Desaturations3)
+}
+
+// ODI4 returns the number of desaturations of at least 4% per hour.
+func (s OximetrySummary) ODI4() float64 {
+	return s.perHour(s.Desaturations4)
+}
+
+func (s OximetrySummary) perHour(n int) float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(n) / s.Duration.Hours()
+}
+
+// isOximetrySignal returns true if the signal is an oxygen saturation signal
+// (eg. "SpO2" or "SaO2 finger").
+func isOximetrySignal(signal Signal) bool {
+	name := strings.ToUpper(signal.Name)
+	return strings.Contains(name, "SPO2") || strings.Contains(name, "SAO2")
+}
+
+// desaturationDetector flags drops in oxygen saturation relative to its recent
+// baseline, and counts them for the oxygen desaturation index (ODI).
+type desaturationDetector struct {
+	mu         sync.Mutex
+	name       string
+	sampleRate float64
+
+	// The current one second block, starting at blockStart.
+	block      []float64
+	blockStart time.Time
+
+	// The saturation of recent seconds outside of desaturations.
+	baseline []float64
+
+	// The current desaturation, if any.
+	eventStart    time.Time
+	eventBaseline float64
+	nadir         float64
+
+	validSeconds   int
+	desaturations3 int
+	desaturations4 int
+}
+
+func newDesaturationDetector(name string, sampleRate uint32) *desaturationDetector {
+	return &desaturationDetector{
+		name:       name,
+		sampleRate: float64(sampleRate),
+		block:      make([]float64, 0, max(sampleRate, 1)),
+	}
+}
+
+// observe adds values (in percent) starting at timestamp, returning any
+// completed desaturations.
+func (d *desaturationDetector) observe(timestamp time.Time, values []float64) []Annotation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var events []Annotation
+	for i, value := range values {
+		if len(d.block) == 0 {
+			d.blockStart = timestamp.Add(time.Duration(float64(i) / d.sampleRate * float64(time.Second)))
+		}
+
+		d.block = append(d.block, value)
+		if len(d.block) < cap(d.block) {
+			continue
+		}
+
+		if event, ok := d.endBlock(); ok {
+			events = append(events, event)
+		}
+		d.block = d.block[:0]
+	}
+
+	return events
+}
+
+// endBlock updates the saturation with the completed block, returning an event
+// if a desaturation has ended.
+func (d *desaturationDetector) endBlock() (Annotation, bool) {
+	// Oximeters report out of range values (eg. zero) when the probe is off,
+	// seconds with mostly invalid values are ignored.
+	var sum float64
+	var valid int
+	for _, value := range d.block {
+		if value > 0 && value <= 100 {
+			sum += value
+			valid++
+		}
+	}
+	if valid < len(d.block)/2 || valid == 0 {
+		return Annotation{}, false
+	}
+
+	saturation := sum / float64(valid)
+	d.validSeconds++
+
+	if len(d.baseline) < int(minDesaturationBaseline.Seconds()) {
+		d.baseline = appendWindow(d.baseline, saturation, int(desaturationBaselineDuration.Seconds()))
+		return Annotation{}, false
+	}
+
+	if d.eventStart.IsZero() {
+		if baseline := mean(d.baseline); saturation <= baseline-minDesaturation {
+			d.eventStart = d.blockStart
+			d.eventBaseline = baseline
+			d.nadir = saturation
+			return Annotation{}, false
+		}
+
+		d.baseline = appendWindow(d.baseline, saturation, int(desaturationBaselineDuration.Seconds()))
+		return Annotation{}, false
+	}
+
+	if saturation <= d.eventBaseline-minDesaturation {
+		d.nadir = min(d.nadir, saturation)
+		return Annotation{}, false
+	}
+
+	start := d.eventStart
+	duration := d.blockStart.Sub(start)
+	d.eventStart = time.Time{}
+	if duration < minDesaturationDuration {
+		return Annotation{}, false
+	}
+
+	drop := math.Floor(d.eventBaseline - d.nadir)
+	d.desaturations3++
+	if drop >= 4 {
+		d.desaturations4++
+	}
+
+	return Annotation{
+		Time:     start,
+		Duration: duration,
+		Text:     fmt.Sprintf("Desaturation %.0f%%: %s", drop, d.name),
+	}, true
+}
+
+// summary returns the desaturations detected so far.
+func (d *desaturationDetector) summary() OximetrySummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return OximetrySummary{
+		Signal:         d.name,
+		Duration:       time.Duration(d.validSeconds) * time.Second,
+		Desaturations3: d.desaturations3,
+		Desaturations4: d.desaturations4,
+	}
+}
@@ -0,0 +1,156 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spo2 is a segment of a saturation trace at 1 Hz.
+type spo2 struct {
+	seconds int
+	value   float64
+}
+
+// spo2Trace returns the values of the segments.
+func spo2Trace(segments ...spo2) []float64 {
+	var values []float64
+	for _, s := range segments {
+		values = append(values, slices.Repeat([]float64{s.value}, s.seconds)...)
+	}
+	return values
+}
+
+func TestDesaturationDetector(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+
+	tests := []struct {
+		name  string
+		trace []float64
+		// The expected desaturations, by their start (in seconds) and
+		// duration.
+		wantEvents []openpsg.Annotation
+		want3      int
+		want4      int
+		// The expected seconds with valid values.
+		wantSeconds int
+	}{
+		{
+			name:        "3% dip",
+			trace:       spo2Trace(spo2{60, 96}, spo2{20, 93}, spo2{20, 96}),
+			wantEvents:  []openpsg.Annotation{{Time: at(60), Duration: 20 * time.Second, Text: "Desaturation 3%: SpO2"}},
+			want3:       1,
+			wantSeconds: 100,
+		},
+		{
+			name:        "4% dip",
+			trace:       spo2Trace(spo2{60, 96}, spo2{20, 92}, spo2{20, 96}),
+			wantEvents:  []openpsg.Annotation{{Time: at(60), Duration: 20 * time.Second, Text: "Desaturation 4%: SpO2"}},
+			want3:       1,
+			want4:       1,
+			wantSeconds: 100,
+		},
+		{
+			name:        "2% dip",
+			trace:       spo2Trace(spo2{60, 96}, spo2{20, 94}, spo2{20, 96}),
+			wantSeconds: 100,
+		},
+		{
+			name:        "Minimum duration",
+			trace:       spo2Trace(spo2{60, 96}, spo2{10, 93}, spo2{20, 96}),
+			wantEvents:  []openpsg.Annotation{{Time: at(60), Duration: 10 * time.Second, Text: "Desaturation 3%: SpO2"}},
+			want3:       1,
+			wantSeconds: 90,
+		},
+		{
+			name:        "Too short",
+			trace:       spo2Trace(spo2{60, 96}, spo2{9, 90}, spo2{20, 96}),
+			wantSeconds: 89,
+		},
+		{
+			name: "Before the baseline",
+			// A baseline of at least 30 seconds is needed.
+			trace:       spo2Trace(spo2{10, 96}, spo2{15, 90}, spo2{35, 96}),
+			wantSeconds: 60,
+		},
+		{
+			name: "Probe off",
+			// Invalid values are ignored, rather than being desaturations.
+			trace:       spo2Trace(spo2{60, 96}, spo2{20, 0}, spo2{20, 96}),
+			wantSeconds: 80,
+		},
+		{
+			name: "Repeated dips",
+			trace: spo2Trace(
+				spo2{60, 96}, spo2{15, 93}, spo2{45, 96},
+				spo2{15, 91}, spo2{45, 96},
+				spo2{15, 92}, spo2{45, 96},
+			),
+			wantEvents: []openpsg.Annotation{
+				{Time: at(60), Duration: 15 * time.Second, Text: "Desaturation 3%: SpO2"},
+				{Time: at(120), Duration: 15 * time.Second, Text: "Desaturation 5%: SpO2"},
+				{Time: at(180), Duration: 15 * time.Second, Text: "Desaturation 4%: SpO2"},
+			},
+			want3:       3,
+			want4:       2,
+			wantSeconds: 240,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := openpsg.NewDesaturationDetector("SpO2", 1)
+
+			// The trace arrives in batches of 5 seconds.
+			var events []openpsg.Annotation
+			for i := 0; i < len(tt.trace); i += 5 {
+				events = append(events, d.Observe(at(i), tt.trace[i:min(i+5, len(tt.trace))])...)
+			}
+			assert.Equal(t, tt.wantEvents, events)
+
+			summary := d.Summary()
+			assert.Equal(t, tt.want3, summary.Desaturations3)
+			assert.Equal(t, tt.want4, summary.Desaturations4)
+			require.Equal(t, time.Duration(tt.wantSeconds)*time.Second, summary.Duration)
+
+			hours := float64(tt.wantSeconds) / 3600
+			assert.InDelta(t, float64(tt.want3)/hours, summary.ODI3(), 1e-9)
+			assert.InDelta(t, float64(tt.want4)/hours, summary.ODI4(), 1e-9)
+		})
+	}
+}
+
+func TestOximetrySummary(t *testing.T) {
+	// 6 desaturations of at least 3% (3 of at least 4%) in 2 hours.
+	summary := openpsg.OximetrySummary{Duration: 2 * time.Hour, Desaturations3: 6, Desaturations4: 3}
+	assert.Equal(t, 3.0, summary.ODI3())
+	assert.Equal(t, 1.5, summary.ODI4())
+
+	// No valid saturation.
+	assert.Zero(t, openpsg.OximetrySummary{Desaturations3: 1}.ODI3())
+}
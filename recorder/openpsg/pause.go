@@ -0,0 +1,81 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import "time"
+
+// WithPause pauses the recording when true is received on the channel, and
+// resumes it when false is received. Data records that are entirely paused are
+// not written, leaving a gap in the recording (EDF+D).
+func WithPause(pause <-chan bool) RecordOption {
+	return func(o *recordOptions) {
+		o.pause = pause
+	}
+}
+
+// pauseInterval is a period during which the recording was paused.
+type pauseInterval struct {
+	start time.Time
+	// Zero while the recording is paused.
+	end time.Time
+}
+
+// pauses tracks when the recording was paused, as data records are written
+// some time after the period they cover.
+type pauses struct {
+	intervals []pauseInterval
+}
+
+// paused returns true if the recording is currently paused.
+func (p *pauses) paused() bool {
+	n := len(p.intervals)
+	return n > 0 && p.intervals[n-1].end.IsZero()
+}
+
+// set pauses or resumes the recording at time t. It returns false if the
+// recording was already in that state.
+func (p *pauses) set(pause bool, t time.Time) bool {
+	if pause == p.paused() {
+		return false
+	}
+
+	if pause {
+		p.intervals = append(p.intervals, pauseInterval{start: t})
+	} else {
+		p.intervals[len(p.intervals)-1].end = t
+	}
+
+	return true
+}
+
+// covers returns true if the recording was paused for the whole of [from, to).
+// Pauses that ended before from are forgotten.
+func (p *pauses) covers(from, to time.Time) bool {
+	for len(p.intervals) > 0 && !p.intervals[0].end.IsZero() && !p.intervals[0].end.After(from) {
+		p.intervals = p.intervals[1:]
+	}
+
+	for _, interval := range p.intervals {
+		if !interval.start.After(from) && (interval.end.IsZero() || !interval.end.Before(to)) {
+			return true
+		}
+	}
+
+	return false
+}
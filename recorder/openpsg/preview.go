@@ -0,0 +1,187 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// How often the preview is shown.
+const previewRefreshInterval = 100 * time.Millisecond
+
+// Preview configures the live preview of a signal (see PreviewSignal).
+type Preview struct {
+	// The name of the signal (the first signal of the device if empty).
+	Signal string
+	// How much of the signal is shown at once.
+	Window time.Duration
+}
+
+// PreviewFrame is the latest window of a signal being previewed, as shown to
+// the operator.
+type PreviewFrame struct {
+	Signal Signal
+	// The physical values of the window, the newest last (fewer than Samples
+	// until the window has filled).
+	Values []float64
+	// The number of values in a full window.
+	Samples int
+	Quality SignalQuality
+	LeadOff bool
+}
+
+// PreviewView shows a signal while it is previewed (see PreviewSignal), and
+// decides when to stop.
+type PreviewView interface {
+	// Show is called with the latest window of the signal, several times a
+	// second.
+	Show(frame PreviewFrame)
+	// Done is closed once the operator has finished previewing the signal.
+	Done() <-chan struct{}
+}
+
+// PreviewSignal streams a signal of the device, showing it along with its
+// quality until the operator is done (eg. to verify the hookup of a sensor
+// before starting a study). The signal is shown by the view (eg. as a
+// scrolling waveform), or changes to its quality are logged (until Enter is
+// pressed) if the view is nil.
+func PreviewSignal(ctx context.Context, deviceAddrPort netip.AddrPort, preview Preview, view PreviewView, opts ...ConnectOption) error {
+	client, err := Connect(ctx, deviceAddrPort, opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	signals, err := client.Signals(ctx)
+	if err != nil {
+		return err
+	}
+
+	var signal *Signal
+	var names []string
+	for i := range signals {
+		if isStatusSignal(signals[i].ID) {
+			continue
+		}
+		names = append(names, signals[i].Name)
+
+		if signal == nil && (preview.Signal == "" || strings.EqualFold(signals[i].Name, preview.Signal)) {
+			signal = &signals[i]
+		}
+	}
+	if signal == nil {
+		return fmt.Errorf("device has no signal %q (signals: %s)", preview.Signal, strings.Join(names, ", "))
+	}
+
+	if err := client.Start(ctx, []uint32{signal.ID}); err != nil {
+		return fmt.Errorf("failed to start streaming: %w", err)
+	}
+	defer func() {
+		_ = client.Stop(context.Background(), []uint32{signal.ID})
+	}()
+
+	if view == nil {
+		view = newPreviewLogView(signal.Name)
+	}
+
+	monitor := newQualityMonitor(signal.Name, signal.SampleRate, float64(signal.Min), float64(signal.Max))
+	samples := max(int(preview.Window.Seconds()*float64(signal.SampleRate)), 1)
+	values := make([]float64, 0, samples)
+	var leadOff bool
+
+	ticker := time.NewTicker(previewRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-view.Done():
+			return nil
+		case v, ok := <-client.SignalValues():
+			if !ok {
+				return fmt.Errorf("lost connection to device")
+			}
+			if v.ID != signal.ID {
+				continue
+			}
+
+			physical := make([]float64, len(v.Values))
+			for i, value := range v.Values {
+				physical[i] = signal.physicalValue(value)
+			}
+
+			monitor.observe(physical)
+
+			values = append(values, physical...)
+			if len(values) > samples {
+				values = append(values[:0], values[len(values)-samples:]...)
+			}
+		case status := <-client.LeadOff():
+			if status.ID == signal.ID {
+				leadOff = status.LeadOff
+			}
+		case <-ticker.C:
+			view.Show(PreviewFrame{
+				Signal:  *signal,
+				Values:  append([]float64(nil), values...),
+				Samples: samples,
+				Quality: monitor.current(),
+				LeadOff: leadOff,
+			})
+		}
+	}
+}
+
+// previewLogView logs changes to the quality and lead-off status of the signal,
+// until Enter is pressed (for when it isn't shown on a dashboard, eg. when
+// stdout isn't a terminal).
+type previewLogView struct {
+	// The problem and lead-off status last logged.
+	problem string
+	leadOff bool
+	done    <-chan struct{}
+}
+
+func newPreviewLogView(signal string) *previewLogView {
+	slog.Info("Previewing signal, press Enter to stop", slog.String("signal", signal))
+
+	return &previewLogView{done: enterPressed()}
+}
+
+func (v *previewLogView) Show(frame PreviewFrame) {
+	if problem := frame.Quality.Problem(); problem != v.problem {
+		v.problem = problem
+		slog.Info("Signal quality changed", slog.String("signal", frame.Signal.Name), slog.String("problem", problem))
+	}
+
+	if frame.LeadOff != v.leadOff {
+		v.leadOff = frame.LeadOff
+		slog.Info("Lead-off status changed", slog.String("signal", frame.Signal.Name), slog.Bool("leadOff", frame.LeadOff))
+	}
+}
+
+func (v *previewLogView) Done() <-chan struct{} {
+	return v.done
+}
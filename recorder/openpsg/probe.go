@@ -0,0 +1,250 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// DurationStats summarizes a set of measured durations.
+type DurationStats struct {
+	Count int
+	Min   time.Duration
+	Mean  time.Duration
+	// The 99th percentile.
+	P99 time.Duration
+	Max time.Duration
+}
+
+func newDurationStats(durations []time.Duration) DurationStats {
+	if len(durations) == 0 {
+		return DurationStats{}
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+
+	return DurationStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Mean:  sum / time.Duration(len(sorted)),
+		P99:   sorted[int(math.Ceil(0.99*float64(len(sorted))))-1],
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// ProbeResult is what was measured of the connection to a device.
+type ProbeResult struct {
+	Device netip.AddrPort
+	// The round-trip times of the requests that were responded to.
+	RTT DurationStats
+	// The number of requests that failed (or timed out).
+	FailedRequests int
+	// The times between consecutive openpsg.values notifications of each
+	// signal.
+	InterArrival DurationStats
+	// The mean difference between consecutive inter-arrival times (as in RFC
+	// 3550), a measure of how unevenly notifications arrive.
+	Jitter time.Duration
+	// The estimated clock offset of the device (if it has the time
+	// capability), and how much the measured offset varied.
+	ClockOffset      ClockOffset
+	ClockOffsetRange time.Duration
+	ClockMeasured    bool
+}
+
+// Probe measures the connection to the device at addrPort for the window:
+// the round-trip time of requests sent every interval (openpsg.time requests
+// if the device has the time capability, also measuring its clock offset,
+// otherwise openpsg.ping), and the jitter of the value notifications of its
+// signals, which are streamed meanwhile.
+func Probe(ctx context.Context, addrPort netip.AddrPort, window, interval time.Duration, opts ...ConnectOption) (ProbeResult, error) {
+	result := ProbeResult{Device: addrPort}
+
+	client, err := Connect(ctx, addrPort, opts...)
+	if err != nil {
+		return result, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	signals, err := client.Signals(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to get signals: %w", err)
+	}
+
+	var signalIDs []uint32
+	for _, signal := range signals {
+		if !isStatusSignal(signal.ID) {
+			signalIDs = append(signalIDs, signal.ID)
+		}
+	}
+
+	if err := client.Start(ctx, signalIDs); err != nil {
+		return result, fmt.Errorf("failed to start signals: %w", err)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	// Notifications are received separately from the requests, so waiting for
+	// a response never delays them (or the other way around).
+	var wg sync.WaitGroup
+	var interArrivals []time.Duration
+	var jitter time.Duration
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		interArrivals, jitter = receiveNotifications(probeCtx, client)
+	}()
+
+	var rtts []time.Duration
+	var clock []ClockOffset
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-probeCtx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		rtt, offset, err := client.probe(probeCtx)
+		if err != nil {
+			if probeCtx.Err() == nil {
+				result.FailedRequests++
+			}
+			continue
+		}
+		rtts = append(rtts, rtt)
+		if offset != nil {
+			clock = append(clock, *offset)
+		}
+	}
+
+	wg.Wait()
+
+	_ = client.Stop(ctx, signalIDs)
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	result.RTT = newDurationStats(rtts)
+	result.InterArrival = newDurationStats(interArrivals)
+	result.Jitter = jitter
+
+	if len(clock) > 0 {
+		var filter clockFilter
+		filter.measurements = clock
+		result.ClockOffset, result.ClockMeasured = filter.estimate()
+
+		offsets := make([]time.Duration, len(clock))
+		for i, m := range clock {
+			offsets[i] = m.Offset
+		}
+		result.ClockOffsetRange = slices.Max(offsets) - slices.Min(offsets)
+	}
+
+	return result, nil
+}
+
+// probe sends a request to the device, returning its round-trip time and, if
+// the device has the time capability, its clock offset.
+func (c *Client) probe(ctx context.Context) (time.Duration, *ClockOffset, error) {
+	rpcConn := c.conn()
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if !c.HasCapability(CapabilityTime) {
+		// Any response will do (firmware without openpsg.ping responds with an
+		// error).
+		start := time.Now()
+		err := rpcConn.Call(ctx, "openpsg.ping", nil, nil)
+		var rpcErr *jsonrpc2.Error
+		if err != nil && !errors.As(err, &rpcErr) {
+			return 0, nil, err
+		}
+		return time.Since(start), nil, nil
+	}
+
+	var t deviceTime
+	t1 := time.Now()
+	if err := rpcConn.Call(ctx, "openpsg.time", nil, &t); err != nil {
+		return 0, nil, err
+	}
+	t4 := time.Now()
+
+	offset := clockOffset(t1, t.Received, t.Transmitted, t4)
+	return t4.Sub(t1), &offset, nil
+}
+
+// receiveNotifications receives value notifications until the context is
+// done, returning the times between consecutive notifications of each signal,
+// and their jitter.
+func receiveNotifications(ctx context.Context, client *Client) ([]time.Duration, time.Duration) {
+	lastArrival := make(map[uint32]time.Time)
+	lastInterArrival := make(map[uint32]time.Duration)
+
+	var interArrivals []time.Duration
+	var variation time.Duration
+	var variations int
+	for {
+		select {
+		case <-ctx.Done():
+			var jitter time.Duration
+			if variations > 0 {
+				jitter = variation / time.Duration(variations)
+			}
+			return interArrivals, jitter
+		case values := <-client.SignalValues():
+			now := time.Now()
+
+			if last, ok := lastArrival[values.ID]; ok {
+				interArrival := now.Sub(last)
+				interArrivals = append(interArrivals, interArrival)
+
+				if previous, ok := lastInterArrival[values.ID]; ok {
+					variation += (interArrival - previous).Abs()
+					variations++
+				}
+				lastInterArrival[values.ID] = interArrival
+			}
+			lastArrival[values.ID] = now
+		}
+	}
+}
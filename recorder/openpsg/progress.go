@@ -0,0 +1,68 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"net/netip"
+	"time"
+)
+
+// RecordingProgress is a snapshot of a recording in progress (eg. to show on a
+// dashboard).
+type RecordingProgress struct {
+	// When the recording started.
+	Start   time.Time
+	Devices []DeviceProgress
+	Signals []SignalProgress
+}
+
+// DeviceProgress is the state of a device being recorded from.
+type DeviceProgress struct {
+	Addr netip.Addr
+	// The friendly name of the device (see DeviceNames), if named.
+	Name      string
+	Connected bool
+	// The number of signal values received from the device.
+	Received uint64
+	// The number of signal values dropped, as they arrived outside of the
+	// buffered window.
+	Dropped uint64
+	// The number of batches of signal values lost in transit (for devices
+	// sending sequence numbers).
+	Lost uint64
+}
+
+// SignalProgress is the state of a signal being recorded.
+type SignalProgress struct {
+	Device  netip.Addr
+	Quality SignalQuality
+	// The fraction of the signal's buffer holding values waiting to be written
+	// (values arriving beyond the buffer are spilled or dropped).
+	BufferFill float64
+	// The number of values spilled to disk (see WithSpill).
+	Spilled int
+}
+
+// WithProgress reports the progress of the recording every interval.
+func WithProgress(interval time.Duration, report func(RecordingProgress)) RecordOption {
+	return func(o *recordOptions) {
+		o.progressInterval = interval
+		o.progressReport = report
+	}
+}
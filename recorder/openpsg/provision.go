@@ -0,0 +1,101 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/netip"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/ca"
+)
+
+// WithPinnedCertificates only accepts devices presenting the certificate they
+// were provisioned with (see Client.InstallCertificate), as looked up by their
+// address. Devices without a pinned certificate (an empty fingerprint) are
+// only verified against the trusted CAs. Requires WithTLS.
+func WithPinnedCertificates(lookup func(addr netip.Addr) (string, error)) ConnectOption {
+	return func(o *connectOptions) {
+		o.pinnedCertificates = lookup
+	}
+}
+
+// verifyPinnedCertificate returns the TLS configuration for connecting to the
+// device at addr, checking the device presents its pinned certificate.
+func verifyPinnedCertificate(config *tls.Config, addr netip.Addr, lookup func(addr netip.Addr) (string, error)) *tls.Config {
+	config = config.Clone()
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		fingerprint, err := lookup(addr)
+		if err != nil {
+			return fmt.Errorf("failed to look up the certificate of the device: %w", err)
+		}
+		if fingerprint == "" {
+			return nil
+		}
+
+		if len(state.PeerCertificates) == 0 || ca.Fingerprint(state.PeerCertificates[0]) != fingerprint {
+			return fmt.Errorf("%w: the device didn't present the certificate it was provisioned with", ErrUnauthorizedDevice)
+		}
+		return nil
+	}
+	return config
+}
+
+// certificateRequest is the response to an openpsg.csr request.
+type certificateRequest struct {
+	// The PEM encoded certificate signing request.
+	CSR string `json:"csr"`
+}
+
+// CertificateRequest asks the device for a certificate signing request for a
+// key it generates (which never leaves the device), to provision it with a
+// certificate.
+func (c *Client) CertificateRequest(ctx context.Context) ([]byte, error) {
+	if !c.HasCapability(CapabilityProvisioning) {
+		return nil, fmt.Errorf("device doesn't support provisioning")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	var req certificateRequest
+	if err := c.conn().Call(ctx, "openpsg.csr", nil, &req); err != nil {
+		return nil, fmt.Errorf("failed to get certificate signing request: %w", err)
+	}
+	return []byte(req.CSR), nil
+}
+
+// InstallCertificate installs the certificate the device presents to the
+// recorder, and the certificate of the CA it trusts to sign the recorder's
+// certificate (both PEM encoded). The device serves TLS connections from then
+// on.
+func (c *Client) InstallCertificate(ctx context.Context, cert, caCert []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	params := map[string]string{
+		"certificate": string(cert),
+		"ca":          string(caCert),
+	}
+	if err := c.conn().Call(ctx, "openpsg.certificate", params, nil); err != nil {
+		return fmt.Errorf("failed to install certificate: %w", err)
+	}
+	return nil
+}
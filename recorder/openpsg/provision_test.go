@@ -0,0 +1,118 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/ca"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPinnedCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// Failed handshakes are expected.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	addr := netip.MustParseAddrPort(server.Listener.Addr().String()).Addr()
+	fingerprint := ca.Fingerprint(server.Certificate())
+	// Trusts the CA that signed the certificate of the server.
+	trusted := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	tests := []struct {
+		name             string
+		config           *tls.Config
+		pin              string
+		pinErr           error
+		wantErr          string
+		wantUnauthorized bool
+	}{
+		{
+			name:   "Pinned",
+			config: trusted,
+			pin:    fingerprint,
+		},
+		{
+			name:   "Not pinned",
+			config: trusted,
+		},
+		{
+			name:             "Other certificate pinned",
+			config:           trusted,
+			pin:              strings.Repeat("ab", 32),
+			wantErr:          "didn't present the certificate it was provisioned with",
+			wantUnauthorized: true,
+		},
+		{
+			name:    "Lookup failed",
+			config:  trusted,
+			pinErr:  errors.New("no such device"),
+			wantErr: "no such device",
+		},
+		{
+			name: "Pinned but untrusted",
+			// The pinned certificate is still verified against the trusted
+			// CAs.
+			config:  &tls.Config{},
+			pin:     fingerprint,
+			wantErr: "certificate signed by unknown authority",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var looked []netip.Addr
+			config := openpsg.VerifyPinnedCertificate(tt.config, addr, func(addr netip.Addr) (string, error) {
+				looked = append(looked, addr)
+				return tt.pin, tt.pinErr
+			})
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+			t.Cleanup(client.CloseIdleConnections)
+
+			resp, err := client.Get(server.URL)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				if tt.wantUnauthorized {
+					assert.ErrorIs(t, err, openpsg.ErrUnauthorizedDevice)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			assert.Equal(t, []netip.Addr{addr}, looked)
+		})
+	}
+
+	// The configuration the pin is checked with is left unchanged.
+	assert.Nil(t, trusted.VerifyConnection)
+}
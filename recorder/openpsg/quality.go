@@ -0,0 +1,147 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// The fraction of values at the limits of a signal's physical range above
+	// which it is considered to be clipping.
+	clippingThreshold = 0.01
+	// Values within this fraction of the physical range are considered equal
+	// (about one 16-bit digital step).
+	qualityResolution = 1.0 / 65536
+)
+
+// SignalQuality describes the quality of a signal over the last second.
+type SignalQuality struct {
+	Signal string
+	// The values haven't changed (eg. a detached electrode).
+	Flat bool
+	// The fraction of values at the limits of the physical range.
+	Clipping float64
+	// The RMS of the values less their mean (in the unit of the signal).
+	RMS float64
+}
+
+// Problem describes what is wrong with the signal, or returns an empty string
+// if nothing is.
+func (q SignalQuality) Problem() string {
+	switch {
+	case q.Flat:
+		return "flat"
+	case q.Clipping > clippingThreshold:
+		return "clipping"
+	default:
+		return ""
+	}
+}
+
+// WithStatus reports the quality of each signal every interval (eg. to display
+// the status of the recording).
+func WithStatus(interval time.Duration, report func([]SignalQuality)) RecordOption {
+	return func(o *recordOptions) {
+		o.statusInterval = interval
+		o.statusReport = report
+	}
+}
+
+// qualityMonitor measures the quality of a signal over one second windows.
+type qualityMonitor struct {
+	name     string
+	min, max float64
+	window   []float64
+
+	mu      sync.Mutex
+	quality SignalQuality
+}
+
+func newQualityMonitor(name string, sampleRate uint32, physicalMin, physicalMax float64) *qualityMonitor {
+	return &qualityMonitor{
+		name:    name,
+		min:     min(physicalMin, physicalMax),
+		max:     max(physicalMin, physicalMax),
+		window:  make([]float64, 0, max(sampleRate, 1)),
+		quality: SignalQuality{Signal: name},
+	}
+}
+
+// observe adds values to the current window. When a window completes and the
+// problem with the signal changes, it returns the new quality and true.
+func (m *qualityMonitor) observe(values []float64) (quality SignalQuality, changed bool) {
+	for _, value := range values {
+		m.window = append(m.window, value)
+		if len(m.window) < cap(m.window) {
+			continue
+		}
+
+		q := m.measure()
+		m.window = m.window[:0]
+
+		m.mu.Lock()
+		if q.Problem() != m.quality.Problem() {
+			quality, changed = q, true
+		}
+		m.quality = q
+		m.mu.Unlock()
+	}
+
+	return quality, changed
+}
+
+// measure calculates the quality of the values in the window.
+func (m *qualityMonitor) measure() SignalQuality {
+	resolution := (m.max - m.min) * qualityResolution
+
+	var mean float64
+	var clipped int
+	for _, value := range m.window {
+		mean += value
+		if value <= m.min+resolution || value >= m.max-resolution {
+			clipped++
+		}
+	}
+	mean /= float64(len(m.window))
+
+	var variance float64
+	for _, value := range m.window {
+		variance += (value - mean) * (value - mean)
+	}
+	variance /= float64(len(m.window))
+
+	return SignalQuality{
+		Signal:   m.name,
+		Flat:     slices.Max(m.window)-slices.Min(m.window) <= resolution,
+		Clipping: float64(clipped) / float64(len(m.window)),
+		RMS:      math.Sqrt(variance),
+	}
+}
+
+// current returns the quality of the signal over the last complete window.
+func (m *qualityMonitor) current() SignalQuality {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.quality
+}
@@ -0,0 +1,117 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+// clip limits values to the physical range of an amplifier.
+func clip(values []float64, limit float64) []float64 {
+	clipped := slices.Clone(values)
+	for i, value := range clipped {
+		clipped[i] = min(max(value, -limit), limit)
+	}
+	return clipped
+}
+
+func TestQualityMonitor(t *testing.T) {
+	const limit = 500
+
+	flat := make([]float64, eegRate)
+	for i := range flat {
+		flat[i] = 20
+	}
+
+	tests := []struct {
+		name    string
+		windows [][]float64
+		// The problem reported as each window completes if it changed, "ok"
+		// once resolved.
+		wantChanged []string
+		want        openpsg.SignalQuality
+	}{
+		{
+			name:        "Clean",
+			windows:     [][]float64{eeg(50, 50, 0), eeg(50, 50, 0)},
+			wantChanged: []string{"", ""},
+			want:        openpsg.SignalQuality{Signal: "EEG", RMS: 50 / math.Sqrt2},
+		},
+		{
+			name:        "Flat",
+			windows:     [][]float64{eeg(50, 50, 0), flat, flat},
+			wantChanged: []string{"", "flat", ""},
+			want:        openpsg.SignalQuality{Signal: "EEG", Flat: true},
+		},
+		{
+			name:        "Clipping",
+			windows:     [][]float64{clip(eeg(1000, 50, 0), limit)},
+			wantChanged: []string{"clipping"},
+			// The sinusoid is beyond the limits for two thirds of each cycle,
+			// reducing its RMS from about 707.
+			want: openpsg.SignalQuality{Signal: "EEG", Clipping: 0.66, RMS: 442},
+		},
+		{
+			name:        "Recovered",
+			windows:     [][]float64{flat, eeg(50, 50, 0)},
+			wantChanged: []string{"flat", "ok"},
+			want:        openpsg.SignalQuality{Signal: "EEG", RMS: 50 / math.Sqrt2},
+		},
+		{
+			// The quality isn't known until a second of values is received.
+			name:        "Incomplete window",
+			windows:     [][]float64{flat[:eegRate-1]},
+			wantChanged: []string{""},
+			want:        openpsg.SignalQuality{Signal: "EEG"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := openpsg.NewQualityMonitor("EEG", eegRate, -limit, limit)
+
+			var changed []string
+			for _, window := range tt.windows {
+				quality, ok := m.Observe(window)
+				problem := ""
+				if ok {
+					problem = quality.Problem()
+					// A return to normal is reported too.
+					if problem == "" {
+						problem = "ok"
+					}
+				}
+				changed = append(changed, problem)
+			}
+
+			got := m.Current()
+			assert.Equal(t, tt.want.Signal, got.Signal)
+			assert.Equal(t, tt.want.Flat, got.Flat)
+			assert.InDelta(t, tt.want.Clipping, got.Clipping, 0.02)
+			assert.InDelta(t, tt.want.RMS, got.RMS, 1)
+			assert.Equal(t, tt.want.Problem(), got.Problem())
+			assert.Equal(t, tt.wantChanged, changed)
+		})
+	}
+}
@@ -23,178 +23,799 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"math"
 	"net/netip"
+	"path/filepath"
+	"slices"
 	"time"
 
-	"github.com/OpenPSG/edf"
-	"github.com/hedzr/go-ringbuf/v2"
-	"github.com/hedzr/go-ringbuf/v2/mpmc"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
 	"golang.org/x/sync/errgroup"
 )
 
-// 30 second epochs are pretty standard for PSG data.
-const dataRecordDuration = 30 * time.Second
+const (
+	// 30 second epochs are pretty standard for PSG data.
+	dataRecordDuration = 30 * time.Second
+	// Space reserved in each data record for EDF+ annotations.
+	annotationBytesPerRecord = 1024
+	// Limits of the delay between attempts to reconnect to a device.
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Annotation is an event to be stored alongside the recorded signals.
+type Annotation struct {
+	// The time at which the event occurred.
+	Time time.Time
+	// The duration of the event (zero if not applicable).
+	Duration time.Duration
+	// A description of the event.
+	Text string
+}
+
+// RecordOption configures optional recording behaviour.
+type RecordOption func(*recordOptions)
+
+type recordOptions struct {
+	annotations <-chan Annotation
+	sidecarPath string
+	leases      leasedb.Store
+	// Annotate the sub-second offset of the first data record.
+	startOffsetAnnotation bool
+	maxSignalsPerFile     int
+	splitFiles            SplitFiles
+	manifestPath          string
+	gapFill               GapFill
+	montage               *Montage
+	signalSelection       *SignalSelection
+	deviceFilter          *DeviceFilter
+	calibration           *Calibration
+	labelCheck            LabelCheck
+	deviceNames           DeviceNames
+	filters               []*ChannelFilters
+	storageRates          []*StorageRate
+	lineFrequency         float64
+	lineNoiseThreshold    float64
+	statusInterval        time.Duration
+	statusReport          func([]SignalQuality)
+	progressInterval      time.Duration
+	progressReport        func(RecordingProgress)
+	report                func(RecordingReport)
+	respiratoryEvents     *SignalSelection
+	audio                 *AudioCapture
+	video                 *VideoCapture
+	sources               map[netip.Addr]SourceOpener
+	localSources          []netip.Addr
+	pause                 <-chan bool
+	driftCompensation     bool
+	clockOffsetCorrection bool
+	udpStreaming          bool
+	connectOptions        []ConnectOption
+	alignEpochs           bool
+	spillDir              string
+	bufferDuration        time.Duration
+	syncInterval          time.Duration
+	journalPath           string
+	resume                *Journal
+	pseudonym             string
+	impedanceCheck        *ImpedanceCheck
+	// The duration of each data record (shortened by tests).
+	recordDuration time.Duration
+}
+
+// WithAnnotations stores the annotations received on the channel (eg. operator
+// events) in the EDF+ annotations signal.
+func WithAnnotations(annotations <-chan Annotation) RecordOption {
+	return func(o *recordOptions) {
+		o.annotations = annotations
+	}
+}
+
+// WithSidecar writes a JSON description of the recording (devices, signal
+// mapping, software version and timing) to the file at path. The file is
+// written when recording starts and updated when it stops.
+func WithSidecar(path string) RecordOption {
+	return func(o *recordOptions) {
+		o.sidecarPath = path
+	}
+}
+
+// WithLeaseDB looks up the MAC address and hostname of each device in the DHCP
+// lease database, so they can be included in the sidecar.
+func WithLeaseDB(db leasedb.Store) RecordOption {
+	return func(o *recordOptions) {
+		o.leases = db
+	}
+}
+
+// WithStartOffsetAnnotation adds an annotation carrying the (microsecond)
+// offset of the first data record from the whole second start time in the
+// header, for readers that ignore the EDF+ time-keeping annotations.
+func WithStartOffsetAnnotation() RecordOption {
+	return func(o *recordOptions) {
+		o.startOffsetAnnotation = true
+	}
+}
+
+// WithBufferDuration sets how much of each signal is buffered in memory, which
+// bounds how late signal values can arrive (the default is two data records).
+// It must be at least one data record.
+func WithBufferDuration(d time.Duration) RecordOption {
+	return func(o *recordOptions) {
+		o.bufferDuration = d
+	}
+}
+
+// WithSyncInterval periodically updates the number of data records in the EDF
+// headers and flushes the files to stable storage, bounding how much of the
+// recording a power loss can corrupt. Data records are written at most once a
+// data record duration, so shorter intervals sync after every data record.
+func WithSyncInterval(d time.Duration) RecordOption {
+	return func(o *recordOptions) {
+		o.syncInterval = d
+	}
+}
+
+// WithEpochAlignment starts data records on wall-clock multiples of the data
+// record duration (eg. :00 and :30 for 30 second records), so scoring epochs
+// line up with clock time. Values received before the first data record are
+// discarded.
+func WithEpochAlignment() RecordOption {
+	return func(o *recordOptions) {
+		o.alignEpochs = true
+	}
+}
+
+// recording is the state of a recording, shared by the goroutines ingesting
+// the signal values of each device (see ingest) and the writer assembling them
+// into data records (see write). Each signal is only processed by the
+// goroutine of its device.
+type recording struct {
+	options recordOptions
+
+	devices []*connectedDevice
+	// The names of the devices, if named.
+	names map[netip.Addr]string
+	// The signals recorded (at their storage rate), the address of the device
+	// of each, and the calibration of each (nil if uncalibrated).
+	signals       []Signal
+	signalDevices []netip.Addr
+	calibrations  []*ChannelCalibration
+	// The index of each signal, by device address and signal ID.
+	signalIndices map[netip.Addr]map[uint32]int
+	sidecar       *Sidecar
+
+	filterChains          []filterChain
+	lineNoiseMonitors     []*lineNoiseMonitor
+	qualityMonitors       []*qualityMonitor
+	respiratoryDetectors  []*respiratoryEventDetector
+	desaturationDetectors []*desaturationDetector
+	// Nil unless drift compensation is enabled.
+	driftEstimators []*driftEstimator
+	// The sample rate of each signal on its device, and the resampler to its
+	// storage rate.
+	deviceRates   []uint32
+	resamplers    []*resampler
+	stats         []*signalStats
+	signalBuffers []*signalBuffer
+
+	derived []derivedSignal
+	// The signals with a lead-off status signal, and their lead-off status.
+	leadOffSignals   []int
+	leadOffTimelines []*leadOffTimeline
+
+	// When this session of the recording started, when the recording first
+	// started (before the session, if resumed), and the start of the first
+	// (new) data record.
+	sessionStart, start, recordStart time.Time
+
+	// Device events (eg. connections) to be annotated.
+	deviceEvents chan Annotation
+	// The time of the first audio sample is passed to the writer, to annotate
+	// it and record it in the sidecar.
+	audioStarted chan time.Time
+
+	// When the recording stopped, and the time it was paused, for the report.
+	endTime    time.Time
+	pausedTime time.Duration
+}
+
+// Record records PSG data from the specified devices and writes it to an EDF+
+// file. Periods where no data is received are left as gaps (EDF+D). If the
+// connection to a device is lost, its signals are missing until it reconnects.
+// Devices in the montage (see WithMontage) may join after recording starts.
+func Record(ctx context.Context, edfFile io.WriteSeeker, patientID, recordingID string, deviceAddrs []netip.Addr, opts ...RecordOption) error {
+	options := recordOptions{
+		recordDuration: dataRecordDuration,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.bufferDuration == 0 {
+		options.bufferDuration = 2 * options.recordDuration
+	}
+	if options.bufferDuration < options.recordDuration {
+		return fmt.Errorf("buffer duration must be at least %s", options.recordDuration)
+	}
 
-// Record records PSG data from the specified devices and writes it to an EDF file.
-func Record(ctx context.Context, edfFile io.WriteSeeker, patientID, recordingID string, deviceAddrs []netip.Addr) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	g, ctx := errgroup.WithContext(ctx)
 
+	r := &recording{options: options}
+	defer r.close()
+
+	if err := r.connect(ctx, deviceAddrs); err != nil {
+		return err
+	}
+	if err := r.prepare(); err != nil {
+		return err
+	}
+
+	r.deviceEvents = make(chan Annotation, len(r.devices))
+	r.capture(ctx, g)
+	r.monitor(ctx, g)
+
+	for _, device := range r.devices {
+		g.Go(func() error {
+			return r.ingest(ctx, device)
+		})
+	}
+
+	g.Go(func() error {
+		return r.write(ctx, edfFile, patientID, recordingID)
+	})
+
+	err := g.Wait()
+
+	if options.report != nil {
+		options.report(r.report())
+	}
+
+	return err
+}
+
+// connect connects to the devices (and sources) to record, and gathers the
+// signals of each. Devices in the montage that can't be connected to have
+// their signals reserved, to join the recording later.
+func (r *recording) connect(ctx context.Context, deviceAddrs []netip.Addr) error {
+	r.names = make(map[netip.Addr]string)
+	r.signalIndices = make(map[netip.Addr]map[uint32]int)
+
 	currentSignalIndice := 0
-	signalIndices := make(map[netip.Addr]map[uint32]int)
-	var signals []Signal
-	var signalBuffers []mpmc.RingBuffer[float64]
+	// Signals must be stored in the same order as before the interruption.
+	if r.options.resume != nil {
+		deviceAddrs = nil
+		for _, signal := range r.options.resume.deviceSignals() {
+			addr, err := netip.ParseAddr(signal.Device)
+			if err != nil {
+				return fmt.Errorf("invalid device address in journal: %w", err)
+			}
 
-	for _, deviceAddr := range deviceAddrs {
-		client, err := Connect(ctx, netip.AddrPortFrom(deviceAddr, 80))
+			if !slices.Contains(deviceAddrs, addr) {
+				deviceAddrs = append(deviceAddrs, addr)
+			}
+		}
+	}
+
+	r.sidecar = &Sidecar{
+		Software:           softwareVersion(),
+		DataRecordDuration: r.options.recordDuration.Seconds(),
+	}
+
+	leases := make(map[string]*leasedb.Lease)
+	if r.options.leases != nil {
+		allLeases, err := r.options.leases.ListLeases()
 		if err != nil {
-			slog.Warn("Failed to connect to device", slog.Any("error", err))
-			continue
+			return fmt.Errorf("failed to list leases: %w", err)
 		}
 
-		deviceSignals, err := client.Signals(ctx)
+		for _, lease := range allLeases {
+			leases[lease.IPAddress] = lease
+		}
+	}
+
+	// Sources attached to the recorder are recorded after the devices.
+	for _, addr := range r.options.localSources {
+		if !slices.Contains(deviceAddrs, addr) {
+			deviceAddrs = append(slices.Clip(deviceAddrs), addr)
+		}
+	}
+
+	deviceAddrs = r.options.montage.addresses(deviceAddrs)
+	openers := make([]SourceOpener, len(deviceAddrs))
+	for i, deviceAddr := range deviceAddrs {
+		openers[i] = r.options.montage.configure(deviceAddr, r.options.opener(deviceAddr))
+	}
+
+	opened := openDevices(ctx, openers)
+	defer func() {
+		// Devices that weren't recorded from, as the recording failed to start.
+		for _, o := range opened {
+			if o.client != nil {
+				_ = o.client.Close()
+			}
+		}
+	}()
+
+	var impedances []ImpedanceReading
+	for i, deviceAddr := range deviceAddrs {
+		montageSignals, inMontage := r.options.montage.signals(deviceAddr)
+
+		var deviceSignals []Signal
+		var deviceInfo DeviceInfo
+		var identity string
+		open := openers[i]
+		client, err := opened[i].client, opened[i].err
+		opened[i].client = nil
 		if err != nil {
-			return fmt.Errorf("failed to get signals: %w", err)
+			if !inMontage {
+				slog.Warn("Failed to connect to device", slog.Any("error", err))
+				continue
+			}
+
+			// The device can join the recording later.
+			slog.Warn("Failed to connect to device, reserving its signals from the montage",
+				slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+			client = nil
+		} else {
+			deviceSignals, err = opened[i].signals, opened[i].signalsErr
+			if err != nil {
+				_ = client.Close()
+				return fmt.Errorf("failed to get signals: %w", err)
+			}
+
+			if !inMontage && !slices.Contains(r.options.localSources, deviceAddr) && !r.options.deviceFilter.matches(deviceSignals) {
+				slog.Info("Device doesn't match the device filter, skipping it", slog.Any("deviceAddr", deviceAddr))
+				_ = client.Close()
+				continue
+			}
+
+			if s, ok := client.(identitySource); ok {
+				identity = s.Identity()
+				if identity != "" {
+					slog.Info("Verified device identity", slog.Any("deviceAddr", deviceAddr), slog.String("identity", identity))
+				}
+			}
+
+			deviceInfo, err = opened[i].info, opened[i].infoErr
+			if err != nil {
+				slog.Warn("Failed to identify device", slog.Any("deviceAddr", deviceAddr), slog.Any("error", err))
+			} else if deviceInfo != (DeviceInfo{}) {
+				slog.Info("Identified device", slog.Any("deviceAddr", deviceAddr), slog.String("device", deviceInfo.String()))
+			}
 		}
 
-		signalIndices[deviceAddr] = make(map[uint32]int)
+		if inMontage {
+			deviceSignals = montageSignals
+		}
+
+		deviceSignals = r.options.signalSelection.filter(deviceAddr, deviceSignals)
+		if len(deviceSignals) == 0 {
+			slog.Warn("No signals selected from device, skipping it", slog.Any("deviceAddr", deviceAddr))
+			if client != nil {
+				_ = client.Close()
+			}
+			continue
+		}
+
+		for _, signal := range deviceSignals {
+			if err := signal.validate(); err != nil {
+				if client != nil {
+					_ = client.Close()
+				}
+				return fmt.Errorf("device %s reported an unsupported signal: %w", deviceAddr, err)
+			}
+		}
+
+		device := SidecarDevice{
+			Address:      deviceAddr.String(),
+			Firmware:     deviceInfo.FirmwareVersion,
+			SerialNumber: deviceInfo.SerialNumber,
+			Model:        deviceInfo.Model,
+			Identity:     identity,
+		}
+		lease := leases[deviceAddr.String()]
+		if lease != nil {
+			device.MAC = lease.MAC
+			device.Hostname = lease.Hostname
+		}
+		device.Name = r.options.deviceNames.lookup(deviceAddr, lease)
+		if device.Name != "" {
+			r.names[deviceAddr] = device.Name
+			slog.Info("Named device", slog.Any("deviceAddr", deviceAddr), slog.String("name", device.Name))
+		}
+
+		var deviceSignalIDs []uint32
+		r.signalIndices[deviceAddr] = make(map[uint32]int)
 		for _, signal := range deviceSignals {
-			signalIndices[deviceAddr][signal.ID] = currentSignalIndice
-			signalBuffers = append(signalBuffers, ringbuf.New[float64](2*uint32(float64(signal.SampleRate)*dataRecordDuration.Seconds())))
+			dmin, dmax := signal.digitalRange()
+			calibration := r.options.calibration.lookup(deviceAddr, signal.ID)
+			device.Signals = append(device.Signals, SidecarSignal{
+				Index:          currentSignalIndice,
+				ID:             signal.ID,
+				Label:          signal.Name,
+				TransducerType: signal.TransducerType,
+				Unit:           signal.Unit,
+				Min:            signal.Min,
+				Max:            signal.Max,
+				DigitalMin:     dmin,
+				DigitalMax:     dmax,
+				SampleFormat:   signal.sampleFormat(),
+				SampleRate:     signal.SampleRate,
+				Calibration:    sidecarCalibration(calibration),
+			})
+
+			r.signalIndices[deviceAddr][signal.ID] = currentSignalIndice
+			deviceSignalIDs = append(deviceSignalIDs, signal.ID)
 			currentSignalIndice++
 
-			signals = append(signals, signal)
+			r.signals = append(r.signals, signal)
+			r.signalDevices = append(r.signalDevices, deviceAddr)
+			r.calibrations = append(r.calibrations, calibration)
 		}
 
-		g.Go(func() error {
-			defer client.Close()
+		r.sidecar.Devices = append(r.sidecar.Devices, device)
+		r.devices = append(r.devices, &connectedDevice{addr: deviceAddr, open: open, client: client, signalIDs: deviceSignalIDs, identity: identity})
 
-			deviceSignalIDs := make([]uint32, len(signals))
-			for i, signal := range signals {
-				deviceSignalIDs[i] = signal.ID
+		if r.options.impedanceCheck != nil && client != nil {
+			readings, err := measureImpedances(ctx, deviceAddr, client, deviceSignals)
+			if err != nil {
+				return fmt.Errorf("failed to check electrode impedance of device %s: %w", deviceAddr, err)
 			}
+			impedances = append(impedances, readings...)
+		}
+	}
 
-			slog.Debug("Starting recording",
-				slog.Any("deviceAddr", deviceAddr),
-				slog.Any("signals", deviceSignalIDs))
+	if r.options.impedanceCheck != nil {
+		if err := r.options.impedanceCheck.check(impedances); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			if err := client.Start(ctx, deviceSignalIDs); err != nil {
-				return fmt.Errorf("failed to start recording: %w", err)
+// prepare orders the signals, and sets up their processing (filtering,
+// monitoring, resampling and buffering) and the signals derived from them.
+func (r *recording) prepare() error {
+	// Store the signals in the order (and with the labels) of the montage.
+	if r.options.montage != nil && len(r.options.montage.Channels) > 0 {
+		order, channels := r.options.montage.order(r.signalDevices, r.signals)
+
+		newIndices := make([]int, len(order))
+		orderedSignals := make([]Signal, len(order))
+		orderedDevices := make([]netip.Addr, len(order))
+		orderedCalibrations := make([]*ChannelCalibration, len(order))
+		for i, j := range order {
+			newIndices[j] = i
+			orderedSignals[i] = r.signals[j]
+			orderedDevices[i] = r.signalDevices[j]
+			orderedCalibrations[i] = r.calibrations[j]
+			if channels[i] != nil {
+				orderedSignals[i].Name = channels[i].Label
 			}
+		}
+		r.signals, r.signalDevices, r.calibrations = orderedSignals, orderedDevices, orderedCalibrations
 
-			deviceSignalValues := client.SignalValues()
+		for addr, indices := range r.signalIndices {
+			for id, index := range indices {
+				r.signalIndices[addr][id] = newIndices[index]
+			}
+		}
 
-			for {
-				select {
-				case <-ctx.Done():
-					slog.Debug("Stopping recording", slog.Any("deviceAddr", deviceAddr))
+		for i := range r.sidecar.Devices {
+			for j := range r.sidecar.Devices[i].Signals {
+				sidecarSignal := &r.sidecar.Devices[i].Signals[j]
+				sidecarSignal.Index = newIndices[sidecarSignal.Index]
+				if channel := channels[sidecarSignal.Index]; channel != nil {
+					sidecarSignal.Reference = channel.Reference
+				}
+			}
+		}
+	}
 
-					if err := client.Stop(context.Background(), deviceSignalIDs); err != nil {
-						return fmt.Errorf("failed to stop recording: %w", err)
-					}
+	r.options.labelCheck.check(r.signals)
+	for i := range r.sidecar.Devices {
+		for j := range r.sidecar.Devices[i].Signals {
+			sidecarSignal := &r.sidecar.Devices[i].Signals[j]
+			sidecarSignal.Label = r.signals[sidecarSignal.Index].Name
+		}
+	}
 
-					return nil
-				case sv := <-deviceSignalValues:
-					// Rewrite the signal id to it's global form.
-					sv.ID = uint32(signalIndices[deviceAddr][sv.ID])
-
-					// TODO: handle missing, and out-of-order signal values.
-					// Given we are using a reliable transport (TCP), we should be okay.
-
-					for _, value := range sv.Values {
-						if err := signalBuffers[sv.ID].Enqueue(convertDigitalToPhysical(
-							value, float64(signals[sv.ID].Min), float64(signals[sv.ID].Max))); err != nil {
-							return fmt.Errorf("signal buffer overrun: %w", err)
-						}
-					}
-				}
+	// Each signal is only filtered by the goroutine of its device.
+	r.filterChains = make([]filterChain, len(r.signals))
+	for i := range r.signals {
+		chain, applied, err := newFilterChain(r.options.filters, r.signalDevices[i], r.signals[i])
+		if err != nil {
+			return err
+		}
+
+		r.filterChains[i] = chain
+		if len(applied) > 0 {
+			r.signals[i].Prefiltering.Filters = append(slices.Clone(r.signals[i].Prefiltering.Filters), applied...)
+		}
+	}
+
+	// Mains interference is measured at the device rate, before filtering.
+	r.lineNoiseMonitors = make([]*lineNoiseMonitor, len(r.signals))
+	for i, signal := range r.signals {
+		r.lineNoiseMonitors[i] = newLineNoiseMonitor(signal, r.options.lineFrequency, r.options.lineNoiseThreshold)
+	}
+
+	// Signal quality is also measured before filtering.
+	r.qualityMonitors = make([]*qualityMonitor, len(r.signals))
+	for i, signal := range r.signals {
+		r.qualityMonitors[i] = newQualityMonitor(signal.Name, signal.SampleRate,
+			r.calibrations[i].apply(float64(signal.Min)), r.calibrations[i].apply(float64(signal.Max)))
+	}
+
+	// Candidate respiratory events are detected in the filtered airflow signals.
+	r.respiratoryDetectors = make([]*respiratoryEventDetector, len(r.signals))
+	if r.options.respiratoryEvents != nil {
+		for i, signal := range r.signals {
+			if r.options.respiratoryEvents.matches(r.signalDevices[i], signal) {
+				r.respiratoryDetectors[i] = newRespiratoryEventDetector(signal.Name, signal.SampleRate)
 			}
-		})
+		}
 	}
 
-	g.Go(func() error {
-		hdr := edf.Header{
-			Version:            edf.Version0,
-			PatientID:          patientID,
-			RecordingID:        recordingID,
-			StartTime:          time.Now(),
-			DataRecordDuration: dataRecordDuration,
-			SignalCount:        len(signals),
-		}
-
-		for _, signal := range signals {
-			hdr.Signals = append(hdr.Signals, edf.SignalHeader{
-				Label:             signal.Name,
-				TransducerType:    string(signal.TransducerType),
-				PhysicalDimension: string(signal.Unit),
-				PhysicalMin:       float64(signal.Min),
-				PhysicalMax:       float64(signal.Max),
-				DigitalMin:        math.MinInt16,
-				DigitalMax:        math.MaxInt16,
-				SamplesPerRecord:  int(float64(signal.SampleRate) * hdr.DataRecordDuration.Seconds()),
-			})
+	// Desaturations are detected in any oximetry signals.
+	r.desaturationDetectors = make([]*desaturationDetector, len(r.signals))
+	for i, signal := range r.signals {
+		if isOximetrySignal(signal) {
+			r.desaturationDetectors[i] = newDesaturationDetector(signal.Name, signal.SampleRate)
 		}
+	}
 
-		slog.Info("Writing EDF file header")
+	// Signals are resampled to their storage rate by the goroutine of their
+	// device, from here on the sample rate of a signal is its storage rate.
+	r.deviceRates = make([]uint32, len(r.signals))
+	r.resamplers = make([]*resampler, len(r.signals))
+	for i := range r.signals {
+		r.deviceRates[i] = r.signals[i].SampleRate
+		rate := storageRate(r.options.storageRates, r.signalDevices[i], r.signals[i])
+		r.resamplers[i] = newResampler(r.deviceRates[i], rate)
+		r.signals[i].SampleRate = rate
+	}
 
-		ew, err := edf.Create(edfFile, hdr)
+	// Statistics of each signal are gathered for the report.
+	r.stats = make([]*signalStats, len(r.signals))
+	for i := range r.signals {
+		r.stats[i] = newSignalStats(r.deviceRates[i], r.signals[i].SampleRate)
+	}
+
+	for i := range r.sidecar.Devices {
+		for j := range r.sidecar.Devices[i].Signals {
+			sidecarSignal := &r.sidecar.Devices[i].Signals[j]
+			if rate := r.signals[sidecarSignal.Index].SampleRate; rate != sidecarSignal.SampleRate {
+				sidecarSignal.StorageRate = rate
+			}
+		}
+	}
+
+	// Signals derived from the recorded signals are stored after them, along
+	// with the body position if there is a three axis accelerometer.
+	var derivedChannels []DerivedChannel
+	if r.options.montage != nil {
+		derivedChannels = r.options.montage.Derived
+	}
+	derivedChannels = append(slices.Clip(derivedChannels), accelerometerPosition(r.signals, derivedChannels)...)
+
+	if len(derivedChannels) > 0 {
+		ranges := make([][2]float64, len(r.signals))
+		for i, signal := range r.signals {
+			ranges[i] = [2]float64{r.calibrations[i].apply(float64(signal.Min)), r.calibrations[i].apply(float64(signal.Max))}
+		}
+
+		var err error
+		r.derived, err = resolveDerived(derivedChannels, r.signals, ranges)
 		if err != nil {
-			return fmt.Errorf("failed to create EDF writer: %w", err)
+			return err
 		}
-		defer ew.Close()
 
-		// Give some time for the signal values to start coming in.
-		select {
-		case <-time.After(hdr.DataRecordDuration / 2):
-		case <-ctx.Done():
-			return nil
+		for i, d := range r.derived {
+			r.sidecar.Derived = append(r.sidecar.Derived, SidecarDerived{
+				Index:     len(r.signals) + i,
+				Label:     d.channel.Label,
+				Inputs:    d.channel.Inputs,
+				Weights:   d.weights,
+				Transform: d.channel.Transform,
+				Scale:     d.channel.Scale,
+			})
+		}
+	}
+
+	// Signals whose devices report when their sensor is detached also get a
+	// status signal, stored after the derived signals.
+	r.leadOffTimelines = make([]*leadOffTimeline, len(r.signals))
+	for i, signal := range r.signals {
+		if signal.LeadOffDetection {
+			r.leadOffSignals = append(r.leadOffSignals, i)
+			r.leadOffTimelines[i] = &leadOffTimeline{}
 		}
+	}
 
-		ticker := time.NewTicker(hdr.DataRecordDuration)
-		defer ticker.Stop()
+	if r.options.resume != nil {
+		resumeSignals := r.options.resume.deviceSignals()
+		if len(r.signals) != len(resumeSignals) {
+			return fmt.Errorf("devices have %d signals, but the interrupted recording has %d", len(r.signals), len(resumeSignals))
+		}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-ticker.C:
+		for i, signal := range resumeSignals {
+			if signal.Device != r.signalDevices[i].String() || signal.ID != r.signals[i].ID {
+				return fmt.Errorf("signal %q does not match the interrupted recording", r.signals[i].Name)
 			}
+		}
+	}
 
-			// Prepare a record to write to the EDF file.
-			record := make([][]float64, len(signals))
-			for i := range record {
-				record[i] = make([]float64, hdr.Signals[i].SamplesPerRecord)
-			}
+	// EDF start times have a resolution of one second, so the first data
+	// record is offset from the start time by the sub-second remainder.
+	r.sessionStart = time.Now().Truncate(time.Microsecond)
+	r.start = r.sessionStart
+	if r.options.resume != nil {
+		r.start = r.options.resume.StartTime
+	}
+
+	// The start of the first (new) data record.
+	r.recordStart = r.sessionStart
+	if r.options.alignEpochs {
+		r.recordStart = r.sessionStart.Truncate(r.options.recordDuration)
+		if r.recordStart.Before(r.sessionStart) {
+			r.recordStart = r.recordStart.Add(r.options.recordDuration)
+		}
+	}
 
-			for i, buf := range signalBuffers {
-				for j := 0; j < int(hdr.Signals[i].SamplesPerRecord); j++ {
-					value, err := buf.Dequeue()
-					if err != nil {
-						slog.Warn("Missing signal values", slog.Any("error", err))
-						break
-					}
+	// Signal values are placed by their timestamps, so the buffers hold more
+	// than a data record worth of values to allow for late arrivals.
+	r.signalBuffers = make([]*signalBuffer, len(r.signals))
+	for i, signal := range r.signals {
+		r.signalBuffers[i] = newSignalBuffer(r.recordStart, float64(signal.SampleRate),
+			int(float64(signal.SampleRate)*r.options.bufferDuration.Seconds()))
+	}
 
-					record[i][j] = value
-				}
+	// Absorb stalls in writing data records (eg. slow storage) by spilling
+	// values that arrive too far ahead to disk.
+	if r.options.spillDir != "" {
+		for _, buf := range r.signalBuffers {
+			spill, err := newSpillFile(r.options.spillDir)
+			if err != nil {
+				return err
 			}
+			buf.spill = spill
+		}
+	}
 
-			slog.Info("Writing record to EDF file",
-				slog.Int("signals", len(record)),
-				slog.Duration("duration", hdr.DataRecordDuration))
+	// Each signal is only accessed by the goroutine of its device.
+	if r.options.driftCompensation {
+		r.driftEstimators = make([]*driftEstimator, len(r.signals))
+		for i, rate := range r.deviceRates {
+			r.driftEstimators[i] = newDriftEstimator(float64(rate))
+		}
+	}
 
-			// Attempt to write the record to the EDF file.
-			if err := ew.WriteRecord(record); err != nil {
-				return fmt.Errorf("failed to write record: %w", err)
+	return nil
+}
+
+// capture starts capturing audio and video alongside the recording, if
+// enabled.
+func (r *recording) capture(ctx context.Context, g *errgroup.Group) {
+	// The time of the first audio sample is passed to the writer, to annotate it
+	// and record it in the sidecar.
+	if r.options.audio != nil {
+		r.audioStarted = make(chan time.Time, 1)
+		r.sidecar.Audio = &SidecarAudio{
+			File:       filepath.Base(r.options.audio.Path),
+			Device:     r.options.audio.Device,
+			SampleRate: r.options.audio.SampleRate,
+		}
+
+		g.Go(func() error {
+			err := captureAudio(ctx, *r.options.audio, func(t time.Time) {
+				r.audioStarted <- t
+			})
+			if err != nil {
+				// The recording continues without audio.
+				slog.Warn("Failed to record audio", slog.Any("error", err))
+				r.deviceEvent(ctx, "Audio capture failed")
 			}
+			return nil
+		})
+	}
+
+	if r.options.video != nil {
+		r.sidecar.Video = &SidecarVideo{
+			File:     filepath.Base(r.options.video.Path),
+			Manifest: filepath.Base(r.options.video.ManifestPath),
 		}
-	})
 
-	return g.Wait()
+		g.Go(func() error {
+			err := captureVideo(ctx, *r.options.video, func(point VideoSyncPoint) {
+				r.annotate(ctx, Annotation{
+					Time: point.Time,
+					Text: fmt.Sprintf("Video sync: %s %.3fs", r.sidecar.Video.File, point.VideoTime),
+				})
+			})
+			if err != nil {
+				// The recording continues without video.
+				slog.Warn("Failed to record video", slog.Any("error", err))
+				r.deviceEvent(ctx, "Video capture failed")
+			}
+			return nil
+		})
+	}
+}
+
+// monitor periodically reports the status and progress of the recording, if
+// enabled.
+func (r *recording) monitor(ctx context.Context, g *errgroup.Group) {
+	if r.options.statusReport != nil && r.options.statusInterval > 0 {
+		g.Go(func() error {
+			ticker := time.NewTicker(r.options.statusInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+
+				qualities := make([]SignalQuality, len(r.qualityMonitors))
+				for i, m := range r.qualityMonitors {
+					qualities[i] = m.current()
+				}
+				r.options.statusReport(qualities)
+			}
+		})
+	}
+
+	if r.options.progressReport != nil && r.options.progressInterval > 0 {
+		g.Go(func() error {
+			ticker := time.NewTicker(r.options.progressInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+
+				progress := RecordingProgress{Start: r.start}
+				for _, device := range r.devices {
+					progress.Devices = append(progress.Devices, DeviceProgress{
+						Addr:      device.addr,
+						Name:      r.names[device.addr],
+						Connected: device.connected.Load(),
+						Received:  device.received.Load(),
+						Dropped:   device.dropped.Load(),
+						Lost:      device.lost.Load(),
+					})
+				}
+				for i, m := range r.qualityMonitors {
+					fill, spilled := r.signalBuffers[i].fill()
+					progress.Signals = append(progress.Signals, SignalProgress{
+						Device:     r.signalDevices[i],
+						Quality:    m.current(),
+						BufferFill: fill,
+						Spilled:    spilled,
+					})
+				}
+				r.options.progressReport(progress)
+			}
+		})
+	}
 }
 
-func convertDigitalToPhysical(digital int16, pmin, pmax float64) float64 {
-	return pmin + (float64(digital)-float64(math.MinInt16))*(pmax-pmin)/float64(math.MaxInt16-math.MinInt16)
+// close closes the signal buffers, and the connections to the devices.
+func (r *recording) close() {
+	for _, buf := range r.signalBuffers {
+		_ = buf.Close()
+	}
+	for _, device := range r.devices {
+		if device.client != nil {
+			_ = device.client.Close()
+		}
+	}
 }
@@ -0,0 +1,585 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource is a signal source streaming a 100 Hz ramp, timestamped from when
+// it is started. It can be opened once, after which it is offline.
+type fakeSource struct {
+	signal       openpsg.Signal
+	values       chan openpsg.SignalValues
+	disconnected chan struct{}
+	stopped      chan struct{}
+	opened       bool
+	mu           sync.Mutex
+	stopOnce     sync.Once
+	disconnect   sync.Once
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		signal: openpsg.Signal{
+			ID:         1,
+			Name:       "ECG",
+			Unit:       openpsg.Millivolts,
+			Min:        -5,
+			Max:        5,
+			SampleRate: 100,
+		},
+		values:       make(chan openpsg.SignalValues),
+		disconnected: make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+func (s *fakeSource) open(ctx context.Context) (openpsg.SignalSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opened {
+		return nil, errors.New("device offline")
+	}
+	s.opened = true
+	return s, nil
+}
+
+func (s *fakeSource) Signals(ctx context.Context) ([]openpsg.Signal, error) {
+	return []openpsg.Signal{s.signal}, nil
+}
+
+func (s *fakeSource) Start(ctx context.Context, signalIDs []uint32) error {
+	go s.stream(time.Now())
+	return nil
+}
+
+func (s *fakeSource) stream(start time.Time) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var sent int
+	for {
+		select {
+		case <-s.stopped:
+			return
+		case <-s.disconnected:
+			return
+		case <-ticker.C:
+		}
+
+		n := int(time.Since(start).Seconds() * 100)
+		values := make([]float64, 0, n-sent)
+		for i := sent; i < n; i++ {
+			values = append(values, float64(i%100))
+		}
+
+		select {
+		case s.values <- openpsg.SignalValues{ID: 1, Timestamp: start.Add(time.Duration(sent) * 10 * time.Millisecond), Values: values}:
+			sent = n
+		case <-s.stopped:
+			return
+		case <-s.disconnected:
+			return
+		}
+	}
+}
+
+func (s *fakeSource) Stop(ctx context.Context, signalIDs []uint32) error {
+	select {
+	case <-s.disconnected:
+		return errors.New("connection is closed")
+	default:
+	}
+
+	s.stopOnce.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func (s *fakeSource) SignalValues() <-chan openpsg.SignalValues {
+	return s.values
+}
+
+func (s *fakeSource) LeadOff() <-chan openpsg.LeadOffStatus {
+	return nil
+}
+
+func (s *fakeSource) Disconnected() <-chan struct{} {
+	return s.disconnected
+}
+
+// Disconnect drops the connection to the source.
+func (s *fakeSource) Disconnect() {
+	s.disconnect.Do(func() { close(s.disconnected) })
+}
+
+func (s *fakeSource) Close() error {
+	s.stopOnce.Do(func() { close(s.stopped) })
+	return nil
+}
+
+// reconnectingSource is a fake source that reconnects by itself (like
+// Client), reporting its outages rather than being disconnected.
+type reconnectingSource struct {
+	*fakeSource
+	outages chan openpsg.Outage
+	lostAt  atomic.Pointer[time.Time]
+}
+
+func newReconnectingSource() *reconnectingSource {
+	return &reconnectingSource{fakeSource: newFakeSource(), outages: make(chan openpsg.Outage)}
+}
+
+func (s *reconnectingSource) open(ctx context.Context) (openpsg.SignalSource, error) {
+	if _, err := s.fakeSource.open(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *reconnectingSource) Outages() <-chan openpsg.Outage {
+	return s.outages
+}
+
+func (s *reconnectingSource) CurrentOutage() (openpsg.Outage, bool) {
+	lostAt := s.lostAt.Load()
+	if lostAt == nil {
+		return openpsg.Outage{}, false
+	}
+	return openpsg.Outage{Start: *lostAt}, true
+}
+
+// Disconnect drops the connection to the source, which never reconnects.
+func (s *reconnectingSource) Disconnect() {
+	now := time.Now()
+	s.lostAt.Store(&now)
+	s.fakeSource.Disconnect()
+}
+
+// identifiedSource is a fake source proving the identity of a device (like
+// Client, for devices with CapabilityIdentity).
+type identifiedSource struct {
+	*fakeSource
+	identity string
+	started  atomic.Bool
+}
+
+func newIdentifiedSource(identity string) *identifiedSource {
+	return &identifiedSource{fakeSource: newFakeSource(), identity: identity}
+}
+
+func (s *identifiedSource) Identity() string {
+	return s.identity
+}
+
+func (s *identifiedSource) Start(ctx context.Context, signalIDs []uint32) error {
+	s.started.Store(true)
+	return s.fakeSource.Start(ctx, signalIDs)
+}
+
+// multiSource is a fake source streaming the ramp on each of its signals,
+// which drops out (sending no values) between dropFrom and dropTo after it's
+// started.
+type multiSource struct {
+	*fakeSource
+	signals          []openpsg.Signal
+	dropFrom, dropTo time.Duration
+	// The value of sample i of each signal, if not the ramp.
+	value func(id uint32, i int) float64
+}
+
+func newMultiSource(n int, dropFrom, dropTo time.Duration) *multiSource {
+	s := &multiSource{fakeSource: newFakeSource(), dropFrom: dropFrom, dropTo: dropTo}
+	for i := range n {
+		signal := s.fakeSource.signal
+		signal.ID = uint32(i + 1)
+		signal.Name = fmt.Sprintf("EEG channel %02d", i+1)
+		s.signals = append(s.signals, signal)
+	}
+	return s
+}
+
+func (s *multiSource) open(ctx context.Context) (openpsg.SignalSource, error) {
+	if _, err := s.fakeSource.open(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *multiSource) Signals(ctx context.Context) ([]openpsg.Signal, error) {
+	return s.signals, nil
+}
+
+func (s *multiSource) Start(ctx context.Context, signalIDs []uint32) error {
+	go s.stream(time.Now())
+	return nil
+}
+
+func (s *multiSource) stream(start time.Time) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var sent int
+	for {
+		select {
+		case <-s.stopped:
+			return
+		case <-ticker.C:
+		}
+
+		elapsed := time.Since(start)
+		n := int(elapsed.Seconds() * 100)
+		if elapsed >= s.dropFrom && elapsed < s.dropTo {
+			sent = n
+			continue
+		}
+
+		for _, signal := range s.signals {
+			values := make([]float64, 0, n-sent)
+			for i := sent; i < n; i++ {
+				if s.value != nil {
+					values = append(values, s.value(signal.ID, i))
+				} else {
+					values = append(values, float64(i%100))
+				}
+			}
+
+			select {
+			case s.values <- openpsg.SignalValues{ID: signal.ID, Timestamp: start.Add(time.Duration(sent) * 10 * time.Millisecond), Values: values}:
+			case <-s.stopped:
+				return
+			}
+		}
+		sent = n
+	}
+}
+
+// record records the source for d, returning the recorded file.
+func record(t *testing.T, open openpsg.SourceOpener, d time.Duration, opts ...openpsg.RecordOption) *edfplus.Reader {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "recording.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	opts = append([]openpsg.RecordOption{
+		openpsg.WithSource(openpsg.LocalSourceAddr(1), open),
+		openpsg.WithDataRecordDuration(time.Second),
+	}, opts...)
+	require.NoError(t, openpsg.Record(ctx, f, "X", "Test", nil, opts...))
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+	return er
+}
+
+func TestRecordStopMidRecord(t *testing.T) {
+	// The recording stops in its second data record, before the first is due
+	// to be written.
+	er := record(t, newFakeSource().open, 1250*time.Millisecond)
+	require.Equal(t, 2, er.Header().DataRecords)
+
+	first, err := er.ReadRecord()
+	require.NoError(t, err)
+
+	second, err := er.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, second.Onset-first.Onset)
+}
+
+func TestRecordStopDisconnected(t *testing.T) {
+	source := newReconnectingSource()
+	time.AfterFunc(500*time.Millisecond, source.Disconnect)
+
+	var report openpsg.RecordingReport
+	withReport := openpsg.WithReport(func(r openpsg.RecordingReport) {
+		report = r
+	})
+
+	// The device is still disconnected when the recording stops, so it can't
+	// be stopped, but the recording is still complete.
+	er := record(t, source.open, 1250*time.Millisecond, withReport)
+	assert.Equal(t, 2, er.Header().DataRecords)
+
+	// The outage in progress is reported.
+	require.Len(t, report.Devices, 1)
+	assert.Equal(t, 0, report.Devices[0].Reconnects)
+	assert.Equal(t, 1, report.Devices[0].Outages)
+	assert.InDelta(t, 0.75, report.Devices[0].Disconnected, 0.1)
+}
+
+func TestRecordDropout(t *testing.T) {
+	// Every channel of a device drops out for a couple of data records, while
+	// another device keeps streaming.
+	source := newMultiSource(32, 500*time.Millisecond, 2500*time.Millisecond)
+	er := record(t, newFakeSource().open, 3250*time.Millisecond,
+		openpsg.WithSource(openpsg.LocalSourceAddr(2), source.open),
+		openpsg.WithGapFill(openpsg.GapFillInvalid))
+
+	var dropouts []edfplus.Annotation
+	start := time.Duration(-1)
+	for {
+		record, err := er.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if start < 0 {
+			start = record.Onset
+		}
+
+		for _, a := range record.Annotations {
+			if strings.HasPrefix(a.Text, "Missing signal values") && a.Duration > time.Second {
+				dropouts = append(dropouts, a)
+			}
+		}
+	}
+
+	// A single annotation covers the dropout of every channel, rather than
+	// one for each channel in each data record.
+	require.Len(t, dropouts, 1)
+	assert.Equal(t, "Missing signal values: 32 signals", dropouts[0].Text)
+	assert.InDelta(t, 0.5, (dropouts[0].Onset - start).Seconds(), 0.2)
+	assert.InDelta(t, 2, dropouts[0].Duration.Seconds(), 0.2)
+}
+
+func TestRecordJournalPseudonym(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "recording.edf.journal")
+
+	record(t, newFakeSource().open, 500*time.Millisecond,
+		openpsg.WithJournal(journalPath),
+		openpsg.WithPseudonym("PSN-K3M9QX7T2B"))
+
+	// The pseudonym is journaled, so a resumed recording keeps it.
+	journal, err := openpsg.LoadJournal(journalPath)
+	require.NoError(t, err)
+	assert.Equal(t, "PSN-K3M9QX7T2B", journal.Pseudonym)
+}
+
+func TestRecordReport(t *testing.T) {
+	var report openpsg.RecordingReport
+	withReport := openpsg.WithReport(func(r openpsg.RecordingReport) {
+		report = r
+	})
+
+	record(t, newFakeSource().open, 1250*time.Millisecond, withReport)
+
+	assert.Equal(t, report.StartTime, report.RecordingStartTime)
+	assert.InDelta(t, 1.25, report.Duration, 0.1)
+
+	// The values expected over the time recorded, rather than in the data
+	// records written (the last of which is padded).
+	require.Len(t, report.Devices, 1)
+	require.Len(t, report.Devices[0].Signals, 1)
+	signal := report.Devices[0].Signals[0]
+	assert.InDelta(t, 125, signal.Expected, 10)
+	assert.LessOrEqual(t, signal.Recorded, signal.Expected)
+}
+
+func TestRecordSampleFormat(t *testing.T) {
+	t.Run("Int24", func(t *testing.T) {
+		source := newFakeSource()
+		source.signal.SampleFormat = openpsg.SampleFormatInt24
+		source.signal.DigitalMin = -1000
+		source.signal.DigitalMax = 1000
+
+		// The digital range is kept, rather than rescaled.
+		er := record(t, source.open, 500*time.Millisecond)
+		require.Len(t, er.Signals(), 1)
+		assert.Equal(t, -1000, er.Signals()[0].DigitalMin)
+		assert.Equal(t, 1000, er.Signals()[0].DigitalMax)
+	})
+
+	t.Run("Int24 Wide Range", func(t *testing.T) {
+		source := newFakeSource()
+		source.signal.SampleFormat = openpsg.SampleFormatInt24
+
+		f, err := os.Create(filepath.Join(t.TempDir(), "recording.edf"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = f.Close()
+		})
+
+		err = openpsg.Record(context.Background(), f, "X", "Test", nil,
+			openpsg.WithSource(openpsg.LocalSourceAddr(1), source.open))
+		assert.ErrorContains(t, err, "wider than the 16-bit samples")
+	})
+}
+
+// newTestRecording prepares a recording of the source, without starting it.
+func newTestRecording(t *testing.T, open openpsg.SourceOpener) *openpsg.Recording {
+	t.Helper()
+
+	r, err := openpsg.NewTestRecording(context.Background(),
+		openpsg.WithSource(openpsg.LocalSourceAddr(1), open),
+		openpsg.WithDataRecordDuration(time.Second))
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestRecordingIngest(t *testing.T) {
+	r := newTestRecording(t, newFakeSource().open)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, r.Ingest(ctx))
+
+	// The ramp is buffered (as physical values, scaled from the full 16-bit
+	// range) from when the source was started, shortly after the start of the
+	// first data record.
+	values, received := r.Take(0, 50)
+	first := slices.Index(received, true)
+	require.GreaterOrEqual(t, first, 0)
+	require.Less(t, first, 10)
+	for i := first; i < 40; i++ {
+		require.True(t, received[i])
+		assert.InDelta(t, -5+float64(i-first+32768)*10/65535, values[i], 1e-9)
+	}
+
+	report := r.Report()
+	require.Len(t, report.Devices, 1)
+	require.Len(t, report.Devices[0].Signals, 1)
+	assert.GreaterOrEqual(t, report.Devices[0].Signals[0].Received, uint64(40))
+}
+
+func TestRecordingReconnectIdentity(t *testing.T) {
+	tests := []struct {
+		name           string
+		identity       string
+		wantReconnects int
+	}{
+		{
+			name:           "Same identity",
+			identity:       "device",
+			wantReconnects: 1,
+		},
+		{
+			// Another device has taken over the address.
+			name:     "Different identity",
+			identity: "impostor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, reconnected := newIdentifiedSource("device"), newIdentifiedSource(tt.identity)
+
+			var opened atomic.Int32
+			r := newTestRecording(t, func(ctx context.Context) (openpsg.SignalSource, error) {
+				switch opened.Add(1) {
+				case 1:
+					return device, nil
+				case 2:
+					return reconnected, nil
+				default:
+					return nil, errors.New("device offline")
+				}
+			})
+
+			time.AfterFunc(250*time.Millisecond, device.Disconnect)
+
+			// Long enough for a single attempt to reconnect.
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			require.NoError(t, r.Ingest(ctx))
+
+			// A refused device is closed without being started.
+			require.EqualValues(t, 2, opened.Load())
+			assert.Equal(t, tt.wantReconnects == 1, reconnected.started.Load())
+			select {
+			case <-reconnected.stopped:
+			default:
+				t.Error("the reconnected device wasn't closed or stopped")
+			}
+
+			report := r.Report()
+			require.Len(t, report.Devices, 1)
+			assert.Equal(t, tt.wantReconnects, report.Devices[0].Reconnects)
+			assert.Equal(t, 1, report.Devices[0].Outages)
+		})
+	}
+}
+
+func TestRecordingWrite(t *testing.T) {
+	// The source is never started, the values are buffered directly.
+	r := newTestRecording(t, newFakeSource().open)
+
+	values := make([]float64, 150)
+	for i := range values {
+		values[i] = float64(i%10) - 5
+	}
+	require.Zero(t, r.Put(0, r.RecordStart(), values))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "recording.edf"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1250*time.Millisecond)
+	defer cancel()
+	require.NoError(t, r.Write(ctx, f))
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	er, err := edfplus.Open(f)
+	require.NoError(t, err)
+	require.Equal(t, 2, er.Header().DataRecords)
+
+	signal := er.Signals()[0]
+	for n := range 2 {
+		record, err := er.ReadRecord()
+		require.NoError(t, err)
+
+		for i, digital := range record.Samples[0] {
+			want := 0.0 // Missing values are filled with zero.
+			if k := 100*n + i; k < len(values) {
+				want = values[k]
+			}
+			assert.InDelta(t, want, edfplus.DigitalToPhysical(signal, digital), 0.001)
+		}
+	}
+}
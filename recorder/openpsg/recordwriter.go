@@ -0,0 +1,173 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+// recordBuffers holds the values of a data record while it is assembled and
+// written.
+type recordBuffers struct {
+	values   [][]float64
+	received [][]bool
+	// The values of the signals in each EDF file.
+	parts [][][]float64
+}
+
+type pendingRecord struct {
+	onset       time.Duration
+	annotations []edfplus.Annotation
+	buffers     *recordBuffers
+}
+
+// recordWriter writes data records to the EDF files on its own goroutine, so
+// slow storage (eg. SD cards or network filesystems) doesn't hold up assembling
+// the next data record. Data records are double buffered, one can be assembled
+// while the other is being written.
+type recordWriter struct {
+	writers   []*edfplus.Writer
+	queue     chan *pendingRecord
+	free      chan *recordBuffers
+	done      chan struct{}
+	failed    chan struct{}
+	err       error
+	closeOnce sync.Once
+	// How often to checkpoint the EDF files (zero to never).
+	syncInterval time.Duration
+	lastSync     time.Time
+	// Called (on the writing goroutine) after each checkpoint.
+	checkpointed func()
+}
+
+func newRecordWriter(writers []*edfplus.Writer, parts [][]int, samplesPerRecord []int, syncInterval time.Duration, checkpointed func()) *recordWriter {
+	const depth = 2
+
+	rw := &recordWriter{
+		writers: writers,
+		queue:   make(chan *pendingRecord, depth),
+		free:    make(chan *recordBuffers, depth),
+		done:    make(chan struct{}),
+		failed:  make(chan struct{}),
+
+		syncInterval: syncInterval,
+		lastSync:     time.Now(),
+		checkpointed: checkpointed,
+	}
+
+	for range depth {
+		buffers := &recordBuffers{
+			values:   make([][]float64, len(samplesPerRecord)),
+			received: make([][]bool, len(samplesPerRecord)),
+			parts:    make([][][]float64, len(parts)),
+		}
+		for i, n := range samplesPerRecord {
+			buffers.values[i] = make([]float64, n)
+			buffers.received[i] = make([]bool, n)
+		}
+		for n, part := range parts {
+			buffers.parts[n] = make([][]float64, len(part))
+			for i, signalIndex := range part {
+				buffers.parts[n][i] = buffers.values[signalIndex]
+			}
+		}
+		rw.free <- buffers
+	}
+
+	go rw.run()
+
+	return rw
+}
+
+func (rw *recordWriter) run() {
+	defer close(rw.done)
+
+	for rec := range rw.queue {
+		// After a failure, data records are discarded (so the queue never blocks)
+		// until the writer is closed.
+		if rw.err == nil {
+			if err := rw.write(rec); err != nil {
+				rw.err = err
+				close(rw.failed)
+			}
+		}
+
+		rw.free <- rec.buffers
+	}
+}
+
+// write writes a data record (and its annotations) to each of the EDF files.
+func (rw *recordWriter) write(rec *pendingRecord) error {
+	for n, ew := range rw.writers {
+		for _, a := range rec.annotations {
+			ew.Annotate(a)
+		}
+
+		if err := ew.WriteRecord(rec.onset, rec.buffers.parts[n]); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	if rw.syncInterval > 0 && time.Since(rw.lastSync) >= rw.syncInterval {
+		for _, ew := range rw.writers {
+			if err := ew.Checkpoint(); err != nil {
+				return fmt.Errorf("failed to checkpoint EDF file: %w", err)
+			}
+		}
+		rw.lastSync = time.Now()
+
+		if rw.checkpointed != nil {
+			rw.checkpointed()
+		}
+	}
+
+	return nil
+}
+
+// buffers returns buffers to assemble the next data record in, waiting for a
+// data record to be written if necessary.
+func (rw *recordWriter) buffers() (*recordBuffers, error) {
+	select {
+	case buffers := <-rw.free:
+		return buffers, nil
+	case <-rw.failed:
+		return nil, rw.err
+	}
+}
+
+// enqueue queues a data record assembled in buffers to be written, along with
+// the annotations to be written in it.
+func (rw *recordWriter) enqueue(onset time.Duration, annotations []edfplus.Annotation, buffers *recordBuffers) {
+	rw.queue <- &pendingRecord{onset: onset, annotations: annotations, buffers: buffers}
+}
+
+// close waits for the queued data records to be written, returning the first
+// error encountered.
+func (rw *recordWriter) close() error {
+	rw.closeOnce.Do(func() {
+		close(rw.queue)
+	})
+	<-rw.done
+
+	return rw.err
+}
@@ -0,0 +1,177 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+)
+
+// Replay replays the signals of a recording (an EDF or EDF+ file) as a
+// simulated device (see SimulatedDevice), eg. to regression test the recorder
+// against known studies. Gaps in discontinuous recordings aren't replayed.
+type Replay struct {
+	f       *os.File
+	signals []Signal
+	// The number of samples of each signal in a data record.
+	samplesPerRecord []int
+	records          int
+	loop             bool
+
+	mu     sync.Mutex
+	reader *edfplus.Reader
+	// The last data record read, and its index.
+	record      *edfplus.Record
+	recordIndex int
+}
+
+// OpenReplay opens a recording to replay, optionally looping back to its start
+// once it has ended.
+func OpenReplay(path string, loop bool) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	r, err := newReplay(f, loop)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func newReplay(f *os.File, loop bool) (*Replay, error) {
+	reader, err := edfplus.Open(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+
+	hdr := reader.Header()
+	if hdr.DataRecordDuration <= 0 {
+		return nil, fmt.Errorf("recording has no data record duration")
+	}
+
+	// The number of data records is only known once a recording has been
+	// closed, so it's counted from the size of the file.
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat recording: %w", err)
+	}
+	records := int((info.Size() - int64(hdr.HeaderBytes)) / int64(reader.RecordSize()))
+	if hdr.DataRecords >= 0 {
+		records = min(records, hdr.DataRecords)
+	}
+	if records <= 0 {
+		return nil, fmt.Errorf("recording has no data records")
+	}
+
+	r := &Replay{
+		f:           f,
+		records:     records,
+		loop:        loop,
+		reader:      reader,
+		recordIndex: -1,
+	}
+
+	for i, header := range reader.Signals() {
+		rate := float64(header.SamplesPerRecord) / hdr.DataRecordDuration.Seconds()
+		if rate <= 0 || rate != math.Trunc(rate) {
+			return nil, fmt.Errorf("unsupported sample rate %g Hz for signal %q", rate, strings.TrimSpace(header.Label))
+		}
+
+		var prefiltering FilterList
+		if err := prefiltering.parse(header.Prefiltering); err != nil {
+			slog.Debug("Ignoring prefiltering of signal",
+				slog.String("signal", header.Label), slog.String("prefiltering", header.Prefiltering), slog.Any("error", err))
+			prefiltering = FilterList{}
+		}
+
+		r.signals = append(r.signals, Signal{
+			ID:             uint32(i + 1),
+			Name:           strings.TrimSpace(header.Label),
+			TransducerType: TransducerType(strings.TrimSpace(header.TransducerType)),
+			Unit:           Unit(strings.TrimSpace(header.PhysicalDimension)),
+			Min:            float32(header.PhysicalMin),
+			Max:            float32(header.PhysicalMax),
+			DigitalMin:     int32(header.DigitalMin),
+			DigitalMax:     int32(header.DigitalMax),
+			SampleFormat:   SampleFormatInt16,
+			Prefiltering:   prefiltering,
+			SampleRate:     uint32(rate),
+		})
+		r.samplesPerRecord = append(r.samplesPerRecord, header.SamplesPerRecord)
+	}
+
+	if len(r.signals) == 0 {
+		return nil, fmt.Errorf("recording has no signals")
+	}
+
+	return r, nil
+}
+
+// Close closes the recording.
+func (r *Replay) Close() error {
+	return r.f.Close()
+}
+
+func (r *Replay) Signals() []Signal {
+	return r.signals
+}
+
+func (r *Replay) Values(signal int, from, to uint64) ([]float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	samplesPerRecord := uint64(r.samplesPerRecord[signal])
+
+	values := make([]float64, 0, to-from)
+	for i := from; i < to; i++ {
+		index := int(i / samplesPerRecord)
+		if r.loop {
+			index %= r.records
+		} else if index >= r.records {
+			break
+		}
+
+		if index != r.recordIndex {
+			r.reader.Seek(index)
+
+			record, err := r.reader.ReadRecord()
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read recording: %w", err)
+			}
+
+			r.record, r.recordIndex = record, index
+		}
+
+		values = append(values, float64(r.record.Samples[signal][i%samplesPerRecord]))
+	}
+	return values, nil
+}
@@ -0,0 +1,225 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"math"
+	"time"
+)
+
+// RecordingReport summarizes a recording once it has stopped, to judge whether
+// its data is usable.
+type RecordingReport struct {
+	// When recording started (when it was resumed, for a resumed recording,
+	// as only the values recorded since are reported).
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// When the recording first started (before StartTime, if it was resumed).
+	RecordingStartTime time.Time `json:"recording_start_time"`
+	// The time recorded in seconds, less any pauses.
+	Duration float64        `json:"duration"`
+	Devices  []DeviceReport `json:"devices"`
+}
+
+// DeviceReport summarizes the recording of a device.
+type DeviceReport struct {
+	Address string `json:"address"`
+	// The friendly name of the device (see DeviceNames).
+	Name string `json:"name,omitempty"`
+	// The number of times the connection to the device was reestablished.
+	Reconnects int `json:"reconnects"`
+	// The number of times the connection to the device was lost (one more than
+	// the reconnects if it was still lost when the recording stopped).
+	Outages int `json:"outages"`
+	// The time the device was disconnected in seconds.
+	Disconnected float64 `json:"disconnected"`
+	// The last measured offset of the device's clock in seconds, if measured.
+	ClockOffset *float64       `json:"clock_offset,omitempty"`
+	Signals     []SignalReport `json:"signals"`
+}
+
+// SignalReport summarizes the recording of a signal.
+type SignalReport struct {
+	Label      string `json:"label"`
+	Unit       Unit   `json:"unit,omitempty"`
+	SampleRate uint32 `json:"sample_rate"`
+	// The number of values the device sent.
+	Received uint64 `json:"received"`
+	// The number of values the recording should hold (the time recorded at
+	// the rate the signal is stored at), and the number it does.
+	Expected uint64 `json:"expected"`
+	Recorded uint64 `json:"recorded"`
+	// The number of runs of missing values, and the longest in seconds.
+	Gaps       int     `json:"gaps"`
+	LongestGap float64 `json:"longest_gap"`
+	// The number of values dropped, as they arrived outside of the buffered
+	// window.
+	Dropped uint64 `json:"dropped"`
+	// The number of batches of values lost in transit (for devices sending
+	// sequence numbers).
+	Lost uint64 `json:"lost"`
+	// The estimated drift of the device's sample clock in parts per million,
+	// if measured.
+	ClockDrift *float64 `json:"clock_drift,omitempty"`
+	// The range of the values received (in the unit of the signal), if any.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// Completeness returns the fraction of the expected values recorded.
+func (r SignalReport) Completeness() float64 {
+	if r.Expected == 0 {
+		return 0
+	}
+	return float64(r.Recorded) / float64(r.Expected)
+}
+
+// WithReport reports a summary of the recording once it has stopped.
+func WithReport(report func(RecordingReport)) RecordOption {
+	return func(o *recordOptions) {
+		o.report = report
+	}
+}
+
+// report summarizes the recording once it has stopped.
+func (r *recording) report() RecordingReport {
+	endTime := r.endTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+
+	duration := max(0, endTime.Sub(r.sessionStart)-r.pausedTime)
+	report := RecordingReport{
+		StartTime:          r.sessionStart,
+		EndTime:            endTime,
+		RecordingStartTime: r.start,
+		Duration:           duration.Seconds(),
+	}
+	for _, device := range r.devices {
+		deviceReport := DeviceReport{
+			Address:      device.addr.String(),
+			Name:         r.names[device.addr],
+			Reconnects:   device.reconnects,
+			Outages:      device.outages,
+			Disconnected: device.disconnected.Seconds(),
+		}
+		if device.clockOffset != nil {
+			offset := device.clockOffset.Offset.Seconds()
+			deviceReport.ClockOffset = &offset
+		}
+		for i, signal := range r.signals {
+			if r.signalDevices[i] == device.addr {
+				deviceReport.Signals = append(deviceReport.Signals, r.stats[i].report(signal, duration))
+			}
+		}
+		report.Devices = append(report.Devices, deviceReport)
+	}
+	return report
+}
+
+// signalStats accumulates the statistics of a signal for the report.
+type signalStats struct {
+	sampleRate  uint32
+	storageRate float64
+	drift       *driftEstimator
+
+	// Updated as values are received.
+	received uint64
+	min, max float64
+	dropped  uint64
+	lost     uint64
+
+	// Updated as data records are written.
+	recorded        uint64
+	gaps            int
+	gap, longestGap int
+}
+
+func newSignalStats(sampleRate, storageRate uint32) *signalStats {
+	return &signalStats{
+		sampleRate:  sampleRate,
+		storageRate: float64(storageRate),
+		drift:       newDriftEstimator(float64(sampleRate)),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// observe adds a batch of values received starting at timestamp.
+func (s *signalStats) observe(timestamp time.Time, values []float64) {
+	s.drift.observe(timestamp, len(values))
+	s.received += uint64(len(values))
+	for _, value := range values {
+		s.min = min(s.min, value)
+		s.max = max(s.max, value)
+	}
+}
+
+// written adds the values of a data record, received or not. Gaps spanning
+// consecutive data records are counted once.
+func (s *signalStats) written(received []bool) {
+	for _, ok := range received {
+		if ok {
+			s.recorded++
+			s.gap = 0
+			continue
+		}
+
+		if s.gap == 0 {
+			s.gaps++
+		}
+		s.gap++
+		s.longestGap = max(s.longestGap, s.gap)
+	}
+}
+
+// skipped adds n values of a data record that wasn't written, as no values
+// had arrived.
+func (s *signalStats) skipped(n int) {
+	if s.gap == 0 {
+		s.gaps++
+	}
+	s.gap += n
+	s.longestGap = max(s.longestGap, s.gap)
+}
+
+// report summarizes the signal recorded for duration (the sample rate of the
+// device is reported, rather than the storage rate).
+func (s *signalStats) report(signal Signal, duration time.Duration) SignalReport {
+	r := SignalReport{
+		Label:      signal.Name,
+		Unit:       signal.Unit,
+		SampleRate: s.sampleRate,
+		Received:   s.received,
+		Expected:   uint64(math.Round(duration.Seconds() * s.storageRate)),
+		Recorded:   s.recorded,
+		Gaps:       s.gaps,
+		LongestGap: float64(s.longestGap) / s.storageRate,
+		Dropped:    s.dropped,
+		Lost:       s.lost,
+	}
+	if s.drift.ratio != 0 {
+		ppm := s.drift.ppm()
+		r.ClockDrift = &ppm
+	}
+	if s.received > 0 {
+		r.Min, r.Max = &s.min, &s.max
+	}
+	return r
+}
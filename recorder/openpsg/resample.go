@@ -0,0 +1,217 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The number of zero crossings of the anti-aliasing filter on each side of
+	// its center, trading sharpness of the cutoff for computation.
+	resamplerZeroCrossings = 10
+	// The cutoff of the anti-aliasing filter, relative to the Nyquist
+	// frequency of the lower of the two rates.
+	resamplerCutoff = 0.9
+	// Batches further than this from where the previous batch ended restart
+	// the resampler.
+	maxResamplerDiscontinuity = time.Second
+)
+
+// StorageRate is the sample rate the selected signals are stored at, when lower
+// (or higher) than the rate they are acquired at.
+type StorageRate struct {
+	selector signalSelector
+	rate     uint32
+}
+
+// ParseStorageRate parses the storage rate of the signals matched by a
+// selector (see ParseSignalSelection), in the form "<selector>=<rate>" (eg.
+// "EEG*=256").
+func ParseStorageRate(s string) (*StorageRate, error) {
+	selector, rate, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid storage rate %q, expected <selector>=<rate>", s)
+	}
+
+	sel, err := parseSignalSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(rate), "Hz"), 10, 32)
+	if err != nil || r == 0 {
+		return nil, fmt.Errorf("invalid storage rate %q", s)
+	}
+
+	return &StorageRate{selector: sel, rate: uint32(r)}, nil
+}
+
+// WithStorageRates resamples the matching signals to their storage rate, with
+// an anti-aliasing filter, before they are stored (eg. to store a signal
+// acquired at 2 kHz at 256 Hz, reducing the size of the recording).
+func WithStorageRates(rates ...*StorageRate) RecordOption {
+	return func(o *recordOptions) {
+		o.storageRates = append(o.storageRates, rates...)
+	}
+}
+
+// storageRate returns the rate to store a signal of the device at addr at.
+func storageRate(rates []*StorageRate, addr netip.Addr, signal Signal) uint32 {
+	for _, r := range rates {
+		if r.selector.matches(addr, signal) {
+			return r.rate
+		}
+	}
+	return signal.SampleRate
+}
+
+// resampler converts a stream of values between sample rates by a rational
+// factor (up/down), using a polyphase windowed-sinc anti-aliasing filter.
+type resampler struct {
+	inRate       float64
+	up, down     int64
+	tapsPerPhase int64
+	// The filter coefficients of each phase, in reverse order.
+	phases [][]float64
+	// The delay of the filter.
+	delay time.Duration
+
+	// Input values not yet needed for any output, the first being input index
+	// bufStart.
+	buf      []float64
+	bufStart int64
+	// The number of values consumed and produced.
+	consumed, produced int64
+	// Where the next batch is expected to start.
+	next time.Time
+}
+
+// newResampler creates a resampler between the rates, returning nil if they are
+// the same.
+func newResampler(inRate, outRate uint32) *resampler {
+	if inRate == outRate {
+		return nil
+	}
+
+	g := gcd(int64(inRate), int64(outRate))
+	up, down := int64(outRate)/g, int64(inRate)/g
+
+	// Design the low-pass filter at the upsampled rate.
+	factor := max(up, down)
+	n := 2*resamplerZeroCrossings*factor + 1
+	tapsPerPhase := (n + up - 1) / up
+	cutoff := resamplerCutoff * 0.5 / float64(factor)
+	center := float64(n-1) / 2
+
+	h := make([]float64, tapsPerPhase*up)
+	for i := range n {
+		x := float64(i) - center
+		sinc := 2 * cutoff
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		// Blackman window.
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(n-1))
+		h[i] = float64(up) * sinc * w
+	}
+
+	phases := make([][]float64, up)
+	for p := range up {
+		phases[p] = make([]float64, tapsPerPhase)
+		for k := range tapsPerPhase {
+			// Reversed so the taps line up with the oldest input first.
+			phases[p][tapsPerPhase-1-k] = h[p+k*up]
+		}
+	}
+
+	return &resampler{
+		inRate:       float64(inRate),
+		up:           up,
+		down:         down,
+		tapsPerPhase: tapsPerPhase,
+		phases:       phases,
+		delay:        time.Duration(center / float64(up) / float64(inRate) * float64(time.Second)),
+	}
+}
+
+// resample appends the values resampled from a batch of input values starting
+// at timestamp to dst, returning the timestamp of the first appended value.
+func (r *resampler) resample(dst []float64, timestamp time.Time, values []float64) (time.Time, []float64) {
+	if !r.next.IsZero() && (timestamp.Sub(r.next) > maxResamplerDiscontinuity || r.next.Sub(timestamp) > maxResamplerDiscontinuity) {
+		r.reset()
+	}
+
+	batchStart := r.consumed
+	r.buf = append(r.buf, values...)
+	r.consumed += int64(len(values))
+	r.next = timestamp.Add(time.Duration(float64(len(values)) / r.inRate * float64(time.Second)))
+
+	first := r.produced
+	for {
+		u := r.produced * r.down
+		n := u / r.up
+		if n >= r.consumed {
+			break
+		}
+
+		var y float64
+		taps := r.phases[u%r.up]
+		// The inputs n-tapsPerPhase+1 to n (zero before the first input).
+		start := n - r.tapsPerPhase + 1
+		for k, tap := range taps {
+			if i := start + int64(k) - r.bufStart; i >= 0 {
+				y += tap * r.buf[i]
+			}
+		}
+
+		dst = append(dst, y)
+		r.produced++
+	}
+
+	// Drop inputs that are no longer needed.
+	if needed := (r.produced*r.down)/r.up - r.tapsPerPhase + 1; needed > r.bufStart {
+		drop := min(needed-r.bufStart, int64(len(r.buf)))
+		r.buf = r.buf[:copy(r.buf, r.buf[drop:])]
+		r.bufStart += drop
+	}
+
+	// The time of the first output (relative to the batch), less the delay of
+	// the filter.
+	offset := float64(first*r.down)/float64(r.up) - float64(batchStart)
+	return timestamp.Add(time.Duration(offset/r.inRate*float64(time.Second)) - r.delay), dst
+}
+
+// reset restarts the resampler, after a discontinuity in the input.
+func (r *resampler) reset() {
+	r.buf = r.buf[:0]
+	r.bufStart, r.consumed, r.produced = 0, 0, 0
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
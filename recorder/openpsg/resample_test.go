@@ -0,0 +1,113 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStorageRate(t *testing.T) {
+	_, err := openpsg.ParseStorageRate("EEG*=256Hz")
+	require.NoError(t, err)
+
+	for _, s := range []string{"EEG*", "EEG*=0", "EEG*=fast", "EEG*=-1"} {
+		_, err := openpsg.ParseStorageRate(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestResampler(t *testing.T) {
+	assert.Nil(t, openpsg.NewResampler(256, 256))
+
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		inRate    uint32
+		outRate   uint32
+		frequency float64
+		// The expected amplitude of the sinusoid once resampled.
+		wantAmplitude float64
+	}{
+		{"Downsample DC", 512, 256, 0, 1},
+		{"Downsample passband", 512, 256, 10, 1},
+		{"Downsample stopband", 512, 256, 200, 0},
+		{"Upsample DC", 256, 512, 0, 1},
+		{"Upsample passband", 256, 512, 10, 1},
+		{"Rational", 200, 256, 10, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := openpsg.NewResampler(tt.inRate, tt.outRate)
+			require.NotNil(t, r)
+
+			values := make([]float64, 2*tt.inRate)
+			for i := range values {
+				values[i] = math.Cos(2 * math.Pi * tt.frequency * float64(i) / float64(tt.inRate))
+			}
+
+			first, resampled := r.Resample(nil, start, values)
+			require.Len(t, resampled, 2*int(tt.outRate))
+			assert.Equal(t, start.Add(-r.Delay()), first)
+
+			// Skip the values before the filter has settled.
+			settled := int(tt.outRate) / 4
+			for i, value := range resampled[settled:] {
+				elapsed := first.Sub(start).Seconds() + float64(settled+i)/float64(tt.outRate)
+				want := tt.wantAmplitude * math.Cos(2*math.Pi*tt.frequency*elapsed)
+				assert.InDelta(t, want, value, 0.01, "value %d", settled+i)
+			}
+		})
+	}
+}
+
+func TestResamplerBatches(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+	period := time.Second / 256
+
+	r := openpsg.NewResampler(512, 256)
+	values := make([]float64, 100)
+
+	// Consecutive batches continue where the previous batch ended, even when
+	// a batch doesn't divide evenly into output values.
+	first, resampled := r.Resample(nil, start, values)
+	assert.Equal(t, start.Add(-r.Delay()), first)
+	require.Len(t, resampled, 50)
+
+	next, resampled := r.Resample(nil, start.Add(100*time.Second/512), values[:51])
+	assert.WithinDuration(t, first.Add(50*period), next, time.Microsecond)
+	require.Len(t, resampled, 26)
+
+	next, resampled = r.Resample(nil, start.Add(151*time.Second/512), values)
+	assert.WithinDuration(t, first.Add(76*period), next, time.Microsecond)
+	require.Len(t, resampled, 50)
+
+	// A discontinuity restarts the resampler.
+	restart := start.Add(5 * time.Second)
+	next, resampled = r.Resample(nil, restart, values)
+	assert.Equal(t, restart.Add(-r.Delay()), next)
+	assert.Len(t, resampled, 50)
+}
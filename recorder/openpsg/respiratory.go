@@ -0,0 +1,187 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// The amplitude of airflow is compared to its average over this period
+	// before each event.
+	respiratoryBaselineDuration = 2 * time.Minute
+	// Events aren't detected until there is at least this much baseline.
+	minRespiratoryBaseline = 30 * time.Second
+	// The amplitude is averaged over about one breath.
+	respiratorySmoothing = 4 * time.Second
+	// The time constant of the slowly varying offset removed from airflow.
+	respiratoryOffsetTimeConstant = 10 * time.Second
+	// A hypopnea is a drop in amplitude of at least 30%, and an apnea of at
+	// least 90%, lasting at least 10 seconds.
+	hypopneaThreshold           = 0.7
+	apneaThreshold              = 0.1
+	minRespiratoryEventDuration = 10 * time.Second
+)
+
+// WithRespiratoryEvents detects candidate apneas and hypopneas in the selected
+// airflow signals (see ParseSignalSelection) in real time, and annotates them
+// for later review.
+func WithRespiratoryEvents(s *SignalSelection) RecordOption {
+	return func(o *recordOptions) {
+		o.respiratoryEvents = s
+	}
+}
+
+// respiratoryEventDetector flags reductions in the amplitude of an airflow
+// signal relative to its recent baseline.
+type respiratoryEventDetector struct {
+	name       string
+	sampleRate float64
+
+	// The slowly varying offset of the signal.
+	offset      float64
+	offsetKnown bool
+
+	// The current one second block, starting at blockStart.
+	block      []float64
+	blockStart time.Time
+
+	// The RMS of recent blocks, and the amplitude of recent blocks outside of
+	// events (for the baseline).
+	amplitudes []float64
+	baseline   []float64
+
+	// The current event, if any.
+	eventStart time.Time
+	minRatio   float64
+}
+
+func newRespiratoryEventDetector(name string, sampleRate uint32) *respiratoryEventDetector {
+	return &respiratoryEventDetector{
+		name:       name,
+		sampleRate: float64(sampleRate),
+		block:      make([]float64, 0, max(sampleRate, 1)),
+	}
+}
+
+// observe adds values starting at timestamp, returning any completed events.
+func (d *respiratoryEventDetector) observe(timestamp time.Time, values []float64) []Annotation {
+	alpha := 1 / (d.sampleRate * respiratoryOffsetTimeConstant.Seconds())
+
+	var events []Annotation
+	for i, value := range values {
+		if !d.offsetKnown {
+			d.offset, d.offsetKnown = value, true
+		}
+		d.offset += alpha * (value - d.offset)
+
+		if len(d.block) == 0 {
+			d.blockStart = timestamp.Add(time.Duration(float64(i) / d.sampleRate * float64(time.Second)))
+		}
+
+		d.block = append(d.block, value-d.offset)
+		if len(d.block) < cap(d.block) {
+			continue
+		}
+
+		if event, ok := d.endBlock(); ok {
+			events = append(events, event)
+		}
+		d.block = d.block[:0]
+	}
+
+	return events
+}
+
+// endBlock updates the amplitude with the completed block, returning an event
+// if one has ended.
+func (d *respiratoryEventDetector) endBlock() (Annotation, bool) {
+	var sumSquares float64
+	for _, value := range d.block {
+		sumSquares += value * value
+	}
+
+	d.amplitudes = appendWindow(d.amplitudes, math.Sqrt(sumSquares/float64(len(d.block))), int(respiratorySmoothing.Seconds()))
+	amplitude := mean(d.amplitudes)
+
+	if len(d.baseline) < int(minRespiratoryBaseline.Seconds()) {
+		d.baseline = appendWindow(d.baseline, amplitude, int(respiratoryBaselineDuration.Seconds()))
+		return Annotation{}, false
+	}
+
+	baseline := mean(d.baseline)
+	ratio := 1.0
+	if baseline > 0 {
+		ratio = amplitude / baseline
+	}
+
+	if d.eventStart.IsZero() {
+		if ratio < hypopneaThreshold {
+			// The smoothing delays the drop in amplitude.
+			d.eventStart = d.blockStart.Add(-respiratorySmoothing / 2)
+			d.minRatio = ratio
+			return Annotation{}, false
+		}
+
+		d.baseline = appendWindow(d.baseline, amplitude, int(respiratoryBaselineDuration.Seconds()))
+		return Annotation{}, false
+	}
+
+	if ratio < hypopneaThreshold {
+		d.minRatio = min(d.minRatio, ratio)
+		return Annotation{}, false
+	}
+
+	start := d.eventStart
+	duration := d.blockStart.Add(-respiratorySmoothing / 2).Sub(start)
+	d.eventStart = time.Time{}
+	if duration < minRespiratoryEventDuration {
+		return Annotation{}, false
+	}
+
+	kind := "Hypopnea"
+	if d.minRatio < apneaThreshold {
+		kind = "Apnea"
+	}
+
+	return Annotation{
+		Time:     start,
+		Duration: duration,
+		Text:     kind + " candidate: " + d.name,
+	}, true
+}
+
+// appendWindow appends a value to a window of at most n values, dropping the
+// oldest.
+func appendWindow(window []float64, value float64, n int) []float64 {
+	window = append(window, value)
+	if len(window) > n {
+		window = window[:copy(window, window[len(window)-n:])]
+	}
+	return window
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,164 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// airflowRate is the sample rate of the synthetic airflow.
+const airflowRate = 10
+
+// airflow returns breathing at 15 breaths a minute (around an offset), with
+// its amplitude scaled by ratio from reducedFrom for reducedFor, for seconds.
+func airflow(seconds, reducedFrom, reducedFor int, ratio float64) []float64 {
+	values := make([]float64, seconds*airflowRate)
+	for i := range values {
+		t := float64(i) / airflowRate
+		amplitude := 1.0
+		if t >= float64(reducedFrom) && t < float64(reducedFrom+reducedFor) {
+			amplitude = ratio
+		}
+		values[i] = 0.2 + amplitude*math.Sin(2*math.Pi*t/4)
+	}
+	return values
+}
+
+func TestRespiratoryEventDetector(t *testing.T) {
+	start := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		reducedFrom int
+		reducedFor  int
+		ratio       float64
+		// The expected event, if any, and its start and duration (in
+		// seconds, within a second as the amplitude is smoothed).
+		wantText     string
+		wantStart    float64
+		wantDuration float64
+	}{
+		{
+			name:         "Hypopnea",
+			reducedFrom:  60,
+			reducedFor:   20,
+			ratio:        0.5,
+			wantText:     "Hypopnea candidate: Flow",
+			wantStart:    60,
+			wantDuration: 19,
+		},
+		{
+			name:         "Apnea",
+			reducedFrom:  60,
+			reducedFor:   20,
+			ratio:        0,
+			wantText:     "Apnea candidate: Flow",
+			wantStart:    59,
+			wantDuration: 20,
+		},
+		{
+			name:         "35% drop",
+			reducedFrom:  60,
+			reducedFor:   20,
+			ratio:        0.65,
+			wantText:     "Hypopnea candidate: Flow",
+			wantStart:    61,
+			wantDuration: 17,
+		},
+		{
+			// Too small a drop for a hypopnea.
+			name:        "25% drop",
+			reducedFrom: 60,
+			reducedFor:  20,
+			ratio:       0.75,
+		},
+		{
+			// Too small a drop for an apnea.
+			name:         "85% drop",
+			reducedFrom:  60,
+			reducedFor:   20,
+			ratio:        0.15,
+			wantText:     "Hypopnea candidate: Flow",
+			wantStart:    59,
+			wantDuration: 20,
+		},
+		{
+			name:         "95% drop",
+			reducedFrom:  60,
+			reducedFor:   20,
+			ratio:        0.05,
+			wantText:     "Apnea candidate: Flow",
+			wantStart:    59,
+			wantDuration: 20,
+		},
+		{
+			name:         "Minimum duration",
+			reducedFrom:  60,
+			reducedFor:   12,
+			ratio:        0.5,
+			wantText:     "Hypopnea candidate: Flow",
+			wantStart:    60,
+			wantDuration: 11,
+		},
+		{
+			name:        "Too short",
+			reducedFrom: 60,
+			reducedFor:  10,
+			ratio:       0.5,
+		},
+		{
+			// Events aren't detected until there is 30 seconds of baseline.
+			name:        "Before the baseline",
+			reducedFrom: 5,
+			reducedFor:  20,
+			ratio:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := openpsg.NewRespiratoryEventDetector("Flow", airflowRate)
+
+			// The airflow arrives in batches of half a second.
+			values := airflow(120, tt.reducedFrom, tt.reducedFor, tt.ratio)
+			var events []openpsg.Annotation
+			for i := 0; i < len(values); i += airflowRate / 2 {
+				timestamp := start.Add(time.Duration(i) * time.Second / airflowRate)
+				events = append(events, d.Observe(timestamp, values[i:i+airflowRate/2])...)
+			}
+
+			if tt.wantText == "" {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			assert.Equal(t, tt.wantText, events[0].Text)
+			assert.InDelta(t, tt.wantStart, events[0].Time.Sub(start).Seconds(), 1)
+			assert.InDelta(t, tt.wantDuration, events[0].Duration.Seconds(), 1)
+			assert.GreaterOrEqual(t, events[0].Duration, 10*time.Second)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// RetryPolicy is how attempts to connect to a device are retried.
+type RetryPolicy struct {
+	// The number of attempts Connect makes before giving up (0 for no limit).
+	// Once connected, a client retries reconnecting until it is closed.
+	Attempts int
+	// The delay before the first retry, doubling with each further retry up to
+	// MaxDelay.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy gives up connecting on the first failure, and reconnects
+// a connected client every 1 to 30 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts: 1,
+	MinDelay: minReconnectDelay,
+	MaxDelay: maxReconnectDelay,
+}
+
+// WithDialTimeout limits how long establishing a connection to a device (and
+// the TLS handshake) may take (5 seconds by default).
+func WithDialTimeout(timeout time.Duration) ConnectOption {
+	return func(o *connectOptions) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithCallTimeout limits how long a device may take to respond to a request
+// (5 seconds by default), eg. for devices behind slow links.
+func WithCallTimeout(timeout time.Duration) ConnectOption {
+	return func(o *connectOptions) {
+		o.callTimeout = timeout
+	}
+}
+
+// WithRetry retries connecting to a device with the policy, rather than
+// DefaultRetryPolicy (eg. for devices that are still booting).
+func WithRetry(policy RetryPolicy) ConnectOption {
+	return func(o *connectOptions) {
+		o.retry = policy
+	}
+}
+
+// retry calls connect until it succeeds, the attempts of the policy are used
+// up or the context is cancelled, backing off between attempts. Unauthorized
+// and incompatible devices aren't retried.
+func (p RetryPolicy) retry(ctx context.Context, name string, connect func(ctx context.Context) error) error {
+	delay := p.MinDelay
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if errors.Is(err, ErrUnauthorizedDevice) || errors.Is(err, ErrIncompatibleDevice) {
+			return err
+		}
+		if p.Attempts > 0 && attempt >= p.Attempts {
+			return err
+		}
+
+		slog.Debug("Failed to connect to device, retrying", slog.String("device", name),
+			slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, p.MaxDelay)
+	}
+}
@@ -0,0 +1,123 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SignalSelection selects the signals to record from each device.
+type SignalSelection struct {
+	selectors []signalSelector
+}
+
+type signalSelector struct {
+	// The device the selector applies to (or any device if invalid).
+	addr    netip.Addr
+	pattern string
+}
+
+// ParseSignalSelection parses a list of signal selectors. Each selector is a
+// signal ID, a signal name or a glob pattern matching signal names (eg. "EEG*"),
+// optionally prefixed by the address of the device it applies to (eg.
+// "10.0.0.12/Nasal Pressure").
+func ParseSignalSelection(selectors []string) (*SignalSelection, error) {
+	var s SignalSelection
+	for _, selector := range selectors {
+		sel, err := parseSignalSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		s.selectors = append(s.selectors, sel)
+	}
+
+	return &s, nil
+}
+
+func parseSignalSelector(selector string) (signalSelector, error) {
+	var sel signalSelector
+	if addr, pattern, ok := strings.Cut(selector, "/"); ok {
+		if parsedAddr, err := netip.ParseAddr(addr); err == nil {
+			sel.addr = parsedAddr
+			selector = pattern
+		}
+	}
+
+	if selector == "" {
+		return sel, fmt.Errorf("empty signal selector")
+	}
+
+	if _, err := path.Match(selector, ""); err != nil {
+		return sel, fmt.Errorf("invalid signal selector %q: %w", selector, err)
+	}
+
+	sel.pattern = selector
+	return sel, nil
+}
+
+// WithSignals records only the selected signals of each device, rather than
+// every signal it advertises.
+func WithSignals(s *SignalSelection) RecordOption {
+	return func(o *recordOptions) {
+		o.signalSelection = s
+	}
+}
+
+// filter returns the selected signals of the device at addr.
+func (s *SignalSelection) filter(addr netip.Addr, signals []Signal) []Signal {
+	if s == nil {
+		return signals
+	}
+
+	var selected []Signal
+	for _, signal := range signals {
+		if s.matches(addr, signal) {
+			selected = append(selected, signal)
+		}
+	}
+
+	return selected
+}
+
+// matches returns true if the signal of the device at addr is selected.
+func (s *SignalSelection) matches(addr netip.Addr, signal Signal) bool {
+	for _, sel := range s.selectors {
+		if sel.matches(addr, signal) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sel signalSelector) matches(addr netip.Addr, signal Signal) bool {
+	if sel.addr.IsValid() && sel.addr != addr {
+		return false
+	}
+
+	if id, err := strconv.ParseUint(sel.pattern, 10, 32); err == nil && uint32(id) == signal.ID {
+		return true
+	}
+
+	matched, _ := path.Match(strings.ToLower(sel.pattern), strings.ToLower(signal.Name))
+	return matched
+}
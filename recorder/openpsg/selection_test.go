@@ -0,0 +1,89 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordSignals(t *testing.T) {
+	tests := []struct {
+		name       string
+		selectors  []string
+		wantLabels []string
+		wantErr    bool
+	}{
+		{
+			name:       "By name",
+			selectors:  []string{"EEG channel 02"},
+			wantLabels: []string{"EEG channel 02"},
+		},
+		{
+			name:       "By ID",
+			selectors:  []string{"3", "1"},
+			wantLabels: []string{"EEG channel 01", "EEG channel 03"},
+		},
+		{
+			name:       "By pattern",
+			selectors:  []string{"eeg channel 0[24]"},
+			wantLabels: []string{"EEG channel 02", "EEG channel 04"},
+		},
+		{
+			name:       "By device",
+			selectors:  []string{"127.0.1.1/4", "127.0.1.2/*"},
+			wantLabels: []string{"EEG channel 04"},
+		},
+		{
+			name:      "Empty selector",
+			selectors: []string{"127.0.1.1/"},
+			wantErr:   true,
+		},
+		{
+			name:      "Invalid pattern",
+			selectors: []string{"EEG channel [0"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := openpsg.ParseSignalSelection(tt.selectors)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// The device streams every signal, but only those selected are
+			// stored.
+			er := record(t, newMultiSource(4, 0, 0).open, 500*time.Millisecond, openpsg.WithSignals(selection))
+
+			var labels []string
+			for _, signal := range er.Signals() {
+				labels = append(labels, signal.Label)
+			}
+			assert.Equal(t, tt.wantLabels, labels)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// Version is the version of the recorder, it can be set at build time with:
+// -ldflags "-X github.com/OpenPSG/OpenPSG/recorder/openpsg.Version=v1.2.3"
+var Version = ""
+
+// Sidecar is a machine-readable description of a recording, written alongside
+// the EDF file to record its provenance.
+type Sidecar struct {
+	// The version of the recorder software.
+	Software string `json:"software"`
+	// The precise start time of the recording (the EDF header start time is
+	// truncated to the second).
+	StartTime time.Time `json:"start_time"`
+	// The time the recording was stopped (empty while in progress).
+	EndTime *time.Time `json:"end_time,omitempty"`
+	// The duration of each data record in seconds.
+	DataRecordDuration float64 `json:"data_record_duration"`
+	// The number of data records written.
+	DataRecords int `json:"data_records"`
+	// Periods where no data was received.
+	Gaps []SidecarGap `json:"gaps,omitempty"`
+	// Periods where the recording was paused.
+	Pauses []SidecarGap `json:"pauses,omitempty"`
+	// The devices that were recorded from.
+	Devices []SidecarDevice `json:"devices"`
+	// Signals computed from the recorded signals.
+	Derived []SidecarDerived `json:"derived,omitempty"`
+	// The oxygen desaturations detected in each oximetry signal (written at the
+	// end of the recording).
+	Oximetry []SidecarOximetry `json:"oximetry,omitempty"`
+	// The audio recorded alongside the signals, if any.
+	Audio *SidecarAudio `json:"audio,omitempty"`
+	// The video recorded alongside the signals, if any.
+	Video *SidecarVideo `json:"video,omitempty"`
+}
+
+// SidecarVideo describes a video file recorded alongside the EDF file.
+type SidecarVideo struct {
+	File string `json:"file"`
+	// The sync manifest relating the time in the video to wall-clock time.
+	Manifest string `json:"manifest"`
+}
+
+// SidecarAudio describes a WAV file recorded alongside the EDF file.
+type SidecarAudio struct {
+	File       string `json:"file"`
+	Device     string `json:"device,omitempty"`
+	SampleRate uint32 `json:"sample_rate"`
+	// The time of the first sample in the file, to align it with the signals
+	// (empty until audio is received).
+	StartTime *time.Time `json:"start_time,omitempty"`
+}
+
+// SidecarOximetry summarizes the desaturations detected in an oximetry signal.
+type SidecarOximetry struct {
+	Label string `json:"label"`
+	// The time with valid saturation values in seconds.
+	Duration float64 `json:"duration"`
+	// The number of desaturations of at least 3% and 4%.
+	Desaturations3 int `json:"desaturations_3"`
+	Desaturations4 int `json:"desaturations_4"`
+	// The oxygen desaturation indices (desaturations per hour).
+	ODI3 float64 `json:"odi_3"`
+	ODI4 float64 `json:"odi_4"`
+}
+
+// SidecarDerived describes a derived signal in the EDF file.
+type SidecarDerived struct {
+	// The index of the signal in the EDF file.
+	Index int `json:"index"`
+	// The EDF file the signal is stored in, if the recording has been split
+	// across multiple files.
+	File   string   `json:"file,omitempty"`
+	Label  string   `json:"label"`
+	Inputs []string `json:"inputs"`
+	// The weight of each input.
+	Weights   []float64        `json:"weights"`
+	Transform DerivedTransform `json:"transform,omitempty"`
+	Scale     float64          `json:"scale,omitempty"`
+}
+
+// SidecarGap is a period of the recording without data.
+type SidecarGap struct {
+	// The onset of the gap in seconds since the start of the recording.
+	Onset float64 `json:"onset"`
+	// The duration of the gap in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// SidecarDevice describes a device that was recorded from.
+type SidecarDevice struct {
+	Address  string `json:"address"`
+	MAC      string `json:"mac,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	// The friendly name of the device (see DeviceNames).
+	Name     string `json:"name,omitempty"`
+	Firmware string `json:"firmware,omitempty"`
+	// The serial number and hardware model reported by the device.
+	SerialNumber string `json:"serial_number,omitempty"`
+	Model        string `json:"model,omitempty"`
+	// The fingerprint of the public key the device proved its identity with.
+	Identity string `json:"identity,omitempty"`
+	// The signals recorded from the device.
+	Signals []SidecarSignal `json:"signals"`
+}
+
+// SidecarSignal maps a device signal to a signal in the EDF file.
+type SidecarSignal struct {
+	// The index of the signal in the EDF file.
+	Index int `json:"index"`
+	// The EDF file the signal is stored in, if the recording has been split
+	// across multiple files.
+	File string `json:"file,omitempty"`
+	// The identifier of the signal on the device.
+	ID             uint32         `json:"id"`
+	Label          string         `json:"label"`
+	TransducerType TransducerType `json:"transducer_type,omitempty"`
+	Unit           Unit           `json:"unit,omitempty"`
+	Min            float32        `json:"min"`
+	Max            float32        `json:"max"`
+	DigitalMin     int            `json:"digital_min"`
+	DigitalMax     int            `json:"digital_max"`
+	SampleFormat   SampleFormat   `json:"sample_format"`
+	SampleRate     uint32         `json:"sample_rate"`
+	// The rate the signal is stored at, if it is resampled.
+	StorageRate uint32 `json:"storage_rate,omitempty"`
+	// The reference the signal is measured against, if given by the montage.
+	Reference string `json:"reference,omitempty"`
+	// The calibration applied to the values of the signal, if any.
+	Calibration *SidecarCalibration `json:"calibration,omitempty"`
+}
+
+// SidecarCalibration describes the calibration applied to a signal.
+type SidecarCalibration struct {
+	Gain   float64 `json:"gain"`
+	Offset float64 `json:"offset"`
+}
+
+// sidecarCalibration describes the calibration c, or returns nil if c is nil.
+func sidecarCalibration(c *ChannelCalibration) *SidecarCalibration {
+	if c == nil {
+		return nil
+	}
+
+	gain, offset := c.coefficients()
+	return &SidecarCalibration{Gain: gain, Offset: offset}
+}
+
+// addGap records a gap, merging it with the previous gap if contiguous.
+func (sc *Sidecar) addGap(onset, duration time.Duration) {
+	sc.Gaps = appendGap(sc.Gaps, onset, duration)
+}
+
+// addPause records a paused period, merging it with the previous one if
+// contiguous.
+func (sc *Sidecar) addPause(onset, duration time.Duration) {
+	sc.Pauses = appendGap(sc.Pauses, onset, duration)
+}
+
+func appendGap(gaps []SidecarGap, onset, duration time.Duration) []SidecarGap {
+	if n := len(gaps); n > 0 {
+		last := &gaps[n-1]
+		if last.Onset+last.Duration == onset.Seconds() {
+			last.Duration += duration.Seconds()
+			return gaps
+		}
+	}
+
+	return append(gaps, SidecarGap{Onset: onset.Seconds(), Duration: duration.Seconds()})
+}
+
+// writeFile atomically replaces the sidecar file at path.
+func (sc *Sidecar) writeFile(path string) error {
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sidecar: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace sidecar: %w", err)
+	}
+
+	return nil
+}
+
+// softwareVersion returns the name and version of the recorder, falling back
+// to the module version or VCS revision embedded in the binary.
+func softwareVersion() string {
+	version := Version
+	if version == "" {
+		version = "unknown"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			if info.Main.Version != "" && info.Main.Version != "(devel)" {
+				version = info.Main.Version
+			} else {
+				for _, setting := range info.Settings {
+					if setting.Key == "vcs.revision" {
+						version = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return "openpsg-recorder " + version
+}
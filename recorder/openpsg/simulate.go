@@ -0,0 +1,405 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// How often simulated devices send a batch of values of each signal.
+const simulatedBatchInterval = 100 * time.Millisecond
+
+// Waveform is the shape of a simulated signal.
+type Waveform string
+
+const (
+	// WaveformSine is a sine wave (eg. EEG alpha rhythm).
+	WaveformSine Waveform = "sine"
+	// WaveformECG is an ECG, with P, QRS and T waves.
+	WaveformECG Waveform = "ecg"
+	// WaveformRespiration is airflow, with a short inspiration and a longer
+	// expiration.
+	WaveformRespiration Waveform = "respiration"
+)
+
+// SimulatedSignal is a signal of a simulated device.
+type SimulatedSignal struct {
+	Signal
+	Waveform Waveform
+	// The frequency of the waveform in Hertz (eg. the heart rate of an ECG).
+	Frequency float64
+	// The amplitude of the waveform, in the unit of the signal.
+	Amplitude float64
+}
+
+// value returns the physical value of the signal t seconds after it started.
+func (s SimulatedSignal) value(t float64) float64 {
+	phase := math.Mod(s.Frequency*t, 1)
+
+	switch s.Waveform {
+	case WaveformECG:
+		// A sum of gaussians for the P, Q, R, S and T waves.
+		var v float64
+		for _, wave := range [...]struct{ center, amplitude, width float64 }{
+			{0.2, 0.15, 0.025},
+			{0.37, -0.15, 0.01},
+			{0.4, 1, 0.012},
+			{0.43, -0.25, 0.01},
+			{0.65, 0.3, 0.04},
+		} {
+			d := (phase - wave.center) / wave.width
+			v += wave.amplitude * math.Exp(-d*d/2)
+		}
+		return s.Amplitude * v
+	case WaveformRespiration:
+		// Inspiration takes 40% of the breath, and expiration the rest (with
+		// the same volume).
+		if phase < 0.4 {
+			return s.Amplitude * math.Sin(math.Pi*phase/0.4)
+		}
+		return -s.Amplitude * 2 / 3 * math.Sin(math.Pi*(phase-0.4)/0.6)
+	default:
+		return s.Amplitude * math.Sin(2*math.Pi*phase)
+	}
+}
+
+// digitalValue converts a physical value of the signal to the value sent by
+// the device.
+func (s SimulatedSignal) digitalValue(value float64) float64 {
+	if s.sampleFormat() == SampleFormatFloat32 {
+		return value
+	}
+
+	dmin, dmax := s.digitalRange()
+	pmin, pmax := float64(s.Min), float64(s.Max)
+	digital := math.Round(float64(dmin) + (value-pmin)*float64(dmax-dmin)/(pmax-pmin))
+	return math.Max(float64(dmin), math.Min(float64(dmax), digital))
+}
+
+// DefaultSimulatedSignals returns the signals of a simulated device: an EEG
+// with a 10 Hz alpha rhythm, an ECG at 60 bpm, and nasal pressure at 15
+// breaths a minute (as the NCPT).
+func DefaultSimulatedSignals() []SimulatedSignal {
+	return []SimulatedSignal{
+		{
+			Signal: Signal{ID: 1, Name: "EEG C4-M1", Unit: Microvolts, Min: -250, Max: 250,
+				Prefiltering: FilterList{Filters: []Filter{{Kind: HighPass, Unit: Hertz, Frequency: 0.3}, {Kind: LowPass, Unit: Hertz, Frequency: 35}}},
+				SampleRate:   256},
+			Waveform: WaveformSine, Frequency: 10, Amplitude: 50,
+		},
+		{
+			Signal: Signal{ID: 2, Name: "ECG", Unit: Millivolts, Min: -5, Max: 5,
+				Prefiltering: FilterList{Filters: []Filter{{Kind: HighPass, Unit: Hertz, Frequency: 0.3}, {Kind: LowPass, Unit: Hertz, Frequency: 70}}},
+				SampleRate:   256},
+			Waveform: WaveformECG, Frequency: 1, Amplitude: 1,
+		},
+		{
+			Signal: Signal{ID: 3, Name: "Nasal Pressure", TransducerType: MEMSPressureTransducer, Unit: Pascal, Min: -200, Max: 200,
+				Prefiltering: FilterList{Filters: []Filter{{Kind: HighPass, Unit: Hertz, Frequency: 0.1}, {Kind: Notch, Unit: Hertz, Frequency: 4}}},
+				SampleRate:   40},
+			Waveform: WaveformRespiration, Frequency: 0.25, Amplitude: 50,
+		},
+	}
+}
+
+// SignalGenerator generates the values of the signals of a simulated device
+// (eg. Waveforms, or a Replay of a recording).
+type SignalGenerator interface {
+	// Signals returns the signals of the device.
+	Signals() []Signal
+	// Values returns the values of a signal (by index) from sample from up to
+	// sample to, in the sample format of the signal. Fewer values are returned
+	// once the signal has ended.
+	Values(signal int, from, to uint64) ([]float64, error)
+}
+
+// Waveforms generates signals with the shape of waveforms.
+type Waveforms []SimulatedSignal
+
+func (w Waveforms) Signals() []Signal {
+	signals := make([]Signal, len(w))
+	for i, signal := range w {
+		signals[i] = signal.Signal
+	}
+	return signals
+}
+
+func (w Waveforms) Values(signal int, from, to uint64) ([]float64, error) {
+	s := w[signal]
+
+	values := make([]float64, 0, to-from)
+	for i := from; i < to; i++ {
+		t := float64(i) / float64(s.SampleRate)
+		values = append(values, s.digitalValue(s.value(t)))
+	}
+	return values, nil
+}
+
+// SimulatedDevice serves the device protocol with generated signals, for
+// testing and demonstrating the recorder without hardware.
+type SimulatedDevice struct {
+	Info      DeviceInfo
+	Generator SignalGenerator
+	// How much faster than real time values are sent (eg. 10 for ten times
+	// faster, real time if zero). Values are still timestamped at the sample
+	// rate of their signal.
+	Speed float64
+
+	startedAt   time.Time
+	connections atomic.Int64
+	valuesSent  atomic.Uint64
+	lag         atomic.Int64
+}
+
+// SimulatedDeviceStats is the throughput of a simulated device (eg. when load
+// testing the recorder).
+type SimulatedDeviceStats struct {
+	// The number of connections from recorders.
+	Connections int
+	// The number of values sent.
+	ValuesSent uint64
+	// How long sending the last batches of values took, which grows when the
+	// recorder doesn't keep up.
+	Lag time.Duration
+}
+
+// Stats returns the throughput of the device.
+func (d *SimulatedDevice) Stats() SimulatedDeviceStats {
+	return SimulatedDeviceStats{
+		Connections: int(d.connections.Load()),
+		ValuesSent:  d.valuesSent.Load(),
+		Lag:         time.Duration(d.lag.Load()),
+	}
+}
+
+// NewSimulatedDevice returns a simulated device with the default signals (see
+// DefaultSimulatedSignals).
+func NewSimulatedDevice(serialNumber string) *SimulatedDevice {
+	return &SimulatedDevice{
+		Info: DeviceInfo{
+			SerialNumber:    serialNumber,
+			Model:           "OpenPSG-Simulator",
+			FirmwareVersion: "0.1.0",
+		},
+		Generator: Waveforms(DefaultSimulatedSignals()),
+	}
+}
+
+// Serve accepts connections from the recorder on the listener until the
+// context is cancelled.
+func (d *SimulatedDevice) Serve(ctx context.Context, l net.Listener) error {
+	d.startedAt = time.Now()
+
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		slog.Debug("Recorder connected to simulated device",
+			slog.String("device", d.Info.SerialNumber), slog.Any("remoteAddr", conn.RemoteAddr()))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			d.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// simulatedConn is a connection from the recorder to a simulated device.
+type simulatedConn struct {
+	device *SimulatedDevice
+
+	mu      sync.Mutex
+	started map[uint32]bool
+}
+
+// serveConn serves a connection until it's closed, or the context cancelled.
+func (d *SimulatedDevice) serveConn(ctx context.Context, conn net.Conn) {
+	c := &simulatedConn{device: d, started: make(map[uint32]bool)}
+
+	d.connections.Add(1)
+	defer d.connections.Add(-1)
+
+	rpcConn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}),
+		jsonrpc2.HandlerWithError(c.handle).SuppressErrClosed())
+	defer rpcConn.Close()
+
+	c.stream(ctx, rpcConn)
+}
+
+func (c *simulatedConn) handle(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request) (any, error) {
+	received := time.Now()
+
+	switch r.Method {
+	case "openpsg.version":
+		return DeviceVersion{
+			Version:      ProtocolVersion,
+			Capabilities: []Capability{CapabilityTime, CapabilityStatus, CapabilityLink},
+		}, nil
+	case "openpsg.info":
+		return c.device.Info, nil
+	case "openpsg.signals":
+		return c.device.Generator.Signals(), nil
+	case "openpsg.link":
+		return LinkInfo{Type: "ethernet", Speed: 100}, nil
+	case "openpsg.ping":
+		return nil, nil
+	case "openpsg.time":
+		return deviceTime{Received: received, Transmitted: time.Now()}, nil
+	case "openpsg.start", "openpsg.stop":
+		var signalIDs []uint32
+		if r.Params == nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "missing signal IDs"}
+		}
+		if err := json.Unmarshal(*r.Params, &signalIDs); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+
+		c.mu.Lock()
+		for _, id := range signalIDs {
+			c.started[id] = r.Method == "openpsg.start"
+		}
+		c.mu.Unlock()
+		return nil, nil
+	default:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", r.Method)}
+	}
+}
+
+// stream sends the values of the started signals in batches, and the status of
+// the device once a second, until the connection is closed.
+func (c *simulatedConn) stream(ctx context.Context, rpcConn *jsonrpc2.Conn) {
+	ticker := time.NewTicker(simulatedBatchInterval)
+	defer ticker.Stop()
+
+	// The values of each signal are generated from when it was started, in
+	// batches of the values due since the last.
+	type signalState struct {
+		startedAt time.Time
+		sent      uint64
+	}
+	states := make(map[uint32]*signalState)
+	// Sequence numbers carry on when a signal is restarted, as the recorder
+	// would otherwise drop its values as duplicates. Like the firmware, they are
+	// 32-bit and wrap around.
+	seqs := make(map[uint32]uint32)
+
+	speed := c.device.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	signals := c.device.Generator.Signals()
+	lastStatus := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rpcConn.DisconnectNotify():
+			return
+		case now := <-ticker.C:
+			for i, signal := range signals {
+				c.mu.Lock()
+				started := c.started[signal.ID]
+				c.mu.Unlock()
+
+				if !started {
+					delete(states, signal.ID)
+					continue
+				}
+
+				state, ok := states[signal.ID]
+				if !ok {
+					state = &signalState{startedAt: now}
+					states[signal.ID] = state
+				}
+
+				due := uint64(now.Sub(state.startedAt).Seconds() * speed * float64(signal.SampleRate))
+				if due <= state.sent {
+					continue
+				}
+
+				values, err := c.device.Generator.Values(i, state.sent, due)
+				if err != nil {
+					slog.Error("Failed to generate values", slog.String("device", c.device.Info.SerialNumber), slog.Any("error", err))
+					return
+				}
+				if len(values) == 0 {
+					continue
+				}
+
+				seq := uint64(seqs[signal.ID])
+				err = rpcConn.Notify(ctx, "openpsg.values", SignalValues{
+					ID:        signal.ID,
+					Seq:       &seq,
+					Timestamp: state.startedAt.Add(time.Duration(float64(state.sent) / float64(signal.SampleRate) * float64(time.Second))),
+					Values:    values,
+				})
+				if err != nil {
+					if !errors.Is(err, jsonrpc2.ErrClosed) {
+						slog.Warn("Failed to send values", slog.String("device", c.device.Info.SerialNumber), slog.Any("error", err))
+					}
+					return
+				}
+
+				state.sent += uint64(len(values))
+				seqs[signal.ID]++
+				c.device.valuesSent.Add(uint64(len(values)))
+			}
+			c.device.lag.Store(int64(time.Since(now)))
+
+			if now.Sub(lastStatus) >= time.Second {
+				lastStatus = now
+
+				err := rpcConn.Notify(ctx, "openpsg.status", DeviceStatus{
+					Timestamp: now,
+					Uptime:    now.Sub(c.device.startedAt).Seconds(),
+				})
+				if err != nil && !errors.Is(err, jsonrpc2.ErrClosed) {
+					slog.Warn("Failed to send status", slog.String("device", c.device.Info.SerialNumber), slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
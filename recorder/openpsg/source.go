@@ -0,0 +1,386 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// The most devices connected to at once when recording starts.
+const maxConcurrentOpens = 32
+
+// Samples from sources without timestamps of their own are timestamped from
+// when they arrive, if that differs from their count by more than this.
+const maxSampleClockError = 500 * time.Millisecond
+
+// SignalSource is a source of signals, such as an OpenPSG device (see Client)
+// or a device attached to the recorder (eg. a CPAP machine). Sources are opened
+// by drivers (see RegisterDriver), and mixed freely in a recording.
+type SignalSource interface {
+	// Signals returns the signals available from the source.
+	Signals(ctx context.Context) ([]Signal, error)
+	// Start streaming the values of the specified signals.
+	Start(ctx context.Context, signalIDs []uint32) error
+	// Stop streaming the values of the specified signals.
+	Stop(ctx context.Context, signalIDs []uint32) error
+	// SignalValues returns the channel the values of signals are sent on.
+	SignalValues() <-chan SignalValues
+	// LeadOff returns the channel changes in the lead-off status of signals are
+	// sent on.
+	LeadOff() <-chan LeadOffStatus
+	// Disconnected is closed when the connection to the source is lost.
+	Disconnected() <-chan struct{}
+	Close() error
+}
+
+// deviceInfoSource is a source that can identify itself (see Client.Info).
+type deviceInfoSource interface {
+	Info(ctx context.Context) (DeviceInfo, error)
+}
+
+// outageSource is a source that reconnects by itself, reporting the outages
+// of its connection (see Client.Outages).
+type outageSource interface {
+	Outages() <-chan Outage
+	CurrentOutage() (Outage, bool)
+}
+
+// sequenceSource is a source detecting lost and duplicated values (see
+// Client.SequenceErrors).
+type sequenceSource interface {
+	SequenceErrors() map[uint32]SequenceErrors
+}
+
+// lostBatches returns the batches of signal values the source lost in transit,
+// if it detects them.
+func lostBatches(source SignalSource) uint64 {
+	s, ok := source.(sequenceSource)
+	if !ok {
+		return 0
+	}
+
+	var lost uint64
+	for _, errs := range s.SequenceErrors() {
+		lost += errs.Lost
+	}
+	return lost
+}
+
+// clockSource is a source measuring the offset of its clock (see
+// Client.ClockOffset).
+type clockSource interface {
+	ClockOffset() (ClockOffset, bool)
+}
+
+// identitySource is a source proving its identity (see Client.Identity).
+type identitySource interface {
+	Identity() string
+}
+
+// SourceOpener opens a connection to a signal source.
+type SourceOpener func(ctx context.Context) (SignalSource, error)
+
+// WithSource records the signals of a source that isn't an OpenPSG device on
+// the network, alongside those of the devices. The source is identified by
+// addr (eg. in signal selections and montages), which should be a loopback
+// address (see LocalSourceAddr) to not clash with the addresses of devices.
+func WithSource(addr netip.Addr, open SourceOpener) RecordOption {
+	return func(o *recordOptions) {
+		if o.sources == nil {
+			o.sources = make(map[netip.Addr]SourceOpener)
+		}
+		if _, ok := o.sources[addr]; !ok {
+			o.localSources = append(o.localSources, addr)
+		}
+		o.sources[addr] = open
+	}
+}
+
+// LocalSourceAddr returns the address identifying the nth (from one) source
+// attached to the recorder, 127.0.1.n.
+func LocalSourceAddr(n int) netip.Addr {
+	return netip.AddrFrom4([4]byte{127, 0, 1, byte(n)})
+}
+
+// opener returns the opener of the source at addr, by default connecting to
+// an OpenPSG device.
+func (o *recordOptions) opener(addr netip.Addr) SourceOpener {
+	open, ok := o.sources[addr]
+	if !ok {
+		open = openDevice(netip.AddrPortFrom(addr, 80), o.connectOptions...)
+	}
+
+	if o.udpStreaming {
+		return streamUDP(open)
+	}
+	return open
+}
+
+// openDevice returns an opener connecting to the OpenPSG device at addrPort.
+func openDevice(addrPort netip.AddrPort, opts ...ConnectOption) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		client, err := Connect(ctx, addrPort, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
+// openedDevice is a device connected to before recording starts.
+type openedDevice struct {
+	client SignalSource
+	// Why the device couldn't be connected to.
+	err     error
+	signals []Signal
+	// Why the signals of the device couldn't be retrieved.
+	signalsErr error
+	info       DeviceInfo
+	infoErr    error
+}
+
+// openDevices connects to the devices concurrently (at most
+// maxConcurrentOpens at a time), retrieving their signals and info, so a
+// recording of many devices (or of devices that are slow to respond) starts
+// promptly rather than waiting on each device in turn.
+func openDevices(ctx context.Context, openers []SourceOpener) []openedDevice {
+	opened := make([]openedDevice, len(openers))
+
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentOpens)
+	for i, open := range openers {
+		g.Go(func() error {
+			o := &opened[i]
+
+			o.client, o.err = open(ctx)
+			if o.err != nil {
+				return nil
+			}
+
+			o.signals, o.signalsErr = o.client.Signals(ctx)
+			if o.signalsErr != nil {
+				return nil
+			}
+
+			if s, ok := o.client.(deviceInfoSource); ok {
+				o.info, o.infoErr = s.Info(ctx)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return opened
+}
+
+// Driver opens signal sources of a kind, given an argument (eg. the path of a
+// serial port). Sources with an address of their own (eg. OpenPSG devices)
+// return it, otherwise the address is invalid and a loopback address is
+// assigned (see LocalSourceAddr). Drivers connecting to OpenPSG devices
+// connect with the connect options (eg. WithTLS).
+type Driver func(arg string, opts ...ConnectOption) (netip.Addr, SourceOpener, error)
+
+var drivers = make(map[string]Driver)
+
+// RegisterDriver makes a driver available by name (eg. for ParseSources). It
+// should be called from an init function.
+func RegisterDriver(name string, driver Driver) {
+	if _, ok := drivers[name]; ok {
+		panic("openpsg: driver registered twice: " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the registered drivers.
+func Drivers() []string {
+	names := slices.Collect(maps.Keys(drivers))
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	// OpenPSG devices at a fixed address (eg. on another network, or a fixed
+	// installation), as ADDRESS[:PORT] or HOSTNAME[:PORT].
+	RegisterDriver("openpsg", func(arg string, opts ...ConnectOption) (netip.Addr, SourceOpener, error) {
+		addrPort, err := resolveDevice(arg)
+		if err != nil {
+			return netip.Addr{}, nil, err
+		}
+
+		return addrPort.Addr(), openDevice(addrPort, opts...), nil
+	})
+}
+
+// resolveDevice parses the address of a device, as ADDRESS[:PORT] or
+// HOSTNAME[:PORT] (port 80 by default), looking up its address by hostname
+// (eg. "sensor-3.local", with mDNS if the system resolves .local names).
+func resolveDevice(arg string) (netip.AddrPort, error) {
+	if addrPort, err := netip.ParseAddrPort(arg); err == nil {
+		return addrPort, nil
+	}
+	if addr, err := netip.ParseAddr(arg); err == nil {
+		return netip.AddrPortFrom(addr, 80), nil
+	}
+
+	host, port := arg, uint64(80)
+	if h, p, err := net.SplitHostPort(arg); err == nil {
+		host = h
+		port, err = strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return netip.AddrPort{}, fmt.Errorf("invalid device port %q", p)
+		}
+	}
+	if host == "" {
+		return netip.AddrPort{}, fmt.Errorf("invalid device address %q", arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("failed to resolve device %q: %w", host, err)
+	}
+
+	// Prefer IPv4, as devices are identified by address.
+	addr := addrs[0].Unmap()
+	for _, a := range addrs {
+		if a.Unmap().Is4() {
+			addr = a.Unmap()
+			break
+		}
+	}
+
+	slog.Info("Resolved device", slog.String("hostname", host), slog.Any("address", addr))
+
+	return netip.AddrPortFrom(addr, uint16(port)), nil
+}
+
+// The default baud rate of OpenPSG devices attached over serial (USB CDC
+// devices ignore it).
+const defaultSerialBaud = 115200
+
+// openSerialDevice returns an opener connecting to the OpenPSG device attached
+// to the serial port at path.
+func openSerialDevice(path string, baud int) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		client, err := ConnectSerial(ctx, path, baud)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
+func init() {
+	// OpenPSG devices attached to the recorder over USB (or serial), as
+	// PORT[@BAUD].
+	RegisterDriver("serial", func(arg string, _ ...ConnectOption) (netip.Addr, SourceOpener, error) {
+		path, baudStr, ok := strings.Cut(arg, "@")
+		baud := defaultSerialBaud
+		if ok {
+			var err error
+			baud, err = strconv.Atoi(baudStr)
+			if err != nil {
+				return netip.Addr{}, nil, fmt.Errorf("invalid baud rate %q", baudStr)
+			}
+		}
+
+		return netip.Addr{}, openSerialDevice(path, baud), nil
+	})
+}
+
+// Source is a signal source opened by a driver.
+type Source struct {
+	// The source as given to ParseSources (eg. "nonin:/dev/ttyUSB0").
+	Spec string
+	// The address identifying the source.
+	Addr netip.Addr
+	Open SourceOpener
+}
+
+// ParseSources opens sources given as DRIVER:ARG (eg. "cpap:/mnt/sdcard" or
+// "nonin:/dev/ttyUSB0"), assigning loopback addresses to the sources without
+// addresses of their own in order, from 127.0.1.1. OpenPSG devices are
+// connected to with the connect options.
+func ParseSources(specs []string, opts ...ConnectOption) ([]Source, error) {
+	var sources []Source
+	var local int
+	for _, spec := range specs {
+		name, arg, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid source %q (expected DRIVER:ARG)", spec)
+		}
+
+		driver, ok := drivers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown driver %q for source %q (available: %s)",
+				name, spec, strings.Join(Drivers(), ", "))
+		}
+
+		addr, open, err := driver(arg, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %q: %w", spec, err)
+		}
+
+		if !addr.IsValid() {
+			local++
+			addr = LocalSourceAddr(local)
+		}
+
+		sources = append(sources, Source{Spec: spec, Addr: addr, Open: open})
+	}
+
+	return sources, nil
+}
+
+// sampleClock timestamps the samples of a source without timestamps of its own
+// (eg. a serial device), by counting them from the first sample.
+type sampleClock struct {
+	rate  float64
+	start time.Time
+	count int64
+}
+
+// timestamp returns the timestamp of the first of n samples that have just
+// arrived.
+func (c *sampleClock) timestamp(n int) time.Time {
+	arrived := time.Now().Add(-time.Duration(float64(n-1) / c.rate * float64(time.Second)))
+	expected := c.start.Add(time.Duration(float64(c.count) / c.rate * float64(time.Second)))
+
+	// Samples were lost, or the clock of the source differs from ours.
+	if c.start.IsZero() || expected.Sub(arrived).Abs() > maxSampleClockError {
+		c.start, c.count = arrived, 0
+		expected = arrived
+	}
+
+	c.count += int64(n)
+	return expected
+}
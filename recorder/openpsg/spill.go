@@ -0,0 +1,142 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	// The maximum size of the values queued in the overflow file of each
+	// signal (four million values, over an hour of a 1 kHz signal).
+	maxSpillBytes = 64 << 20
+	// The size of each spilled value (sample index and value).
+	spillEntrySize = 16
+	// The maximum number of values queued in each overflow file.
+	maxSpillValues = maxSpillBytes / spillEntrySize
+	// The minimum size of the consumed prefix of an overflow file before it is
+	// reclaimed.
+	minCompactBytes = 1 << 20
+)
+
+// WithSpill writes signal values that arrive too far ahead of the data record
+// being written (eg. while writing is stalled) to overflow files in dir, rather
+// than dropping them. They are read back once there is room in the buffer.
+func WithSpill(dir string) RecordOption {
+	return func(o *recordOptions) {
+		o.spillDir = dir
+	}
+}
+
+// spillFile is an on-disk FIFO queue of signal values.
+type spillFile struct {
+	f *os.File
+	// The offsets of the next entry to read and write.
+	readOffset  int64
+	writeOffset int64
+	buf         [spillEntrySize]byte
+}
+
+func newSpillFile(dir string) (*spillFile, error) {
+	f, err := os.CreateTemp(dir, "openpsg-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	return &spillFile{f: f}, nil
+}
+
+// len returns the number of queued values.
+func (s *spillFile) len() int {
+	return int((s.writeOffset - s.readOffset) / spillEntrySize)
+}
+
+// push appends a value to the queue. It returns false if the queue is full.
+func (s *spillFile) push(index int64, value float64) (bool, error) {
+	if s.len() >= maxSpillValues {
+		return false, nil
+	}
+
+	binary.LittleEndian.PutUint64(s.buf[:8], uint64(index))
+	binary.LittleEndian.PutUint64(s.buf[8:], math.Float64bits(value))
+	if _, err := s.f.WriteAt(s.buf[:], s.writeOffset); err != nil {
+		return false, fmt.Errorf("failed to write spill file: %w", err)
+	}
+	s.writeOffset += spillEntrySize
+
+	return true, nil
+}
+
+// peek returns the next value in the queue, without removing it.
+func (s *spillFile) peek() (int64, float64, error) {
+	if _, err := s.f.ReadAt(s.buf[:], s.readOffset); err != nil {
+		return 0, 0, fmt.Errorf("failed to read spill file: %w", err)
+	}
+
+	return int64(binary.LittleEndian.Uint64(s.buf[:8])), math.Float64frombits(binary.LittleEndian.Uint64(s.buf[8:])), nil
+}
+
+// pop removes the next value from the queue.
+func (s *spillFile) pop() error {
+	s.readOffset += spillEntrySize
+
+	// Reclaim the space once the queue has been drained.
+	if s.readOffset == s.writeOffset {
+		s.readOffset, s.writeOffset = 0, 0
+		if err := s.f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate spill file: %w", err)
+		}
+		return nil
+	}
+
+	// Reclaim the consumed prefix once it outgrows the queued values, so a
+	// queue that never fully drains doesn't grow the file without bound.
+	if s.readOffset >= minCompactBytes && s.readOffset >= s.writeOffset-s.readOffset {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// compact moves the queued values to the start of the file and truncates it.
+func (s *spillFile) compact() error {
+	// The consumed prefix is at least as large as the queued values, so the
+	// source and destination ranges don't overlap.
+	n := s.writeOffset - s.readOffset
+	src := io.NewSectionReader(s.f, s.readOffset, n)
+	if _, err := io.Copy(io.NewOffsetWriter(s.f, 0), src); err != nil {
+		return fmt.Errorf("failed to compact spill file: %w", err)
+	}
+	if err := s.f.Truncate(n); err != nil {
+		return fmt.Errorf("failed to truncate spill file: %w", err)
+	}
+	s.readOffset, s.writeOffset = 0, n
+
+	return nil
+}
+
+// Close closes and removes the spill file.
+func (s *spillFile) Close() error {
+	_ = s.f.Close()
+	return os.Remove(s.f.Name())
+}
@@ -0,0 +1,93 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillFile(t *testing.T) {
+	dir := t.TempDir()
+
+	spill, err := openpsg.NewSpillFile(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, spill.Close())
+	})
+
+	// Twice the consumed prefix that is reclaimed.
+	n := 2 * openpsg.MinCompactBytes / openpsg.SpillEntrySize
+	for i := range n {
+		ok, err := spill.Push(int64(i), float64(i)/2)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	assert.Equal(t, n, spill.Len())
+
+	// Pop until just before the consumed prefix is reclaimed.
+	for range n/2 - 1 {
+		require.NoError(t, spill.Pop())
+	}
+
+	size, err := spill.Size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(n*openpsg.SpillEntrySize), size)
+
+	// Once the consumed prefix outgrows the queued values it is reclaimed.
+	require.NoError(t, spill.Pop())
+
+	size, err = spill.Size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(n/2*openpsg.SpillEntrySize), size)
+	assert.Equal(t, n/2, spill.Len())
+
+	index, value, err := spill.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, int64(n/2), index)
+	assert.Equal(t, float64(n/2)/2, value)
+
+	// Values pushed after compacting follow on from those queued.
+	ok, err := spill.Push(int64(n), 42)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	for i := n / 2; i < n; i++ {
+		index, _, err := spill.Peek()
+		require.NoError(t, err)
+		require.Equal(t, int64(i), index)
+		require.NoError(t, spill.Pop())
+	}
+
+	index, value, err = spill.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, int64(n), index)
+	assert.Equal(t, 42.0, value)
+
+	// The file is truncated once drained.
+	require.NoError(t, spill.Pop())
+	assert.Zero(t, spill.Len())
+
+	size, err = spill.Size()
+	require.NoError(t, err)
+	assert.Zero(t, size)
+}
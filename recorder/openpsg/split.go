@@ -0,0 +1,109 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Many EDF readers struggle with files containing hundreds of signals (the
+// header alone grows by 256 bytes per signal), so larger recordings are split
+// across multiple files. This includes the EDF+ annotations signal.
+const defaultMaxSignalsPerFile = 256
+
+// SplitFiles creates the additional EDF files used when a recording has too
+// many signals for a single file.
+type SplitFiles interface {
+	// Create creates the file for the nth part of the recording. It is only
+	// called for n >= 1, the first part is written to the file passed to Record.
+	Create(n int) (io.WriteSeeker, error)
+	// Name returns the name of the file for the nth part of the recording (as
+	// referenced in the manifest).
+	Name(n int) string
+}
+
+// WithSplitting splits the signals across multiple synchronized EDF files if
+// there are more than maxSignalsPerFile (zero for the default). Each file
+// shares the same start time, data records and annotations. When a recording is
+// split, a JSON manifest listing the files and their signals is written to
+// manifestPath.
+func WithSplitting(maxSignalsPerFile int, files SplitFiles, manifestPath string) RecordOption {
+	return func(o *recordOptions) {
+		o.maxSignalsPerFile = maxSignalsPerFile
+		o.splitFiles = files
+		o.manifestPath = manifestPath
+	}
+}
+
+// Manifest links the files of a recording that has been split across multiple
+// EDF files.
+type Manifest struct {
+	// The precise start time of the recording.
+	StartTime time.Time `json:"start_time"`
+	// The files making up the recording.
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile is one of the files of a split recording.
+type ManifestFile struct {
+	Name string `json:"name"`
+	// The labels of the signals stored in the file (excluding annotations).
+	Signals []string `json:"signals"`
+}
+
+// splitSignals partitions the signal indices into parts with at most
+// maxSignalsPerFile signals (including the annotations signal) each.
+func splitSignals(n, maxSignalsPerFile int) ([][]int, error) {
+	if maxSignalsPerFile < 2 {
+		return nil, fmt.Errorf("invalid maximum number of signals per file: %d", maxSignalsPerFile)
+	}
+
+	parts := [][]int{{}}
+	for i := 0; i < n; i++ {
+		if len(parts[len(parts)-1]) == maxSignalsPerFile-1 {
+			parts = append(parts, nil)
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], i)
+	}
+
+	return parts, nil
+}
+
+// writeFile atomically replaces the manifest file at path.
+func (m *Manifest) writeFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"log/slog"
+	"time"
+)
+
+// The IDs of the status channels of a device, reserved at the top of the range
+// of signal IDs.
+const (
+	statusBattery     uint32 = 0xffffff01
+	statusTemperature uint32 = 0xffffff02
+)
+
+// A warning is logged when the battery charge of a device drops below this.
+const lowBatteryThreshold = 20
+
+// isStatusSignal reports whether a signal is a status channel, recorded from
+// the status notifications of a device rather than streamed by it.
+func isStatusSignal(id uint32) bool {
+	return id == statusBattery || id == statusTemperature
+}
+
+// statusSignals returns the status channels of a device with the specified
+// capabilities.
+func statusSignals(version DeviceVersion) []Signal {
+	var signals []Signal
+	for _, s := range []struct {
+		capability Capability
+		signal     Signal
+	}{
+		{CapabilityBattery, Signal{ID: statusBattery, Name: "Battery", Unit: Percent, Min: 0, Max: 100,
+			SampleFormat: SampleFormatFloat32, SampleRate: 1}},
+		{CapabilityTemperature, Signal{ID: statusTemperature, Name: "Device temp", Unit: DegreesCelsius, Min: -40, Max: 125,
+			SampleFormat: SampleFormatFloat32, SampleRate: 1}},
+	} {
+		if version.has(CapabilityStatus) && version.has(s.capability) {
+			signals = append(signals, s.signal)
+		}
+	}
+	return signals
+}
+
+// Status returns the latest status reported by the device, if any.
+func (c *Client) Status() (DeviceStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status, !c.status.Timestamp.IsZero()
+}
+
+// handleStatus logs notable changes in the status of the device, and sends
+// the values of the enabled status channels.
+func (c *Client) handleStatus(status DeviceStatus) {
+	c.mu.Lock()
+	prev := c.status
+	c.status = status
+	battery := c.enabled[statusBattery]
+	temperature := c.enabled[statusTemperature]
+	c.mu.Unlock()
+
+	slog.Debug("Device status",
+		slog.Time("timestamp", status.Timestamp),
+		slog.Any("battery", status.Battery),
+		slog.Any("temperature", status.Temperature),
+		slog.Float64("uptime", status.Uptime))
+
+	if !prev.Timestamp.IsZero() && status.Uptime < prev.Uptime {
+		slog.Warn("Device restarted", slog.Duration("uptime", time.Duration(status.Uptime*float64(time.Second))))
+	}
+
+	if status.Battery != nil && *status.Battery < lowBatteryThreshold &&
+		(prev.Battery == nil || *prev.Battery >= lowBatteryThreshold) {
+		slog.Warn("Device battery low", slog.Float64("battery", *status.Battery))
+	}
+
+	if battery && status.Battery != nil {
+		c.publish(SignalValues{ID: statusBattery, Timestamp: status.Timestamp, Values: []float64{*status.Battery}})
+	}
+	if temperature && status.Temperature != nil {
+		c.publish(SignalValues{ID: statusTemperature, Timestamp: status.Timestamp, Values: []float64{*status.Temperature}})
+	}
+}
@@ -0,0 +1,189 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// The number of batches of values buffered for a subscription by default.
+const subscriptionBufferSize = 64
+
+// SubscribeOption configures a subscription to the values of a device.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	bufferSize   int
+	dropWhenFull bool
+}
+
+// WithSubscriptionBuffer buffers up to size batches of values for the
+// subscription (64 by default).
+func WithSubscriptionBuffer(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.bufferSize = size
+	}
+}
+
+// WithDropWhenFull drops values once the buffer of the subscription is full,
+// rather than waiting for them to be received, so a slow subscriber (eg. a
+// live viewer) can't hold up the others (eg. the recording).
+func WithDropWhenFull() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.dropWhenFull = true
+	}
+}
+
+// Subscription receives the values of the signals of a device, until either
+// it or the client is closed.
+type Subscription struct {
+	client       *Client
+	values       chan SignalValues
+	dropWhenFull bool
+	dropped      atomic.Uint64
+
+	// Closed when the subscription is closed, interrupting sends.
+	done      chan struct{}
+	closeOnce sync.Once
+	// Held for reading while sending values, so the channel isn't closed
+	// meanwhile.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Subscribe subscribes to the values of the signals of the device. Each
+// subscription receives every batch of values received after it subscribed.
+// By default a full subscription holds up the delivery of values (to every
+// subscription) until values are received from it. Subscribing to a closed
+// client returns a closed subscription.
+func (c *Client) Subscribe(opts ...SubscribeOption) *Subscription {
+	options := subscribeOptions{bufferSize: subscriptionBufferSize}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	s := &Subscription{
+		client:       c,
+		values:       make(chan SignalValues, options.bufferSize),
+		dropWhenFull: options.dropWhenFull,
+		done:         make(chan struct{}),
+	}
+
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+
+	if c.subscriptionsClosed {
+		s.close()
+		return s
+	}
+	c.subscriptions = append(c.subscriptions, s)
+
+	return s
+}
+
+// Values returns a channel that will receive the values of the signals,
+// closed once the subscription is closed.
+func (s *Subscription) Values() <-chan SignalValues {
+	return s.values
+}
+
+// Dropped returns the number of batches of values dropped as the subscription
+// was full (see WithDropWhenFull).
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes from the values of the device. It is safe to call while
+// values are being sent, and more than once.
+func (s *Subscription) Close() {
+	s.client.subscriptionsMu.Lock()
+	s.client.subscriptions = slices.DeleteFunc(s.client.subscriptions, func(other *Subscription) bool {
+		return other == s
+	})
+	s.client.subscriptionsMu.Unlock()
+
+	s.close()
+}
+
+// close closes the channel of the subscription, once any values being sent
+// have been sent (or interrupted).
+func (s *Subscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.closed = true
+		close(s.values)
+	})
+}
+
+// send sends values to the subscription, until it or the client is closed.
+func (s *Subscription) send(values SignalValues, cancel <-chan struct{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.dropWhenFull {
+		select {
+		case s.values <- values:
+		default:
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case s.values <- values:
+	case <-s.done:
+	case <-cancel:
+	}
+}
+
+// publish sends values to every subscription, returning false if the client
+// has been closed.
+func (c *Client) publish(values SignalValues) bool {
+	c.subscriptionsMu.Lock()
+	subscriptions := slices.Clone(c.subscriptions)
+	c.subscriptionsMu.Unlock()
+
+	for _, s := range subscriptions {
+		s.send(values, c.ctx.Done())
+	}
+	return c.ctx.Err() == nil
+}
+
+// closeSubscriptions closes every subscription, and any made from then on.
+func (c *Client) closeSubscriptions() {
+	c.subscriptionsMu.Lock()
+	subscriptions := c.subscriptions
+	c.subscriptions = nil
+	c.subscriptionsClosed = true
+	c.subscriptionsMu.Unlock()
+
+	for _, s := range subscriptions {
+		s.close()
+	}
+}
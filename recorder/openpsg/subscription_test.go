@@ -0,0 +1,106 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscription(t *testing.T) {
+	c := openpsg.NewTestClient()
+	t.Cleanup(c.CloseSubscriptions)
+
+	all := c.Subscribe()
+	latest := c.Subscribe(openpsg.WithSubscriptionBuffer(1), openpsg.WithDropWhenFull())
+
+	for i := range 3 {
+		assert.True(t, c.Publish(openpsg.SignalValues{ID: uint32(i)}))
+	}
+
+	for i := range 3 {
+		assert.Equal(t, uint32(i), (<-all.Values()).ID)
+	}
+
+	// A full subscription dropping values doesn't hold up the others.
+	assert.Equal(t, uint32(0), (<-latest.Values()).ID)
+	assert.Equal(t, uint64(2), latest.Dropped())
+
+	// Closed subscriptions no longer receive values.
+	latest.Close()
+	assert.True(t, c.Publish(openpsg.SignalValues{ID: 3}))
+	_, ok := <-latest.Values()
+	assert.False(t, ok)
+
+	assert.Equal(t, uint32(3), (<-all.Values()).ID)
+
+	c.CloseSubscriptions()
+	_, ok = <-all.Values()
+	assert.False(t, ok)
+
+	// Subscribing to a closed client returns a closed subscription.
+	_, ok = <-c.Subscribe().Values()
+	assert.False(t, ok)
+	assert.False(t, c.Publish(openpsg.SignalValues{ID: 4}))
+}
+
+func TestSubscriptionCloseWhilePublishing(t *testing.T) {
+	for range 100 {
+		c := openpsg.NewTestClient()
+
+		// Full subscriptions hold up publishing until they are closed.
+		subscriptions := []*openpsg.Subscription{
+			c.Subscribe(openpsg.WithSubscriptionBuffer(1)),
+			c.Subscribe(openpsg.WithSubscriptionBuffer(1)),
+			c.Subscribe(openpsg.WithSubscriptionBuffer(1), openpsg.WithDropWhenFull()),
+		}
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for c.Publish(openpsg.SignalValues{ID: 1}) {
+				}
+			}()
+		}
+
+		for _, s := range subscriptions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				s.Close()
+			}()
+		}
+
+		// Closing the client interrupts publishing.
+		c.CloseSubscriptions()
+		wg.Wait()
+
+		for _, s := range subscriptions {
+			for range s.Values() {
+			}
+		}
+	}
+}
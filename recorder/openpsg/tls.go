@@ -0,0 +1,105 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// ConnectOption configures the connection to a device.
+type ConnectOption func(*connectOptions)
+
+type connectOptions struct {
+	tlsConfig          *tls.Config
+	deviceKey          []byte
+	pinnedCertificates func(addr netip.Addr) (string, error)
+	captureDir         string
+	dialTimeout        time.Duration
+	callTimeout        time.Duration
+	retry              RetryPolicy
+}
+
+// newConnectOptions applies the options to the defaults.
+func newConnectOptions(opts ...ConnectOption) connectOptions {
+	options := connectOptions{
+		dialTimeout: timeout,
+		callTimeout: timeout,
+		retry:       DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithTLS connects to devices over TLS, so physiological data isn't sent in
+// cleartext (eg. on shared networks).
+func WithTLS(config *tls.Config) ConnectOption {
+	return func(o *connectOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithConnectOptions connects to devices with the options (eg. WithTLS).
+func WithConnectOptions(opts ...ConnectOption) RecordOption {
+	return func(o *recordOptions) {
+		o.connectOptions = append(o.connectOptions, opts...)
+	}
+}
+
+// LoadTLSConfig loads the TLS configuration for connecting to devices: the
+// certificates of the certificate authorities trusted to sign the
+// certificates of devices (the system's if caPath is empty), and optionally a
+// client certificate and key the recorder presents to devices (mutual TLS).
+func LoadTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caPath != "" {
+		pem, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %q", caPath)
+		}
+	}
+
+	if certPath != "" || keyPath != "" {
+		if certPath == "" || keyPath == "" {
+			return nil, fmt.Errorf("both a client certificate and key are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
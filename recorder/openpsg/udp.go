@@ -0,0 +1,291 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Values arriving out of order over UDP are held back for up to
+// reorderTimeout (or until reorderWindow later batches have arrived) waiting
+// for the missing batches, before they are treated as lost.
+const (
+	reorderTimeout = 100 * time.Millisecond
+	reorderWindow  = 32
+)
+
+// The largest UDP datagram.
+const maxDatagramSize = 65535
+
+// WithUDPStreaming streams signal values from devices with the udp capability
+// over UDP, rather than the JSON-RPC connection (which is still used for
+// control). Lost datagrams are dropped rather than retransmitted, so a slow
+// link doesn't hold up later values (eg. for real-time display).
+func WithUDPStreaming() RecordOption {
+	return func(o *recordOptions) {
+		o.udpStreaming = true
+	}
+}
+
+// udpSource is a source that can stream values over UDP (see
+// Client.StreamUDP).
+type udpSource interface {
+	StreamUDP(ctx context.Context) error
+}
+
+// streamUDP returns an opener that streams the values of the sources opened
+// over UDP, if they can.
+func streamUDP(open SourceOpener) SourceOpener {
+	return func(ctx context.Context) (SignalSource, error) {
+		source, err := open(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if s, ok := source.(udpSource); ok {
+			if err := s.StreamUDP(ctx); err != nil {
+				slog.Warn("Failed to stream values over UDP, streaming over the connection", slog.Any("error", err))
+			}
+		}
+		return source, nil
+	}
+}
+
+// StreamUDP asks the device to stream the values of signals over UDP, as CBOR
+// frames (see CapabilityBinaryStreaming), one per datagram. Values arriving out
+// of order are reordered by their sequence numbers.
+func (c *Client) StreamUDP(ctx context.Context) error {
+	if !c.host.IsValid() {
+		return errors.New("UDP streaming requires a network connection")
+	}
+	if !c.HasCapability(CapabilityUDP) {
+		return errors.New("device doesn't support UDP streaming")
+	}
+	// Datagrams aren't authenticated (only checked to come from the device's
+	// address), so they would bypass the authentication of the connection.
+	if c.options.deviceKey != nil || c.options.tlsConfig != nil {
+		return errors.New("UDP streaming isn't authenticated, and is refused for devices authenticated with a key or TLS")
+	}
+
+	udpConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return fmt.Errorf("failed to listen for UDP datagrams: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.callTimeout)
+	defer cancel()
+
+	if err := requestUDP(ctx, c.conn(), udpConn); err != nil {
+		_ = udpConn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.udpConn = udpConn
+	c.udpDone = make(chan struct{})
+	c.reorder = make(map[uint32]*reorderBuffer)
+	c.mu.Unlock()
+
+	go c.receiveUDP(udpConn)
+
+	return nil
+}
+
+// requestUDP asks the device to send values to the port of udpConn.
+func requestUDP(ctx context.Context, rpcConn *jsonrpc2.Conn, udpConn *net.UDPConn) error {
+	port := udpConn.LocalAddr().(*net.UDPAddr).Port
+	params := map[string]int{"port": port}
+	if err := rpcConn.Call(ctx, "openpsg.udp", params, nil); err != nil {
+		return fmt.Errorf("failed to request UDP streaming: %w", err)
+	}
+	return nil
+}
+
+// receiveUDP receives values from the device until the UDP connection is
+// closed.
+func (c *Client) receiveUDP(udpConn *net.UDPConn) {
+	c.mu.Lock()
+	done := c.udpDone
+	c.mu.Unlock()
+	defer close(done)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		_ = udpConn.SetReadDeadline(time.Now().Add(reorderTimeout / 2))
+
+		n, addr, err := udpConn.ReadFromUDPAddrPort(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if !c.sendValues(c.reorderValues(nil, time.Now())) {
+				return
+			}
+			continue
+		} else if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("Failed to receive UDP datagram", slog.String("device", c.name), slog.Any("error", err))
+			}
+			return
+		}
+
+		// Ignore datagrams from anywhere but the device.
+		if addr.Addr().Unmap() != c.host {
+			continue
+		}
+
+		values, err := decodeBinaryValues(buf[:n], c.sampleFormat)
+		if err != nil {
+			slog.Error("Failed to decode UDP values", slog.String("device", c.name), slog.Any("error", err))
+			continue
+		}
+
+		if !c.sendValues(c.reorderValues(&values, time.Now())) {
+			return
+		}
+	}
+}
+
+// reorderValues adds values received over UDP (if any) to the reorder buffer of
+// their signal, returning the values ready to be delivered in order.
+func (c *Client) reorderValues(values *SignalValues, now time.Time) []SignalValues {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if values != nil {
+		buf, ok := c.reorder[values.ID]
+		if !ok {
+			buf = &reorderBuffer{pending: make(map[uint32]pendingValues)}
+			c.reorder[values.ID] = buf
+		}
+
+		if !buf.add(*values, now) {
+			slog.Debug("Dropped late signal values",
+				slog.String("device", c.name), slog.Any("id", values.ID), slog.Uint64("seq", *values.Seq))
+		}
+	}
+
+	var ready []SignalValues
+	for _, buf := range c.reorder {
+		ready = append(ready, buf.release(now)...)
+	}
+	return ready
+}
+
+// sendValues checks the sequence numbers of values and sends them, returning
+// false if the client has been closed.
+func (c *Client) sendValues(values []SignalValues) bool {
+	for _, v := range values {
+		if !c.checkSequence(v.ID, *v.Seq) {
+			continue
+		}
+
+		if !c.publish(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// reorderBuffer restores the order of the batches of values of a signal, by
+// their sequence numbers.
+type reorderBuffer struct {
+	// The sequence number of the next batch to deliver.
+	next    uint32
+	started bool
+	pending map[uint32]pendingValues
+	ready   []SignalValues
+}
+
+// pendingValues are values waiting for earlier batches to arrive.
+type pendingValues struct {
+	values  SignalValues
+	arrived time.Time
+}
+
+// add adds a batch of values to the buffer, returning false if it arrived too
+// late (after its batch was given up on, or it was already delivered).
+func (b *reorderBuffer) add(values SignalValues, now time.Time) bool {
+	seq := uint32(*values.Seq)
+	if !b.started {
+		b.next, b.started = seq, true
+	}
+
+	if seqDelta(seq, b.next) < 0 {
+		return false
+	}
+	if _, ok := b.pending[seq]; ok {
+		return false
+	}
+
+	b.pending[seq] = pendingValues{values: values, arrived: now}
+	b.advance()
+	return true
+}
+
+// release returns the batches ready to be delivered, giving up on missing
+// batches once later batches have waited reorderTimeout, or more than
+// reorderWindow batches are waiting.
+func (b *reorderBuffer) release(now time.Time) []SignalValues {
+	for len(b.pending) > 0 {
+		oldest := b.oldest()
+		if len(b.pending) <= reorderWindow && now.Sub(b.pending[oldest].arrived) < reorderTimeout {
+			break
+		}
+
+		// Skip the missing batches (reported as lost by the sequence check).
+		b.next = oldest
+		b.advance()
+	}
+
+	ready := b.ready
+	b.ready = nil
+	return ready
+}
+
+// advance moves the batches following on from the last delivered batch to the
+// ready list.
+func (b *reorderBuffer) advance() {
+	for {
+		p, ok := b.pending[b.next]
+		if !ok {
+			return
+		}
+		delete(b.pending, b.next)
+		b.ready = append(b.ready, p.values)
+		b.next++
+	}
+}
+
+// oldest returns the earliest sequence number of the pending batches.
+func (b *reorderBuffer) oldest() uint32 {
+	first := true
+	var oldest uint32
+	for seq := range b.pending {
+		if first || seqDelta(seq, oldest) < 0 {
+			oldest, first = seq, false
+		}
+	}
+	return oldest
+}
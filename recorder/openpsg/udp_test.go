@@ -0,0 +1,126 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderBuffer(t *testing.T) {
+	now := time.Date(2025, 1, 1, 22, 30, 0, 0, time.UTC)
+
+	// The sequence numbers of batches 0 to n, starting at first.
+	batches := func(first uint64, n int) []uint64 {
+		var seqs []uint64
+		for i := range n {
+			seqs = append(seqs, uint64(uint32(first)+uint32(i)))
+		}
+		return seqs
+	}
+
+	type release struct {
+		after time.Duration
+		want  []uint64
+	}
+
+	tests := []struct {
+		name     string
+		added    []uint64
+		wantAdd  []bool
+		releases []release
+	}{
+		{
+			name:     "In order",
+			added:    []uint64{0, 1, 2},
+			releases: []release{{0, []uint64{0, 1, 2}}},
+		},
+		{
+			name:     "Out of order",
+			added:    []uint64{0, 2, 3, 1},
+			releases: []release{{0, []uint64{0, 1, 2, 3}}},
+		},
+		{
+			name:  "Missing",
+			added: []uint64{0, 2, 3},
+			releases: []release{
+				{0, []uint64{0}},
+				{openpsg.ReorderTimeout / 2, nil},
+				{openpsg.ReorderTimeout, []uint64{2, 3}},
+			},
+		},
+		{
+			name:     "Late",
+			added:    []uint64{1, 0, 2},
+			wantAdd:  []bool{true, false, true},
+			releases: []release{{0, []uint64{1, 2}}},
+		},
+		{
+			name:     "Duplicated",
+			added:    []uint64{0, 2, 2, 0},
+			wantAdd:  []bool{true, true, false, false},
+			releases: []release{{openpsg.ReorderTimeout, []uint64{0, 2}}},
+		},
+		{
+			name:     "Window full",
+			added:    append([]uint64{0}, batches(2, openpsg.ReorderWindow+1)...),
+			releases: []release{{0, append([]uint64{0}, batches(2, openpsg.ReorderWindow+1)...)}},
+		},
+		{
+			name:     "Wraparound",
+			added:    []uint64{math.MaxUint32 - 1, 0, math.MaxUint32, 1},
+			releases: []release{{0, []uint64{math.MaxUint32 - 1, math.MaxUint32, 0, 1}}},
+		},
+		{
+			name:  "Missing across wraparound",
+			added: []uint64{math.MaxUint32, 1, 2},
+			releases: []release{
+				{0, []uint64{math.MaxUint32}},
+				{openpsg.ReorderTimeout, []uint64{1, 2}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := openpsg.NewReorderBuffer()
+
+			for i, seq := range tt.added {
+				ok := buf.Add(openpsg.SignalValues{ID: 1, Seq: &seq, Timestamp: now}, now)
+				if tt.wantAdd != nil {
+					assert.Equal(t, tt.wantAdd[i], ok, "sequence number %d", seq)
+				} else {
+					assert.True(t, ok, "sequence number %d", seq)
+				}
+			}
+
+			for _, r := range tt.releases {
+				var released []uint64
+				for _, values := range buf.Release(now.Add(r.after)) {
+					released = append(released, *values.Seq)
+				}
+				assert.Equal(t, r.want, released, "released after %s", r.after)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package openpsg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultVideoSyncInterval is the default interval between sync points.
+	DefaultVideoSyncInterval = time.Minute
+	// How long ffmpeg is given to finalize the video file once stopped.
+	videoStopTimeout = 10 * time.Second
+)
+
+// VideoCapture configures the recording of video (eg. from a webcam or IP
+// camera) alongside the EDF file.
+type VideoCapture struct {
+	// A V4L2 device (eg. "/dev/video0") or a stream URL (eg. "rtsp://...").
+	Input string
+	// The path of the video file (eg. "recording.mkv").
+	Path string
+	// The path of the sync manifest (see VideoManifest).
+	ManifestPath string
+	// The interval between sync points (DefaultVideoSyncInterval if zero).
+	SyncInterval time.Duration
+}
+
+// VideoManifest relates the time in a video file to wall-clock time, so
+// reviewers can correlate movements with events in the recording.
+type VideoManifest struct {
+	// The video file.
+	File string `json:"file"`
+	// The input the video was captured from.
+	Input string `json:"input"`
+	// Points where the wall-clock time of the video was observed.
+	SyncPoints []VideoSyncPoint `json:"sync_points"`
+}
+
+// VideoSyncPoint is the wall-clock time of a point in a video.
+type VideoSyncPoint struct {
+	Time time.Time `json:"time"`
+	// The time in the video in seconds.
+	VideoTime float64 `json:"video_time"`
+}
+
+// WithVideo records video alongside the recording using ffmpeg. Periodic sync
+// points are annotated in the EDF file (eg. "Video sync: recording.mkv
+// 60.000s") and written to a sync manifest.
+func WithVideo(v VideoCapture) RecordOption {
+	return func(o *recordOptions) {
+		if v.SyncInterval <= 0 {
+			v.SyncInterval = DefaultVideoSyncInterval
+		}
+		o.video = &v
+	}
+}
+
+// captureVideo records video until the context is cancelled, calling sync for
+// each sync point.
+func captureVideo(ctx context.Context, v VideoCapture, sync func(VideoSyncPoint)) error {
+	cmd := exec.Command("ffmpeg", v.args()...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to capture video: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to capture video: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to capture video: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+
+		// ffmpeg finalizes the video file when asked to quit.
+		_, _ = io.WriteString(stdin, "q")
+		_ = stdin.Close()
+
+		select {
+		case <-time.After(videoStopTimeout):
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	manifest := &VideoManifest{File: filepath.Base(v.Path), Input: v.Input}
+
+	// ffmpeg periodically reports the time of the encoded video.
+	var next time.Duration
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != "out_time_us" {
+			continue
+		}
+
+		// The time is "N/A" until the first frame.
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+
+		videoTime := time.Duration(us) * time.Microsecond
+		if videoTime < next {
+			continue
+		}
+		next = (videoTime/v.SyncInterval + 1) * v.SyncInterval
+
+		point := VideoSyncPoint{Time: time.Now(), VideoTime: videoTime.Seconds()}
+		manifest.SyncPoints = append(manifest.SyncPoints, point)
+		if err := manifest.writeFile(v.ManifestPath); err != nil {
+			slog.Warn("Failed to write video manifest", slog.Any("error", err))
+		}
+
+		sync(point)
+	}
+
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("video capture stopped: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// args returns the ffmpeg arguments to capture the video.
+func (v VideoCapture) args() []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-nostats", "-progress", "pipe:1"}
+
+	switch {
+	case strings.HasPrefix(v.Input, "/dev/"):
+		args = append(args, "-f", "v4l2")
+	case strings.HasPrefix(v.Input, "rtsp://"):
+		args = append(args, "-rtsp_transport", "tcp")
+	}
+
+	// Matroska files remain readable if ffmpeg is killed before finalizing them.
+	return append(args, "-i", v.Input, "-an",
+		"-c:v", "libx264", "-preset", "veryfast", "-tune", "zerolatency", "-pix_fmt", "yuv420p",
+		"-f", "matroska", "-y", v.Path)
+}
+
+// writeFile atomically replaces the manifest file at path.
+func (m *VideoManifest) writeFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal video manifest: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write video manifest: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace video manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/urfave/cli/v2"
+)
+
+func newPreviewCommand(logLevel *slog.Level) *cli.Command {
+	return &cli.Command{
+		Name:      "preview",
+		Usage:     "Shows a live waveform of a signal of a device, to check the hookup before starting a study",
+		ArgsUsage: "<device address>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "signal",
+				Usage: "Name of the signal to preview (defaults to the first signal of the device)",
+			},
+			&cli.DurationFlag{
+				Name:  "window",
+				Value: 10 * time.Second,
+				Usage: "How much of the signal to show at once",
+			},
+			&cli.IntFlag{
+				Name:  "height",
+				Value: 8,
+				Usage: "Height of the waveform in lines",
+			},
+			noTUIFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a device address")
+			}
+
+			addrPort, err := netip.ParseAddrPort(c.Args().First())
+			if err != nil {
+				addr, err := netip.ParseAddr(c.Args().First())
+				if err != nil {
+					return fmt.Errorf("invalid device address %q", c.Args().First())
+				}
+				addrPort = netip.AddrPortFrom(addr, 80)
+			}
+
+			var view openpsg.PreviewView
+			if useTUI(c) {
+				previewView := newPreviewView(max(c.Int("height"), 1), *logLevel)
+				defer previewView.Close()
+				view = previewView
+			}
+
+			return openpsg.PreviewSignal(c.Context, addrPort, openpsg.Preview{
+				Signal: c.String("signal"),
+				Window: c.Duration("window"),
+			}, view)
+		},
+	}
+}
+
+// previewView draws a scrolling waveform of the signal full screen, along with
+// its quality, until Enter or q is pressed.
+type previewView struct {
+	*screen
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// newPreviewView starts the view, drawing the waveform height lines high.
+func newPreviewView(height int, level slog.Level) *previewView {
+	v := &previewView{done: make(chan struct{})}
+	v.screen = newScreen(&previewModel{previewView: v, height: height}, level, v.finish)
+	return v
+}
+
+// Show implements openpsg.PreviewView.
+func (v *previewView) Show(frame openpsg.PreviewFrame) {
+	v.program.Send(previewMsg(frame))
+}
+
+// Done implements openpsg.PreviewView.
+func (v *previewView) Done() <-chan struct{} {
+	return v.done
+}
+
+// finish stops previewing the signal.
+func (v *previewView) finish() {
+	v.doneOnce.Do(func() { close(v.done) })
+}
+
+type previewMsg openpsg.PreviewFrame
+
+type previewModel struct {
+	*previewView
+	height int
+
+	width int
+	frame *openpsg.PreviewFrame
+}
+
+func (m *previewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *previewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case previewMsg:
+		frame := openpsg.PreviewFrame(msg)
+		m.frame = &frame
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter", "q", "ctrl+c":
+			m.finish()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *previewModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("OpenPSG Recorder — Preview"))
+	b.WriteString("\n\n")
+
+	if m.frame == nil {
+		b.WriteString(dimStyle.Render("Waiting for the signal ...") + "\n")
+	} else {
+		signal := m.frame.Signal
+		problem := m.frame.Quality.Problem()
+		if m.frame.LeadOff {
+			problem = "lead off"
+		}
+		status := okStyle.Render("OK")
+		if problem != "" {
+			status = problemStyle.Render(problem)
+		}
+		fmt.Fprintf(&b, "%s (%d Hz, %s): %s, RMS %.3g %s\n", signal.Name, signal.SampleRate, signal.Unit, status, m.frame.Quality.RMS, signal.Unit)
+
+		width := m.width
+		if width == 0 {
+			width = 80
+		}
+
+		lo, hi := waveformRange(m.frame.Values)
+		waveform := renderWaveform(m.frame.Values, m.frame.Samples, lo, hi, width-12, m.height)
+		for i, line := range waveform {
+			// The waveform is labelled with its range.
+			var label string
+			switch i {
+			case 0:
+				label = fmt.Sprintf("%.4g", hi)
+			case len(waveform) - 1:
+				label = fmt.Sprintf("%.4g", lo)
+			}
+			fmt.Fprintf(&b, "%10s │%s\n", label, line)
+		}
+	}
+
+	b.WriteString("\n" + dimStyle.Render("enter or q stop"))
+
+	return b.String()
+}
+
+// waveformRange returns the range the values are drawn over, never empty.
+func waveformRange(values []float64) (lo, hi float64) {
+	if len(values) == 0 {
+		return -1, 1
+	}
+
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, value := range values {
+		lo = min(lo, value)
+		hi = max(hi, value)
+	}
+	if hi-lo < 1e-9 {
+		lo, hi = lo-1, hi+1
+	}
+	return lo, hi
+}
+
+// renderWaveform draws the values (scaled from lo to hi) as lines of braille
+// characters, each character drawing 2 columns and 4 rows of dots. Each column
+// spans the range of the values it covers, the newest values on the right.
+// Values not yet received (of the samples shown) are left blank on the left.
+func renderWaveform(values []float64, samples int, lo, hi float64, width, height int) []string {
+	width = max(width, 1)
+	columns := width * 2
+	rows := height * 4
+
+	dots := make([][]bool, rows)
+	for i := range dots {
+		dots[i] = make([]bool, columns)
+	}
+
+	if len(values) > 0 {
+		row := func(value float64) int {
+			return min(rows-1, max(0, int((hi-value)/(hi-lo)*float64(rows-1)+0.5)))
+		}
+
+		// The values are right aligned within the samples shown.
+		offset := samples - len(values)
+		for col := range columns {
+			start := col*samples/columns - offset
+			end := (col+1)*samples/columns - offset
+			start, end = max(start, 0), min(end, len(values))
+			if start >= end {
+				// Fewer samples than columns, draw the nearest value.
+				if start >= len(values) || end <= 0 {
+					continue
+				}
+				end = start + 1
+			}
+
+			top, bottom := rows-1, 0
+			for _, value := range values[start:end] {
+				r := row(value)
+				top, bottom = min(top, r), max(bottom, r)
+			}
+			for r := top; r <= bottom; r++ {
+				dots[r][col] = true
+			}
+		}
+	}
+
+	// The bits of the dots of a braille character, by row and column.
+	bits := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+	lines := make([]string, height)
+	for line := range lines {
+		var b strings.Builder
+		for char := range width {
+			r := rune(0x2800)
+			for dy := range 4 {
+				for dx := range 2 {
+					if dots[line*4+dy][char*2+dx] {
+						r |= bits[dy][dx]
+					}
+				}
+			}
+			b.WriteRune(r)
+		}
+		lines[line] = b.String()
+	}
+
+	return lines
+}
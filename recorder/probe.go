@@ -0,0 +1,133 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+func newProbeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "probe",
+		Usage:     "Measures the latency, jitter and clock offset of devices (eg. to vet cabling and switches)",
+		ArgsUsage: "<device address>...",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "duration",
+				Value: 30 * time.Second,
+				Usage: "How long to measure each device for",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Value: 100 * time.Millisecond,
+				Usage: "How often to send a request to each device",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return fmt.Errorf("expected at least one device address")
+			}
+
+			var deviceAddrs []netip.AddrPort
+			for _, arg := range c.Args().Slice() {
+				addrPort, err := netip.ParseAddrPort(arg)
+				if err != nil {
+					addr, err := netip.ParseAddr(arg)
+					if err != nil {
+						return fmt.Errorf("invalid device address %q", arg)
+					}
+					addrPort = netip.AddrPortFrom(addr, 80)
+				}
+				deviceAddrs = append(deviceAddrs, addrPort)
+			}
+
+			slog.Info("Probing devices", slog.Int("devices", len(deviceAddrs)), slog.Duration("duration", c.Duration("duration")))
+
+			// The devices are probed at the same time, as they would be recorded.
+			ctx := appContext(c.Context)
+			results := make([]openpsg.ProbeResult, len(deviceAddrs))
+			errs := make([]error, len(deviceAddrs))
+
+			var wg sync.WaitGroup
+			for i, addrPort := range deviceAddrs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					results[i], errs[i] = openpsg.Probe(ctx, addrPort, c.Duration("duration"), c.Duration("interval"))
+				}()
+			}
+			wg.Wait()
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Device", "RTT (min/mean/p99/max)", "Failed", "Inter-arrival (mean/max)", "Jitter", "Clock Offset"})
+			table.SetBorder(false)
+			table.SetAutoWrapText(false)
+
+			var failed int
+			for i, result := range results {
+				if errs[i] != nil {
+					failed++
+					table.Append([]string{deviceAddrs[i].String(), errs[i].Error(), "", "", "", ""})
+					continue
+				}
+
+				clockOffset := "unsupported"
+				if result.ClockMeasured {
+					clockOffset = fmt.Sprintf("%s (±%s)", formatLatency(result.ClockOffset.Offset), formatLatency(result.ClockOffsetRange/2))
+				}
+
+				table.Append([]string{
+					deviceAddrs[i].String(),
+					fmt.Sprintf("%s / %s / %s / %s", formatLatency(result.RTT.Min), formatLatency(result.RTT.Mean),
+						formatLatency(result.RTT.P99), formatLatency(result.RTT.Max)),
+					fmt.Sprintf("%d of %d", result.FailedRequests, result.FailedRequests+result.RTT.Count),
+					fmt.Sprintf("%s / %s", formatLatency(result.InterArrival.Mean), formatLatency(result.InterArrival.Max)),
+					formatLatency(result.Jitter),
+					clockOffset,
+				})
+			}
+
+			table.Render()
+
+			if failed > 0 {
+				return fmt.Errorf("failed to probe %d of %d devices", failed, len(deviceAddrs))
+			}
+			return nil
+		},
+	}
+}
+
+// formatLatency formats a measured duration to the microsecond.
+func formatLatency(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,384 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// The file describing a profile, in its directory.
+const profileFileName = "profile.yaml"
+
+// Flags specific to a study or to the recorder, rather than the setup of a
+// lab, which can't be saved in a profile.
+var unprofiledFlags = []string{
+	"output", "patient-id", "recording-id", "resume", "start-at", "stop-at",
+	"profile", "log-level", "db-path",
+}
+
+// Flags naming files that are copied into the profile when it is saved, so it
+// doesn't depend on them staying where they are.
+var profileFileFlags = []string{"montage", "calibration", "required-devices", "device-names"}
+
+var profileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// profile is a saved set of recorder flags (eg. a lab's standard full PSG
+// setup), applied with --profile.
+type profile struct {
+	// A description of the profile.
+	Description string `yaml:"description,omitempty"`
+	// The values of the flags, by name (a list of values for flags that can be
+	// repeated).
+	Flags map[string]any `yaml:"flags"`
+}
+
+func newProfileCommand(profilesDir string) *cli.Command {
+	return &cli.Command{
+		Name:  "profile",
+		Usage: "Manages study profiles, saved sets of recorder flags applied with --profile",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "profiles-dir",
+				Value: profilesDir,
+				Usage: "Directory the profiles are stored in",
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "save",
+				Usage:     "Saves the recorder flags (eg. --montage, --filter and --required-devices) as a profile",
+				ArgsUsage: "<name> [recorder flags]...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "description",
+						Usage: "A description of the profile",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Replace an existing profile",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() == 0 {
+						return fmt.Errorf("expected the name of the profile")
+					}
+
+					dir, err := profileDir(c.String("profiles-dir"), c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					if _, err := os.Stat(dir); err == nil && !c.Bool("force") {
+						return fmt.Errorf("profile %q already exists, replace it with --force", c.Args().First())
+					}
+
+					return saveProfile(dir, c.String("description"), recorderFlags(c), c.Args().Tail())
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "Lists the saved profiles",
+				Action: func(c *cli.Context) error {
+					entries, err := os.ReadDir(c.String("profiles-dir"))
+					if err != nil && !errors.Is(err, os.ErrNotExist) {
+						return fmt.Errorf("failed to list profiles: %w", err)
+					}
+
+					table := tablewriter.NewWriter(os.Stdout)
+					table.SetHeader([]string{"Name", "Description"})
+					table.SetBorder(false)
+
+					for _, entry := range entries {
+						p, err := readProfile(filepath.Join(c.String("profiles-dir"), entry.Name()))
+						if err != nil {
+							continue
+						}
+						table.Append([]string{entry.Name(), p.Description})
+					}
+
+					table.Render()
+
+					return nil
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Shows the flags saved in a profile",
+				ArgsUsage: "<name>",
+				Action: func(c *cli.Context) error {
+					dir, err := profileDir(c.String("profiles-dir"), c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					f, err := os.Open(filepath.Join(dir, profileFileName))
+					if err != nil {
+						return fmt.Errorf("failed to open profile: %w", err)
+					}
+					defer f.Close()
+
+					_, err = io.Copy(os.Stdout, f)
+					return err
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Deletes a profile",
+				ArgsUsage: "<name>",
+				Action: func(c *cli.Context) error {
+					dir, err := profileDir(c.String("profiles-dir"), c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					if _, err := os.Stat(filepath.Join(dir, profileFileName)); err != nil {
+						return fmt.Errorf("failed to find profile: %w", err)
+					}
+
+					if err := os.RemoveAll(dir); err != nil {
+						return fmt.Errorf("failed to delete profile: %w", err)
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// profileDir returns the directory of the named profile. Profiles can also be
+// given by the path of their directory (eg. one shared between recorders).
+func profileDir(profilesDir, name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return name, nil
+	}
+
+	if !profileNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+
+	return filepath.Join(profilesDir, name), nil
+}
+
+// saveProfile saves the recorder flags given in args as a profile in dir,
+// copying the files they name into it.
+func saveProfile(dir, description string, flags []cli.Flag, args []string) error {
+	set := flag.NewFlagSet("profile", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	for _, f := range flags {
+		if err := f.Apply(set); err != nil {
+			return err
+		}
+	}
+
+	if err := set.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse recorder flags: %w", err)
+	}
+	if set.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", set.Arg(0))
+	}
+
+	// The files are copied into a new directory, replacing the profile once
+	// saved.
+	tmpDir := dir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return fmt.Errorf("failed to remove stale profile: %w", err)
+	}
+	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	p := profile{Description: description, Flags: make(map[string]any)}
+
+	var visitErr error
+	set.Visit(func(f *flag.Flag) {
+		if visitErr != nil {
+			return
+		}
+
+		name := canonicalFlagName(flags, f.Name)
+		if slices.Contains(unprofiledFlags, name) {
+			visitErr = fmt.Errorf("--%s is specific to a study, and can't be saved in a profile", name)
+			return
+		}
+
+		if values, ok := f.Value.(interface{ Value() []string }); ok {
+			p.Flags[name] = values.Value()
+			return
+		}
+
+		value := f.Value.String()
+		if slices.Contains(profileFileFlags, name) {
+			fileName := name + filepath.Ext(value)
+			if err := copyFile(value, filepath.Join(tmpDir, fileName)); err != nil {
+				visitErr = fmt.Errorf("failed to copy the file of --%s into the profile: %w", name, err)
+				return
+			}
+			value = fileName
+		}
+		p.Flags[name] = value
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, profileFileName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace profile: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return nil
+}
+
+// recorderFlags returns the flags of the recorder (the root command).
+func recorderFlags(c *cli.Context) []cli.Flag {
+	var flags []cli.Flag
+	for _, ancestor := range c.Lineage() {
+		if ancestor.App != nil {
+			flags = ancestor.App.Flags
+		}
+	}
+	return flags
+}
+
+// canonicalFlagName returns the name of the flag with the given name or alias.
+func canonicalFlagName(flags []cli.Flag, name string) string {
+	for _, f := range flags {
+		if slices.Contains(f.Names(), name) {
+			return f.Names()[0]
+		}
+	}
+	return name
+}
+
+// readProfile reads the profile in dir.
+func readProfile(dir string) (*profile, error) {
+	data, err := os.ReadFile(filepath.Join(dir, profileFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	var p profile
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+
+	return &p, nil
+}
+
+// applyProfileFlag applies the profile given by --profile (if any), from the
+// profiles directory.
+func applyProfileFlag(c *cli.Context, profilesDir string) error {
+	name := c.String("profile")
+	if name == "" {
+		return nil
+	}
+
+	dir, err := profileDir(profilesDir, name)
+	if err != nil {
+		return err
+	}
+
+	if err := applyProfile(c, dir); err != nil {
+		return fmt.Errorf("failed to apply profile %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// applyProfile sets the flags saved in the profile in dir, other than those
+// given on the command line.
+func applyProfile(c *cli.Context, dir string) error {
+	p, err := readProfile(dir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(p.Flags))
+	for name := range p.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if slices.Contains(unprofiledFlags, name) {
+			return fmt.Errorf("profile can't set --%s", name)
+		}
+		if c.IsSet(name) {
+			continue
+		}
+
+		var values []string
+		switch value := p.Flags[name].(type) {
+		case []any:
+			for _, v := range value {
+				values = append(values, fmt.Sprint(v))
+			}
+		default:
+			values = []string{fmt.Sprint(value)}
+		}
+
+		for _, value := range values {
+			// Files copied into the profile are relative to it.
+			if slices.Contains(profileFileFlags, name) && !filepath.IsAbs(value) {
+				value = filepath.Join(dir, value)
+			}
+
+			if err := c.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value of --%s in profile: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
@@ -0,0 +1,250 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+// recorderSettings are some of the recorder flags, as seen by the recorder.
+type recorderSettings struct {
+	montage       string
+	filters       []string
+	gapFill       string
+	lineFrequency float64
+	maxDuration   time.Duration
+}
+
+// runRecorderWithProfile parses the recorder flags given in args, applying the
+// profile in dir.
+func runRecorderWithProfile(dir string, args ...string) (recorderSettings, error) {
+	var settings recorderSettings
+	app := &cli.App{
+		Flags: recordFlags("openpsg.key"),
+		Action: func(c *cli.Context) error {
+			if err := applyProfile(c, dir); err != nil {
+				return err
+			}
+
+			settings = recorderSettings{
+				montage:       c.String("montage"),
+				filters:       c.StringSlice("filter"),
+				gapFill:       c.String("gap-fill"),
+				lineFrequency: c.Float64("line-frequency"),
+				maxDuration:   c.Duration("max-duration"),
+			}
+			return nil
+		},
+	}
+
+	err := app.Run(append([]string{"recorder"}, args...))
+	return settings, err
+}
+
+func TestSaveProfile(t *testing.T) {
+	montagePath := filepath.Join(t.TempDir(), "full-psg.yaml")
+	require.NoError(t, os.WriteFile(montagePath, []byte("channels: []\n"), 0o644))
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "Flags",
+			args: []string{"--gap-fill", "hold-last", "--line-frequency", "50", "--filter", "highpass:0.3", "--filter", "notch:50"},
+			want: map[string]any{
+				"gap-fill":       "hold-last",
+				"line-frequency": "50",
+				"filter":         []any{"highpass:0.3", "notch:50"},
+			},
+		},
+		{
+			// Files are copied into the profile.
+			name: "Files",
+			args: []string{"--montage", montagePath},
+			want: map[string]any{"montage": "montage.yaml"},
+		},
+		{
+			name:    "Missing file",
+			args:    []string{"--montage", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: true,
+		},
+		{
+			name:    "Study specific flag",
+			args:    []string{"--gap-fill", "hold-last", "--output", "study.edf"},
+			wantErr: true,
+		},
+		{
+			name:    "Study specific alias",
+			args:    []string{"-p", "Jane Doe"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown flag",
+			args:    []string{"--no-such-flag"},
+			wantErr: true,
+		},
+		{
+			name:    "Argument",
+			args:    []string{"--gap-fill", "hold-last", "study.edf"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "full-psg")
+
+			err := saveProfile(dir, "Full PSG", recordFlags("openpsg.key"), tt.args)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NoDirExists(t, dir)
+				return
+			}
+			require.NoError(t, err)
+
+			p, err := readProfile(dir)
+			require.NoError(t, err)
+			assert.Equal(t, "Full PSG", p.Description)
+			assert.Equal(t, tt.want, p.Flags)
+
+			for name, value := range tt.want {
+				if name == "montage" {
+					assert.FileExists(t, filepath.Join(dir, value.(string)))
+				}
+			}
+		})
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	montagePath := filepath.Join(t.TempDir(), "full-psg.yaml")
+	require.NoError(t, os.WriteFile(montagePath, []byte("channels: []\n"), 0o644))
+
+	dir := filepath.Join(t.TempDir(), "full-psg")
+	require.NoError(t, saveProfile(dir, "Full PSG", recordFlags("openpsg.key"), []string{
+		"--montage", montagePath,
+		"--gap-fill", "hold-last",
+		"--line-frequency", "50",
+		"--filter", "highpass:0.3", "--filter", "notch:50",
+	}))
+
+	profiled := recorderSettings{
+		montage:       filepath.Join(dir, "montage.yaml"),
+		filters:       []string{"highpass:0.3", "notch:50"},
+		gapFill:       "hold-last",
+		lineFrequency: 50,
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want func(s *recorderSettings)
+	}{
+		{
+			name: "Profile",
+			want: func(s *recorderSettings) {},
+		},
+		{
+			name: "Flag overrides profile",
+			args: []string{"--gap-fill", "zero", "--line-frequency", "60"},
+			want: func(s *recorderSettings) {
+				s.gapFill = "zero"
+				s.lineFrequency = 60
+			},
+		},
+		{
+			// Repeated flags replace those in the profile, rather than being
+			// added to them.
+			name: "Repeated flag overrides profile",
+			args: []string{"--filter", "lowpass:35"},
+			want: func(s *recorderSettings) {
+				s.filters = []string{"lowpass:35"}
+			},
+		},
+		{
+			name: "File flag overrides profile",
+			args: []string{"--montage", "/etc/openpsg/montage.yaml"},
+			want: func(s *recorderSettings) {
+				s.montage = "/etc/openpsg/montage.yaml"
+			},
+		},
+		{
+			name: "Flag not in profile",
+			args: []string{"--max-duration", "8h"},
+			want: func(s *recorderSettings) {
+				s.maxDuration = 8 * time.Hour
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := profiled
+			tt.want(&want)
+
+			got, err := runRecorderWithProfile(dir, tt.args...)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestApplyInvalidProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+	}{
+		{
+			name:    "Study specific flag",
+			profile: "flags:\n  output: study.edf\n",
+		},
+		{
+			name:    "Invalid value",
+			profile: "flags:\n  line-frequency: fifty\n",
+		},
+		{
+			name:    "Unknown flag",
+			profile: "flags:\n  no-such-flag: true\n",
+		},
+		{
+			name:    "Not a profile",
+			profile: "- gap-fill\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, profileFileName), []byte(tt.profile), 0o644))
+
+			_, err := runRecorderWithProfile(dir)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/ca"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newProvisionCommand(caDir string) *cli.Command {
+	return &cli.Command{
+		Name:      "provision",
+		Usage:     "Issues certificates to devices, for mutual TLS connections (see --tls-ca-dir)",
+		ArgsUsage: "<device address>...",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "ca-dir",
+				Value: caDir,
+				Usage: "Path to the directory of the certificate authority (created if it doesn't exist)",
+			},
+			&cli.DurationFlag{
+				Name:  "validity",
+				Value: 2 * 365 * 24 * time.Hour,
+				Usage: "How long the issued certificates are valid for",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				return fmt.Errorf("expected the addresses of the devices to provision")
+			}
+
+			var deviceAddrs []netip.Addr
+			for _, arg := range c.Args().Slice() {
+				addr, err := netip.ParseAddr(arg)
+				if err != nil {
+					return fmt.Errorf("invalid device address %q", arg)
+				}
+				deviceAddrs = append(deviceAddrs, addr)
+			}
+
+			authority, err := ca.Open(c.String("ca-dir"))
+			if err != nil {
+				return err
+			}
+
+			db, err := openLeaseDB(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			for _, addr := range deviceAddrs {
+				fingerprint, err := provision(c, authority, db, addr)
+				if err != nil {
+					return fmt.Errorf("failed to provision device %s: %w", addr, err)
+				}
+
+				slog.Info("Provisioned device", slog.Any("deviceAddr", addr), slog.String("fingerprint", fingerprint))
+			}
+
+			return nil
+		},
+	}
+}
+
+// provision issues a certificate to the device at addr, for the key it
+// generates, and pins the fingerprint of the certificate in the lease database.
+func provision(c *cli.Context, authority *ca.CA, db *leasedb.DB, addr netip.Addr) (string, error) {
+	// The certificate is pinned by MAC address, as addresses can change.
+	lease, err := db.GetLeaseByIP(addr)
+	if err != nil {
+		return "", fmt.Errorf("the device has no DHCP lease (has it joined the network?): %w", err)
+	}
+
+	mac, err := net.ParseMAC(lease.MAC)
+	if err != nil {
+		return "", fmt.Errorf("invalid MAC address in lease: %w", err)
+	}
+
+	client, err := openpsg.Connect(c.Context, netip.AddrPortFrom(addr, 80))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	pem, err := client.CertificateRequest(c.Context)
+	if err != nil {
+		return "", err
+	}
+
+	csr, err := ca.ParseCertificateRequest(pem)
+	if err != nil {
+		return "", err
+	}
+
+	cert, err := authority.Issue(csr, addr.AsSlice(), lease.Hostname, c.Duration("validity"))
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.InstallCertificate(c.Context, ca.EncodeCertificate(cert), ca.EncodeCertificate(authority.Certificate())); err != nil {
+		return "", err
+	}
+
+	fingerprint := ca.Fingerprint(cert)
+	if err := db.SetFingerprint(mac, fingerprint); err != nil {
+		return "", fmt.Errorf("failed to store certificate fingerprint: %w", err)
+	}
+
+	return fingerprint, nil
+}
+
+// deviceFingerprint looks up the fingerprint of the certificate a device was
+// provisioned with (empty if the device has no lease, or wasn't provisioned).
+func deviceFingerprint(db leasedb.Store, addr netip.Addr) (string, error) {
+	if db == nil {
+		return "", nil
+	}
+
+	lease, err := db.GetLeaseByIP(addr)
+	if err != nil {
+		return "", nil
+	}
+
+	mac, err := net.ParseMAC(lease.MAC)
+	if err != nil {
+		return "", fmt.Errorf("invalid MAC address in lease: %w", err)
+	}
+
+	return db.GetFingerprint(mac)
+}
@@ -0,0 +1,120 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/pseudonym"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// Environment variable that holds the key file passphrase (for unattended use).
+const passphraseEnvVar = "OPENPSG_KEY_PASSPHRASE"
+
+func newPseudonymsCommand(keyFilePath string) *cli.Command {
+	return &cli.Command{
+		Name:  "pseudonyms",
+		Usage: "Lists the pseudonyms in an encrypted key file, for re-identification",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "key-file",
+				Value: keyFilePath,
+				Usage: "Path to the encrypted pseudonym key file",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			passphrase, err := readPassphrase()
+			if err != nil {
+				return err
+			}
+
+			mappings, err := pseudonym.Read(c.String("key-file"), passphrase)
+			if err != nil {
+				return err
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Pseudonym", "Patient ID", "Recording ID", "Created At"})
+			table.SetBorder(false)
+
+			for _, m := range mappings {
+				table.Append([]string{m.Pseudonym, m.PatientID, m.RecordingID, m.CreatedAt.Format(time.RFC3339)})
+			}
+
+			table.Render()
+
+			return nil
+		},
+	}
+}
+
+// anonymize replaces the patient and recording identifiers with a new
+// pseudonym, storing the mapping in the encrypted key file before anything is
+// recorded, so even an interrupted recording can be re-identified.
+func anonymize(keyFilePath, patientID, recordingID string) (string, string, error) {
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return "", "", err
+	}
+
+	m := pseudonym.Mapping{
+		Pseudonym:   pseudonym.New(),
+		PatientID:   patientID,
+		RecordingID: recordingID,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := pseudonym.Append(keyFilePath, passphrase, m); err != nil {
+		return "", "", fmt.Errorf("failed to store pseudonym: %w", err)
+	}
+
+	// "X" marks an unknown EDF+ subfield.
+	return m.Pseudonym, "X", nil
+}
+
+// readPassphrase reads the key file passphrase from the environment, or
+// prompts for it if running interactively.
+func readPassphrase() (string, error) {
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("key file passphrase required, set %s", passphraseEnvVar)
+	}
+
+	fmt.Fprint(os.Stderr, "Key file passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("key file passphrase must not be empty")
+	}
+
+	return string(passphrase), nil
+}
@@ -0,0 +1,951 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/ca"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+func newRecordCommand(keyFilePath, socketPath, profilesDir string, logLevel *slog.Level) *cli.Command {
+	return &cli.Command{
+		Name:  "record",
+		Usage: "Records a study, attached to the network services (DHCP, NTP and discovery) of a running serve (unless only recording the devices and sources given with --device or --source)",
+		Flags: append(recordFlags(keyFilePath), socketFlag(socketPath)),
+		Before: func(c *cli.Context) error {
+			return applyProfileFlag(c, profilesDir)
+		},
+		Action: func(c *cli.Context) error {
+			return runRecorder(c, *logLevel, true)
+		},
+	}
+}
+
+// recordFlags returns the flags configuring a recording.
+func recordFlags(keyFilePath string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   "openpsg.edf",
+			Usage:   "Output file for the recording",
+		},
+		&cli.StringFlag{
+			Name:    "patient-id",
+			Aliases: []string{"p"},
+			Value:   "X",
+			Usage:   "Patient ID for the recording",
+		},
+		&cli.StringFlag{
+			Name:    "recording-id",
+			Aliases: []string{"r"},
+			Value:   "1",
+			Usage:   "Recording ID for the recording",
+		},
+		&cli.BoolFlag{
+			Name:  "start-offset-annotation",
+			Usage: "Add an annotation carrying the sub-second offset of the first data record",
+		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Resume an interrupted recording, appending to its partial file",
+		},
+		&cli.BoolFlag{
+			Name:  "anonymize",
+			Usage: "Replace the patient and recording IDs with a pseudonym, storing the mapping in an encrypted key file",
+		},
+		&cli.StringFlag{
+			Name:  "key-file",
+			Value: keyFilePath,
+			Usage: "Path to the encrypted pseudonym key file",
+		},
+		&cli.BoolFlag{
+			Name:  "sidecar",
+			Value: true,
+			Usage: "Write a JSON sidecar describing the devices, signals and timing alongside the recording",
+		},
+		&cli.IntFlag{
+			Name:  "max-signals-per-file",
+			Value: 256,
+			Usage: "Split the recording across multiple EDF files if it has more signals than this (including annotations)",
+		},
+		&cli.StringFlag{
+			Name:  "gap-fill",
+			Value: string(openpsg.GapFillZero),
+			Usage: "How to fill missing signal values (zero, physical-min, hold-last, invalid)",
+		},
+		&cli.StringFlag{
+			Name:  "label-check",
+			Value: string(openpsg.LabelCheckWarn),
+			Usage: "Check signal labels against the EDF+ standard texts and 10-20 electrode names (off, warn, normalize)",
+		},
+		&cli.StringFlag{
+			Name:  "impedance-check",
+			Value: "off",
+			Usage: "Check electrode impedances before recording starts (off, warn, block)",
+		},
+		&cli.Float64Flag{
+			Name:  "max-impedance",
+			Value: openpsg.DefaultImpedanceThreshold,
+			Usage: "Highest acceptable electrode impedance in ohms, for --impedance-check",
+		},
+		&cli.StringFlag{
+			Name:  "start-at",
+			Usage: "Wait until this time (HH:MM or RFC 3339) before starting the recording",
+		},
+		&cli.StringFlag{
+			Name:  "stop-at",
+			Usage: "Stop the recording at this time (HH:MM or RFC 3339)",
+		},
+		&cli.DurationFlag{
+			Name:  "max-duration",
+			Usage: "Stop the recording after this long (eg. 9h)",
+		},
+		&cli.DurationFlag{
+			Name:  "sync-interval",
+			Value: 5 * time.Minute,
+			Usage: "How often to flush the recording to disk and update its header, bounding what a power loss can corrupt (0 to disable)",
+		},
+		&cli.DurationFlag{
+			Name:  "buffer-duration",
+			Value: time.Minute,
+			Usage: "How much of each signal to buffer in memory, bounding how late signal values can arrive",
+		},
+		&cli.StringFlag{
+			Name:  "spill-dir",
+			Usage: "Directory to spill signal values to if writing the recording stalls, rather than dropping them (disabled by default)",
+		},
+		&cli.BoolFlag{
+			Name:  "align-epochs",
+			Usage: "Start 30 second data records on :00/:30 wall-clock boundaries, so scoring epochs line up with clock time",
+		},
+		&cli.BoolFlag{
+			Name:  "drift-compensation",
+			Usage: "Resample signals to their nominal sample rate, correcting for device clock drift",
+		},
+		&cli.BoolFlag{
+			Name:  "clock-offset-correction",
+			Usage: "Correct device timestamps by the clock offset of each device, measured by the recorder",
+		},
+		&cli.BoolFlag{
+			Name:  "tls",
+			Usage: "Connect to devices over TLS",
+		},
+		&cli.StringFlag{
+			Name:  "tls-ca",
+			Usage: "Path to the PEM certificates of the CAs trusted to sign device certificates (default: the system's)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "Path to the PEM client certificate presented to devices (for mutual TLS)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "Path to the PEM private key of the client certificate",
+		},
+		&cli.BoolFlag{
+			Name:  "arp-sweep",
+			Value: true,
+			Usage: "Also discover devices that don't hold a lease (eg. statically configured devices) by sweeping the network prefix with ARP",
+		},
+		&cli.BoolFlag{
+			Name:  "require-approval",
+			Usage: "Only record from devices approved with 'devices approve' (unapproved devices are shown, but not recorded)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-ca-dir",
+			Usage: "Connect to devices over mutual TLS with the certificates issued by the provisioning CA in the directory (see provision), checking provisioned devices present their certificates",
+		},
+		&cli.StringFlag{
+			Name:  "device-key-file",
+			Usage: "Path to a file holding a pre-shared key devices must prove they hold before being recorded from",
+		},
+		&cli.StringFlag{
+			Name:  "capture-dir",
+			Usage: "Capture the frames sent to and received from devices to a file per connection in this directory, for debugging (see replay-capture)",
+		},
+		&cli.DurationFlag{
+			Name:  "dial-timeout",
+			Value: 5 * time.Second,
+			Usage: "How long connecting to a device may take",
+		},
+		&cli.DurationFlag{
+			Name:  "request-timeout",
+			Value: 5 * time.Second,
+			Usage: "How long a device may take to respond to a request",
+		},
+		&cli.IntFlag{
+			Name:  "connect-attempts",
+			Value: 1,
+			Usage: "How many times to try connecting to a device before giving up on it (0 for no limit), backing off between attempts",
+		},
+		&cli.BoolFlag{
+			Name:  "udp-streaming",
+			Usage: "Stream signal values from devices over UDP, dropping lost values rather than delaying later ones (not with --device-key-file or TLS, as datagrams aren't authenticated)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "signals",
+			Usage: "Record only the selected signals, by ID, name or glob pattern (eg. 'EEG*'), optionally prefixed by a device address (eg. '10.0.0.12/Nasal Pressure')",
+		},
+		&cli.StringSliceFlag{
+			Name:  "device-filter",
+			Usage: "Record only devices advertising a signal matching the selector, by name, or transducer or unit with 'transducer=' or 'unit=' (eg. 'transducer=*Pressure*')",
+		},
+		&cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Filter the selected signals before they are stored, eg. 'EEG*=HP:0.3Hz LP:35Hz N:50Hz' (can be repeated)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "storage-rate",
+			Usage: "Resample the selected signals to a lower (or higher) sample rate before they are stored, eg. 'EEG*=256' (can be repeated)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "respiratory-events",
+			Usage: "Annotate candidate apneas and hypopneas detected in the selected airflow signals, eg. 'Resp nasal*'",
+		},
+		&cli.StringSliceFlag{
+			Name:  "device",
+			Usage: "Record the OpenPSG device at ADDRESS[:PORT] or HOSTNAME[:PORT] (eg. '10.24.0.12' or 'sensor-3.local:8080'), skipping discovery of devices, equivalent to --source openpsg:ADDRESS",
+		},
+		&cli.StringSliceFlag{
+			Name:  "source",
+			Usage: "Record a signal source, as DRIVER:ARG (eg. 'cpap:/mnt/sdcard/DATALOG', 'nonin:/dev/ttyUSB0' or 'openpsg:192.168.1.20'), available drivers: " + strings.Join(openpsg.Drivers(), ", "),
+		},
+		&cli.StringFlag{
+			Name:  "cpap-dir",
+			Usage: "Record the flow, pressure and leak of a CPAP machine from the logs on its SD card (eg. '/mnt/sdcard/DATALOG'), equivalent to --source cpap:DIR",
+		},
+		&cli.StringSliceFlag{
+			Name:  "oximeter",
+			Usage: "Record a pulse oximeter attached over serial, as PROTOCOL:PORT (eg. 'nonin:/dev/ttyUSB0' or 'contec:/dev/ttyUSB1'), equivalent to --source",
+		},
+		&cli.StringFlag{
+			Name:  "audio-device",
+			Usage: "Record audio (eg. from a snore microphone) from this ALSA capture device (eg. 'default' or 'hw:1,0') to a WAV file alongside the recording",
+		},
+		&cli.UintFlag{
+			Name:  "audio-sample-rate",
+			Usage: "The sample rate of the recorded audio in Hz",
+			Value: openpsg.DefaultAudioSampleRate,
+		},
+		&cli.StringFlag{
+			Name:  "video-input",
+			Usage: "Record video from this V4L2 device (eg. '/dev/video0') or stream URL (eg. 'rtsp://camera/stream') alongside the recording",
+		},
+		&cli.DurationFlag{
+			Name:  "video-sync-interval",
+			Usage: "The interval between video sync point annotations",
+			Value: openpsg.DefaultVideoSyncInterval,
+		},
+		&cli.DurationFlag{
+			Name:  "status-interval",
+			Usage: "Print the quality (flat-line, clipping and RMS noise) of each signal at this interval while recording",
+		},
+		&cli.DurationFlag{
+			Name:  "progress-interval",
+			Value: 5 * time.Second,
+			Usage: "Show the throughput and health of the recording (samples per second, dropped values, buffer fill and file size) at this interval while recording, as a status line on a terminal or logged otherwise (0 to disable)",
+		},
+		noTUIFlag(),
+		&cli.StringSliceFlag{
+			Name:  "hotkey",
+			Usage: "Annotate the recording when a key is pressed on the dashboard, as KEY=TEXT (eg. 'b=Bathroom break'), in addition to l (Lights off), o (Lights on) and n (Nurse in room) (can be repeated)",
+		},
+		&cli.Float64Flag{
+			Name:  "line-frequency",
+			Usage: "The mains frequency in Hz (50 or 60), by default both are checked for line noise",
+		},
+		&cli.Float64Flag{
+			Name:  "line-noise-threshold",
+			Value: openpsg.DefaultLineNoiseThreshold,
+			Usage: "Warn when more than this fraction of a voltage signal's power is mains interference (0 to disable)",
+		},
+		&cli.StringFlag{
+			Name:  "calibration",
+			Usage: "Path to a JSON or YAML file with the gain and offset (or two-point calibration) of device signals",
+		},
+		&cli.StringFlag{
+			Name:  "montage",
+			Usage: "Path to a JSON or YAML montage giving the channel order and labels, and listing devices that may join the recording after it has started",
+		},
+		&cli.StringFlag{
+			Name:  "device-names",
+			Usage: "Path to a JSON or YAML file mapping device MAC addresses, hostnames or addresses to friendly names (eg. \"Bed 3 head-box\")",
+		},
+		&cli.StringFlag{
+			Name:  "required-devices",
+			Usage: "Path to a JSON or YAML manifest of the devices (and signals) that must be online before recording starts",
+		},
+		&cli.DurationFlag{
+			Name:  "wait-for-devices",
+			Usage: "How long to wait for the required devices to be ready before refusing to record (by default they must be ready straight away)",
+		},
+		&cli.Uint64Flag{
+			Name:  "low-space-warn",
+			Value: 1024,
+			Usage: "Warn when the free space on the output volume falls below this many MiB",
+		},
+		&cli.Uint64Flag{
+			Name:  "low-space-stop",
+			Value: 100,
+			Usage: "Stop the recording cleanly (or switch to the secondary output) when the free space on the output volume falls below this many MiB (0 to disable)",
+		},
+		&cli.StringFlag{
+			Name:  "secondary-output",
+			Usage: "Continue the recording in this file (eg. on another volume) if the output volume runs low on space",
+		},
+		&cli.StringFlag{
+			Name:  "fhir-output",
+			Usage: "Write a FHIR bundle describing the recording to this file",
+		},
+		&cli.StringFlag{
+			Name:  "fhir-endpoint",
+			Usage: "Post a FHIR bundle describing the recording to this FHIR server base URL",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "Apply the flags saved in a study profile (see the profile subcommand), by name or directory, unless given on the command line",
+		},
+	}
+}
+
+// runRecorder records a study, logging at level. Unless attaching to the
+// network services of serve, the recorder runs them itself (if given a network
+// interface) until the recording has finished.
+func runRecorder(c *cli.Context, level slog.Level, attach bool) error {
+	// Not marked as required, as it would also be required by subcommands.
+	// Without a network interface (or attaching to the network services of
+	// serve) only the sources attached to the recorder (eg. over USB) are
+	// recorded.
+	ifname := c.String("interface")
+
+	// Don't clobber the partial output of an interrupted recording.
+	outputPath := c.String("output")
+	partialPath := outputPath + partialSuffix
+	journalPath := outputPath + journalSuffix
+
+	var journal *openpsg.Journal
+	if _, err := os.Stat(partialPath); err == nil {
+		if !c.Bool("resume") {
+			return fmt.Errorf("found partial recording %s, resume it with --resume, recover it with the recover subcommand or remove it", partialPath)
+		}
+
+		journal, err = openpsg.LoadJournal(journalPath)
+		if err != nil {
+			return fmt.Errorf("failed to resume recording: %w", err)
+		}
+	} else if c.Bool("resume") {
+		return fmt.Errorf("no partial recording to resume: %s", partialPath)
+	}
+
+	split := &splitFiles{outputPath: outputPath}
+	if partialPaths := split.partialPaths(); len(partialPaths) > 0 {
+		return fmt.Errorf("found partial recording %s, recover it with the recover subcommand or remove it", partialPaths[0])
+	}
+
+	secondaryOutputPath := c.String("secondary-output")
+	if secondaryOutputPath != "" {
+		for _, path := range []string{secondaryOutputPath, secondaryOutputPath + partialSuffix} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("secondary output %s already exists", path)
+			}
+		}
+	}
+
+	// Refuse to start a recording that would be stopped straight away.
+	if err := checkDiskSpace(filepath.Dir(outputPath), c.Uint64("low-space-stop")<<20, freeSpace); err != nil {
+		if secondaryOutputPath == "" {
+			return err
+		}
+
+		if err := checkDiskSpace(filepath.Dir(secondaryOutputPath), c.Uint64("low-space-stop")<<20, freeSpace); err != nil {
+			return err
+		}
+	}
+
+	patientID := c.String("patient-id")
+	recordingID := c.String("recording-id")
+	var pseudonym string
+	if journal != nil && journal.Pseudonym != "" {
+		// The resumed recording keeps its pseudonym (and its header).
+		pseudonym = journal.Pseudonym
+		slog.Info("Resuming recording with pseudonym", slog.String("pseudonym", pseudonym))
+		patientID, recordingID = pseudonym, "X"
+	} else if c.Bool("anonymize") {
+		// Before discovery, as both read from stdin.
+		var scrubbedRecordingID string
+		var err error
+		pseudonym, scrubbedRecordingID, err = anonymize(c.String("key-file"), patientID, recordingID)
+		if err != nil {
+			return fmt.Errorf("failed to anonymize recording: %w", err)
+		}
+
+		slog.Info("Recording with pseudonym", slog.String("pseudonym", pseudonym))
+		patientID, recordingID = pseudonym, scrubbedRecordingID
+	}
+
+	gapFill, err := openpsg.ParseGapFill(c.String("gap-fill"))
+	if err != nil {
+		return err
+	}
+
+	labelCheck, err := openpsg.ParseLabelCheck(c.String("label-check"))
+	if err != nil {
+		return err
+	}
+
+	// Electrode impedances are checked before the study starts, not when
+	// it's resumed or continued on the secondary output.
+	var impedanceCheck *openpsg.ImpedanceCheck
+	switch c.String("impedance-check") {
+	case "off":
+	case "warn", "block":
+		if !c.Bool("resume") {
+			impedanceCheck = &openpsg.ImpedanceCheck{
+				Threshold: c.Float64("max-impedance"),
+				Block:     c.String("impedance-check") == "block",
+			}
+		}
+	default:
+		return fmt.Errorf("unknown impedance check mode: %s", c.String("impedance-check"))
+	}
+
+	startAt, stopAt, err := resolveSchedule(c.String("start-at"), c.String("stop-at"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	var montage *openpsg.Montage
+	if montagePath := c.String("montage"); montagePath != "" {
+		montage, err = openpsg.LoadMontage(montagePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var deviceNames openpsg.DeviceNames
+	if namesPath := c.String("device-names"); namesPath != "" {
+		deviceNames, err = openpsg.LoadDeviceNames(namesPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var requiredDevices *openpsg.DeviceManifest
+	if manifestPath := c.String("required-devices"); manifestPath != "" {
+		requiredDevices, err = openpsg.LoadDeviceManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var calibration *openpsg.Calibration
+	if calibrationPath := c.String("calibration"); calibrationPath != "" {
+		calibration, err = openpsg.LoadCalibration(calibrationPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var filters []*openpsg.ChannelFilters
+	for _, f := range c.StringSlice("filter") {
+		cf, err := openpsg.ParseChannelFilters(f)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, cf)
+	}
+
+	var storageRates []*openpsg.StorageRate
+	for _, r := range c.StringSlice("storage-rate") {
+		rate, err := openpsg.ParseStorageRate(r)
+		if err != nil {
+			return err
+		}
+		storageRates = append(storageRates, rate)
+	}
+
+	hotkeys, err := parseHotkeys(c.StringSlice("hotkey"))
+	if err != nil {
+		return err
+	}
+
+	var respiratoryEvents *openpsg.SignalSelection
+	if selectors := c.StringSlice("respiratory-events"); len(selectors) > 0 {
+		respiratoryEvents, err = openpsg.ParseSignalSelection(selectors)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The shorthand flags are equivalent to sources given with --source.
+	var sourceSpecs []string
+	if cpapDir := c.String("cpap-dir"); cpapDir != "" {
+		sourceSpecs = append(sourceSpecs, "cpap:"+cpapDir)
+	}
+	sourceSpecs = append(sourceSpecs, c.StringSlice("oximeter")...)
+	sourceSpecs = append(sourceSpecs, c.StringSlice("source")...)
+	for _, device := range c.StringSlice("device") {
+		sourceSpecs = append(sourceSpecs, "openpsg:"+device)
+	}
+
+	// Opened once the network interface has been configured (or attached to).
+	var leases leasedb.Store
+
+	var connectOpts []openpsg.ConnectOption
+	if c.Bool("tls") && c.String("tls-ca-dir") != "" {
+		return fmt.Errorf("--tls and --tls-ca-dir can't be combined")
+	}
+	// Datagrams aren't authenticated, so anyone on the network could inject
+	// values into the recording of an authenticated device.
+	if c.Bool("udp-streaming") && (c.String("device-key-file") != "" || c.Bool("tls") || c.String("tls-ca-dir") != "") {
+		return fmt.Errorf("--udp-streaming can't be combined with --device-key-file or TLS")
+	}
+	if caDir := c.String("tls-ca-dir"); caDir != "" {
+		authority, err := ca.Open(caDir)
+		if err != nil {
+			return err
+		}
+		connectOpts = append(connectOpts,
+			openpsg.WithTLS(authority.TLSConfig()),
+			openpsg.WithPinnedCertificates(func(addr netip.Addr) (string, error) {
+				return deviceFingerprint(leases, addr)
+			}))
+	}
+	if c.Bool("tls") {
+		tlsConfig, err := openpsg.LoadTLSConfig(c.String("tls-ca"), c.String("tls-cert"), c.String("tls-key"))
+		if err != nil {
+			return err
+		}
+		connectOpts = append(connectOpts, openpsg.WithTLS(tlsConfig))
+	}
+	if path := c.String("device-key-file"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read device key: %w", err)
+		}
+		key = bytes.TrimSpace(key)
+		if len(key) == 0 {
+			return fmt.Errorf("device key file %q is empty", path)
+		}
+		connectOpts = append(connectOpts, openpsg.WithDeviceKey(key))
+	}
+	if captureDir := c.String("capture-dir"); captureDir != "" {
+		connectOpts = append(connectOpts, openpsg.WithCapture(captureDir))
+	}
+	retry := openpsg.DefaultRetryPolicy
+	retry.Attempts = c.Int("connect-attempts")
+	connectOpts = append(connectOpts,
+		openpsg.WithDialTimeout(c.Duration("dial-timeout")),
+		openpsg.WithCallTimeout(c.Duration("request-timeout")),
+		openpsg.WithRetry(retry))
+
+	sources, err := openpsg.ParseSources(sourceSpecs, connectOpts...)
+	if err != nil {
+		return err
+	}
+	if !attach && ifname == "" && len(sources) == 0 {
+		return fmt.Errorf("network interface name is required (unless recording only sources given with --source or --device)")
+	}
+
+	var signalSelection *openpsg.SignalSelection
+	if selectors := c.StringSlice("signals"); len(selectors) > 0 {
+		signalSelection, err = openpsg.ParseSignalSelection(selectors)
+		if err != nil {
+			return err
+		}
+	}
+
+	var deviceFilter *openpsg.DeviceFilter
+	if selectors := c.StringSlice("device-filter"); len(selectors) > 0 {
+		deviceFilter, err = openpsg.ParseDeviceFilter(selectors)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The network services are either run by the recorder, or by serve (so
+	// they keep running between recordings).
+	var network leasedb.Network
+	var db *leasedb.DB
+	if attach {
+		// Discovery needs the lease database, as do approval and pinning the
+		// certificates of devices, otherwise the given sources can be recorded
+		// without serve (only missing out on the MAC address and hostname of
+		// each device in the sidecar).
+		required := len(sources) == 0 || c.Bool("require-approval") || c.String("tls-ca-dir") != ""
+
+		client, err := leasedb.Dial(c.Context, c.String("socket"))
+		if err != nil && required {
+			return fmt.Errorf("failed to attach to network services (is serve running?): %w", err)
+		} else if err != nil {
+			slog.Info("Not attached to network services, recording only the given sources", slog.Any("error", err))
+		} else {
+			defer client.Close()
+
+			network, leases = client.Network(), client
+		}
+	} else if ifname != "" {
+		network, db, err = openNetwork(c, ifname)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		leases = db
+	}
+
+	// Cancelled once the recording has finished, to stop the servers.
+	ctx, stopServers := context.WithCancel(appContext(c.Context))
+	defer stopServers()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// The dashboard takes over the terminal until the recorder exits.
+	var dash *dashboard
+	var discoveryView openpsg.DiscoveryView
+	if useTUI(c) {
+		dash = newDashboard(stopServers, level, hotkeys)
+		defer dash.Close()
+		discoveryView = dash
+	}
+
+	if db != nil {
+		runNetworkServices(ctx, g, db, network)
+
+		// So the leases can be listed while recording.
+		if ln, err := listenSocket(c.String("socket")); err != nil {
+			slog.Warn("Failed to serve lease database", slog.Any("error", err))
+		} else {
+			serveLeaseDB(ctx, g, ln, db, network)
+		}
+	}
+
+	g.Go(func() error {
+		defer stopServers()
+
+		var deviceAddrs []netip.Addr
+		var err error
+		// A fixed installation lists its devices instead.
+		if leases != nil && len(c.StringSlice("device")) == 0 {
+			slog.Info("Discovering devices ...")
+
+			var sweep *openpsg.ARPSweep
+			if c.Bool("arp-sweep") {
+				sweep = &openpsg.ARPSweep{Interface: network.Interface, Prefix: network.Prefix}
+			}
+
+			deviceAddrs, err = openpsg.Discover(ctx, leases, c.Bool("require-approval"), sweep, deviceNames, deviceFilter, discoveryView, connectOpts...)
+			if err != nil {
+				return fmt.Errorf("failed to discover devices: %w", err)
+			}
+		}
+
+		if requiredDevices != nil {
+			slog.Info("Checking required devices ...")
+
+			addrs, err := requiredDevices.WaitForDevices(ctx, c.Duration("wait-for-devices"), func() ([]openpsg.Candidate, error) {
+				candidates := openpsg.SourceCandidates(sources)
+				if leases != nil {
+					leased, err := openpsg.LeaseCandidates(leases, c.Bool("require-approval"))
+					if err != nil {
+						return nil, err
+					}
+					candidates = append(candidates, leased...)
+				}
+				return candidates, nil
+			}, connectOpts...)
+			if err != nil {
+				return err
+			}
+
+			// Record the required devices that came online after discovery.
+			for _, addr := range addrs {
+				if !slices.Contains(deviceAddrs, addr) && !slices.ContainsFunc(sources, func(source openpsg.Source) bool {
+					return source.Addr == addr
+				}) {
+					deviceAddrs = append(deviceAddrs, addr)
+				}
+			}
+		}
+
+		if !startAt.IsZero() {
+			slog.Info("Waiting to start recording", slog.Time("startAt", startAt))
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Until(startAt)):
+			}
+		}
+
+		recordCtx := ctx
+		if !stopAt.IsZero() {
+			var cancel context.CancelFunc
+			recordCtx, cancel = context.WithDeadline(recordCtx, stopAt)
+			defer cancel()
+		}
+		if maxDuration := c.Duration("max-duration"); maxDuration > 0 {
+			var cancel context.CancelFunc
+			recordCtx, cancel = context.WithTimeout(recordCtx, maxDuration)
+			defer cancel()
+		}
+
+		slog.Info("Recording from devices", slog.Any("deviceAddrs", deviceAddrs))
+
+		// record records to the output file until the recording is stopped, or
+		// the output volume runs low on disk space (returning true).
+		record := func(outputPath string, journal *openpsg.Journal) (bool, error) {
+			partialPath := outputPath + partialSuffix
+			journalPath := outputPath + journalSuffix
+			split := &splitFiles{outputPath: outputPath}
+
+			fileCtx, cancel := context.WithCancel(recordCtx)
+			defer cancel()
+
+			lowSpace := watchDiskSpace(fileCtx, filepath.Dir(outputPath),
+				c.Uint64("low-space-warn")<<20, c.Uint64("low-space-stop")<<20, diskSpaceCheckInterval, freeSpace)
+			go func() {
+				select {
+				case <-lowSpace:
+					cancel()
+				case <-fileCtx.Done():
+				}
+			}()
+
+			// Write to a partial file, so an interrupted recording is never
+			// mistaken for a complete one.
+			var f *os.File
+			var err error
+			if journal != nil {
+				f, err = openPartial(partialPath)
+			} else {
+				f, err = os.Create(partialPath)
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to create file: %w", err)
+			}
+			defer f.Close()
+			defer split.close()
+
+			outputBase := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+
+			opts := []openpsg.RecordOption{
+				openpsg.WithLeaseDB(leases),
+				openpsg.WithDeviceNames(deviceNames),
+				openpsg.WithDeviceFilter(deviceFilter),
+				openpsg.WithSplitting(c.Int("max-signals-per-file"), split, outputBase+".manifest.json"),
+				openpsg.WithGapFill(gapFill),
+				openpsg.WithLabelCheck(labelCheck),
+				openpsg.WithLineNoiseDetection(c.Float64("line-frequency"), c.Float64("line-noise-threshold")),
+				openpsg.WithJournal(journalPath),
+			}
+			if pseudonym != "" {
+				opts = append(opts, openpsg.WithPseudonym(pseudonym))
+			}
+			if journal != nil {
+				opts = append(opts, openpsg.WithResume(journal))
+			}
+			if impedanceCheck != nil {
+				if dash != nil {
+					impedanceCheck.Output = dash.logs
+				}
+				opts = append(opts, openpsg.WithImpedanceCheck(*impedanceCheck))
+				impedanceCheck = nil
+			}
+			if montage != nil {
+				opts = append(opts, openpsg.WithMontage(montage))
+			}
+			if calibration != nil {
+				opts = append(opts, openpsg.WithCalibration(calibration))
+			}
+			if len(filters) > 0 {
+				opts = append(opts, openpsg.WithFilters(filters...))
+			}
+			if dash != nil {
+				dash.recording(outputPath)
+				opts = append(opts,
+					openpsg.WithProgress(time.Second, dash.progress),
+					openpsg.WithAnnotations(dash.annotations))
+			} else {
+				if statusInterval := c.Duration("status-interval"); statusInterval > 0 {
+					opts = append(opts, openpsg.WithStatus(statusInterval, printStatus))
+				}
+				if progressInterval := c.Duration("progress-interval"); progressInterval > 0 {
+					status := newStatusLine(partialPath)
+					defer status.Close()
+					opts = append(opts, openpsg.WithProgress(progressInterval, status.update))
+				}
+			}
+			if len(storageRates) > 0 {
+				opts = append(opts, openpsg.WithStorageRates(storageRates...))
+			}
+			if respiratoryEvents != nil {
+				opts = append(opts, openpsg.WithRespiratoryEvents(respiratoryEvents))
+			}
+			if signalSelection != nil {
+				opts = append(opts, openpsg.WithSignals(signalSelection))
+			}
+			opts = append(opts, openpsg.WithBufferDuration(c.Duration("buffer-duration")))
+			if syncInterval := c.Duration("sync-interval"); syncInterval > 0 {
+				opts = append(opts, openpsg.WithSyncInterval(syncInterval))
+			}
+			if spillDir := c.String("spill-dir"); spillDir != "" {
+				opts = append(opts, openpsg.WithSpill(spillDir))
+			}
+			if c.Bool("align-epochs") {
+				opts = append(opts, openpsg.WithEpochAlignment())
+			}
+			if c.Bool("drift-compensation") {
+				opts = append(opts, openpsg.WithDriftCompensation())
+			}
+			if c.Bool("clock-offset-correction") {
+				opts = append(opts, openpsg.WithClockOffsetCorrection())
+			}
+			if c.Bool("udp-streaming") {
+				opts = append(opts, openpsg.WithUDPStreaming())
+			}
+			opts = append(opts, openpsg.WithConnectOptions(connectOpts...))
+			opts = append(opts, openpsg.WithPause(pauseSignals(fileCtx)))
+			if c.Bool("start-offset-annotation") {
+				opts = append(opts, openpsg.WithStartOffsetAnnotation())
+			}
+			if c.Bool("sidecar") {
+				opts = append(opts, openpsg.WithSidecar(outputBase+".json"))
+			}
+			for _, source := range sources {
+				slog.Info("Recording source", slog.String("source", source.Spec), slog.Any("address", source.Addr))
+				opts = append(opts, openpsg.WithSource(source.Addr, source.Open))
+			}
+			if audioDevice := c.String("audio-device"); audioDevice != "" {
+				opts = append(opts, openpsg.WithAudio(openpsg.AudioCapture{
+					Device:     audioDevice,
+					SampleRate: uint32(c.Uint("audio-sample-rate")),
+					Path:       outputBase + ".wav",
+				}))
+			}
+			if videoInput := c.String("video-input"); videoInput != "" {
+				opts = append(opts, openpsg.WithVideo(openpsg.VideoCapture{
+					Input:        videoInput,
+					Path:         outputBase + ".mkv",
+					ManifestPath: outputBase + ".video.json",
+					SyncInterval: c.Duration("video-sync-interval"),
+				}))
+			}
+
+			// The statistics of the recording are reported once it stops, even if
+			// it failed.
+			var report *openpsg.RecordingReport
+			opts = append(opts, openpsg.WithReport(func(r openpsg.RecordingReport) {
+				report = &r
+			}))
+
+			err = openpsg.Record(fileCtx, f, patientID, recordingID, deviceAddrs, opts...)
+			if report != nil {
+				if dash != nil {
+					printReport(dash.logs, *report)
+				} else {
+					printReport(os.Stdout, *report)
+				}
+
+				reportPath := outputBase + ".report.json"
+				if err := saveReport(reportPath, *report); err != nil {
+					slog.Warn("Failed to save recording report", slog.Any("error", err))
+				} else {
+					slog.Info("Saved recording report", slog.String("path", reportPath))
+				}
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to record from devices: %w", err)
+			}
+
+			if err := f.Sync(); err != nil {
+				return false, fmt.Errorf("failed to sync file: %w", err)
+			}
+
+			if err := f.Close(); err != nil {
+				return false, fmt.Errorf("failed to close file: %w", err)
+			}
+
+			if err := os.Rename(partialPath, outputPath); err != nil {
+				return false, fmt.Errorf("failed to rename file: %w", err)
+			}
+
+			if err := split.finalize(); err != nil {
+				return false, err
+			}
+
+			if err := os.Remove(journalPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return false, fmt.Errorf("failed to remove journal: %w", err)
+			}
+
+			if c.String("fhir-output") != "" || c.String("fhir-endpoint") != "" {
+				if err := publishFHIR(outputPath, patientID, recordingID,
+					c.String("fhir-output"), c.String("fhir-endpoint")); err != nil {
+					return false, fmt.Errorf("failed to publish FHIR resources: %w", err)
+				}
+			}
+
+			select {
+			case <-lowSpace:
+				return true, nil
+			default:
+				return false, nil
+			}
+		}
+
+		lowSpace, err := record(outputPath, journal)
+		if err != nil {
+			return err
+		}
+
+		if lowSpace && secondaryOutputPath != "" {
+			slog.Warn("Low on disk space, continuing the recording on the secondary output",
+				slog.String("path", secondaryOutputPath))
+
+			lowSpace, err = record(secondaryOutputPath, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		if lowSpace {
+			slog.Error("Stopped recording as the disk is low on space")
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
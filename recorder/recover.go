@@ -0,0 +1,109 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// Recordings are written to a partial file, which is renamed on completion.
+	partialSuffix = ".partial"
+	// The journal of a recording (used to resume it) is alongside the output
+	// file, and removed on completion.
+	journalSuffix = ".journal"
+)
+
+func newRecoverCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "recover",
+		Usage:     "Finalizes a partial recording left behind by an interrupted recorder",
+		ArgsUsage: "<recording.edf.partial>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single partial recording to recover")
+			}
+
+			partialPath := c.Args().First()
+			if !strings.HasSuffix(partialPath, partialSuffix) {
+				return fmt.Errorf("expected a %s file", partialSuffix)
+			}
+
+			outputPath := strings.TrimSuffix(partialPath, partialSuffix)
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("refusing to overwrite existing recording: %s", outputPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to stat recording: %w", err)
+			}
+
+			f, err := os.OpenFile(partialPath, os.O_RDWR, 0)
+			if err != nil {
+				return fmt.Errorf("failed to open partial recording: %w", err)
+			}
+			defer f.Close()
+
+			dataRecords, err := edfplus.Recover(f)
+			if err != nil {
+				return fmt.Errorf("failed to recover recording: %w", err)
+			}
+
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to close recording: %w", err)
+			}
+
+			if err := os.Rename(partialPath, outputPath); err != nil {
+				return fmt.Errorf("failed to rename recording: %w", err)
+			}
+
+			// The recording can no longer be resumed.
+			if err := os.Remove(outputPath + journalSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove journal: %w", err)
+			}
+
+			slog.Info("Recovered recording",
+				slog.String("path", outputPath),
+				slog.Int("dataRecords", dataRecords))
+
+			return nil
+		},
+	}
+}
+
+// openPartial opens the partial file of an interrupted recording so it can be
+// resumed, removing any truncated data record.
+func openPartial(partialPath string) (*os.File, error) {
+	f, err := os.OpenFile(partialPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial recording: %w", err)
+	}
+
+	if _, err := edfplus.Recover(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to recover recording: %w", err)
+	}
+
+	return f, nil
+}
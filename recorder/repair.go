@@ -0,0 +1,130 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/urfave/cli/v2"
+)
+
+func newRepairCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "repair",
+		Usage:     "Writes a copy of an EDF recording with its recoverable problems fixed",
+		ArgsUsage: "<recording.edf>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Path of the repaired recording (defaults to <recording>_repaired.edf)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording to repair")
+			}
+
+			inputPath := c.Args().First()
+
+			outputPath := c.String("output")
+			if outputPath == "" {
+				base := strings.TrimSuffix(inputPath, partialSuffix)
+				ext := filepath.Ext(base)
+				outputPath = strings.TrimSuffix(base, ext) + "_repaired" + ext
+			}
+
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("refusing to overwrite existing recording: %s", outputPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to stat recording: %w", err)
+			}
+
+			f, err := os.Open(inputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			in, err := edfplus.Inspect(f)
+			if err != nil {
+				return fmt.Errorf("failed to inspect recording: %w", err)
+			}
+
+			if len(in.Anomalies) == 0 {
+				fmt.Println("No anomalies found, nothing to repair.")
+				return nil
+			}
+
+			partialPath := outputPath + partialSuffix
+			out, err := os.Create(partialPath)
+			if err != nil {
+				return fmt.Errorf("failed to create repaired recording: %w", err)
+			}
+			defer out.Close()
+
+			repaired, err := edfplus.Repair(out, f)
+			if err != nil {
+				_ = os.Remove(partialPath)
+				return fmt.Errorf("failed to repair recording: %w", err)
+			}
+
+			remaining, err := edfplus.Inspect(out)
+			if err != nil {
+				_ = os.Remove(partialPath)
+				return fmt.Errorf("failed to inspect repaired recording: %w", err)
+			}
+
+			if err := out.Sync(); err != nil {
+				return fmt.Errorf("failed to sync repaired recording: %w", err)
+			}
+
+			if err := out.Close(); err != nil {
+				return fmt.Errorf("failed to close repaired recording: %w", err)
+			}
+
+			if err := os.Rename(partialPath, outputPath); err != nil {
+				return fmt.Errorf("failed to rename repaired recording: %w", err)
+			}
+
+			fmt.Printf("Wrote %s\n", outputPath)
+
+			if len(repaired) > 0 {
+				fmt.Println("Repaired:")
+				for _, anomaly := range repaired {
+					fmt.Printf("  - %s\n", anomaly)
+				}
+			}
+
+			if len(remaining.Anomalies) > 0 {
+				fmt.Println("Not repaired:")
+				for _, anomaly := range remaining.Anomalies {
+					fmt.Printf("  - %s\n", anomaly)
+				}
+			}
+
+			return nil
+		},
+	}
+}
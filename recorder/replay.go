@@ -0,0 +1,90 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newReplayCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "Serves an EDF recording as a device, to regression test the recorder against known studies",
+		ArgsUsage: "<recording>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "127.0.0.1:8080",
+				Usage: "Address the device listens on",
+			},
+			&cli.Float64Flag{
+				Name:  "speed",
+				Value: 1,
+				Usage: "How much faster than real time to replay the recording (eg. 10)",
+			},
+			&cli.BoolFlag{
+				Name:  "loop",
+				Usage: "Replay the recording from its start once it has ended",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording")
+			}
+			path := c.Args().First()
+
+			if c.Float64("speed") <= 0 {
+				return fmt.Errorf("invalid speed: %g", c.Float64("speed"))
+			}
+
+			replay, err := openpsg.OpenReplay(path, c.Bool("loop"))
+			if err != nil {
+				return err
+			}
+			defer replay.Close()
+
+			device := &openpsg.SimulatedDevice{
+				Info: openpsg.DeviceInfo{
+					SerialNumber: filepath.Base(path),
+					Model:        "OpenPSG-Replay",
+				},
+				Generator: replay,
+				Speed:     c.Float64("speed"),
+			}
+
+			l, err := net.Listen("tcp", c.String("listen"))
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+
+			slog.Info("Replaying recording",
+				slog.String("path", path),
+				slog.Any("address", l.Addr()),
+				slog.Float64("speed", c.Float64("speed")))
+
+			return device.Serve(appContext(c.Context), l)
+		},
+	}
+}
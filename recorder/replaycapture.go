@@ -0,0 +1,66 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newReplayCaptureCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay-capture",
+		Usage:     "Replays a connection captured with --capture-dir as the device, to reproduce device misbehavior offline",
+		ArgsUsage: "<capture>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "127.0.0.1:8080",
+				Usage: "Address the device listens on",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single capture")
+			}
+
+			frames, err := openpsg.LoadCapture(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			l, err := net.Listen("tcp", c.String("listen"))
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+
+			slog.Info("Serving capture",
+				slog.String("path", c.Args().First()),
+				slog.Int("frames", len(frames)),
+				slog.Any("address", l.Addr()))
+
+			replay := &openpsg.CaptureReplay{Frames: frames}
+			return replay.Serve(appContext(c.Context), l)
+		},
+	}
+}
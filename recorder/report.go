@@ -0,0 +1,120 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/olekukonko/tablewriter"
+)
+
+// printReport prints the statistics of each device and signal of a recording as
+// tables.
+func printReport(w io.Writer, report openpsg.RecordingReport) {
+	fmt.Fprintf(w, "Recorded %s from %s to %s",
+		(time.Duration(report.Duration * float64(time.Second))).Truncate(time.Second),
+		report.StartTime.Format(time.DateTime), report.EndTime.Format(time.DateTime))
+	if report.RecordingStartTime.Before(report.StartTime) {
+		fmt.Fprintf(w, " (resuming the recording started at %s)", report.RecordingStartTime.Format(time.DateTime))
+	}
+	fmt.Fprintln(w, ":")
+
+	devices := tablewriter.NewWriter(w)
+	devices.SetHeader([]string{"Device", "Name", "Reconnects", "Outages", "Disconnected", "Clock Offset"})
+	devices.SetBorder(false)
+
+	signals := tablewriter.NewWriter(w)
+	signals.SetHeader([]string{"Device", "Signal", "Rate", "Received", "Recorded", "Gaps", "Longest Gap", "Dropped", "Lost", "Drift", "Min", "Max"})
+	signals.SetBorder(false)
+
+	for _, device := range report.Devices {
+		offset := ""
+		if device.ClockOffset != nil {
+			offset = time.Duration(*device.ClockOffset * float64(time.Second)).Round(time.Microsecond).String()
+		}
+
+		devices.Append([]string{
+			device.Address,
+			device.Name,
+			strconv.Itoa(device.Reconnects),
+			strconv.Itoa(device.Outages),
+			formatSeconds(device.Disconnected),
+			offset,
+		})
+
+		for _, signal := range device.Signals {
+			drift, minimum, maximum := "", "", ""
+			if signal.ClockDrift != nil {
+				ppm := *signal.ClockDrift
+				if math.Abs(ppm) < 0.5 {
+					ppm = 0
+				}
+				drift = fmt.Sprintf("%.0f ppm", ppm)
+			}
+			if signal.Min != nil {
+				minimum = fmt.Sprintf("%.4g %s", *signal.Min, signal.Unit)
+				maximum = fmt.Sprintf("%.4g %s", *signal.Max, signal.Unit)
+			}
+
+			signals.Append([]string{
+				device.Address,
+				signal.Label,
+				fmt.Sprintf("%d Hz", signal.SampleRate),
+				strconv.FormatUint(signal.Received, 10),
+				fmt.Sprintf("%.2f%%", 100*signal.Completeness()),
+				strconv.Itoa(signal.Gaps),
+				formatSeconds(signal.LongestGap),
+				strconv.FormatUint(signal.Dropped, 10),
+				strconv.FormatUint(signal.Lost, 10),
+				drift,
+				minimum,
+				maximum,
+			})
+		}
+	}
+
+	devices.Render()
+	signals.Render()
+}
+
+// formatSeconds formats a number of seconds as a duration.
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+// saveReport writes the report to path as JSON.
+func saveReport(path string, report openpsg.RecordingReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseScheduleTime parses either an RFC 3339 timestamp, or a local clock time
+// (HH:MM) which refers to its next occurrence after the specified time.
+func parseScheduleTime(s string, after time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected HH:MM or RFC 3339", s)
+	}
+
+	t := time.Date(after.Year(), after.Month(), after.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+	if !t.After(after) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// resolveSchedule returns the times to start and stop recording at (zero if
+// not given), from the start and stop times given at now.
+func resolveSchedule(start, stop string, now time.Time) (startAt, stopAt time.Time, err error) {
+	if start != "" {
+		startAt, err = parseScheduleTime(start, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse start time: %w", err)
+		}
+	}
+
+	if stop != "" {
+		// Clock times refer to the next occurrence after the recording starts.
+		after := now
+		if startAt.After(now) {
+			after = startAt
+		}
+
+		stopAt, err = parseScheduleTime(stop, after)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse stop time: %w", err)
+		}
+
+		if !stopAt.After(after) {
+			return time.Time{}, time.Time{}, fmt.Errorf("stop time %s is before the recording starts", stopAt.Format(time.RFC3339))
+		}
+	}
+
+	return startAt, stopAt, nil
+}
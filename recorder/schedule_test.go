@@ -0,0 +1,161 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSchedule(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		start, stop string
+		wantStart   time.Time
+		wantStop    time.Time
+		wantErr     bool
+	}{
+		{
+			name: "Not scheduled",
+			now:  at(1, 21, 0),
+		},
+		{
+			name:      "Overnight",
+			now:       at(1, 21, 0),
+			start:     "22:00",
+			stop:      "06:00",
+			wantStart: at(1, 22, 0),
+			wantStop:  at(2, 6, 0),
+		},
+		{
+			name:      "Start after midnight",
+			now:       at(1, 21, 0),
+			start:     "01:00",
+			stop:      "07:00",
+			wantStart: at(2, 1, 0),
+			wantStop:  at(2, 7, 0),
+		},
+		{
+			name:      "Started after midnight",
+			now:       at(2, 0, 30),
+			start:     "01:00",
+			stop:      "07:00",
+			wantStart: at(2, 1, 0),
+			wantStop:  at(2, 7, 0),
+		},
+		{
+			// A clock time that has passed today refers to tomorrow.
+			name:      "Start time passed",
+			now:       at(1, 22, 30),
+			start:     "22:00",
+			wantStart: at(2, 22, 0),
+		},
+		{
+			name:      "Start now",
+			now:       at(1, 22, 0),
+			start:     "22:00",
+			wantStart: at(2, 22, 0),
+		},
+		{
+			name:     "Stop only",
+			now:      at(1, 21, 0),
+			stop:     "06:00",
+			wantStop: at(2, 6, 0),
+		},
+		{
+			name:     "Stop later today",
+			now:      at(1, 21, 0),
+			stop:     "23:59",
+			wantStop: at(1, 23, 59),
+		},
+		{
+			// The stop time is the next occurrence after the start, rather
+			// than after now.
+			name:      "Stop before start",
+			now:       at(1, 12, 0),
+			start:     "22:00",
+			stop:      "20:00",
+			wantStart: at(1, 22, 0),
+			wantStop:  at(2, 20, 0),
+		},
+		{
+			name:      "Timestamps",
+			now:       at(1, 21, 0),
+			start:     at(1, 22, 0).Format(time.RFC3339),
+			stop:      at(2, 6, 0).Format(time.RFC3339),
+			wantStart: at(1, 22, 0),
+			wantStop:  at(2, 6, 0),
+		},
+		{
+			name:      "Clock time after timestamp",
+			now:       at(1, 21, 0),
+			start:     at(1, 23, 0).Format(time.RFC3339),
+			stop:      "06:00",
+			wantStart: at(1, 23, 0),
+			wantStop:  at(2, 6, 0),
+		},
+		{
+			name:    "Stop timestamp before start",
+			now:     at(1, 21, 0),
+			start:   at(1, 22, 0).Format(time.RFC3339),
+			stop:    at(1, 21, 30).Format(time.RFC3339),
+			wantErr: true,
+		},
+		{
+			name:    "Stop timestamp passed",
+			now:     at(1, 21, 0),
+			stop:    at(1, 20, 0).Format(time.RFC3339),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid start",
+			now:     at(1, 21, 0),
+			start:   "25:00",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid stop",
+			now:     at(1, 21, 0),
+			stop:    "6am",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startAt, stopAt, err := resolveSchedule(tt.start, tt.stop, tt.now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, tt.wantStart.Equal(startAt), "start at %s, want %s", startAt, tt.wantStart)
+			assert.True(t, tt.wantStop.Equal(stopAt), "stop at %s, want %s", stopAt, tt.wantStop)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/dhcp"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/leasedb"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/netutil"
+	"github.com/OpenPSG/sntp"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+func newServeCommand(socketPath string) *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Runs the network services (DHCP, NTP and discovery) persistently, for recordings made with record",
+		Flags: append(networkFlags(), socketFlag(socketPath)),
+		Action: func(c *cli.Context) error {
+			ifname := c.String("interface")
+			if ifname == "" {
+				return fmt.Errorf("network interface name is required")
+			}
+
+			ln, err := listenSocket(c.String("socket"))
+			if err != nil {
+				return err
+			}
+			defer ln.Close()
+
+			network, db, err := openNetwork(c, ifname)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			g, ctx := errgroup.WithContext(appContext(c.Context))
+
+			slog.Info("Serving network services",
+				slog.String("interface", ifname),
+				slog.Any("prefix", network.Prefix),
+				slog.String("socket", c.String("socket")))
+
+			runNetworkServices(ctx, g, db, network)
+			serveLeaseDB(ctx, g, ln, db, network)
+
+			return g.Wait()
+		},
+	}
+}
+
+// networkFlags returns the flags configuring the network the devices are
+// attached to.
+func networkFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "interface",
+			Aliases: []string{"i"},
+			Usage:   "Network interface name (required, unless recording only sources given with --source or --device)",
+		},
+		&cli.StringFlag{
+			Name:  "prefix",
+			Value: "10.24.0.0/24",
+			Usage: "CIDR prefix for the network",
+		},
+		&cli.StringFlag{
+			Name:  "gateway",
+			Value: "10.24.0.1",
+			Usage: "Gateway IP address",
+		},
+	}
+}
+
+// socketFlag returns the flag giving the Unix socket the lease database is
+// served on (by serve, or a recorder running the network services itself).
+func socketFlag(socketPath string) cli.Flag {
+	return &cli.StringFlag{
+		Name:  "socket",
+		Value: socketPath,
+		Usage: "Path to the Unix socket the lease database is served on while the network services are running, for record to attach to (and leases list)",
+	}
+}
+
+// openNetwork configures the network interface (as given by the flags), and
+// opens its DHCP lease database.
+func openNetwork(c *cli.Context, ifname string) (leasedb.Network, *leasedb.DB, error) {
+	prefix, err := netip.ParsePrefix(c.String("prefix"))
+	if err != nil {
+		return leasedb.Network{}, nil, fmt.Errorf("failed to parse network prefix: %w", err)
+	}
+
+	gateway, err := netip.ParseAddr(c.String("gateway"))
+	if err != nil {
+		return leasedb.Network{}, nil, fmt.Errorf("failed to parse network gateway address: %w", err)
+	}
+
+	// Configure the network interface.
+	if err := netutil.ConfigureNetworkInterface(ifname, gateway, prefix); err != nil {
+		return leasedb.Network{}, nil, fmt.Errorf("failed to setup interface: %w", err)
+	}
+
+	// Open the DHCP lease database.
+	db, err := leasedb.Open(c.String("db-path"), prefix, gateway)
+	if err != nil {
+		return leasedb.Network{}, nil, fmt.Errorf("failed to open dhcp lease database: %w", err)
+	}
+
+	return leasedb.Network{Interface: ifname, Prefix: prefix, Gateway: gateway}, db, nil
+}
+
+// runNetworkServices runs the DHCP and NTP servers of the network in the
+// group, until the context is cancelled.
+func runNetworkServices(ctx context.Context, g *errgroup.Group, db *leasedb.DB, network leasedb.Network) {
+	// Set up the DHCP server.
+	dhcpServer := dhcp.NewServer(db, network.Interface, network.Prefix, network.Gateway)
+	g.Go(func() error {
+		slog.Debug("Starting DHCP server",
+			slog.String("interface", network.Interface),
+			slog.Any("prefix", network.Prefix),
+			slog.Any("gateway", network.Gateway))
+
+		err := dhcpServer.ListenAndServe(ctx)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			return fmt.Errorf("failed to run DHCP server: %w", err)
+		}
+
+		return nil
+	})
+
+	// Set up the NTP server
+	ntpServer := sntp.NewServer()
+	g.Go(func() error {
+		slog.Debug("Starting NTP server")
+
+		err := ntpServer.ListenAndServe(ctx, net.JoinHostPort(network.Gateway.String(), "123"))
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			return fmt.Errorf("failed to run NTP server: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// serveLeaseDB serves a read only view of the lease database on the listener
+// in the group, until the context is cancelled. It can't be opened by another
+// process while it's in use, so recordings made with record discover devices
+// through it (as does leases list).
+func serveLeaseDB(ctx context.Context, g *errgroup.Group, ln net.Listener, db *leasedb.DB, network leasedb.Network) {
+	srv := &http.Server{Handler: leasedb.NewHandler(db, network)}
+	g.Go(func() error {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve lease database: %w", err)
+		}
+
+		return nil
+	})
+
+	g.Go(func() error {
+		<-ctx.Done()
+		return srv.Close()
+	})
+}
+
+// listenSocket listens on the Unix socket, replacing a socket left behind by
+// a serve that didn't exit cleanly.
+func listenSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("serve is already running on %s", path)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on socket: %w", err)
+	}
+
+	return ln, nil
+}
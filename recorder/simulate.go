@@ -0,0 +1,123 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/dhcp"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/netutil"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+func newSimulateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "simulate",
+		Usage: "Simulates devices streaming generated signals, for testing and demos without hardware",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "devices",
+				Value: 1,
+				Usage: "Number of devices to simulate",
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Value: "127.0.2.1:8080",
+				Usage: "Address the first device listens on (the others listen on the following addresses, as the recorder tells devices apart by address)",
+			},
+			&cli.StringFlag{
+				Name:  "dhcp-interface",
+				Usage: "Lease an address for each device from the recorder's DHCP server on this network interface, and listen on port 80 of it (as real devices do)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			// Cancelled if a device fails to start, to stop the others.
+			ctx, cancel := context.WithCancel(appContext(c.Context))
+			defer cancel()
+
+			n := c.Int("devices")
+			if n < 1 || n > 255 {
+				return fmt.Errorf("invalid number of devices: %d", n)
+			}
+
+			listenAddr, err := netip.ParseAddrPort(c.String("listen"))
+			if err != nil {
+				return fmt.Errorf("invalid listen address: %w", err)
+			}
+
+			g, ctx := errgroup.WithContext(ctx)
+
+			addr := listenAddr
+			for i := range n {
+				device := openpsg.NewSimulatedDevice(fmt.Sprintf("SIM%03d", i+1))
+
+				if i > 0 {
+					addr = netip.AddrPortFrom(addr.Addr().Next(), addr.Port())
+				}
+				if ifname := c.String("dhcp-interface"); ifname != "" {
+					// A locally administered MAC address for each device.
+					mac := net.HardwareAddr{0x02, 0x00, 0x50, 0x53, 0x47, byte(i + 1)}
+					hostname := fmt.Sprintf("openpsg-sim-%d", i+1)
+
+					lease, err := dhcp.RequestLease(ctx, ifname, mac, hostname)
+					if err != nil {
+						return err
+					}
+					defer func() {
+						if err := lease.Release(); err != nil {
+							slog.Warn("Failed to release lease", slog.String("hostname", hostname), slog.Any("error", err))
+						}
+					}()
+
+					if err := netutil.AddAddress(ifname, lease.Prefix); err != nil {
+						return err
+					}
+					defer func() {
+						if err := netutil.RemoveAddress(ifname, lease.Prefix); err != nil {
+							slog.Warn("Failed to remove address", slog.Any("address", lease.Prefix), slog.Any("error", err))
+						}
+					}()
+
+					addr = netip.AddrPortFrom(lease.Prefix.Addr(), 80)
+				}
+
+				l, err := net.Listen("tcp", addr.String())
+				if err != nil {
+					return fmt.Errorf("failed to listen: %w", err)
+				}
+
+				slog.Info("Simulating device",
+					slog.String("device", device.Info.String()),
+					slog.Any("address", addr))
+
+				g.Go(func() error {
+					return device.Serve(ctx, l)
+				})
+			}
+
+			return g.Wait()
+		},
+	}
+}
@@ -0,0 +1,94 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// splitFiles creates the additional EDF files of a recording that has been
+// split across multiple files (eg. openpsg_2.edf, openpsg_3.edf). Like the
+// main output file, they are written as partial files until finalized.
+type splitFiles struct {
+	outputPath string
+	files      []*os.File
+}
+
+func (sf *splitFiles) Create(n int) (io.WriteSeeker, error) {
+	f, err := os.Create(sf.path(n) + partialSuffix)
+	if err != nil {
+		return nil, err
+	}
+	sf.files = append(sf.files, f)
+
+	return f, nil
+}
+
+func (sf *splitFiles) Name(n int) string {
+	return filepath.Base(sf.path(n))
+}
+
+// finalize syncs and closes the files, and renames them from their partial
+// names.
+func (sf *splitFiles) finalize() error {
+	for _, f := range sf.files {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close file: %w", err)
+		}
+
+		if err := os.Rename(f.Name(), strings.TrimSuffix(f.Name(), partialSuffix)); err != nil {
+			return fmt.Errorf("failed to rename file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// close closes the files without finalizing them.
+func (sf *splitFiles) close() {
+	for _, f := range sf.files {
+		_ = f.Close()
+	}
+}
+
+// partialPaths returns the paths of any partial split files left behind by an
+// interrupted recording.
+func (sf *splitFiles) partialPaths() []string {
+	ext := filepath.Ext(sf.outputPath)
+	matches, _ := filepath.Glob(strings.TrimSuffix(sf.outputPath, ext) + "_*" + ext + partialSuffix)
+	return matches
+}
+
+// path returns the path of the file for the nth part of the recording.
+func (sf *splitFiles) path(n int) string {
+	if n == 0 {
+		return sf.outputPath
+	}
+
+	ext := filepath.Ext(sf.outputPath)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(sf.outputPath, ext), n+1, ext)
+}
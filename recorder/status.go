@@ -0,0 +1,52 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/olekukonko/tablewriter"
+)
+
+// printStatus prints the quality of each signal as a table.
+func printStatus(qualities []openpsg.SignalQuality) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Signal", "Quality", "Clipping", "RMS"})
+	table.SetBorder(false)
+
+	for _, q := range qualities {
+		quality := q.Problem()
+		if quality == "" {
+			quality = "OK"
+		}
+
+		table.Append([]string{
+			q.Signal,
+			quality,
+			fmt.Sprintf("%.1f%%", 100*q.Clipping),
+			fmt.Sprintf("%.3g", q.RMS),
+		})
+	}
+
+	fmt.Printf("Signal quality at %s:\n", time.Now().Format(time.TimeOnly))
+	table.Render()
+}
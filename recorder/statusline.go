@@ -0,0 +1,195 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/termutil"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+)
+
+// deviceThroughput summarizes the progress of a device since the previous
+// progress report.
+type deviceThroughput struct {
+	openpsg.DeviceProgress
+	// The signal values received per second.
+	Rate float64
+	// The fullest buffer of the signals of the device.
+	BufferFill float64
+}
+
+// throughput summarizes the progress of each device, with the rate values were
+// received at since the previous report (or the start of the recording).
+func throughput(prev *openpsg.RecordingProgress, prevAt time.Time, progress openpsg.RecordingProgress, at time.Time) []deviceThroughput {
+	devices := make([]deviceThroughput, len(progress.Devices))
+	for i, device := range progress.Devices {
+		devices[i].DeviceProgress = device
+
+		since, received := progress.Start, uint64(0)
+		if prev != nil && i < len(prev.Devices) {
+			since, received = prevAt, prev.Devices[i].Received
+		}
+		if at.After(since) {
+			devices[i].Rate = float64(device.Received-received) / at.Sub(since).Seconds()
+		}
+
+		for _, signal := range progress.Signals {
+			if signal.Device == device.Addr {
+				devices[i].BufferFill = max(devices[i].BufferFill, signal.BufferFill)
+			}
+		}
+	}
+	return devices
+}
+
+// fileSize returns the size of the file at path, or zero if it can't be
+// determined.
+func fileSize(path string) uint64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(fi.Size())
+}
+
+// statusLine shows the throughput and health of the recording. On a terminal,
+// it's a line redrawn in place below the log. Otherwise, it's logged.
+type statusLine struct {
+	interactive bool
+	// The file being recorded to.
+	path string
+
+	mu     sync.Mutex
+	line   string
+	prev   *openpsg.RecordingProgress
+	prevAt time.Time
+}
+
+// newStatusLine shows the status of the recording to the file at path, until
+// closed.
+func newStatusLine(path string) *statusLine {
+	s := &statusLine{
+		interactive: termutil.IsTerminal(),
+		path:        path,
+	}
+
+	// The log is written above the status line.
+	if s.interactive {
+		log.SetOutput(s)
+	}
+
+	return s
+}
+
+// Write writes a log line, redrawing the status line below it.
+func (s *statusLine) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clear()
+	n, err := os.Stderr.Write(p)
+	s.draw()
+
+	return n, err
+}
+
+func (s *statusLine) clear() {
+	if s.line != "" {
+		fmt.Print("\r\033[2K")
+	}
+}
+
+func (s *statusLine) draw() {
+	if s.line != "" {
+		fmt.Print(s.line)
+	}
+}
+
+// update shows the progress of the recording (see openpsg.WithProgress).
+func (s *statusLine) update(progress openpsg.RecordingProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	devices := throughput(s.prev, s.prevAt, progress, now)
+	s.prev, s.prevAt = &progress, now
+
+	elapsed := now.Sub(progress.Start).Truncate(time.Second)
+	size := fileSize(s.path)
+
+	if !s.interactive {
+		attrs := []any{slog.Duration("elapsed", elapsed), slog.String("fileSize", formatBytes(size))}
+		for _, d := range devices {
+			attrs = append(attrs, slog.Group(d.Addr.String(),
+				slog.Bool("connected", d.Connected),
+				slog.String("samplesPerSecond", fmt.Sprintf("%.0f", d.Rate)),
+				slog.Uint64("dropped", d.Dropped),
+				slog.Uint64("lost", d.Lost),
+				slog.String("buffer", fmt.Sprintf("%.0f%%", 100*d.BufferFill))))
+		}
+		slog.Info("Recording status", attrs...)
+		return
+	}
+
+	parts := []string{fmt.Sprintf("Recording %s, %s", elapsed, formatBytes(size))}
+	for _, d := range devices {
+		name := d.Addr.String()
+		if d.Name != "" {
+			name = d.Name
+		}
+
+		if !d.Connected {
+			parts = append(parts, name+": disconnected")
+			continue
+		}
+
+		part := fmt.Sprintf("%s: %.0f/s, buffer %.0f%%", name, d.Rate, 100*d.BufferFill)
+		if d.Dropped > 0 || d.Lost > 0 {
+			part += fmt.Sprintf(", %d dropped, %d lost", d.Dropped, d.Lost)
+		}
+		parts = append(parts, part)
+	}
+
+	s.clear()
+	s.line = strings.Join(parts, " | ")
+	if width, ok := termutil.Width(); ok {
+		s.line = truncate(s.line, width-1)
+	}
+	s.draw()
+}
+
+// Close clears the status line, and restores the log.
+func (s *statusLine) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clear()
+	s.line = ""
+
+	if s.interactive {
+		log.SetOutput(os.Stderr)
+	}
+}
@@ -0,0 +1,190 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/edfplus"
+	"github.com/OpenPSG/OpenPSG/recorder/internal/studyreport"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	"github.com/urfave/cli/v2"
+)
+
+func newReportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "report",
+		Usage:     "Renders an overview of an EDF recording as an HTML or PDF document",
+		ArgsUsage: "<recording.edf>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Output file, ending in .html or .pdf (defaults to the recording with a .html extension)",
+			},
+			&cli.StringFlag{
+				Name:  "sidecar",
+				Usage: "Sidecar describing the devices of the recording (defaults to the recording with a .json extension)",
+			},
+			&cli.StringFlag{
+				Name:  "title",
+				Usage: "Title of the report",
+				Value: "Sleep Study Report",
+			},
+			&cli.StringFlag{
+				Name:  "pdf-converter",
+				Usage: "Command converting the HTML report to PDF, invoked with the input and output paths",
+				Value: "wkhtmltopdf",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected a single recording to report on")
+			}
+
+			inputPath := c.Args().First()
+			base := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+
+			outputPath := c.String("output")
+			if outputPath == "" {
+				outputPath = base + ".html"
+			}
+
+			f, err := os.Open(inputPath)
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer f.Close()
+
+			er, err := edfplus.Open(f)
+			if err != nil {
+				return fmt.Errorf("failed to read recording: %w", err)
+			}
+
+			study, err := studyreport.Analyze(er, c.String("title"))
+			if err != nil {
+				return fmt.Errorf("failed to analyze recording: %w", err)
+			}
+
+			// The sidecar is optional, unless explicitly given.
+			sidecarPath := c.String("sidecar")
+			if sidecarPath == "" {
+				sidecarPath = base + ".json"
+			}
+
+			study.Devices, err = readSidecarDevices(sidecarPath)
+			if err != nil && (c.IsSet("sidecar") || !errors.Is(err, fs.ErrNotExist)) {
+				return err
+			}
+
+			slog.Info("Writing report",
+				slog.String("input", inputPath),
+				slog.String("output", outputPath))
+
+			if strings.EqualFold(filepath.Ext(outputPath), ".pdf") {
+				return writePDFReport(c.Context, study, outputPath, c.String("pdf-converter"))
+			}
+
+			return writeHTMLReport(study, outputPath)
+		},
+	}
+}
+
+// readSidecarDevices reads the devices of a recording from its sidecar.
+func readSidecarDevices(path string) ([]studyreport.Device, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar: %w", err)
+	}
+
+	var sidecar openpsg.Sidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar: %w", err)
+	}
+
+	devices := make([]studyreport.Device, len(sidecar.Devices))
+	for i, device := range sidecar.Devices {
+		devices[i] = studyreport.Device{
+			Address:      device.Address,
+			Name:         device.Name,
+			MAC:          device.MAC,
+			Hostname:     device.Hostname,
+			Model:        device.Model,
+			SerialNumber: device.SerialNumber,
+			Firmware:     device.Firmware,
+		}
+		for _, signal := range device.Signals {
+			devices[i].Signals = append(devices[i].Signals, signal.Label)
+		}
+	}
+
+	return devices, nil
+}
+
+func writeHTMLReport(study *studyreport.Study, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	if err := study.WriteHTML(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close report: %w", err)
+	}
+
+	return nil
+}
+
+// writePDFReport renders the report as HTML, and converts it to PDF using an
+// external command (eg. wkhtmltopdf).
+func writePDFReport(ctx context.Context, study *studyreport.Study, path, converter string) error {
+	dir, err := os.MkdirTemp("", "openpsg-report-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	htmlPath := filepath.Join(dir, "report.html")
+	if err := writeHTMLReport(study, htmlPath); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, converter, htmlPath, path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if output := strings.TrimSpace(string(output)); output != "" {
+			return fmt.Errorf("failed to convert report to PDF: %w: %s", err, output)
+		}
+		return fmt.Errorf("failed to convert report to PDF: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,563 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenPSG/OpenPSG/recorder/internal/termutil"
+	"github.com/OpenPSG/OpenPSG/recorder/openpsg"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// How many log lines the dashboard retains.
+	dashboardLogLines = 500
+	// How often the dashboard checks the free space on the output volume.
+	dashboardRefreshInterval = time.Second
+)
+
+// useTUI returns whether full screen views are shown (on a terminal, unless
+// the no-tui flag is set), rather than plain output.
+func useTUI(c *cli.Context) bool {
+	return termutil.IsTerminal() && !c.Bool("no-tui")
+}
+
+// noTUIFlag opts out of full screen views on a terminal.
+func noTUIFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "no-tui",
+		Usage: "Show plain output rather than a full screen view on a terminal (the default when stdout isn't a terminal)",
+	}
+}
+
+// screen runs a full screen view, taking over the terminal and the logger
+// until closed.
+type screen struct {
+	program *tea.Program
+	// Closed once the program has exited.
+	exited chan struct{}
+	logs   *logWriter
+	logger *slog.Logger
+}
+
+// newScreen starts the view of the model, taking over the terminal and the
+// logger (logging at level). If the view fails, failed is called.
+func newScreen(m tea.Model, level slog.Level, failed func()) *screen {
+	s := &screen{
+		exited: make(chan struct{}),
+		logger: slog.Default(),
+	}
+
+	// Signals are handled by appContext.
+	s.program = tea.NewProgram(m, tea.WithAltScreen(), tea.WithoutSignalHandler())
+
+	s.logs = &logWriter{program: s.program}
+	slog.SetDefault(slog.New(slog.NewTextHandler(s.logs, &slog.HandlerOptions{Level: level})))
+
+	go func() {
+		defer close(s.exited)
+
+		if _, err := s.program.Run(); err != nil {
+			s.logger.Error("Failed to run full screen view", slog.Any("error", err))
+			failed()
+		}
+	}()
+
+	return s
+}
+
+// Close exits the view, restoring the terminal and the logger, and writes the
+// log lines logged meanwhile to stderr (so they aren't lost with the screen).
+func (s *screen) Close() {
+	s.program.Quit()
+	<-s.exited
+
+	slog.SetDefault(s.logger)
+
+	for _, line := range s.logs.retained() {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+// dashboard is a full screen view of the recorder, showing the devices found
+// while discovering devices, then the progress of the recording. Operator
+// events are annotated with e, a hotkey (eg. l for "Lights off") or a typed
+// note with t, and the recorder is stopped with q (or ctrl+c).
+type dashboard struct {
+	*screen
+	annotations chan openpsg.Annotation
+	// Closed once the operator has finished discovering devices.
+	discovered     chan struct{}
+	discoveredOnce sync.Once
+}
+
+// newDashboard starts the dashboard, taking over the terminal and the logger
+// (logging at level). Stopping the recorder calls stop.
+func newDashboard(stop context.CancelFunc, level slog.Level, hotkeys []hotkey) *dashboard {
+	d := &dashboard{
+		annotations: make(chan openpsg.Annotation, 16),
+		discovered:  make(chan struct{}),
+	}
+
+	d.screen = newScreen(&dashboardModel{
+		dashboard: d,
+		stop:      stop,
+		hotkeys:   hotkeys,
+		started:   time.Now(),
+	}, level, stop)
+
+	return d
+}
+
+// Show implements openpsg.DiscoveryView.
+func (d *dashboard) Show(devices []openpsg.DiscoveredDevice) {
+	d.program.Send(devicesMsg(devices))
+}
+
+// Done implements openpsg.DiscoveryView.
+func (d *dashboard) Done() <-chan struct{} {
+	return d.discovered
+}
+
+// recording switches the dashboard to show the recording to the output file.
+func (d *dashboard) recording(outputPath string) {
+	d.discoveredOnce.Do(func() { close(d.discovered) })
+	d.program.Send(recordingMsg(outputPath))
+}
+
+// progress shows the progress of the recording (see openpsg.WithProgress).
+func (d *dashboard) progress(progress openpsg.RecordingProgress) {
+	d.program.Send(progressMsg(progress))
+}
+
+type (
+	devicesMsg   []openpsg.DiscoveredDevice
+	recordingMsg string
+	progressMsg  openpsg.RecordingProgress
+	logMsg       string
+	diskMsg      struct {
+		free, size uint64
+		err        error
+	}
+	tickMsg time.Time
+)
+
+// logWriter sends each line written (eg. by the logger) to the dashboard.
+type logWriter struct {
+	program *tea.Program
+
+	mu      sync.Mutex
+	partial string
+	lines   []string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := strings.Split(w.partial+string(p), "\n")
+	w.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		w.lines = append(w.lines, line)
+		w.program.Send(logMsg(line))
+	}
+	if len(w.lines) > dashboardLogLines {
+		w.lines = w.lines[len(w.lines)-dashboardLogLines:]
+	}
+
+	return len(p), nil
+}
+
+func (w *logWriter) retained() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string(nil), w.lines...)
+}
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	headerStyle  = lipgloss.NewStyle().Bold(true)
+	okStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	problemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	dimStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+type dashboardModel struct {
+	*dashboard
+	stop    context.CancelFunc
+	hotkeys []hotkey
+
+	width, height   int
+	started         time.Time
+	now             time.Time
+	devices         []openpsg.DiscoveredDevice
+	outputPath      string
+	disk            diskMsg
+	progress        *openpsg.RecordingProgress
+	progressAt      time.Time
+	devicesRecorded []deviceThroughput
+	logs            []string
+	// Whether a note is being typed, and its text.
+	typingNote bool
+	note       string
+	stopping   bool
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(dashboardRefreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tickMsg:
+		m.now = time.Time(msg)
+		if m.outputPath == "" {
+			return m, tick()
+		}
+		outputPath := m.outputPath
+		return m, tea.Batch(tick(), func() tea.Msg {
+			free, err := freeSpace(filepath.Dir(outputPath))
+			return diskMsg{free: free, size: fileSize(outputPath + partialSuffix), err: err}
+		})
+	case diskMsg:
+		m.disk = msg
+	case devicesMsg:
+		m.devices = msg
+	case recordingMsg:
+		m.outputPath = string(msg)
+	case progressMsg:
+		progress := openpsg.RecordingProgress(msg)
+		now := time.Now()
+		m.devicesRecorded = throughput(m.progress, m.progressAt, progress, now)
+		m.progress, m.progressAt = &progress, now
+	case logMsg:
+		m.logs = append(m.logs, string(msg))
+		if len(m.logs) > dashboardLogLines {
+			m.logs = m.logs[len(m.logs)-dashboardLogLines:]
+		}
+	case tea.KeyMsg:
+		if m.typingNote {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.typingNote = false
+				if note := strings.TrimSpace(m.note); note != "" {
+					return m, m.annotate(note)
+				}
+			case tea.KeyEsc:
+				m.typingNote = false
+			case tea.KeyBackspace:
+				if runes := []rune(m.note); len(runes) > 0 {
+					m.note = string(runes[:len(runes)-1])
+				}
+			case tea.KeyRunes, tea.KeySpace:
+				m.note += string(msg.Runes)
+			case tea.KeyCtrlC:
+				return m, m.stopRecorder()
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, m.stopRecorder()
+		case "enter":
+			m.discoveredOnce.Do(func() { close(m.discovered) })
+		case "e":
+			if m.progress != nil {
+				return m, m.annotate("Operator event")
+			}
+		case "t":
+			if m.progress != nil {
+				m.typingNote = true
+				m.note = ""
+			}
+		default:
+			if hotkey, ok := lookupHotkey(m.hotkeys, msg.String()); ok && m.progress != nil {
+				return m, m.annotate(hotkey.text)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// annotate annotates the recording with the text (once recording).
+func (m *dashboardModel) annotate(text string) tea.Cmd {
+	annotation := openpsg.Annotation{Time: time.Now(), Text: text}
+	return func() tea.Msg {
+		select {
+		case m.annotations <- annotation:
+			slog.Info("Annotated recording", slog.String("text", text))
+		default:
+			slog.Warn("Dropped annotation, too many pending", slog.String("text", text))
+		}
+		return nil
+	}
+}
+
+// stopRecorder stops the recorder. Nothing is logged from Update, as the log is
+// sent to the program.
+func (m *dashboardModel) stopRecorder() tea.Cmd {
+	if m.stopping {
+		return nil
+	}
+	m.stopping = true
+	m.stop()
+
+	return func() tea.Msg {
+		slog.Info("Stopping the recorder ...")
+		return nil
+	}
+}
+
+func (m *dashboardModel) View() string {
+	var b strings.Builder
+
+	// The header.
+	phase := "Discovering devices"
+	elapsed := m.now.Sub(m.started)
+	if m.progress != nil {
+		phase = "Recording"
+		elapsed = m.now.Sub(m.progress.Start)
+	}
+	if m.stopping {
+		phase = "Stopping"
+	}
+	b.WriteString(titleStyle.Render("OpenPSG Recorder — " + phase))
+	if m.now.IsZero() {
+		elapsed = 0
+	}
+	fmt.Fprintf(&b, "  Elapsed %s", elapsed.Truncate(time.Second))
+	if m.outputPath != "" {
+		fmt.Fprintf(&b, "  Output %s", m.outputPath)
+		if m.disk.size > 0 {
+			fmt.Fprintf(&b, " (%s)", formatBytes(m.disk.size))
+		}
+		if m.disk.err != nil {
+			b.WriteString("  Free ?")
+		} else if m.disk.free > 0 {
+			fmt.Fprintf(&b, "  Free %s", formatBytes(m.disk.free))
+		}
+	}
+	b.WriteString("\n\n")
+
+	lines := 2
+	if m.progress == nil {
+		lines += m.viewDevices(&b)
+	} else {
+		lines += m.viewProgress(&b)
+	}
+
+	// The log fills the rest of the screen, less the help line.
+	b.WriteString("\n" + headerStyle.Render("Log") + "\n")
+	lines += 2
+	if n := m.height - lines - 2; n > 0 {
+		logs := m.logs
+		if len(logs) > n {
+			logs = logs[len(logs)-n:]
+		}
+		for _, line := range logs {
+			b.WriteString(truncate(line, m.width) + "\n")
+		}
+		for range n - len(logs) {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.typingNote:
+		b.WriteString("Note: " + m.note + "█  " + dimStyle.Render("enter annotate • esc cancel"))
+	case m.progress == nil:
+		b.WriteString(dimStyle.Render("enter start recording • q stop"))
+	default:
+		var help []string
+		for _, hotkey := range m.hotkeys {
+			help = append(help, hotkey.key+" "+hotkey.text)
+		}
+		help = append(help, "e mark event", "t add note", "q stop recording")
+		b.WriteString(dimStyle.Render(truncate(strings.Join(help, " • "), m.width)))
+	}
+
+	return b.String()
+}
+
+// viewDevices writes the devices found while discovering devices, returning
+// the number of lines written.
+func (m *dashboardModel) viewDevices(b *strings.Builder) int {
+	rows := [][]string{{"IP Address", "MAC Address", "Hostname", "Name", "Device", "Signals", "Battery", "Clock Offset", "Link", "Status"}}
+	for _, d := range m.devices {
+		rows = append(rows, []string{
+			d.Addr.String(), d.MAC, d.Hostname, d.Name, d.Device,
+			strings.Join(d.Signals, ", "), d.Battery, d.ClockOffset, d.Link, d.Status,
+		})
+	}
+
+	lines := writeColumns(b, rows, m.width, func(row, _ int, cell string) string {
+		if row > 0 && !m.devices[row-1].Online {
+			return problemStyle.Render(cell)
+		}
+		return cell
+	})
+	if len(m.devices) == 0 {
+		b.WriteString(dimStyle.Render("No devices found yet") + "\n")
+		lines++
+	}
+
+	return lines
+}
+
+// viewProgress writes the devices and signals being recorded, returning the
+// number of lines written.
+func (m *dashboardModel) viewProgress(b *strings.Builder) int {
+	deviceRows := [][]string{{"Device", "Name", "Status", "Samples/s", "Dropped", "Lost"}}
+	for _, d := range m.devicesRecorded {
+		status := "Connected"
+		if !d.Connected {
+			status = "Disconnected"
+		}
+		deviceRows = append(deviceRows, []string{
+			d.Addr.String(), d.Name, status,
+			fmt.Sprintf("%.0f", d.Rate), fmt.Sprint(d.Dropped), fmt.Sprint(d.Lost),
+		})
+	}
+	lines := writeColumns(b, deviceRows, m.width, func(row, _ int, cell string) string {
+		if row > 0 && !m.devicesRecorded[row-1].Connected {
+			return problemStyle.Render(cell)
+		}
+		return cell
+	})
+
+	b.WriteString("\n")
+	lines++
+
+	signalRows := [][]string{{"Signal", "Device", "Quality", "Clipping", "RMS", "Buffer"}}
+	for _, s := range m.progress.Signals {
+		quality := s.Quality.Problem()
+		if quality == "" {
+			quality = "OK"
+		}
+		buffer := fillBar(s.BufferFill, 10)
+		if s.Spilled > 0 {
+			buffer += fmt.Sprintf(" +%d spilled", s.Spilled)
+		}
+		signalRows = append(signalRows, []string{
+			s.Quality.Signal,
+			s.Device.String(),
+			quality,
+			fmt.Sprintf("%.1f%%", 100*s.Quality.Clipping),
+			fmt.Sprintf("%.3g", s.Quality.RMS),
+			buffer,
+		})
+	}
+	lines += writeColumns(b, signalRows, m.width, func(row, col int, cell string) string {
+		if row > 0 && col == 2 {
+			if cell == "OK" {
+				return okStyle.Render(cell)
+			}
+			return problemStyle.Render(cell)
+		}
+		return cell
+	})
+
+	return lines
+}
+
+// writeColumns writes the rows (the first being the header) as aligned
+// columns, truncated to the width, styling each cell. It returns the number
+// of lines written.
+func writeColumns(b *strings.Builder, rows [][]string, width int, style func(row, col int, cell string) string) int {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	for r, row := range rows {
+		var used int
+		for i, cell := range row {
+			cellWidth := widths[i] + 2
+			if width > 0 && used+cellWidth > width {
+				break
+			}
+			used += cellWidth
+
+			padded := cell + strings.Repeat(" ", cellWidth-lipgloss.Width(cell))
+			if r == 0 {
+				b.WriteString(headerStyle.Render(padded))
+			} else {
+				b.WriteString(style(r, i, padded))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return len(rows)
+}
+
+// fillBar draws the fraction as a bar of width characters.
+func fillBar(fraction float64, width int) string {
+	filled := min(width, max(0, int(fraction*float64(width)+0.5)))
+	return fmt.Sprintf("%s%s %3.0f%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), 100*fraction)
+}
+
+// truncate truncates the line to the width (if known).
+func truncate(line string, width int) string {
+	if runes := []rune(line); width > 0 && len(runes) > width {
+		return string(runes[:width])
+	}
+	return line
+}
+
+// formatBytes formats the number of bytes in binary units.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
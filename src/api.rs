@@ -18,8 +18,10 @@
 
 use crate::ncpt;
 use crate::task::TaskSignal;
+use crate::time::clock_gettime;
 use core::fmt::Debug;
 use defmt::warn;
+use embassy_stm32::uid::uid;
 use embassy_sync::blocking_mutex::raw::ThreadModeRawMutex;
 use embassy_sync::signal::Signal as EmbassySignal;
 use embedded_jsonrpc::{
@@ -32,6 +34,16 @@ use rfc3339::Timestamp;
 use serde::de::Deserializer;
 use serde::{Deserialize, Serialize};
 
+/// The version of the protocol spoken by the firmware, incremented on
+/// incompatible changes.
+const PROTOCOL_VERSION: u32 = 1;
+
+/// The optional protocol features supported by the firmware.
+const CAPABILITIES: [&str; 2] = ["status", "time"];
+
+/// The hardware model of the device.
+const HARDWARE_MODEL: &str = "OpenPSG-NCPT";
+
 /// The transducer type used to measure a signal.
 #[derive(Debug, Deserialize, Serialize)]
 enum TransducerType {
@@ -39,6 +51,13 @@ enum TransducerType {
     MEMSPressureTransducer,
 }
 
+/// The format of the values of a signal.
+#[derive(Debug, Deserialize, Serialize)]
+enum SampleFormat {
+    #[serde(rename = "int16")]
+    Int16,
+}
+
 /// The unit of a signal.
 #[derive(Clone, Copy, Debug, Deserialize, Serialize)]
 enum Unit {
@@ -207,6 +226,15 @@ struct Signal<'a> {
     min: f32,
     /// The maximum value of the signal (in the unit of the signal).
     max: f32,
+    /// The digital value corresponding to the minimum value of the signal.
+    #[serde(rename(serialize = "digitalMin"))]
+    digital_min: i32,
+    /// The digital value corresponding to the maximum value of the signal.
+    #[serde(rename(serialize = "digitalMax"))]
+    digital_max: i32,
+    /// The format of the values of the signal.
+    #[serde(rename(serialize = "sampleFormat"))]
+    sample_format: SampleFormat,
     /// The list of filters applied to the signal.
     prefiltering: FilterList,
     /// The sample rate of the signal (in Hertz).
@@ -214,11 +242,41 @@ struct Signal<'a> {
     sample_rate: u32,
 }
 
+#[derive(Debug, Serialize)]
+struct Version<'a> {
+    /// The version of the protocol.
+    version: u32,
+    /// The optional protocol features supported.
+    capabilities: &'a [&'a str],
+}
+
+#[derive(Debug, Serialize)]
+struct Info<'a> {
+    /// The serial number of the device (its unique ID).
+    #[serde(rename(serialize = "serialNumber"))]
+    serial_number: &'a str,
+    /// The hardware model of the device.
+    model: &'a str,
+    /// The version of the firmware.
+    #[serde(rename(serialize = "firmwareVersion"))]
+    firmware_version: &'a str,
+}
+
+#[derive(Debug, Serialize)]
+struct Time {
+    /// When the request was received.
+    received: Timestamp,
+    /// When the response was sent.
+    transmitted: Timestamp,
+}
+
 /// The values of a signal at a given timestamp.
 #[derive(Debug, Serialize)]
 pub struct SignalValues<'a> {
     /// The unique identifier of the signal these values belong to.
     pub id: u32,
+    /// The sequence number of the values, incremented with each batch.
+    pub seq: u32,
     /// The start timestamp of the values.
     pub timestamp: Timestamp,
     /// The list of values.
@@ -238,6 +296,84 @@ impl RpcHandler {
         }
     }
 
+    async fn version<'a>(
+        &self,
+        id: Option<u64>,
+        response_json: &'a mut [u8],
+    ) -> Result<usize, RpcError> {
+        let response: RpcResponse<Version> = RpcResponse {
+            jsonrpc: JSONRPC_VERSION,
+            error: None,
+            result: Some(Version {
+                version: PROTOCOL_VERSION,
+                capabilities: &CAPABILITIES,
+            }),
+            id,
+        };
+
+        Ok(serde_json_core::to_slice(&response, response_json).unwrap())
+    }
+
+    async fn ping<'a>(
+        &self,
+        id: Option<u64>,
+        response_json: &'a mut [u8],
+    ) -> Result<usize, RpcError> {
+        let response: RpcResponse<'static, ()> = RpcResponse {
+            jsonrpc: JSONRPC_VERSION,
+            error: None,
+            result: None,
+            id,
+        };
+
+        Ok(serde_json_core::to_slice(&response, response_json).unwrap())
+    }
+
+    async fn time<'a>(
+        &self,
+        id: Option<u64>,
+        response_json: &'a mut [u8],
+    ) -> Result<usize, RpcError> {
+        let received = clock_gettime().unwrap();
+        let transmitted = clock_gettime().unwrap();
+
+        let response: RpcResponse<Time> = RpcResponse {
+            jsonrpc: JSONRPC_VERSION,
+            error: None,
+            result: Some(Time {
+                received: rfc3339::format_unix(received.seconds, received.micros),
+                transmitted: rfc3339::format_unix(transmitted.seconds, transmitted.micros),
+            }),
+            id,
+        };
+
+        Ok(serde_json_core::to_slice(&response, response_json).unwrap())
+    }
+
+    async fn info<'a>(
+        &self,
+        id: Option<u64>,
+        response_json: &'a mut [u8],
+    ) -> Result<usize, RpcError> {
+        let mut serial_number: String<24> = String::new();
+        for b in uid() {
+            core::fmt::write(&mut serial_number, format_args!("{:02X}", b)).unwrap();
+        }
+
+        let response: RpcResponse<Info> = RpcResponse {
+            jsonrpc: JSONRPC_VERSION,
+            error: None,
+            result: Some(Info {
+                serial_number: &serial_number,
+                model: HARDWARE_MODEL,
+                firmware_version: env!("CARGO_PKG_VERSION"),
+            }),
+            id,
+        };
+
+        Ok(serde_json_core::to_slice(&response, response_json).unwrap())
+    }
+
     async fn signals<'a>(
         &self,
         id: Option<u64>,
@@ -250,6 +386,9 @@ impl RpcHandler {
             unit: Unit::Pascals,
             min: -200.0,
             max: 200.0,
+            digital_min: -(i16::MAX as i32),
+            digital_max: i16::MAX as i32,
+            sample_format: SampleFormat::Int16,
             prefiltering: FilterList {
                 filters: Vec::from_slice(&[
                     Filter {
@@ -365,6 +504,10 @@ impl embedded_jsonrpc::RpcHandler for RpcHandler {
     ) -> StackFuture<'a, Result<usize, RpcError>, DEFAULT_HANDLER_STACK_SIZE> {
         StackFuture::from(async move {
             match method {
+                "openpsg.version" => self.version(id, response_json).await,
+                "openpsg.ping" => self.ping(id, response_json).await,
+                "openpsg.time" => self.time(id, response_json).await,
+                "openpsg.info" => self.info(id, response_json).await,
                 "openpsg.signals" => self.signals(id, response_json).await,
                 "openpsg.start" => self.start(id, request_json, response_json).await,
                 "openpsg.stop" => self.stop(id, request_json, response_json).await,
@@ -51,6 +51,7 @@ mod biquad_filter;
 mod cs1237;
 mod ncpt;
 mod net_util;
+mod status;
 mod task;
 mod time;
 
@@ -244,6 +245,9 @@ async fn main(spawner: Spawner) -> ! {
         ))
         .unwrap();
 
+    // Launch status reporting task.
+    spawner.spawn(status::report(rpc_server)).unwrap();
+
     let mut rx_buffer = [0; 128]; // Received commands are nice and small.
     let mut tx_buffer = [0; 1460]; // One Ethernet frame worth of data.
 
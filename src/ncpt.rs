@@ -61,6 +61,10 @@ pub async fn sample(
         ANTIALIAS_NOTCH_FILTER_4HZ_DENOMINATOR,
     );
 
+    // Incremented with each batch of values sent, so the recorder can detect
+    // lost or duplicated batches.
+    let mut seq: u32 = 0;
+
     loop {
         // Wait for the start signal
         while signals.wait().await != TaskSignal::Start {}
@@ -96,6 +100,7 @@ pub async fn sample(
 
                         let notification_payload = &SignalValues {
                             id: NCPT_SIGNAL_ID,
+                            seq,
                             timestamp: rfc3339::format_unix(
                                 samples_start.seconds,
                                 samples_start.micros,
@@ -120,6 +125,7 @@ pub async fn sample(
                             .await
                             .unwrap();
 
+                        seq = seq.wrapping_add(1);
                         samples_start = clock_gettime().unwrap();
                         samples.clear();
                     }
@@ -0,0 +1,70 @@
+/* SPDX-License-Identifier: AGPL-3.0-or-later
+ *
+ * Copyright (C) 2025 The OpenPSG Authors.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published
+ * by the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+use crate::time::clock_gettime;
+use defmt::warn;
+use embassy_net::tcp::Error as TcpReadError;
+use embassy_time::{Duration, Instant, Timer};
+use embedded_jsonrpc::{RpcRequest, RpcServer, JSONRPC_VERSION};
+use rfc3339::Timestamp;
+use serde::Serialize;
+
+/// How often the status of the device is reported.
+const STATUS_INTERVAL: Duration = Duration::from_secs(1);
+
+/// The status of the device. The device is powered over Ethernet, so has no
+/// battery, and doesn't measure its temperature.
+#[derive(Debug, Serialize)]
+struct Status {
+    /// When the status was reported.
+    timestamp: Timestamp,
+    /// The time since the device started (in seconds).
+    uptime: u64,
+}
+
+#[embassy_executor::task]
+pub async fn report(rpc_server: &'static RpcServer<'static, TcpReadError>) -> ! {
+    loop {
+        Timer::after(STATUS_INTERVAL).await;
+
+        let now = clock_gettime().unwrap();
+        let status = &Status {
+            timestamp: rfc3339::format_unix(now.seconds, now.micros),
+            uptime: Instant::now().as_secs(),
+        };
+
+        let notification: RpcRequest<&Status> = RpcRequest {
+            jsonrpc: JSONRPC_VERSION,
+            id: None,
+            method: "openpsg.status",
+            params: Some(status),
+        };
+
+        let mut notification_json = [0u8; 128];
+        let notification_len =
+            serde_json_core::to_slice(&notification, &mut notification_json).unwrap();
+
+        if rpc_server
+            .notify(&notification_json[..notification_len])
+            .await
+            .is_err()
+        {
+            warn!("Failed to report status");
+        }
+    }
+}